@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var adviseCmd = &cobra.Command{
+	Use:   "advise",
+	Short: "Suggest which worktrees to clean up, rebase or finish",
+	Long: `Look at every koh worktree and suggest what to do with it, most useful
+first, each with the exact koh command to run:
+
+  - merged branches with nothing left to commit can be cleaned up
+  - stale worktrees (no commits for --stale-days) can be cleaned up,
+    keeping their branch
+  - merged or stale worktrees with uncommitted changes need a look first
+  - branches that conflict with their base branch (see 'koh base') should
+    be rebased
+
+Branches count as merged when git sees them merged into the default branch,
+or when their pull request was merged (with the GitHub CLI installed).
+Worktrees with uncommitted changes are never suggested for cleanup, and
+read-only worktrees (see 'koh readonly') get no advice at all.
+Nothing is changed; run it weekly as a hygiene report.`,
+	Args: cobra.NoArgs,
+	RunE: runAdvise,
+}
+
+// adviseStaleDays is how many days without commits make a worktree stale
+var adviseStaleDays int
+
+func init() {
+	adviseCmd.Flags().IntVar(&adviseStaleDays, "stale-days", 30, "Days without commits after which a worktree is stale")
+	rootCmd.AddCommand(adviseCmd)
+}
+
+// Kinds of advice, in the order they are shown
+const (
+	adviceRemoveMerged     = "remove_merged"
+	adviceRemoveStale      = "remove_stale"
+	adviceReviewMerged     = "review_merged"
+	adviceReviewStale      = "review_stale"
+	adviceResolveConflicts = "resolve_conflicts"
+)
+
+// adviceRank orders kinds of advice, most useful first
+var adviceRank = map[string]int{
+	adviceRemoveMerged:     0,
+	adviceRemoveStale:      1,
+	adviceReviewMerged:     2,
+	adviceReviewStale:      3,
+	adviceResolveConflicts: 4,
+}
+
+// worktreeHealth is what 'koh advise' found out about a worktree
+type worktreeHealth struct {
+	Name   string
+	Branch string
+	Dirty  bool
+	// MergedVia is how the branch was found merged, "" when it isn't
+	MergedVia string
+	// Base is the branch the worktree targets when it isn't the default
+	// branch (see 'koh base')
+	Base string
+	// Conflicts is set when merging the branch into the base would conflict
+	Conflicts  bool
+	LastCommit time.Time
+	// Readonly is set for worktrees marked with 'koh readonly', which are
+	// kept as they are
+	Readonly bool
+}
+
+// advice is a suggested action for a worktree
+type advice struct {
+	Kind     string `json:"kind"`
+	Worktree string `json:"worktree"`
+	Reason   string `json:"reason"`
+	Command  string `json:"command"`
+
+	lastCommit time.Time
+}
+
+// adviseResult is the machine-readable result of 'koh advise'
+type adviseResult struct {
+	Base      string   `json:"base"`
+	Worktrees int      `json:"worktrees"`
+	Advice    []advice `json:"advice"`
+}
+
+// idleDays returns the whole days between t and now
+func idleDays(t, now time.Time) int {
+	return int(now.Sub(t).Hours() / 24)
+}
+
+// adviseWorktrees returns the suggested actions for a set of worktrees,
+// ranked by kind and then oldest first. A worktree gets at most one
+// cleanup or review suggestion; conflicts only matter while it's kept.
+func adviseWorktrees(worktrees []worktreeHealth, base string, now time.Time, staleAfter time.Duration) []advice {
+	result := []advice{}
+	for _, wt := range worktrees {
+		if wt.Readonly {
+			continue
+		}
+		add := func(kind, reason, command string) {
+			result = append(result, advice{Kind: kind, Worktree: wt.Name, Reason: reason, Command: command, lastCommit: wt.LastCommit})
+		}
+
+		if wt.MergedVia != "" {
+			merged := fmt.Sprintf("%s was merged into %s", wt.Branch, base)
+			if wt.MergedVia == mergedViaPullRequest {
+				merged = fmt.Sprintf("the pull request for %s was merged", wt.Branch)
+			}
+			if wt.Dirty {
+				add(adviceReviewMerged, merged+" but there are uncommitted changes; commit or discard them, then clean up", "koh switch "+wt.Name)
+			} else {
+				add(adviceRemoveMerged, merged, "koh cleanup "+wt.Name)
+			}
+			continue
+		}
+
+		if !wt.LastCommit.IsZero() && now.Sub(wt.LastCommit) >= staleAfter {
+			idle := fmt.Sprintf("no commits for %d days", idleDays(wt.LastCommit, now))
+			if !wt.Dirty {
+				add(adviceRemoveStale, idle+"; the branch is kept when the worktree is removed", "koh cleanup "+wt.Name)
+				continue
+			}
+			add(adviceReviewStale, idle+" and there are uncommitted changes; finish or park the work", "koh switch "+wt.Name)
+		}
+
+		if wt.Conflicts {
+			target := base
+			if wt.Base != "" {
+				target = wt.Base
+			}
+			add(adviceResolveConflicts, fmt.Sprintf("%s conflicts with %s; rebase and resolve the conflicts", wt.Branch, target), fmt.Sprintf("koh exec %s -- git rebase %s", wt.Name, target))
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if adviceRank[result[i].Kind] != adviceRank[result[j].Kind] {
+			return adviceRank[result[i].Kind] < adviceRank[result[j].Kind]
+		}
+		return result[i].lastCommit.Before(result[j].lastCommit)
+	})
+	return result
+}
+
+// loadWorktreeHealth checks each worktree against base, given the branches
+// git sees merged into it. Conflicts are checked against the worktree's own
+// base branch when one is recorded. Conflicts aren't checked when git is
+// older than git.MergeTreeVersion or once checking fails; the returned error
+// says why.
+func loadWorktreeHealth(ctx context.Context, worktrees []git.Worktree, base string, merged map[string]bool) ([]worktreeHealth, error) {
+	isAncestor := func(ancestor, descendant string) bool {
+		return git.IsAncestorWithContext(ctx, ancestor, descendant)
+	}
+	upstreams, _ := git.BranchUpstreamsWithContext(ctx)
+	mergedVia := map[string]string{}
+	for _, m := range selectMergedWorktrees(worktrees, base, merged, loadPullRequests(ctx), isAncestor, upstreams, loadCleanupConfig()) {
+		mergedVia[m.worktree.Path] = m.via
+	}
+
+	recorded := loadRecordedWorktrees()
+	var conflictErr error
+	if !gitAtLeast(ctx, git.MergeTreeVersion) {
+		conflictErr = fmt.Errorf("it needs git %s or newer", git.MergeTreeVersion)
+	}
+	health := make([]worktreeHealth, 0, len(worktrees))
+	for _, wt := range worktrees {
+		h := worktreeHealth{Name: filepath.Base(wt.Path), Branch: displayBranch(wt), MergedVia: mergedVia[wt.Path]}
+		if r := recorded[h.Name]; r != nil {
+			h.Readonly = r.Readonly
+			if r.Parent == "" {
+				h.Base = r.Base
+			}
+		}
+		h.Dirty, _ = git.IsDirty(wt.Path)
+		h.LastCommit, _ = git.LastCommitTimeWithContext(ctx, wt.Path)
+
+		if wt.Branch != "" && h.MergedVia == "" && conflictErr == nil {
+			target := base
+			if h.Base != "" {
+				target = h.Base
+			}
+			h.Conflicts, conflictErr = git.MergeConflictsWithContext(ctx, target, wt.Branch)
+		}
+		health = append(health, h)
+	}
+	return health, conflictErr
+}
+
+func runAdvise(cmd *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+	if adviseStaleDays < 1 {
+		return fmt.Errorf("--stale-days must be at least 1")
+	}
+	p := newPrinter(cmd)
+
+	ctx := context.Background()
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	base, err := git.GetDefaultBranchWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	merged, err := git.MergedBranchesWithContext(ctx, base)
+	if err != nil {
+		return err
+	}
+
+	health, err := loadWorktreeHealth(ctx, worktrees, base, merged)
+	if err != nil {
+		p.Warn("Skipping the conflict check: %v", err)
+	}
+
+	staleAfter := time.Duration(adviseStaleDays) * 24 * time.Hour
+	result := adviseResult{Base: base, Worktrees: len(worktrees), Advice: adviseWorktrees(health, base, time.Now(), staleAfter)}
+	return p.Result(result, func(w io.Writer) {
+		fprintln(w, "\n"+styles.RenderTitle(styles.IconTree+" Koh Advice"))
+		if len(result.Advice) == 0 {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("All %d worktrees look tidy", result.Worktrees)))
+			fprintln(w)
+			return
+		}
+		for i, a := range result.Advice {
+			fprintln(w, fmt.Sprintf("%2d. %s  %s", i+1, styles.Key.Render(a.Worktree), a.Reason))
+			fprintln(w, "    "+styles.Active.Render(a.Command))
+		}
+		fprintln(w)
+	})
+}
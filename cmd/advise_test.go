@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdviseWorktrees(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+
+	worktrees := []worktreeHealth{
+		{Name: "active", Branch: "active", Dirty: true, LastCommit: daysAgo(1)},
+		{Name: "conflicting", Branch: "conflicting", Conflicts: true, LastCommit: daysAgo(2)},
+		{Name: "retargeted", Branch: "retargeted", Base: "release/1.2", Conflicts: true, LastCommit: daysAgo(1)},
+		{Name: "stale-dirty", Branch: "stale-dirty", Dirty: true, Conflicts: true, LastCommit: daysAgo(60)},
+		{Name: "stale", Branch: "stale", Conflicts: true, LastCommit: daysAgo(45)},
+		{Name: "merged-dirty", Branch: "merged-dirty", Dirty: true, MergedVia: mergedViaGit, LastCommit: daysAgo(3)},
+		{Name: "squashed", Branch: "squashed", MergedVia: mergedViaPullRequest, LastCommit: daysAgo(5)},
+		{Name: "merged", Branch: "merged", MergedVia: mergedViaGit, Conflicts: true, LastCommit: daysAgo(10)},
+	}
+
+	got := adviseWorktrees(worktrees, "origin/main", now, 30*24*time.Hour)
+
+	want := []struct{ kind, worktree, command string }{
+		{adviceRemoveMerged, "merged", "koh cleanup merged"},
+		{adviceRemoveMerged, "squashed", "koh cleanup squashed"},
+		{adviceRemoveStale, "stale", "koh cleanup stale"},
+		{adviceReviewMerged, "merged-dirty", "koh switch merged-dirty"},
+		{adviceReviewStale, "stale-dirty", "koh switch stale-dirty"},
+		{adviceResolveConflicts, "stale-dirty", "koh exec stale-dirty -- git rebase origin/main"},
+		{adviceResolveConflicts, "conflicting", "koh exec conflicting -- git rebase origin/main"},
+		{adviceResolveConflicts, "retargeted", "koh exec retargeted -- git rebase release/1.2"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d suggestions, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Kind != w.kind || got[i].Worktree != w.worktree || got[i].Command != w.command {
+			t.Errorf("Suggestion %d: Expected %s for %s (%s), got %+v", i+1, w.kind, w.worktree, w.command, got[i])
+		}
+	}
+
+	if !strings.Contains(got[1].Reason, "pull request") {
+		t.Errorf("Expected a squash merge to mention the pull request, got %q", got[1].Reason)
+	}
+	if !strings.Contains(got[2].Reason, "45 days") {
+		t.Errorf("Expected the idle time in the reason, got %q", got[2].Reason)
+	}
+}
+
+func TestAdviseWorktreesSkipsReadonly(t *testing.T) {
+	now := time.Now()
+	worktrees := []worktreeHealth{{Name: "release", Branch: "release", Readonly: true, MergedVia: mergedViaGit, Conflicts: true, LastCommit: now.Add(-90 * 24 * time.Hour)}}
+	if got := adviseWorktrees(worktrees, "main", now, time.Hour); len(got) != 0 {
+		t.Errorf("Expected no suggestions for a read-only worktree, got %+v", got)
+	}
+}
+
+func TestAdviseWorktreesTidy(t *testing.T) {
+	now := time.Now()
+	got := adviseWorktrees([]worktreeHealth{{Name: "fresh", Branch: "fresh", LastCommit: now}}, "main", now, time.Hour)
+	if len(got) != 0 {
+		t.Errorf("Expected no suggestions, got %+v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// isBuiltinCommand reports whether name runs one of koh's own commands
+func isBuiltinCommand(name string) bool {
+	// cobra only adds these once it executes
+	switch name {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandIndex returns the index of the command in koh's arguments, the
+// first one that isn't a global flag or its value, or -1 when there is none
+func commandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return -1
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			return i
+		case strings.Contains(arg, "="):
+			continue
+		}
+
+		// Flags that aren't booleans take the next argument as their value
+		if takesValue(arg) {
+			i++
+		}
+	}
+	return -1
+}
+
+// takesValue reports whether arg is a global flag whose value is the next
+// argument, such as "--repo" but not "--json"
+func takesValue(arg string) bool {
+	flags := rootCmd.PersistentFlags()
+	flag := flags.Lookup(strings.TrimPrefix(arg, "--"))
+	if !strings.HasPrefix(arg, "--") {
+		// Shorthands can be combined, as in -yq; only the last can take a value
+		flag = flags.ShorthandLookup(arg[len(arg)-1:])
+	}
+	return flag != nil && flag.NoOptDefVal == ""
+}
+
+// expandAlias replaces an alias from the global config in the command, the
+// first argument after any global flags such as --json, with the command it
+// stands for. Built-in commands take precedence over aliases of the same
+// name, and aliases don't expand further.
+func expandAlias(g *config.Global, args []string) ([]string, error) {
+	i := commandIndex(args)
+	if i < 0 || isBuiltinCommand(args[i]) {
+		return args, nil
+	}
+	expansion, err := g.Alias(args[i])
+	if err != nil || expansion == nil {
+		return args, err
+	}
+	expanded := append(slices.Clone(args[:i]), expansion...)
+	return append(expanded, args[i+1:]...), nil
+}
+
+// shadowedAliases returns the aliases named after built-in commands, which
+// never run
+func shadowedAliases(g *config.Global) []string {
+	var shadowed []string
+	for name := range g.Aliases {
+		if isBuiltinCommand(name) {
+			shadowed = append(shadowed, name)
+		}
+	}
+	sort.Strings(shadowed)
+	return shadowed
+}
+
+// commandLineArgs returns koh's arguments with aliases expanded. Problems
+// with the global config other than a broken alias are reported by
+// 'koh doctor'; until they're fixed aliases don't expand.
+func commandLineArgs(args []string) ([]string, error) {
+	g, err := config.LoadGlobal()
+	if err != nil {
+		return args, nil
+	}
+	expanded, err := expandAlias(g, args)
+	if err != nil {
+		path, _ := config.GlobalPath()
+		return nil, fmt.Errorf("%w\nFix the aliases in %s", err, path)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+)
+
+func TestExpandAlias(t *testing.T) {
+	g := &config.Global{Aliases: map[string]string{
+		"kn":   "new --base origin/main --profile backend",
+		"ls":   "list --json",
+		"list": "list --json",
+		"kk":   "kn",
+	}}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: nil, want: nil},
+		{args: []string{"kn", "feature"}, want: []string{"new", "--base", "origin/main", "--profile", "backend", "feature"}},
+		{args: []string{"ls"}, want: []string{"list", "--json"}},
+		// Built-in commands win over aliases
+		{args: []string{"list"}, want: []string{"list"}},
+		{args: []string{"help", "kn"}, want: []string{"help", "kn"}},
+		// Aliases don't expand further
+		{args: []string{"kk"}, want: []string{"kn"}},
+		// Only the command is expanded
+		{args: []string{"new", "kn"}, want: []string{"new", "kn"}},
+		// The command may follow global flags
+		{args: []string{"--json", "ls"}, want: []string{"--json", "list", "--json"}},
+		{args: []string{"-y", "--no-cache", "kn", "x"}, want: []string{"-y", "--no-cache", "new", "--base", "origin/main", "--profile", "backend", "x"}},
+		{args: []string{"--json", "list"}, want: []string{"--json", "list"}},
+		{args: []string{"--", "kn"}, want: []string{"--", "kn"}},
+	}
+
+	for _, tt := range tests {
+		got, err := expandAlias(g, tt.args)
+		if err != nil {
+			t.Errorf("expandAlias(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+
+	if _, err := expandAlias(&config.Global{Aliases: map[string]string{"kn": ""}}, []string{"kn"}); err == nil {
+		t.Error("Expected an empty alias to fail")
+	}
+}
+
+func TestShadowedAliases(t *testing.T) {
+	g := &config.Global{Aliases: map[string]string{"kn": "new", "new": "new --open", "help": "list"}}
+	if got, want := shadowedAliases(g), []string{"help", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
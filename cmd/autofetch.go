@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+)
+
+// fetchDue reports whether a background fetch should start, given the
+// configured interval and when the last one started
+func fetchDue(interval time.Duration, lastFetch, now time.Time) bool {
+	return interval > 0 && now.Sub(lastFetch) >= interval
+}
+
+// maybeAutoFetch starts a background 'git fetch' when auto_fetch is configured
+// and the last fetch is older than its interval, so ahead/behind counts stay
+// fresh without every command waiting on the network. The fetch finishes
+// after this command returns: its results show up the next time.
+func maybeAutoFetch(mainRepoRoot string) {
+	exists, err := config.ConfigExists()
+	if err != nil || !exists {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	interval, err := cfg.AutoFetchInterval()
+	if err != nil || interval == 0 {
+		return
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+
+	s, err := state.Load(commonDir)
+	now := time.Now()
+	if err != nil || !fetchDue(interval, s.LastFetch, now) {
+		return
+	}
+
+	// Record the fetch before starting it, so the next command doesn't start another
+	if err := state.Update(commonDir, func(s *state.State) error {
+		s.LastFetch = now
+		return nil
+	}); err != nil {
+		return
+	}
+	_ = git.StartBackgroundFetch(mainRepoRoot)
+}
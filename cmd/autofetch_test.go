@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchDue(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		lastFetch time.Time
+		want      bool
+	}{
+		{"disabled", 0, time.Time{}, false},
+		{"never fetched", 15 * time.Minute, time.Time{}, true},
+		{"recent fetch", 15 * time.Minute, now.Add(-5 * time.Minute), false},
+		{"old fetch", 15 * time.Minute, now.Add(-20 * time.Minute), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fetchDue(tt.interval, tt.lastFetch, now); got != tt.want {
+				t.Errorf("Expected fetchDue = %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
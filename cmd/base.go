@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var baseCmd = &cobra.Command{
+	Use:   "base",
+	Short: "Show or change the branch a worktree targets",
+	Long: `Every worktree targets a base branch: the one given with 'koh new --base',
+or the default branch. 'koh advise' checks branches for conflicts with their
+base, and 'koh info' shows how far a branch has drifted from it.
+
+When a feature is retargeted, e.g. from develop to release/1.2, record the
+new base with 'koh base set', and pass --rebase to move the branch's own
+commits onto it.`,
+}
+
+var baseSetCmd = &cobra.Command{
+	Use:   "set <worktree-name> <ref>",
+	Short: "Retarget a worktree to another base branch",
+	Long: `Record ref as the base branch of a worktree. With --rebase, the commits
+the worktree's branch adds on top of its previous base are rebased onto
+ref, so commits only on the previous base are left behind.
+
+Worktrees with uncommitted changes can't be rebased. When the rebase stops
+on conflicts, resolve them in the worktree and run 'git rebase --continue';
+the new base is recorded either way.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runBaseSet,
+}
+
+var baseShowCmd = &cobra.Command{
+	Use:   "show [worktree-name]",
+	Short: "Show the base branch of a worktree",
+	Long: `Show the base branch of the given or current worktree, with the commits
+its branch adds on top of the base and the commits of the base it's missing.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runBaseShow,
+}
+
+// baseRebase rebases the worktree's commits onto the new base
+var baseRebase bool
+
+func init() {
+	baseSetCmd.Flags().BoolVar(&baseRebase, "rebase", false, "Rebase the branch's own commits onto the new base")
+	baseCmd.AddCommand(baseSetCmd, baseShowCmd)
+	rootCmd.AddCommand(baseCmd)
+}
+
+// baseResult is the machine-readable result of 'koh base set' and 'koh base show'
+type baseResult struct {
+	Name string `json:"name"`
+	Base string `json:"base"`
+	// Default is set when no base was recorded, so the default branch is used
+	Default bool `json:"default"`
+	// Previous is the base the worktree targeted before 'koh base set'
+	Previous string `json:"previous,omitempty"`
+	Rebased  bool   `json:"rebased,omitempty"`
+	// Drift is how far the branch has diverged from its base
+	Drift *git.Tracking `json:"drift,omitempty"`
+}
+
+// worktreeBase returns the base branch of the worktree called name, and
+// whether it is the default branch because none was recorded
+func worktreeBase(ctx context.Context, recorded map[string]*state.Worktree, name string) (string, bool, error) {
+	if wt := recorded[name]; wt != nil && wt.Base != "" {
+		return wt.Base, false, nil
+	}
+	base, err := git.GetDefaultBranchWithContext(ctx)
+	return base, true, err
+}
+
+// recordBase records the base branch of a worktree, or forgets it when base is ""
+func recordBase(commonDir, name, base string) error {
+	return state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(name).Base = base
+		return nil
+	})
+}
+
+func runBaseSet(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	name, ref := args[0], args[1]
+	if err := validation.ValidateWorktreeName(name); err != nil {
+		return fmt.Errorf("invalid worktree name: %w", err)
+	}
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	wt, err := stackedWorktree(worktrees, name)
+	if err != nil {
+		return err
+	}
+	recorded := loadRecordedWorktrees()
+	if parent := stackParents(recorded)[name]; parent != "" {
+		return fmt.Errorf("%s is stacked on %s, which is its base\nRun 'koh stack off %s' first to give it a base branch", name, parent, name)
+	}
+	onto, err := git.ResolveCommitWithContext(ctx, wt.Path, ref)
+	if err != nil {
+		return err
+	}
+	previous, _, err := worktreeBase(ctx, recorded, name)
+	if err != nil {
+		return err
+	}
+
+	// Find the branch's own commits before the base changes
+	var upstream string
+	if baseRebase {
+		if upstream, err = retargetUpstream(ctx, wt, previous); err != nil {
+			return err
+		}
+	}
+
+	if err := recordBase(info.CommonDir, name, ref); err != nil {
+		return err
+	}
+	result := baseResult{Name: name, Base: ref, Previous: previous}
+
+	if baseRebase {
+		p.Info("Rebasing %s from %s onto %s", wt.Branch, previous, ref)
+		if err := git.RebaseOntoWithContext(ctx, wt.Path, onto, upstream); err != nil {
+			return fmt.Errorf("rebasing %s onto %s stopped: %w\nResolve the conflicts in %s and run 'git rebase --continue'", wt.Branch, ref, err, wt.Path)
+		}
+		result.Rebased = true
+	}
+	result.Drift, _ = git.DivergenceWithContext(ctx, wt.Path, ref, wt.Branch)
+
+	return p.Result(result, func(w io.Writer) {
+		message := fmt.Sprintf("Retargeted %s from %s to %s", name, previous, ref)
+		if result.Rebased {
+			message = fmt.Sprintf("Rebased %s from %s onto %s", name, previous, ref)
+		}
+		fprintln(w, styles.RenderSuccess(message))
+		if drift := renderTracking(result.Drift); drift != "" {
+			fprintln(w, styles.Muted.Render("Drift from "+ref+": "+drift))
+		}
+	})
+}
+
+// retargetUpstream checks that the worktree can be rebased and returns the
+// commit its branch's own commits start after: where it meets its base
+func retargetUpstream(ctx context.Context, wt git.Worktree, base string) (string, error) {
+	if git.RebaseInProgressWithContext(ctx, wt.Path) {
+		return "", fmt.Errorf("a rebase is in progress in %s\nFinish it with 'git rebase --continue' or 'git rebase --abort' first", wt.Path)
+	}
+	dirty, err := git.IsDirty(wt.Path)
+	if err != nil {
+		return "", err
+	}
+	if dirty {
+		return "", fmt.Errorf("%s has uncommitted changes\nCommit or stash them before rebasing", wt.Path)
+	}
+	return git.MergeBaseWithContext(ctx, wt.Path, base, wt.Branch)
+}
+
+func runBaseShow(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+	ctx := context.Background()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	name, err := stackTarget(info, args)
+	if err != nil {
+		return err
+	}
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	wt, err := stackedWorktree(worktrees, name)
+	if err != nil {
+		return err
+	}
+
+	recorded := loadRecordedWorktrees()
+	if parent := stackParents(recorded)[name]; parent != "" {
+		return fmt.Errorf("%s is stacked on %s\nRun 'koh stack status %s' to see how far it is behind", name, parent, name)
+	}
+	result, err := loadBase(ctx, recorded, wt, name)
+	if err != nil {
+		return err
+	}
+	return p.Result(result, func(w io.Writer) {
+		fprintln(w, renderBase(result))
+	})
+}
+
+// loadBase describes the base of the worktree wt called name
+func loadBase(ctx context.Context, recorded map[string]*state.Worktree, wt git.Worktree, name string) (baseResult, error) {
+	base, isDefault, err := worktreeBase(ctx, recorded, name)
+	if err != nil {
+		return baseResult{}, err
+	}
+	result := baseResult{Name: name, Base: base, Default: isDefault}
+	result.Drift, _ = git.DivergenceWithContext(ctx, wt.Path, base, wt.Branch)
+	return result, nil
+}
+
+// renderBase renders a worktree's base as a single line, e.g.
+// "feature → release/1.2 ↑2 ↓1"
+func renderBase(b baseResult) string {
+	line := styles.Key.Render(b.Name) + " → " + b.Base
+	if b.Default {
+		line += styles.Muted.Render(" (default branch)")
+	}
+	if b.Drift != nil {
+		drift := renderTracking(b.Drift)
+		if drift == "" {
+			drift = "up to date"
+		}
+		line += " " + styles.Muted.Render(drift)
+	}
+	return line
+}
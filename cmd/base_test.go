@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+)
+
+func TestWorktreeBase(t *testing.T) {
+	newDashboardRepo(t)
+	recorded := map[string]*state.Worktree{"retargeted": {Base: "release/1.2"}, "plain": {}}
+
+	tests := []struct {
+		name        string
+		want        string
+		wantDefault bool
+	}{
+		{name: "retargeted", want: "release/1.2"},
+		{name: "plain", want: "main", wantDefault: true},
+		{name: "unknown", want: "main", wantDefault: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isDefault, err := worktreeBase(context.Background(), recorded, tt.name)
+			if err != nil {
+				t.Fatalf("worktreeBase() failed: %v", err)
+			}
+			if got != tt.want || isDefault != tt.wantDefault {
+				t.Errorf("Expected %q (default %v), got %q (default %v)", tt.want, tt.wantDefault, got, isDefault)
+			}
+		})
+	}
+}
+
+func TestBaseSetRebase(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	featPath := filepath.Join(repo, ".koh", "feat-a")
+	commit := func(dir, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, strings.ReplaceAll(message, " ", "-")), []byte(message), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		runGit(t, "-C", dir, "add", ".")
+		runGit(t, "-C", dir, "commit", "-q", "-m", message)
+	}
+
+	runGit(t, "checkout", "-q", "-b", "develop")
+	commit(repo, "develop one")
+	runGit(t, "checkout", "-q", "-b", "release", "main")
+	commit(repo, "release one")
+	runGit(t, "-C", featPath, "reset", "-q", "--hard", "develop")
+	commit(featPath, "feature one")
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+	if err := recordBase(commonDir, "feat-a", "develop"); err != nil {
+		t.Fatalf("recordBase() failed: %v", err)
+	}
+
+	baseRebase = true
+	t.Cleanup(func() { baseRebase = false })
+	var out bytes.Buffer
+	baseSetCmd.SetOut(&out)
+	t.Cleanup(func() { baseSetCmd.SetOut(nil) })
+	if err := runBaseSet(baseSetCmd, []string{"feat-a", "release"}); err != nil {
+		t.Fatalf("runBaseSet() failed: %v", err)
+	}
+
+	log, err := exec.Command("git", "-C", featPath, "log", "--format=%s").Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	if got, want := strings.Fields(strings.ReplaceAll(string(log), " ", "_")), "feature_one release_one init"; strings.Join(got, " ") != want {
+		t.Errorf("Expected only feat-a's own commits on release (%s), got %v", want, got)
+	}
+	if base := loadRecordedWorktrees()["feat-a"].Base; base != "release" {
+		t.Errorf("Expected release to be recorded as the base, got %q", base)
+	}
+}
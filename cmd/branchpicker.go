@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// branchPickerHeight is how many branches the picker shows at once
+const branchPickerHeight = 12
+
+// branchPickerModel lets the user pick a branch, filtering as they type
+type branchPickerModel struct {
+	title    string
+	branches []git.Branch
+	filter   textinput.Model
+	// matches are the branches matching the filter, most recent first
+	matches  []git.Branch
+	cursor   int
+	now      time.Time
+	selected string
+	quitting bool
+}
+
+// newBranchPicker returns a picker over branches, most recent first
+func newBranchPicker(title string, branches []git.Branch) branchPickerModel {
+	filter := textinput.New()
+	filter.Placeholder = "type to filter"
+	filter.Prompt = "❯ "
+	filter.Focus()
+
+	return branchPickerModel{
+		title:    title,
+		branches: branches,
+		filter:   filter,
+		matches:  branches,
+		now:      time.Now(),
+	}
+}
+
+// pickBranch runs the picker on stderr, keeping stdout free for --json, and
+// returns the chosen branch or "" when the user cancelled
+func pickBranch(title string, branches []git.Branch) (string, error) {
+	if len(branches) == 0 {
+		return "", fmt.Errorf("no branches to pick from")
+	}
+
+	final, err := tea.NewProgram(newBranchPicker(title, branches), tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return "", fmt.Errorf("error running branch picker: %w", err)
+	}
+	return final.(branchPickerModel).selected, nil
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in s in order,
+// ignoring case, so "fl" matches "feature/login"
+func fuzzyMatch(pattern, s string) bool {
+	rest := []rune(strings.ToLower(s))
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := 0
+		for i < len(rest) && rest[i] != r {
+			i++
+		}
+		if i == len(rest) {
+			return false
+		}
+		rest = rest[i+1:]
+	}
+	return true
+}
+
+// filterBranches returns the branches whose name matches pattern
+func filterBranches(branches []git.Branch, pattern string) []git.Branch {
+	var matches []git.Branch
+	for _, b := range branches {
+		if fuzzyMatch(pattern, b.Name) {
+			matches = append(matches, b)
+		}
+	}
+	return matches
+}
+
+// commitAge describes how long ago a commit was made
+func commitAge(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch days := idleDays(t, now); {
+	case days < 1:
+		return "today"
+	case days == 1:
+		return "1 day ago"
+	default:
+		return fmt.Sprintf("%d days ago", days)
+	}
+}
+
+// Init initializes the bubbletea model
+func (m branchPickerModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles keyboard input and updates the model
+func (m branchPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.quitting = true
+			return m, tea.Quit
+		case "enter":
+			if m.cursor < len(m.matches) {
+				m.selected = m.matches[m.cursor].Name
+				m.quitting = true
+				return m, tea.Quit
+			}
+			return m, nil
+		case "up", "ctrl+p":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			return m, nil
+		case "down", "ctrl+n":
+			if m.cursor < len(m.matches)-1 {
+				m.cursor++
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.matches = filterBranches(m.branches, m.filter.Value())
+	m.cursor = min(m.cursor, max(len(m.matches)-1, 0))
+	return m, cmd
+}
+
+// View renders the UI
+func (m branchPickerModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	var s strings.Builder
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+" "+m.title) + "\n\n")
+	s.WriteString(m.filter.View() + "\n\n")
+
+	// Scroll so the cursor stays visible
+	start := max(m.cursor-branchPickerHeight+1, 0)
+	end := min(start+branchPickerHeight, len(m.matches))
+	for i := start; i < end; i++ {
+		b := m.matches[i]
+		cursor := "  "
+		name := b.Name
+		if i == m.cursor {
+			cursor = styles.Active.Render("▶ ")
+			name = styles.Active.Render(name)
+		} else if b.Remote {
+			name = styles.Muted.Render(name)
+		}
+		details := strings.TrimSpace(commitAge(b.CommittedAt, m.now) + "  " + b.Subject)
+		s.WriteString(fmt.Sprintf("%s%s %s  %s\n", cursor, styles.IconBranch, name, styles.Muted.Render(details)))
+	}
+	if len(m.matches) == 0 {
+		s.WriteString(styles.Muted.Render("  No matching branches") + "\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(styles.RenderHelp(fmt.Sprintf("%d/%d branches • ↑/↓: navigate • enter: pick • esc: cancel", len(m.matches), len(m.branches))))
+	s.WriteString("\n")
+	return s.String()
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "", s: "main", want: true},
+		{pattern: "main", s: "main", want: true},
+		{pattern: "fl", s: "feature/login", want: true},
+		{pattern: "FL", s: "feature/login", want: true},
+		{pattern: "o/m", s: "origin/main", want: true},
+		{pattern: "o m", s: "origin/main", want: true},
+		{pattern: "lf", s: "feature/login", want: false},
+		{pattern: "mainx", s: "main", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.s, func(t *testing.T) {
+			if got := fuzzyMatch(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("Expected fuzzyMatch(%q, %q) to be %v, got %v", tt.pattern, tt.s, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommitAge(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{name: "unknown", want: ""},
+		{name: "today", t: now.Add(-3 * time.Hour), want: "today"},
+		{name: "one day", t: now.Add(-30 * time.Hour), want: "1 day ago"},
+		{name: "days", t: now.Add(-5 * 24 * time.Hour), want: "5 days ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commitAge(tt.t, now); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBranchPickerModel(t *testing.T) {
+	branches := []git.Branch{
+		{Ref: git.Ref{Name: "feature/login"}},
+		{Ref: git.Ref{Name: "main"}},
+		{Ref: git.Ref{Name: "origin/main", Remote: true}},
+	}
+	update := func(m branchPickerModel, msg tea.KeyMsg) branchPickerModel {
+		updated, _ := m.Update(msg)
+		return updated.(branchPickerModel)
+	}
+	typeText := func(m branchPickerModel, text string) branchPickerModel {
+		return update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	}
+
+	t.Run("picks the branch under the cursor", func(t *testing.T) {
+		m := newBranchPicker("Start feature from", branches)
+		m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+		m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+		m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+		if m.cursor != 2 {
+			t.Fatalf("Expected cursor to stop at the last branch, got %d", m.cursor)
+		}
+		m = update(m, tea.KeyMsg{Type: tea.KeyUp})
+		m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+		if m.selected != "main" {
+			t.Errorf("Expected main to be picked, got %q", m.selected)
+		}
+	})
+
+	t.Run("filters as the user types", func(t *testing.T) {
+		m := newBranchPicker("Start feature from", branches)
+		m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+		m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+		m = typeText(m, "om")
+		if len(m.matches) != 1 || m.matches[0].Name != "origin/main" {
+			t.Fatalf("Expected only origin/main to match, got %v", m.matches)
+		}
+		if m.cursor != 0 {
+			t.Errorf("Expected cursor to move back onto the matches, got %d", m.cursor)
+		}
+		m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+		if m.selected != "origin/main" {
+			t.Errorf("Expected origin/main to be picked, got %q", m.selected)
+		}
+	})
+
+	t.Run("enter without matches does nothing", func(t *testing.T) {
+		m := newBranchPicker("Start feature from", branches)
+		m = typeText(m, "zzz")
+		m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+		if m.selected != "" || m.quitting {
+			t.Errorf("Expected nothing to be picked, got %q", m.selected)
+		}
+	})
+
+	t.Run("esc cancels", func(t *testing.T) {
+		m := newBranchPicker("Start feature from", branches)
+		m = update(m, tea.KeyMsg{Type: tea.KeyEsc})
+		if !m.quitting || m.selected != "" {
+			t.Errorf("Expected picker to quit without a branch, got %q", m.selected)
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var ciCmd = &cobra.Command{
+	Use:   "ci [worktree-name]",
+	Short: "Show the CI status of a worktree's branch",
+	Long: `Show the CI runs of a worktree's branch for the last commit CI ran on,
+using the GitHub CLI, and whether the branch is safe to merge or the
+worktree safe to clean up.
+
+A branch is safe to merge when every run passed on the commit the worktree
+is at; commits CI hasn't seen yet need pushing first. A worktree is safe to
+clean up once its pull request is merged.
+
+If no worktree name is provided and you're currently in a worktree, the
+current worktree is shown.
+
+With --watch, koh checks again every --interval until no run is pending,
+first waiting up to five minutes for CI to start on the worktree's commit.
+koh ci exits non-zero unless every run passed on that commit, which
+includes CI not having run on it, so 'koh ci --watch && koh cleanup' only
+cleans up after a green run.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runCI,
+}
+
+var (
+	// ciWatch waits until no run is pending
+	ciWatch bool
+	// ciInterval is how long --watch waits between checks
+	ciInterval time.Duration
+)
+
+func init() {
+	ciCmd.Flags().BoolVar(&ciWatch, "watch", false, "Wait until CI finishes")
+	ciCmd.Flags().DurationVar(&ciInterval, "interval", 15*time.Second, "How often --watch checks CI")
+	rootCmd.AddCommand(ciCmd)
+}
+
+// listRuns lists the CI runs of a branch; tests replace it
+var listRuns = forge.ListRuns
+
+// ciStartTimeout is how long --watch waits for CI to start on the
+// worktree's commit, which may never happen when it isn't pushed
+var ciStartTimeout = 5 * time.Minute
+
+// ciResult is the machine-readable result of 'koh ci'
+type ciResult struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	// State is the CI state of the runs, "" when CI never ran on the branch
+	State string      `json:"state"`
+	Runs  []forge.Run `json:"runs"`
+	// Current is set when the runs are for the commit the worktree is at
+	Current bool               `json:"current"`
+	PR      *forge.PullRequest `json:"pr,omitempty"`
+	// SafeToMerge is set when every run passed on the worktree's commit
+	SafeToMerge bool `json:"safe_to_merge"`
+	// SafeToCleanUp is set once the branch's pull request is merged
+	SafeToCleanUp bool `json:"safe_to_clean_up"`
+}
+
+// newCIResult summarizes the runs of a worktree's branch. head is the
+// commit the worktree is at.
+func newCIResult(name, branch, head string, runs []forge.Run, pr *forge.PullRequest) ciResult {
+	latest := forge.LatestRuns(runs)
+	if latest == nil {
+		latest = []forge.Run{}
+	}
+	result := ciResult{
+		Name:    name,
+		Branch:  branch,
+		State:   forge.RollupRuns(latest),
+		Runs:    latest,
+		Current: len(latest) > 0 && latest[0].HeadSHA == head,
+		PR:      pr,
+	}
+	result.SafeToMerge = result.State == forge.CIPassing && result.Current
+	result.SafeToCleanUp = pr != nil && pr.State == forge.StateMerged
+	return result
+}
+
+// ciVerdict explains in a sentence what the result means for merging and
+// cleaning up
+func ciVerdict(r ciResult) string {
+	switch {
+	case r.SafeToCleanUp:
+		return "The pull request is merged; safe to clean up"
+	case r.State == "":
+		return "CI hasn't run on this branch"
+	case !r.Current:
+		return "CI hasn't run on the latest commit; push it first"
+	case r.State == forge.CIFailing:
+		return "CI failed; not safe to merge"
+	case r.State == forge.CIPending:
+		return "CI is still running"
+	}
+	return "CI passed; safe to merge"
+}
+
+// renderCIResult renders the runs and the verdict
+func renderCIResult(r ciResult) string {
+	var content strings.Builder
+	content.WriteString(styles.RenderKeyValue("Branch", r.Branch) + "\n")
+	if r.PR != nil {
+		content.WriteString(styles.RenderKeyValue("Pull Request", renderPullRequest(r.PR)) + "\n")
+	}
+	for _, run := range r.Runs {
+		marker := styles.MarkerCIPassing
+		switch run.State {
+		case forge.CIFailing:
+			marker = styles.MarkerCIFailing
+		case forge.CIPending:
+			marker = styles.MarkerCIPending
+		}
+		content.WriteString(marker.RenderWith(run.Workflow) + "  " + styles.Muted.Render(run.URL) + "\n")
+	}
+	content.WriteString("\n" + ciVerdict(r))
+	return styles.RenderBox(content.String())
+}
+
+// checkCI looks up the CI state of a worktree
+func checkCI(ctx context.Context, wt git.Worktree) (ciResult, error) {
+	runs, err := listRuns(ctx, wt.Branch)
+	if err != nil {
+		return ciResult{}, err
+	}
+	pr := forge.ForBranch(loadPullRequests(ctx), wt.Branch)
+	return newCIResult(filepath.Base(wt.Path), wt.Branch, wt.Head, runs, pr), nil
+}
+
+// watchCI checks CI every ciInterval until no run is pending, reporting
+// each change of state. Until runs show up for the worktree's commit, it
+// waits for them for up to ciStartTimeout.
+func watchCI(ctx context.Context, p *output.Printer, wt git.Worktree) (ciResult, error) {
+	last := ""
+	startDeadline := time.Now().Add(ciStartTimeout)
+	for {
+		result, err := checkCI(ctx, wt)
+		if err != nil {
+			return result, err
+		}
+
+		var status string
+		switch {
+		case !result.Current:
+			if time.Now().After(startDeadline) {
+				return result, nil
+			}
+			status = "CI to start on " + shortCommit(wt.Head)
+		case result.State != forge.CIPending:
+			return result, nil
+		default:
+			var running []string
+			for _, run := range result.Runs {
+				if run.State == forge.CIPending {
+					running = append(running, run.Workflow)
+				}
+			}
+			status = strings.Join(running, ", ")
+		}
+		if status != last {
+			p.Info("Waiting for %s", status)
+			last = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("operation cancelled")
+		case <-time.After(ciInterval):
+		}
+	}
+}
+
+func runCI(cmd *cobra.Command, args []string) error {
+	var worktreeName string
+	if len(args) == 0 {
+		var err error
+		worktreeName, err = extractWorkTreeName()
+		if err != nil {
+			return fmt.Errorf("failed to extract worktree name: %w", err)
+		}
+	} else {
+		worktreeName = args[0]
+	}
+	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
+		return fmt.Errorf("invalid worktree name: %w", err)
+	}
+	if ciInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	var wt *git.Worktree
+	for i := range worktrees {
+		if filepath.Base(worktrees[i].Path) == worktreeName {
+			wt = &worktrees[i]
+		}
+	}
+	if wt == nil {
+		return fmt.Errorf("worktree %s does not exist", worktreeName)
+	}
+	if wt.Branch == "" {
+		return fmt.Errorf("worktree %s has no branch checked out", worktreeName)
+	}
+
+	p := newPrinter(cmd)
+	var result ciResult
+	if ciWatch {
+		result, err = watchCI(ctx, p, *wt)
+	} else {
+		result, err = checkCI(ctx, *wt)
+	}
+	if errors.Is(err, forge.ErrUnavailable) {
+		return fmt.Errorf("koh ci needs the GitHub CLI (gh)\nInstall it from https://cli.github.com and run 'gh auth login'")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.Result(result, func(w io.Writer) {
+		fprintln(w, renderCIResult(result))
+	}); err != nil {
+		return err
+	}
+
+	if !result.SafeToMerge {
+		// The result already shows what's missing
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return silentFailure(errors.New("ci checks are not safe to merge"))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestNewCIResult(t *testing.T) {
+	passed := []forge.Run{{Workflow: "test", State: forge.CIPassing, HeadSHA: "b2"}}
+	merged := &forge.PullRequest{Number: 3, State: forge.StateMerged}
+
+	tests := []struct {
+		name    string
+		head    string
+		runs    []forge.Run
+		pr      *forge.PullRequest
+		merge   bool
+		cleanUp bool
+		verdict string
+	}{
+		{name: "passed on head", head: "b2", runs: passed, merge: true, verdict: "CI passed; safe to merge"},
+		{name: "unpushed commit", head: "c3", runs: passed, verdict: "CI hasn't run on the latest commit; push it first"},
+		{name: "failed", head: "b2", runs: []forge.Run{{Workflow: "test", State: forge.CIFailing, HeadSHA: "b2"}}, verdict: "CI failed; not safe to merge"},
+		{name: "running", head: "b2", runs: []forge.Run{{Workflow: "test", State: forge.CIPending, HeadSHA: "b2"}}, verdict: "CI is still running"},
+		{name: "never ran", head: "b2", verdict: "CI hasn't run on this branch"},
+		{name: "merged", head: "b2", runs: passed, pr: merged, merge: true, cleanUp: true, verdict: "The pull request is merged; safe to clean up"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := newCIResult("feature", "feature", tt.head, tt.runs, tt.pr)
+			if result.SafeToMerge != tt.merge || result.SafeToCleanUp != tt.cleanUp {
+				t.Errorf("Expected safe to merge %v and clean up %v, got %v and %v", tt.merge, tt.cleanUp, result.SafeToMerge, result.SafeToCleanUp)
+			}
+			if got := ciVerdict(result); got != tt.verdict {
+				t.Errorf("Expected %q, got %q", tt.verdict, got)
+			}
+		})
+	}
+}
+
+func TestWatchCI(t *testing.T) {
+	states := []string{forge.CIPending, forge.CIPending, forge.CIPassing}
+	calls := 0
+	original, originalInterval := listRuns, ciInterval
+	listRuns = func(context.Context, string) ([]forge.Run, error) {
+		state := states[min(calls, len(states)-1)]
+		calls++
+		return []forge.Run{{Workflow: "test", State: state, HeadSHA: "b2"}}, nil
+	}
+	ciInterval = time.Millisecond
+	t.Cleanup(func() { listRuns, ciInterval = original, originalInterval })
+
+	p := output.New(io.Discard, output.Human)
+	result, err := watchCI(context.Background(), p, git.Worktree{Path: "/repo/.koh/feature", Branch: "feature", Head: "b2"})
+	if err != nil {
+		t.Fatalf("watchCI() failed: %v", err)
+	}
+	if calls != 3 || !result.SafeToMerge {
+		t.Errorf("Expected to stop at the passing run after 3 checks, got %d checks and %+v", calls, result)
+	}
+}
+
+func TestWatchCIWaitsForHead(t *testing.T) {
+	calls := 0
+	original, originalInterval, originalTimeout := listRuns, ciInterval, ciStartTimeout
+	listRuns = func(context.Context, string) ([]forge.Run, error) {
+		calls++
+		// CI picks up the pushed commit on the third check
+		if calls < 3 {
+			return []forge.Run{{Workflow: "test", State: forge.CIPassing, HeadSHA: "a1"}}, nil
+		}
+		return []forge.Run{{Workflow: "test", State: forge.CIPassing, HeadSHA: "b2"}}, nil
+	}
+	ciInterval = time.Millisecond
+	t.Cleanup(func() { listRuns, ciInterval, ciStartTimeout = original, originalInterval, originalTimeout })
+
+	p := output.New(io.Discard, output.Human)
+	wt := git.Worktree{Path: "/repo/.koh/feature", Branch: "feature", Head: "b2"}
+	result, err := watchCI(context.Background(), p, wt)
+	if err != nil {
+		t.Fatalf("watchCI() failed: %v", err)
+	}
+	if calls != 3 || !result.SafeToMerge {
+		t.Errorf("Expected to wait for the run on b2, got %d checks and %+v", calls, result)
+	}
+
+	// An unpushed commit is given up on
+	ciStartTimeout = 0
+	wt.Head = "c3"
+	if result, err = watchCI(context.Background(), p, wt); err != nil || result.Current || result.SafeToMerge {
+		t.Errorf("Expected to give up on c3 without a run, got %+v (%v)", result, err)
+	}
+}
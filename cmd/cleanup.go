@@ -1,14 +1,22 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/forge"
 	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
 	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
 	"github.com/bshakr/koh/internal/tmux"
 	"github.com/bshakr/koh/internal/validation"
 	"github.com/spf13/cobra"
@@ -20,15 +28,161 @@ var cleanupCmd = &cobra.Command{
 	Long: `Closes the associated tmux window and removes the git worktree.
 
 If no worktree name is provided and you're currently in a worktree,
-it will automatically clean up the current worktree.`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runCleanup,
+it will automatically clean up the current worktree.
+
+With --delete-remote-branch, the branch the worktree's branch tracks is also
+deleted from its remote after the worktree is removed; branches without an
+upstream are left alone. Set "cleanup.delete_remote_branch" in
+.kohconfig to make this the default. Branches matching
+"cleanup.protected_branches" (plus main and master) are never deleted, and
+neither are branches the forge protects from deletion (checked with the
+GitHub CLI when it is installed).
+
+Before the worktree is removed, the "cleanup_commands" in .kohconfig run in
+it, e.g. to drop its database. Their output is appended to cleanup.log in
+koh's log directory, and a failing command stops the cleanup unless --force
+is given.
+
+With --merged, every koh worktree whose branch has been merged into the
+default branch, or the branch given with --into (e.g. --into develop), is
+cleaned up instead. Git only recognizes regular and fast-forward merges;
+add --remote to also ask the forge (via the GitHub CLI) which pull requests
+were merged into it, which catches squash and rebase merges. Use --dry-run
+to see what would be removed, and --delete-branch to also delete the local
+branch of each cleaned up worktree.
+
+With --all, every koh worktree is cleaned up, e.g. at the end of a day of
+reviews. A worktree that fails to clean up doesn't stop the rest; koh
+reports which ones were removed and which failed, and exits non-zero if
+any failed.
+
+With --interactive, koh shows a checklist of the koh worktrees, marking
+the ones with uncommitted changes and the merged ones. Pick several with
+space (m picks every merged one), press enter and confirm to clean them
+up like --all.
+
+Removing a worktree with uncommitted changes discards them, so koh asks
+first, as it does before cleaning up several merged worktrees at once.
+--force (or the global --yes) proceeds without asking; without a terminal,
+or with --json, cleanup fails instead of asking.
+
+Before the window is closed, the keys in "cleanup.pre_close" are typed
+into the panes running the programs they name, e.g.
+{"pane": "editor", "keys": ":wqa"}, so editors can save and quit instead
+of leaving swap files behind. What they save counts as uncommitted changes.
+
+--events-json streams each step as a line of JSON, to stdout or to a file
+with --events-json=<file>, like 'koh new'.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runCleanup,
 }
 
+var (
+	// cleanupDeleteRemoteBranch deletes the worktree's branch from its remote
+	cleanupDeleteRemoteBranch bool
+	// cleanupMerged cleans up all worktrees whose branches have been merged
+	cleanupMerged bool
+	// cleanupDeleteBranch also deletes the local branches --merged cleans up
+	cleanupDeleteBranch bool
+	// cleanupRemote also treats branches with a merged pull request as merged
+	cleanupRemote bool
+	// cleanupInto is the branch --merged checks against, the default branch when empty
+	cleanupInto string
+	// cleanupAll cleans up every koh worktree
+	cleanupAll bool
+	// cleanupInteractive picks the worktrees to clean up from a checklist
+	cleanupInteractive bool
+	// cleanupDryRun lists what --merged or --all would remove without removing anything
+	cleanupDryRun bool
+	// cleanupEventsJSON is where progress events are streamed, "-" for stdout
+	cleanupEventsJSON string
+	// cleanupForce removes worktrees with uncommitted changes without asking
+	cleanupForce bool
+)
+
 func init() {
+	cleanupCmd.Flags().BoolVar(&cleanupDeleteRemoteBranch, "delete-remote-branch", false, "Delete the worktree's branch from its remote after removal")
+	cleanupCmd.Flags().BoolVar(&cleanupMerged, "merged", false, "Clean up all worktrees whose branches are merged into the default branch")
+	cleanupCmd.Flags().BoolVar(&cleanupRemote, "remote", false, "With --merged, also use pull request state from the forge (detects squash merges)")
+	cleanupCmd.Flags().BoolVar(&cleanupDeleteBranch, "delete-branch", false, "With --merged, also delete the local branch of each cleaned up worktree")
+	cleanupCmd.Flags().StringVar(&cleanupInto, "into", "", "With --merged, the branch to check against (default: the repository's default branch)")
+	cleanupCmd.Flags().BoolVar(&cleanupAll, "all", false, "Clean up every koh worktree of the repository")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "With --merged or --all, only list the worktrees that would be removed")
+	cleanupCmd.Flags().BoolVarP(&cleanupForce, "force", "f", false, "Remove worktrees even with uncommitted changes, without asking")
+	cleanupCmd.Flags().BoolVarP(&cleanupInteractive, "interactive", "i", false, "Pick the worktrees to clean up from a checklist")
+	cleanupCmd.MarkFlagsMutuallyExclusive("merged", "all", "interactive")
+	addEventsFlag(cleanupCmd, &cleanupEventsJSON)
 	rootCmd.AddCommand(cleanupCmd)
 }
 
+// loadCleanupConfig returns the cleanup defaults from .kohconfig, or nil
+// when no configuration exists
+func loadCleanupConfig() *config.Cleanup {
+	exists, err := config.ConfigExists()
+	if err != nil || !exists {
+		return nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil
+	}
+	return cfg.Cleanup
+}
+
+// worktreeBranch returns the branch checked out in the named koh worktree,
+// or an empty string when it can't be determined or HEAD is detached
+func worktreeBranch(ctx context.Context, worktreeName string) string {
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return ""
+	}
+	for _, wt := range worktrees {
+		if filepath.Base(wt.Path) == worktreeName {
+			return wt.Branch
+		}
+	}
+	return ""
+}
+
+// branchProtection looks up what the forge enforces on a branch; tests replace it
+var branchProtection = forge.GetBranchProtection
+
+// deleteRemoteBranch deletes the branch a worktree's branch tracks from its
+// remote unless there is no upstream, or the branch is protected in the
+// config or on the forge, which would reject the push. It reports whether the
+// branch was deleted.
+func deleteRemoteBranch(ctx context.Context, p *output.Printer, branch string, cleanupCfg *config.Cleanup) bool {
+	if branch == "" {
+		p.Warn("Could not determine the worktree's branch, skipping remote branch deletion")
+		return false
+	}
+	// Only the branch this one tracks is deleted: a local branch that was never
+	// pushed may share its name with someone else's branch on the remote
+	remote, remoteBranch, ok := git.BranchUpstreamWithContext(ctx, branch)
+	if !ok {
+		p.Info("Branch %s has no upstream, skipping remote branch deletion", branch)
+		return false
+	}
+	if cleanupCfg.IsProtectedBranch(remoteBranch) {
+		p.Warn("Branch %s is protected, skipping remote branch deletion", remoteBranch)
+		return false
+	}
+	// Without gh, or off GitHub, the push itself is the check
+	if protection, err := branchProtection(ctx, remoteBranch); err == nil && protection.NoDeletion {
+		p.Warn("Branch %s is protected on the forge and can't be deleted, skipping remote branch deletion", remoteBranch)
+		return false
+	}
+
+	p.Info("Deleting remote branch: %s/%s", remote, remoteBranch)
+	if err := git.DeleteRemoteBranchWithContext(ctx, remote, remoteBranch); err != nil {
+		p.Warn("Failed to delete remote branch: %v", err)
+		return false
+	}
+	p.Info("Remote branch deleted successfully")
+	return true
+}
+
 func extractWorkTreeName() (string, error) {
 	if !git.IsInWorktree() {
 		return "", fmt.Errorf("not in a worktree")
@@ -42,12 +196,87 @@ func extractWorkTreeName() (string, error) {
 	return filepath.Base(currentPath), nil
 }
 
-func runCleanup(_ *cobra.Command, args []string) error {
+// cleanupResult is the machine-readable result of 'koh cleanup'
+type cleanupResult struct {
+	Name            string `json:"name"`
+	Path            string `json:"path"`
+	WorktreeRemoved bool   `json:"worktree_removed"`
+	WindowClosed    bool   `json:"window_closed"`
+	Branch          string `json:"branch,omitempty"`
+	RemoteDeleted   bool   `json:"remote_branch_deleted"`
+	BranchDeleted   bool   `json:"branch_deleted"`
+	// MergedVia records why --merged selected the worktree ("git" or "pull_request")
+	MergedVia string `json:"merged_via,omitempty"`
+	// FailedCleanupCommands lists the cleanup_commands that failed under --force
+	FailedCleanupCommands []string `json:"failed_cleanup_commands,omitempty"`
+}
+
+// Reasons a worktree is considered merged
+const (
+	mergedViaGit         = "git"
+	mergedViaPullRequest = "pull_request"
+)
+
+func runCleanup(cmd *cobra.Command, args []string) error {
+	p, closeEvents, err := newEventsPrinter(cmd, cleanupEventsJSON)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+
+	if err := runCleanupWith(cmd, p, args); err != nil {
+		if !errors.Is(err, errSilentFailure) {
+			p.Fail(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// runCleanupWith runs 'koh cleanup' with the given printer
+func runCleanupWith(cmd *cobra.Command, p *output.Printer, args []string) error {
 	// Windows is not supported due to differences in process management
 	if runtime.GOOS == "windows" {
 		return fmt.Errorf("cleanup command is not supported on Windows")
 	}
 
+	unlock, err := lockRepo(p, commandLine(cmd.CommandPath(), args))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if cleanupMerged {
+		if len(args) > 0 {
+			return fmt.Errorf("--merged cannot be combined with a worktree name")
+		}
+		return runCleanupMerged(cmd, p)
+	}
+	if cleanupAll {
+		if len(args) > 0 {
+			return fmt.Errorf("--all cannot be combined with a worktree name")
+		}
+		if cleanupRemote || cleanupInto != "" || cleanupDeleteBranch {
+			return fmt.Errorf("--remote, --into and --delete-branch require --merged")
+		}
+		return runCleanupAll(cmd, p)
+	}
+	if cleanupInteractive {
+		if len(args) > 0 {
+			return fmt.Errorf("--interactive cannot be combined with a worktree name")
+		}
+		if cleanupRemote || cleanupInto != "" || cleanupDryRun || cleanupDeleteBranch {
+			return fmt.Errorf("--remote, --into, --dry-run and --delete-branch can't be used with --interactive")
+		}
+		return runCleanupInteractive(cmd, p)
+	}
+	if cleanupRemote || cleanupInto != "" || cleanupDryRun {
+		return fmt.Errorf("--remote, --into and --dry-run require --merged or --all")
+	}
+	if cleanupDeleteBranch {
+		return fmt.Errorf("--delete-branch requires --merged")
+	}
+
 	var worktreeName string
 
 	// If no argument provided, try to detect current worktree
@@ -57,7 +286,7 @@ func runCleanup(_ *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to extract worktree name: %w", err)
 		}
-		fmt.Printf("Detected current worktree: %s\n", worktreeName)
+		p.Info("Detected current worktree: %s", worktreeName)
 	} else {
 		worktreeName = args[0]
 	}
@@ -78,30 +307,112 @@ func runCleanup(_ *cobra.Command, args []string) error {
 	}
 
 	// Build worktree path
-	worktreePath := filepath.Join(mainRepoRoot, ".koh", worktreeName)
+	worktreePath := kohWorktreePath(mainRepoRoot, worktreeName)
+
+	// The main checkout is never cleaned up, including when it was auto-detected
+	guardPaths := []string{worktreePath}
+	if len(args) == 0 {
+		if currentPath, err := git.GetCurrentWorktreePath(); err == nil {
+			guardPaths = append(guardPaths, currentPath)
+		}
+	}
+	if err := guardMainCheckout(mainRepoRoot, worktreeName, guardPaths...); err != nil {
+		return err
+	}
+
+	cleanupCfg := loadCleanupConfig()
+	opts := cleanupOptions{cfg: cleanupCfg, deleteRemote: resolveDeleteRemote(cmd, cleanupCfg), force: cleanupForce, forceCommands: cleanupForce}
+	result, err := cleanupWorktree(ctx, p, mainRepoRoot, worktreeName, opts)
+	if err != nil {
+		return err
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		fprintln(w, "Cleanup complete!")
+	})
+}
+
+// resolveDeleteRemote decides whether remote branches are deleted: the
+// --delete-remote-branch flag wins over the configured default
+func resolveDeleteRemote(cmd *cobra.Command, cleanupCfg *config.Cleanup) bool {
+	if cmd.Flags().Changed("delete-remote-branch") {
+		return cleanupDeleteRemoteBranch
+	}
+	return cleanupCfg != nil && cleanupCfg.DeleteRemoteBranch
+}
+
+// cleanupOptions controls how cleanupWorktree removes a worktree
+type cleanupOptions struct {
+	// cfg holds the cleanup defaults from .kohconfig, nil without any
+	cfg *config.Cleanup
+	// deleteRemote deletes the worktree's branch from its remote
+	deleteRemote bool
+	// force removes a worktree with uncommitted changes without asking
+	force bool
+	// forceCommands removes a worktree even when its cleanup_commands fail
+	forceCommands bool
+	// deleteBranch deletes the worktree's local branch once it's removed
+	deleteBranch bool
+	// keepWindow leaves the worktree's tmux window open, for the caller to
+	// close with closeCleanedWindow once it's done printing
+	keepWindow bool
+}
+
+// cleanupWorktree removes a koh worktree, optionally deletes its remote
+// branch and closes its tmux window. Failures of individual steps are
+// reported as warnings and reflected in the result.
+func cleanupWorktree(ctx context.Context, p *output.Printer, mainRepoRoot, worktreeName string, opts cleanupOptions) (*cleanupResult, error) {
+	worktreePath := kohWorktreePath(mainRepoRoot, worktreeName)
+	label := worktreeLabel(mainRepoRoot, worktreeName)
 
 	// Check if worktree exists
 	worktreeExists := true
 	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
-		fmt.Printf("Warning: Worktree .koh/%s not found\n", worktreeName)
-		fmt.Println("Will attempt to clean up tmux window only")
+		p.Warn("Worktree %s not found", label)
+		p.Info("Will attempt to clean up tmux window only")
 		worktreeExists = false
 	}
 
+	// Removing the worktree discards its uncommitted changes
+	dirtyOp := destructiveOp{What: fmt.Sprintf("Remove %s and its uncommitted changes", label), ForceFlag: "--force"}
+	wasDirty := false
+	if worktreeExists {
+		if dirty, _ := git.IsDirty(worktreePath); dirty {
+			if err := confirmDestructive(p, dirtyOp, opts.force); err != nil {
+				return nil, err
+			}
+			wasDirty = true
+		}
+	}
+
+	// Let editors save and quit once the removal is settled, so no swap
+	// files are left behind and nothing is typed into them otherwise. What
+	// they save is asked about like any other uncommitted change.
+	if tmux.IsInTmux() {
+		runPreClose(ctx, p, worktreeName, opts.cfg.PreCloseKeys())
+		if worktreeExists && !wasDirty {
+			if dirty, _ := git.IsDirty(worktreePath); dirty {
+				if err := confirmDestructive(p, dirtyOp, opts.force); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	// Get current directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	// Check if we're running from within the worktree being cleaned up
 	currentPath, err := filepath.Abs(currentDir)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 	absWorktreePath, err := filepath.Abs(worktreePath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute worktree path: %w", err)
+		return nil, fmt.Errorf("failed to get absolute worktree path: %w", err)
 	}
 
 	// Use filepath.Rel for robust path comparison
@@ -110,41 +421,290 @@ func runCleanup(_ *cobra.Command, args []string) error {
 
 	// Step 1: If in target worktree, switch to parent directory
 	if isInTargetWorktree {
-		fmt.Println("Running from within target worktree, switching to parent repository...")
+		p.Info("Running from within target worktree, switching to parent repository...")
 		if err := os.Chdir(mainRepoRoot); err != nil {
-			return fmt.Errorf("failed to change to parent directory: %w", err)
+			return nil, fmt.Errorf("failed to change to parent directory: %w", err)
 		}
-		fmt.Printf("Changed directory to: %s\n", mainRepoRoot)
+		p.Info("Changed directory to: %s", mainRepoRoot)
 	}
 
-	// Step 2: Remove the git worktree
+	// Resolve the branch before the worktree (and its branch info) is gone
+	result := &cleanupResult{Name: worktreeName, Path: worktreePath}
 	if worktreeExists {
-		fmt.Printf("Removing git worktree: .koh/%s\n", worktreeName)
+		result.Branch = worktreeBranch(ctx, worktreeName)
+	}
+
+	// Step 2: Tear down what the worktree set up outside its directory,
+	// while the worktree is still there to run in
+	if worktreeExists {
+		p.Step("cleanup_commands")
+		failed, err := runCleanupCommands(ctx, p, worktreeName, worktreePath, result.Branch, opts.forceCommands)
+		if err != nil {
+			return nil, err
+		}
+		result.FailedCleanupCommands = failed
+	}
+
+	// Step 3: Remove the git worktree
+	if worktreeExists {
+		p.Step("remove_worktree")
+		p.Info("Removing git worktree: %s", label)
 		if err := git.RemoveWorktreeWithContext(ctx, worktreePath); err != nil {
-			fmt.Printf("Warning: Failed to remove worktree: %v\n", err)
+			p.Warn("Failed to remove worktree: %v", err)
 		} else {
-			fmt.Println("Worktree removed successfully")
+			p.Info("Worktree removed successfully")
+			result.WorktreeRemoved = true
+			forgetWorktree(worktreeName)
 		}
+		invalidateWorktreeCache()
 	}
 
-	// Step 3: Close tmux window (tmux will automatically switch to previous window)
-	if tmux.IsInTmux() {
-		repoName, err := git.GetRepoName()
-		if err != nil {
-			fmt.Printf("Warning: Failed to get repository name: %v\n", err)
-			repoName = ""
+	// The scratch directory and ports never outlive their worktree
+	if !worktreeExists || result.WorktreeRemoved {
+		if err := removeScratch(mainRepoRoot, worktreeName); err != nil {
+			p.Warn("%v", err)
 		}
+		if err := releasePorts(worktreePath); err != nil {
+			p.Warn("%v", err)
+		}
+	}
 
-		windowName := fmt.Sprintf("%s|%s", repoName, worktreeName)
-		if err := tmux.CloseWindow(windowName, worktreeName); err != nil {
-			fmt.Printf("Warning: %v\n", err)
+	// Step 3b: Delete the branch from its remote (before the window closes,
+	// since cleanup may be running inside that window)
+	if opts.deleteRemote && result.WorktreeRemoved {
+		p.Step("delete_remote_branch")
+		result.RemoteDeleted = deleteRemoteBranch(ctx, p, result.Branch, opts.cfg)
+	}
+
+	// Step 3c: Delete the local branch, after the remote one since that
+	// is found through the local branch's upstream
+	if opts.deleteBranch && result.WorktreeRemoved && result.Branch != "" {
+		p.Step("delete_branch")
+		if err := git.DeleteBranchWithContext(ctx, result.Branch); err != nil {
+			p.Warn("Failed to delete branch %s: %v", result.Branch, err)
 		} else {
-			fmt.Println("Tmux window closed (switched to previous window)")
+			p.Info("Deleted branch %s", result.Branch)
+			result.BranchDeleted = true
 		}
-	} else {
-		fmt.Println("Not in a tmux session, skipping tmux cleanup")
 	}
 
-	fmt.Println("Cleanup complete!")
-	return nil
+	// Step 4: Close tmux window (tmux will automatically switch to previous window)
+	if !opts.keepWindow {
+		p.Step("close_window")
+		result.WindowClosed = closeCleanedWindow(p, worktreeName)
+	}
+
+	return result, nil
+}
+
+// closeCleanedWindow closes the tmux window of a cleaned up worktree,
+// reporting whether it did. tmux switches to the previous window.
+func closeCleanedWindow(p *output.Printer, worktreeName string) bool {
+	if !tmux.IsInTmux() {
+		p.Info("Not in a tmux session, skipping tmux cleanup")
+		return false
+	}
+
+	repoName, err := git.GetRepoName()
+	if err != nil {
+		p.Warn("Failed to get repository name: %v", err)
+		repoName = ""
+	}
+	windowName := tmux.WindowName(repoName, worktreeName)
+	if err := tmux.CloseWindow(windowName, worktreeName); err != nil {
+		p.Warn("%v", err)
+		return false
+	}
+	p.Info("Tmux window closed (switched to previous window)")
+	return true
+}
+
+// mergedWorktree is a worktree selected by --merged
+type mergedWorktree struct {
+	worktree git.Worktree
+	via      string
+}
+
+// isMergeTarget reports whether branch is into, the branch --merged checks
+// against, which may be given as the remote-tracking branch branch tracks
+// (e.g. "upstream/main"), as found in upstreams
+func isMergeTarget(branch, into string, upstreams map[string]string) bool {
+	return branch == into || upstreams[branch] == into
+}
+
+// selectMergedWorktrees returns the worktrees whose branches are merged into
+// into, either according to git (merged) or, when prs is given, because the
+// branch's pull request was merged into it and the worktree holds no newer
+// commits. isAncestor reports whether one commit is reachable from another,
+// and upstreams maps local branches to the remote-tracking branches they
+// track. Detached worktrees, protected branches and into itself are never
+// selected.
+func selectMergedWorktrees(worktrees []git.Worktree, into string, merged map[string]bool, prs []forge.PullRequest, isAncestor func(ancestor, descendant string) bool, upstreams map[string]string, cleanupCfg *config.Cleanup) []mergedWorktree {
+	var selected []mergedWorktree
+	for _, wt := range worktrees {
+		if wt.Branch == "" || cleanupCfg.IsProtectedBranch(wt.Branch) || isMergeTarget(wt.Branch, into, upstreams) {
+			continue
+		}
+
+		if merged[wt.Branch] {
+			selected = append(selected, mergedWorktree{worktree: wt, via: mergedViaGit})
+			continue
+		}
+
+		pr := forge.ForBranch(prs, wt.Branch)
+		if pr == nil || pr.State != forge.StateMerged || pr.HeadSHA == "" {
+			continue
+		}
+		// A PR merged into another branch (e.g. a stacked PR) hasn't landed yet
+		if pr.Base != "" && !isMergeTarget(pr.Base, into, upstreams) {
+			continue
+		}
+		// Commits made after the PR was merged would be lost
+		if wt.Head == pr.HeadSHA || isAncestor(wt.Head, pr.HeadSHA) {
+			selected = append(selected, mergedWorktree{worktree: wt, via: mergedViaPullRequest})
+		}
+	}
+	return selected
+}
+
+// mergedCleanupOp describes cleaning up merged worktrees for confirmation,
+// pointing out the ones with uncommitted changes
+func mergedCleanupOp(mainRepoRoot string, targets []mergedWorktree) destructiveOp {
+	op := destructiveOp{What: fmt.Sprintf("Clean up %d merged worktree(s)", len(targets)), ForceFlag: "--force"}
+	for _, m := range targets {
+		item := fmt.Sprintf("%s (%s)", worktreeLabel(mainRepoRoot, filepath.Base(m.worktree.Path)), m.worktree.Branch)
+		if dirty, _ := git.IsDirty(m.worktree.Path); dirty {
+			item += ", uncommitted changes will be lost"
+		}
+		op.Items = append(op.Items, item)
+	}
+	return op
+}
+
+// resolveMergeTarget returns the branch --merged checks against: into when
+// given, which has to exist, and the default branch otherwise
+func resolveMergeTarget(ctx context.Context, into string) (string, error) {
+	if into == "" {
+		return git.GetDefaultBranchWithContext(ctx)
+	}
+	if !git.VerifyCommitWithContext(ctx, into) {
+		return "", fmt.Errorf("branch %q not found\nUse a local branch or a remote one such as origin/%s", into, into)
+	}
+	return into, nil
+}
+
+// runCleanupMerged cleans up every koh worktree whose branch has been merged
+func runCleanupMerged(cmd *cobra.Command, p *output.Printer) error {
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	mainRepoRoot, err := git.GetMainRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get main repository root: %w", err)
+	}
+
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	base, err := resolveMergeTarget(ctx, cleanupInto)
+	if err != nil {
+		return err
+	}
+	merged, err := git.MergedBranchesWithContext(ctx, base)
+	if err != nil {
+		return err
+	}
+
+	// Pull request state is always queried fresh since it decides what gets deleted
+	var prs []forge.PullRequest
+	if cleanupRemote {
+		if !forge.Available() {
+			return fmt.Errorf("--remote requires the GitHub CLI (gh)\nInstall it from https://cli.github.com/")
+		}
+		prs, err = forge.ListPullRequests(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	cleanupCfg := loadCleanupConfig()
+	isAncestor := func(ancestor, descendant string) bool {
+		return git.IsAncestorWithContext(ctx, ancestor, descendant)
+	}
+	// Without upstreams, into only matches branches by their local name
+	upstreams, _ := git.BranchUpstreamsWithContext(ctx)
+	selected := selectMergedWorktrees(worktrees, base, merged, prs, isAncestor, upstreams, cleanupCfg)
+
+	// The worktree we're running in goes last, and its window is closed once
+	// the summary is printed there
+	currentPath, _ := git.GetCurrentWorktreePath()
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[j].worktree.Path == currentPath && selected[i].worktree.Path != currentPath
+	})
+	current := ""
+	if n := len(selected); n > 0 && selected[n-1].worktree.Path == currentPath {
+		current = filepath.Base(currentPath)
+	}
+
+	// The main checkout is never cleaned up, and read-only worktrees only by name
+	recorded := loadRecordedWorktrees()
+	var targets []mergedWorktree
+	for _, m := range selected {
+		name := filepath.Base(m.worktree.Path)
+		if err := guardMainCheckout(mainRepoRoot, name, m.worktree.Path); err == nil && !keepReadonly(p, recorded, name) {
+			targets = append(targets, m)
+		}
+	}
+
+	results := []*cleanupResult{}
+	switch {
+	case cleanupDryRun:
+		for _, m := range targets {
+			results = append(results, &cleanupResult{Name: filepath.Base(m.worktree.Path), Path: m.worktree.Path, Branch: m.worktree.Branch, MergedVia: m.via})
+		}
+	case len(targets) > 0:
+		if err := confirmDestructive(p, mergedCleanupOp(mainRepoRoot, targets), cleanupForce); err != nil {
+			return err
+		}
+
+		// Consent covers the uncommitted changes listed in the confirmation
+		opts := cleanupOptions{cfg: cleanupCfg, deleteRemote: resolveDeleteRemote(cmd, cleanupCfg), deleteBranch: cleanupDeleteBranch, force: true, forceCommands: cleanupForce}
+		for _, m := range targets {
+			name := filepath.Base(m.worktree.Path)
+			p.Info("Cleaning up %s (branch %s merged into %s)", worktreeLabel(mainRepoRoot, name), m.worktree.Branch, base)
+			o := opts
+			o.keepWindow = name == current
+			result, err := cleanupWorktree(ctx, p, mainRepoRoot, name, o)
+			if err != nil {
+				return err
+			}
+			result.MergedVia = m.via
+			results = append(results, result)
+		}
+	}
+
+	err = p.Result(results, func(w io.Writer) {
+		if len(results) == 0 {
+			fprintln(w, styles.Muted.Render(fmt.Sprintf("No worktrees merged into %s found", base)))
+			return
+		}
+		if cleanupDryRun {
+			fprintln(w, "Would clean up:")
+			for _, r := range results {
+				fprintln(w, fmt.Sprintf("  %s %s", worktreeLabel(mainRepoRoot, r.Name), styles.Muted.Render(fmt.Sprintf("(%s, merged via %s)", r.Branch, r.MergedVia))))
+			}
+			return
+		}
+		fprintln(w, fmt.Sprintf("Cleaned up %d merged worktree(s)", len(results)))
+	})
+	if !cleanupDryRun {
+		for _, r := range results {
+			if r.Name == current {
+				closeCleanedWindow(p, current)
+			}
+		}
+	}
+	return err
 }
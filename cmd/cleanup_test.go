@@ -1,8 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+	"github.com/bshakr/koh/internal/tmux"
 	"github.com/bshakr/koh/internal/validation"
 )
 
@@ -83,3 +93,222 @@ func TestCleanupAutoDetection(t *testing.T) {
 	t.Log("Cleanup command supports auto-detection of current worktree")
 	t.Log("Run 'koh cleanup' from within a worktree to test this feature")
 }
+
+func TestSelectMergedWorktrees(t *testing.T) {
+	worktrees := []git.Worktree{
+		{Path: "/repo/.koh/merged", Branch: "merged", Head: "m1"},
+		{Path: "/repo/.koh/squashed", Branch: "squashed", Head: "s1"},
+		{Path: "/repo/.koh/reused", Branch: "reused", Head: "r2"},
+		{Path: "/repo/.koh/open", Branch: "open", Head: "o1"},
+		{Path: "/repo/.koh/detached", Head: "d1", Detached: true},
+		{Path: "/repo/.koh/main", Branch: "main", Head: "x1"},
+		{Path: "/repo/.koh/stacked", Branch: "stacked", Head: "t1"},
+	}
+	merged := map[string]bool{"merged": true, "main": true}
+	prs := []forge.PullRequest{
+		{Number: 4, Branch: "stacked", State: forge.StateMerged, HeadSHA: "t1", Base: "squashed"},
+		{Number: 3, Branch: "squashed", State: forge.StateMerged, HeadSHA: "s1", Base: "main"},
+		{Number: 2, Branch: "reused", State: forge.StateMerged, HeadSHA: "r1"},
+		{Number: 1, Branch: "open", State: forge.StateOpen, HeadSHA: "o1"},
+	}
+	// r2 was committed after the PR was merged, so it isn't reachable from r1
+	isAncestor := func(ancestor, descendant string) bool { return false }
+
+	upstreams := map[string]string{"main": "origin/main"}
+	selected := selectMergedWorktrees(worktrees, "origin/main", merged, prs, isAncestor, upstreams, nil)
+
+	got := map[string]string{}
+	for _, m := range selected {
+		got[m.worktree.Branch] = m.via
+	}
+	want := map[string]string{"merged": mergedViaGit, "squashed": mergedViaPullRequest}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for branch, via := range want {
+		if got[branch] != via {
+			t.Errorf("Expected %s merged via %q, got %q", branch, via, got[branch])
+		}
+	}
+
+	// Without PR data only git merges are detected
+	if gitOnly := selectMergedWorktrees(worktrees, "origin/main", merged, nil, isAncestor, upstreams, nil); len(gitOnly) != 1 {
+		t.Errorf("Expected 1 worktree without PR data, got %d", len(gitOnly))
+	}
+
+	// Into another branch, that branch's own worktree is kept and so are PRs
+	// merged elsewhere
+	got = map[string]string{}
+	for _, m := range selectMergedWorktrees(worktrees, "squashed", map[string]bool{"squashed": true}, prs, isAncestor, upstreams, nil) {
+		got[m.worktree.Branch] = m.via
+	}
+	if want := map[string]string{"stacked": mergedViaPullRequest}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// main tracking a remote other than origin is still recognized as the
+	// target, so neither it nor PRs merged into it are mistaken
+	got = map[string]string{}
+	forked := map[string]string{"main": "upstream/main"}
+	for _, m := range selectMergedWorktrees(worktrees, "upstream/main", merged, prs, isAncestor, forked, nil) {
+		got[m.worktree.Branch] = m.via
+	}
+	if want := map[string]string{"merged": mergedViaGit, "squashed": mergedViaPullRequest}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v into upstream/main, got %v", want, got)
+	}
+}
+
+func TestResolveMergeTarget(t *testing.T) {
+	newDashboardRepo(t)
+	runGit(t, "branch", "develop")
+	ctx := context.Background()
+
+	if got, err := resolveMergeTarget(ctx, "develop"); err != nil || got != "develop" {
+		t.Errorf("Expected develop, got %q (%v)", got, err)
+	}
+	if _, err := resolveMergeTarget(ctx, "nonexistent"); err == nil {
+		t.Error("Expected an error for a branch that doesn't exist")
+	}
+	if got, err := resolveMergeTarget(ctx, ""); err != nil || got == "" {
+		t.Errorf("Expected the default branch, got %q (%v)", got, err)
+	}
+}
+
+func TestCleanupWorktreeInTmux(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	repo := newDashboardRepo(t)
+	p := output.New(io.Discard, output.Human)
+	if _, err := switchToWorktree(p, "feat-a", true, false); err != nil {
+		t.Fatalf("switchToWorktree() failed: %v", err)
+	}
+
+	result, err := cleanupWorktree(context.Background(), p, repo, "feat-a", cleanupOptions{})
+	if err != nil {
+		t.Fatalf("cleanupWorktree() failed: %v", err)
+	}
+	if !result.WorktreeRemoved || !result.WindowClosed {
+		t.Errorf("Expected the worktree and its window to be removed, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".koh", "feat-a")); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree directory to be gone, got %v", err)
+	}
+	if windows := tm.Windows(t); len(windows) != 1 {
+		t.Errorf("Expected only the first window to be left, got %v", windows)
+	}
+	if exists, _ := tmux.WindowExists("feat-a"); exists {
+		t.Error("Expected the window to be closed")
+	}
+}
+
+func TestCleanupWorktreeKeepWindow(t *testing.T) {
+	testutil.NewTmux(t)
+	repo := newDashboardRepo(t)
+	p := output.New(io.Discard, output.Human)
+	if _, err := switchToWorktree(p, "feat-a", true, false); err != nil {
+		t.Fatalf("switchToWorktree() failed: %v", err)
+	}
+
+	result, err := cleanupWorktree(context.Background(), p, repo, "feat-a", cleanupOptions{keepWindow: true})
+	if err != nil {
+		t.Fatalf("cleanupWorktree() failed: %v", err)
+	}
+	if !result.WorktreeRemoved || result.WindowClosed {
+		t.Errorf("Expected the worktree to be removed and its window kept, got %+v", result)
+	}
+	if exists, _ := tmux.WindowExists("feat-a"); !exists {
+		t.Fatal("Expected the window to be left open")
+	}
+
+	if !closeCleanedWindow(p, "feat-a") {
+		t.Error("Expected closeCleanedWindow() to close the window")
+	}
+	if exists, _ := tmux.WindowExists("feat-a"); exists {
+		t.Error("Expected the window to be closed")
+	}
+}
+
+func TestRunCleanupMergedDeletesBranchAndKeepsCurrentWindow(t *testing.T) {
+	testutil.NewTmux(t)
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	if _, err := switchToWorktree(output.New(io.Discard, output.Human), "feat-a", true, false); err != nil {
+		t.Fatalf("switchToWorktree() failed: %v", err)
+	}
+	featPath := filepath.Join(repo, ".koh", "feat-a")
+	testutil.WriteFile(t, featPath, "feature.txt", "done")
+	testutil.Git(t, featPath, "add", ".")
+	testutil.Git(t, featPath, "commit", "-q", "-m", "feature")
+	testutil.Git(t, repo, "merge", "-q", "--ff-only", "feat-a")
+	t.Chdir(featPath)
+
+	cleanupInto, cleanupDeleteBranch, cleanupForce = "main", true, true
+	t.Cleanup(func() { cleanupInto, cleanupDeleteBranch, cleanupForce = "", false, false })
+
+	openAtSummary := false
+	p := output.New(windowProbe{window: "feat-a", open: &openAtSummary}, output.Human)
+	if err := runCleanupMerged(cleanupCmd, p); err != nil {
+		t.Fatalf("runCleanupMerged() failed: %v", err)
+	}
+	if !openAtSummary {
+		t.Error("Expected the current window to be open while the summary was printed")
+	}
+	if exists, _ := tmux.WindowExists("feat-a"); exists {
+		t.Error("Expected the current window to be closed after the summary")
+	}
+	if got := testutil.Git(t, repo, "branch", "--list", "feat-a"); got != "" {
+		t.Errorf("Expected the merged branch to be deleted, got %q", got)
+	}
+}
+
+func TestDeleteRemoteBranchProtectedOnForge(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	remote := t.TempDir()
+	testutil.Git(t, remote, "init", "-q", "--bare")
+	testutil.Git(t, repo, "remote", "add", "origin", remote)
+	testutil.Git(t, repo, "push", "-q", "origin", "main:guarded", "main:feature")
+	testutil.Git(t, repo, "fetch", "-q", "origin")
+	testutil.Git(t, repo, "branch", "-q", "--track", "guarded", "origin/guarded")
+	testutil.Git(t, repo, "branch", "-q", "--track", "feature", "origin/feature")
+
+	original := branchProtection
+	branchProtection = func(_ context.Context, branch string) (*forge.Protection, error) {
+		return &forge.Protection{Protected: branch == "guarded", NoDeletion: branch == "guarded"}, nil
+	}
+	t.Cleanup(func() { branchProtection = original })
+
+	p := output.New(io.Discard, output.Human)
+	ctx := context.Background()
+	if deleteRemoteBranch(ctx, p, "guarded", nil) {
+		t.Error("Expected a branch protected on the forge to be kept")
+	}
+	if !deleteRemoteBranch(ctx, p, "feature", nil) {
+		t.Error("Expected an unprotected branch to be deleted")
+	}
+	if got := testutil.Git(t, remote, "branch", "--format=%(refname:short)"); got != "guarded" {
+		t.Errorf("Expected only guarded to be left on the remote, got %q", got)
+	}
+}
+
+func TestDeleteRemoteBranchOnlyDeletesUpstream(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	remote := t.TempDir()
+	testutil.Git(t, remote, "init", "-q", "--bare")
+	testutil.Git(t, repo, "remote", "add", "origin", remote)
+	testutil.Git(t, repo, "push", "-q", "origin", "main:local-only", "main:theirs", "main:renamed")
+	testutil.Git(t, repo, "fetch", "-q", "origin")
+	// local-only was never pushed from here; mine tracks a branch of another name
+	testutil.Git(t, repo, "branch", "-q", "local-only")
+	testutil.Git(t, repo, "branch", "-q", "--track", "mine", "origin/renamed")
+
+	p := output.New(io.Discard, output.Human)
+	ctx := context.Background()
+	if deleteRemoteBranch(ctx, p, "local-only", nil) {
+		t.Error("Expected a branch without an upstream to be left alone on the remote")
+	}
+	if !deleteRemoteBranch(ctx, p, "mine", nil) {
+		t.Error("Expected the branch mine tracks to be deleted")
+	}
+	if got := testutil.Git(t, remote, "branch", "--format=%(refname:short)"); got != "local-only\ntheirs" {
+		t.Errorf("Expected only the tracked branch to be deleted, got %q", got)
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+// cleanupFailure is a worktree 'koh cleanup --all' failed to clean up
+type cleanupFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// cleanupAllResult is the machine-readable result of 'koh cleanup --all'
+type cleanupAllResult struct {
+	Removed []*cleanupResult `json:"removed"`
+	Failed  []cleanupFailure `json:"failed"`
+	// DryRun is set when nothing was removed because of --dry-run
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// allCleanupOp describes cleaning up every koh worktree for confirmation,
+// pointing out the ones with uncommitted changes
+func allCleanupOp(mainRepoRoot string, worktrees []git.Worktree) destructiveOp {
+	op := destructiveOp{What: fmt.Sprintf("Clean up all %d koh worktree(s)", len(worktrees)), ForceFlag: "--force"}
+	for _, wt := range worktrees {
+		item := fmt.Sprintf("%s (%s)", worktreeLabel(mainRepoRoot, filepath.Base(wt.Path)), displayBranch(wt))
+		if dirty, _ := git.IsDirty(wt.Path); dirty {
+			item += ", uncommitted changes will be lost"
+		}
+		op.Items = append(op.Items, item)
+	}
+	return op
+}
+
+// cleanupWorktrees cleans up each worktree in turn. A failure is reported
+// and recorded, and the remaining worktrees are still cleaned up.
+func cleanupWorktrees(p *output.Printer, worktrees []git.Worktree, cleanup func(name string) (*cleanupResult, error)) cleanupAllResult {
+	results := cleanupAllResult{Removed: []*cleanupResult{}, Failed: []cleanupFailure{}}
+	for i, wt := range worktrees {
+		name := filepath.Base(wt.Path)
+		p.ForWorktree(name)
+		p.Info("Cleaning up %s (%d/%d)", name, i+1, len(worktrees))
+
+		result, err := cleanup(name)
+		if err != nil {
+			p.Fail(err)
+			p.Warn("Failed to clean up %s: %v", name, err)
+			results.Failed = append(results.Failed, cleanupFailure{Name: name, Error: err.Error()})
+			continue
+		}
+		results.Removed = append(results.Removed, result)
+	}
+	p.ForWorktree("")
+	return results
+}
+
+// runCleanupAll cleans up every koh worktree of the repository
+func runCleanupAll(cmd *cobra.Command, p *output.Printer) error {
+	ctx, cancel := signals.SetupCancellableContext()
+	defer cancel()
+
+	mainRepoRoot, err := git.GetMainRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get main repository root: %w", err)
+	}
+
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The main checkout is never cleaned up, and read-only worktrees only by name
+	recorded := loadRecordedWorktrees()
+	var targets []git.Worktree
+	for _, wt := range worktrees {
+		name := filepath.Base(wt.Path)
+		if err := guardMainCheckout(mainRepoRoot, name, wt.Path); err == nil && !keepReadonly(p, recorded, name) {
+			targets = append(targets, wt)
+		}
+	}
+
+	current := currentLast(targets)
+
+	if len(targets) == 0 || cleanupDryRun {
+		results := cleanupAllResult{Removed: []*cleanupResult{}, Failed: []cleanupFailure{}, DryRun: cleanupDryRun}
+		for _, wt := range targets {
+			results.Removed = append(results.Removed, &cleanupResult{Name: filepath.Base(wt.Path), Path: wt.Path, Branch: wt.Branch})
+		}
+		return p.Result(results, func(w io.Writer) {
+			if len(targets) == 0 {
+				fprintln(w, styles.Muted.Render("No koh worktrees to clean up"))
+				return
+			}
+			fprintln(w, "Would clean up:")
+			for _, r := range results.Removed {
+				fprintln(w, fmt.Sprintf("  %s %s", worktreeLabel(mainRepoRoot, r.Name), styles.Muted.Render(r.Branch)))
+			}
+		})
+	}
+
+	if err := confirmDestructive(p, allCleanupOp(mainRepoRoot, targets), cleanupForce); err != nil {
+		return err
+	}
+
+	// Consent covers the uncommitted changes listed in the confirmation
+	cleanupCfg := loadCleanupConfig()
+	opts := cleanupOptions{cfg: cleanupCfg, deleteRemote: resolveDeleteRemote(cmd, cleanupCfg), force: true, forceCommands: cleanupForce}
+	return cleanupAndReport(ctx, cmd, p, mainRepoRoot, targets, current, opts)
+}
+
+// currentLast moves the worktree we're running in to the end of worktrees,
+// since closing its window may end this process, and returns its name, or
+// "" when it isn't one of them
+func currentLast(worktrees []git.Worktree) string {
+	currentPath, _ := git.GetCurrentWorktreePath()
+	sort.SliceStable(worktrees, func(i, j int) bool {
+		return worktrees[j].Path == currentPath && worktrees[i].Path != currentPath
+	})
+	if n := len(worktrees); n > 0 && worktrees[n-1].Path == currentPath {
+		return filepath.Base(currentPath)
+	}
+	return ""
+}
+
+// cleanupAndReport cleans up targets with opts and prints the summary. The
+// window of current, the worktree we're running in, is kept open until the
+// summary is printed there.
+func cleanupAndReport(ctx context.Context, cmd *cobra.Command, p *output.Printer, mainRepoRoot string, targets []git.Worktree, current string, opts cleanupOptions) error {
+	results := cleanupWorktrees(p, targets, func(name string) (*cleanupResult, error) {
+		o := opts
+		o.keepWindow = name == current
+		return cleanupWorktree(ctx, p, mainRepoRoot, name, o)
+	})
+	err := reportCleanupResults(cmd, p, results, len(targets))
+	for _, r := range results.Removed {
+		if r.Name == current {
+			closeCleanedWindow(p, current)
+		}
+	}
+	return err
+}
+
+// reportCleanupResults prints which of total worktrees were cleaned up and
+// which failed, returning errSilentFailure if any failed
+func reportCleanupResults(cmd *cobra.Command, p *output.Printer, results cleanupAllResult, total int) error {
+	if err := p.Result(results, func(w io.Writer) {
+		fprintln(w)
+		for _, r := range results.Removed {
+			fprintln(w, styles.SuccessMessage.Render(styles.IconCheck+" "+r.Name)+" "+styles.Muted.Render(r.Branch))
+		}
+		for _, f := range results.Failed {
+			fprintln(w, styles.ErrorMessage.Render(styles.IconCross+" "+f.Name)+" "+styles.Muted.Render(f.Error))
+		}
+		fprintln(w)
+		if len(results.Failed) == 0 {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Cleaned up %d worktree(s)", total)))
+		} else {
+			fprintln(w, styles.RenderError(fmt.Sprintf("Failed to clean up %d of %d worktrees", len(results.Failed), total)))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if len(results.Failed) > 0 {
+		// The summary already reports the failures
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return silentFailure(errors.New(results.Failed[0].Error))
+	}
+	return nil
+}
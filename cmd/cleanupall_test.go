@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+func TestCleanupWorktrees(t *testing.T) {
+	p := output.New(io.Discard, output.Human)
+	worktrees := []git.Worktree{{Path: "/repo/.koh/a"}, {Path: "/repo/.koh/b"}, {Path: "/repo/.koh/c"}}
+
+	var attempted []string
+	results := cleanupWorktrees(p, worktrees, func(name string) (*cleanupResult, error) {
+		attempted = append(attempted, name)
+		if name == "b" {
+			return nil, errors.New("boom")
+		}
+		return &cleanupResult{Name: name, WorktreeRemoved: true}, nil
+	})
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(attempted, want) {
+		t.Errorf("Expected every worktree to be attempted (%v), got %v", want, attempted)
+	}
+	if len(results.Removed) != 2 || results.Removed[0].Name != "a" || results.Removed[1].Name != "c" {
+		t.Errorf("Expected a and c to be removed, got %+v", results.Removed)
+	}
+	if want := []cleanupFailure{{Name: "b", Error: "boom"}}; !reflect.DeepEqual(results.Failed, want) {
+		t.Errorf("Expected %v, got %v", want, results.Failed)
+	}
+}
+
+func TestCleanupWorktreesRemovesAll(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	runGit(t, "worktree", "add", "-q", "-b", "feat-b", filepath.Join(".koh", "feat-b"))
+	ctx := context.Background()
+	p := output.New(io.Discard, output.Human)
+
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("loadKohWorktrees() failed: %v", err)
+	}
+	op := allCleanupOp(repo, worktrees)
+	if len(op.Items) != 2 {
+		t.Errorf("Expected both worktrees listed for confirmation, got %v", op.Items)
+	}
+
+	results := cleanupWorktrees(p, worktrees, func(name string) (*cleanupResult, error) {
+		return cleanupWorktree(ctx, p, repo, name, cleanupOptions{force: true})
+	})
+	if len(results.Removed) != 2 || len(results.Failed) != 0 {
+		t.Fatalf("Expected both worktrees removed, got %+v", results)
+	}
+	for _, name := range []string{"feat-a", "feat-b"} {
+		if _, err := os.Stat(filepath.Join(repo, ".koh", name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone, got %v", name, err)
+		}
+	}
+}
+
+// windowProbe records whether a tmux window was open when the summary was
+// written
+type windowProbe struct {
+	window string
+	open   *bool
+}
+
+func (w windowProbe) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), "Cleaned up") {
+		*w.open, _ = tmux.WindowExists(w.window)
+	}
+	return len(b), nil
+}
+
+func TestCleanupAndReportClosesCurrentWindowLast(t *testing.T) {
+	testutil.NewTmux(t)
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	runGit(t, "worktree", "add", "-q", "-b", "feat-b", filepath.Join(".koh", "feat-b"))
+	quiet := output.New(io.Discard, output.Human)
+	for _, name := range []string{"feat-a", "feat-b"} {
+		if _, err := switchToWorktree(quiet, name, true, false); err != nil {
+			t.Fatalf("switchToWorktree() failed: %v", err)
+		}
+	}
+	t.Chdir(filepath.Join(repo, ".koh", "feat-a"))
+
+	worktrees, err := loadKohWorktrees(context.Background())
+	if err != nil {
+		t.Fatalf("loadKohWorktrees() failed: %v", err)
+	}
+	current := currentLast(worktrees)
+	if current != "feat-a" || filepath.Base(worktrees[len(worktrees)-1].Path) != "feat-a" {
+		t.Fatalf("Expected feat-a to be current and last, got %q", current)
+	}
+
+	openAtSummary := false
+	p := output.New(windowProbe{window: "feat-a", open: &openAtSummary}, output.Human)
+	if err := cleanupAndReport(context.Background(), &cobra.Command{}, p, repo, worktrees, current, cleanupOptions{force: true}); err != nil {
+		t.Fatalf("cleanupAndReport() failed: %v", err)
+	}
+	if !openAtSummary {
+		t.Error("Expected the current window to be open while the summary was printed")
+	}
+	if exists, _ := tmux.WindowExists("feat-a"); exists {
+		t.Error("Expected the current window to be closed after the summary")
+	}
+}
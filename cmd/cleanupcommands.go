@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/paths"
+	"github.com/bshakr/koh/internal/ports"
+	"github.com/bshakr/koh/internal/state"
+)
+
+// cleanupLogName is the file in koh's log directory that cleanup_commands
+// write their output to
+const cleanupLogName = "cleanup.log"
+
+// loadCleanupCommands returns the cleanup_commands configured for a
+// worktree's profile along with the environment they run with
+func loadCleanupCommands(name, path string) ([]string, []string, error) {
+	exists, err := config.ConfigExists()
+	if err != nil || !exists {
+		return nil, nil, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cfg.CleanupCommands) == 0 {
+		return nil, nil, nil
+	}
+	profileCfg, err := worktreeProfileConfig(cfg, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var env []string
+	if r, err := state.LoadPorts(); err == nil {
+		env = ports.Env(r.Reserved[path])
+	}
+	return cfg.CleanupCommands, append(env, configEnv(profileCfg, name, path, env)...), nil
+}
+
+// openCleanupLog opens the cleanup log for appending
+func openCleanupLog() (*os.File, error) {
+	logDir, err := paths.LogDir()
+	if err != nil {
+		return nil, err
+	}
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFile := filepath.Join(logDir, cleanupLogName)
+	//nolint:gosec // G304: the log file lives in koh's own log directory
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", logFile, err)
+	}
+	return log, nil
+}
+
+// runCleanupCommands runs the cleanup_commands in a worktree before it is
+// removed, in order, and returns the ones that failed. Their output goes to
+// the cleanup log as well as stderr. A failing command stops the cleanup so
+// whatever it was meant to tear down isn't forgotten, unless force is set,
+// in which case the remaining commands still run.
+func runCleanupCommands(ctx context.Context, p *output.Printer, name, path, branch string, force bool) ([]string, error) {
+	commands, env, err := loadCleanupCommands(name, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	log, err := openCleanupLog()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = log.Close() }()
+
+	var failed []string
+	for _, command := range commands {
+		// The worktree is passed in the environment rather than pasted into
+		// the command, so a branch name can't inject shell syntax
+		p.Info("Running cleanup command: %s", command)
+		_, _ = fmt.Fprintf(log, "==> %s %s: %s\n", time.Now().Format(time.RFC3339), name, command)
+		out := io.MultiWriter(log, p.CommandOutput())
+		c := execCommand(ctx, []string{command})
+		c.Dir = path
+		c.Env = append(append(os.Environ(), env...), "KOH_WORKTREE="+name, "KOH_WORKTREE_PATH="+path, "KOH_BRANCH="+branch)
+		c.Stdout = out
+		c.Stderr = out
+		err := c.Run()
+		if err == nil {
+			continue
+		}
+
+		if !force {
+			return failed, fmt.Errorf("cleanup command %q failed: %w\nSee %s for its output, or pass --force to remove the worktree anyway", command, err, log.Name())
+		}
+		p.Warn("Cleanup command %q failed: %v", command, err)
+		failed = append(failed, command)
+	}
+	return failed, nil
+}
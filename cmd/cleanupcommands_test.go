@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestCleanupCommands(t *testing.T) {
+	tests := []struct {
+		name         string
+		force        bool
+		wantErr      bool
+		wantRemoved  bool
+		wantFailed   int
+		wantTornDown bool
+	}{
+		{name: "failure blocks removal", wantErr: true},
+		{name: "forced", force: true, wantRemoved: true, wantFailed: 1, wantTornDown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newDashboardRepo(t)
+			t.Setenv("KOH_STATE_DIR", t.TempDir())
+			logDir := t.TempDir()
+			t.Setenv("KOH_LOG_DIR", logDir)
+			marker := filepath.Join(t.TempDir(), "torn-down")
+			testutil.WriteFile(t, repo, ".kohconfig", `{"cleanup_commands": ["echo dropping app_$KOH_WORKTREE; exit 3", "echo \"$KOH_BRANCH\" \"$KOH_WORKTREE\" > `+marker+`"]}`)
+
+			p := output.New(io.Discard, output.Human)
+			result, err := cleanupWorktree(context.Background(), p, repo, "feat-a", cleanupOptions{forceCommands: tt.force})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected the failing command to stop the cleanup")
+				}
+				if _, err := os.Stat(filepath.Join(repo, ".koh", "feat-a")); err != nil {
+					t.Errorf("Expected the worktree to be kept, got %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("cleanupWorktree() failed: %v", err)
+				}
+				if result.WorktreeRemoved != tt.wantRemoved || len(result.FailedCleanupCommands) != tt.wantFailed {
+					t.Errorf("Expected removed %v with %d failed commands, got %+v", tt.wantRemoved, tt.wantFailed, result)
+				}
+			}
+
+			data, _ := os.ReadFile(marker)
+			if tornDown := strings.TrimSpace(string(data)) == "feat-a feat-a"; tornDown != tt.wantTornDown {
+				t.Errorf("Expected the later command to run: %v, got output %q", tt.wantTornDown, data)
+			}
+			log, err := os.ReadFile(filepath.Join(logDir, cleanupLogName))
+			if err != nil {
+				t.Fatalf("Failed to read the cleanup log: %v", err)
+			}
+			if !strings.Contains(string(log), "dropping app_feat-a") {
+				t.Errorf("Expected the command's output in the cleanup log, got %q", log)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// cleanupPickerItem is a worktree in the checklist of 'koh cleanup
+// --interactive'
+type cleanupPickerItem struct {
+	worktreeItem
+	dirty  bool
+	merged bool
+}
+
+// pickable reports whether the checklist lets the worktree be picked.
+// Read-only worktrees are only cleaned up by name.
+func (item cleanupPickerItem) pickable() bool {
+	return !item.readonly
+}
+
+// cleanupPickerModel is the bubbletea model for picking several worktrees
+// to clean up, then confirming their removal
+type cleanupPickerModel struct {
+	items  []cleanupPickerItem
+	cursor int
+	picked map[int]bool
+	// confirming is set while the picked worktrees wait for confirmation
+	confirming bool
+	confirmed  bool
+	quitting   bool
+
+	// statusMessage explains why a key did nothing
+	statusMessage string
+}
+
+// newCleanupPicker returns a checklist over items with nothing picked
+func newCleanupPicker(items []cleanupPickerItem) cleanupPickerModel {
+	return cleanupPickerModel{items: items, picked: map[int]bool{}}
+}
+
+// pickedItems returns the picked worktrees in list order
+func (m cleanupPickerModel) pickedItems() []cleanupPickerItem {
+	var picked []cleanupPickerItem
+	for i, item := range m.items {
+		if m.picked[i] {
+			picked = append(picked, item)
+		}
+	}
+	return picked
+}
+
+// Init initializes the bubbletea model
+func (m cleanupPickerModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles keyboard input and updates the model
+func (m cleanupPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if key.String() == "ctrl+c" {
+		m.quitting = true
+		return m, tea.Quit
+	}
+
+	if m.confirming {
+		switch key.String() {
+		case "y", "enter":
+			m.confirmed = true
+			m.quitting = true
+			return m, tea.Quit
+		case "n", "q", "esc":
+			m.confirming = false
+		}
+		return m, nil
+	}
+
+	m.statusMessage = ""
+	switch key.String() {
+	case "q", "esc":
+		m.quitting = true
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	case "g", "home":
+		m.cursor = 0
+	case "G", "end":
+		m.cursor = max(len(m.items)-1, 0)
+	case " ", "x":
+		if m.cursor < len(m.items) {
+			if item := m.items[m.cursor]; !item.pickable() {
+				m.statusMessage = styles.Muted.Render(fmt.Sprintf("%s is read-only; run 'koh cleanup %s' to clean it up", item.name, item.name))
+				return m, nil
+			}
+			m.picked[m.cursor] = !m.picked[m.cursor]
+		}
+	case "m":
+		// Pick every merged worktree, leaving the others as they are
+		for i, item := range m.items {
+			if item.merged && item.pickable() {
+				m.picked[i] = true
+			}
+		}
+	case "a":
+		// Pick everything, or nothing when everything is already picked
+		var pickable []int
+		for i, item := range m.items {
+			if item.pickable() {
+				pickable = append(pickable, i)
+			}
+		}
+		all := len(m.pickedItems()) < len(pickable)
+		for _, i := range pickable {
+			m.picked[i] = all
+		}
+	case "enter":
+		if len(m.pickedItems()) == 0 {
+			m.statusMessage = styles.Muted.Render("Pick worktrees with space first")
+			return m, nil
+		}
+		m.confirming = true
+	}
+	return m, nil
+}
+
+// View renders the UI
+func (m cleanupPickerModel) View() string {
+	if m.quitting {
+		return ""
+	}
+	if m.confirming {
+		return m.viewConfirm()
+	}
+
+	var s strings.Builder
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+" Clean up worktrees") + "\n\n")
+
+	for i, item := range m.items {
+		cursor := "  "
+		name := item.name
+		if i == m.cursor {
+			cursor = styles.Active.Render("▶ ")
+			name = styles.Active.Render(name)
+		}
+		check := styles.Muted.Render("[ ]")
+		if m.picked[i] {
+			check = styles.SuccessMessage.Render("[" + styles.IconCheck + "]")
+		}
+
+		line := fmt.Sprintf("%s%s %s %s", cursor, check, name, styles.MarkerBranch.RenderWith(item.branch))
+		if item.dirty {
+			line += " " + styles.MarkerDirty.Render()
+		}
+		if item.merged {
+			line += " " + styles.MarkerMerged.Render()
+		}
+		if item.readonly {
+			line += " " + styles.MarkerReadonly.Render()
+		}
+		if item.isCurrent {
+			line += " " + styles.MarkerHere.Render()
+		}
+		s.WriteString(line + "\n")
+	}
+
+	if m.statusMessage != "" {
+		s.WriteString("\n" + m.statusMessage + "\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(styles.RenderHelp(fmt.Sprintf("%d/%d picked • ↑/↓ or j/k: navigate • space: pick • m: pick merged • a: pick all • enter: clean up • q: quit", len(m.pickedItems()), len(m.items))))
+	s.WriteString("\n")
+	return s.String()
+}
+
+// viewConfirm renders the picked worktrees for confirmation, pointing out
+// the ones whose uncommitted changes would be lost
+func (m cleanupPickerModel) viewConfirm() string {
+	picked := m.pickedItems()
+
+	var s strings.Builder
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+fmt.Sprintf(" Clean up %d worktree(s)?", len(picked))) + "\n\n")
+	for _, item := range picked {
+		line := fmt.Sprintf("  %s %s", item.name, styles.MarkerBranch.RenderWith(item.branch))
+		if item.dirty {
+			line += " " + styles.WarningMessage.Render("uncommitted changes will be lost")
+		}
+		s.WriteString(line + "\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(styles.RenderHelp("y/enter: clean up • n/esc: back"))
+	s.WriteString("\n")
+	return s.String()
+}
+
+// loadCleanupPickerItems returns the koh worktrees to offer for cleanup,
+// marking the dirty and read-only ones and those merged into the default
+// branch. Merges are also taken from pull requests when gh is available. The
+// worktree we're running in goes last, since closing its window may end this
+// process.
+func loadCleanupPickerItems(ctx context.Context, mainRepoRoot string) ([]cleanupPickerItem, error) {
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The main checkout is never cleaned up
+	var targets []git.Worktree
+	for _, wt := range worktrees {
+		if err := guardMainCheckout(mainRepoRoot, filepath.Base(wt.Path), wt.Path); err == nil {
+			targets = append(targets, wt)
+		}
+	}
+
+	mergedPaths := map[string]bool{}
+	if base, err := git.GetDefaultBranchWithContext(ctx); err == nil {
+		if merged, err := git.MergedBranchesWithContext(ctx, base); err == nil {
+			isAncestor := func(ancestor, descendant string) bool {
+				return git.IsAncestorWithContext(ctx, ancestor, descendant)
+			}
+			upstreams, _ := git.BranchUpstreamsWithContext(ctx)
+			for _, m := range selectMergedWorktrees(targets, base, merged, loadPullRequests(ctx), isAncestor, upstreams, loadCleanupConfig()) {
+				mergedPaths[m.worktree.Path] = true
+			}
+		}
+	}
+
+	dirty := dirtyWorktrees(targets)
+	recorded := loadRecordedWorktrees()
+	currentPath, _ := git.GetCurrentWorktreePath()
+	items := make([]cleanupPickerItem, 0, len(targets))
+	for _, wt := range targets {
+		name := filepath.Base(wt.Path)
+		items = append(items, cleanupPickerItem{
+			worktreeItem: worktreeItem{
+				name:      name,
+				branch:    displayBranch(wt),
+				path:      wt.Path,
+				isCurrent: currentPath != "" && wt.Path == currentPath,
+				readonly:  recorded[name] != nil && recorded[name].Readonly,
+			},
+			dirty:  dirty[wt.Path],
+			merged: mergedPaths[wt.Path],
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[j].isCurrent && !items[i].isCurrent
+	})
+	return items, nil
+}
+
+// runCleanupInteractive lets the user pick the worktrees to clean up from a
+// checklist, then cleans them up like --all
+func runCleanupInteractive(cmd *cobra.Command, p *output.Printer) error {
+	if p.IsJSON() || !stdinIsTerminal() {
+		return fmt.Errorf("--interactive needs a terminal\nName the worktree to clean up, or use --merged or --all")
+	}
+
+	ctx, cancel := signals.SetupCancellableContext()
+	defer cancel()
+
+	mainRepoRoot, err := git.GetMainRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get main repository root: %w", err)
+	}
+	items, err := loadCleanupPickerItems(ctx, mainRepoRoot)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		p.Info("No koh worktrees to clean up")
+		return nil
+	}
+
+	// The checklist goes to stderr like the branch picker's
+	final, err := tea.NewProgram(newCleanupPicker(items), tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return fmt.Errorf("error running cleanup checklist: %w", err)
+	}
+	picker := final.(cleanupPickerModel)
+	if !picker.confirmed {
+		return errAborted
+	}
+
+	// Confirming in the checklist covers the uncommitted changes it listed
+	var targets []git.Worktree
+	for _, item := range picker.pickedItems() {
+		targets = append(targets, git.Worktree{Path: item.path, Branch: item.branch})
+	}
+	current := currentLast(targets)
+	cleanupCfg := loadCleanupConfig()
+	opts := cleanupOptions{cfg: cleanupCfg, deleteRemote: resolveDeleteRemote(cmd, cleanupCfg), force: true, forceCommands: cleanupForce}
+	return cleanupAndReport(ctx, cmd, p, mainRepoRoot, targets, current, opts)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/testutil"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCleanupPickerModel(t *testing.T) {
+	items := []cleanupPickerItem{
+		{worktreeItem: worktreeItem{name: "auth", branch: "feature/auth"}, merged: true},
+		{worktreeItem: worktreeItem{name: "spike", branch: "spike"}, dirty: true},
+		{worktreeItem: worktreeItem{name: "docs", branch: "docs"}, merged: true},
+	}
+	update := func(m cleanupPickerModel, msgs ...tea.KeyMsg) cleanupPickerModel {
+		for _, msg := range msgs {
+			updated, _ := m.Update(msg)
+			m = updated.(cleanupPickerModel)
+		}
+		return m
+	}
+	key := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	pickedNames := func(m cleanupPickerModel) string {
+		var names []string
+		for _, item := range m.pickedItems() {
+			names = append(names, item.name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	t.Run("picks several and confirms", func(t *testing.T) {
+		m := update(newCleanupPicker(items), tea.KeyMsg{Type: tea.KeySpace}, key("j"), tea.KeyMsg{Type: tea.KeySpace})
+		if got := pickedNames(m); got != "auth,spike" {
+			t.Fatalf("Expected auth and spike to be picked, got %q", got)
+		}
+
+		m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+		if !m.confirming || m.quitting {
+			t.Fatal("Expected enter to ask for confirmation first")
+		}
+		if view := m.View(); !strings.Contains(view, "uncommitted changes will be lost") {
+			t.Errorf("Expected the confirmation to warn about spike's changes, got:\n%s", view)
+		}
+
+		m = update(m, key("y"))
+		if !m.confirmed || !m.quitting {
+			t.Error("Expected y to confirm the cleanup")
+		}
+	})
+
+	t.Run("picks merged worktrees", func(t *testing.T) {
+		m := update(newCleanupPicker(items), key("m"))
+		if got := pickedNames(m); got != "auth,docs" {
+			t.Errorf("Expected the merged worktrees to be picked, got %q", got)
+		}
+	})
+
+	t.Run("toggles all", func(t *testing.T) {
+		m := update(newCleanupPicker(items), key("a"))
+		if got := len(m.pickedItems()); got != 3 {
+			t.Errorf("Expected all 3 worktrees to be picked, got %d", got)
+		}
+		if m = update(m, key("a")); len(m.pickedItems()) != 0 {
+			t.Errorf("Expected a second a to pick nothing, got %q", pickedNames(m))
+		}
+	})
+
+	t.Run("leaves read-only worktrees out", func(t *testing.T) {
+		withReadonly := append([]cleanupPickerItem{
+			{worktreeItem: worktreeItem{name: "release", branch: "release/1.2", readonly: true}, merged: true},
+		}, items...)
+		m := update(newCleanupPicker(withReadonly), tea.KeyMsg{Type: tea.KeySpace})
+		if got := pickedNames(m); got != "" {
+			t.Errorf("Expected space not to pick the read-only worktree, got %q", got)
+		}
+		if !strings.Contains(m.View(), "release is read-only") {
+			t.Errorf("Expected the checklist to explain why, got:\n%s", m.View())
+		}
+		if got := pickedNames(update(newCleanupPicker(withReadonly), key("m"))); got != "auth,docs" {
+			t.Errorf("Expected m to skip the read-only worktree, got %q", got)
+		}
+		if got := pickedNames(update(newCleanupPicker(withReadonly), key("a"))); got != "auth,spike,docs" {
+			t.Errorf("Expected a to skip the read-only worktree, got %q", got)
+		}
+	})
+
+	t.Run("enter without picks does nothing", func(t *testing.T) {
+		m := update(newCleanupPicker(items), tea.KeyMsg{Type: tea.KeyEnter})
+		if m.confirming || m.quitting {
+			t.Error("Expected enter to do nothing without picks")
+		}
+	})
+
+	t.Run("n goes back and esc quits", func(t *testing.T) {
+		m := update(newCleanupPicker(items), tea.KeyMsg{Type: tea.KeySpace}, tea.KeyMsg{Type: tea.KeyEnter}, key("n"))
+		if m.confirming || m.confirmed {
+			t.Fatal("Expected n to go back to the checklist")
+		}
+		if m = update(m, tea.KeyMsg{Type: tea.KeyEsc}); !m.quitting || m.confirmed {
+			t.Error("Expected esc to quit without cleaning up")
+		}
+	})
+}
+
+func TestLoadCleanupPickerItems(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	testutil.WriteFile(t, filepath.Join(repo, ".koh", "feat-a"), "notes.txt", "todo")
+
+	mainRepoRoot, err := git.GetMainRepoRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := loadCleanupPickerItems(context.Background(), mainRepoRoot)
+	if err != nil {
+		t.Fatalf("loadCleanupPickerItems() failed: %v", err)
+	}
+	if len(items) != 1 || items[0].name != "feat-a" {
+		t.Fatalf("Expected only feat-a, got %+v", items)
+	}
+	if !items[0].dirty || items[0].merged {
+		t.Errorf("Expected feat-a to be dirty and not merged, got %+v", items[0])
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var completionWorktreesCmd = &cobra.Command{
+	Use:   "worktrees",
+	Short: "Print worktree names for pickers and editor plugins",
+	Long: `Print the names of all koh worktrees, one per line, for fzf pickers,
+launcher workflows and editor plugins. Unlike the shell completion scripts,
+the output is meant to be parsed and its format is stable:
+
+  name                  without --descriptions
+  name<TAB>description  with --descriptions
+
+The description starts with the branch, followed by "dirty" when the
+worktree has uncommitted changes and by when koh last switched to it, e.g.
+"feature/login, dirty, used 5m ago". The shell completion scripts show the
+same descriptions when completing worktree names, in shells that support
+them such as zsh and fish.
+
+Worktree data is read through the cache, and uncommitted changes are
+checked at most every few seconds, so this is fast enough to run on every
+keystroke. Use --json for the name, branch, path, dirty state and last use
+of each worktree.
+
+Example:
+  koh switch "$(koh completion worktrees | fzf)"`,
+	Args: cobra.NoArgs,
+	RunE: runCompletionWorktrees,
+}
+
+var (
+	// completionDescriptions appends a description of each worktree after a tab
+	completionDescriptions bool
+)
+
+func init() {
+	// Attach to cobra's default completion command, creating it now instead
+	// of lazily at execution so it keeps its shell script subcommands
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == "completion" {
+			c.AddCommand(completionWorktreesCmd)
+		}
+	}
+
+	completionWorktreesCmd.Flags().BoolVar(&completionDescriptions, "descriptions", false, "Append each worktree's branch, dirty state and last use, separated by a tab")
+}
+
+// dirtyCacheTTL is how long completions trust which worktrees have
+// uncommitted changes
+const dirtyCacheTTL = 10 * time.Second
+
+// completionCandidate is the machine-readable form of a worktree candidate
+type completionCandidate struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
+	// Dirty is set when the worktree has uncommitted changes
+	Dirty bool `json:"dirty"`
+	// UsedAt is when koh last switched to the worktree
+	UsedAt time.Time `json:"used_at,omitzero"`
+}
+
+// description describes a candidate for completion menus, e.g.
+// "feature/login, dirty, used 5m ago"
+func (c completionCandidate) description(now time.Time) string {
+	parts := []string{c.Branch}
+	if c.Dirty {
+		parts = append(parts, styles.MarkerDirty.Text)
+	}
+	if !c.UsedAt.IsZero() {
+		parts = append(parts, "used "+usedAgo(c.UsedAt, now))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// renderCompletionCandidates renders candidates in the stable line format
+func renderCompletionCandidates(w io.Writer, candidates []completionCandidate, descriptions bool) {
+	now := time.Now()
+	for _, c := range candidates {
+		if descriptions {
+			fprintln(w, c.Name+"\t"+c.description(now))
+		} else {
+			fprintln(w, c.Name)
+		}
+	}
+}
+
+// dirtyWorktrees returns which of the worktrees have uncommitted changes,
+// checking them all at once and caching the answer briefly
+func dirtyWorktrees(worktrees []git.Worktree) map[string]bool {
+	check := func() (map[string]bool, error) {
+		dirty := make([]bool, len(worktrees))
+		var wg sync.WaitGroup
+		for i, wt := range worktrees {
+			wg.Add(1)
+			go func(i int, path string) {
+				defer wg.Done()
+				dirty[i], _ = git.IsDirty(path)
+			}(i, wt.Path)
+		}
+		wg.Wait()
+
+		byPath := make(map[string]bool, len(worktrees))
+		for i, wt := range worktrees {
+			byPath[wt.Path] = dirty[i]
+		}
+		return byPath, nil
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		dirty, _ := check()
+		return dirty
+	}
+	dirty, _ := cache.Recent(commonDir, cache.KindDirty, dirtyCacheTTL, check)
+	return dirty
+}
+
+// loadCompletionCandidates returns the koh worktrees as completion
+// candidates. With details their dirty state and last use are filled in,
+// which takes a git status of each worktree.
+func loadCompletionCandidates(ctx context.Context, details bool) ([]completionCandidate, error) {
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirty map[string]bool
+	var recorded map[string]*state.Worktree
+	if details {
+		dirty = dirtyWorktrees(worktrees)
+		recorded = loadRecordedWorktrees()
+	}
+
+	candidates := []completionCandidate{}
+	for _, wt := range worktrees {
+		c := completionCandidate{
+			Name:   filepath.Base(wt.Path),
+			Branch: displayBranch(wt),
+			Path:   wt.Path,
+			Dirty:  dirty[wt.Path],
+		}
+		if r := recorded[c.Name]; r != nil {
+			c.UsedAt = r.UsedAt
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// completeWorktreeNames completes the worktree name argument of commands
+// such as 'koh switch', described like 'koh completion worktrees
+// --descriptions'. Shells without descriptions, and --no-descriptions
+// scripts, get the names alone.
+func completeWorktreeNames(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 || !git.IsGitRepo() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	candidates, err := loadCompletionCandidates(context.Background(), true)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	now := time.Now()
+	completions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		completions = append(completions, c.Name+"\t"+c.description(now))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func runCompletionWorktrees(cmd *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+
+	p := newPrinter(cmd)
+	candidates, err := loadCompletionCandidates(context.Background(), completionDescriptions || p.IsJSON())
+	if err != nil {
+		return err
+	}
+
+	return p.Result(candidates, func(w io.Writer) {
+		renderCompletionCandidates(w, candidates, completionDescriptions)
+	})
+}
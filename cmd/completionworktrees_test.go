@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/testutil"
+	"github.com/spf13/cobra"
+)
+
+func TestRenderCompletionCandidates(t *testing.T) {
+	candidates := []completionCandidate{
+		{Name: "auth", Branch: "feature/auth", Path: "/repo/.koh/auth"},
+		{Name: "spike", Branch: "detached", Path: "/repo/.koh/spike"},
+	}
+
+	var plain bytes.Buffer
+	renderCompletionCandidates(&plain, candidates, false)
+	if got, want := plain.String(), "auth\nspike\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	var described bytes.Buffer
+	renderCompletionCandidates(&described, candidates, true)
+	if got, want := described.String(), "auth\tfeature/auth\nspike\tdetached\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCompletionCandidateDescription(t *testing.T) {
+	now := time.Date(2025, 6, 30, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		candidate completionCandidate
+		want      string
+	}{
+		{completionCandidate{Branch: "feature/auth"}, "feature/auth"},
+		{completionCandidate{Branch: "feature/auth", Dirty: true}, "feature/auth, dirty"},
+		{completionCandidate{Branch: "detached", UsedAt: now.Add(-5 * time.Minute)}, "detached, used 5m ago"},
+		{completionCandidate{Branch: "fix", Dirty: true, UsedAt: now.Add(-3 * 24 * time.Hour)}, "fix, dirty, used 3 days ago"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.candidate.description(now); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestCompleteWorktreeNames(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	testutil.WriteFile(t, filepath.Join(repo, ".koh", "feat-a"), "notes.txt", "todo")
+
+	got, directive := completeWorktreeNames(switchCmd, nil, "")
+	if want := []string{"feat-a\tfeat-a, dirty"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected file completion to be off, got %v", directive)
+	}
+
+	if got, _ := completeWorktreeNames(switchCmd, []string{"feat-a"}, ""); len(got) != 0 {
+		t.Errorf("Expected nothing to complete after the name, got %q", got)
+	}
+}
+
+func TestCompletionWorktreesRegistered(t *testing.T) {
+	found, _, err := rootCmd.Find([]string{"completion", "worktrees"})
+	if err != nil || found != completionWorktreesCmd {
+		t.Errorf("Expected 'completion worktrees' to resolve to the worktrees helper, got %v (%v)", found, err)
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		if c, _, err := rootCmd.Find([]string{"completion", shell}); err != nil || c.Name() != shell {
+			t.Errorf("Expected the %s completion script command to remain available, got %v (%v)", shell, c, err)
+		}
+	}
+}
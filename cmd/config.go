@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/linkdir"
 	"github.com/bshakr/koh/internal/styles"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -20,7 +23,13 @@ func init() {
 	rootCmd.AddCommand(configCmd)
 }
 
-func runConfig(_ *cobra.Command, _ []string) error {
+// configResult is the machine-readable result of 'koh config'
+type configResult struct {
+	Path string `json:"path"`
+	*config.Config
+}
+
+func runConfig(cmd *cobra.Command, _ []string) error {
 	// Get terminal width
 	terminalWidth := styles.GetTerminalWidth()
 
@@ -34,6 +43,11 @@ func runConfig(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to get config path: %w", err)
 	}
 
+	p := newPrinter(cmd)
+	if p.IsJSON() {
+		return p.Result(configResult{Path: configPath, Config: cfg}, nil)
+	}
+
 	// Print title (centered)
 	title := lipgloss.NewStyle().
 		Align(lipgloss.Center).
@@ -52,16 +66,70 @@ func runConfig(_ *cobra.Command, _ []string) error {
 	// Create a styled box for config values
 	var content string
 	content += styles.RenderKeyValue("Setup Script", cfg.SetupScript) + "\n"
+	if len(cfg.Include) > 0 {
+		content += styles.RenderKeyValue("Includes", strings.Join(cfg.Include, ", ")) + "\n"
+	}
+	if cfg.WorktreeDir != "" {
+		content += styles.RenderKeyValue("Worktree Directory", cfg.ResolveWorktreeDir(filepath.Dir(configPath))) + "\n"
+	}
+	if cfg.BranchTemplate != "" {
+		content += styles.RenderKeyValue("Branch Template", cfg.BranchTemplate) + "\n"
+	}
+	if len(cfg.SparsePaths) > 0 {
+		content += styles.RenderKeyValue("Sparse Paths", strings.Join(cfg.SparsePaths, ", ")) + "\n"
+	}
+	if len(cfg.LinkDirs) > 0 {
+		mode := cfg.LinkMode
+		if mode == "" {
+			mode = linkdir.Symlink
+		}
+		content += styles.RenderKeyValue("Linked Directories", fmt.Sprintf("%s (%s)", strings.Join(cfg.LinkDirs, ", "), mode)) + "\n"
+	}
+	if len(cfg.Env) > 0 {
+		// Values may hold secrets such as passwords in URLs
+		content += styles.RenderKeyValue("Environment", strings.Join(sortedEnvNames(cfg.Env), ", ")) + "\n"
+	}
+	if start, count := cfg.Ports.Range(); count > 0 {
+		content += styles.RenderKeyValue("Ports", fmt.Sprintf("%d per worktree, from %d", count, start)) + "\n"
+	}
 	content += "\n"
 	if len(cfg.PaneCommands) > 0 {
 		content += styles.Key.Render("Pane Commands:") + "\n"
-		for i, cmdStr := range cfg.PaneCommands {
-			content += fmt.Sprintf("  %d. %s\n", i+1, styles.Key.Render(cmdStr))
+		for i, pane := range cfg.PaneCommands {
+			content += fmt.Sprintf("  %d. %s\n", i+1, styles.Key.Render(pane.String()))
 		}
 	} else {
 		content += styles.Muted.Render("No pane commands configured") + "\n"
 	}
 
+	if cfg.MainCheckout != nil {
+		content += "\n"
+		content += styles.RenderKeyValue("Main Checkout", cfg.MainCheckout.ResolvePath(filepath.Dir(configPath))) + "\n"
+		for i, pane := range cfg.MainCheckout.PaneCommands {
+			content += fmt.Sprintf("  %d. %s\n", i+1, styles.Key.Render(pane.String()))
+		}
+	}
+
+	for _, name := range cfg.ProfileNames() {
+		profile := cfg.Profiles[name]
+		if profile == nil {
+			continue
+		}
+		content += "\n"
+		content += styles.RenderKeyValue("Profile "+name, profile.SetupScript) + "\n"
+		for i, pane := range profile.PaneCommands {
+			content += fmt.Sprintf("  %d. %s\n", i+1, styles.Key.Render(pane.String()))
+		}
+	}
+
+	if hooks := cfg.Hooks.PostCreateHooks(); len(hooks) > 0 {
+		content += "\n"
+		content += styles.Key.Render("Post-create Hooks:") + "\n"
+		for i, hook := range hooks {
+			content += fmt.Sprintf("  %d. %s\n", i+1, styles.Key.Render(hook))
+		}
+	}
+
 	box := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(styles.Subtle).
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for problems",
+	Long: `Check .kohconfig for commands that would break a pane: scripts that
+don't exist or aren't executable, programs missing from PATH, and text that
+tmux send-keys would mangle (trailing semicolons, line breaks, key names).
+
+Exits with an error when problems are found.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// configValidateResult is the machine-readable result of 'koh config validate'
+type configValidateResult struct {
+	Path     string           `json:"path"`
+	Warnings []config.Warning `json:"warnings"`
+}
+
+// lintConfig lints a configuration against the repository it belongs to
+func lintConfig(cfg *config.Config) []config.Warning {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return nil
+	}
+	return cfg.Lint(filepath.Dir(configPath))
+}
+
+// renderConfigWarnings renders lint warnings as an indented list
+func renderConfigWarnings(warnings []config.Warning) string {
+	var b strings.Builder
+	for _, w := range warnings {
+		b.WriteString(fmt.Sprintf("  %s %s %s\n",
+			styles.WarningMessage.Render("⚠"),
+			styles.Key.Render(w.Source+":"),
+			w.Message))
+	}
+	return b.String()
+}
+
+func runConfigValidate(cmd *cobra.Command, _ []string) error {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to get config path: %w", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	result := configValidateResult{Path: configPath, Warnings: lintConfig(cfg)}
+	if result.Warnings == nil {
+		result.Warnings = []config.Warning{}
+	}
+
+	if err := newPrinter(cmd).Result(result, func(w io.Writer) {
+		if len(result.Warnings) == 0 {
+			fprintln(w, styles.RenderSuccess("No problems found in "+configPath))
+			return
+		}
+		fprintln(w, styles.WarningMessage.Render(fmt.Sprintf("Found %d problem(s) in %s:", len(result.Warnings), configPath)))
+		_, _ = io.WriteString(w, renderConfigWarnings(result.Warnings))
+	}); err != nil {
+		return err
+	}
+
+	if len(result.Warnings) > 0 {
+		// The result already lists the problems
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return silentFailure(fmt.Errorf("config has %d problem(s)", len(result.Warnings)))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRenderConfigWarnings(t *testing.T) {
+	rendered := renderConfigWarnings([]config.Warning{
+		{Source: "pane_commands[0]", Command: "nope", Message: `command "nope" not found on PATH`},
+	})
+
+	for _, want := range []string{"pane_commands[0]", "not found on PATH"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("Expected warnings to contain %q, got %q", want, rendered)
+		}
+	}
+}
+
+func TestInitLintsBeforeConfirm(t *testing.T) {
+	m := initialModel()
+	m.step = stepAddPaneChoice
+	m.choice = 1
+	m.setupInput.SetValue("")
+	m.paneCommands = []string{"definitely-not-a-real-command-xyz"}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(initModel)
+
+	if m.step != stepConfirm {
+		t.Fatalf("Expected confirm step, got %v", m.step)
+	}
+	if len(m.warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", m.warnings)
+	}
+	if !strings.Contains(m.View(), "Possible problems") {
+		t.Error("Expected confirm view to show warnings")
+	}
+}
+
+func TestInitProfile(t *testing.T) {
+	newDashboardRepo(t)
+	cfg := &config.Config{SetupScript: "./bin/setup", PaneCommands: config.PlainPaneCommands([]string{"vim"})}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	m := initialModelFor(cfg, "backend")
+	if m.setupInput.Value() != "" {
+		t.Errorf("Expected a new profile to start without a setup script, got %q", m.setupInput.Value())
+	}
+	m.setupInput.SetValue("./bin/setup-api")
+	m.paneCommands = []string{"rails s"}
+	m.step = stepConfirm
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if err := updated.(initModel).err; err != nil {
+		t.Fatalf("Failed to save the profile: %v", err)
+	}
+
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if saved.SetupScript != "./bin/setup" || len(saved.PaneCommands) != 1 || saved.PaneCommands[0].Command != "vim" {
+		t.Errorf("Expected the top-level setup to be kept, got %+v", saved)
+	}
+	profile := saved.Profiles["backend"]
+	if profile == nil || profile.SetupScript != "./bin/setup-api" || len(profile.PaneCommands) != 1 || profile.PaneCommands[0].Command != "rails s" {
+		t.Errorf("Expected the backend profile to be saved, got %+v", profile)
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bshakr/koh/internal/output"
+	"golang.org/x/term"
+)
+
+// errAborted is returned when the user declines a destructive operation
+var errAborted = errors.New("aborted")
+
+// stdin is where answers to questions are read from
+var stdin io.Reader = os.Stdin
+
+// stdinIsTerminal reports whether there is a person to ask on stdin
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// stdoutIsTerminal reports whether stdout is shown on a terminal
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// destructiveOp describes an operation that loses work or is hard to undo
+type destructiveOp struct {
+	// What says what will happen, e.g. "Remove .koh/feature and its uncommitted changes"
+	What string
+	// Items lists what is affected, one per line below What
+	Items []string
+	// ForceFlag is the command's flag that allows the operation, e.g. "--force"
+	ForceFlag string
+}
+
+// confirmDestructive gets consent for a destructive operation. Consent comes
+// from force (the command's own flag, already set), from --yes, or from
+// answering yes when asked. Without a terminal to ask on, and in JSON mode,
+// it fails instead of prompting and names the flags that give consent.
+func confirmDestructive(p *output.Printer, op destructiveOp, force bool) error {
+	if force || assumeYes {
+		return nil
+	}
+
+	flags := "--yes"
+	if op.ForceFlag != "" {
+		flags = op.ForceFlag + " or --yes"
+	}
+	if p.IsJSON() || !stdinIsTerminal() {
+		return fmt.Errorf("%s needs confirmation\nRe-run with %s to proceed", lowerFirst(op.What), flags)
+	}
+
+	for _, item := range op.Items {
+		p.Info("  %s", item)
+	}
+	answer, err := p.Prompt(stdin, "%s? [y/N] ", op.What)
+	if err != nil {
+		return errAborted
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return nil
+	}
+	return errAborted
+}
+
+// lowerFirst lowercases the first letter of s
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestConfirmDestructive(t *testing.T) {
+	op := destructiveOp{What: "Remove .koh/feature and its uncommitted changes", Items: []string{"app.go"}, ForceFlag: "--force"}
+
+	tests := []struct {
+		name        string
+		force       bool
+		yes         bool
+		json        bool
+		terminal    bool
+		answer      string
+		wantErr     bool
+		wantAborted bool
+	}{
+		{name: "force", force: true},
+		{name: "yes flag", yes: true},
+		{name: "yes flag in JSON mode", yes: true, json: true},
+		{name: "answered yes", terminal: true, answer: "y\n"},
+		{name: "answered YES", terminal: true, answer: "YES\n"},
+		{name: "answered no", terminal: true, answer: "n\n", wantErr: true, wantAborted: true},
+		{name: "default is no", terminal: true, answer: "\n", wantErr: true, wantAborted: true},
+		{name: "no answer", terminal: true, answer: "", wantErr: true, wantAborted: true},
+		{name: "no terminal", terminal: false, wantErr: true},
+		{name: "JSON mode never prompts", terminal: true, json: true, answer: "y\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStdin, oldTerminal, oldYes := stdin, stdinIsTerminal, assumeYes
+			t.Cleanup(func() { stdin, stdinIsTerminal, assumeYes = oldStdin, oldTerminal, oldYes })
+			stdin = strings.NewReader(tt.answer)
+			stdinIsTerminal = func() bool { return tt.terminal }
+			assumeYes = tt.yes
+
+			format := output.Human
+			if tt.json {
+				format = output.JSON
+			}
+			var out bytes.Buffer
+			p := output.New(&out, format)
+
+			err := confirmDestructive(p, op, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantAborted != errors.Is(err, errAborted) {
+				t.Errorf("Expected aborted %v, got %v", tt.wantAborted, err)
+			}
+			if err != nil && !tt.wantAborted && !strings.Contains(err.Error(), "--force or --yes") {
+				t.Errorf("Expected the error to name the flags that give consent, got %q", err)
+			}
+			if tt.answer != "" && tt.terminal && !tt.json && !strings.Contains(out.String(), "app.go") {
+				t.Errorf("Expected the affected items to be listed, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestConfirmDestructiveWithoutForceFlag(t *testing.T) {
+	oldTerminal := stdinIsTerminal
+	t.Cleanup(func() { stdinIsTerminal = oldTerminal })
+	stdinIsTerminal = func() bool { return false }
+
+	err := confirmDestructive(output.New(io.Discard, output.Human), destructiveOp{What: "Delete everything"}, false)
+	if err == nil || !strings.Contains(err.Error(), "delete everything needs confirmation\nRe-run with --yes") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/fsutil"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// matchCopyFiles returns the files below root matched by copy_files
+// patterns, relative to root and sorted. Matching directories contribute the
+// files inside them. Symlinks, the .git directory and the worktree directory
+// are skipped. Matches that only lie inside the repository because of a
+// symlinked directory are skipped with a warning, and the other matches are
+// still copied.
+func matchCopyFiles(p *output.Printer, root string, patterns []string) ([]string, error) {
+	kohDir := config.WorktreeDir(root)
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		cleaned, err := validation.ValidateRepositoryPath(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid copy_files pattern %q: %w", pattern, err)
+		}
+		matches, err := filepath.Glob(filepath.Join(root, cleaned))
+		if err != nil {
+			return nil, fmt.Errorf("invalid copy_files pattern %q: %w", pattern, err)
+		}
+
+		for _, match := range matches {
+			// WalkDir doesn't follow symlinks below match, so only its own
+			// directories can lead outside
+			if err := validation.ValidatePathWithinRepository(match, root); err != nil {
+				p.Warn("Not copying %s: copy_files pattern %q matches it through a symlink: %v", match, pattern, err)
+				continue
+			}
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(root, path)
+				if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+					return fmt.Errorf("copy_files pattern %q matches %s outside the repository", pattern, path)
+				}
+				if top, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); top == ".git" || path == kohDir || strings.HasPrefix(path, kohDir+string(filepath.Separator)) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() {
+					seen[rel] = true
+				}
+				return nil
+			})
+			if err != nil {
+				p.Warn("Not copying %s: %v", match, err)
+			}
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// selectCopyFiles returns the files to copy in the configured mode. In
+// ignored-only mode only files git ignores in the main repository are kept.
+func selectCopyFiles(ctx context.Context, mainRepoRoot string, files []string, mode string) ([]string, error) {
+	if mode != config.CopyIgnoredOnly {
+		return files, nil
+	}
+
+	ignored, err := git.IgnoredPathsWithContext(ctx, mainRepoRoot, files)
+	if err != nil {
+		return nil, err
+	}
+	var selected []string
+	for _, file := range files {
+		if ignored[file] {
+			selected = append(selected, file)
+		}
+	}
+	return selected, nil
+}
+
+// copyWorktreeFiles copies the files matched by copy_files from the main
+// repository into a new worktree and returns the ones copied. Problems are
+// reported as warnings since the worktree is usable without the files.
+func copyWorktreeFiles(ctx context.Context, p *output.Printer, mainRepoRoot, worktreePath string, cfg *config.Config) []string {
+	files, err := matchCopyFiles(p, mainRepoRoot, cfg.CopyFiles)
+	if err != nil {
+		p.Warn("Not copying files: %v", err)
+		return nil
+	}
+	if files, err = selectCopyFiles(ctx, mainRepoRoot, files, cfg.CopyFilesMode); err != nil {
+		p.Warn("Not copying files: %v", err)
+		return nil
+	}
+
+	var copied []string
+	for _, file := range files {
+		// A symlink checked into the worktree could lead the copy elsewhere
+		if err := validation.ValidatePathWithinRepository(filepath.Join(worktreePath, file), worktreePath); err != nil {
+			p.Warn("Not copying %s: %v", file, err)
+			continue
+		}
+		if err := fsutil.CopyFile(filepath.Join(mainRepoRoot, file), filepath.Join(worktreePath, file)); err != nil {
+			p.Warn("Failed to copy %s: %v", file, err)
+			continue
+		}
+		copied = append(copied, filepath.ToSlash(file))
+	}
+	if len(copied) > 0 {
+		p.Info("Copied %d files from the main repository", len(copied))
+	}
+	return copied
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestMatchCopyFiles(t *testing.T) {
+	root := t.TempDir()
+	p := output.New(io.Discard, output.Human)
+	for _, file := range []string{".env", ".env.test", "config/local.yml", "config/app.yml", ".vscode/settings.json", ".vscode/nested/tasks.json", ".koh/other/.env", "main.go"} {
+		path := filepath.Join(root, file)
+		//nolint:gosec // G301: Test directory
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{name: "single file", patterns: []string{".env"}, want: []string{".env"}},
+		{name: "glob", patterns: []string{".env*", "config/*.yml"}, want: []string{".env", ".env.test", "config/app.yml", "config/local.yml"}},
+		{name: "directory", patterns: []string{".vscode"}, want: []string{".vscode/nested/tasks.json", ".vscode/settings.json"}},
+		{name: "duplicates", patterns: []string{".env", ".env*"}, want: []string{".env", ".env.test"}},
+		{name: "koh worktrees skipped", patterns: []string{".koh", ".koh/*/.env"}, want: []string{}},
+		{name: "no match", patterns: []string{"missing.txt"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchCopyFiles(p, root, tt.patterns)
+			if err != nil {
+				t.Fatalf("matchCopyFiles() failed: %v", err)
+			}
+			for i := range got {
+				got[i] = filepath.ToSlash(got[i])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := matchCopyFiles(p, filepath.Join(root, "config"), []string{"../main.go"}); err == nil {
+		t.Error("Expected an error for a pattern outside the repository")
+	}
+
+	// A symlinked directory must not lead copy_files out of the repository
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	var warnings bytes.Buffer
+	got, err := matchCopyFiles(output.New(&warnings, output.Human), root, []string{"linked/secret", "main.go"})
+	if err != nil {
+		t.Fatalf("matchCopyFiles() failed: %v", err)
+	}
+	if want := []string{"main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the symlinked match to be skipped and %v kept, got %v", want, got)
+	}
+	if !strings.Contains(warnings.String(), "symlink") {
+		t.Errorf("Expected a warning about the symlink, got %q", warnings.String())
+	}
+}
+
+func TestSelectCopyFilesAll(t *testing.T) {
+	files := []string{".env", "main.go"}
+	got, err := selectCopyFiles(context.Background(), t.TempDir(), files, config.CopyAll)
+	if err != nil || !reflect.DeepEqual(got, files) {
+		t.Errorf("Expected every file in all mode, got %v (%v)", got, err)
+	}
+}
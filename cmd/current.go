@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var currentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Show the worktree the current shell belongs to",
+	Long: `Print the koh worktree (name, branch, path) the current directory belongs to.
+
+Exits with a non-zero status when not inside a koh worktree, so it can be
+used from shell prompts and scripts.
+
+Use --format with a Go template to customize the output, for example:
+  koh current --format '{{.Name}} on {{.Branch}}'`,
+	Args:          cobra.NoArgs,
+	RunE:          runCurrent,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+// currentFormat is a Go template used to render the worktree info
+var currentFormat string
+
+func init() {
+	currentCmd.Flags().StringVar(&currentFormat, "format", "", "Go template for output (fields: Name, Branch, Path)")
+	rootCmd.AddCommand(currentCmd)
+}
+
+// currentWorktreeInfo describes the koh worktree the current directory belongs to
+type currentWorktreeInfo struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
+}
+
+// detectCurrentWorktree returns the koh worktree containing the current directory.
+// Returns an error when not inside a koh-managed worktree.
+func detectCurrentWorktree() (*currentWorktreeInfo, error) {
+	worktreePath, err := git.GetCurrentWorktreePath()
+	if err != nil {
+		return nil, fmt.Errorf("not inside a koh worktree")
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil, fmt.Errorf("not inside a koh worktree")
+	}
+	mainRepoRoot := filepath.Dir(commonDir)
+
+	// Only worktrees directly inside the worktree directory are koh-managed
+	if !inWorktreeDir(config.WorktreeDir(mainRepoRoot), worktreePath) {
+		return nil, fmt.Errorf("not inside a koh worktree")
+	}
+
+	info := &currentWorktreeInfo{Name: filepath.Base(worktreePath), Path: worktreePath}
+
+	// Prefer the cached worktree list to avoid another git call
+	if worktrees, err := cache.Worktrees(context.Background(), commonDir); err == nil {
+		for _, wt := range worktrees {
+			if wt.Path == worktreePath {
+				info.Branch = displayBranch(wt)
+				return info, nil
+			}
+		}
+	}
+
+	branch, err := git.GetCurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	info.Branch = branch
+	return info, nil
+}
+
+// formatCurrent renders worktree info using the given output mode.
+// A non-empty format is treated as a Go template; otherwise JSON or
+// human-readable key/value lines are produced.
+func formatCurrent(info *currentWorktreeInfo, format string, asJSON bool) (string, error) {
+	if format != "" {
+		tmpl, err := template.New("current").Parse(format)
+		if err != nil {
+			return "", fmt.Errorf("invalid format template: %w", err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, info); err != nil {
+			return "", fmt.Errorf("failed to render format template: %w", err)
+		}
+		return b.String(), nil
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal worktree info: %w", err)
+		}
+		return string(data), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(styles.RenderKeyValue("Name", info.Name) + "\n")
+	b.WriteString(styles.RenderKeyValue("Branch", info.Branch) + "\n")
+	b.WriteString(styles.RenderKeyValue("Path", info.Path))
+	return b.String(), nil
+}
+
+func runCurrent(cmd *cobra.Command, _ []string) error {
+	info, err := detectCurrentWorktree()
+	if err != nil {
+		return err
+	}
+
+	out, err := formatCurrent(info, currentFormat, jsonOutput)
+	if err != nil {
+		return err
+	}
+
+	fprintln(cmd.OutOrStdout(), out)
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatCurrent(t *testing.T) {
+	info := &currentWorktreeInfo{
+		Name:   "auth-fix",
+		Branch: "feature/auth-fix",
+		Path:   "/repo/.koh/auth-fix",
+	}
+
+	t.Run("template", func(t *testing.T) {
+		out, err := formatCurrent(info, "{{.Name}}@{{.Branch}}", false)
+		if err != nil {
+			t.Fatalf("formatCurrent() failed: %v", err)
+		}
+		if out != "auth-fix@feature/auth-fix" {
+			t.Errorf("Unexpected output: %q", out)
+		}
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		if _, err := formatCurrent(info, "{{.Name", false); err == nil {
+			t.Error("Expected error for invalid template, got nil")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := formatCurrent(info, "", true)
+		if err != nil {
+			t.Fatalf("formatCurrent() failed: %v", err)
+		}
+		var decoded currentWorktreeInfo
+		if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		if decoded != *info {
+			t.Errorf("Decoded %+v, want %+v", decoded, *info)
+		}
+	})
+
+	t.Run("human", func(t *testing.T) {
+		out, err := formatCurrent(info, "", false)
+		if err != nil {
+			t.Fatalf("formatCurrent() failed: %v", err)
+		}
+		for _, want := range []string{info.Name, info.Branch, info.Path} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected output to contain %q, got %q", want, out)
+			}
+		}
+	})
+}
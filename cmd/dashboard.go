@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// refreshCacheCmd refreshes the cached worktree list. The dashboard starts it
+// in the background when it rendered from a stale cache entry.
+var refreshCacheCmd = &cobra.Command{
+	Use:    "refresh-cache",
+	Short:  "Refresh the cached worktree list",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		commonDir, err := git.GetCommonDir()
+		if err != nil {
+			return err
+		}
+		_, err = cache.Worktrees(context.Background(), commonDir)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refreshCacheCmd)
+}
+
+// dashboardStatus is the repository status the bare 'koh' dashboard shows
+type dashboardStatus struct {
+	RepoName string
+	// Worktrees is the number of koh worktrees
+	Worktrees int
+	// Current is the worktree the current directory belongs to, or "main"
+	Current    string
+	Configured bool
+	// Refreshing is set when Worktrees came from a stale cache entry that is
+	// being refreshed in the background
+	Refreshing bool
+}
+
+// startCacheRefresh starts 'koh refresh-cache' without waiting for it
+var startCacheRefresh = func() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // G204: runs koh's own executable
+	cmd := exec.Command(executable, "refresh-cache")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// loadDashboardStatus gathers the dashboard status with as little work as
+// possible: one git call to locate the repository, and the worktree count
+// from the cache. A stale count is shown as is while the cache refreshes in
+// the background; git is only asked directly when nothing is cached.
+func loadDashboardStatus(ctx context.Context) (*dashboardStatus, error) {
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dashboardStatus{RepoName: filepath.Base(info.TopLevel), Current: "main"}
+
+	// The config lives in the main checkout, as found by config.ConfigPath
+	mainRepoRoot, _ := os.Getwd()
+	if info.InWorktree() {
+		mainRepoRoot = filepath.Dir(info.CommonDir)
+		status.Current = filepath.Base(info.TopLevel)
+	}
+	if _, err := os.Stat(filepath.Join(mainRepoRoot, ".kohconfig")); err == nil {
+		status.Configured = true
+	}
+
+	kohDir := config.WorktreeDir(mainRepoRoot)
+	if _, err := os.Stat(kohDir); err != nil {
+		return status, nil
+	}
+
+	worktrees, fresh, ok := cache.PeekWorktrees(info.CommonDir)
+	switch {
+	case !ok:
+		if worktrees, err = cache.Worktrees(ctx, info.CommonDir); err != nil {
+			return status, nil
+		}
+	case !fresh:
+		status.Refreshing = startCacheRefresh() == nil
+	}
+
+	for _, wt := range worktrees {
+		if inWorktreeDir(kohDir, wt.Path) {
+			status.Worktrees++
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+// newDashboardRepo creates a repository with a .kohconfig and one koh
+// worktree, and changes into it
+func newDashboardRepo(t testing.TB) string {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	runGit(t, "worktree", "add", "-q", "-b", "feat-a", filepath.Join(".koh", "feat-a"))
+	testutil.WriteFile(t, repo, ".kohconfig", "{}")
+	return repo
+}
+
+// runGit runs git in the current directory
+func runGit(t testing.TB, args ...string) {
+	t.Helper()
+	testutil.Git(t, ".", args...)
+}
+
+func TestLoadDashboardStatus(t *testing.T) {
+	repo := newDashboardRepo(t)
+	ctx := context.Background()
+
+	refreshes := 0
+	original := startCacheRefresh
+	startCacheRefresh = func() error {
+		refreshes++
+		return nil
+	}
+	t.Cleanup(func() { startCacheRefresh = original })
+
+	status, err := loadDashboardStatus(ctx)
+	if err != nil {
+		t.Fatalf("loadDashboardStatus() failed: %v", err)
+	}
+	want := dashboardStatus{RepoName: filepath.Base(repo), Worktrees: 1, Current: "main", Configured: true}
+	if *status != want {
+		t.Errorf("Expected %+v, got %+v", want, *status)
+	}
+
+	// A new worktree makes the cached list stale: the old count is shown
+	// while the cache refreshes in the background
+	runGit(t, "worktree", "add", "-q", "-b", "feat-b", filepath.Join(".koh", "feat-b"))
+	status, err = loadDashboardStatus(ctx)
+	if err != nil {
+		t.Fatalf("loadDashboardStatus() failed: %v", err)
+	}
+	if status.Worktrees != 1 || !status.Refreshing || refreshes != 1 {
+		t.Errorf("Expected the cached count while refreshing, got %+v after %d refreshes", *status, refreshes)
+	}
+
+	t.Chdir(filepath.Join(repo, ".koh", "feat-a"))
+	status, err = loadDashboardStatus(ctx)
+	if err != nil {
+		t.Fatalf("loadDashboardStatus() failed: %v", err)
+	}
+	if status.Current != "feat-a" || status.RepoName != "feat-a" || !status.Configured {
+		t.Errorf("Expected the status of feat-a, got %+v", *status)
+	}
+}
+
+func TestLoadDashboardStatusOutsideRepo(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(t.TempDir()))
+
+	if _, err := loadDashboardStatus(context.Background()); err == nil {
+		t.Error("Expected an error outside a repository")
+	}
+}
+
+func BenchmarkLoadDashboardStatus(b *testing.B) {
+	newDashboardRepo(b)
+	ctx := context.Background()
+
+	for b.Loop() {
+		if _, err := loadDashboardStatus(ctx); err != nil {
+			b.Fatalf("loadDashboardStatus() failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+// idleWindows returns the worktrees whose window hasn't been touched for
+// idleFor, leaving out keep and the session's current window. A window is
+// touched when it shows output (including what's typed into it) or when its
+// worktree gets a commit; lastCommits maps worktrees to their last commit.
+func idleWindows(windows []tmux.WindowActivity, lastCommits map[string]time.Time, keep string, now time.Time, idleFor time.Duration) []string {
+	idle := []string{}
+	for _, w := range windows {
+		if w.Worktree == keep || w.Active {
+			continue
+		}
+		touched := w.Activity
+		if lastCommits[w.Worktree].After(touched) {
+			touched = lastCommits[w.Worktree]
+		}
+		if now.Sub(touched) >= idleFor {
+			idle = append(idle, w.Worktree)
+		}
+	}
+	sort.Strings(idle)
+	return idle
+}
+
+// detachIdleWindows moves the repository's windows that have been idle for
+// idleFor to the tmux.DetachedSession session, or closes them with
+// closeWindows, and returns the worktrees whose window went away. keep is
+// the worktree just switched to. Failures are reported as warnings.
+func detachIdleWindows(ctx context.Context, p *output.Printer, mainRepoRoot, keep string, idleFor time.Duration, closeWindows bool) []string {
+	repoName, err := git.GetRepoName()
+	if err != nil {
+		p.Warn("Failed to get repository name: %v", err)
+		return nil
+	}
+	windows, err := tmux.WindowActivitiesWithContext(ctx, repoName)
+	if err != nil {
+		p.Warn("%v", err)
+		return nil
+	}
+
+	lastCommits := make(map[string]time.Time, len(windows))
+	for _, w := range windows {
+		path := kohWorktreePath(mainRepoRoot, w.Worktree)
+		if w.Worktree == mainCheckoutName {
+			_, path = resolveMainCheckout(mainRepoRoot)
+		}
+		// Worktrees without commits are judged by their window alone
+		if t, err := git.LastCommitTimeWithContext(ctx, path); err == nil {
+			lastCommits[w.Worktree] = t
+		}
+	}
+
+	gone := []string{}
+	for _, name := range idleWindows(windows, lastCommits, keep, time.Now(), idleFor) {
+		label := worktreeLabel(mainRepoRoot, name)
+		if name == mainCheckoutName {
+			label = "the main checkout"
+		}
+		windowName := tmux.WindowName(repoName, name)
+		if closeWindows {
+			if err := tmux.CloseWindow(windowName, name); err != nil {
+				p.Warn("Failed to close the window of %s: %v", label, err)
+				continue
+			}
+			p.Info("Closed the idle window of %s", label)
+		} else {
+			if err := tmux.DetachWindowWithContext(ctx, windowName); err != nil {
+				p.Warn("Failed to detach the window of %s: %v", label, err)
+				continue
+			}
+			p.Info("Moved the idle window of %s to the %s session", label, tmux.DetachedSession)
+		}
+		gone = append(gone, name)
+	}
+	return gone
+}
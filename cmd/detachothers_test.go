@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestIdleWindows(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+	windows := []tmux.WindowActivity{
+		{Worktree: "idle", Activity: daysAgo(10)},
+		{Worktree: "busy", Activity: daysAgo(1)},
+		{Worktree: "committed", Activity: daysAgo(10)},
+		{Worktree: "target", Activity: daysAgo(30)},
+		{Worktree: "current", Activity: daysAgo(30), Active: true},
+		{Worktree: "edge", Activity: daysAgo(7)},
+	}
+	lastCommits := map[string]time.Time{"committed": daysAgo(2), "idle": daysAgo(20)}
+
+	got := idleWindows(windows, lastCommits, "target", now, 7*24*time.Hour)
+	if want := []string{"edge", "idle"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// Answers to the dirty worktree prompt
+const (
+	dirtyKeep   = "k"
+	dirtyStash  = "s"
+	dirtyCommit = "c"
+	dirtyAbort  = "a"
+)
+
+// dirtySwitchMode returns the dirty_switch setting of the global config.
+// Problems with the setting are reported by 'koh doctor'; until they're fixed
+// koh warns.
+func dirtySwitchMode() string {
+	g, err := config.LoadGlobal()
+	if err != nil {
+		return config.DirtySwitchWarn
+	}
+	mode, _ := g.DirtySwitchMode()
+	return mode
+}
+
+// parseDirtyAnswer maps an answer to the dirty worktree prompt to a choice,
+// keeping the changes by default
+func parseDirtyAnswer(answer string) (string, bool) {
+	switch strings.ToLower(answer) {
+	case "", "k", "keep":
+		return dirtyKeep, true
+	case "s", "stash":
+		return dirtyStash, true
+	case "c", "commit":
+		return dirtyCommit, true
+	case "a", "abort":
+		return dirtyAbort, true
+	}
+	return "", false
+}
+
+// currentCheckoutName returns the name of the worktree the current directory
+// belongs to, or mainCheckoutName for the main checkout
+func currentCheckoutName(info *git.RepoInfo) string {
+	if !info.InWorktree() {
+		return mainCheckoutName
+	}
+	return filepath.Base(info.TopLevel)
+}
+
+// checkDirtyBeforeSwitch looks for uncommitted changes in the worktree the
+// current directory belongs to before switching to target, as configured by
+// dirty_switch. It warns, or asks whether to keep, stash or commit the
+// changes, and returns errAborted when the user calls the switch off.
+// Without a terminal to ask on, and in JSON mode, asking falls back to a
+// warning.
+func checkDirtyBeforeSwitch(ctx context.Context, p *output.Printer, target string) error {
+	mode := dirtySwitchMode()
+	if mode == config.DirtySwitchIgnore {
+		return nil
+	}
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+	current := currentCheckoutName(info)
+	if current == target {
+		return nil
+	}
+	if dirty, err := git.IsDirty(info.TopLevel); err != nil || !dirty {
+		return nil
+	}
+
+	if mode == config.DirtySwitchWarn || p.IsJSON() || !stdinIsTerminal() {
+		p.Warn("%s has uncommitted changes; they stay there while you work in %s", current, target)
+		return nil
+	}
+
+	for {
+		answer, err := p.Prompt(stdin, "%s has uncommitted changes. [k]eep them and switch, [s]tash them, [c]ommit them as WIP or [a]bort? [K/s/c/a] ", current)
+		if err != nil {
+			return errAborted
+		}
+		choice, ok := parseDirtyAnswer(answer)
+		if !ok {
+			continue
+		}
+
+		switch choice {
+		case dirtyStash, dirtyCommit:
+			if choice == dirtyCommit {
+				warnReadonlyCommit(p, current)
+			}
+			if _, err := saveWIP(ctx, info, choice == dirtyStash, wipMessageFor("switched to "+target)); err != nil {
+				return err
+			}
+			p.Info("Saved the changes in %s; run 'koh unwip' there to bring them back", current)
+		case dirtyAbort:
+			return errAborted
+		}
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestParseDirtyAnswer(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{answer: "", want: dirtyKeep, ok: true},
+		{answer: "K", want: dirtyKeep, ok: true},
+		{answer: "stash", want: dirtyStash, ok: true},
+		{answer: "c", want: dirtyCommit, ok: true},
+		{answer: "a", want: dirtyAbort, ok: true},
+		{answer: "x", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			got, ok := parseDirtyAnswer(tt.answer)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestCheckDirtyBeforeSwitch(t *testing.T) {
+	repo := newDashboardRepo(t)
+	worktree := filepath.Join(repo, ".koh", "feat-a")
+	t.Chdir(worktree)
+	ctx := context.Background()
+	p := output.New(io.Discard, output.Human)
+
+	configDir := t.TempDir()
+	t.Setenv("KOH_CONFIG_DIR", configDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"dirty_switch": "ask"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+
+	oldStdin, oldTerminal := stdin, stdinIsTerminal
+	t.Cleanup(func() { stdin, stdinIsTerminal = oldStdin, oldTerminal })
+	stdinIsTerminal = func() bool { return true }
+
+	makeDirty := func() {
+		if err := os.WriteFile(filepath.Join(worktree, "notes.txt"), []byte("todo"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	gitOutput := func(args ...string) string {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	makeDirty()
+
+	stdin = strings.NewReader("a\n")
+	if err := checkDirtyBeforeSwitch(ctx, p, "feat-b"); !errors.Is(err, errAborted) {
+		t.Errorf("Expected abort, got %v", err)
+	}
+
+	stdin = strings.NewReader("\n")
+	if err := checkDirtyBeforeSwitch(ctx, p, "feat-a"); err != nil {
+		t.Errorf("Expected no prompt when staying in the worktree, got %v", err)
+	}
+
+	stdin = strings.NewReader("s\n")
+	if err := checkDirtyBeforeSwitch(ctx, p, "feat-b"); err != nil {
+		t.Fatalf("checkDirtyBeforeSwitch() failed: %v", err)
+	}
+	if status := gitOutput("status", "--porcelain"); status != "" {
+		t.Errorf("Expected the changes to be stashed, got status %q", status)
+	}
+	if stashes := gitOutput("stash", "list"); !strings.Contains(stashes, "WIP: switched to feat-b") {
+		t.Errorf("Expected a WIP stash, got %q", stashes)
+	}
+
+	makeDirty()
+	stdin = strings.NewReader("c\n")
+	if err := checkDirtyBeforeSwitch(ctx, p, "main"); err != nil {
+		t.Fatalf("checkDirtyBeforeSwitch() failed: %v", err)
+	}
+	if subject := gitOutput("log", "-1", "--format=%s"); subject != "WIP: switched to main" {
+		t.Errorf("Expected a WIP commit, got %q", subject)
+	}
+	if recorded := loadRecordedWorktrees()["feat-a"]; recorded == nil || len(recorded.WIP) != 2 {
+		t.Errorf("Expected both saves to be recorded for 'koh unwip', got %+v", recorded)
+	}
+
+	// Clean worktrees switch without a prompt
+	stdin = strings.NewReader("a\n")
+	if err := checkDirtyBeforeSwitch(ctx, p, "feat-b"); err != nil {
+		t.Errorf("Expected a clean worktree to switch, got %v", err)
+	}
+}
@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the koh setup for problems",
+	Long: `Check that recent enough git and tmux are installed, the configuration is
+valid and the repository is in a state koh expects.
+
+Some problems have a safe fix: the worktree directory (.koh by default) not
+being ignored by git, a setup script that isn't executable, stale worktree
+entries whose directories are gone and recorded state for worktrees that no
+longer exist. Pass --fix to apply these fixes after the checks are listed;
+pruning stale worktree entries asks first, like other destructive
+operations, unless --yes is given.
+
+Worktrees in the worktree directory that koh has no record of, such as ones
+created with plain git, are listed as a note; 'koh import' adopts them.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var (
+	// doctorFix applies safe fixes for the problems found
+	doctorFix bool
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Apply safe fixes for the problems found")
+}
+
+// doctorCheck is the outcome of a single doctor check
+type doctorCheck struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Message  string `json:"message"`
+	Fixable  bool   `json:"fixable"`
+	Fixed    bool   `json:"fixed,omitempty"`
+	FixError string `json:"fix_error,omitempty"`
+
+	// fix remediates the problem; nil when there is no safe fix
+	fix func(ctx context.Context) error
+}
+
+// doctorResult is the machine-readable result of 'koh doctor'
+type doctorResult struct {
+	Checks []doctorCheck `json:"checks"`
+}
+
+// problems returns the number of checks that failed and weren't fixed
+func (r doctorResult) problems() int {
+	n := 0
+	for _, c := range r.Checks {
+		if !c.OK && !c.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+// passed returns a successful check
+func passed(name, message string) doctorCheck {
+	return doctorCheck{Name: name, OK: true, Message: message}
+}
+
+// failed returns a failed check, fixable when fix is non-nil
+func failed(name, message string, fix func(ctx context.Context) error) doctorCheck {
+	return doctorCheck{Name: name, Message: message, Fixable: fix != nil, fix: fix}
+}
+
+// checkTool checks that a required program is on PATH and recent enough.
+// versionFlag makes the program print its version.
+func checkTool(ctx context.Context, name, versionFlag, minVersion string) doctorCheck {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return failed(name, name+" not found in PATH", nil)
+	}
+
+	//nolint:gosec // G204: name and versionFlag are constants
+	output, err := exec.CommandContext(ctx, path, versionFlag).Output()
+	if err != nil {
+		return failed(name, fmt.Sprintf("failed to run %s %s: %v", name, versionFlag, err), nil)
+	}
+	version := parseToolVersion(string(output))
+	if version == "" {
+		// Development builds such as "tmux master" have no version number
+		return passed(name, fmt.Sprintf("%s (unknown version)", path))
+	}
+	if !versionAtLeast(version, minVersion) {
+		return failed(name, fmt.Sprintf("%s %s is older than the required %s", name, version, minVersion), nil)
+	}
+	return passed(name, fmt.Sprintf("%s %s", name, version))
+}
+
+// checkConfig lints the configuration
+func checkConfig() doctorCheck {
+	exists, err := config.ConfigExists()
+	if err != nil || !exists {
+		return failed("config", "no .kohconfig found, run 'koh init'", nil)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return failed("config", err.Error(), nil)
+	}
+	if warnings := lintConfig(cfg); len(warnings) > 0 {
+		return failed("config", fmt.Sprintf("%d problem(s), run 'koh config validate' for details", len(warnings)), nil)
+	}
+	return passed("config", "no problems found")
+}
+
+// checkGlobalConfig checks that the global config can be read and its
+// settings are valid
+func checkGlobalConfig() doctorCheck {
+	g, err := config.LoadGlobal()
+	if err != nil {
+		return failed("global_config", err.Error(), nil)
+	}
+	policy, err := g.ValidationPolicy()
+	if err != nil {
+		return failed("global_config", err.Error(), nil)
+	}
+	if _, err := g.EnterOpensMenu(); err != nil {
+		return failed("global_config", err.Error(), nil)
+	}
+	if _, err := g.DirtySwitchMode(); err != nil {
+		return failed("global_config", err.Error(), nil)
+	}
+	if err := g.ValidateAliases(); err != nil {
+		return failed("global_config", err.Error(), nil)
+	}
+	if shadowed := shadowedAliases(g); len(shadowed) > 0 {
+		return failed("global_config", fmt.Sprintf("alias %q is hidden by the built-in command of the same name", shadowed[0]), nil)
+	}
+	return passed("global_config", fmt.Sprintf("name validation policy is %s", policy))
+}
+
+// checkKohIgnored checks that git ignores the worktree directory, so
+// worktrees don't show up as untracked files in the main checkout
+func checkKohIgnored(ctx context.Context, mainRepoRoot, commonDir string) doctorCheck {
+	rel, err := filepath.Rel(mainRepoRoot, config.WorktreeDir(mainRepoRoot))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return passed("ignore", "worktree directory is outside the repository")
+	}
+	rel = filepath.ToSlash(rel)
+	if git.IsIgnoredWithContext(ctx, mainRepoRoot, rel+"/") {
+		return passed("ignore", rel+" is ignored by git")
+	}
+	return failed("ignore", rel+" is not ignored by git", func(context.Context) error {
+		return git.AddExclude(commonDir, "/"+rel+"/")
+	})
+}
+
+// executableMode adds execute permission wherever mode grants read permission
+func executableMode(mode os.FileMode) os.FileMode {
+	return mode | (mode&0444)>>2
+}
+
+// checkSetupScript checks that the configured setup script can be run
+func checkSetupScript(mainRepoRoot string) doctorCheck {
+	cfg, err := config.Load()
+	if err != nil || cfg.SetupScript == "" {
+		return passed("setup_script", "no setup script configured")
+	}
+
+	path := cfg.SetupScript
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(mainRepoRoot, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return failed("setup_script", cfg.SetupScript+" does not exist", nil)
+	}
+	if info.Mode()&0111 != 0 {
+		return passed("setup_script", cfg.SetupScript+" is executable")
+	}
+	return failed("setup_script", cfg.SetupScript+" is not executable", func(context.Context) error {
+		return os.Chmod(path, executableMode(info.Mode().Perm()))
+	})
+}
+
+// checkPrunableWorktrees checks for worktrees whose directories were deleted
+// without 'koh cleanup'. Pruning them is confirmed through p, since git
+// forgets them for good.
+func checkPrunableWorktrees(p *output.Printer, worktrees []git.Worktree) doctorCheck {
+	var names []string
+	for _, wt := range worktrees {
+		if wt.Prunable {
+			names = append(names, filepath.Base(wt.Path))
+		}
+	}
+	if len(names) == 0 {
+		return passed("worktrees", "no stale worktree entries")
+	}
+	return failed("worktrees", "stale worktree entries: "+strings.Join(names, ", "), func(ctx context.Context) error {
+		op := destructiveOp{What: "Prune the git records of worktrees whose directories were deleted", Items: names}
+		if err := confirmDestructive(p, op, false); err != nil {
+			return err
+		}
+		if err := git.PruneWorktreesWithContext(ctx); err != nil {
+			return err
+		}
+		invalidateWorktreeCache()
+		return nil
+	})
+}
+
+// staleStateEntries returns the names of worktrees with recorded state that
+// are not live koh worktrees in the worktree directory kohDir
+func staleStateEntries(s *state.State, kohDir string, worktrees []git.Worktree) []string {
+	live := map[string]bool{mainCheckoutName: true}
+	for _, wt := range worktrees {
+		if !wt.Prunable && inWorktreeDir(kohDir, wt.Path) {
+			live[filepath.Base(wt.Path)] = true
+		}
+	}
+
+	var stale []string
+	for name := range s.Worktrees {
+		if !live[name] {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// unmanagedWorktrees returns the names of worktrees in the worktree directory
+// kohDir that koh has no record of, i.e. ones created with plain
+// 'git worktree add'
+func unmanagedWorktrees(s *state.State, kohDir string, worktrees []git.Worktree) []string {
+	var unmanaged []string
+	for _, wt := range worktrees {
+		if wt.Prunable || !inWorktreeDir(kohDir, wt.Path) {
+			continue
+		}
+		if name := filepath.Base(wt.Path); s.Worktrees[name] == nil {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+	return unmanaged
+}
+
+// loadInventoriedState loads the recorded state of the repository. The first
+// time, it records every worktree already in the worktree directory: koh
+// created those before it kept records, and they would otherwise all be
+// reported as created outside koh.
+func loadInventoriedState(commonDir string, worktrees []git.Worktree) (*state.State, error) {
+	s, err := state.Load(commonDir)
+	if err != nil || s.Inventoried {
+		return s, err
+	}
+
+	kohDir := config.WorktreeDir(filepath.Dir(commonDir))
+	err = state.Update(commonDir, func(s *state.State) error {
+		for _, wt := range worktrees {
+			if !wt.Prunable && inWorktreeDir(kohDir, wt.Path) && s.Worktrees[filepath.Base(wt.Path)] == nil {
+				s.Worktree(filepath.Base(wt.Path)).Branch = displayBranch(wt)
+			}
+		}
+		s.Inventoried = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state.Load(commonDir)
+}
+
+// checkUnmanaged notes the worktrees in the worktree directory koh has no
+// record of, i.e. ones added with plain git since koh took inventory
+func checkUnmanaged(commonDir string, worktrees []git.Worktree) doctorCheck {
+	s, err := loadInventoriedState(commonDir, worktrees)
+	if err != nil {
+		return failed("adoption", err.Error(), nil)
+	}
+
+	unmanaged := unmanagedWorktrees(s, config.WorktreeDir(filepath.Dir(commonDir)), worktrees)
+	if len(unmanaged) == 0 {
+		return passed("adoption", "all worktrees are managed by koh")
+	}
+	return passed("adoption", fmt.Sprintf("no koh record of %s; 'koh import <worktree-name>' adopts one", strings.Join(unmanaged, ", ")))
+}
+
+// checkState checks for recorded state about worktrees that no longer exist
+func checkState(commonDir string, worktrees []git.Worktree) doctorCheck {
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return failed("state", err.Error(), nil)
+	}
+
+	stale := staleStateEntries(s, config.WorktreeDir(filepath.Dir(commonDir)), worktrees)
+	if len(stale) == 0 {
+		return passed("state", "no stale state entries")
+	}
+	return failed("state", "state recorded for removed worktrees: "+strings.Join(stale, ", "), func(context.Context) error {
+		return state.Update(commonDir, func(s *state.State) error {
+			for _, name := range stale {
+				delete(s.Worktrees, name)
+			}
+			return nil
+		})
+	})
+}
+
+// renderDoctorCheck renders a check as a single line for humans
+func renderDoctorCheck(c doctorCheck) string {
+	switch {
+	case c.Fixed:
+		return fmt.Sprintf("  %s %s %s", styles.SuccessMessage.Render(styles.IconCheck), styles.Key.Render(c.Name+":"), c.Message+" (fixed)")
+	case c.OK:
+		return fmt.Sprintf("  %s %s %s", styles.SuccessMessage.Render(styles.IconCheck), styles.Key.Render(c.Name+":"), styles.Muted.Render(c.Message))
+	}
+
+	line := fmt.Sprintf("  %s %s %s", styles.WarningMessage.Render("⚠"), styles.Key.Render(c.Name+":"), c.Message)
+	if c.FixError != "" {
+		line += styles.ErrorMessage.Render(" (fix failed: " + c.FixError + ")")
+	} else if c.Fixable {
+		line += styles.Muted.Render(" (fixable)")
+	}
+	return line
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	ctx := context.Background()
+	p := newPrinter(cmd)
+
+	result := doctorResult{Checks: []doctorCheck{
+		checkTool(ctx, "git", "--version", minGitVersion),
+		checkTool(ctx, "tmux", "-V", minTmuxVersion),
+		checkGlobalConfig(),
+	}}
+
+	if git.IsGitRepo() {
+		mainRepoRoot, err := git.GetMainRepoRoot()
+		if err != nil {
+			return fmt.Errorf("failed to get main repo root: %w", err)
+		}
+		commonDir, err := git.GetCommonDir()
+		if err != nil {
+			return err
+		}
+		// Read worktrees uncached: stale entries are what we're looking for
+		worktrees, err := git.ListWorktreesWithContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		result.Checks = append(result.Checks,
+			checkConfig(),
+			checkKohIgnored(ctx, mainRepoRoot, commonDir),
+			checkSetupScript(mainRepoRoot),
+			checkPrunableWorktrees(p, worktrees),
+			checkState(commonDir, worktrees),
+			checkUnmanaged(commonDir, worktrees),
+		)
+	}
+
+	fixable, fixed := 0, 0
+	for i := range result.Checks {
+		c := &result.Checks[i]
+		if c.OK || c.fix == nil {
+			continue
+		}
+		fixable++
+		if !doctorFix {
+			continue
+		}
+		if err := c.fix(ctx); err != nil {
+			c.FixError = err.Error()
+			continue
+		}
+		c.Fixed = true
+		fixed++
+	}
+
+	if fixed > 0 {
+		// Fixes such as making the setup script executable also resolve config warnings
+		for i, c := range result.Checks {
+			if c.Name == "config" {
+				result.Checks[i] = checkConfig()
+			}
+		}
+	}
+
+	problems := result.problems()
+	if err := p.Result(result, func(w io.Writer) {
+		fprintln(w, "\n"+styles.RenderTitle(styles.IconConfig+" Koh Doctor"))
+		for _, c := range result.Checks {
+			fprintln(w, renderDoctorCheck(c))
+		}
+		fprintln(w)
+		switch {
+		case problems == 0:
+			fprintln(w, styles.RenderSuccess("Everything looks good"))
+		case fixable > 0 && !doctorFix:
+			fprintln(w, styles.WarningMessage.Render(fmt.Sprintf("Found %d problem(s); run 'koh doctor --fix' to fix %d of them", problems, fixable)))
+		default:
+			fprintln(w, styles.WarningMessage.Render(fmt.Sprintf("Found %d problem(s) that need attention", problems)))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if problems > 0 {
+		// The result already lists the problems
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return silentFailure(fmt.Errorf("doctor found %d problem(s)", problems))
+	}
+	return nil
+}
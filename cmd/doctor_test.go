@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/state"
+)
+
+func TestExecutableMode(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want os.FileMode
+	}{
+		{0644, 0755},
+		{0600, 0700},
+		{0640, 0750},
+		{0755, 0755},
+	}
+
+	for _, tt := range tests {
+		if got := executableMode(tt.mode); got != tt.want {
+			t.Errorf("Expected executableMode(%o) = %o, got %o", tt.mode, tt.want, got)
+		}
+	}
+}
+
+func TestStaleStateEntries(t *testing.T) {
+	s := &state.State{Worktrees: map[string]*state.Worktree{
+		"live":    {},
+		"gone":    {},
+		"pruned":  {},
+		"main":    {},
+		"another": {},
+	}}
+	worktrees := []git.Worktree{
+		{Path: "/repo"},
+		{Path: "/repo/.koh/live"},
+		{Path: "/repo/.koh/pruned", Prunable: true},
+	}
+
+	got := staleStateEntries(s, "/repo/.koh", worktrees)
+	want := []string{"another", "gone", "pruned"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected stale entries %v, got %v", want, got)
+	}
+}
+
+func TestCheckPrunableWorktrees(t *testing.T) {
+	p := output.New(io.Discard, output.Human)
+	ok := checkPrunableWorktrees(p, []git.Worktree{{Path: "/repo/.koh/a"}})
+	if !ok.OK {
+		t.Errorf("Expected check to pass without prunable worktrees, got %+v", ok)
+	}
+
+	stale := checkPrunableWorktrees(p, []git.Worktree{{Path: "/repo/.koh/a"}, {Path: "/repo/.koh/b", Prunable: true}})
+	if stale.OK || !stale.Fixable {
+		t.Errorf("Expected a fixable failure, got %+v", stale)
+	}
+	if stale.Message != "stale worktree entries: b" {
+		t.Errorf("Unexpected message: %q", stale.Message)
+	}
+}
+
+func TestCheckPrunableWorktreesFixNeedsConsent(t *testing.T) {
+	repo := newDashboardRepo(t)
+	if err := os.RemoveAll(filepath.Join(repo, ".koh", "feat-a")); err != nil {
+		t.Fatalf("Failed to delete the worktree: %v", err)
+	}
+	oldTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	t.Cleanup(func() { stdinIsTerminal = oldTerminal })
+
+	p := output.New(io.Discard, output.Human)
+	ctx := context.Background()
+	prunable := func() git.Worktree {
+		t.Helper()
+		worktrees, err := git.ListWorktreesWithContext(ctx)
+		if err != nil || len(worktrees) != 2 {
+			t.Fatalf("ListWorktreesWithContext() = %v, %v", worktrees, err)
+		}
+		return worktrees[1]
+	}
+
+	check := checkPrunableWorktrees(p, []git.Worktree{prunable()})
+	if err := check.fix(ctx); err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("Expected pruning without a terminal to need --yes, got %v", err)
+	}
+	if !prunable().Prunable {
+		t.Fatal("Expected the worktree entry to be kept without consent")
+	}
+
+	assumeYes = true
+	t.Cleanup(func() { assumeYes = false })
+	if err := check.fix(ctx); err != nil {
+		t.Fatalf("Expected --yes to prune, got %v", err)
+	}
+	if worktrees, _ := git.ListWorktreesWithContext(ctx); len(worktrees) != 1 {
+		t.Errorf("Expected the stale entry to be pruned, got %v", worktrees)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		min     string
+		want    bool
+	}{
+		{"2.43.0", "2.17", true},
+		{"2.17", "2.17", true},
+		{"2.9.5", "2.17", false},
+		{"3.3", "3.0", true},
+		{"2.9", "3.0", false},
+		{"3", "3.0", true},
+	}
+
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.min); got != tt.want {
+			t.Errorf("Expected versionAtLeast(%q, %q) = %v, got %v", tt.version, tt.min, tt.want, got)
+		}
+	}
+}
+
+func TestParseToolVersion(t *testing.T) {
+	tests := map[string]string{
+		"git version 2.43.0\n":                 "2.43.0",
+		"git version 2.39.3 (Apple Git-145)\n": "2.39.3",
+		"tmux 3.3a\n":                          "3.3",
+		"tmux next-3.5\n":                      "3.5",
+		"tmux master\n":                        "",
+	}
+
+	for output, want := range tests {
+		if got := parseToolVersion(output); got != want {
+			t.Errorf("Expected parseToolVersion(%q) = %q, got %q", output, want, got)
+		}
+	}
+}
+
+func TestUnmanagedWorktrees(t *testing.T) {
+	s := &state.State{Worktrees: map[string]*state.Worktree{"made-by-koh": {}}}
+	worktrees := []git.Worktree{
+		{Path: "/repo"},
+		{Path: "/repo/.koh/made-by-koh"},
+		{Path: "/repo/.koh/raw-git"},
+		{Path: "/repo/.koh/deleted", Prunable: true},
+		{Path: "/elsewhere/checkout"},
+	}
+
+	got := unmanagedWorktrees(s, "/repo/.koh", worktrees)
+	want := []string{"raw-git"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected unmanaged worktrees %v, got %v", want, got)
+	}
+}
+
+func TestLoadInventoriedState(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	repo := newDashboardRepo(t)
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+	load := func() []string {
+		t.Helper()
+		worktrees, err := git.ListWorktrees()
+		if err != nil {
+			t.Fatalf("ListWorktrees() failed: %v", err)
+		}
+		s, err := loadInventoriedState(commonDir, worktrees)
+		if err != nil {
+			t.Fatalf("loadInventoriedState() failed: %v", err)
+		}
+		return unmanagedWorktrees(s, filepath.Join(repo, ".koh"), worktrees)
+	}
+
+	// feat-a predates koh's records, so it's taken as koh's own
+	if got := load(); len(got) != 0 {
+		t.Errorf("Expected existing worktrees to be recorded on first load, got unmanaged %v", got)
+	}
+
+	runGit(t, "worktree", "add", "-q", "-b", "raw", filepath.Join(".koh", "raw"))
+	if got, want := load(), []string{"raw"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected worktrees added later to be unmanaged (%v), got %v", want, got)
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/diskusage"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Show how much disk space each worktree takes up",
+	Long: `Show how much disk space each koh worktree takes up, biggest first,
+with the largest directory inside it, such as node_modules, and the total.
+
+Set disk_quota in .kohconfig to be warned about worktrees that grow too big:
+
+  "disk_quota": {"worktree": "2GB", "total": "20GB"}
+
+'koh du' marks the worktrees over the quota, and 'koh status' and 'koh new'
+warn when a quota is exceeded. They reuse sizes measured in the last few
+minutes, since measuring big worktrees takes a while; 'koh du' always
+measures afresh.
+
+With --top, only the biggest worktrees are listed: 10, or as many as given
+with --top=N.`,
+	Args: cobra.NoArgs,
+	RunE: runDu,
+}
+
+// duTop limits 'koh du' to the biggest worktrees, 0 for all of them
+var duTop int
+
+// diskUsageCacheTTL is how long 'koh status' and 'koh new' reuse measured sizes
+const diskUsageCacheTTL = 10 * time.Minute
+
+func init() {
+	duCmd.Flags().IntVar(&duTop, "top", 0, "List only the biggest worktrees (10 unless given as --top=N)")
+	duCmd.Flags().Lookup("top").NoOptDefVal = "10"
+	rootCmd.AddCommand(duCmd)
+}
+
+// worktreeDiskUsage is the space a koh worktree takes up
+type worktreeDiskUsage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	diskusage.Usage
+	// OverQuota is set when the worktree takes up more than disk_quota.worktree
+	OverQuota bool `json:"over_quota,omitempty"`
+}
+
+// duResult is the machine-readable result of 'koh du'
+type duResult struct {
+	// Worktrees are sorted biggest first
+	Worktrees []worktreeDiskUsage `json:"worktrees"`
+	// TotalBytes is what all worktrees take up, including ones --top left out
+	TotalBytes int64 `json:"total_bytes"`
+	// WorktreeQuota and TotalQuota are the configured quotas in bytes, 0 when unset
+	WorktreeQuota int64 `json:"worktree_quota,omitempty"`
+	TotalQuota    int64 `json:"total_quota,omitempty"`
+	// OverTotal is set when all worktrees together exceed disk_quota.total
+	OverTotal bool `json:"over_total,omitempty"`
+}
+
+// measureWorktrees returns the space each worktree takes up, by name.
+// Worktrees that can't be measured are left out.
+func measureWorktrees(ctx context.Context, worktrees []git.Worktree) map[string]diskusage.Usage {
+	usage := map[string]diskusage.Usage{}
+	for _, wt := range worktrees {
+		if u, err := diskusage.MeasureWithContext(ctx, wt.Path); err == nil {
+			usage[filepath.Base(wt.Path)] = u
+		}
+	}
+	return usage
+}
+
+// recentWorktreeUsage is measureWorktrees for commands that only warn about
+// quotas: sizes measured in the last diskUsageCacheTTL are reused, unless a
+// worktree was added since, e.g. with plain git
+func recentWorktreeUsage(ctx context.Context, worktrees []git.Worktree) map[string]diskusage.Usage {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return measureWorktrees(ctx, worktrees)
+	}
+	measure := func() (map[string]diskusage.Usage, error) {
+		return measureWorktrees(ctx, worktrees), nil
+	}
+	usage, _ := cache.Recent(commonDir, cache.KindDiskUsage, diskUsageCacheTTL, measure)
+	for _, wt := range worktrees {
+		if _, ok := usage[filepath.Base(wt.Path)]; !ok {
+			_ = cache.Forget(commonDir, cache.KindDiskUsage)
+			usage, _ = cache.Recent(commonDir, cache.KindDiskUsage, diskUsageCacheTTL, measure)
+			break
+		}
+	}
+	return usage
+}
+
+// loadDiskQuota returns the configured quotas in bytes, 0 for the ones not
+// set or when there is no valid configuration
+func loadDiskQuota() (worktree, total int64) {
+	cfg, err := config.Load()
+	if err != nil {
+		return 0, 0
+	}
+	worktree, total, err = cfg.DiskQuota.Limits()
+	if err != nil {
+		return 0, 0
+	}
+	return worktree, total
+}
+
+// newDuResult sizes up worktrees against the quotas, biggest first
+func newDuResult(worktrees []git.Worktree, usage map[string]diskusage.Usage, worktreeQuota, totalQuota int64) duResult {
+	result := duResult{Worktrees: []worktreeDiskUsage{}, WorktreeQuota: worktreeQuota, TotalQuota: totalQuota}
+	for _, wt := range worktrees {
+		name := filepath.Base(wt.Path)
+		u, ok := usage[name]
+		if !ok {
+			continue
+		}
+		result.TotalBytes += u.Bytes
+		result.Worktrees = append(result.Worktrees, worktreeDiskUsage{
+			Name:      name,
+			Path:      wt.Path,
+			Usage:     u,
+			OverQuota: worktreeQuota > 0 && u.Bytes > worktreeQuota,
+		})
+	}
+	result.OverTotal = totalQuota > 0 && result.TotalBytes > totalQuota
+
+	sort.SliceStable(result.Worktrees, func(i, j int) bool {
+		return result.Worktrees[i].Bytes > result.Worktrees[j].Bytes
+	})
+	return result
+}
+
+// quotaWarnings describes the quotas r exceeds
+func quotaWarnings(r duResult) []string {
+	var warnings []string
+	for _, wt := range r.Worktrees {
+		if !wt.OverQuota {
+			continue
+		}
+		warning := fmt.Sprintf("%s takes up %s, over the disk quota of %s per worktree", wt.Name, diskusage.Format(wt.Bytes), diskusage.Format(r.WorktreeQuota))
+		if wt.Largest != "" {
+			warning += fmt.Sprintf(" (%s: %s)", wt.Largest, diskusage.Format(wt.LargestBytes))
+		}
+		warnings = append(warnings, warning)
+	}
+	if r.OverTotal {
+		warnings = append(warnings, totalQuotaWarning(r))
+	}
+	return warnings
+}
+
+// totalQuotaWarning says that all worktrees together exceed the total quota
+func totalQuotaWarning(r duResult) string {
+	return fmt.Sprintf("Worktrees take up %s, over the total disk quota of %s; 'koh du --top' shows the biggest", diskusage.Format(r.TotalBytes), diskusage.Format(r.TotalQuota))
+}
+
+// warnDiskQuota warns about the quotas worktrees exceed, using recently
+// measured sizes. Nothing is measured when no quota is configured.
+func warnDiskQuota(ctx context.Context, p *output.Printer, worktrees []git.Worktree) {
+	worktreeQuota, totalQuota := loadDiskQuota()
+	if worktreeQuota == 0 && totalQuota == 0 {
+		return
+	}
+	result := newDuResult(worktrees, recentWorktreeUsage(ctx, worktrees), worktreeQuota, totalQuota)
+	for _, warning := range quotaWarnings(result) {
+		p.Warn("%s", warning)
+	}
+}
+
+func runDu(cmd *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+	if duTop < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+
+	ctx := context.Background()
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	worktreeQuota, totalQuota := loadDiskQuota()
+	result := newDuResult(worktrees, measureWorktrees(ctx, worktrees), worktreeQuota, totalQuota)
+	if duTop > 0 && len(result.Worktrees) > duTop {
+		result.Worktrees = result.Worktrees[:duTop]
+	}
+
+	return newPrinter(cmd).Result(result, func(w io.Writer) {
+		fprintln(w, "\n"+styles.RenderTitle(styles.IconTree+" Koh Worktree Disk Usage"))
+		if len(result.Worktrees) == 0 {
+			fprintln(w, styles.Muted.Render("No koh worktrees found"))
+			return
+		}
+		for _, wt := range result.Worktrees {
+			line := fmt.Sprintf("%8s  %s", diskusage.Format(wt.Bytes), wt.Name)
+			if wt.Largest != "" {
+				line += styles.Muted.Render(fmt.Sprintf("  %s: %s", wt.Largest, diskusage.Format(wt.LargestBytes)))
+			}
+			if wt.OverQuota {
+				line += " " + styles.MarkerOverQuota.Render()
+			}
+			fprintln(w, line)
+		}
+
+		total := fmt.Sprintf("%8s  total", diskusage.Format(result.TotalBytes))
+		if result.TotalQuota > 0 {
+			total += " of " + diskusage.Format(result.TotalQuota)
+		}
+		if result.OverTotal {
+			total = styles.WarningMessage.Render(total + ", over quota")
+		}
+		fprintln(w, "\n"+total)
+		fprintln(w)
+	})
+}
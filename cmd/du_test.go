@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/diskusage"
+	"github.com/bshakr/koh/internal/git"
+)
+
+func TestNewDuResult(t *testing.T) {
+	worktrees := []git.Worktree{
+		{Path: "/repo/.koh/small"},
+		{Path: "/repo/.koh/big"},
+		{Path: "/repo/.koh/unmeasured"},
+		{Path: "/repo/.koh/medium"},
+	}
+	usage := map[string]diskusage.Usage{
+		"small":  {Bytes: 100 << 20},
+		"big":    {Bytes: 3 << 30, Largest: "node_modules", LargestBytes: 2 << 30},
+		"medium": {Bytes: 1 << 30},
+	}
+
+	tests := []struct {
+		name          string
+		worktreeQuota int64
+		totalQuota    int64
+		wantOver      []string
+		wantOverTotal bool
+		wantWarnings  []string
+	}{
+		{name: "no quota"},
+		{
+			name:          "worktree quota",
+			worktreeQuota: 2 << 30,
+			wantOver:      []string{"big"},
+			wantWarnings:  []string{"big takes up 3.0 GB, over the disk quota of 2.0 GB per worktree (node_modules: 2.0 GB)"},
+		},
+		{
+			name:          "total quota",
+			totalQuota:    4 << 30,
+			wantOverTotal: true,
+			wantWarnings:  []string{"Worktrees take up 4.1 GB, over the total disk quota of 4.0 GB"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := newDuResult(worktrees, usage, tt.worktreeQuota, tt.totalQuota)
+
+			var order, over []string
+			for _, wt := range result.Worktrees {
+				order = append(order, wt.Name)
+				if wt.OverQuota {
+					over = append(over, wt.Name)
+				}
+			}
+			if got := strings.Join(order, " "); got != "big medium small" {
+				t.Errorf("Expected the measured worktrees biggest first, got %s", got)
+			}
+			if strings.Join(over, " ") != strings.Join(tt.wantOver, " ") {
+				t.Errorf("Expected %v over the quota, got %v", tt.wantOver, over)
+			}
+			if result.TotalBytes != 4196<<20 || result.OverTotal != tt.wantOverTotal {
+				t.Errorf("Expected a total of %d, over quota: %v, got %d, %v", 4196<<20, tt.wantOverTotal, result.TotalBytes, result.OverTotal)
+			}
+
+			warnings := quotaWarnings(result)
+			if len(warnings) != len(tt.wantWarnings) {
+				t.Fatalf("Expected warnings %q, got %q", tt.wantWarnings, warnings)
+			}
+			for i, want := range tt.wantWarnings {
+				if !strings.HasPrefix(warnings[i], want) {
+					t.Errorf("Expected a warning starting with %q, got %q", want, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStatusLineOverQuota(t *testing.T) {
+	line := renderStatusLine(worktreeStatus{Name: "big", Branch: "big", OverQuota: true})
+	if !strings.Contains(line, "[over disk quota]") {
+		t.Errorf("Expected the status line to flag the worktree, got %q", line)
+	}
+}
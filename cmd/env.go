@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/bshakr/koh/internal/config"
+)
+
+// configEnv returns the variables of the env setting for the worktree called
+// name at path, whose values can refer to KOH_WORKTREE, KOH_WORKTREE_PATH and
+// the variables in env that koh already set for it, such as KOH_PORT
+func configEnv(cfg *config.Config, name, path string, env []string) []string {
+	return cfg.ExpandEnv(append([]string{"KOH_WORKTREE=" + name, "KOH_WORKTREE_PATH=" + path}, env...))
+}
+
+// sortedEnvNames returns the names of the env setting in sorted order
+func sortedEnvNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// eventsToStdout is the --events-json value that streams events to stdout
+const eventsToStdout = "-"
+
+// addEventsFlag registers --events-json on a command. Without a value it
+// streams to stdout; --events-json=<file> appends to a file instead.
+func addEventsFlag(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVar(target, "events-json", "", "Stream progress events as JSON lines to stdout, or to a file with --events-json=<file>")
+	cmd.Flags().Lookup("events-json").NoOptDefVal = eventsToStdout
+}
+
+// newEventsPrinter returns the output printer for a command that can stream
+// progress events to target, the --events-json value. When events go to
+// stdout, all other output moves to stderr so stdout holds only events. The
+// returned function closes the events file.
+func newEventsPrinter(cmd *cobra.Command, target string) (*output.Printer, func(), error) {
+	switch target {
+	case "":
+		return newPrinter(cmd), func() {}, nil
+	case eventsToStdout:
+		p := output.New(cmd.ErrOrStderr(), printerFormat())
+		p.StreamEvents(cmd.OutOrStdout())
+		return p, func() {}, nil
+	}
+
+	//nolint:gosec // G304: the events file is chosen by the user
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open events file: %w", err)
+	}
+	p := newPrinter(cmd)
+	p.StreamEvents(f)
+	return p, func() { _ = f.Close() }, nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewEventsPrinter(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     func(dir string) string
+		wantStdout string
+		wantStderr string
+		wantFile   bool
+	}{
+		{
+			name:       "no events",
+			target:     func(string) string { return "" },
+			wantStdout: "Creating feature\n",
+		},
+		{
+			name:       "events to stdout move output to stderr",
+			target:     func(string) string { return eventsToStdout },
+			wantStdout: `"type":"info"`,
+			wantStderr: "Creating feature\n",
+		},
+		{
+			name:       "events to a file",
+			target:     func(dir string) string { return filepath.Join(dir, "events.jsonl") },
+			wantStdout: "Creating feature\n",
+			wantFile:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			target := tt.target(t.TempDir())
+
+			p, closeEvents, err := newEventsPrinter(cmd, target)
+			if err != nil {
+				t.Fatalf("newEventsPrinter() failed: %v", err)
+			}
+			p.Info("Creating feature")
+			closeEvents()
+
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("Expected stdout to contain %q, got %q", tt.wantStdout, stdout.String())
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("Expected stderr %q, got %q", tt.wantStderr, stderr.String())
+			}
+			if tt.wantFile {
+				data, err := os.ReadFile(target)
+				if err != nil {
+					t.Fatalf("Expected an events file: %v", err)
+				}
+				if !strings.Contains(string(data), `"type":"info"`) {
+					t.Errorf("Expected an info event in the file, got %q", data)
+				}
+			}
+		})
+	}
+}
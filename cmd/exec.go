@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [worktree-name...] -- <command> [args...]",
+	Short: "Run a command in several worktrees and report the results",
+	Long: `Run a command in each selected worktree and show a pass/fail matrix with
+exit codes and durations, like a small local CI across branches.
+
+Select worktrees by name, with --match (a glob matched against worktree
+names and branches, e.g. 'feature-*'), or all of them with --all. A single
+command argument is run by the shell, so it can use pipes and &&; several
+arguments are run directly. The command gets KOH_WORKTREE set to the
+worktree's name.
+
+Output is captured; the output of failing runs is shown after the matrix,
+and --json includes the output of every run. koh exits non-zero if any run
+failed.
+
+Examples:
+  koh exec --all -- go test ./...
+  koh exec --match 'feature-*' 'npm ci && npm test'
+  koh exec auth-fix billing -- make lint`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+var (
+	// execAll runs the command in every koh worktree
+	execAll bool
+
+	// execMatch selects worktrees whose name or branch matches a glob
+	execMatch string
+
+	// execJobs is how many worktrees run the command at the same time
+	execJobs int
+)
+
+// execFailureOutputLines bounds the output shown for each failing run
+const execFailureOutputLines = 20
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().BoolVar(&execAll, "all", false, "Run in every koh worktree")
+	execCmd.Flags().StringVar(&execMatch, "match", "", "Run in worktrees whose name or branch matches a glob")
+	execCmd.Flags().IntVarP(&execJobs, "jobs", "j", 1, "Number of worktrees to run in parallel")
+}
+
+// execRun is the outcome of running the command in one worktree
+type execRun struct {
+	Name       string `json:"name"`
+	Branch     string `json:"branch"`
+	Path       string `json:"path"`
+	Passed     bool   `json:"passed"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Output     string `json:"output"`
+	// Error is set when the command couldn't be started at all
+	Error string `json:"error,omitempty"`
+
+	duration time.Duration
+}
+
+// selectExecTargets picks the worktrees to run in from names, a glob
+// pattern or all of them. Unknown names are an error.
+func selectExecTargets(worktrees []git.Worktree, names []string, pattern string, all bool) ([]git.Worktree, error) {
+	if all {
+		return worktrees, nil
+	}
+	if len(names) == 0 && pattern == "" {
+		return nil, fmt.Errorf("no worktrees selected\nName worktrees, or use --match or --all")
+	}
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid --match pattern %q: %w", pattern, err)
+		}
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	var targets []git.Worktree
+	for _, wt := range worktrees {
+		name := filepath.Base(wt.Path)
+		matched := false
+		if pattern != "" {
+			nameMatch, _ := path.Match(pattern, name)
+			branchMatch, _ := path.Match(pattern, wt.Branch)
+			matched = nameMatch || branchMatch
+		}
+		if wanted[name] || matched {
+			targets = append(targets, wt)
+			delete(wanted, name)
+		}
+	}
+
+	for _, name := range names {
+		if wanted[name] {
+			return nil, fmt.Errorf("worktree %s does not exist", name)
+		}
+	}
+	return targets, nil
+}
+
+// execCommand builds the command to run in a worktree. A single argument is
+// run by the shell; several are run directly.
+func execCommand(ctx context.Context, command []string) *exec.Cmd {
+	if len(command) == 1 {
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		//nolint:gosec // G204: the user runs their own command in their own worktrees
+		return exec.CommandContext(ctx, shell, "-c", command[0])
+	}
+	//nolint:gosec // G204: the user runs their own command in their own worktrees
+	return exec.CommandContext(ctx, command[0], command[1:]...)
+}
+
+// runInWorktree runs command in a worktree and records the outcome
+func runInWorktree(ctx context.Context, wt git.Worktree, command []string) execRun {
+	run := execRun{Name: filepath.Base(wt.Path), Branch: displayBranch(wt), Path: wt.Path}
+
+	c := execCommand(ctx, command)
+	c.Dir = wt.Path
+	c.Env = append(os.Environ(), "KOH_WORKTREE="+run.Name)
+
+	start := time.Now()
+	output, err := c.CombinedOutput()
+	run.duration = time.Since(start)
+	run.DurationMS = run.duration.Milliseconds()
+	run.Output = string(output)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		run.Passed = true
+	case errors.As(err, &exitErr):
+		run.ExitCode = exitErr.ExitCode()
+	default:
+		run.ExitCode = -1
+		run.Error = err.Error()
+	}
+	return run
+}
+
+// runExecMatrix runs command in every target with up to jobs at a time.
+// Results keep the order of targets.
+func runExecMatrix(ctx context.Context, targets []git.Worktree, command []string, jobs int) []execRun {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	runs := make([]execRun, len(targets))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, wt := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, wt git.Worktree) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			runs[i] = runInWorktree(ctx, wt, command)
+		}(i, wt)
+	}
+	wg.Wait()
+	return runs
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderExecMatrix renders the results as an aligned pass/fail table
+func renderExecMatrix(w io.Writer, runs []execRun) {
+	nameWidth, branchWidth := len("WORKTREE"), len("BRANCH")
+	for _, r := range runs {
+		nameWidth = max(nameWidth, len(r.Name))
+		branchWidth = max(branchWidth, len(r.Branch))
+	}
+
+	fprintln(w, styles.Muted.Render(fmt.Sprintf("  %-*s  %-*s  %-6s  %4s  %s", nameWidth, "WORKTREE", branchWidth, "BRANCH", "RESULT", "EXIT", "TIME")))
+	for _, r := range runs {
+		result := styles.SuccessMessage.Render(styles.IconCheck + " pass")
+		if !r.Passed {
+			result = styles.ErrorMessage.Render(styles.IconCross + " fail")
+		}
+		fprintln(w, fmt.Sprintf("  %-*s  %-*s  %s  %4d  %s",
+			nameWidth, r.Name, branchWidth, r.Branch, result, r.ExitCode, r.duration.Round(time.Millisecond)))
+	}
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	names, command := args, []string(nil)
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		names, command = args[:dash], args[dash:]
+	} else if execAll || execMatch != "" {
+		// Without "--", all arguments are the command when worktrees are selected by flag
+		names, command = nil, args
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("no command given\nSeparate it from worktree names with --, e.g. koh exec %s -- make test", strings.Join(names, " "))
+	}
+
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	targets, err := selectExecTargets(worktrees, names, execMatch, execAll)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no worktrees match")
+	}
+
+	p := newPrinter(cmd)
+	p.Info("Running %q in %d worktree(s)...", strings.Join(command, " "), len(targets))
+	runs := runExecMatrix(ctx, targets, command, execJobs)
+
+	failed := 0
+	for _, r := range runs {
+		if !r.Passed {
+			failed++
+		}
+	}
+
+	if err := p.Result(runs, func(w io.Writer) {
+		fprintln(w)
+		renderExecMatrix(w, runs)
+		for _, r := range runs {
+			if r.Passed {
+				continue
+			}
+			fprintln(w, "\n"+styles.ErrorMessage.Render(fmt.Sprintf("%s %s (exit %d):", styles.IconCross, r.Name, r.ExitCode)))
+			if r.Error != "" {
+				fprintln(w, r.Error)
+			} else if out := strings.TrimSpace(r.Output); out != "" {
+				fprintln(w, lastLines(out, execFailureOutputLines))
+			}
+		}
+		fprintln(w)
+		if failed == 0 {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Passed in all %d worktree(s)", len(runs))))
+		} else {
+			fprintln(w, styles.RenderError(fmt.Sprintf("Failed in %d of %d worktree(s)", failed, len(runs))))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		// The matrix already reports the failures
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return silentFailure(fmt.Errorf("command exited non-zero in %d worktree(s)", failed))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+)
+
+func TestSelectExecTargets(t *testing.T) {
+	worktrees := []git.Worktree{
+		{Path: "/repo/.koh/feature-a", Branch: "feature-a"},
+		{Path: "/repo/.koh/feature-b", Branch: "feature-b"},
+		{Path: "/repo/.koh/fix", Branch: "bugfix/login"},
+	}
+
+	names := func(wts []git.Worktree) []string {
+		var out []string
+		for _, wt := range wts {
+			out = append(out, filepath.Base(wt.Path))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		names   []string
+		pattern string
+		all     bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", all: true, want: []string{"feature-a", "feature-b", "fix"}},
+		{name: "by name", names: []string{"fix"}, want: []string{"fix"}},
+		{name: "name glob", pattern: "feature-*", want: []string{"feature-a", "feature-b"}},
+		{name: "branch glob", pattern: "bugfix/*", want: []string{"fix"}},
+		{name: "names and glob", names: []string{"fix"}, pattern: "feature-a", want: []string{"feature-a", "fix"}},
+		{name: "nothing selected", wantErr: true},
+		{name: "unknown name", names: []string{"nope"}, wantErr: true},
+		{name: "bad pattern", pattern: "[", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectExecTargets(worktrees, tt.names, tt.pattern, tt.all)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectExecTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if gotNames := names(got); len(gotNames) != len(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, gotNames)
+			} else {
+				for i := range gotNames {
+					if gotNames[i] != tt.want[i] {
+						t.Errorf("Expected %v, got %v", tt.want, gotNames)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunExecMatrix(t *testing.T) {
+	dir := t.TempDir()
+	targets := []git.Worktree{
+		{Path: filepath.Join(dir), Branch: "one"},
+		{Path: filepath.Join(dir), Branch: "two"},
+	}
+
+	runs := runExecMatrix(context.Background(), targets, []string{`test "$KOH_WORKTREE" = "` + filepath.Base(dir) + `" && echo ok`}, 2)
+	for _, r := range runs {
+		if !r.Passed || r.ExitCode != 0 || r.Output != "ok\n" {
+			t.Errorf("Expected a passing run with output ok, got %+v", r)
+		}
+	}
+
+	failing := runExecMatrix(context.Background(), targets[:1], []string{"sh", "-c", "exit 3"}, 1)
+	if failing[0].Passed || failing[0].ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %+v", failing[0])
+	}
+
+	missing := runExecMatrix(context.Background(), targets[:1], []string{"koh-missing-binary-xyz", "arg"}, 1)
+	if missing[0].Passed || missing[0].Error == "" {
+		t.Errorf("Expected a start error for a missing binary, got %+v", missing[0])
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// stashPick is the --from-stash value given without an entry. '?' can't
+// appear in a ref name, so it never clashes with a real entry.
+const stashPick = "?"
+
+// stashRefPattern matches stash entries such as "stash@{2}"
+var stashRefPattern = regexp.MustCompile(`^stash@\{\d+\}$`)
+
+// checkStashArgs rejects a stash entry passed as an argument. Since
+// --from-stash takes an optional value, cobra reads
+// 'koh new feat --from-stash stash@{1}' as a bare flag and two arguments, so
+// the entry has to be attached with '='.
+func checkStashArgs(args []string) error {
+	for _, arg := range args {
+		if stashRefPattern.MatchString(arg) {
+			return fmt.Errorf("%s looks like a stash entry\nPass it as --from-stash=%s", arg, arg)
+		}
+	}
+	return nil
+}
+
+// pickStash asks which stash entry to apply to the new worktree. Without a
+// terminal to ask on, and in JSON mode, it takes the latest entry, as
+// 'git stash apply' does.
+func pickStash(ctx context.Context, p *output.Printer) (string, error) {
+	stashes, err := git.ListStashesWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(stashes) == 0 {
+		return "", fmt.Errorf("there are no stash entries to apply")
+	}
+	if len(stashes) == 1 || p.IsJSON() || !stdinIsTerminal() {
+		return stashes[0].Ref, nil
+	}
+
+	now := time.Now()
+	for i, stash := range stashes {
+		p.Info("  %d  %s  %s", i, stash.Message, commitAge(stash.CreatedAt, now))
+	}
+	for {
+		answer, err := p.Prompt(stdin, "Apply which stash? [0-%d, default 0] ", len(stashes)-1)
+		if err != nil {
+			return "", errAborted
+		}
+		if choice, ok := parseStashChoice(answer, len(stashes)); ok {
+			return stashes[choice].Ref, nil
+		}
+	}
+}
+
+// parseStashChoice maps an answer to the stash prompt to an index into n
+// entries, taking the latest by default
+func parseStashChoice(answer string, n int) (int, bool) {
+	if answer == "" {
+		return 0, true
+	}
+	choice, err := strconv.Atoi(answer)
+	if err != nil || choice < 0 || choice >= n {
+		return 0, false
+	}
+	return choice, true
+}
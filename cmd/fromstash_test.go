@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestCheckStashArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "name only", args: []string{"feat"}},
+		{name: "entry after name", args: []string{"feat", "stash@{2}"}, wantErr: true},
+		{name: "entry before name", args: []string{"stash@{0}", "feat"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStashArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkStashArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "--from-stash=stash@{") {
+				t.Errorf("Expected the error to show --from-stash=<entry>, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseStashChoice(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   int
+		ok     bool
+	}{
+		{answer: "", want: 0, ok: true},
+		{answer: "2", want: 2, ok: true},
+		{answer: "3", ok: false},
+		{answer: "-1", ok: false},
+		{answer: "latest", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			got, ok := parseStashChoice(tt.answer, 3)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestPickStash(t *testing.T) {
+	repo := newDashboardRepo(t)
+	ctx := context.Background()
+	p := output.New(io.Discard, output.Human)
+
+	oldStdin, oldTerminal := stdin, stdinIsTerminal
+	t.Cleanup(func() { stdin, stdinIsTerminal = oldStdin, oldTerminal })
+
+	if _, err := pickStash(ctx, p); err == nil || !strings.Contains(err.Error(), "no stash entries") {
+		t.Errorf("Expected an error without stash entries, got %v", err)
+	}
+
+	for _, note := range []string{"first", "second"} {
+		if err := os.WriteFile(filepath.Join(repo, "notes.txt"), []byte(note), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		runGit(t, "stash", "push", "--include-untracked", "-m", note)
+	}
+
+	stdinIsTerminal = func() bool { return false }
+	if got, err := pickStash(ctx, p); err != nil || got != "stash@{0}" {
+		t.Errorf("Expected the latest entry without a terminal, got %q, %v", got, err)
+	}
+
+	stdinIsTerminal = func() bool { return true }
+	stdin = strings.NewReader("1\n")
+	if got, err := pickStash(ctx, p); err != nil || got != "stash@{1}" {
+		t.Errorf("Expected the chosen entry, got %q, %v", got, err)
+	}
+}
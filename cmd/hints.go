@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bshakr/koh/internal/styles"
+	"golang.org/x/term"
+)
+
+// showHints is whether koh suggests what to do next, from hints in the
+// global config
+var showHints = true
+
+// hintContext describes how a command went, for picking what to suggest
+// next
+type hintContext struct {
+	// command is the command that ran, named as in telemetry (e.g.
+	// "config validate", or "koh" for the dashboard)
+	command string
+	// err is the error the command failed with, nil when it succeeded
+	err error
+	// configured is whether the repository has a .kohconfig (dashboard only)
+	configured bool
+	// worktrees is the number of koh worktrees (dashboard only)
+	worktrees int
+}
+
+// hintRule suggests a next step after a command succeeded or, with failed,
+// after it failed
+type hintRule struct {
+	command string
+	failed  bool
+	// when narrows the rule down further, nil to always apply
+	when func(c hintContext) bool
+	hint string
+}
+
+// doctorHint is the suggestion after a command failed in a way a broken
+// setup can explain
+const doctorHint = "Run 'koh doctor' to check tmux, git and your configuration"
+
+// hintRules are the suggestions koh makes, the first matching rule winning
+var hintRules = []hintRule{
+	{command: "koh", when: func(c hintContext) bool { return !c.configured }, hint: "Run 'koh init' to set up your configuration first"},
+	{command: "koh", when: func(c hintContext) bool { return c.worktrees == 0 }, hint: "Run 'koh new feature-name' to create your first worktree"},
+	{command: "koh", hint: "Use 'koh list' to see all your worktrees"},
+	{command: "init", hint: "Run 'koh new feature-name' to create your first worktree"},
+	{command: "new", hint: "Push the branch and open a pull request when it's ready; 'koh ci' then tells you when it's safe to merge"},
+	{command: "new", failed: true, hint: doctorHint},
+	{command: "switch", failed: true, hint: doctorHint},
+	{command: "import", hint: "Run 'koh list' to see it alongside your other worktrees"},
+	{command: "cleanup", hint: "Run 'koh advise' to find other worktrees ready to clean up"},
+	{command: "readonly", hint: "Run 'koh new feature-name --stack-on <worktree-name>' to start work from a read-only worktree"},
+	{command: "pause", hint: "Run 'koh resume <worktree-name>' to bring its window back"},
+	{command: "wip", hint: "Run 'koh unwip' in the worktree to pick the work back up"},
+	{command: "config validate", failed: true, hint: "Fix the settings above, then run 'koh config validate' again"},
+}
+
+// nextHint returns the suggestion for what to do after a command, or ""
+// when there is none. Nothing is suggested after the user aborted.
+func nextHint(c hintContext) string {
+	if errors.Is(c.err, errAborted) {
+		return ""
+	}
+	for _, rule := range hintRules {
+		if rule.command != c.command || rule.failed != (c.err != nil) {
+			continue
+		}
+		if rule.when == nil || rule.when(c) {
+			return rule.hint
+		}
+	}
+	return ""
+}
+
+// printNextHint prints the suggestion for what to do after a command to w.
+// Hints are for people, so they're left out of JSON output, when stderr
+// isn't a terminal and when the global config turns them off.
+func printNextHint(w io.Writer, c hintContext) {
+	if !showHints || jsonOutput || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	if hint := nextHint(c); hint != "" {
+		_, _ = fmt.Fprintln(w, styles.Muted.Render("💡 "+hint))
+	}
+}
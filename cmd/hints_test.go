@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNextHint(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name string
+		ctx  hintContext
+		want string
+	}{
+		{name: "dashboard unconfigured", ctx: hintContext{command: "koh"}, want: "Run 'koh init' to set up your configuration first"},
+		{name: "dashboard without worktrees", ctx: hintContext{command: "koh", configured: true}, want: "Run 'koh new feature-name' to create your first worktree"},
+		{name: "dashboard", ctx: hintContext{command: "koh", configured: true, worktrees: 2}, want: "Use 'koh list' to see all your worktrees"},
+		{name: "new", ctx: hintContext{command: "new"}, want: "Push the branch and open a pull request when it's ready; 'koh ci' then tells you when it's safe to merge"},
+		{name: "failed new", ctx: hintContext{command: "new", err: failed}, want: doctorHint},
+		{name: "failed switch", ctx: hintContext{command: "switch", err: fmt.Errorf("wrapped: %w", failed)}, want: doctorHint},
+		{name: "switch", ctx: hintContext{command: "switch"}, want: ""},
+		{name: "aborted", ctx: hintContext{command: "new", err: errAborted}, want: ""},
+		{name: "no rule", ctx: hintContext{command: "version"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextHint(tt.ctx); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/snapshot"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+// recordCreated marks a worktree as created by koh with branch checked out,
+// opened at file and set up with profile, if any. Failures are ignored since
+// the worktree itself was created successfully.
+func recordCreated(worktreeName, branch, file, profile string) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+
+	_ = state.Update(commonDir, func(s *state.State) error {
+		wt := s.Worktree(worktreeName)
+		wt.CreatedAt = time.Now()
+		wt.Branch = branch
+		wt.File = file
+		wt.Profile = profile
+		return nil
+	})
+}
+
+// recordUsed marks a worktree as just switched to. Failures are ignored
+// since the switch itself succeeded.
+func recordUsed(worktreeName string) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+
+	_ = state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(worktreeName).UsedAt = time.Now()
+		return nil
+	})
+}
+
+func init() {
+	tmux.RecordedPaneIDs = recordedPaneIDs
+}
+
+// recordedPaneIDs returns the pane IDs recorded for a worktree's window by
+// pane number, nil when there are none
+func recordedPaneIDs(worktreeName string) []string {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return nil
+	}
+	return s.Worktree(worktreeName).PaneIDs
+}
+
+// recordPaneIDs records the panes of a worktree's new window by position, so
+// commands sent to a pane later reach it wherever the user moves it. It must
+// run before anything targets the window's panes, since IDs recorded for an
+// earlier window no longer apply.
+func recordPaneIDs(ctx context.Context, worktreeName string) {
+	window, err := tmux.WindowInfoWithContext(ctx, worktreeName)
+	if err != nil || window == nil {
+		return
+	}
+	setPaneIDs(worktreeName, window.Panes)
+}
+
+// renumberPane gives the pane numbered from the unused number to, e.g. so a
+// pane added for pane_commands[1] is pane 2 even when others were split off
+// by hand, and returns to
+func renumberPane(ctx context.Context, worktreeName string, from, to int) (int, error) {
+	ids, err := tmux.PaneIDsWithContext(ctx, worktreeName)
+	if err != nil {
+		return from, err
+	}
+	if from == to {
+		setPaneIDs(worktreeName, ids)
+		return to, nil
+	}
+	if from < 0 || from >= len(ids) || (to < len(ids) && ids[to] != "") {
+		return from, fmt.Errorf("can't renumber pane %d to %d", from, to)
+	}
+	for len(ids) <= to {
+		ids = append(ids, "")
+	}
+	ids[to], ids[from] = ids[from], ""
+	setPaneIDs(worktreeName, ids)
+	return to, nil
+}
+
+// setPaneIDs records a worktree's pane IDs by number. Failures are ignored,
+// leaving panes numbered by position.
+func setPaneIDs(worktreeName string, ids []string) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+	_ = state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(worktreeName).PaneIDs = ids
+		return nil
+	})
+}
+
+// recordPaneCommands adds the commands sent to a new window's panes to the
+// worktree's history. Failures are ignored since the history is a convenience.
+func recordPaneCommands(worktreeName string, cfg *config.Config) {
+	recordSentCommands(worktreeName, tmux.PaneCommands(cfg))
+}
+
+// recordSentCommands adds commands sent to a worktree's panes to its history
+func recordSentCommands(worktreeName string, commands []tmux.PaneCommand) {
+	if len(commands) == 0 {
+		return
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	_ = state.Update(commonDir, func(s *state.State) error {
+		wt := s.Worktree(worktreeName)
+		for _, pc := range commands {
+			wt.RecordPaneCommand(pc.Pane, pc.Command, now)
+		}
+		return nil
+	})
+}
+
+// recordRerun moves a re-run command to the end of the worktree's history
+func recordRerun(worktreeName string, pc state.PaneCommand) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+
+	_ = state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(worktreeName).RecordPaneCommand(pc.Pane, pc.Command, time.Now())
+		return nil
+	})
+}
+
+// lastPaneCommands returns the most recent command sent to each of a
+// worktree's panes, or an empty map when nothing was recorded
+func lastPaneCommands(worktreeName string) map[int]string {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return map[int]string{}
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return map[int]string{}
+	}
+	return s.Worktree(worktreeName).LastPaneCommands()
+}
+
+// recordedFile returns the file a worktree was opened at with
+// 'koh new --file', or "" when there is none
+func recordedFile(worktreeName string) string {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return ""
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return ""
+	}
+	return s.Worktree(worktreeName).File
+}
+
+// recordedProfile returns the profile a worktree was created with, if any
+func recordedProfile(worktreeName string) string {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return ""
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return ""
+	}
+	return s.Worktree(worktreeName).Profile
+}
+
+// worktreeProfileConfig returns the configuration for the window of a
+// worktree, applying the profile it was created with
+func worktreeProfileConfig(cfg *config.Config, worktreeName string) (*config.Config, error) {
+	profile := recordedProfile(worktreeName)
+	profileCfg, err := cfg.ForProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("worktree %s was created with profile %q: %w", worktreeName, profile, err)
+	}
+	return profileCfg, nil
+}
+
+// loadRecordedWorktrees returns what is recorded about each worktree, or
+// nil when no state is available
+func loadRecordedWorktrees() map[string]*state.Worktree {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return nil
+	}
+	return s.Worktrees
+}
+
+// recordPinned pins or unpins a worktree in 'koh list'
+func recordPinned(worktreeName string, pinned bool) error {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return err
+	}
+	return state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(worktreeName).Pinned = pinned
+		return nil
+	})
+}
+
+// recordNote attaches a note to a worktree, removing it when note is empty
+func recordNote(worktreeName, note string) error {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return err
+	}
+	return state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(worktreeName).Note = note
+		return nil
+	})
+}
+
+// loadPaneHistory returns the recorded pane commands of every worktree,
+// or nil when no state is available
+func loadPaneHistory() map[string][]state.PaneCommand {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil
+	}
+
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return nil
+	}
+
+	history := make(map[string][]state.PaneCommand, len(s.Worktrees))
+	for name, wt := range s.Worktrees {
+		history[name] = wt.PaneCommands
+	}
+	return history
+}
+
+// forgetWorktree drops everything recorded about a removed worktree
+func forgetWorktree(worktreeName string) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+	_ = snapshot.Remove(commonDir, worktreeName)
+
+	s, err := state.Load(commonDir)
+	if err != nil || s.Worktrees[worktreeName] == nil {
+		return
+	}
+	_ = state.Update(commonDir, func(s *state.State) error {
+		// Worktrees stacked on the removed one move down to its parent,
+		// keeping their base so a restack drops the removed branch's commits
+		if removed := s.Worktrees[worktreeName]; removed != nil {
+			for _, wt := range s.Worktrees {
+				if wt.Parent == worktreeName {
+					wt.Parent = removed.Parent
+				}
+			}
+		}
+		delete(s.Worktrees, worktreeName)
+		return nil
+	})
+}
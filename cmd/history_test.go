@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestRecordedPaneIDs(t *testing.T) {
+	newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+
+	if got := recordedPaneIDs("feat-a"); got != nil {
+		t.Errorf("Expected no pane IDs before any were recorded, got %v", got)
+	}
+
+	setPaneIDs("feat-a", []string{"%1", "", "%3"})
+	if want, got := []string{"%1", "", "%3"}, tmux.RecordedPaneIDs("feat-a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected tmux to see %v, got %v", want, got)
+	}
+
+	forgetWorktree("feat-a")
+	if got := recordedPaneIDs("feat-a"); got != nil {
+		t.Errorf("Expected the pane IDs to be forgotten with the worktree, got %v", got)
+	}
+}
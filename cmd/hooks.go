@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// hookEnv returns the environment describing a new worktree to its hooks
+func hookEnv(result *newResult) []string {
+	return []string{
+		"KOH_WORKTREE=" + result.Name,
+		"KOH_WORKTREE_PATH=" + result.Path,
+		"KOH_BRANCH=" + result.Branch,
+	}
+}
+
+// runPostCreateHooks runs the post_create hooks in a new worktree, in order,
+// and returns the ones that failed. A failing hook doesn't stop the rest,
+// since the worktree is already usable. Their output goes to stderr so that
+// --json output on stdout stays parseable (see output.Printer.CommandOutput).
+func runPostCreateHooks(ctx context.Context, p *output.Printer, hooks []string, result *newResult, env []string) []string {
+	var failed []string
+	for _, hook := range hooks {
+		if err := checkHookScript(hook, result.Path); err != nil {
+			p.Warn("Not running post_create hook %q: %v", hook, err)
+			failed = append(failed, hook)
+			continue
+		}
+		p.Info("Running post_create hook: %s", hook)
+		c := execCommand(ctx, []string{hook})
+		c.Dir = result.Path
+		c.Env = append(append(os.Environ(), env...), hookEnv(result)...)
+		c.Stdout = p.CommandOutput()
+		c.Stderr = p.CommandOutput()
+		if err := c.Run(); err != nil {
+			p.Warn("post_create hook %q failed: %v", hook, err)
+			failed = append(failed, hook)
+		}
+	}
+	return failed
+}
+
+// checkHookScript checks that a hook naming a script by a relative path,
+// such as "./bin/register-branch", runs one inside the worktree rather than
+// wherever a symlink or ".." leads
+func checkHookScript(hook, worktreePath string) error {
+	script := config.ScriptPath(hook)
+	if script == "" || filepath.IsAbs(script) {
+		return nil
+	}
+	if err := validation.ValidatePathWithinRepository(filepath.Join(worktreePath, script), worktreePath); err != nil {
+		return fmt.Errorf("script %s: %w", script, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestRunPostCreateHooks(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	dir := t.TempDir()
+	result := &newResult{Name: "feat-a", Path: dir, Branch: "feature/a"}
+	p := output.New(io.Discard, output.Human)
+
+	hooks := []string{
+		`echo "$KOH_WORKTREE $KOH_BRANCH $KOH_WORKTREE_PATH $KOH_SCRATCH" > first.txt`,
+		"exit 3",
+		"pwd > second.txt",
+	}
+	failed := runPostCreateHooks(context.Background(), p, hooks, result, []string{"KOH_SCRATCH=/tmp/scratch"})
+
+	if want := []string{"exit 3"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("Expected failed hooks %v, got %v", want, failed)
+	}
+
+	first, err := os.ReadFile(filepath.Join(dir, "first.txt"))
+	if err != nil {
+		t.Fatalf("Expected the first hook to run in the worktree: %v", err)
+	}
+	if want := "feat-a feature/a " + dir + " /tmp/scratch"; strings.TrimSpace(string(first)) != want {
+		t.Errorf("Expected %q, got %q", want, strings.TrimSpace(string(first)))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second.txt")); err != nil {
+		t.Errorf("Expected hooks after a failing one to run: %v", err)
+	}
+}
+
+func TestCheckHookScript(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	//nolint:gosec // G301: Test directory
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "bin", "shared")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hook    string
+		wantErr bool
+	}{
+		{hook: "./bin/register-branch \"$KOH_BRANCH\""},
+		{hook: "FOO=bar bin/register-branch"},
+		{hook: "make register"},
+		{hook: "/usr/local/bin/register-branch"},
+		{hook: "../register-branch", wantErr: true},
+		{hook: "./bin/shared/register-branch", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := checkHookScript(tt.hook, dir)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Expected error %v for %q, got %v", tt.wantErr, tt.hook, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <path-or-branch> [worktree-name]",
+	Short: "Adopt an existing git worktree as a koh worktree",
+	Long: `Adopt a worktree created with plain 'git worktree add', given by its path
+or by the branch checked out in it, so koh manages it like its own.
+
+A worktree outside the worktree directory (.koh by default) is moved into
+it with 'git worktree move', which keeps its files, untracked ones
+included, as they are. It is named after its directory unless a name is
+given. koh then records the worktree and, inside tmux, opens its window
+the way 'koh switch' does.
+
+Worktrees already in the worktree directory are adopted where they are.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runImport,
+}
+
+var (
+	// importNoTmux skips opening the worktree's window
+	importNoTmux bool
+	// importBackground opens the window without switching to it
+	importBackground bool
+)
+
+func init() {
+	importCmd.Flags().BoolVar(&importNoTmux, "no-tmux", false, "Don't open a tmux window for the worktree")
+	importCmd.Flags().BoolVar(&importBackground, "background", false, "Open the window without switching to it")
+	importCmd.Flags().StringVar(&repoDir, "repo", "", repoFlagUsage)
+	rootCmd.AddCommand(importCmd)
+}
+
+// importResult is the machine-readable result of 'koh import'
+type importResult struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Branch string `json:"branch,omitempty"`
+	// MovedFrom is where the worktree was before it was moved into the
+	// worktree directory
+	MovedFrom string `json:"moved_from,omitempty"`
+	Window    string `json:"window,omitempty"`
+}
+
+// findImportWorktree returns the worktree at the path arg or, when no such
+// path exists, the one with the branch arg checked out. mainRepoRoot is left
+// out, since the main checkout can't become a koh worktree.
+func findImportWorktree(worktrees []git.Worktree, mainRepoRoot, arg string) (*git.Worktree, error) {
+	if _, err := os.Stat(arg); err == nil {
+		if samePath(arg, mainRepoRoot) {
+			return nil, fmt.Errorf("%s is the main checkout, which can't be imported", arg)
+		}
+		for i := range worktrees {
+			if samePath(worktrees[i].Path, arg) {
+				return &worktrees[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not a worktree of this repository", arg)
+	}
+
+	for i := range worktrees {
+		if worktrees[i].Branch != arg {
+			continue
+		}
+		if samePath(worktrees[i].Path, mainRepoRoot) {
+			return nil, fmt.Errorf("branch %s is checked out in the main checkout, which can't be imported", arg)
+		}
+		return &worktrees[i], nil
+	}
+	return nil, fmt.Errorf("no worktree found at %s or with branch %s checked out\nUse 'koh new <name> --branch %s' to create one for a branch", arg, arg, arg)
+}
+
+// recordAdopted records a worktree created outside koh with branch checked
+// out. Failures are ignored since the worktree is in place either way.
+func recordAdopted(worktreeName, branch string) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return
+	}
+
+	_ = state.Update(commonDir, func(s *state.State) error {
+		wt := s.Worktree(worktreeName)
+		wt.AdoptedAt = time.Now()
+		wt.Branch = branch
+		return nil
+	})
+}
+
+// importWorktree adopts the worktree given by arg under name, or under the
+// name of its directory when name is "", moving it into the worktree
+// directory when it is elsewhere
+func importWorktree(ctx context.Context, arg, name string) (*importResult, error) {
+	mainRepoRoot, err := git.GetMainRepoRoot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get main repo root: %w", err)
+	}
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil, err
+	}
+	// Read worktrees uncached, so one added moments ago is found
+	worktrees, err := git.ListWorktreesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wt, err := findImportWorktree(worktrees, mainRepoRoot, arg)
+	if err != nil {
+		return nil, err
+	}
+	if wt.Prunable {
+		return nil, fmt.Errorf("the directory of worktree %s is missing\nRun 'koh doctor --fix' to remove its entry", wt.Path)
+	}
+
+	if name == "" {
+		name = filepath.Base(wt.Path)
+	}
+	if err := validation.ValidateWorktreeName(name); err != nil {
+		return nil, fmt.Errorf("invalid worktree name: %w", err)
+	}
+	if name == mainCheckoutName {
+		return nil, fmt.Errorf("%s is reserved for the main checkout\nPass another name: koh import %s <worktree-name>", name, arg)
+	}
+
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return nil, err
+	}
+	kohDir := config.WorktreeDir(mainRepoRoot)
+	if inWorktreeDir(kohDir, wt.Path) && s.Worktrees[filepath.Base(wt.Path)] != nil {
+		return nil, fmt.Errorf("worktree %s is already managed by koh", filepath.Base(wt.Path))
+	}
+
+	result := &importResult{Name: name, Path: kohWorktreePath(mainRepoRoot, name), Branch: wt.Branch}
+	if !samePath(wt.Path, result.Path) {
+		if _, err := os.Stat(result.Path); err == nil {
+			return nil, fmt.Errorf("worktree %s already exists\nPass another name: koh import %s <worktree-name>", worktreeLabel(mainRepoRoot, name), arg)
+		}
+		//nolint:gosec // G301: 0755 is standard permission for user directories
+		if err := os.MkdirAll(kohDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+		}
+		if err := git.MoveWorktreeWithContext(ctx, wt.Path, result.Path); err != nil {
+			return nil, err
+		}
+		result.MovedFrom = wt.Path
+	}
+
+	recordAdopted(name, wt.Branch)
+	invalidateWorktreeCache()
+	return result, nil
+}
+
+// pathWithin reports whether path is dir or inside it
+func pathWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+	// A path is taken relative to where koh was started, before --repo
+	// changes the directory
+	arg := args[0]
+	if _, err := os.Stat(arg); err == nil {
+		if abs, err := filepath.Abs(arg); err == nil {
+			arg = abs
+		}
+	}
+	cwd, _ := os.Getwd()
+
+	if err := resolveNestedRepo(p, repoDir); err != nil {
+		return err
+	}
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	unlock, err := lockRepo(p, commandLine(cmd.CommandPath(), args))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	result, err := importWorktree(ctx, arg, name)
+	if err != nil {
+		return err
+	}
+	if result.MovedFrom != "" {
+		p.Info("Moved %s to %s", result.MovedFrom, result.Path)
+		// The shell stays behind in the directory that moved
+		if cwd != "" && pathWithin(result.MovedFrom, cwd) {
+			p.Warn("Your shell is still in the old directory; run: cd %s", result.Path)
+		}
+	}
+
+	switch {
+	case importNoTmux:
+	case !tmux.IsInTmux():
+		p.Info("Run 'koh switch %s' from tmux to open its window", result.Name)
+	default:
+		if _, err := switchToWorktree(p, result.Name, false, importBackground); err != nil {
+			p.Warn("Failed to open the window of %s: %v", result.Name, err)
+			p.Info("Run 'koh switch %s' to try again", result.Name)
+		} else if repoName, err := git.GetRepoName(); err == nil {
+			result.Window = tmux.WindowName(repoName, result.Name)
+		}
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		fprintln(w, fmt.Sprintf("Imported %s as worktree %s", result.Path, result.Name))
+	})
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestFindImportWorktree(t *testing.T) {
+	repo := t.TempDir()
+	other := t.TempDir()
+	stray := t.TempDir()
+	worktrees := []git.Worktree{
+		{Path: repo, Branch: "main"},
+		{Path: other, Branch: "feature"},
+	}
+
+	tests := []struct {
+		arg      string
+		wantPath string
+		wantErr  string
+	}{
+		{arg: other, wantPath: other},
+		{arg: "feature", wantPath: other},
+		{arg: repo, wantErr: "is the main checkout"},
+		{arg: "main", wantErr: "checked out in the main checkout"},
+		{arg: stray, wantErr: "is not a worktree"},
+		{arg: "missing", wantErr: "no worktree found"},
+	}
+
+	for _, tt := range tests {
+		wt, err := findImportWorktree(worktrees, repo, tt.arg)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q for %s, got %v", tt.wantErr, tt.arg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %s to be found, got %v", tt.arg, err)
+			continue
+		}
+		if wt.Path != tt.wantPath {
+			t.Errorf("Expected %s for %s, got %s", tt.wantPath, tt.arg, wt.Path)
+		}
+	}
+}
+
+func TestImportWorktree(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	outside := filepath.Join(t.TempDir(), "spike")
+	testutil.Git(t, repo, "worktree", "add", "-b", "spike", outside)
+	testutil.WriteFile(t, outside, "notes.txt", "untracked")
+
+	result, err := importWorktree(context.Background(), "spike", "")
+	if err != nil {
+		t.Fatalf("importWorktree() failed: %v", err)
+	}
+	want := kohWorktreePath(repo, "spike")
+	if result.Path != want || result.MovedFrom != outside || result.Branch != "spike" {
+		t.Errorf("Expected spike to move from %s to %s, got %+v", outside, want, result)
+	}
+	if data, err := os.ReadFile(filepath.Join(want, "notes.txt")); err != nil || string(data) != "untracked" {
+		t.Errorf("Expected untracked files to move along, got %q (%v)", data, err)
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wt := s.Worktrees["spike"]; wt == nil || wt.AdoptedAt.IsZero() || wt.Branch != "spike" {
+		t.Errorf("Expected spike to be recorded as adopted, got %+v", wt)
+	}
+
+	if _, err := importWorktree(context.Background(), want, ""); err == nil || !strings.Contains(err.Error(), "already managed") {
+		t.Errorf("Expected importing spike again to fail, got %v", err)
+	}
+}
+
+func TestImportWorktreeInWorktreeDir(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	path := kohWorktreePath(repo, "plain")
+	testutil.Git(t, repo, "worktree", "add", "-b", "plain", path)
+
+	result, err := importWorktree(context.Background(), path, "")
+	if err != nil {
+		t.Fatalf("importWorktree() failed: %v", err)
+	}
+	if result.MovedFrom != "" || result.Name != "plain" {
+		t.Errorf("Expected plain to be adopted in place, got %+v", result)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/ports"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info [worktree-name]",
+	Short: "Show details about a worktree",
+	Long: `Show details about a koh worktree: its path, branch, HEAD commit,
+working tree state and tmux window.
+
+If no worktree name is provided and you're currently in a worktree,
+details for the current worktree are shown.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runInfo,
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+// worktreeDetails is the machine-readable result of 'koh info'
+type worktreeDetails struct {
+	worktreeStatus
+	Head   string `json:"head"`
+	Window string `json:"window,omitempty"`
+	Locked bool   `json:"locked"`
+	// Base is the branch the worktree targets, unset for stacked worktrees
+	Base *baseResult `json:"base,omitempty"`
+	// Ports are the ports reserved for the worktree (see ports in .kohconfig)
+	Ports []int `json:"ports,omitempty"`
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	var worktreeName string
+	if len(args) == 0 {
+		var err error
+		worktreeName, err = extractWorkTreeName()
+		if err != nil {
+			return fmt.Errorf("failed to extract worktree name: %w", err)
+		}
+	} else {
+		worktreeName = args[0]
+	}
+
+	// Validate worktree name for security
+	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
+		return fmt.Errorf("invalid worktree name: %w", err)
+	}
+
+	ctx := context.Background()
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	var currentPath string
+	if git.IsInWorktree() {
+		currentPath, _ = git.GetCurrentWorktreePath()
+	}
+
+	if mainRepoRoot, err := git.GetMainRepoRootOrCwd(); err == nil {
+		maybeAutoFetch(mainRepoRoot)
+	}
+
+	windows := loadWorktreeWindows(ctx)
+	for _, wt := range worktrees {
+		if filepath.Base(wt.Path) != worktreeName {
+			continue
+		}
+
+		details := worktreeDetails{
+			worktreeStatus: collectWorktreeStatus(wt, windows, currentPath),
+			Head:           wt.Head,
+			Locked:         wt.Locked,
+		}
+		if details.WindowOpen {
+			details.Window = windows[worktreeName]
+		}
+		if reserved, err := state.LoadPorts(); err == nil {
+			details.Ports = reserved.Reserved[wt.Path]
+		}
+		if wt.Branch != "" {
+			details.PR = forge.ForBranch(loadPullRequests(ctx), wt.Branch)
+			if recorded := loadRecordedWorktrees(); stackParents(recorded)[worktreeName] == "" {
+				if base, err := loadBase(ctx, recorded, wt, worktreeName); err == nil {
+					details.Base = &base
+				}
+			}
+		}
+
+		return newPrinter(cmd).Result(details, func(w io.Writer) {
+			fprintln(w, renderWorktreeDetails(details))
+		})
+	}
+
+	return fmt.Errorf("worktree %s does not exist", worktreeName)
+}
+
+// renderWorktreeDetails renders worktree details as a styled box
+func renderWorktreeDetails(d worktreeDetails) string {
+	var content strings.Builder
+	content.WriteString(styles.RenderKeyValue("Name", d.Name) + "\n")
+	content.WriteString(styles.RenderKeyValue("Path", d.Path) + "\n")
+	content.WriteString(styles.RenderKeyValue("Branch", d.Branch) + "\n")
+
+	head := d.Head
+	if len(head) > 12 {
+		head = head[:12]
+	}
+	content.WriteString(styles.RenderKeyValue("HEAD", head) + "\n")
+
+	state := styles.SuccessMessage.Render(styles.IconCheck + " Clean")
+	if d.Dirty {
+		state = styles.WarningMessage.Render(styles.IconDirty + " Uncommitted changes")
+	}
+	content.WriteString(styles.Key.Render("State:") + " " + state + "\n")
+
+	if d.Tracking != nil {
+		tracking := renderTracking(d.Tracking)
+		if tracking == "" {
+			tracking = "up to date"
+		}
+		content.WriteString(styles.RenderKeyValue("Upstream", tracking) + "\n")
+	}
+	if d.Base != nil {
+		base := d.Base.Base
+		if drift := renderTracking(d.Base.Drift); drift != "" {
+			base += " " + drift
+		}
+		content.WriteString(styles.RenderKeyValue("Base", base) + "\n")
+	}
+
+	window := styles.Muted.Render("Not open")
+	if d.WindowOpen {
+		window = d.Window
+	} else if !tmux.IsInTmux() {
+		window = styles.Muted.Render("Unknown (not in tmux)")
+	}
+	content.WriteString(styles.Key.Render("Window:") + " " + window)
+
+	if len(d.Ports) > 0 {
+		content.WriteString("\n" + styles.RenderKeyValue("Ports", ports.Format(d.Ports)))
+	}
+
+	if d.PR != nil {
+		content.WriteString("\n" + styles.Key.Render("PR:") + " " + renderPullRequest(d.PR))
+		content.WriteString("\n" + styles.Key.Render("URL:") + " " + styles.Muted.Render(d.PR.URL))
+	}
+
+	if d.Locked {
+		content.WriteString("\n" + styles.Key.Render("Locked:") + " yes")
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.Subtle).
+		Padding(0, 1).
+		Render(content.String())
+}
@@ -15,11 +15,20 @@ import (
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Interactive configuration setup",
-	Long:  `Run an interactive wizard to configure koh settings.`,
-	RunE:  runInit,
+	Long: `Run an interactive wizard to configure koh settings.
+
+With --profile, the wizard adds a profile to the existing .kohconfig, or
+replaces it, instead of the top-level setup script and pane commands.
+Create worktrees with it using 'koh new --profile'.`,
+	RunE: runInit,
 }
 
+// initProfile is the profile the wizard configures, "" for the top level
+var initProfile string
+
 func init() {
+	initCmd.Flags().StringVar(&initProfile, "profile", "", "Configure the named profile instead of the top-level setup")
+	initCmd.Flags().StringVar(&repoDir, "repo", "", repoFlagUsage)
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -41,15 +50,31 @@ type initModel struct {
 	config       *config.Config
 	step         step
 	choice       int // 0 = add pane, 1 = finish setup
+	// warnings are lint results for the configuration under review
+	warnings []config.Warning
+	// profile is the profile being configured, "" for the top level
+	profile string
 }
 
 func initialModel() initModel {
-	cfg := config.DefaultConfig()
+	return initialModelFor(config.DefaultConfig(), "")
+}
+
+// initialModelFor returns the wizard for the named profile of cfg, or for
+// its top level when profile is ""
+func initialModelFor(cfg *config.Config, profile string) initModel {
+	setupScript := cfg.SetupScript
+	if profile != "" {
+		setupScript = ""
+		if existing := cfg.Profiles[profile]; existing != nil {
+			setupScript = existing.SetupScript
+		}
+	}
 
 	// Setup script input
 	setupInput := textinput.New()
 	setupInput.Placeholder = "./bin/setup"
-	setupInput.SetValue(cfg.SetupScript)
+	setupInput.SetValue(setupScript)
 	setupInput.Focus()
 	setupInput.CharLimit = 100
 	setupInput.Width = 50
@@ -65,6 +90,7 @@ func initialModel() initModel {
 	return initModel{
 		step:         stepSetupScript,
 		config:       cfg,
+		profile:      profile,
 		setupInput:   setupInput,
 		paneInput:    paneInput,
 		paneCommands: []string{},
@@ -86,8 +112,6 @@ func (m initModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			switch m.step {
 			case stepSetupScript:
-				// Save setup script and move to choice
-				m.config.SetupScript = m.setupInput.Value()
 				m.step = stepAddPaneChoice
 				return m, nil
 
@@ -98,7 +122,8 @@ func (m initModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.paneInput.Focus()
 					m.step = stepPaneCommand
 				} else {
-					// User chose "Finish setup"
+					// User chose "Finish setup": lint before the user confirms
+					m.warnings = lintConfig(m.reviewedConfig())
 					m.step = stepConfirm
 				}
 				return m, nil
@@ -113,9 +138,19 @@ func (m initModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 
 			case stepConfirm:
-				// Save configuration (always overwrites existing config)
-				m.config.SetupScript = m.setupInput.Value()
-				m.config.PaneCommands = m.paneCommands
+				// Save configuration (always overwrites the top level or the profile)
+				if m.profile != "" {
+					if m.config.Profiles == nil {
+						m.config.Profiles = map[string]*config.Profile{}
+					}
+					m.config.Profiles[m.profile] = &config.Profile{
+						SetupScript:  m.setupInput.Value(),
+						PaneCommands: config.PlainPaneCommands(m.paneCommands),
+					}
+				} else {
+					m.config.SetupScript = m.setupInput.Value()
+					m.config.PaneCommands = config.PlainPaneCommands(m.paneCommands)
+				}
 
 				if err := m.config.Save(); err != nil {
 					m.err = err
@@ -148,6 +183,19 @@ func (m initModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// reviewedConfig returns the configuration the wizard's answers describe,
+// with the profile applied when configuring one
+func (m initModel) reviewedConfig() *config.Config {
+	cfg := &config.Config{
+		SetupScript:  m.setupInput.Value(),
+		PaneCommands: config.PlainPaneCommands(m.paneCommands),
+	}
+	if m.profile != "" && cfg.SetupScript == "" {
+		cfg.SetupScript = m.config.SetupScript
+	}
+	return cfg
+}
+
 func (m initModel) View() string {
 	var b strings.Builder
 
@@ -156,7 +204,7 @@ func (m initModel) View() string {
 		Bold(true).
 		Foreground(styles.Primary).
 		MarginBottom(1).
-		Render(styles.IconConfig + " Koh Configuration Setup")
+		Render(styles.IconConfig + " Koh Configuration Setup" + m.profileSuffix())
 	b.WriteString("\n" + title + "\n\n")
 
 	switch m.step {
@@ -167,6 +215,10 @@ func (m initModel) View() string {
 		b.WriteString("\n\n")
 		b.WriteString(styles.Muted.Render("  This script runs once when creating a new worktree"))
 		b.WriteString("\n")
+		if m.profile != "" {
+			b.WriteString(styles.Muted.Render("  Leave it empty to use the top-level setup script (" + m.config.SetupScript + ")"))
+			b.WriteString("\n")
+		}
 		b.WriteString("\n")
 		b.WriteString(styles.Help.Render("  Press Enter to continue, Ctrl+C to cancel"))
 		b.WriteString("\n")
@@ -238,6 +290,12 @@ func (m initModel) View() string {
 
 		b.WriteString(box)
 		b.WriteString("\n\n")
+		if len(m.warnings) > 0 {
+			b.WriteString(styles.WarningMessage.Render("  Possible problems:"))
+			b.WriteString("\n")
+			b.WriteString(renderConfigWarnings(m.warnings))
+			b.WriteString("\n")
+		}
 		b.WriteString(styles.Help.Render("  Press Enter to save, Ctrl+C to cancel"))
 		b.WriteString("\n")
 
@@ -256,8 +314,31 @@ func (m initModel) View() string {
 	return b.String()
 }
 
-func runInit(_ *cobra.Command, _ []string) error {
-	p := tea.NewProgram(initialModel())
+// profileSuffix names the profile being configured in the title
+func (m initModel) profileSuffix() string {
+	if m.profile == "" {
+		return ""
+	}
+	return ": profile " + m.profile
+}
+
+func runInit(cmd *cobra.Command, _ []string) error {
+	if err := resolveNestedRepo(newPrinter(cmd), repoDir); err != nil {
+		return err
+	}
+
+	model := initialModel()
+	if initProfile != "" {
+		// Profiles inherit what they don't set from the top level. Only
+		// .kohconfig itself is saved back, not the files it includes.
+		cfg, err := config.LoadLocal()
+		if err != nil {
+			return fmt.Errorf("%w\nProfiles extend the top-level configuration, so run 'koh init' without --profile first", err)
+		}
+		model = initialModelFor(cfg, initProfile)
+	}
+
+	p := tea.NewProgram(model)
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("error running interactive setup: %w", err)
 	}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var legendCmd = &cobra.Command{
+	Use:   "legend",
+	Short: "Explain the icons and colors shown next to worktrees",
+	Long: `Explain every icon, color and label 'koh list' and 'koh status' show next
+to worktrees. The same explanation is shown by pressing ? in 'koh list'.
+
+Use --markdown to print it as a Markdown table, as used in the README.`,
+	Args: cobra.NoArgs,
+	RunE: runLegend,
+}
+
+// legendMarkdown prints the legend as a Markdown table
+var legendMarkdown bool
+
+func init() {
+	legendCmd.Flags().BoolVar(&legendMarkdown, "markdown", false, "Print the legend as a Markdown table")
+	rootCmd.AddCommand(legendCmd)
+}
+
+// renderLegend renders each marker in its style next to its meaning
+func renderLegend() string {
+	width := 0
+	for _, marker := range styles.Legend {
+		width = max(width, lipgloss.Width(marker.Label()))
+	}
+
+	var s strings.Builder
+	for _, marker := range styles.Legend {
+		padding := strings.Repeat(" ", width-lipgloss.Width(marker.Label()))
+		s.WriteString(fmt.Sprintf("  %s%s  %s\n", marker.Render(), padding, marker.Meaning))
+	}
+	return s.String()
+}
+
+// legendMarkdownTable returns the legend as a Markdown table
+func legendMarkdownTable() string {
+	var s strings.Builder
+	s.WriteString("| Marker | Meaning |\n")
+	s.WriteString("| --- | --- |\n")
+	for _, marker := range styles.Legend {
+		s.WriteString(fmt.Sprintf("| `%s` | %s |\n", marker.Label(), marker.Meaning))
+	}
+	return s.String()
+}
+
+func runLegend(cmd *cobra.Command, _ []string) error {
+	p := newPrinter(cmd)
+	return p.Result(styles.Legend, func(w io.Writer) {
+		if legendMarkdown {
+			_, _ = fmt.Fprint(w, legendMarkdownTable())
+			return
+		}
+		fprintln(w, "\n"+styles.RenderTitle(styles.IconTree+" Koh Legend"))
+		_, _ = fmt.Fprint(w, renderLegend())
+		fprintln(w)
+	})
+}
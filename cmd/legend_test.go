@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/styles"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLegendMarkers(t *testing.T) {
+	names := map[string]bool{}
+	for _, marker := range styles.Legend {
+		if names[marker.Name] {
+			t.Errorf("Expected unique marker names, got %q twice", marker.Name)
+		}
+		names[marker.Name] = true
+
+		if marker.Label() == "" || marker.Meaning == "" {
+			t.Errorf("Expected marker %q to have a label and a meaning", marker.Name)
+		}
+		if !strings.Contains(renderLegend(), marker.Meaning) {
+			t.Errorf("Expected the legend to explain %q", marker.Name)
+		}
+	}
+}
+
+// The README embeds 'koh legend --markdown'; regenerate it when markers change
+func TestLegendMatchesReadme(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	if err != nil {
+		t.Fatalf("Failed to read README: %v", err)
+	}
+	if !strings.Contains(string(readme), legendMarkdownTable()) {
+		t.Errorf("Expected README to contain the output of 'koh legend --markdown':\n%s", legendMarkdownTable())
+	}
+}
+
+func TestListModelLegend(t *testing.T) {
+	m := listModel{worktrees: []worktreeItem{{name: "feature", branch: "feature"}}, inTmux: true}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	m = updated.(listModel)
+	if !m.showLegend {
+		t.Fatal("Expected ? to open the legend")
+	}
+	view := m.View()
+	if !contains(view, styles.MarkerDirty.Meaning) || !contains(view, "Switch to the worktree") {
+		t.Errorf("Expected legend to explain keys and markers, got %q", view)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(listModel)
+	if m.showLegend || m.quitting {
+		t.Error("Expected esc to return to the list without quitting")
+	}
+}
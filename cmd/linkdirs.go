@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/linkdir"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// linkWorktreeDirs shares the link_dirs directories of the main repository
+// with a new worktree and returns the ones shared. Directories the main
+// repository doesn't have yet, or the worktree already has, are skipped with
+// a warning since the worktree is usable without them.
+func linkWorktreeDirs(ctx context.Context, p *output.Printer, mainRepoRoot, worktreePath string, cfg *config.Config) []string {
+	mode := cfg.LinkMode
+	if mode == "" {
+		mode = linkdir.Symlink
+	}
+
+	var linked []string
+	for _, dir := range cfg.LinkDirs {
+		rel, err := validation.ValidateRepositoryPath(dir)
+		if err != nil || rel == "." {
+			p.Warn("Not linking %s: link_dirs entries must be directories inside the repository", dir)
+			continue
+		}
+		src := filepath.Join(mainRepoRoot, rel)
+		dst := filepath.Join(worktreePath, rel)
+		if err := validation.ValidatePathWithinRepository(src, mainRepoRoot); err != nil {
+			p.Warn("Not linking %s: %v", rel, err)
+			continue
+		}
+		if err := validation.ValidatePathWithinRepository(dst, worktreePath); err != nil {
+			p.Warn("Not linking %s into the worktree: %v", rel, err)
+			continue
+		}
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			p.Warn("Not linking %s: it doesn't exist in the main repository yet", rel)
+			continue
+		}
+
+		used, err := linkdir.Link(src, dst, mode)
+		if err != nil {
+			p.Warn("Failed to link %s: %v", rel, err)
+			continue
+		}
+		if used != mode {
+			p.Warn("Couldn't %s %s, copied it instead", mode, rel)
+		}
+		// git sees a symlink as a file, which "node_modules/" in .gitignore
+		// doesn't match
+		if used == linkdir.Symlink && !git.IsIgnoredWithContext(ctx, worktreePath, rel) {
+			if commonDir, err := git.GetCommonDir(); err == nil {
+				if err := git.AddExclude(commonDir, "/"+filepath.ToSlash(rel)); err != nil {
+					p.Warn("%v", err)
+				}
+			}
+		}
+		linked = append(linked, filepath.ToSlash(rel))
+	}
+	if len(linked) > 0 {
+		p.Info("Linked %s from the main repository", strings.Join(linked, ", "))
+	}
+	return linked
+}
@@ -3,14 +3,23 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/forge"
 	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/shell"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
 	"github.com/bshakr/koh/internal/styles"
 	"github.com/bshakr/koh/internal/tmux"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -19,12 +28,77 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all koh worktrees",
-	Long:  `List all git worktrees in the .koh directory. Use arrow keys or j/k to navigate, g/G to jump, Enter to switch, q to quit.`,
-	RunE:  runList,
+	Long: `List all git worktrees in the worktree directory (.koh by default). Use arrow keys or j/k to navigate, g/G to jump, Enter to switch, q to quit.
+
+Press a (or Enter outside tmux) to open the actions menu of the selected
+worktree: switch, open it in $EDITOR, show its uncommitted changes or the
+output of its panes, pin it to the top of the list, attach a note or clean it
+up. Set "list_enter": "menu" in the global config.json to make Enter open the
+menu too.
+
+Press r to see the commands koh sent to the selected worktree's panes and
+re-run one of them (the pane is interrupted with Ctrl-C first), e.g. to
+restart a dev server without remembering its command.
+
+--format selects a non-interactive output instead: json (same as --json), or
+raycast/alfred for a launcher "script filter" document. Launchers don't run
+in the repository, so each item's argument is a complete shell command that
+switches to the worktree from the repository's root, e.g.
+"cd '/src/myapp' && koh switch 'auth'", for the launcher to run.
+
+With --current-repo=false the repository's main checkout is listed too, as
+"main", so the list doubles as a way back to it.`,
+	RunE: runList,
 }
 
+var (
+	// listFormat selects the output format: text (interactive), json, raycast or alfred
+	listFormat string
+	// listCurrentRepo lists only koh worktrees; when false the main checkout is listed too
+	listCurrentRepo bool
+)
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVar(&listFormat, "format", "text", "Output format: text, json, raycast or alfred")
+	listCmd.Flags().BoolVar(&listCurrentRepo, "current-repo", true, "List only koh worktrees; set to false to include the main checkout")
+}
+
+// isLauncherFormat reports whether format is a launcher script filter format
+func isLauncherFormat(format string) bool {
+	return format == "raycast" || format == "alfred"
+}
+
+// scriptFilterItems converts list entries of the repository at root to
+// launcher items
+func scriptFilterItems(root string, entries []listEntry) []output.ScriptFilterItem {
+	items := make([]output.ScriptFilterItem, 0, len(entries))
+	for _, e := range entries {
+		subtitle := styles.IconBranch + " " + e.Branch + "  ·  " + e.Path
+		if e.Main {
+			subtitle += "  (main checkout)"
+		}
+		if e.Current {
+			subtitle += "  (current)"
+		}
+		if e.Note != "" {
+			subtitle += "  ·  " + e.Note
+		}
+		items = append(items, output.ScriptFilterItem{
+			UID:          e.Path,
+			Title:        e.Name,
+			Subtitle:     subtitle,
+			Arg:          switchCommand(root, e.Name),
+			Autocomplete: e.Name,
+		})
+	}
+	return items
+}
+
+// switchCommand returns a shell command that switches to the worktree called
+// name of the repository at root from anywhere, for launchers
+func switchCommand(root, name string) string {
+	return fmt.Sprintf("cd %s && koh switch %s", shell.Quote(root), shell.Quote(name))
 }
 
 // worktreeItem represents a single worktree in the list
@@ -33,6 +107,13 @@ type worktreeItem struct {
 	branch    string
 	path      string
 	isCurrent bool
+	// isMain marks the main checkout
+	isMain bool
+	// pinned and note are set from the actions menu
+	pinned bool
+	note   string
+	// readonly is set with 'koh readonly'
+	readonly bool
 }
 
 // listModel is the bubbletea model for the interactive worktree list
@@ -43,9 +124,148 @@ type listModel struct {
 	quitting      bool
 	inTmux        bool
 	switchSuccess bool
+
+	// Pull requests arrive asynchronously so the list opens instantly.
+	// forgeEnabled is set when gh is available and PR data is expected.
+	forgeEnabled bool
+	pullRequests []forge.PullRequest
+	prsLoaded    bool
+
+	// Pane command history per worktree, browsed with "r"
+	history       map[string][]state.PaneCommand
+	showHistory   bool
+	historyCursor int
+	// showLegend is set while the "?" screen explains keys and markers
+	showLegend bool
+
+	// Actions menu of the selected worktree, opened with "a"
+	showActions  bool
+	actionCursor int
+	// enterOpensMenu makes Enter open the menu instead of switching
+	enterOpensMenu bool
+	editingNote    bool
+	noteInput      textinput.Model
+	// cleanup is the worktree to clean up once the list has quit, and
+	// cleanupForce is set once its uncommitted changes were confirmed
+	cleanup      string
+	cleanupForce bool
+	// confirmingCleanup is set while the removal of a worktree with
+	// uncommitted changes waits for confirmation
+	confirmingCleanup bool
+
+	// statusMessage reports the outcome of the last action
+	statusMessage string
+}
+
+// pullRequestsMsg delivers pull requests loaded in the background
+type pullRequestsMsg []forge.PullRequest
+
+// rerunMsg reports the outcome of re-sending a pane command
+type rerunMsg struct {
+	command string
+	err     error
+}
+
+// loadKohWorktrees returns the worktrees that live directly inside the
+// repository's worktree directory, read through the git query cache.
+func loadKohWorktrees(ctx context.Context) ([]git.Worktree, error) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees, err := cache.Worktrees(ctx, commonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	kohDir := config.WorktreeDir(filepath.Dir(commonDir))
+	var kohWorktrees []git.Worktree
+	for _, wt := range worktrees {
+		if inWorktreeDir(kohDir, wt.Path) {
+			kohWorktrees = append(kohWorktrees, wt)
+		}
+	}
+	return kohWorktrees, nil
+}
+
+// loadMainCheckoutItem returns the list entry of the repository's main checkout
+func loadMainCheckoutItem(mainRepoRoot string) (worktreeItem, error) {
+	ctx := context.Background()
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return worktreeItem{}, err
+	}
+	worktrees, err := cache.Worktrees(ctx, commonDir)
+	if err != nil {
+		return worktreeItem{}, err
+	}
+
+	_, mainPath := resolveMainCheckout(mainRepoRoot)
+	currentPath, _ := git.GetCurrentWorktreePath()
+	return mainCheckoutItem(worktrees, mainPath, currentPath), nil
+}
+
+// invalidateWorktreeCache drops cached worktree data after a mutation.
+// Failures are ignored since the fingerprint check also catches stale entries.
+func invalidateWorktreeCache() {
+	if commonDir, err := git.GetCommonDir(); err == nil {
+		_ = cache.Invalidate(commonDir)
+	}
+}
+
+// displayBranch returns the branch label shown for a worktree
+func displayBranch(wt git.Worktree) string {
+	if wt.Branch == "" {
+		return "detached"
+	}
+	return wt.Branch
+}
+
+// listEntry is the machine-readable form of a worktree in 'koh list'
+type listEntry struct {
+	Name    string `json:"name"`
+	Branch  string `json:"branch"`
+	Path    string `json:"path"`
+	Current bool   `json:"current"`
+	Main    bool   `json:"main,omitempty"`
+	Pinned  bool   `json:"pinned,omitempty"`
+	Note    string `json:"note,omitempty"`
+	// Readonly is set when the worktree is marked with 'koh readonly'
+	Readonly bool `json:"readonly,omitempty"`
+}
+
+// mainCheckoutItem returns the list entry of the main checkout at mainPath.
+// Its branch is looked up among the repository's worktrees and left empty
+// when the checkout isn't one of them.
+func mainCheckoutItem(worktrees []git.Worktree, mainPath, currentPath string) worktreeItem {
+	item := worktreeItem{
+		name:      mainCheckoutName,
+		path:      mainPath,
+		isCurrent: currentPath != "" && samePath(currentPath, mainPath),
+		isMain:    true,
+	}
+	for _, wt := range worktrees {
+		if samePath(wt.Path, mainPath) {
+			item.branch = displayBranch(wt)
+			break
+		}
+	}
+	return item
 }
 
-func runList(_ *cobra.Command, _ []string) error {
+func runList(cmd *cobra.Command, _ []string) error {
+	switch listFormat {
+	case "text", "json", "raycast", "alfred":
+	default:
+		return fmt.Errorf("unknown format %q (expected text, json, raycast or alfred)", listFormat)
+	}
+
+	out := newPrinter(cmd)
+	if listFormat == "json" {
+		out = output.New(cmd.OutOrStdout(), output.JSON)
+	}
+
 	// Check if we're in a git repository
 	if !git.IsGitRepo() {
 		return fmt.Errorf("not in a git repository")
@@ -66,45 +286,32 @@ func runList(_ *cobra.Command, _ []string) error {
 		}
 	}
 
-	// Check if .koh directory exists
-	koDir := filepath.Join(mainRepoRoot, ".koh")
-	if _, err := os.Stat(koDir); err != nil {
+	// Check if the worktree directory exists (the main checkout is listed regardless)
+	koDir := config.WorktreeDir(mainRepoRoot)
+	if _, err := os.Stat(koDir); err != nil && listCurrentRepo {
 		if os.IsNotExist(err) {
-			fmt.Println(styles.Muted.Render("No worktrees found (no .koh directory)"))
-			return nil
+			if isLauncherFormat(listFormat) {
+				return output.WriteScriptFilter(cmd.OutOrStdout(), nil)
+			}
+			return out.Result([]listEntry{}, func(w io.Writer) {
+				fprintln(w, styles.Muted.Render("No worktrees found (no "+koDir+" directory)"))
+			})
 		}
-		return fmt.Errorf("failed to check .koh directory: %w", err)
+		return fmt.Errorf("failed to check worktree directory: %w", err)
 	}
 
-	// List git worktrees
-	ctx := context.Background()
-	gitCmd := exec.CommandContext(ctx, "git", "worktree", "list")
-	output, err := gitCmd.Output()
+	worktrees, err := loadListItems(mainRepoRoot, currentWorktreePath, !listCurrentRepo)
 	if err != nil {
-		return fmt.Errorf("failed to list worktrees: %w", err)
+		return err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	var worktrees []worktreeItem
-
-	for _, line := range lines {
-		if strings.Contains(line, "/.koh/") {
-			// Extract worktree name and branch
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				path := parts[0]
-				branch := strings.Trim(parts[len(parts)-1], "[]")
-				name := filepath.Base(path)
-				isCurrent := currentWorktreePath != "" && path == currentWorktreePath
-
-				worktrees = append(worktrees, worktreeItem{
-					name:      name,
-					branch:    branch,
-					path:      path,
-					isCurrent: isCurrent,
-				})
-			}
+	// JSON and launcher output is non-interactive
+	if out.IsJSON() || isLauncherFormat(listFormat) {
+		entries := listEntries(worktrees)
+		if isLauncherFormat(listFormat) {
+			return output.WriteScriptFilter(cmd.OutOrStdout(), scriptFilterItems(mainRepoRoot, entries))
 		}
+		return out.Result(entries, nil)
 	}
 
 	if len(worktrees) == 0 {
@@ -117,9 +324,12 @@ func runList(_ *cobra.Command, _ []string) error {
 
 	// Create and run the interactive list
 	m := listModel{
-		worktrees: worktrees,
-		cursor:    0,
-		inTmux:    inTmux,
+		worktrees:      worktrees,
+		cursor:         0,
+		inTmux:         inTmux,
+		forgeEnabled:   forge.Available(),
+		history:        loadPaneHistory(),
+		enterOpensMenu: listEnterOpensMenu(),
 	}
 
 	// Set cursor to current worktree if found
@@ -131,6 +341,11 @@ func runList(_ *cobra.Command, _ []string) error {
 	}
 
 	p := tea.NewProgram(m)
+	if m.forgeEnabled {
+		go func() {
+			p.Send(pullRequestsMsg(loadPullRequests(context.Background())))
+		}()
+	}
 	finalModel, err := p.Run()
 	if err != nil {
 		return fmt.Errorf("error running interactive list: %w", err)
@@ -139,14 +354,110 @@ func runList(_ *cobra.Command, _ []string) error {
 	// Check if user selected a worktree to switch to
 	if finalModel, ok := finalModel.(listModel); ok {
 		if finalModel.selected != "" && inTmux {
+			if err := checkDirtyBeforeSwitch(context.Background(), out, finalModel.selected); err != nil {
+				return err
+			}
 			// Switch to the selected worktree using the extracted function
-			return switchToWorktree(finalModel.selected, true)
+			_, err := switchToWorktree(out, finalModel.selected, true, false)
+			return err
+		}
+		if finalModel.cleanup != "" {
+			return cleanupFromList(out, mainRepoRoot, finalModel.cleanup, finalModel.cleanupForce)
 		}
 	}
 
 	return nil
 }
 
+// loadListItems returns the koh worktrees of the repository at mainRepoRoot
+// with their recorded pins and notes, after the main checkout when
+// includeMain is set. currentPath is the worktree the user is in, if any.
+func loadListItems(mainRepoRoot, currentPath string, includeMain bool) ([]worktreeItem, error) {
+	kohWorktrees, err := loadKohWorktrees(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var worktrees []worktreeItem
+	if includeMain {
+		item, err := loadMainCheckoutItem(mainRepoRoot)
+		if err != nil {
+			return nil, err
+		}
+		worktrees = append(worktrees, item)
+	}
+	for _, wt := range kohWorktrees {
+		worktrees = append(worktrees, worktreeItem{
+			name:      filepath.Base(wt.Path),
+			branch:    displayBranch(wt),
+			path:      wt.Path,
+			isCurrent: currentPath != "" && wt.Path == currentPath,
+		})
+	}
+	applyRecordedWorktrees(worktrees, loadRecordedWorktrees())
+	return worktrees, nil
+}
+
+// listEntries returns the machine-readable form of list items
+func listEntries(worktrees []worktreeItem) []listEntry {
+	entries := []listEntry{}
+	for _, wt := range worktrees {
+		entries = append(entries, listEntry{Name: wt.name, Branch: wt.branch, Path: wt.path, Current: wt.isCurrent, Main: wt.isMain, Pinned: wt.pinned, Note: wt.note, Readonly: wt.readonly})
+	}
+	return entries
+}
+
+// applyRecordedWorktrees sets the pins, notes and read-only marks recorded
+// for worktrees and moves pinned worktrees to the top, below the main
+// checkout
+func applyRecordedWorktrees(worktrees []worktreeItem, recorded map[string]*state.Worktree) {
+	for i := range worktrees {
+		if wt := recorded[worktrees[i].name]; wt != nil {
+			worktrees[i].pinned = wt.Pinned && !worktrees[i].isMain
+			worktrees[i].note = wt.Note
+			worktrees[i].readonly = wt.Readonly
+		}
+	}
+
+	rank := func(wt worktreeItem) int {
+		switch {
+		case wt.isMain:
+			return 0
+		case wt.pinned:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(worktrees, func(i, j int) bool {
+		return rank(worktrees[i]) < rank(worktrees[j])
+	})
+}
+
+// cleanupFromList cleans up the worktree picked in the actions menu, with
+// the cleanup defaults from .kohconfig. force is set when the list already
+// confirmed the loss of its uncommitted changes.
+func cleanupFromList(p *output.Printer, mainRepoRoot, worktreeName string, force bool) error {
+	unlock, err := lockRepo(p, "koh cleanup "+worktreeName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ctx, cancel := signals.SetupCancellableContext()
+	defer cancel()
+
+	cleanupCfg := loadCleanupConfig()
+	opts := cleanupOptions{cfg: cleanupCfg, deleteRemote: cleanupCfg != nil && cleanupCfg.DeleteRemoteBranch, force: force}
+	result, err := cleanupWorktree(ctx, p, mainRepoRoot, worktreeName, opts)
+	if err != nil {
+		return err
+	}
+	return p.Result(result, func(w io.Writer) {
+		fprintln(w, "Cleanup complete!")
+	})
+}
+
 // Init initializes the bubbletea model
 func (m listModel) Init() tea.Cmd {
 	return nil
@@ -155,7 +466,46 @@ func (m listModel) Init() tea.Cmd {
 // Update handles keyboard input and updates the model
 func (m listModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case pullRequestsMsg:
+		m.pullRequests = msg
+		m.prsLoaded = true
+
+	case actionDoneMsg:
+		if msg.err != nil {
+			m.statusMessage = styles.ErrorMessage.Render(styles.IconCross + " " + msg.err.Error())
+		}
+
+	case rerunMsg:
+		if msg.err != nil {
+			m.statusMessage = styles.ErrorMessage.Render(styles.IconCross + " " + msg.err.Error())
+		} else {
+			m.statusMessage = styles.SuccessMessage.Render(styles.IconCheck + " Re-ran: " + msg.command)
+		}
+
 	case tea.KeyMsg:
+		if m.editingNote {
+			return m.updateNote(msg)
+		}
+		if m.confirmingCleanup {
+			return m.updateCleanupConfirm(msg)
+		}
+		if m.showActions {
+			return m.updateActions(msg)
+		}
+		if m.showHistory {
+			return m.updateHistory(msg)
+		}
+		if m.showLegend {
+			switch msg.String() {
+			case "ctrl+c":
+				m.quitting = true
+				return m, tea.Quit
+			case "q", "esc", "?":
+				m.showLegend = false
+			}
+			return m, nil
+		}
+
 		switch msg.String() {
 		// Quit keys
 		case "q", "esc", "ctrl+c":
@@ -190,26 +540,128 @@ func (m listModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor = len(m.worktrees) - 1
 			}
 
+		// Keys and markers
+		case "?":
+			m.showLegend = true
+
+		// Actions menu
+		case "a":
+			m = m.openActions()
+
+		// Pane command history
+		case "r":
+			if m.cursor >= 0 && m.cursor < len(m.worktrees) {
+				name := m.worktrees[m.cursor].name
+				if len(m.history[name]) == 0 {
+					m.statusMessage = styles.Muted.Render("No recorded commands for " + name)
+				} else {
+					m.showHistory = true
+					m.historyCursor = 0
+					m.statusMessage = ""
+				}
+			}
+
 		// Select and switch
 		case "enter":
+			if m.enterOpensMenu || !m.inTmux {
+				m = m.openActions()
+				return m, nil
+			}
 			// Defensive check (should always be true due to navigation bounds and empty list early return)
-			if m.inTmux && m.cursor >= 0 && m.cursor < len(m.worktrees) {
+			if m.cursor >= 0 && m.cursor < len(m.worktrees) {
 				m.selected = m.worktrees[m.cursor].name
 				m.switchSuccess = true
 				return m, tea.Quit
 			}
 		}
+
+	default:
+		// Keep the note editor's cursor blinking
+		if m.editingNote {
+			var cmd tea.Cmd
+			m.noteInput, cmd = m.noteInput.Update(msg)
+			return m, cmd
+		}
 	}
 
 	return m, nil
 }
 
+// selectedHistory returns the selected worktree's pane commands, newest first
+func (m listModel) selectedHistory() []state.PaneCommand {
+	if m.cursor < 0 || m.cursor >= len(m.worktrees) {
+		return nil
+	}
+
+	recorded := m.history[m.worktrees[m.cursor].name]
+	history := make([]state.PaneCommand, 0, len(recorded))
+	for i := len(recorded) - 1; i >= 0; i-- {
+		history = append(history, recorded[i])
+	}
+	return history
+}
+
+// updateHistory handles keys while the pane command history is shown
+func (m listModel) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	history := m.selectedHistory()
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "q", "esc":
+		m.showHistory = false
+	case "up", "k":
+		if m.historyCursor > 0 {
+			m.historyCursor--
+		}
+	case "down", "j":
+		if m.historyCursor < len(history)-1 {
+			m.historyCursor++
+		}
+	case "enter":
+		if !m.inTmux {
+			m.statusMessage = styles.Muted.Render("Not in tmux, can't re-run commands")
+		} else if m.historyCursor >= 0 && m.historyCursor < len(history) {
+			return m, rerunPaneCommand(m.worktrees[m.cursor].name, history[m.historyCursor])
+		}
+	}
+	return m, nil
+}
+
+// rerunPaneCommand re-sends a recorded command to its pane in the background
+func rerunPaneCommand(worktreeName string, pc state.PaneCommand) tea.Cmd {
+	return func() tea.Msg {
+		err := tmux.RerunPaneCommandWithContext(context.Background(), worktreeName, pc.Pane, pc.Command)
+		if err == nil {
+			recordRerun(worktreeName, pc)
+		}
+		return rerunMsg{command: pc.Command, err: err}
+	}
+}
+
 // View renders the UI
 func (m listModel) View() string {
 	if m.quitting && !m.switchSuccess {
 		return ""
 	}
 
+	if m.editingNote {
+		return m.viewNote()
+	}
+	if m.confirmingCleanup {
+		return m.viewCleanupConfirm()
+	}
+	if m.showActions {
+		return m.viewActions()
+	}
+	if m.showHistory {
+		return m.viewHistory()
+	}
+	if m.showLegend {
+		return m.viewLegend()
+	}
+
 	var s strings.Builder
 
 	// Title
@@ -223,6 +675,11 @@ func (m listModel) View() string {
 			cursor = styles.Active.Render("▶ ")
 		}
 
+		branch := wt.branch
+		if branch == "" {
+			branch = "unknown"
+		}
+
 		var line string
 		if wt.isCurrent {
 			// Current session in green text (no background)
@@ -230,31 +687,136 @@ func (m listModel) View() string {
 				Bold(true).
 				Foreground(lipgloss.Color("2")) // Green
 
-			icon := greenStyle.Render(styles.IconCurrent)
+			icon := greenStyle.Render(styles.MarkerCurrent.Icon)
 			nameStyled := greenStyle.Render(wt.name)
-			branchStyled := greenStyle.Render(styles.IconBranch + " " + wt.branch)
-			currentLabel := styles.Muted.Render("[current]")
+			branchStyled := greenStyle.Render(styles.IconBranch + " " + branch)
+			currentLabel := styles.MarkerHere.Render()
 			line = fmt.Sprintf("%s%s %s %s %s", cursor, icon, nameStyled, branchStyled, currentLabel)
+		} else if wt.isMain {
+			icon := styles.MarkerMain.Render()
+			nameStyled := styles.Active.Render(wt.name)
+			branchStyled := styles.MarkerBranch.RenderWith(branch)
+			line = fmt.Sprintf("%s%s %s %s", cursor, icon, nameStyled, branchStyled)
 		} else {
-			icon := styles.Muted.Render(styles.IconBullet)
+			icon := styles.MarkerWorktree.Render()
 			nameStyled := wt.name
-			branchStyled := styles.Muted.Render(styles.IconBranch + " " + wt.branch)
+			branchStyled := styles.MarkerBranch.RenderWith(branch)
 			line = fmt.Sprintf("%s%s %s %s", cursor, icon, nameStyled, branchStyled)
 		}
+		if wt.isMain {
+			line += " " + styles.MarkerCheckout.Render()
+		}
+		if wt.pinned {
+			line += " " + styles.MarkerPinned.Render()
+		}
+		if wt.readonly {
+			line += " " + styles.MarkerReadonly.Render()
+		}
 
 		s.WriteString(line + "\n")
 	}
 
+	// Preview of the selected worktree
+	if m.cursor >= 0 && m.cursor < len(m.worktrees) {
+		s.WriteString("\n" + m.renderPreview(m.worktrees[m.cursor]) + "\n")
+	}
+
+	if m.statusMessage != "" {
+		s.WriteString("\n" + m.statusMessage + "\n")
+	}
+
 	// Help text
 	s.WriteString("\n")
-	if m.inTmux {
-		help := styles.RenderHelp("↑/↓ or j/k: navigate • g/G: jump to top/bottom • enter: switch • q: quit")
+	switch {
+	case m.inTmux && m.enterOpensMenu:
+		help := styles.RenderHelp("↑/↓ or j/k: navigate • g/G: jump to top/bottom • enter/a: actions • r: re-run command • ?: legend • q: quit")
+		s.WriteString(help)
+	case m.inTmux:
+		help := styles.RenderHelp("↑/↓ or j/k: navigate • g/G: jump to top/bottom • enter: switch • a: actions • r: re-run command • ?: legend • q: quit")
 		s.WriteString(help)
-	} else {
-		help := styles.RenderHelp("↑/↓ or j/k: navigate • g/G: jump to top/bottom • q: quit (not in tmux)")
+	default:
+		help := styles.RenderHelp("↑/↓ or j/k: navigate • g/G: jump to top/bottom • enter/a: actions • ?: legend • q: quit (not in tmux)")
 		s.WriteString(help)
 	}
 	s.WriteString("\n")
 
 	return s.String()
 }
+
+// renderPreview renders details of the selected worktree below the list
+func (m listModel) renderPreview(wt worktreeItem) string {
+	lines := []string{styles.Key.Render("Path:") + " " + styles.Muted.Render(wt.path)}
+	if wt.note != "" {
+		lines = append(lines, styles.Key.Render("Note:")+" "+wt.note)
+	}
+
+	if m.forgeEnabled {
+		pr := styles.Muted.Render("loading...")
+		if m.prsLoaded {
+			pr = styles.Muted.Render("none")
+			if found := forge.ForBranch(m.pullRequests, wt.branch); found != nil {
+				pr = renderPullRequest(found) + " " + found.Title
+			}
+		}
+		lines = append(lines, styles.Key.Render("PR:")+" "+pr)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// viewHistory renders the selected worktree's pane command history
+func (m listModel) viewHistory() string {
+	var s strings.Builder
+
+	title := styles.RenderTitle(styles.IconTree + " Commands sent to " + m.worktrees[m.cursor].name)
+	s.WriteString("\n" + title + "\n\n")
+
+	for i, pc := range m.selectedHistory() {
+		cursor := "  "
+		if m.historyCursor == i {
+			cursor = styles.Active.Render("▶ ")
+		}
+		pane := styles.Muted.Render(fmt.Sprintf("pane %d", pc.Pane))
+		s.WriteString(fmt.Sprintf("%s%s  %s\n", cursor, pane, pc.Command))
+	}
+
+	if m.statusMessage != "" {
+		s.WriteString("\n" + m.statusMessage + "\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(styles.RenderHelp("↑/↓ or j/k: navigate • enter: interrupt pane and re-run • esc: back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
+
+// listKeys are the key bindings explained on the legend screen
+var listKeys = [][2]string{
+	{"↑/↓ or j/k", "Move between worktrees"},
+	{"g/G", "Jump to the top or bottom"},
+	{"enter", "Switch to the worktree (in tmux), or open the actions menu"},
+	{"a", "Open the actions menu: editor, diff, pane output, pin, note, cleanup"},
+	{"r", "Browse and re-run commands sent to its panes"},
+	{"?", "Show or hide this screen"},
+	{"q/esc", "Quit"},
+}
+
+// viewLegend renders the key bindings and what each marker means
+func (m listModel) viewLegend() string {
+	var s strings.Builder
+
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+" Keys") + "\n\n")
+	for _, key := range listKeys {
+		s.WriteString(fmt.Sprintf("  %-12s %s\n", key[0], styles.Muted.Render(key[1])))
+	}
+
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+" Markers") + "\n\n")
+	s.WriteString(renderLegend())
+
+	s.WriteString("\n")
+	s.WriteString(styles.RenderHelp("?/esc: back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
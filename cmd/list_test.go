@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -263,6 +267,48 @@ func TestListModelView(t *testing.T) {
 	}
 }
 
+func TestListModelViewMainCheckout(t *testing.T) {
+	m := listModel{
+		worktrees: []worktreeItem{
+			{name: "main", branch: "main", path: "/repo", isMain: true},
+			{name: "feature", branch: "feature", path: "/repo/.koh/feature"},
+		},
+		inTmux: true,
+	}
+
+	view := m.View()
+	if !contains(view, "[main checkout]") {
+		t.Error("Expected the main checkout to be marked")
+	}
+	if strings.Count(view, "[main checkout]") != 1 {
+		t.Error("Expected only the main checkout to be marked")
+	}
+}
+
+func TestMainCheckoutItem(t *testing.T) {
+	repo := t.TempDir()
+	worktrees := []git.Worktree{
+		{Path: repo, Branch: "trunk"},
+		{Path: repo + "/.koh/feature", Branch: "feature"},
+	}
+
+	item := mainCheckoutItem(worktrees, repo, repo)
+	if item.name != mainCheckoutName || !item.isMain {
+		t.Errorf("Expected a main checkout entry, got %+v", item)
+	}
+	if item.branch != "trunk" {
+		t.Errorf("Expected branch trunk, got %q", item.branch)
+	}
+	if !item.isCurrent {
+		t.Error("Expected the main checkout to be current when running in it")
+	}
+
+	other := mainCheckoutItem(worktrees, "/elsewhere", repo+"/.koh/feature")
+	if other.branch != "" || other.isCurrent {
+		t.Errorf("Expected no branch for a checkout outside the repository, got %+v", other)
+	}
+}
+
 func TestListModelViewQuitting(t *testing.T) {
 	m := listModel{
 		worktrees: []worktreeItem{{name: "test", branch: "main", path: "/", isCurrent: false}},
@@ -330,3 +376,99 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestListModelPreviewShowsPullRequest(t *testing.T) {
+	m := listModel{
+		worktrees:    []worktreeItem{{name: "feature", branch: "feature", path: "/repo/.koh/feature"}},
+		inTmux:       true,
+		forgeEnabled: true,
+	}
+
+	if !contains(m.View(), "loading...") {
+		t.Error("Expected preview to show PR loading state")
+	}
+
+	updated, _ := m.Update(pullRequestsMsg([]forge.PullRequest{
+		{Number: 7, Title: "Add feature", Branch: "feature", State: forge.StateOpen},
+	}))
+	view := updated.(listModel).View()
+	if !contains(view, "#7 open") || !contains(view, "Add feature") {
+		t.Errorf("Expected preview to show PR #7, got %q", view)
+	}
+	if !contains(view, "/repo/.koh/feature") {
+		t.Error("Expected preview to show worktree path")
+	}
+}
+
+func TestListModelHistory(t *testing.T) {
+	m := listModel{
+		worktrees: []worktreeItem{{name: "feature", branch: "feature", path: "/repo/.koh/feature"}, {name: "other", branch: "other"}},
+		inTmux:    true,
+		history: map[string][]state.PaneCommand{
+			"feature": {{Pane: 0, Command: "./bin/setup"}, {Pane: 1, Command: "npm run dev"}},
+		},
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = updated.(listModel)
+	if !m.showHistory {
+		t.Fatal("Expected r to open the command history")
+	}
+
+	view := m.View()
+	if !contains(view, "Commands sent to feature") || !contains(view, "npm run dev") {
+		t.Errorf("Expected history view to list commands, got %q", view)
+	}
+	if history := m.selectedHistory(); history[0].Command != "npm run dev" {
+		t.Errorf("Expected newest command first, got %+v", history)
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(listModel)
+	if cmd == nil {
+		t.Error("Expected enter to re-run the selected command")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(listModel)
+	if m.showHistory || m.quitting {
+		t.Error("Expected esc to return to the list without quitting")
+	}
+
+	// A worktree without history reports it instead of opening the view
+	m.cursor = 1
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = updated.(listModel)
+	if m.showHistory || !contains(m.View(), "No recorded commands") {
+		t.Error("Expected a message for a worktree without history")
+	}
+}
+
+func TestListModelRerunMessage(t *testing.T) {
+	m := listModel{worktrees: []worktreeItem{{name: "feature", branch: "feature"}}, inTmux: true}
+
+	updated, _ := m.Update(rerunMsg{command: "npm run dev"})
+	if !contains(updated.(listModel).View(), "Re-ran: npm run dev") {
+		t.Error("Expected view to confirm the re-run")
+	}
+}
+
+func TestScriptFilterItems(t *testing.T) {
+	items := scriptFilterItems("/src/my app", []listEntry{
+		{Name: "auth", Branch: "feature/auth", Path: "/src/my app/.koh/auth", Current: true},
+	})
+
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	// The argument works from wherever the launcher runs it
+	if item.Title != "auth" || item.Arg != "cd '/src/my app' && koh switch 'auth'" || item.UID != "/src/my app/.koh/auth" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	for _, want := range []string{"feature/auth", "/src/my app/.koh/auth", "current"} {
+		if !strings.Contains(item.Subtitle, want) {
+			t.Errorf("Expected subtitle to contain %q, got %q", want, item.Subtitle)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listAction is an entry of the actions menu 'koh list' opens on a worktree
+type listAction struct {
+	// key runs the action directly from the menu
+	key   string
+	label string
+	// needsTmux is set for actions that act on tmux windows
+	needsTmux bool
+	// worktreeOnly hides the action on the main checkout
+	worktreeOnly bool
+}
+
+// listActions are the actions of the menu, in the order shown
+var listActions = []listAction{
+	{key: "s", label: "Switch to the worktree", needsTmux: true},
+	{key: "e", label: "Open in $EDITOR"},
+	{key: "d", label: "Show uncommitted changes"},
+	{key: "l", label: "Show the output of its panes", needsTmux: true},
+	{key: "p", label: "Pin to the top of the list", worktreeOnly: true},
+	{key: "n", label: "Edit note"},
+	{key: "x", label: "Clean up the worktree", worktreeOnly: true},
+}
+
+// actionDoneMsg reports the outcome of a program the menu ran in the terminal
+type actionDoneMsg struct {
+	err error
+}
+
+// listEnterOpensMenu reports whether Enter opens the actions menu, as set by
+// list_enter in the global config. Problems with the setting are reported by
+// 'koh doctor'; until they're fixed Enter switches.
+func listEnterOpensMenu() bool {
+	g, err := config.LoadGlobal()
+	if err != nil {
+		return false
+	}
+	menu, _ := g.EnterOpensMenu()
+	return menu
+}
+
+// availableActions returns the menu entries that apply to a worktree
+func availableActions(wt worktreeItem, inTmux bool) []listAction {
+	var actions []listAction
+	for _, action := range listActions {
+		if (action.needsTmux && !inTmux) || (action.worktreeOnly && wt.isMain) {
+			continue
+		}
+		if action.key == "p" && wt.pinned {
+			action.label = "Unpin"
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// envCommand splits the command in the first set environment variable, e.g.
+// "code --wait", falling back to fallback
+func envCommand(fallback string, names ...string) []string {
+	for _, name := range names {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{fallback}
+}
+
+// editorCommand returns the command that opens a worktree in the user's editor
+func editorCommand(path string) *exec.Cmd {
+	editor := editorArgs()
+	//nolint:gosec // G204: the editor comes from the user's own config or environment
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Dir = path
+	return cmd
+}
+
+// diffCommand returns the command that shows a worktree's uncommitted changes
+func diffCommand(path string) *exec.Cmd {
+	//nolint:gosec // G204: path is a worktree path from git
+	return exec.Command("git", "-C", path, "diff", "HEAD")
+}
+
+// paneOutputCommand returns a pager showing the output of each pane of a
+// worktree's window
+func paneOutputCommand(ctx context.Context, worktreeName string) (*exec.Cmd, error) {
+	panes, err := tmux.ListWindowPanesWithContext(ctx, worktreeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var s strings.Builder
+	for _, pane := range panes {
+		contents, err := tmux.CapturePaneWithContext(ctx, worktreeName, pane.Pane)
+		if err != nil {
+			return nil, err
+		}
+		s.WriteString(fmt.Sprintf("── pane %d (%s) ──\n%s\n", pane.Pane, pane.CurrentCommand, contents))
+	}
+
+	pager := envCommand("less", "PAGER")
+	//nolint:gosec // G204: the pager comes from the user's own environment
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(s.String())
+	return cmd, nil
+}
+
+// openActions opens the actions menu of the selected worktree
+func (m listModel) openActions() listModel {
+	if m.cursor >= 0 && m.cursor < len(m.worktrees) {
+		m.showActions = true
+		m.actionCursor = 0
+		m.statusMessage = ""
+	}
+	return m
+}
+
+// updateActions handles keys while the actions menu is shown
+func (m listModel) updateActions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	actions := availableActions(m.worktrees[m.cursor], m.inTmux)
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "q", "esc":
+		m.showActions = false
+	case "up", "k":
+		if m.actionCursor > 0 {
+			m.actionCursor--
+		}
+	case "down", "j":
+		if m.actionCursor < len(actions)-1 {
+			m.actionCursor++
+		}
+	case "enter":
+		if m.actionCursor >= 0 && m.actionCursor < len(actions) {
+			return m.runAction(actions[m.actionCursor].key)
+		}
+	default:
+		for _, action := range actions {
+			if msg.String() == action.key {
+				return m.runAction(action.key)
+			}
+		}
+	}
+	return m, nil
+}
+
+// runAction runs a menu action on the selected worktree
+func (m listModel) runAction(key string) (tea.Model, tea.Cmd) {
+	wt := &m.worktrees[m.cursor]
+	m.showActions = false
+	done := func(err error) tea.Msg { return actionDoneMsg{err: err} }
+
+	switch key {
+	case "s":
+		m.selected = wt.name
+		m.switchSuccess = true
+		return m, tea.Quit
+	case "e":
+		return m, tea.ExecProcess(editorCommand(wt.path), done)
+	case "d":
+		return m, tea.ExecProcess(diffCommand(wt.path), done)
+	case "l":
+		cmd, err := paneOutputCommand(context.Background(), wt.name)
+		if err != nil {
+			m.statusMessage = styles.ErrorMessage.Render(styles.IconCross + " " + err.Error())
+			return m, nil
+		}
+		return m, tea.ExecProcess(cmd, done)
+	case "p":
+		if err := recordPinned(wt.name, !wt.pinned); err != nil {
+			m.statusMessage = styles.ErrorMessage.Render(styles.IconCross + " " + err.Error())
+			return m, nil
+		}
+		wt.pinned = !wt.pinned
+		if wt.pinned {
+			m.statusMessage = styles.SuccessMessage.Render(styles.IconCheck + " Pinned " + wt.name)
+		} else {
+			m.statusMessage = styles.SuccessMessage.Render(styles.IconCheck + " Unpinned " + wt.name)
+		}
+	case "n":
+		m.editingNote = true
+		m.noteInput = textinput.New()
+		m.noteInput.Placeholder = "a reminder about this worktree"
+		m.noteInput.SetValue(wt.note)
+		return m, m.noteInput.Focus()
+	case "x":
+		// Uncommitted changes are confirmed while the list still has the
+		// terminal, rather than on stdin once it has quit
+		if dirty, _ := git.IsDirty(wt.path); dirty {
+			m.confirmingCleanup = true
+			return m, nil
+		}
+		m.cleanup = wt.name
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// updateCleanupConfirm handles keys while the removal of a worktree with
+// uncommitted changes waits for confirmation
+func (m listModel) updateCleanupConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.confirmingCleanup = false
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "y":
+		m.cleanup = m.worktrees[m.cursor].name
+		m.cleanupForce = true
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// viewCleanupConfirm asks to remove the selected worktree along with its
+// uncommitted changes
+func (m listModel) viewCleanupConfirm() string {
+	var s strings.Builder
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+" Clean up "+m.worktrees[m.cursor].name+"?") + "\n\n")
+	s.WriteString("  " + styles.WarningMessage.Render("Its uncommitted changes will be lost") + "\n\n")
+	s.WriteString(styles.RenderHelp("y: clean up • any other key: back"))
+	s.WriteString("\n")
+	return s.String()
+}
+
+// updateNote handles keys while the note of the selected worktree is edited
+func (m listModel) updateNote(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.editingNote = false
+		return m, nil
+	case "enter":
+		wt := &m.worktrees[m.cursor]
+		note := strings.TrimSpace(m.noteInput.Value())
+		m.editingNote = false
+		if err := recordNote(wt.name, note); err != nil {
+			m.statusMessage = styles.ErrorMessage.Render(styles.IconCross + " " + err.Error())
+			return m, nil
+		}
+		wt.note = note
+		m.statusMessage = styles.SuccessMessage.Render(styles.IconCheck + " Saved note for " + wt.name)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+// viewActions renders the actions menu of the selected worktree
+func (m listModel) viewActions() string {
+	var s strings.Builder
+
+	title := styles.RenderTitle(styles.IconTree + " Actions for " + m.worktrees[m.cursor].name)
+	s.WriteString("\n" + title + "\n\n")
+
+	for i, action := range availableActions(m.worktrees[m.cursor], m.inTmux) {
+		cursor := "  "
+		if m.actionCursor == i {
+			cursor = styles.Active.Render("▶ ")
+		}
+		s.WriteString(fmt.Sprintf("%s%s  %s\n", cursor, styles.Key.Render(action.key), action.label))
+	}
+
+	s.WriteString("\n")
+	s.WriteString(styles.RenderHelp("↑/↓ or j/k: navigate • enter or key: run • esc: back"))
+	s.WriteString("\n")
+
+	return s.String()
+}
+
+// viewNote renders the note editor of the selected worktree
+func (m listModel) viewNote() string {
+	var s strings.Builder
+
+	title := styles.RenderTitle(styles.IconTree + " Note for " + m.worktrees[m.cursor].name)
+	s.WriteString("\n" + title + "\n\n")
+	s.WriteString(m.noteInput.View() + "\n\n")
+	s.WriteString(styles.RenderHelp("enter: save (empty removes the note) • esc: cancel"))
+	s.WriteString("\n")
+
+	return s.String()
+}
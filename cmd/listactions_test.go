@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/testutil"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// actionKeys returns the keys of actions, e.g. "sedlpnx"
+func actionKeys(actions []listAction) string {
+	var keys strings.Builder
+	for _, action := range actions {
+		keys.WriteString(action.key)
+	}
+	return keys.String()
+}
+
+func TestAvailableActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		wt     worktreeItem
+		inTmux bool
+		want   string
+	}{
+		{name: "worktree in tmux", wt: worktreeItem{name: "feature"}, inTmux: true, want: "sedlpnx"},
+		{name: "worktree outside tmux", wt: worktreeItem{name: "feature"}, want: "edpnx"},
+		{name: "main checkout", wt: worktreeItem{name: "main", isMain: true}, inTmux: true, want: "sedln"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := actionKeys(availableActions(tt.wt, tt.inTmux)); got != tt.want {
+				t.Errorf("Expected actions %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	for _, action := range availableActions(worktreeItem{name: "feature", pinned: true}, true) {
+		if action.key == "p" && action.label != "Unpin" {
+			t.Errorf("Expected a pinned worktree to offer Unpin, got %q", action.label)
+		}
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	if got := strings.Join(envCommand("vi", "VISUAL", "EDITOR"), " "); got != "code --wait" {
+		t.Errorf("Expected $EDITOR to be used, got %q", got)
+	}
+
+	t.Setenv("VISUAL", "nvim")
+	if got := strings.Join(envCommand("vi", "VISUAL", "EDITOR"), " "); got != "nvim" {
+		t.Errorf("Expected $VISUAL to win, got %q", got)
+	}
+
+	t.Setenv("VISUAL", " ")
+	t.Setenv("EDITOR", "")
+	if got := strings.Join(envCommand("vi", "VISUAL", "EDITOR"), " "); got != "vi" {
+		t.Errorf("Expected the fallback, got %q", got)
+	}
+}
+
+func TestApplyRecordedWorktrees(t *testing.T) {
+	worktrees := []worktreeItem{
+		{name: "main", isMain: true},
+		{name: "alpha"},
+		{name: "beta"},
+		{name: "gamma"},
+	}
+	recorded := map[string]*state.Worktree{
+		"main":  {Pinned: true},
+		"beta":  {Note: "waiting on review"},
+		"gamma": {Pinned: true},
+	}
+
+	applyRecordedWorktrees(worktrees, recorded)
+
+	var names []string
+	for _, wt := range worktrees {
+		names = append(names, wt.name)
+	}
+	if got := strings.Join(names, " "); got != "main gamma alpha beta" {
+		t.Errorf("Expected main first, then pinned worktrees, got %q", got)
+	}
+	if worktrees[0].pinned {
+		t.Error("Expected the main checkout not to be pinned")
+	}
+	if worktrees[3].note != "waiting on review" {
+		t.Errorf("Expected beta's note, got %q", worktrees[3].note)
+	}
+}
+
+func TestListModelActions(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+
+	newModel := func() listModel {
+		return listModel{worktrees: []worktreeItem{{name: "feature", branch: "feature"}}, inTmux: true}
+	}
+	press := func(m listModel, key string) listModel {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		}
+		updated, _ := m.Update(msg)
+		return updated.(listModel)
+	}
+
+	t.Run("a opens the menu and esc closes it", func(t *testing.T) {
+		m := press(newModel(), "a")
+		if !m.showActions || !contains(m.View(), "Clean up the worktree") {
+			t.Fatalf("Expected a to open the actions menu, got %q", m.View())
+		}
+		m = press(m, "esc")
+		if m.showActions || m.quitting {
+			t.Error("Expected esc to return to the list without quitting")
+		}
+	})
+
+	t.Run("enter switches by default", func(t *testing.T) {
+		m := press(newModel(), "enter")
+		if m.showActions || m.selected != "feature" {
+			t.Errorf("Expected enter to switch, got selected %q", m.selected)
+		}
+	})
+
+	t.Run("enter opens the menu when configured", func(t *testing.T) {
+		m := newModel()
+		m.enterOpensMenu = true
+		m = press(m, "enter")
+		if !m.showActions || m.selected != "" {
+			t.Fatal("Expected enter to open the actions menu")
+		}
+		m = press(m, "enter")
+		if m.selected != "feature" {
+			t.Errorf("Expected the first action to switch, got selected %q", m.selected)
+		}
+	})
+
+	t.Run("x cleans up after quitting", func(t *testing.T) {
+		m := newModel()
+		m.worktrees[0].path = testutil.NewRepo(t)
+		m = press(press(m, "a"), "x")
+		if m.cleanup != "feature" || m.cleanupForce || !m.quitting {
+			t.Errorf("Expected feature to be cleaned up, got %q", m.cleanup)
+		}
+	})
+
+	t.Run("x confirms uncommitted changes before quitting", func(t *testing.T) {
+		repo := testutil.NewRepo(t)
+		testutil.WriteFile(t, repo, "wip.txt", "unsaved")
+		m := newModel()
+		m.worktrees[0].path = repo
+
+		m = press(press(m, "a"), "x")
+		if !m.confirmingCleanup || m.quitting || !contains(m.View(), "uncommitted changes will be lost") {
+			t.Fatalf("Expected the removal to wait for confirmation, got %q", m.View())
+		}
+		if m = press(m, "n"); m.confirmingCleanup || m.cleanup != "" {
+			t.Errorf("Expected any other key to go back, got cleanup %q", m.cleanup)
+		}
+
+		m = press(press(press(m, "a"), "x"), "y")
+		if m.cleanup != "feature" || !m.cleanupForce || !m.quitting {
+			t.Errorf("Expected y to clean up feature with its changes, got %q (force %v)", m.cleanup, m.cleanupForce)
+		}
+	})
+
+	t.Run("p pins and n edits the note", func(t *testing.T) {
+		m := press(press(newModel(), "a"), "p")
+		if !m.worktrees[0].pinned || !contains(m.View(), "[pinned]") {
+			t.Errorf("Expected feature to be pinned, got %q", m.View())
+		}
+
+		m = press(press(m, "a"), "n")
+		if !m.editingNote {
+			t.Fatal("Expected n to edit the note")
+		}
+		m = press(m, "ship it")
+		m = press(m, "enter")
+		if m.editingNote || m.worktrees[0].note != "ship it" {
+			t.Errorf("Expected the note to be saved, got %q", m.worktrees[0].note)
+		}
+
+		recorded := loadRecordedWorktrees()["feature"]
+		if recorded == nil || !recorded.Pinned || recorded.Note != "ship it" {
+			t.Errorf("Expected pin and note to be recorded, got %+v", recorded)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+// mainCheckoutName is the reserved name addressing the repository's main checkout.
+// It can't be used for koh worktrees.
+const mainCheckoutName = "main"
+
+// resolveMainCheckout returns the configured main checkout (nil when not
+// configured) and its absolute path. Without configuration the main
+// repository root is the main checkout.
+func resolveMainCheckout(mainRepoRoot string) (*config.MainCheckout, string) {
+	var checkout *config.MainCheckout
+	if exists, err := config.ConfigExists(); err == nil && exists {
+		if cfg, err := config.Load(); err == nil {
+			checkout = cfg.MainCheckout
+		}
+	}
+	return checkout, checkout.ResolvePath(mainRepoRoot)
+}
+
+// samePath reports whether two paths refer to the same location,
+// resolving symlinks when possible
+func samePath(a, b string) bool {
+	resolve := func(p string) string {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			p = real
+		}
+		return filepath.Clean(p)
+	}
+	return resolve(a) == resolve(b)
+}
+
+// guardMainCheckout returns an error when a worktree name or any of the given
+// paths refers to the main checkout, which must never be removed
+func guardMainCheckout(mainRepoRoot, worktreeName string, paths ...string) error {
+	if worktreeName == mainCheckoutName {
+		return fmt.Errorf("refusing to clean up the main checkout")
+	}
+
+	_, mainPath := resolveMainCheckout(mainRepoRoot)
+	for _, path := range paths {
+		if samePath(path, mainPath) || samePath(path, mainRepoRoot) {
+			return fmt.Errorf("refusing to clean up the main checkout (%s)", mainPath)
+		}
+	}
+	return nil
+}
+
+// switchToMainCheckout switches to the main checkout's tmux window, creating
+// it from the main_checkout window configuration if needed. With background,
+// the window is only created when missing and never selected.
+func switchToMainCheckout(p *output.Printer, mainRepoRoot string, quiet, background bool) (*switchResult, error) {
+	checkout, mainPath := resolveMainCheckout(mainRepoRoot)
+
+	info, err := os.Stat(mainPath)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("main checkout %s does not exist\nUpdate main_checkout.path in .kohconfig", mainPath)
+	}
+
+	exists, err := tmux.WindowExists(mainCheckoutName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing tmux window: %w", err)
+	}
+
+	if exists && background {
+		if !quiet {
+			p.Info("Window for the main checkout already exists")
+		}
+		return &switchResult{Name: mainCheckoutName, Path: mainPath}, nil
+	}
+	if exists {
+		if !quiet {
+			p.Info("Switching to main checkout: %s", mainPath)
+		}
+		if err := tmux.SwitchToWindow(mainCheckoutName); err != nil {
+			return nil, fmt.Errorf("failed to switch to tmux window: %w", err)
+		}
+		return &switchResult{Name: mainCheckoutName, Path: mainPath}, nil
+	}
+
+	if !quiet {
+		p.Info("Creating new tmux session for main checkout: %s", mainPath)
+	}
+
+	repoName, err := git.GetRepoName()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository name: %w", err)
+	}
+
+	windowCfg := checkout.WindowConfig().RenderPaneCommands(config.PaneTemplateData{})
+	createSession := tmux.CreateSessionWithContext
+	if background {
+		createSession = tmux.CreateBackgroundSessionWithContext
+	}
+	if err := createSession(context.Background(), repoName, mainCheckoutName, mainPath, windowCfg); err != nil {
+		return nil, fmt.Errorf("failed to create tmux session: %w", err)
+	}
+	recordPaneIDs(context.Background(), mainCheckoutName)
+	recordPaneCommands(mainCheckoutName, windowCfg)
+
+	return &switchResult{Name: mainCheckoutName, Path: mainPath, Created: true}, nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSamePath(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	if !samePath(dir, link) {
+		t.Errorf("Expected %s and %s to be the same path", dir, link)
+	}
+	if !samePath(dir, dir+"/") {
+		t.Error("Expected trailing slash to be ignored")
+	}
+	if samePath(dir, filepath.Join(dir, "child")) {
+		t.Error("Expected different paths to differ")
+	}
+}
+
+func TestGuardMainCheckout(t *testing.T) {
+	repoRoot := t.TempDir()
+
+	if err := guardMainCheckout(repoRoot, mainCheckoutName); err == nil {
+		t.Error("Expected the reserved name to be refused")
+	}
+
+	if err := guardMainCheckout(repoRoot, "feature", repoRoot); err == nil {
+		t.Error("Expected the repository root to be refused")
+	}
+
+	if err := guardMainCheckout(repoRoot, "feature", filepath.Join(repoRoot, ".koh", "feature")); err != nil {
+		t.Errorf("Expected a koh worktree to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// sanitizeNames turns names 'koh new' would reject into slugs, from
+// sanitize_names in the global config
+var sanitizeNames = false
+
+// slugNames replaces the names that aren't valid worktree names, such as
+// the branch name feature/login, with slugs such as feature-login. It
+// returns the names to use and, by index, the name typed for each slug, ""
+// where the name was kept. Names no slug can save are kept, so they fail
+// validation under the name that was typed.
+func slugNames(p *output.Printer, names []string) (slugs, typed []string) {
+	slugs = make([]string, len(names))
+	typed = make([]string, len(names))
+	for i, name := range names {
+		slugs[i] = name
+		if validation.ValidateWorktreeName(name) == nil {
+			continue
+		}
+		slug := validation.Normalize(name)
+		if slug == "" || validation.ValidateWorktreeName(slug) != nil {
+			continue
+		}
+		p.Info("Using worktree name %s for %s", slug, name)
+		slugs[i] = slug
+		typed[i] = name
+	}
+	return slugs, typed
+}
+
+// slugBranches maps the worktree names finally used to the branch names
+// typed for them, given the typed names slugNames returned. names may have
+// been renamed since, such as to feature-login-2, but keep their order.
+func slugBranches(names, typed []string) map[string]string {
+	branches := map[string]string{}
+	for i, name := range names {
+		if i < len(typed) && typed[i] != "" {
+			branches[name] = typed[i]
+		}
+	}
+	return branches
+}
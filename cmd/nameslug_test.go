@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestSlugNames(t *testing.T) {
+	p := output.New(io.Discard, output.Human)
+	slugs, typed := slugNames(p, []string{"feature/login", "auth", "..", "bshakr/fix auth"})
+
+	if want := []string{"feature-login", "auth", "..", "bshakr-fix-auth"}; !reflect.DeepEqual(slugs, want) {
+		t.Errorf("Expected names %v, got %v", want, slugs)
+	}
+	if want := []string{"feature/login", "", "", "bshakr/fix auth"}; !reflect.DeepEqual(typed, want) {
+		t.Errorf("Expected typed names %v, got %v", want, typed)
+	}
+
+	// Renamed worktrees keep the branch typed for them
+	got := slugBranches([]string{"feature-login-2", "auth", "..", "bshakr-fix-auth"}, typed)
+	want := map[string]string{"feature-login-2": "feature/login", "bshakr-fix-auth": "bshakr/fix auth"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCreateWorktreeSlugBranch(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	p := output.New(io.Discard, output.Human)
+
+	result, err := createWorktree(p, "feature-login", newOptions{noTmux: true, slugBranches: map[string]string{"feature-login": "feature/login"}})
+	if err != nil {
+		t.Fatalf("createWorktree() failed: %v", err)
+	}
+	if result.Branch != "feature/login" {
+		t.Errorf("Expected the branch feature/login, got %q", result.Branch)
+	}
+	if want := filepath.Join(repo, ".koh", "feature-login"); result.Path != want {
+		t.Errorf("Expected the worktree at %s, got %s", want, result.Path)
+	}
+
+	if _, err := createWorktree(p, "bad-branch", newOptions{noTmux: true, slugBranches: map[string]string{"bad-branch": "bad branch"}}); err == nil {
+		t.Error("Expected an error for a typed name git rejects as a branch")
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// timestampSuffixLayout is the time format of timestamp suffixes, such as
+// "spike-20250630-1405"
+const timestampSuffixLayout = "20060102-1504"
+
+// nextFreeName returns the first of name-2, name-3, … that isn't taken
+func nextFreeName(name string, taken func(string) bool) string {
+	for n := 2; ; n++ {
+		if candidate := fmt.Sprintf("%s-%d", name, n); !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// timestampName returns name with the time as a suffix, or the first free
+// numbered variant of that when two worktrees are created within a minute
+func timestampName(name string, now time.Time, taken func(string) bool) string {
+	candidate := name + "-" + now.Format(timestampSuffixLayout)
+	if !taken(candidate) {
+		return candidate
+	}
+	return nextFreeName(candidate, taken)
+}
+
+// nameTakenFunc returns a check for whether a name is taken for a new
+// worktree created with opts: its directory exists, another name in the
+// same run already claimed it, or, when koh creates the branch, the branch
+// it would create exists. The requested name only counts as taken by its
+// directory, since reusing a branch of that name is what 'koh new' does.
+func nameTakenFunc(ctx context.Context, mainRepoRoot string, opts newOptions, claimed map[string]bool) func(string) bool {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	newBranch := opts.branch == "" && opts.remote == "" && opts.pr == 0
+	return func(name string) bool {
+		if claimed[name] {
+			return true
+		}
+		if _, err := os.Stat(kohWorktreePath(mainRepoRoot, name)); err == nil {
+			return true
+		}
+		if !newBranch {
+			return false
+		}
+		branch, err := newBranchName(ctx, cfg, name)
+		return err == nil && git.BranchExistsWithContext(ctx, branch)
+	}
+}
+
+// Answers to the name collision prompt
+const (
+	suffixNumber    = "n"
+	suffixTimestamp = "t"
+	suffixAbort     = "a"
+)
+
+// parseSuffixAnswer maps an answer to the name collision prompt to a
+// choice, taking the numbered name by default
+func parseSuffixAnswer(answer string) (string, bool) {
+	switch strings.ToLower(answer) {
+	case "", "n", "number":
+		return suffixNumber, true
+	case "t", "timestamp":
+		return suffixTimestamp, true
+	case "a", "abort":
+		return suffixAbort, true
+	}
+	return "", false
+}
+
+// resolveNameCollisions returns the names to create worktrees under. A name
+// whose worktree already exists gets a free numbered suffix with auto, and
+// otherwise the user is asked whether to take a numbered or timestamp
+// suffix. Without a terminal to ask on, and in JSON mode, names are kept and
+// 'koh new' fails as usual.
+func resolveNameCollisions(ctx context.Context, p *output.Printer, names []string, opts newOptions, auto bool) ([]string, error) {
+	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
+	if err != nil {
+		return names, nil
+	}
+
+	claimed := map[string]bool{}
+	taken := nameTakenFunc(ctx, mainRepoRoot, opts, claimed)
+	ask := !auto && !p.IsJSON() && stdinIsTerminal()
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		_, err := os.Stat(kohWorktreePath(mainRepoRoot, name))
+		if !claimed[name] && err != nil {
+			claimed[name] = true
+			resolved = append(resolved, name)
+			continue
+		}
+
+		var renamed string
+		switch {
+		case auto:
+			renamed = nextFreeName(name, taken)
+			p.Info("Worktree %s already exists, creating %s", name, renamed)
+		case ask:
+			numbered := nextFreeName(name, taken)
+			stamped := timestampName(name, time.Now(), taken)
+			for renamed == "" {
+				answer, err := p.Prompt(stdin, "Worktree %s already exists. Create [n] %s, [t] %s or [a]bort? [N/t/a] ", name, numbered, stamped)
+				if err != nil {
+					return nil, errAborted
+				}
+				switch choice, _ := parseSuffixAnswer(answer); choice {
+				case suffixNumber:
+					renamed = numbered
+				case suffixTimestamp:
+					renamed = stamped
+				case suffixAbort:
+					return nil, errAborted
+				}
+			}
+		default:
+			renamed = name
+		}
+		claimed[renamed] = true
+		resolved = append(resolved, renamed)
+	}
+	return resolved, nil
+}
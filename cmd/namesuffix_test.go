@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestSuffixedNames(t *testing.T) {
+	taken := map[string]bool{"spike-2": true, "spike-3": true, "spike-20250630-1405": true}
+	isTaken := func(name string) bool { return taken[name] }
+
+	if got := nextFreeName("spike", isTaken); got != "spike-4" {
+		t.Errorf("Expected spike-4, got %s", got)
+	}
+	now := time.Date(2025, 6, 30, 14, 5, 0, 0, time.UTC)
+	if got := timestampName("exp", now, isTaken); got != "exp-20250630-1405" {
+		t.Errorf("Expected exp-20250630-1405, got %s", got)
+	}
+	if got := timestampName("spike", now, isTaken); got != "spike-20250630-1405-2" {
+		t.Errorf("Expected spike-20250630-1405-2, got %s", got)
+	}
+}
+
+func TestParseSuffixAnswer(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{"", suffixNumber, true},
+		{"N", suffixNumber, true},
+		{"timestamp", suffixTimestamp, true},
+		{"a", suffixAbort, true},
+		{"x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSuffixAnswer(tt.answer)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseSuffixAnswer(%q) = %q, %v; want %q, %v", tt.answer, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolveNameCollisionsAuto(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	if err := os.MkdirAll(kohWorktreePath(repo, "spike"), 0o755); err != nil {
+		t.Fatalf("Failed to create worktree directory: %v", err)
+	}
+	// A branch left over from an earlier spike-2 is skipped too
+	testutil.Git(t, repo, "branch", "spike-2")
+	p := output.New(io.Discard, output.Human)
+
+	got, err := resolveNameCollisions(context.Background(), p, []string{"spike", "other", "spike"}, newOptions{}, true)
+	if err != nil {
+		t.Fatalf("resolveNameCollisions() failed: %v", err)
+	}
+	if want := []string{"spike-3", "other", "spike-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Without --auto-suffix and a terminal, names are left for 'koh new' to reject
+	got, _ = resolveNameCollisions(context.Background(), p, []string{"spike"}, newOptions{}, false)
+	if !reflect.DeepEqual(got, []string{"spike"}) {
+		t.Errorf("Expected the name to be kept, got %v", got)
+	}
+}
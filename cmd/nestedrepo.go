@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// repoDir is the repository 'koh new', 'koh init' and 'koh switch --create'
+// use, as if koh was started in it
+var repoDir string
+
+// repoFlagUsage describes --repo
+const repoFlagUsage = "Use the repository at this path, e.g. from inside a nested repository or submodule"
+
+// nestedRepo is a repository inside the work tree of another one
+type nestedRepo struct {
+	// Inner is the work tree of the repository the current directory is in
+	Inner string
+	// Outer is the work tree of the repository around it
+	Outer string
+	// Submodule is set when Inner is a submodule of Outer
+	Submodule bool
+}
+
+// detectNestedRepo reports whether the repository the current directory is
+// in sits inside another repository's work tree, either as a submodule or
+// as a repository of its own. koh's own worktrees inside .koh are part of
+// the repository around them, so they don't count.
+func detectNestedRepo(ctx context.Context, info *git.RepoInfo) *nestedRepo {
+	if super := git.SuperprojectWithContext(ctx); super != "" {
+		return &nestedRepo{Inner: info.TopLevel, Outer: super, Submodule: true}
+	}
+	outer, err := git.RepoInfoAtWithContext(ctx, filepath.Dir(info.TopLevel))
+	if err != nil || outer.CommonDir == info.CommonDir {
+		return nil
+	}
+	return &nestedRepo{Inner: info.TopLevel, Outer: outer.TopLevel}
+}
+
+// kohConfigured reports whether the repository with work tree dir has a .kohconfig
+func kohConfigured(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".kohconfig"))
+	return err == nil
+}
+
+// resolveNestedRepo makes sure commands that create worktrees or
+// configuration run in the repository the user means. With repo set, koh
+// moves there, as if it was started in it. Otherwise, inside a nested
+// repository where only one of the two is set up for koh, that one is used;
+// when it's unclear, koh asks whether to use the inner or the outer one,
+// and without a terminal to ask on uses the inner one with a warning. Inside
+// a submodule, whose worktrees koh can't manage, it offers the superproject
+// instead, and fails without a terminal, naming --repo.
+func resolveNestedRepo(p *output.Printer, repo string) error {
+	ctx := context.Background()
+	if repo != "" {
+		if err := os.Chdir(repo); err != nil {
+			return fmt.Errorf("failed to use --repo: %w", err)
+		}
+	}
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		// Commands report being outside a repository themselves
+		return nil
+	}
+	nested := detectNestedRepo(ctx, info)
+	if nested == nil || (repo != "" && !nested.Submodule) {
+		return nil
+	}
+
+	innerConfigured, outerConfigured := kohConfigured(nested.Inner), kohConfigured(nested.Outer)
+	switch {
+	case nested.Submodule && outerConfigured:
+		p.Info("Using the superproject at %s, since koh can't create worktrees for submodules", nested.Outer)
+		return chdirRepo(nested.Outer)
+	case nested.Submodule:
+	case innerConfigured && !outerConfigured:
+		return nil
+	case outerConfigured && !innerConfigured:
+		p.Info("Using the repository at %s, since %s isn't set up for koh", nested.Outer, nested.Inner)
+		return chdirRepo(nested.Outer)
+	}
+
+	if nested.Submodule {
+		if p.IsJSON() || !stdinIsTerminal() {
+			return fmt.Errorf("%s is a submodule of %s, and koh can't create worktrees for submodules\nPass --repo %s to use the superproject", nested.Inner, nested.Outer, nested.Outer)
+		}
+		answer, err := p.Prompt(stdin, "%s is a submodule of %s, and koh can't create worktrees for submodules.\nUse the superproject instead? [y/N] ", nested.Inner, nested.Outer)
+		if err != nil || !strings.EqualFold(answer, "y") {
+			return errAborted
+		}
+		return chdirRepo(nested.Outer)
+	}
+
+	if p.IsJSON() || !stdinIsTerminal() {
+		p.Warn("%s is a repository nested inside %s; using the inner one (pass --repo %s for the outer one)", nested.Inner, nested.Outer, nested.Outer)
+		return nil
+	}
+	for {
+		answer, err := p.Prompt(stdin, "%s is a repository nested inside %s.\nUse the [i]nner or the [o]uter repository? [I/o] ", nested.Inner, nested.Outer)
+		if err != nil {
+			return errAborted
+		}
+		switch strings.ToLower(answer) {
+		case "", "i":
+			return chdirRepo(nested.Inner)
+		case "o":
+			return chdirRepo(nested.Outer)
+		}
+	}
+}
+
+// chdirRepo moves to the work tree of the repository koh should use
+func chdirRepo(dir string) error {
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("failed to change to %s: %w", dir, err)
+	}
+	return nil
+}
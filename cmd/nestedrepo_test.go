@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// newNestedRepo creates a repository inside the work tree of the dashboard
+// repository and returns its path
+func newNestedRepo(t *testing.T, outer string) string {
+	t.Helper()
+	inner := filepath.Join(outer, "vendor", "lib")
+	if err := os.MkdirAll(inner, 0o755); err != nil {
+		t.Fatalf("Failed to create nested repository: %v", err)
+	}
+	runGit(t, "-C", inner, "init", "-q", "-b", "main")
+	runGit(t, "-C", inner, "commit", "-q", "--allow-empty", "-m", "init")
+	return inner
+}
+
+func TestDetectNestedRepo(t *testing.T) {
+	outer := newDashboardRepo(t)
+	inner := newNestedRepo(t, outer)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		dir  string
+		want *nestedRepo
+	}{
+		{name: "main checkout", dir: outer},
+		{name: "koh worktree", dir: filepath.Join(outer, ".koh", "feat-a")},
+		{name: "nested repository", dir: inner, want: &nestedRepo{Inner: inner, Outer: outer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(tt.dir)
+			info, err := git.RepoInfoWithContext(ctx)
+			if err != nil {
+				t.Fatalf("RepoInfoWithContext() failed: %v", err)
+			}
+			got := detectNestedRepo(ctx, info)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveNestedRepo(t *testing.T) {
+	outer := newDashboardRepo(t)
+	inner := newNestedRepo(t, outer)
+	p := output.New(io.Discard, output.Human)
+	oldStdin, oldTerminal := stdin, stdinIsTerminal
+	t.Cleanup(func() { stdin, stdinIsTerminal = oldStdin, oldTerminal })
+	stdinIsTerminal = func() bool { return false }
+
+	cwd := func() string {
+		t.Helper()
+		dir, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("Getwd() failed: %v", err)
+		}
+		return dir
+	}
+
+	// Only the outer repository is set up for koh
+	t.Chdir(inner)
+	if err := resolveNestedRepo(p, ""); err != nil {
+		t.Fatalf("resolveNestedRepo() failed: %v", err)
+	}
+	if got := cwd(); got != outer {
+		t.Errorf("Expected to move to the configured outer repository, got %s", got)
+	}
+
+	// Both are set up, so koh can't tell without asking and keeps to the
+	// inner one
+	if err := os.WriteFile(filepath.Join(inner, ".kohconfig"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Chdir(inner)
+	var warnings bytes.Buffer
+	if err := resolveNestedRepo(output.New(&warnings, output.Human), ""); err != nil {
+		t.Fatalf("resolveNestedRepo() failed: %v", err)
+	}
+	if got := cwd(); got != inner || !strings.Contains(warnings.String(), "--repo") {
+		t.Errorf("Expected to stay in the inner repository with a warning naming --repo, got %s and %q", got, warnings.String())
+	}
+
+	// Giving no answer cancels
+	stdin = strings.NewReader("")
+	stdinIsTerminal = func() bool { return true }
+	if err := resolveNestedRepo(p, ""); !errors.Is(err, errAborted) {
+		t.Errorf("Expected errAborted without an answer, got %v", err)
+	}
+
+	stdin = strings.NewReader("o\n")
+	if err := resolveNestedRepo(p, ""); err != nil {
+		t.Fatalf("resolveNestedRepo() failed: %v", err)
+	}
+	if got := cwd(); got != outer {
+		t.Errorf("Expected to move to the outer repository when asked to, got %s", got)
+	}
+
+	// --repo settles it
+	t.Chdir(outer)
+	if err := resolveNestedRepo(p, inner); err != nil {
+		t.Fatalf("resolveNestedRepo() failed: %v", err)
+	}
+	if got := cwd(); got != inner {
+		t.Errorf("Expected --repo to move to %s, got %s", inner, got)
+	}
+}
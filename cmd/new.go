@@ -1,37 +1,443 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/bshakr/koh/internal/cache"
 	"github.com/bshakr/koh/internal/config"
 	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/ports"
 	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
 	"github.com/bshakr/koh/internal/tmux"
 	"github.com/bshakr/koh/internal/validation"
 	"github.com/spf13/cobra"
 )
 
 var newCmd = &cobra.Command{
-	Use:   "new <worktree-name>",
+	Use:   "new <worktree-name>...",
 	Short: "Create a new worktree and tmux session",
 	Long: `Create a new git worktree and automatically set up a tmux session.
-The session will have one pane for the setup script and additional panes for configured commands.`,
-	Args: cobra.ExactArgs(1),
+The session will have one pane for the setup script and additional panes for configured commands.
+
+The worktree gets a new branch named after it, started from HEAD or from
+the branch, tag or commit given with --base, or picked from a filterable
+list of local and remote branches with --pick. To check out a branch that
+already exists instead, pass it with --branch. To work on a branch from a
+remote, pass it with --remote (e.g. origin/feature-x): koh fetches it and
+creates a local branch of the same name that tracks it. The worktree name
+can then be left out; it defaults to the branch name with / replaced by -.
+
+Set branch_template in .kohconfig to name new branches differently from
+the worktree, e.g. "{{user}}/{{name}}" makes 'koh new login-fix' create
+the branch bshakr/login-fix in the worktree login-fix.
+
+With sanitize_names in the global config, a name that isn't a valid
+worktree name, such as feature/login, names the branch as given and the
+worktree with a slug of it, feature-login.
+
+For stacked pull requests, --stack-on starts the new branch from another
+worktree's branch and records the stack, e.g. koh new api-tests
+--stack-on api. See 'koh stack'. When that worktree's HEAD is detached, the
+new branch starts from its commit instead, without a stack. Uncommitted
+changes in it are not carried over.
+
+To set the worktree up with one of the profiles in .kohconfig instead of
+the top-level setup script and pane commands, pass --profile, e.g.
+koh new api-fix --profile backend. The window is recreated with the same
+profile later on.
+
+To review a pull request, pass its number with --pr. koh looks it up with
+the GitHub CLI (gh), fetches its branch (from forks too) and names the
+worktree pr-<number> unless you give a name. A local branch of the pull
+request is fast-forwarded to its head first; one with commits of its own
+that the pull request doesn't have is refused. A branch fetched just for
+the worktree is deleted again if 'koh new' fails.
+
+To open the worktree at the code you mean to change, pass --file with a
+path relative to the repository and optionally a line, e.g.
+app/models/user.rb:42. Panes get it as KOH_FILE and KOH_LINE, and pane
+commands can use it as a template: "nvim +{{.Line}} {{.File}}".
+
+Pass several names to create several worktrees at once, e.g.
+koh new feat-a feat-b feat-c. They share the other flags; one that fails
+doesn't stop the rest, and koh exits non-zero once all were attempted.
+--branch, --remote, --pr, --from-stash and --apply-patch take a single name.
+
+When a worktree of that name already exists, koh asks whether to create
+name-2 (the next free number) or name with a timestamp instead. With
+--auto-suffix it takes the numbered name without asking, which suits
+repeated throwaway spikes; without a terminal, koh fails as before.
+
+Use --bare-create to skip provisioning entirely and get just the worktree
+and an empty tmux window, which is handy for quick throwaway checkouts.
+
+Outside tmux, or on CI, pass --no-tmux to create just the worktree: the
+setup script runs in the current shell and koh waits for it, exiting
+non-zero if it fails. Its output goes to stderr. Pane commands are skipped.
+
+Once the worktree is set up, the commands in hooks.post_create in
+.kohconfig run in it one after another, outside tmux, with KOH_WORKTREE,
+KOH_WORKTREE_PATH and KOH_BRANCH set. A failing hook is reported but
+doesn't undo the worktree.
+
+To start from current branches, pass --fetch, or set fetch_before_new in
+.kohconfig, and koh runs 'git fetch --all --prune' first. When the fetch
+fails, e.g. offline, koh warns and carries on.
+
+When a step fails after the worktree was created, such as opening the tmux
+window or running the setup script with --no-tmux, koh removes the
+worktree again, and the branch when it created it, so the same command can
+simply be retried. A branch that gained commits before the failure is only
+deleted once confirmed. Pass --keep-on-failure to keep the worktree to look into
+what went wrong.
+
+On a terminal, koh shows each step as it runs, with the output of the
+command behind it, and marks it done or failed. When a step fails, koh
+names it and says how to retry.
+
+With --background the window is created but the current window stays
+selected, so you can keep working while the new one sets itself up.
+
+With --open, the worktree is opened in your editor in a new pane of its
+window, or with --no-tmux in the foreground once it's set up. The editor
+is editor from the global config, else $VISUAL or $EDITOR; set
+open_editor there to always open it.
+
+To pick up work that was parked earlier, --from-stash=<entry> applies a
+stash entry (e.g. --from-stash=stash@{0}) and --apply-patch applies a patch
+file to the new worktree.
+Without an entry, --from-stash lists the stash and asks which one to apply,
+or takes the latest when there is no terminal to ask on. The stash is kept;
+drop it yourself once you're happy with the result.
+
+--events-json streams each step as a line of JSON (step_started,
+step_finished, info, warning, error and the final result), to stdout or to
+a file with --events-json=<file>, for wrappers that show their own progress.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := checkStashArgs(args); err != nil {
+			return err
+		}
+		if newPR < 0 {
+			return fmt.Errorf("--pr must be a pull request number")
+		}
+		if newRemote != "" || newPR > 0 {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		if len(args) > 1 {
+			return checkBulkNew(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: runNew,
 }
 
+var (
+	// newBareCreate skips setup and all provisioning steps when set
+	newBareCreate bool
+	// newNoTmux creates no tmux window and runs the setup script in the current shell
+	newNoTmux bool
+	// newBackground creates the window without switching to it
+	newBackground bool
+	// newFromStash is a stash entry to apply to the new worktree
+	newFromStash string
+	// newApplyPatch is a patch file to apply to the new worktree
+	newApplyPatch string
+	// newBranch is an existing local branch to check out instead of a new one
+	newBranch string
+	// newRemote is a remote branch, e.g. "origin/feature", to track
+	newRemote string
+	// newPR is the number of a pull request to check out
+	newPR int
+	// newPick asks for the base branch in an interactive picker
+	newPick bool
+	// newBase is the ref the new branch starts from instead of HEAD
+	newBase string
+	// newFile is a file, optionally with a line, the worktree is opened at
+	newFile string
+	// newEventsJSON is where progress events are streamed, "-" for stdout
+	newEventsJSON string
+	// newProfile is the .kohconfig profile to set the worktree up with
+	newProfile string
+	// newFetch fetches from all remotes before creating the worktree
+	newFetch bool
+	// newStackOn is the worktree whose branch the new one is stacked on
+	newStackOn string
+	// newKeepOnFailure keeps a partially created worktree when a later step fails
+	newKeepOnFailure bool
+	// newOpen opens the worktree in the editor once it's created
+	newOpen bool
+	// newAutoSuffix adds a free numbered suffix to names that are taken
+	newAutoSuffix bool
+)
+
 func init() {
+	newCmd.Flags().StringVar(&newBranch, "branch", "", "Check out an existing local branch instead of creating one")
+	newCmd.Flags().StringVar(&newRemote, "remote", "", "Fetch a remote branch (e.g. origin/feature) and track it in a new local branch")
+	newCmd.Flags().IntVar(&newPR, "pr", 0, "Check out a GitHub pull request by number (requires gh)")
+	newCmd.Flags().BoolVar(&newPick, "pick", false, "Pick the branch to start from in an interactive list")
+	newCmd.Flags().StringVar(&newBase, "base", "", "Branch, tag or commit to start the new branch from (default HEAD)")
+	newCmd.Flags().StringVar(&newStackOn, "stack-on", "", "Start the new branch from another worktree's branch and stack it there (see 'koh stack'), or from its commit when detached")
+	newCmd.Flags().BoolVar(&newFetch, "fetch", false, "Fetch from all remotes, pruning deleted branches, before creating the worktree")
+	newCmd.Flags().StringVar(&newFile, "file", "", "File to open, as path or path:line (KOH_FILE and {{.File}} in pane commands)")
+	newCmd.Flags().StringVar(&newProfile, "profile", "", "Set the worktree up with a profile from .kohconfig")
+	newCmd.Flags().StringVar(&repoDir, "repo", "", repoFlagUsage)
+	newCmd.Flags().BoolVar(&newBareCreate, "bare-create", false, "Create only the worktree and window, skipping setup and provisioning")
+	newCmd.Flags().BoolVar(&newNoTmux, "no-tmux", false, "Create no tmux window; run the setup script in the current shell and wait for it")
+	newCmd.Flags().BoolVar(&newBackground, "background", false, "Create the window without switching to it")
+	newCmd.Flags().StringVar(&newFromStash, "from-stash", "", "Apply a stash entry (e.g. --from-stash=stash@{0}) to the new worktree, or pick one when none is given")
+	newCmd.Flags().Lookup("from-stash").NoOptDefVal = stashPick
+	newCmd.Flags().StringVar(&newApplyPatch, "apply-patch", "", "Apply a patch file to the new worktree")
+	newCmd.Flags().BoolVar(&newKeepOnFailure, "keep-on-failure", false, "Keep the worktree when a step after creating it fails, instead of removing it")
+	newCmd.Flags().BoolVar(&newAutoSuffix, "auto-suffix", false, "When the worktree already exists, create name-2 (or the next free number) instead of failing")
+	newCmd.Flags().BoolVar(&newOpen, "open", false, "Open the worktree in your editor, in a new pane of its window (default from open_editor)")
+	newCmd.MarkFlagsMutuallyExclusive("from-stash", "apply-patch")
+	newCmd.MarkFlagsMutuallyExclusive("base", "branch", "remote", "pr", "pick", "stack-on")
+	newCmd.MarkFlagsMutuallyExclusive("no-tmux", "background")
+	newCmd.MarkFlagsMutuallyExclusive("profile", "bare-create")
+	_ = newCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	_ = newCmd.RegisterFlagCompletionFunc("base", completeBranchRefs(true))
+	_ = newCmd.RegisterFlagCompletionFunc("branch", completeBranchRefs(false))
+	addEventsFlag(newCmd, &newEventsJSON)
 	rootCmd.AddCommand(newCmd)
 }
 
-func runNew(_ *cobra.Command, args []string) error {
-	worktreeName := args[0]
+// newResult is the machine-readable result of 'koh new'
+type newResult struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+	// Profile is the .kohconfig profile the worktree was set up with
+	Profile string `json:"profile,omitempty"`
+	// Window is the tmux window, "" with --no-tmux
+	Window string `json:"window"`
+	// WindowID is the tmux ID of the window (e.g. "@3"), "" with --no-tmux
+	WindowID string `json:"window_id,omitempty"`
+	// Panes are the tmux IDs of the window's panes (e.g. "%7"), indexed by
+	// pane number
+	Panes []string `json:"panes,omitempty"`
+	// CopiedFiles are the files copied from the main repository by copy_files
+	CopiedFiles []string `json:"copied_files,omitempty"`
+	// LinkedDirs are the directories shared with the main repository by link_dirs
+	LinkedDirs []string `json:"linked_dirs,omitempty"`
+	// Ports are the ports reserved for the worktree (see ports in .kohconfig)
+	Ports []int `json:"ports,omitempty"`
+	// StackedOn is the worktree the new one is stacked on (see 'koh stack')
+	StackedOn string `json:"stacked_on,omitempty"`
+	// FailedHooks are the post_create hooks that exited non-zero
+	FailedHooks []string `json:"failed_hooks,omitempty"`
+}
+
+func runNew(cmd *cobra.Command, args []string) error {
+	p, closeEvents, err := newEventsPrinter(cmd, newEventsJSON)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+
+	if err := resolveNestedRepo(p, repoDir); err != nil {
+		return err
+	}
+
+	fromStash := newFromStash
+	names := args
+	switch {
+	case len(args) > 0:
+	case newPR > 0:
+		names = []string{prWorktreeName(newPR)}
+	default:
+		// Without the remotes, fetchRemoteBranch reports the problem
+		remotes, _ := git.ListRemotesWithContext(context.Background())
+		names = []string{remoteWorktreeName(remotes, newRemote)}
+	}
+
+	opts := newOptions{
+		branch:        newBranch,
+		remote:        newRemote,
+		pr:            newPR,
+		base:          newBase,
+		file:          newFile,
+		profile:       newProfile,
+		bare:          newBareCreate,
+		noTmux:        newNoTmux,
+		background:    newBackground,
+		fromStash:     fromStash,
+		applyPatch:    newApplyPatch,
+		fetch:         newFetch,
+		stackOn:       newStackOn,
+		keepOnFailure: newKeepOnFailure,
+		open:          wantsOpenEditor(cmd, newOpen),
+	}
+
+	progress := useNewProgress(p, names, opts)
+
+	// Fetch once up front, so the picker lists current branches and several
+	// worktrees don't fetch one after another. The progress view shows the
+	// fetch as a step of creating the worktree instead.
+	if wantsFetchBeforeNew(opts) && (!progress || newPick) {
+		ctx, cleanup := signals.SetupCancellableContext()
+		err := fetchBeforeNew(ctx, p)
+		cleanup()
+		if err != nil {
+			p.Fail(err)
+			return err
+		}
+		opts.fetched = true
+	}
+
+	if newPick {
+		if opts.base, err = pickBaseBranch(strings.Join(names, ", ")); err != nil {
+			p.Fail(err)
+			return err
+		}
+	}
+
+	if opts.fromStash == stashPick {
+		if opts.fromStash, err = pickStash(context.Background(), p); err != nil {
+			p.Fail(err)
+			return err
+		}
+	}
+
+	// Names are checked and taken under the lock, so a concurrent 'koh new'
+	// can't claim the same one
+	unlock, err := lockRepo(p, commandLine(cmd.CommandPath(), names))
+	if err != nil {
+		p.Fail(err)
+		return err
+	}
+	defer unlock()
+
+	var typed []string
+	if sanitizeNames && len(args) > 0 {
+		names, typed = slugNames(p, names)
+	}
+	if names, err = resolveNameCollisions(context.Background(), p, names, opts, newAutoSuffix); err != nil {
+		p.Fail(err)
+		return err
+	}
+	opts.slugBranches = slugBranches(names, typed)
+
+	// Point out worktrees hogging the disk before adding another one
+	if worktrees, err := loadKohWorktrees(context.Background()); err == nil {
+		warnDiskQuota(context.Background(), p, worktrees)
+	}
+
+	if len(names) > 1 {
+		if opts.open && opts.noTmux {
+			p.Warn("Not opening the editor: without windows it can only open a single worktree")
+		}
+		return runNewBulk(cmd, p, names, opts)
+	}
+
+	var result *newResult
+	if progress {
+		result, err = createWorktreeWithProgress(names[0], opts)
+	} else {
+		result, err = createWorktree(p, names[0], opts)
+	}
+	if err != nil {
+		if errors.Is(err, errSilentFailure) {
+			// The progress view already showed the error
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return err
+		}
+		p.Fail(err)
+		return err
+	}
+
+	if err := p.Result(result, func(w io.Writer) {
+		fprintln(w, "Worktree setup complete!")
+		if result.Window == "" {
+			fprintln(w, styles.Muted.Render("cd "+result.Path))
+		}
+	}); err != nil {
+		return err
+	}
+
+	// Without a window the editor takes over the terminal instead
+	if opts.open && result.Window == "" {
+		if !stdinIsTerminal() || p.IsJSON() {
+			p.Warn("Not opening the editor without a terminal")
+			return nil
+		}
+		return runEditor(result.Path)
+	}
+	return nil
+}
+
+// newOptions controls how createWorktree provisions a worktree
+type newOptions struct {
+	// branch is an existing local branch to check out, "" for a new branch
+	// named after the worktree
+	branch string
+	// remote is a remote branch to fetch and track in a new local branch
+	remote string
+	// pr is a pull request whose branch is checked out, 0 for none
+	pr int
+	// base is the ref a new branch starts from, "" for HEAD
+	base string
+	// file is the file to open, as "path" or "path:line"
+	file string
+	// profile is the .kohconfig profile to set the worktree up with, "" for
+	// the top-level setup
+	profile string
+	// bare skips the setup script and all provisioning steps
+	bare bool
+	// noTmux creates no tmux window and runs the setup script in the
+	// current shell instead of a pane
+	noTmux bool
+	// background creates the window without switching to it
+	background bool
+	// fromStash is a stash entry applied to the new worktree
+	fromStash string
+	// applyPatch is a patch file applied to the new worktree
+	applyPatch string
+	// fetch fetches from all remotes first, as fetch_before_new does
+	fetch bool
+	// fetched is set when the caller already fetched
+	fetched bool
+	// stackOn is the worktree the new one is stacked on, "" for none. The
+	// new branch starts from its branch, or its commit when it's detached.
+	stackOn string
+	// slugBranches are the branch names typed as worktree names and turned
+	// into slugs by sanitize_names, keyed by worktree name. The branch is
+	// created under the name as typed.
+	slugBranches map[string]string
+	// keepOnFailure keeps the worktree when a step after creating it fails,
+	// instead of rolling it back
+	keepOnFailure bool
+	// open opens the editor in a new pane of the window. Without a window
+	// the caller runs it once the worktree is set up.
+	open bool
+	// noPrompt keeps createWorktree from asking anything, for callers that
+	// hold the repository lock; questions get their non-interactive answer
+	noPrompt bool
+}
+
+// createWorktree runs the full 'koh new' pipeline: it creates the git worktree
+// and its tmux window, or with opts.noTmux runs the setup script in the
+// current shell. It is shared by 'koh new' and 'koh switch --create'. When a
+// step fails once the worktree exists, the worktree is rolled back unless
+// opts.keepOnFailure is set.
+func createWorktree(p *output.Printer, worktreeName string, opts newOptions) (_ *newResult, err error) {
+	p.Step("prepare")
 
 	// Validate worktree name for security
 	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
-		return fmt.Errorf("invalid worktree name: %w", err)
+		return nil, fmt.Errorf("invalid worktree name: %w", err)
+	}
+	if worktreeName == mainCheckoutName {
+		return nil, fmt.Errorf("invalid worktree name: %q is reserved for the main checkout", mainCheckoutName)
 	}
 
 	// Set up context with cancellation for long-running operations and signal handling
@@ -40,28 +446,22 @@ func runNew(_ *cobra.Command, args []string) error {
 
 	// Check if we're in a git repository
 	if !git.IsGitRepo() {
-		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+		return nil, fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
 	}
 
-	// Check if config exists, if not prompt user to run init
-	exists, err := config.ConfigExists()
+	// Load configuration (bare creation doesn't need one)
+	cfg, err := loadNewConfig(opts.bare)
 	if err != nil {
-		return fmt.Errorf("failed to check for .kohconfig: %w", err)
+		return nil, err
 	}
-	if !exists {
-		return fmt.Errorf("no .kohconfig found\nPlease run 'koh init' to set up your configuration first")
-	}
-
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	if cfg, err = cfg.ForProfile(opts.profile); err != nil {
+		return nil, err
 	}
 
 	// Determine the main repo root (handles both main repo and worktrees)
 	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
 	if err != nil {
-		return fmt.Errorf("failed to get repository root: %w", err)
+		return nil, fmt.Errorf("failed to get repository root: %w", err)
 	}
 
 	// Check if setup script exists and is within repository boundaries
@@ -70,45 +470,586 @@ func runNew(_ *cobra.Command, args []string) error {
 
 		// Validate setup script is within repository (security check)
 		if err := validation.ValidatePathWithinRepository(setupPath, mainRepoRoot); err != nil {
-			return fmt.Errorf("setup script %w\nAttempted path: %s", err, cfg.SetupScript)
+			return nil, fmt.Errorf("setup script %w\nAttempted path: %s", err, cfg.SetupScript)
 		}
 
 		// Check if the script exists
 		if _, err := os.Stat(setupPath); os.IsNotExist(err) {
-			return fmt.Errorf("%s not found\nPlease create a setup script at %s", cfg.SetupScript, cfg.SetupScript)
+			return nil, fmt.Errorf("%s not found\nPlease create a setup script at %s", cfg.SetupScript, cfg.SetupScript)
 		}
 	}
 
-	// Create .koh directory if it doesn't exist
-	koDir := filepath.Join(mainRepoRoot, ".koh")
+	// Check the stash or patch up front so a typo doesn't leave a half-made worktree
+	patchPath, err := checkParkedWork(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var file config.PaneTemplateData
+	if opts.file != "" {
+		if file, err = parseFileArg(opts.file, mainRepoRoot); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.fetched && (opts.fetch || cfg.FetchBeforeNew) {
+		if err := fetchBeforeNew(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	// A branch fetched for a pull request just now goes again if 'koh new'
+	// fails before the worktree exists; after that, the rollback owns it
+	var fetchedBranch string
+	if opts.pr > 0 {
+		p.Step("pull_request")
+		if fetchedBranch, err = preparePullRequest(ctx, p, &opts); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if err == nil || fetchedBranch == "" {
+			return
+		}
+		if deleteErr := git.DeleteBranchWithContext(context.Background(), fetchedBranch); deleteErr != nil {
+			p.Warn("Failed to delete branch %s: %v", fetchedBranch, deleteErr)
+		}
+	}()
+
+	branch := worktreeName
+	if opts.branch != "" {
+		if err := checkExistingBranch(ctx, opts.branch); err != nil {
+			return nil, err
+		}
+		branch = opts.branch
+	}
+	var stackBase string
+	if opts.stackOn != "" {
+		var stackBranch string
+		if stackBranch, stackBase, err = stackOnBase(ctx, p, opts.stackOn); err != nil {
+			return nil, err
+		}
+		opts.base = stackBranch
+		if stackBranch == "" {
+			// Without a branch there's nothing to stack on
+			p.Info("%s is detached; starting from its commit without stacking", opts.stackOn)
+			opts.base, opts.stackOn = stackBase, ""
+		}
+	}
+	if err := checkBase(ctx, opts.base); err != nil {
+		return nil, err
+	}
+	if typed := opts.slugBranches[worktreeName]; typed != "" && opts.branch == "" && opts.remote == "" {
+		if err := git.CheckBranchNameWithContext(ctx, typed); err != nil {
+			return nil, fmt.Errorf("invalid branch name: %w", err)
+		}
+		branch = typed
+	} else if opts.branch == "" && opts.remote == "" {
+		if branch, err = newBranchName(ctx, cfg, worktreeName); err != nil {
+			return nil, err
+		}
+	}
+	if opts.remote != "" {
+		p.Step("fetch")
+		if branch, err = fetchRemoteBranch(ctx, p, opts.remote); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create the worktree directory if it doesn't exist
+	koDir := config.WorktreeDir(mainRepoRoot)
 	//nolint:gosec // G301: 0755 is standard permission for user directories
 	if err := os.MkdirAll(koDir, 0755); err != nil {
-		return fmt.Errorf("failed to create .koh directory: %w", err)
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
 	}
 
 	// Check if worktree already exists
 	worktreePath := filepath.Join(koDir, worktreeName)
+	label := worktreeLabel(mainRepoRoot, worktreeName)
 	if _, err := os.Stat(worktreePath); err == nil {
-		return fmt.Errorf("worktree .koh/%s already exists", worktreeName)
+		return nil, fmt.Errorf("worktree %s already exists\nPass --auto-suffix to pick a free name such as %s-2", label, worktreeName)
+	}
+
+	// A new branch goes with the worktree if a later step fails; one that
+	// was there before stays
+	rollback := &newRollback{mainRepoRoot: mainRepoRoot, name: worktreeName, path: worktreePath}
+	if opts.branch == "" && !git.BranchExistsWithContext(ctx, branch) {
+		rollback.branch = branch
+	}
+
+	// With sparse_paths, files are checked out once the worktree is limited
+	// to them
+	var addOpts []git.AddOption
+	if len(cfg.SparsePaths) > 0 {
+		addOpts = append(addOpts, git.NoCheckout)
 	}
 
 	// Create git worktree with context
-	fmt.Printf("Creating git worktree: .koh/%s\n", worktreeName)
-	if err := git.CreateWorktreeWithContext(ctx, worktreePath); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+	p.Step("create_worktree")
+	p.Info("Creating git worktree: %s", label)
+	switch {
+	case opts.branch != "":
+		err = git.CreateWorktreeForBranchWithContext(ctx, worktreePath, opts.branch, addOpts...)
+	case opts.remote != "":
+		err = git.CreateWorktreeTrackingWithContext(ctx, worktreePath, branch, opts.remote, addOpts...)
+	case opts.base != "":
+		p.Info("Starting branch %s from %s", branch, opts.base)
+		err = git.CreateWorktreeFromRefWithContext(ctx, worktreePath, branch, opts.base, addOpts...)
+	case branch == worktreeName:
+		err = git.CreateWorktreeWithContext(ctx, worktreePath, addOpts...)
+	case rollback.branch == "":
+		// branch_template named an existing branch
+		err = git.CreateWorktreeForBranchWithContext(ctx, worktreePath, branch, addOpts...)
+	default:
+		err = git.CreateWorktreeFromRefWithContext(ctx, worktreePath, branch, "HEAD", addOpts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+	if fetchedBranch != "" {
+		rollback.branch, fetchedBranch = fetchedBranch, ""
+	}
+	if rollback.branch != "" {
+		rollback.start, _ = git.HeadCommitWithContext(ctx, worktreePath)
+	}
+	defer func() {
+		switch {
+		case err == nil:
+		case opts.keepOnFailure:
+			err = fmt.Errorf("%w\nKept the worktree at %s", err, worktreePath)
+		default:
+			err = rollbackNew(p, rollback, err)
+		}
+	}()
+	if len(cfg.SparsePaths) > 0 {
+		p.Step("sparse_checkout")
+		p.Info("Checking out %s", strings.Join(cfg.SparsePaths, ", "))
+		if err = git.SparseCheckoutWithContext(ctx, worktreePath, cfg.SparsePaths); err != nil {
+			return nil, fmt.Errorf("failed to check out sparse_paths: %w", err)
+		}
+	}
+	invalidateWorktreeCache()
+	recordCreated(worktreeName, branch, fileArg(file), opts.profile)
+	if !opts.background {
+		recordUsed(worktreeName)
+	}
+	if commonDir, err := git.GetCommonDir(); err == nil {
+		switch {
+		case opts.stackOn != "":
+			_ = recordStackParent(commonDir, worktreeName, opts.stackOn, stackBase)
+		case opts.base != "":
+			_ = recordBase(commonDir, worktreeName, opts.base)
+		}
+	}
+
+	if file.File != "" {
+		if _, err := os.Stat(filepath.Join(worktreePath, file.File)); err != nil {
+			p.Warn("%s does not exist in the worktree yet", file.File)
+		}
+	}
+
+	if len(cfg.GitConfig) > 0 {
+		p.Step("git_config")
+		applyGitConfig(ctx, p, worktreePath, cfg.GitConfig)
+	}
+
+	var copied []string
+	if len(cfg.CopyFiles) > 0 {
+		p.Step("copy_files")
+		copied = copyWorktreeFiles(ctx, p, mainRepoRoot, worktreePath, cfg)
+	}
+
+	var linked []string
+	if len(cfg.LinkDirs) > 0 {
+		p.Step("link_dirs")
+		linked = linkWorktreeDirs(ctx, p, mainRepoRoot, worktreePath, cfg)
+	}
+
+	// Bring in parked work. Conflicts are left in the worktree to resolve there.
+	if opts.fromStash != "" || patchPath != "" {
+		p.Step("apply_parked_work")
+		applyParkedWork(ctx, p, worktreePath, opts.fromStash, patchPath)
+	}
+
+	// Give the window a scratch directory for temporary artifacts (bare creation skips it)
+	var env []string
+	if !opts.bare {
+		p.Step("scratch")
+		if env, err = ensureScratch(mainRepoRoot, worktreeName); err != nil {
+			p.Warn("%v", err)
+		}
+	}
+
+	// Reserve the worktree's ports, so its dev servers don't collide with
+	// other worktrees'
+	var reserved []int
+	if !opts.bare && cfg.Ports != nil {
+		p.Step("ports")
+		if reserved, err = reservePorts(cfg, worktreePath); err != nil {
+			p.Warn("%v", err)
+		} else if len(reserved) > 0 {
+			p.Info("Reserved ports %s", ports.Format(reserved))
+			env = append(env, ports.Env(reserved)...)
+		}
+	}
+
+	env = append(env, configEnv(cfg, worktreeName, worktreePath, env)...)
+
+	result := &newResult{
+		Name:        worktreeName,
+		Path:        worktreePath,
+		Branch:      branch,
+		Profile:     opts.profile,
+		StackedOn:   opts.stackOn,
+		CopiedFiles: copied,
+		LinkedDirs:  linked,
+		Ports:       reserved,
+	}
+
+	if opts.noTmux {
+		p.Step("setup")
+		if cfg, err = confirmSetupScript(ctx, p, mainRepoRoot, worktreePath, cfg, !opts.noPrompt); err != nil {
+			return nil, err
+		}
+		env = append(env, fileEnv(file)...)
+		if err := runSetupScript(ctx, p, worktreePath, cfg.SetupScript, env); err != nil {
+			return nil, err
+		}
+		if hooks := cfg.Hooks.PostCreateHooks(); len(hooks) > 0 {
+			p.Step("post_create")
+			result.FailedHooks = runPostCreateHooks(ctx, p, hooks, result, env)
+		}
+		return result, nil
 	}
 
 	// Get repository name
 	repoName, err := git.GetRepoName()
 	if err != nil {
-		return fmt.Errorf("failed to get repository name: %w", err)
+		return nil, fmt.Errorf("failed to get repository name: %w", err)
+	}
+
+	p.Step("create_window")
+	if cfg, err = confirmSetupScript(ctx, p, mainRepoRoot, worktreePath, cfg, !opts.noPrompt); err != nil {
+		return nil, err
 	}
 
+	cfg = cfg.RenderPaneCommands(file)
+	env = append(env, fileEnv(file)...)
+
 	// Create tmux session with config and context
-	if err := tmux.CreateSessionWithContext(ctx, repoName, worktreeName, worktreePath, cfg); err != nil {
-		return fmt.Errorf("failed to create tmux session: %w", err)
+	createSession := tmux.CreateSessionWithContext
+	if opts.background {
+		createSession = tmux.CreateBackgroundSessionWithContext
+	}
+	if err := createSession(ctx, repoName, worktreeName, worktreePath, cfg, env...); err != nil {
+		if !tmux.IsInTmux() {
+			return nil, fmt.Errorf("failed to create tmux session: %w\nPass --no-tmux to create the worktree without a window", err)
+		}
+		return nil, fmt.Errorf("failed to create tmux session: %w", err)
+	}
+	recordPaneIDs(ctx, worktreeName)
+	recordPaneCommands(worktreeName, cfg)
+	_ = tmux.SetWindowBranchWithContext(ctx, worktreeName, branch)
+
+	result.Window = tmux.WindowName(repoName, worktreeName)
+	if opts.background {
+		p.Info("Created window %s in the background", result.Window)
+	}
+	if opts.open {
+		p.Step("open_editor")
+		if err := openEditorPane(ctx, p, worktreeName, worktreePath); err != nil {
+			p.Warn("%v", err)
+		}
+	}
+	if window, err := tmux.WindowInfoWithContext(ctx, worktreeName); err == nil && window != nil {
+		result.WindowID = window.ID
+	}
+	if panes, err := tmux.PaneIDsWithContext(ctx, worktreeName); err == nil {
+		result.Panes = panes
+	}
+	if hooks := cfg.Hooks.PostCreateHooks(); len(hooks) > 0 {
+		p.Step("post_create")
+		result.FailedHooks = runPostCreateHooks(ctx, p, hooks, result, env)
+	}
+	return result, nil
+}
+
+// checkExistingBranch validates --branch before anything is created
+func checkExistingBranch(ctx context.Context, branch string) error {
+	refs, err := git.ListBranchRefsWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	worktrees, err := git.ListWorktreesWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	return checkBranchAvailable(refs, worktrees, branch)
+}
+
+// checkBranchAvailable reports whether branch is a local branch that isn't
+// checked out in any worktree yet. For a branch that only exists on a remote,
+// the error says how to create a local branch from it.
+func checkBranchAvailable(refs []git.Ref, worktrees []git.Worktree, branch string) error {
+	var local []git.Ref
+	for _, ref := range refs {
+		if !ref.Remote {
+			local = append(local, ref)
+		}
+	}
+
+	if err := git.CheckBranchRef(local, branch); err != nil {
+		for _, ref := range refs {
+			if !ref.Remote {
+				continue
+			}
+			if _, name, ok := strings.Cut(ref.Name, "/"); ok && (name == branch || ref.Name == branch) {
+				return fmt.Errorf("%w\nCreate a local branch from %s first: git branch --track %s %s", err, ref.Name, name, ref.Name)
+			}
+		}
+		return err
+	}
+
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			return fmt.Errorf("branch %q is already checked out at %s", branch, wt.Path)
+		}
+	}
+	return nil
+}
+
+// pickBaseBranch asks for the branch a new worktree starts from
+func pickBaseBranch(worktreeName string) (string, error) {
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("--pick needs a terminal\nPass the branch with --base instead")
+	}
+	branches, err := git.ListBranchesWithContext(context.Background())
+	if err != nil {
+		return "", err
+	}
+
+	base, err := pickBranch("Start "+worktreeName+" from", branches)
+	if err != nil {
+		return "", err
+	}
+	if base == "" {
+		return "", errAborted
+	}
+	return base, nil
+}
+
+// remoteWorktreeName returns the default worktree name for a remote branch,
+// e.g. "feature-login" for "origin/feature/login". The longest of remotes the
+// ref starts with is stripped, so remotes with a '/' in their name work too.
+func remoteWorktreeName(remotes []string, remoteRef string) string {
+	branch := remoteRef
+	if _, b, ok := git.SplitRemoteRef(remotes, remoteRef); ok {
+		branch = b
+	}
+	return validation.Normalize(branch)
+}
+
+// fetchRemoteBranch fetches the branch of a remote ref such as
+// "origin/feature" and returns the name of the local branch to create for it
+func fetchRemoteBranch(ctx context.Context, p *output.Printer, remoteRef string) (string, error) {
+	remotes, err := git.ListRemotesWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	remote, branch, ok := git.SplitRemoteRef(remotes, remoteRef)
+	if !ok {
+		return "", fmt.Errorf("%q is not a remote branch such as origin/feature\nRemotes: %s", remoteRef, strings.Join(remotes, ", "))
+	}
+
+	refs, err := git.ListBranchRefsWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs {
+		if !ref.Remote && ref.Name == branch {
+			return "", fmt.Errorf("branch %q already exists\nUse --branch %s to check it out", branch, branch)
+		}
 	}
 
-	fmt.Println("Worktree setup complete!")
+	p.Info("Fetching %s from %s", branch, remote)
+	if err := git.FetchBranchWithContext(ctx, remote, branch); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// wantsFetchBeforeNew reports whether creating worktrees with opts starts
+// with a fetch, because of --fetch or fetch_before_new. Bare creation
+// ignores the configuration, as it does everywhere else.
+func wantsFetchBeforeNew(opts newOptions) bool {
+	switch {
+	case opts.fetched:
+		return false
+	case opts.fetch:
+		return true
+	case opts.bare:
+		return false
+	}
+	cfg, err := config.Load()
+	return err == nil && cfg.FetchBeforeNew
+}
+
+// fetchBeforeNew fetches from all remotes so new branches start from
+// current ones. git's output goes to stderr so that --json output on stdout
+// stays parseable. A failed fetch, e.g. when offline, is only a warning:
+// the worktree is created from the branches as they are. Interrupting it
+// cancels the command.
+func fetchBeforeNew(ctx context.Context, p *output.Printer) error {
+	p.Step("fetch_remotes")
+	p.Info("Fetching from all remotes")
+	if err := git.FetchAllWithContext(ctx, p.CommandOutput()); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		p.Warn("%v; continuing with the branches as they are", err)
+	}
 	return nil
 }
+
+// checkBase validates --base before anything is created
+func checkBase(ctx context.Context, base string) error {
+	if base == "" {
+		return nil
+	}
+	if strings.HasPrefix(base, "-") || !git.VerifyCommitWithContext(ctx, base) {
+		return fmt.Errorf("base %q does not exist\nPass a branch, tag or commit", base)
+	}
+	return nil
+}
+
+// checkParkedWork validates --from-stash and --apply-patch before anything is
+// created. It returns the absolute path of the patch file, if any.
+func checkParkedWork(ctx context.Context, opts newOptions) (string, error) {
+	if opts.fromStash != "" && !git.VerifyCommitWithContext(ctx, opts.fromStash) {
+		return "", fmt.Errorf("stash %q does not exist\nRun 'git stash list' to see available entries", opts.fromStash)
+	}
+
+	if opts.applyPatch == "" {
+		return "", nil
+	}
+	patchPath, err := filepath.Abs(opts.applyPatch)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve patch path: %w", err)
+	}
+	if _, err := os.Stat(patchPath); err != nil {
+		return "", fmt.Errorf("patch file %s not found", opts.applyPatch)
+	}
+	return patchPath, nil
+}
+
+// applyParkedWork applies a stash or patch to the new worktree. Failures are
+// reported as warnings so the window still opens and the user can resolve them.
+func applyParkedWork(ctx context.Context, p *output.Printer, worktreePath, stash, patchPath string) {
+	switch {
+	case stash != "":
+		p.Info("Applying %s", stash)
+		if err := git.ApplyStashWithContext(ctx, worktreePath, stash); err != nil {
+			p.Warn("Failed to apply %s: %v", stash, err)
+		}
+	case patchPath != "":
+		p.Info("Applying patch %s", patchPath)
+		if err := git.ApplyPatchWithContext(ctx, worktreePath, patchPath); err != nil {
+			p.Warn("Failed to apply patch: %v", err)
+		}
+	}
+}
+
+// applyGitConfig sets the configured git_config keys in the new worktree.
+// Failures are reported as warnings since the worktree is usable without them.
+func applyGitConfig(ctx context.Context, p *output.Printer, worktreePath string, gitConfig map[string]string) {
+	keys := make([]string, 0, len(gitConfig))
+	for key := range gitConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := git.SetWorktreeConfigWithContext(ctx, worktreePath, key, gitConfig[key]); err != nil {
+			p.Warn("%v", err)
+		}
+	}
+}
+
+// completeProfiles completes --profile with the profiles in .kohconfig
+func completeProfiles(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBranchRefs completes branch names from the cached refs, including
+// remote-tracking branches when remote is set
+func completeBranchRefs(remote bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		commonDir, err := git.GetCommonDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		refs, err := cache.Refs(context.Background(), commonDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, ref := range refs {
+			if remote || !ref.Remote {
+				names = append(names, ref.Name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// newBranchName returns the branch koh creates for a new worktree, named
+// after it or as branch_template says
+func newBranchName(ctx context.Context, cfg *config.Config, worktreeName string) (string, error) {
+	branch, err := cfg.BranchName(worktreeName, func() (string, error) {
+		return git.IdentityWithContext(ctx).Handle()
+	})
+	if err != nil {
+		return "", err
+	}
+	if branch != worktreeName {
+		if err := git.CheckBranchNameWithContext(ctx, branch); err != nil {
+			return "", fmt.Errorf("branch_template in .kohconfig gives %w", err)
+		}
+	}
+	return branch, nil
+}
+
+// loadNewConfig returns the configuration used to provision a new worktree.
+// Bare creation uses an empty configuration so the window gets a single pane
+// with no setup script or pane commands.
+func loadNewConfig(bare bool) (*config.Config, error) {
+	if bare {
+		// Branches are still named the repository's way, and huge
+		// repositories still checked out sparsely
+		cfg := &config.Config{}
+		if loaded, err := config.Load(); err == nil {
+			cfg.BranchTemplate = loaded.BranchTemplate
+			cfg.SparsePaths = loaded.SparsePaths
+		}
+		return cfg, nil
+	}
+
+	// Check if config exists, if not prompt user to run init
+	exists, err := config.ConfigExists()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for .kohconfig: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("no .kohconfig found\nPlease run 'koh init' to set up your configuration first")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+	"github.com/bshakr/koh/internal/tmux"
 	"github.com/bshakr/koh/internal/validation"
 )
 
@@ -65,8 +76,8 @@ func TestNewCommandStructure(t *testing.T) {
 		t.Fatal("newCmd is nil")
 	}
 
-	if newCmd.Use != "new <worktree-name>" {
-		t.Errorf("Expected Use 'new <worktree-name>', got %q", newCmd.Use)
+	if newCmd.Use != "new <worktree-name>..." {
+		t.Errorf("Expected Use 'new <worktree-name>...', got %q", newCmd.Use)
 	}
 
 	if newCmd.Short == "" {
@@ -178,3 +189,432 @@ func TestRunNewValidatesInputBeforeSettingUpSignals(t *testing.T) {
 		})
 	}
 }
+
+// TestLoadNewConfigBare verifies bare creation uses an empty configuration
+// without requiring a .kohconfig
+func TestLoadNewConfigBare(t *testing.T) {
+	cfg, err := loadNewConfig(true)
+	if err != nil {
+		t.Fatalf("loadNewConfig(true) failed: %v", err)
+	}
+
+	if cfg.SetupScript != "" {
+		t.Errorf("Expected empty SetupScript for bare creation, got %q", cfg.SetupScript)
+	}
+
+	if len(cfg.PaneCommands) != 0 {
+		t.Errorf("Expected no PaneCommands for bare creation, got %v", cfg.PaneCommands)
+	}
+
+	if newCmd.Flags().Lookup("bare-create") == nil {
+		t.Error("Expected --bare-create flag to be registered")
+	}
+}
+
+func TestCheckParkedWork(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	ctx := context.Background()
+
+	patchPath, err := checkParkedWork(ctx, newOptions{})
+	if err != nil || patchPath != "" {
+		t.Errorf("Expected no patch and no error without options, got %q, %v", patchPath, err)
+	}
+
+	if _, err := checkParkedWork(ctx, newOptions{applyPatch: filepath.Join(t.TempDir(), "missing.diff")}); err == nil {
+		t.Error("Expected error for a missing patch file")
+	}
+
+	patch := filepath.Join(t.TempDir(), "fix.diff")
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(patch, []byte(""), 0644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+	patchPath, err = checkParkedWork(ctx, newOptions{applyPatch: patch})
+	if err != nil {
+		t.Fatalf("checkParkedWork() failed: %v", err)
+	}
+	if !filepath.IsAbs(patchPath) {
+		t.Errorf("Expected absolute patch path, got %q", patchPath)
+	}
+
+	if _, err := checkParkedWork(ctx, newOptions{fromStash: "stash@{999}"}); err == nil {
+		t.Error("Expected error for a missing stash entry")
+	}
+
+	testutil.WriteFile(t, repo, "a.txt", "parked\n")
+	testutil.Git(t, repo, "stash", "-q", "-u")
+	if _, err := checkParkedWork(ctx, newOptions{fromStash: "stash@{0}"}); err != nil {
+		t.Errorf("Expected the stash entry to be accepted, got %v", err)
+	}
+}
+
+func TestCheckBranchAvailable(t *testing.T) {
+	refs := []git.Ref{
+		{Name: "main"},
+		{Name: "feature"},
+		{Name: "in-use"},
+		{Name: "origin/main", Remote: true},
+		{Name: "origin/remote-only", Remote: true},
+	}
+	worktrees := []git.Worktree{
+		{Path: "/repo", Branch: "main"},
+		{Path: "/repo/.koh/other", Branch: "in-use"},
+	}
+
+	tests := []struct {
+		name    string
+		branch  string
+		wantErr string
+	}{
+		{name: "free local branch", branch: "feature"},
+		{name: "missing branch", branch: "missing", wantErr: `branch "missing" does not exist`},
+		{name: "remote only", branch: "remote-only", wantErr: "git branch --track remote-only origin/remote-only"},
+		{name: "remote-tracking name", branch: "origin/remote-only", wantErr: "git branch --track remote-only origin/remote-only"},
+		{name: "checked out elsewhere", branch: "in-use", wantErr: "already checked out at /repo/.koh/other"},
+		{name: "checked out in main checkout", branch: "main", wantErr: "already checked out at /repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBranchAvailable(refs, worktrees, tt.branch)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckBase(t *testing.T) {
+	testutil.NewRepo(t)
+	ctx := context.Background()
+	if err := checkBase(ctx, ""); err != nil {
+		t.Errorf("Expected no error without --base, got %v", err)
+	}
+	if err := checkBase(ctx, "HEAD"); err != nil {
+		t.Errorf("Expected HEAD to be a valid base, got %v", err)
+	}
+	for _, base := range []string{"no-such-ref-koh", "--orphan"} {
+		if err := checkBase(ctx, base); err == nil {
+			t.Errorf("Expected error for base %q", base)
+		}
+	}
+}
+
+func TestRemoteWorktreeName(t *testing.T) {
+	remotes := []string{"origin", "upstream", "team/fork"}
+	tests := map[string]string{
+		"origin/feature-x":         "feature-x",
+		"origin/feature/login":     "feature-login",
+		"upstream/fix":             "fix",
+		"team/fork/fix/crash":      "fix-crash",
+		"origin/feature/Login Bug": "feature-Login-Bug",
+	}
+	for ref, want := range tests {
+		if got := remoteWorktreeName(remotes, ref); got != want {
+			t.Errorf("remoteWorktreeName(%q): Expected %q, got %q", ref, want, got)
+		}
+	}
+}
+
+func TestWorktreeProfileConfig(t *testing.T) {
+	newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	cfg := &config.Config{
+		SetupScript: "./bin/setup",
+		Profiles:    map[string]*config.Profile{"backend": {SetupScript: "./bin/setup-api"}},
+	}
+
+	recordCreated("feat-a", "feat-a", "", "backend")
+	got, err := worktreeProfileConfig(cfg, "feat-a")
+	if err != nil {
+		t.Fatalf("worktreeProfileConfig() failed: %v", err)
+	}
+	if got.SetupScript != "./bin/setup-api" {
+		t.Errorf("Expected the recorded profile's setup script, got %q", got.SetupScript)
+	}
+
+	if got, err := worktreeProfileConfig(cfg, "feat-b"); err != nil || got.SetupScript != "./bin/setup" {
+		t.Errorf("Expected the top-level setup without a recorded profile, got %+v, %v", got, err)
+	}
+
+	delete(cfg.Profiles, "backend")
+	if _, err := worktreeProfileConfig(cfg, "feat-a"); err == nil || !strings.Contains(err.Error(), `created with profile "backend"`) {
+		t.Errorf("Expected an error naming the missing profile, got %v", err)
+	}
+}
+
+func TestWantsFetchBeforeNew(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+
+	tests := []struct {
+		name   string
+		config string
+		opts   newOptions
+		want   bool
+	}{
+		{name: "default", config: `{}`, want: false},
+		{name: "flag", config: `{}`, opts: newOptions{fetch: true}, want: true},
+		{name: "config", config: `{"fetch_before_new": true}`, want: true},
+		{name: "already fetched", config: `{"fetch_before_new": true}`, opts: newOptions{fetch: true, fetched: true}, want: false},
+		{name: "bare ignores config", config: `{"fetch_before_new": true}`, opts: newOptions{bare: true}, want: false},
+		{name: "bare with flag", config: `{}`, opts: newOptions{bare: true, fetch: true}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(repo, ".kohconfig"), []byte(tt.config), 0o644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			if got := wantsFetchBeforeNew(tt.opts); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFetchBeforeNewWarnsOnFailure(t *testing.T) {
+	newDashboardRepo(t)
+	runGit(t, "remote", "add", "origin", filepath.Join(t.TempDir(), "missing"))
+
+	var out strings.Builder
+	p := output.New(&out, output.Human)
+	if err := fetchBeforeNew(context.Background(), p); err != nil {
+		t.Fatalf("Expected a failed fetch not to stop koh new, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: git fetch failed") {
+		t.Errorf("Expected a warning, got %q", out.String())
+	}
+}
+
+func TestNewBranchName(t *testing.T) {
+	newDashboardRepo(t)
+	runGit(t, "config", "github.user", "bshakr")
+	ctx := context.Background()
+
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{template: "", want: "login-fix"},
+		{template: "{{user}}/{{name}}", want: "bshakr/login-fix"},
+		{template: "{{user}}//{{name}}", wantErr: true},
+		{template: "{{user}/{{name}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := newBranchName(ctx, &config.Config{BranchTemplate: tt.template}, "login-fix")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateWorktreeBranchTemplate(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	runGit(t, "config", "github.user", "bshakr")
+	if err := os.WriteFile(filepath.Join(repo, ".kohconfig"), []byte(`{"branch_template": "{{user}}/{{name}}"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	runGit(t, "branch", "bshakr/existing")
+	p := output.New(io.Discard, output.Human)
+
+	for _, name := range []string{"login-fix", "existing"} {
+		result, err := createWorktree(p, name, newOptions{noTmux: true})
+		if err != nil {
+			t.Fatalf("createWorktree(%q) failed: %v", name, err)
+		}
+		if want := "bshakr/" + name; result.Branch != want {
+			t.Errorf("Expected branch %q, got %q", want, result.Branch)
+		}
+		if want := filepath.Join(repo, ".koh", name); result.Path != want {
+			t.Errorf("Expected the worktree at %s, got %s", want, result.Path)
+		}
+	}
+}
+
+func TestCreateWorktreeStackedOnDetached(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	ctx := context.Background()
+	p := output.New(io.Discard, output.Human)
+	featPath := filepath.Join(repo, ".koh", "feat-a")
+	testutil.WriteFile(t, featPath, "api.go", "package api")
+	runGit(t, "-C", featPath, "add", ".")
+	runGit(t, "-C", featPath, "commit", "-q", "-m", "add api")
+	featHead, err := git.ResolveCommitWithContext(ctx, featPath, "HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommitWithContext() failed: %v", err)
+	}
+
+	result, err := createWorktree(p, "feat-b", newOptions{noTmux: true, stackOn: "feat-a"})
+	if err != nil {
+		t.Fatalf("createWorktree() failed: %v", err)
+	}
+	if head, _ := git.ResolveCommitWithContext(ctx, result.Path, "HEAD"); head != featHead {
+		t.Errorf("Expected feat-b to start at feat-a's %s, got %s", featHead, head)
+	}
+	if result.Branch != "feat-b" || result.StackedOn != "feat-a" {
+		t.Errorf("Expected branch feat-b stacked on feat-a, got %+v", result)
+	}
+
+	// A detached worktree is started from at its commit, without a stack
+	runGit(t, "-C", featPath, "checkout", "-q", "--detach", "HEAD~1")
+	result, err = createWorktree(p, "feat-c", newOptions{noTmux: true, stackOn: "feat-a"})
+	if err != nil {
+		t.Fatalf("createWorktree() from a detached worktree failed: %v", err)
+	}
+	detached, _ := git.ResolveCommitWithContext(ctx, featPath, "HEAD")
+	if head, _ := git.ResolveCommitWithContext(ctx, result.Path, "HEAD"); head != detached {
+		t.Errorf("Expected feat-c to start at feat-a's detached %s, got %s", detached, head)
+	}
+	if result.StackedOn != "" {
+		t.Errorf("Expected feat-c not to be stacked, got %+v", result)
+	}
+
+	if _, err := createWorktree(p, "feat-d", newOptions{noTmux: true, stackOn: "missing"}); err == nil {
+		t.Error("Expected an error for a worktree that doesn't exist")
+	}
+}
+
+func TestCreateWorktreeInTmux(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	repo := testutil.NewRepo(t)
+	testutil.WriteFile(t, repo, ".kohconfig", `{"pane_commands": ["echo koh-pane-$((40+2))"]}`)
+	p := output.New(io.Discard, output.Human)
+
+	result, err := createWorktree(p, "feat", newOptions{})
+	if err != nil {
+		t.Fatalf("createWorktree() failed: %v", err)
+	}
+	window := tmux.WindowName(filepath.Base(repo), "feat")
+	if result.Window != window || result.WindowID != tm.Run(t, "display-message", "-p", "-t", window, "#{window_id}") {
+		t.Errorf("Expected window %s to be reported with its ID, got %s (%s)", window, result.Window, result.WindowID)
+	}
+	if current := tm.CurrentWindow(t); current != window {
+		t.Errorf("Expected window %s to be selected, got %s", window, current)
+	}
+	if panes := tm.PaneCount(t, window); panes != 2 {
+		t.Errorf("Expected 2 panes, got %d", panes)
+	}
+	ids := recordedPaneIDs("feat")
+	if len(ids) != 2 {
+		t.Fatalf("Expected the IDs of 2 panes to be recorded, got %v", ids)
+	}
+	if !reflect.DeepEqual(result.Panes, ids) {
+		t.Errorf("Expected panes %v in the result, got %v", ids, result.Panes)
+	}
+	tm.WaitForText(t, ids[1], "koh-pane-42")
+}
+
+func TestCreateWorktreeEnv(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	repo := testutil.NewRepo(t)
+	testutil.WriteFile(t, repo, ".kohconfig", `{
+		"pane_commands": ["echo db=$DATABASE_URL"],
+		"env": {"DATABASE_URL": "postgres:///app_${KOH_WORKTREE}"}
+	}`)
+	p := output.New(io.Discard, output.Human)
+
+	if _, err := createWorktree(p, "feat", newOptions{}); err != nil {
+		t.Fatalf("createWorktree() failed: %v", err)
+	}
+	ids := recordedPaneIDs("feat")
+	if len(ids) != 2 {
+		t.Fatalf("Expected the IDs of 2 panes to be recorded, got %v", ids)
+	}
+	tm.WaitForText(t, ids[1], "db=postgres:///app_feat")
+}
+
+func TestCreateWorktreeSparse(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	for _, name := range []string{"Makefile", "services/api/main.go", "services/web/index.js"} {
+		testutil.WriteFile(t, repo, name, name)
+	}
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "files")
+	testutil.WriteFile(t, repo, ".kohconfig", `{"sparse_paths": ["services/api"]}`)
+	p := output.New(io.Discard, output.Human)
+
+	result, err := createWorktree(p, "api", newOptions{noTmux: true})
+	if err != nil {
+		t.Fatalf("createWorktree() failed: %v", err)
+	}
+	for name, want := range map[string]bool{"Makefile": true, "services/api/main.go": true, "services/web/index.js": false} {
+		_, err := os.Stat(filepath.Join(result.Path, name))
+		if got := err == nil; got != want {
+			t.Errorf("Expected %s checked out: %v, got %v", name, want, got)
+		}
+	}
+
+	// A failed sparse checkout leaves nothing behind
+	testutil.WriteFile(t, repo, ".kohconfig", `{"sparse_paths": ["services/*"]}`)
+	if _, err := createWorktree(p, "broken", newOptions{noTmux: true}); err == nil {
+		t.Fatal("Expected an error for a pattern in cone mode")
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".koh", "broken")); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree to be rolled back, got %v", err)
+	}
+}
+
+func TestCreateWorktreeLinkDirs(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	testutil.WriteFile(t, repo, ".gitignore", "node_modules/\n")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "ignore")
+	testutil.WriteFile(t, repo, "node_modules/left-pad/index.js", "module.exports = 1\n")
+	testutil.WriteFile(t, repo, ".kohconfig", `{"link_dirs": ["node_modules", ".venv"]}`)
+	p := output.New(io.Discard, output.Human)
+
+	result, err := createWorktree(p, "linked", newOptions{noTmux: true})
+	if err != nil {
+		t.Fatalf("createWorktree() failed: %v", err)
+	}
+	if want := []string{"node_modules"}; !reflect.DeepEqual(result.LinkedDirs, want) {
+		t.Errorf("Expected %v to be linked, got %v", want, result.LinkedDirs)
+	}
+	link := filepath.Join(result.Path, "node_modules")
+	if target, err := os.Readlink(link); err != nil || target != filepath.Join(repo, "node_modules") {
+		t.Errorf("Expected a symlink to the main repository's node_modules, got %q (%v)", target, err)
+	}
+	// The symlink doesn't show up as an untracked file
+	if status := testutil.Git(t, result.Path, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected a clean worktree, got %q", status)
+	}
+
+	// Removing the worktree leaves the shared directory alone
+	if err := git.RemoveWorktreeWithContext(context.Background(), result.Path); err != nil {
+		t.Fatalf("RemoveWorktreeWithContext() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "node_modules", "left-pad", "index.js")); err != nil {
+		t.Errorf("Expected the main repository's node_modules to survive, got %v", err)
+	}
+}
+
+func TestCompleteBranchRefs(t *testing.T) {
+	newDashboardRepo(t)
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	runGit(t, "update-ref", "refs/remotes/origin/main", "HEAD")
+
+	base, _ := completeBranchRefs(true)(newCmd, nil, "")
+	if want := []string{"feat-a", "main", "origin/main"}; !reflect.DeepEqual(base, want) {
+		t.Errorf("Expected --base to complete %v, got %v", want, base)
+	}
+	branch, _ := completeBranchRefs(false)(newCmd, nil, "")
+	if want := []string{"feat-a", "main"}; !reflect.DeepEqual(branch, want) {
+		t.Errorf("Expected --branch to complete local branches %v, got %v", want, branch)
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+// singleWorktreeFlags are the 'koh new' flags that only make sense for one worktree
+var singleWorktreeFlags = []string{"branch", "remote", "pr", "from-stash", "apply-patch"}
+
+// newFailure is a worktree 'koh new' failed to create
+type newFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// newBulkResult is the machine-readable result of 'koh new' with several names
+type newBulkResult struct {
+	Created []*newResult `json:"created"`
+	Failed  []newFailure `json:"failed"`
+}
+
+// checkBulkNew checks that several worktree names can be created together:
+// each name is given once and no single-worktree flag is set
+func checkBulkNew(cmd *cobra.Command, names []string) error {
+	for _, flag := range singleWorktreeFlags {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--%s creates a single worktree, but %d names were given", flag, len(names))
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			return fmt.Errorf("worktree %q is given more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// createWorktrees creates each named worktree in turn. A failure is reported
+// and recorded, and the remaining worktrees are still created.
+func createWorktrees(p *output.Printer, names []string, opts newOptions, create func(*output.Printer, string, newOptions) (*newResult, error)) newBulkResult {
+	results := newBulkResult{Created: []*newResult{}, Failed: []newFailure{}}
+	for i, name := range names {
+		p.ForWorktree(name)
+		p.Info("Creating %s (%d/%d)", name, i+1, len(names))
+
+		result, err := create(p, name, opts)
+		if err != nil {
+			p.Fail(err)
+			p.Warn("Failed to create %s: %v", name, err)
+			results.Failed = append(results.Failed, newFailure{Name: name, Error: err.Error()})
+			continue
+		}
+		results.Created = append(results.Created, result)
+	}
+	p.ForWorktree("")
+	return results
+}
+
+// runNewBulk runs 'koh new' with several worktree names
+func runNewBulk(cmd *cobra.Command, p *output.Printer, names []string, opts newOptions) error {
+	results := createWorktrees(p, names, opts, createWorktree)
+
+	if err := p.Result(results, func(w io.Writer) {
+		fprintln(w)
+		for _, r := range results.Created {
+			fprintln(w, styles.SuccessMessage.Render(styles.IconCheck+" "+r.Name)+" "+styles.Muted.Render(r.Branch))
+		}
+		for _, f := range results.Failed {
+			fprintln(w, styles.ErrorMessage.Render(styles.IconCross+" "+f.Name)+" "+styles.Muted.Render(f.Error))
+		}
+		fprintln(w)
+		if len(results.Failed) == 0 {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Created all %d worktrees", len(names))))
+		} else {
+			fprintln(w, styles.RenderError(fmt.Sprintf("Failed to create %d of %d worktrees", len(results.Failed), len(names))))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if len(results.Failed) > 0 {
+		// The summary already reports the failures
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return silentFailure(errors.New(results.Failed[0].Error))
+	}
+	return nil
+}
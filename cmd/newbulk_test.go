@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func TestCheckBulkNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		names   []string
+		wantErr bool
+	}{
+		{name: "distinct names", names: []string{"feat-a", "feat-b"}},
+		{name: "shared base", flags: []string{"--base", "main"}, names: []string{"feat-a", "feat-b"}},
+		{name: "duplicate name", names: []string{"feat-a", "feat-b", "feat-a"}, wantErr: true},
+		{name: "branch", flags: []string{"--branch", "main"}, names: []string{"feat-a", "feat-b"}, wantErr: true},
+		{name: "from stash", flags: []string{"--from-stash=stash@{0}"}, names: []string{"feat-a", "feat-b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			for _, flag := range append(singleWorktreeFlags, "base") {
+				cmd.Flags().String(flag, "", "")
+			}
+			if err := cmd.Flags().Parse(tt.flags); err != nil {
+				t.Fatalf("Failed to parse flags: %v", err)
+			}
+
+			err := checkBulkNew(cmd, tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCreateWorktrees(t *testing.T) {
+	p := output.New(io.Discard, output.Human)
+	var attempted []string
+	create := func(_ *output.Printer, name string, opts newOptions) (*newResult, error) {
+		attempted = append(attempted, name)
+		if name == "feat-b" {
+			return nil, errors.New("branch feat-b already exists")
+		}
+		return &newResult{Name: name, Branch: name + "@" + opts.base}, nil
+	}
+
+	results := createWorktrees(p, []string{"feat-a", "feat-b", "feat-c"}, newOptions{base: "main"}, create)
+
+	if len(attempted) != 3 {
+		t.Errorf("Expected every worktree to be attempted, got %v", attempted)
+	}
+	if len(results.Created) != 2 || results.Created[0].Name != "feat-a" || results.Created[1].Name != "feat-c" {
+		t.Errorf("Expected feat-a and feat-c to be created, got %+v", results.Created)
+	}
+	if results.Created[0].Branch != "feat-a@main" {
+		t.Errorf("Expected the options to be passed on, got %q", results.Created[0].Branch)
+	}
+	if len(results.Failed) != 1 || results.Failed[0].Name != "feat-b" || results.Failed[0].Error != "branch feat-b already exists" {
+		t.Errorf("Expected feat-b to fail, got %+v", results.Failed)
+	}
+}
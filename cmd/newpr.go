@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// prWorktreeName returns the default worktree name for a pull request
+func prWorktreeName(number int) string {
+	return fmt.Sprintf("pr-%d", number)
+}
+
+// prCheckout is how a pull request's branch gets into a new worktree
+type prCheckout struct {
+	// branch is a local branch to check out
+	branch string
+	// remote is a remote branch to fetch and track, e.g. "origin/feature"
+	remote string
+	// fetchHead is set when branch has to be fetched from the pull
+	// request's head first
+	fetchHead bool
+	// update is set when branch exists locally and has to be brought up to
+	// date with the pull request's head first
+	update bool
+}
+
+// pullRequestRemote returns the remote pull requests are fetched from:
+// origin when there is one, otherwise the only remote
+func pullRequestRemote(remotes []string) (string, error) {
+	switch {
+	case slices.Contains(remotes, "origin"):
+		return "origin", nil
+	case len(remotes) == 1:
+		return remotes[0], nil
+	case len(remotes) == 0:
+		return "", fmt.Errorf("the repository has no remote to fetch pull requests from")
+	default:
+		return "", fmt.Errorf("can't tell which remote has the pull request: there is no origin among %v", remotes)
+	}
+}
+
+// planPullRequestCheckout decides how to check out a pull request. A branch
+// of this repository is updated to the pull request's head when it exists
+// locally and tracked from the remote otherwise. A branch from a fork isn't
+// on any remote, so its head is fetched into a pr-<number> branch, or
+// updated there when it was fetched before.
+func planPullRequestCheckout(pr forge.PullRequest, remote string, refs []git.Ref) prCheckout {
+	hasLocal := func(name string) bool {
+		return slices.ContainsFunc(refs, func(ref git.Ref) bool { return !ref.Remote && ref.Name == name })
+	}
+
+	if !pr.CrossRepository {
+		if hasLocal(pr.Branch) {
+			return prCheckout{branch: pr.Branch, update: true}
+		}
+		return prCheckout{remote: remote + "/" + pr.Branch}
+	}
+
+	branch := prWorktreeName(pr.Number)
+	if hasLocal(branch) {
+		return prCheckout{branch: branch, update: true}
+	}
+	return prCheckout{branch: branch, fetchHead: true}
+}
+
+// updatePullRequestBranch brings the local branch of a pull request up to
+// date with the pull request's head, fetched from remote. A branch behind the
+// head is fast-forwarded and one ahead of it, with commits not pushed yet, is
+// left alone. A branch that has diverged is refused rather than checked out
+// stale.
+func updatePullRequestBranch(ctx context.Context, p *output.Printer, number int, remote, branch string) error {
+	// Checked out elsewhere, the branch can't be moved or used
+	if err := checkExistingBranch(ctx, branch); err != nil {
+		return err
+	}
+	head, err := git.FetchPullRequestHeadWithContext(ctx, remote, number)
+	if err != nil {
+		return err
+	}
+	local, err := git.ResolveCommitWithContext(ctx, ".", branch)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case local == head:
+		return nil
+	case git.IsAncestorWithContext(ctx, head, local):
+		p.Info("%s has commits pull request #%d doesn't; checking it out as is", branch, number)
+		return nil
+	case !git.IsAncestorWithContext(ctx, local, head):
+		return fmt.Errorf("%s has diverged from pull request #%d\nBring it up to date, or delete it with 'git branch -D %s' to check out the pull request as it is", branch, number, branch)
+	}
+	p.Info("Fast-forwarding %s to pull request #%d", branch, number)
+	return git.FastForwardBranchWithContext(ctx, branch, local, head)
+}
+
+// preparePullRequest looks up a pull request and points opts at its branch,
+// so the rest of 'koh new' treats it like --branch or --remote. It returns
+// the branch it fetched the pull request into, "" when it didn't create one,
+// for the caller to delete if 'koh new' fails.
+func preparePullRequest(ctx context.Context, p *output.Printer, opts *newOptions) (string, error) {
+	pr, err := forge.GetPullRequest(ctx, opts.pr)
+	if errors.Is(err, forge.ErrUnavailable) {
+		return "", fmt.Errorf("--pr needs the GitHub CLI (gh)\nInstall it from https://cli.github.com and run 'gh auth login'")
+	}
+	if err != nil {
+		return "", err
+	}
+	p.Info("Pull request #%d: %s (%s)", pr.Number, pr.Title, pr.Branch)
+	if pr.State == forge.StateMerged || pr.State == forge.StateClosed {
+		p.Warn("Pull request #%d is %s", pr.Number, pr.State)
+	}
+
+	remotes, err := git.ListRemotesWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	remote, err := pullRequestRemote(remotes)
+	if err != nil {
+		return "", err
+	}
+	refs, err := git.ListBranchRefsWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	checkout := planPullRequestCheckout(*pr, remote, refs)
+	var fetched string
+	switch {
+	case checkout.fetchHead:
+		p.Info("Fetching pull request #%d from %s into %s", pr.Number, remote, checkout.branch)
+		if err := git.FetchPullRequestWithContext(ctx, remote, pr.Number, checkout.branch); err != nil {
+			return "", err
+		}
+		fetched = checkout.branch
+	case checkout.update:
+		if err := updatePullRequestBranch(ctx, p, pr.Number, remote, checkout.branch); err != nil {
+			return "", err
+		}
+	}
+	opts.branch, opts.remote = checkout.branch, checkout.remote
+	return fetched, nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestPullRequestRemote(t *testing.T) {
+	tests := []struct {
+		remotes []string
+		want    string
+		wantErr bool
+	}{
+		{remotes: []string{"upstream", "origin"}, want: "origin"},
+		{remotes: []string{"upstream"}, want: "upstream"},
+		{remotes: nil, wantErr: true},
+		{remotes: []string{"upstream", "fork"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := pullRequestRemote(tt.remotes)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("pullRequestRemote(%v): Expected %q (error %v), got %q (%v)", tt.remotes, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestPlanPullRequestCheckout(t *testing.T) {
+	refs := []git.Ref{
+		{Name: "main"},
+		{Name: "mine"},
+		{Name: "pr-9"},
+		{Name: "origin/theirs", Remote: true},
+	}
+
+	tests := []struct {
+		name string
+		pr   forge.PullRequest
+		want prCheckout
+	}{
+		{
+			name: "local branch",
+			pr:   forge.PullRequest{Number: 5, Branch: "mine"},
+			want: prCheckout{branch: "mine", update: true},
+		},
+		{
+			name: "branch on the remote",
+			pr:   forge.PullRequest{Number: 6, Branch: "theirs"},
+			want: prCheckout{remote: "origin/theirs"},
+		},
+		{
+			name: "fork",
+			pr:   forge.PullRequest{Number: 7, Branch: "main", CrossRepository: true},
+			want: prCheckout{branch: "pr-7", fetchHead: true},
+		},
+		{
+			name: "fork fetched before",
+			pr:   forge.PullRequest{Number: 9, Branch: "fix", CrossRepository: true},
+			want: prCheckout{branch: "pr-9", update: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planPullRequestCheckout(tt.pr, "origin", refs); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// addPullRequestRemote gives the repository an origin whose pull request
+// number has its head at commit
+func addPullRequestRemote(t *testing.T, repo string, number int, commit string) {
+	t.Helper()
+	origin := t.TempDir()
+	testutil.Git(t, origin, "init", "-q", "--bare")
+	testutil.Git(t, repo, "remote", "add", "origin", origin)
+	testutil.Git(t, repo, "push", "-q", "origin", commit+":refs/pull/"+strconv.Itoa(number)+"/head")
+}
+
+func TestUpdatePullRequestBranch(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	base := testutil.Git(t, repo, "rev-parse", "HEAD")
+	testutil.Git(t, repo, "commit", "-q", "--allow-empty", "-m", "pushed")
+	head := testutil.Git(t, repo, "rev-parse", "HEAD")
+	testutil.Git(t, repo, "commit", "-q", "--allow-empty", "-m", "not pushed")
+	ahead := testutil.Git(t, repo, "rev-parse", "HEAD")
+	testutil.Git(t, repo, "checkout", "-q", "-b", "other", base)
+	testutil.Git(t, repo, "commit", "-q", "--allow-empty", "-m", "elsewhere")
+	diverged := testutil.Git(t, repo, "rev-parse", "HEAD")
+	addPullRequestRemote(t, repo, 5, head)
+	ctx := context.Background()
+	p := output.New(io.Discard, output.Human)
+
+	tests := []struct {
+		name    string
+		start   string
+		want    string
+		wantErr string
+	}{
+		{name: "behind", start: base, want: head},
+		{name: "up to date", start: head, want: head},
+		{name: "unpushed commits", start: ahead, want: ahead},
+		{name: "diverged", start: diverged, want: diverged, wantErr: "has diverged from pull request #5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.Git(t, repo, "branch", "-f", "feat", tt.start)
+			err := updatePullRequestBranch(ctx, p, 5, "origin", "feat")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if got := testutil.Git(t, repo, "rev-parse", "feat"); got != tt.want {
+				t.Errorf("Expected feat at %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	// A branch checked out elsewhere isn't moved under its worktree
+	testutil.Git(t, repo, "branch", "-f", "feat", base)
+	testutil.Git(t, repo, "worktree", "add", "-q", filepath.Join(t.TempDir(), "feat"), "feat")
+	if err := updatePullRequestBranch(ctx, p, 5, "origin", "feat"); err == nil || !strings.Contains(err.Error(), "already checked out") {
+		t.Errorf("Expected an error for a checked out branch, got %v", err)
+	}
+	if got := testutil.Git(t, repo, "rev-parse", "feat"); got != base {
+		t.Errorf("Expected feat to stay at %s, got %s", base, got)
+	}
+}
+
+func TestCreateWorktreeDeletesFetchedPullRequestBranch(t *testing.T) {
+	repo := newDashboardRepo(t)
+	addPullRequestRemote(t, repo, 7, testutil.Git(t, repo, "rev-parse", "HEAD"))
+
+	// gh describes pull request #7 as coming from a fork
+	bin := t.TempDir()
+	gh := `#!/bin/sh
+echo '{"number": 7, "title": "Fix", "state": "OPEN", "headRefName": "fix", "isCrossRepository": true}'
+`
+	//nolint:gosec // G306: The fake gh needs to be executable
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// A worktree of that name is in the way once the branch is fetched
+	testutil.WriteFile(t, repo, filepath.Join(".koh", "pr-7", "file"), "")
+	p := output.New(io.Discard, output.Human)
+	if _, err := createWorktree(p, "pr-7", newOptions{pr: 7, noTmux: true}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Expected createWorktree() to fail, got %v", err)
+	}
+	if git.BranchExistsWithContext(context.Background(), "pr-7") {
+		t.Error("Expected the fetched pr-7 branch to be deleted")
+	}
+}
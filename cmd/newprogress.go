@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newProgressLines is how many lines of output the running step shows
+const newProgressLines = 6
+
+// newStepLabels describe the steps of 'koh new' in the progress view
+var newStepLabels = map[string]string{
+	"prepare":           "Checking the configuration",
+	"fetch_remotes":     "Fetching from all remotes",
+	"pull_request":      "Looking up the pull request",
+	"fetch":             "Fetching the remote branch",
+	"create_worktree":   "Creating the git worktree",
+	"sparse_checkout":   "Checking out sparse paths",
+	"git_config":        "Applying git settings",
+	"copy_files":        "Copying local files",
+	"link_dirs":         "Linking dependency directories",
+	"apply_parked_work": "Applying parked work",
+	"scratch":           "Creating the scratch directory",
+	"ports":             "Reserving ports",
+	"setup":             "Running the setup script",
+	"create_window":     "Opening the tmux window and starting the setup script",
+	"open_editor":       "Opening the editor",
+	"post_create":       "Running post-create hooks",
+}
+
+// newStepLabel returns how the progress view names a step
+func newStepLabel(step string) string {
+	if label, ok := newStepLabels[step]; ok {
+		return label
+	}
+	return strings.ReplaceAll(step, "_", " ")
+}
+
+// newRetryHint says how to carry on after 'koh new name' failed in step.
+// Before the worktree exists nothing needs undoing. A worktree kept with
+// --keep-on-failure can have its window opened on its own, or be removed to
+// start over. A rolled back worktree gets no hint, since the error says how
+// the rollback went.
+func newRetryHint(step, name string, kept bool) string {
+	switch step {
+	case "", "prepare", "fetch_remotes", "pull_request", "fetch", "create_worktree":
+		return "Nothing was created; fix the problem and run 'koh new " + name + "' again"
+	}
+	if !kept {
+		return ""
+	}
+	switch step {
+	case "create_window", "post_create":
+		return "The worktree was created; run 'koh switch " + name + "' to open its window"
+	}
+	return "Run 'koh cleanup " + name + "' and then 'koh new " + name + "' to start over"
+}
+
+// useNewProgress reports whether 'koh new' shows its steps in the progress
+// view: for a single worktree created in tmux, with people watching. With
+// --no-tmux the setup script runs in the current shell and owns it instead.
+func useNewProgress(p *output.Printer, names []string, opts newOptions) bool {
+	return len(names) == 1 && !opts.noTmux && !p.IsJSON() && newEventsJSON == "" &&
+		stdinIsTerminal() && stdoutIsTerminal()
+}
+
+// progressState is how far a step in the progress view got
+type progressState int
+
+const (
+	progressRunning progressState = iota
+	progressDone
+	progressFailed
+)
+
+// progressStep is a step of 'koh new' in the progress view
+type progressStep struct {
+	name  string
+	state progressState
+	// lines is the output of the step: messages and the output of the
+	// commands it ran
+	lines []string
+	// warnings stay visible once the step is done
+	warnings []string
+}
+
+// progressEventMsg carries a progress event of the printer
+type progressEventMsg output.Event
+
+// progressLineMsg carries a line of output of a command a step ran
+type progressLineMsg string
+
+// progressPromptMsg asks the user a question; the answer is sent on reply
+type progressPromptMsg struct {
+	question string
+	reply    chan string
+}
+
+// progressDoneMsg ends the progress view with the outcome of 'koh new'
+type progressDoneMsg struct {
+	result *newResult
+	err    error
+}
+
+// newProgressModel shows the steps of 'koh new' as they run, with a spinner
+// on the running step, a check on the finished ones and a cross on the one
+// that failed
+type newProgressModel struct {
+	name string
+	// kept is set when a failure leaves the worktree in place
+	kept    bool
+	steps   []*progressStep
+	spinner spinner.Model
+	// question is the prompt being asked, if any
+	question string
+	answer   textinput.Model
+	reply    chan string
+
+	result *newResult
+	err    error
+	done   bool
+	// interrupted is set when Ctrl+C ended the view before 'koh new' did
+	interrupted bool
+}
+
+// newNewProgressModel returns the progress view for creating the worktree
+// name, which is kept on failure when kept is set
+func newNewProgressModel(name string, kept bool) newProgressModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = styles.Active
+
+	answer := textinput.New()
+	answer.Prompt = "❯ "
+
+	return newProgressModel{name: name, kept: kept, spinner: s, answer: answer}
+}
+
+// current returns the running step, or nil between steps
+func (m *newProgressModel) current() *progressStep {
+	if len(m.steps) == 0 || m.steps[len(m.steps)-1].state != progressRunning {
+		return nil
+	}
+	return m.steps[len(m.steps)-1]
+}
+
+// Init initializes the bubbletea model
+func (m newProgressModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+// Update handles progress messages and keyboard input
+func (m newProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case progressEventMsg:
+		m.handleEvent(output.Event(msg))
+		return m, nil
+
+	case progressLineMsg:
+		if step := m.current(); step != nil {
+			step.lines = append(step.lines, string(msg))
+		}
+		return m, nil
+
+	case progressPromptMsg:
+		m.question, m.reply = msg.question, msg.reply
+		m.answer.Reset()
+		return m, m.answer.Focus()
+
+	case progressDoneMsg:
+		m.result, m.err, m.done = msg.result, msg.err, true
+		if step := m.current(); step != nil {
+			step.state = progressDone
+			if m.err != nil {
+				step.state = progressFailed
+			}
+		}
+		return m, tea.Quit
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			// Quit first so the terminal leaves raw mode; the interrupt is
+			// passed on to createWorktree once it has
+			m.interrupted = true
+			return m, tea.Quit
+		}
+		if m.reply != nil {
+			if msg.String() == "enter" {
+				m.reply <- m.answer.Value()
+				m.question, m.reply = "", nil
+				m.answer.Blur()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.answer, cmd = m.answer.Update(msg)
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+// handleEvent updates the steps for a progress event of the printer
+func (m *newProgressModel) handleEvent(e output.Event) {
+	switch e.Type {
+	case output.EventStepStarted:
+		m.steps = append(m.steps, &progressStep{name: e.Step})
+	case output.EventStepFinished:
+		if step := m.current(); step != nil {
+			step.state = progressDone
+		}
+	case output.EventInfo:
+		if step := m.current(); step != nil {
+			step.lines = append(step.lines, strings.Split(e.Message, "\n")...)
+		}
+	case output.EventWarning:
+		if step := m.current(); step != nil {
+			step.warnings = append(step.warnings, e.Message)
+		}
+	}
+}
+
+// failedStep returns the name of the step that failed, "" when none did
+func (m newProgressModel) failedStep() string {
+	for _, step := range m.steps {
+		if step.state == progressFailed {
+			return step.name
+		}
+	}
+	return ""
+}
+
+// View renders the steps
+func (m newProgressModel) View() string {
+	var s strings.Builder
+	s.WriteString("\n" + styles.RenderTitle(styles.IconTree+" Creating "+m.name) + "\n")
+
+	for _, step := range m.steps {
+		label := newStepLabel(step.name)
+		switch step.state {
+		case progressRunning:
+			s.WriteString(m.spinner.View() + " " + label + "\n")
+			lines := step.lines
+			if m.question == "" && len(lines) > newProgressLines {
+				lines = lines[len(lines)-newProgressLines:]
+			}
+			for _, line := range lines {
+				s.WriteString("    " + styles.Muted.Render(line) + "\n")
+			}
+		case progressDone:
+			s.WriteString(styles.SuccessMessage.Render(styles.IconCheck) + " " + label + "\n")
+		case progressFailed:
+			s.WriteString(styles.ErrorMessage.Render(styles.IconCross) + " " + label + "\n")
+		}
+		for _, warning := range step.warnings {
+			s.WriteString("    " + styles.WarningMessage.Render("⚠ "+warning) + "\n")
+		}
+	}
+
+	if m.question != "" {
+		s.WriteString("\n" + m.question + "\n" + m.answer.View() + "\n")
+	}
+
+	if m.err != nil {
+		step := m.failedStep()
+		where := "koh new failed"
+		if step != "" {
+			where = "Failed while " + strings.ToLower(newStepLabel(step)[:1]) + newStepLabel(step)[1:]
+		}
+		s.WriteString("\n" + styles.ErrorMessage.Render(where+": ") + m.err.Error() + "\n")
+		if hint := newRetryHint(step, m.name, m.kept); hint != "" {
+			s.WriteString(styles.Muted.Render(hint) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// progressWriter sends what commands write to the progress view line by
+// line. Carriage returns, which git uses to redraw its progress, end lines
+// too.
+type progressWriter struct {
+	mu      sync.Mutex
+	program *tea.Program
+	partial []byte
+}
+
+// Write sends each complete line in data to the progress view
+func (w *progressWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, data...)
+	for {
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			return len(data), nil
+		}
+		if line := strings.TrimSpace(string(w.partial[:i])); line != "" {
+			w.program.Send(progressLineMsg(line))
+		}
+		w.partial = w.partial[i+1:]
+	}
+}
+
+// createWorktreeWithProgress runs createWorktree while the progress view
+// shows its steps. When it fails, the view already showed the error, so a
+// silent failure carrying it is returned.
+func createWorktreeWithProgress(name string, opts newOptions) (*newResult, error) {
+	program := tea.NewProgram(newNewProgressModel(name, opts.keepOnFailure))
+
+	p := output.New(io.Discard, output.Human)
+	p.Observe(func(e output.Event) { program.Send(progressEventMsg(e)) })
+	p.SetCommandOutput(&progressWriter{program: program})
+	stopped := make(chan struct{})
+	p.SetPrompter(func(question string) (string, error) {
+		reply := make(chan string)
+		program.Send(progressPromptMsg{question: question, reply: reply})
+		select {
+		case answer := <-reply:
+			return answer, nil
+		case <-stopped:
+			return "", errAborted
+		}
+	})
+
+	// Keep an interrupt that arrives after createWorktree stopped listening
+	// for it from killing koh
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	done := make(chan progressDoneMsg, 1)
+	go func() {
+		result, err := createWorktree(p, name, opts)
+		done <- progressDoneMsg{result: result, err: err}
+		program.Send(progressDoneMsg{result: result, err: err})
+	}()
+
+	final, err := program.Run()
+	close(stopped)
+	if err != nil {
+		return nil, fmt.Errorf("error running the progress view: %w", err)
+	}
+	m := final.(newProgressModel)
+	if m.interrupted {
+		// The terminal is restored, so interrupt createWorktree like Ctrl+C
+		// outside the view would and wait for it to roll back
+		if proc, err := os.FindProcess(os.Getpid()); err == nil {
+			_ = proc.Signal(os.Interrupt)
+		}
+		if r := <-done; r.err == nil {
+			// It finished before the interrupt got to it
+			return r.result, nil
+		}
+		return nil, errAborted
+	}
+	if m.err != nil {
+		return nil, silentFailure(m.err)
+	}
+	return m.result, nil
+}
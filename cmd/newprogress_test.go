@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNewRetryHint(t *testing.T) {
+	tests := []struct {
+		name string
+		step string
+		kept bool
+		want string
+	}{
+		{name: "before fetching", step: "fetch", want: "run 'koh new feat' again"},
+		{name: "creating the worktree", step: "create_worktree", kept: true, want: "run 'koh new feat' again"},
+		{name: "kept after copying", step: "copy_files", kept: true, want: "Run 'koh cleanup feat'"},
+		{name: "kept without a window", step: "create_window", kept: true, want: "run 'koh switch feat'"},
+		{name: "rolled back", step: "create_window", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newRetryHint(tt.step, "feat", tt.kept)
+			if (tt.want == "" && got != "") || !strings.Contains(got, tt.want) {
+				t.Errorf("Expected the hint to contain %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewProgressModel(t *testing.T) {
+	var m tea.Model = newNewProgressModel("feat", true)
+	send := func(msg tea.Msg) {
+		m, _ = m.Update(msg)
+	}
+
+	send(progressEventMsg{Type: output.EventStepStarted, Step: "create_worktree"})
+	send(progressLineMsg("Preparing worktree (new branch 'feat')"))
+	send(progressEventMsg{Type: output.EventWarning, Step: "create_worktree", Message: "branch exists"})
+	send(progressEventMsg{Type: output.EventStepFinished, Step: "create_worktree"})
+	send(progressEventMsg{Type: output.EventStepStarted, Step: "copy_files"})
+
+	view := m.View()
+	for _, want := range []string{"Creating the git worktree", "branch exists", "Copying local files"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the view to contain %q, got:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "Preparing worktree") {
+		t.Errorf("Expected the output of finished steps to be hidden, got:\n%s", view)
+	}
+
+	send(progressDoneMsg{err: errors.New("permission denied")})
+	progress := m.(newProgressModel)
+	if got := progress.failedStep(); got != "copy_files" {
+		t.Errorf("Expected copy_files to have failed, got %q", got)
+	}
+	view = m.View()
+	for _, want := range []string{"Failed while copying local files", "permission denied", "koh cleanup feat"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the view to contain %q, got:\n%s", want, view)
+		}
+	}
+}
+
+func TestNewProgressModelPrompt(t *testing.T) {
+	var m tea.Model = newNewProgressModel("feat", false)
+	reply := make(chan string, 1)
+	m, _ = m.Update(progressPromptMsg{question: "Overwrite .env? [y/N]", reply: reply})
+	if !strings.Contains(m.View(), "Overwrite .env?") {
+		t.Errorf("Expected the question to be shown, got:\n%s", m.View())
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := <-reply; got != "y" {
+		t.Errorf("Expected the answer %q, got %q", "y", got)
+	}
+	if strings.Contains(m.View(), "Overwrite .env?") {
+		t.Errorf("Expected the question to be gone once answered, got:\n%s", m.View())
+	}
+}
+
+func TestNewProgressModelInterrupt(t *testing.T) {
+	var m tea.Model = newNewProgressModel("feat", false)
+	m, _ = m.Update(progressPromptMsg{question: "Overwrite .env? [y/N]", reply: make(chan string)})
+
+	// Ctrl+C quits the view without answering, so the terminal is restored
+	// before createWorktree is interrupted
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if !m.(newProgressModel).interrupted {
+		t.Error("Expected Ctrl+C to mark the view interrupted")
+	}
+	if cmd == nil {
+		t.Fatal("Expected Ctrl+C to quit the view")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Expected Ctrl+C to quit the view, got %T", cmd())
+	}
+}
+
+func TestUseNewProgress(t *testing.T) {
+	oldIn, oldOut := stdinIsTerminal, stdoutIsTerminal
+	t.Cleanup(func() { stdinIsTerminal, stdoutIsTerminal = oldIn, oldOut })
+	stdinIsTerminal = func() bool { return true }
+	stdoutIsTerminal = func() bool { return true }
+	human := output.New(nil, output.Human)
+
+	if !useNewProgress(human, []string{"feat"}, newOptions{}) {
+		t.Error("Expected the progress view for a single worktree on a terminal")
+	}
+	if useNewProgress(human, []string{"a", "b"}, newOptions{}) {
+		t.Error("Expected no progress view when creating several worktrees")
+	}
+	if useNewProgress(human, []string{"feat"}, newOptions{noTmux: true}) {
+		t.Error("Expected no progress view with --no-tmux")
+	}
+	if useNewProgress(output.New(nil, output.JSON), []string{"feat"}, newOptions{}) {
+		t.Error("Expected no progress view with --json")
+	}
+	stdoutIsTerminal = func() bool { return false }
+	if useNewProgress(human, []string{"feat"}, newOptions{}) {
+		t.Error("Expected no progress view when stdout is not a terminal")
+	}
+}
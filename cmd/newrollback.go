@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+// newRollback undoes a worktree 'koh new' created before failing, so a
+// failed run leaves nothing behind to clean up before retrying
+type newRollback struct {
+	mainRepoRoot string
+	name         string
+	path         string
+	// branch is deleted along with the worktree when koh created it, ""
+	// when the worktree checked out a branch that already existed
+	branch string
+	// start is the commit branch was created at. While it's still there,
+	// deleting the branch loses nothing and needs no confirmation.
+	start string
+}
+
+// run removes the worktree's window, the worktree, the branch koh created
+// for it and everything recorded about it. It runs on a fresh context, so a
+// cancelled 'koh new' is still rolled back. Steps that fail are reported and
+// the rest carry on.
+func (r *newRollback) run(p *output.Printer) error {
+	ctx := context.Background()
+	label := worktreeLabel(r.mainRepoRoot, r.name)
+
+	if tmux.IsInTmux() {
+		// The window may not exist yet; there's nothing to close then. Its
+		// full name spares a worktree of the same name in another repository.
+		if repoName, err := git.GetRepoName(); err == nil {
+			_ = tmux.CloseWindow(tmux.WindowName(repoName, r.name), r.name)
+		}
+	}
+
+	if err := git.RemoveWorktreeWithContext(ctx, r.path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", label, err)
+	}
+	invalidateWorktreeCache()
+	forgetWorktree(r.name)
+	if err := removeScratch(r.mainRepoRoot, r.name); err != nil {
+		p.Warn("%v", err)
+	}
+	if err := releasePorts(r.path); err != nil {
+		p.Warn("%v", err)
+	}
+
+	if r.branch != "" {
+		// Commits made on the branch before the failure, e.g. by the setup
+		// script, would go with it
+		head, _ := git.ResolveCommitWithContext(ctx, r.mainRepoRoot, r.branch)
+		op := destructiveOp{What: fmt.Sprintf("Delete branch %s and the commits made on it", r.branch)}
+		if err := confirmDestructive(p, op, head != "" && head == r.start); err != nil {
+			p.Warn("Kept branch %s: %v", r.branch, err)
+		} else if err := git.DeleteBranchWithContext(ctx, r.branch); err != nil {
+			p.Warn("Failed to delete branch %s: %v", r.branch, err)
+		}
+	}
+	return nil
+}
+
+// rollbackNew rolls back a worktree after 'koh new' failed with err and
+// returns the error to report, saying what happened to the worktree
+func rollbackNew(p *output.Printer, r *newRollback, err error) error {
+	if rollbackErr := r.run(p); rollbackErr != nil {
+		return fmt.Errorf("%w\nRolling back failed: %v\nRun 'koh cleanup %s' to remove what's left", err, rollbackErr, r.name)
+	}
+	return fmt.Errorf("%w\nRemoved the partially created worktree; pass --keep-on-failure to keep it next time", err)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestNewRollback(t *testing.T) {
+	repo := newDashboardRepo(t)
+	p := output.New(io.Discard, output.Human)
+	ctx := context.Background()
+	runGit(t, "branch", "existing")
+
+	tests := []struct {
+		name       string
+		branch     string
+		created    bool
+		wantBranch bool
+	}{
+		{name: "new branch", branch: "feat-b", created: true},
+		{name: "existing branch", branch: "existing", wantBranch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(repo, ".koh", tt.branch)
+			if tt.created {
+				runGit(t, "worktree", "add", "-q", "-b", tt.branch, path)
+			} else {
+				runGit(t, "worktree", "add", "-q", path, tt.branch)
+			}
+
+			r := &newRollback{mainRepoRoot: repo, name: tt.branch, path: path}
+			if tt.created {
+				r.branch = tt.branch
+				r.start = testutil.Git(t, path, "rev-parse", "HEAD")
+			}
+			err := rollbackNew(p, r, errors.New("window failed"))
+			if err == nil || !strings.Contains(err.Error(), "window failed") || !strings.Contains(err.Error(), "--keep-on-failure") {
+				t.Errorf("Expected the error to say the worktree was removed, got %v", err)
+			}
+
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("Expected %s to be removed, got %v", path, err)
+			}
+			if got := git.BranchExistsWithContext(ctx, tt.branch); got != tt.wantBranch {
+				t.Errorf("Expected branch %s to exist: %v, got %v", tt.branch, tt.wantBranch, got)
+			}
+		})
+	}
+}
+
+func TestNewRollbackKeepsBranchWithNewCommits(t *testing.T) {
+	repo := newDashboardRepo(t)
+	p := output.New(io.Discard, output.Human)
+	path := filepath.Join(repo, ".koh", "feat-b")
+	runGit(t, "worktree", "add", "-q", "-b", "feat-b", path)
+	r := &newRollback{mainRepoRoot: repo, name: "feat-b", path: path, branch: "feat-b", start: testutil.Git(t, path, "rev-parse", "HEAD")}
+	testutil.Git(t, path, "commit", "-q", "--allow-empty", "-m", "made by the setup script")
+
+	// Without a terminal to ask on, the branch and its commit are kept
+	oldTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	t.Cleanup(func() { stdinIsTerminal = oldTerminal })
+	_ = rollbackNew(p, r, errors.New("window failed"))
+	if !git.BranchExistsWithContext(context.Background(), "feat-b") {
+		t.Error("Expected a branch with new commits to be kept without confirmation")
+	}
+
+	runGit(t, "worktree", "add", "-q", path, "feat-b")
+	assumeYes = true
+	t.Cleanup(func() { assumeYes = false })
+	_ = rollbackNew(p, r, errors.New("window failed"))
+	if git.BranchExistsWithContext(context.Background(), "feat-b") {
+		t.Error("Expected --yes to delete the branch")
+	}
+}
+
+func TestNewRollbackFailure(t *testing.T) {
+	repo := newDashboardRepo(t)
+	p := output.New(io.Discard, output.Human)
+
+	r := &newRollback{mainRepoRoot: repo, name: "gone", path: filepath.Join(repo, ".koh", "gone")}
+	err := rollbackNew(p, r, errors.New("window failed"))
+	if err == nil || !strings.Contains(err.Error(), "koh cleanup gone") {
+		t.Errorf("Expected the error to say how to clean up, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+// addWindowPane reaches tmux; tests replace it
+var addWindowPane = tmux.AddPaneWithContext
+
+// editorArgs returns the editor worktrees are opened in: editor from the
+// global config, else $VISUAL or $EDITOR, else vi
+func editorArgs() []string {
+	if g, err := config.LoadGlobal(); err == nil {
+		if fields := strings.Fields(g.Editor); len(fields) > 0 {
+			return fields
+		}
+	}
+	return envCommand("vi", "VISUAL", "EDITOR")
+}
+
+// wantsOpenEditor reports whether to open the editor: --open when given,
+// otherwise open_editor from the global config
+func wantsOpenEditor(cmd *cobra.Command, open bool) bool {
+	if cmd.Flags().Changed("open") {
+		return open
+	}
+	g, err := config.LoadGlobal()
+	return err == nil && g.OpenEditor
+}
+
+// openEditorPane opens the editor in a new pane of a worktree's window,
+// unless a pane already runs it
+func openEditorPane(ctx context.Context, p *output.Printer, worktreeName, path string) error {
+	editor := editorArgs()
+	panes, err := listWindowPanes(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+	for _, pane := range panes {
+		if filepath.Base(pane.CurrentCommand) == filepath.Base(editor[0]) {
+			p.Info("%s is already open in pane %d", editor[0], pane.Pane)
+			return nil
+		}
+	}
+
+	pane, err := addWindowPane(ctx, worktreeName, path)
+	if err != nil {
+		return fmt.Errorf("failed to add a pane for the editor: %w", err)
+	}
+	p.Info("Opening %s in pane %d", editor[0], pane)
+	return sendToPane(ctx, worktreeName, pane, strings.Join(editor, " ")+" .")
+}
+
+// runEditor opens the editor on a worktree in the foreground, for worktrees
+// without a window, and returns once it exits
+func runEditor(path string) error {
+	cmd := editorCommand(path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", cmd.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+// writeGlobalConfig points the global config at a file with content
+func writeGlobalConfig(t *testing.T, content string) {
+	t.Helper()
+	configDir := t.TempDir()
+	t.Setenv("KOH_CONFIG_DIR", configDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+}
+
+func TestEditorArgs(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "nvim")
+	writeGlobalConfig(t, `{}`)
+	if got := strings.Join(editorArgs(), " "); got != "nvim" {
+		t.Errorf("Expected $EDITOR without an editor setting, got %q", got)
+	}
+
+	writeGlobalConfig(t, `{"editor": "code --wait"}`)
+	if got := strings.Join(editorArgs(), " "); got != "code --wait" {
+		t.Errorf("Expected the editor setting to win, got %q", got)
+	}
+}
+
+func TestWantsOpenEditor(t *testing.T) {
+	openCmd := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("open", false, "")
+		return cmd
+	}
+
+	writeGlobalConfig(t, `{}`)
+	if wantsOpenEditor(openCmd(), false) {
+		t.Error("Expected the editor to stay closed by default")
+	}
+
+	writeGlobalConfig(t, `{"open_editor": true}`)
+	if !wantsOpenEditor(openCmd(), false) {
+		t.Error("Expected open_editor to open the editor")
+	}
+	cmd := openCmd()
+	_ = cmd.Flags().Set("open", "false")
+	if wantsOpenEditor(cmd, false) {
+		t.Error("Expected --open=false to override open_editor")
+	}
+}
+
+func TestOpenEditorPane(t *testing.T) {
+	oldList, oldSend, oldAdd := listWindowPanes, sendToPane, addWindowPane
+	t.Cleanup(func() { listWindowPanes, sendToPane, addWindowPane = oldList, oldSend, oldAdd })
+	writeGlobalConfig(t, `{"editor": "nvim"}`)
+
+	panes := []tmux.PaneInfo{{Pane: 0, CurrentCommand: "zsh"}, {Pane: 1, CurrentCommand: "node"}}
+	listWindowPanes = func(context.Context, string) ([]tmux.PaneInfo, error) {
+		return panes, nil
+	}
+	var added []string
+	addWindowPane = func(_ context.Context, _, dir string, _ ...string) (int, error) {
+		added = append(added, dir)
+		return len(panes), nil
+	}
+	var sent []string
+	sendToPane = func(_ context.Context, _ string, pane int, keys string) error {
+		sent = append(sent, keys)
+		return nil
+	}
+
+	p := output.New(io.Discard, output.Human)
+	if err := openEditorPane(context.Background(), p, "feat", "/repo/.koh/feat"); err != nil {
+		t.Fatalf("openEditorPane() failed: %v", err)
+	}
+	if want := []string{"/repo/.koh/feat"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("Expected a pane in %v, got %v", want, added)
+	}
+	if want := []string{"nvim ."}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("Expected %v to be typed, got %v", want, sent)
+	}
+
+	// A window with the editor open already gets no second one
+	added, sent = nil, nil
+	panes = append(panes, tmux.PaneInfo{Pane: 2, CurrentCommand: "nvim"})
+	if err := openEditorPane(context.Background(), p, "feat", "/repo/.koh/feat"); err != nil {
+		t.Fatalf("openEditorPane() failed: %v", err)
+	}
+	if len(added) != 0 || len(sent) != 0 {
+		t.Errorf("Expected the open editor to be reused, got panes %v and keys %v", added, sent)
+	}
+}
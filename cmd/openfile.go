@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+)
+
+// parseFileArg parses a file given as "path" or "path:line". The path is made
+// relative to the repository root, so it names the same file in any worktree.
+func parseFileArg(arg, mainRepoRoot string) (config.PaneTemplateData, error) {
+	var data config.PaneTemplateData
+	file := arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		if n, err := strconv.Atoi(arg[i+1:]); err == nil {
+			if n < 1 {
+				return data, fmt.Errorf("invalid file %q: the line must be at least 1", arg)
+			}
+			file, data.Line = arg[:i], n
+		}
+	}
+	if file == "" {
+		return data, fmt.Errorf("invalid file %q: the path is empty", arg)
+	}
+
+	if filepath.IsAbs(file) {
+		rel, err := filepath.Rel(mainRepoRoot, file)
+		if err != nil {
+			return data, fmt.Errorf("invalid file %q: %w", arg, err)
+		}
+		file = rel
+	}
+	file = filepath.Clean(file)
+	if file == ".." || strings.HasPrefix(file, ".."+string(filepath.Separator)) {
+		return data, fmt.Errorf("invalid file %q: it must be inside the repository", arg)
+	}
+
+	data.File = filepath.ToSlash(file)
+	return data, nil
+}
+
+// fileArg formats template data back into "path:line" for recording
+func fileArg(data config.PaneTemplateData) string {
+	if data.Line == 0 {
+		return data.File
+	}
+	return fmt.Sprintf("%s:%d", data.File, data.Line)
+}
+
+// fileEnv returns the environment that tells panes which file to open
+func fileEnv(data config.PaneTemplateData) []string {
+	if data.File == "" {
+		return nil
+	}
+	env := []string{"KOH_FILE=" + data.File}
+	if data.Line > 0 {
+		env = append(env, fmt.Sprintf("KOH_LINE=%d", data.Line))
+	}
+	return env
+}
+
+// renderWindowConfig fills in the pane command placeholders of a worktree's
+// window with the file it was opened at, returning the rendered config and
+// the environment to set in its panes
+func renderWindowConfig(cfg *config.Config, worktreeName, mainRepoRoot string) (*config.Config, []string) {
+	var data config.PaneTemplateData
+	if file := recordedFile(worktreeName); file != "" {
+		// A recorded file was valid when it was recorded
+		data, _ = parseFileArg(file, mainRepoRoot)
+	}
+
+	return cfg.RenderPaneCommands(data), fileEnv(data)
+}
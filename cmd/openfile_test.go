@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+)
+
+func TestParseFileArg(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    config.PaneTemplateData
+		wantErr bool
+	}{
+		{arg: "app/models/user.rb", want: config.PaneTemplateData{File: "app/models/user.rb"}},
+		{arg: "app/models/user.rb:42", want: config.PaneTemplateData{File: "app/models/user.rb", Line: 42}},
+		{arg: "./app/../main.go", want: config.PaneTemplateData{File: "main.go"}},
+		{arg: "/repo/app/user.rb:7", want: config.PaneTemplateData{File: "app/user.rb", Line: 7}},
+		{arg: "notes:draft.md", want: config.PaneTemplateData{File: "notes:draft.md"}},
+		{arg: "main.go:0", wantErr: true},
+		{arg: ":12", wantErr: true},
+		{arg: "../other/main.go", wantErr: true},
+		{arg: "/elsewhere/main.go", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseFileArg(tt.arg, "/repo")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+			if err == nil && tt.want.Line > 0 {
+				if again, _ := parseFileArg(fileArg(got), "/repo"); again != got {
+					t.Errorf("Expected %q to round-trip, got %+v", fileArg(got), again)
+				}
+			}
+		})
+	}
+}
+
+func TestFileEnv(t *testing.T) {
+	if env := fileEnv(config.PaneTemplateData{}); env != nil {
+		t.Errorf("Expected no environment without a file, got %v", env)
+	}
+	env := fileEnv(config.PaneTemplateData{File: "main.go", Line: 3})
+	if len(env) != 2 || env[0] != "KOH_FILE=main.go" || env[1] != "KOH_LINE=3" {
+		t.Errorf("Expected KOH_FILE and KOH_LINE, got %v", env)
+	}
+}
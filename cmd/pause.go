@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <worktree-name>",
+	Short: "Stop a worktree's running processes without closing its window",
+	Long: `Free the CPU and memory used by an idle worktree's dev servers and
+watchers without destroying its window.
+
+By default every pane running something other than a shell gets Ctrl-C, and
+'koh resume' sends the command koh last sent to it again. The setup pane is
+left alone, since the setup script is never re-run.
+
+With --suspend, panes get Ctrl-Z instead, which stops the processes where
+they are (including the setup script) and keeps their state in memory;
+'koh resume' continues them with 'fg'.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runPause,
+}
+
+// pauseSuspend stops processes with Ctrl-Z instead of interrupting them
+var pauseSuspend bool
+
+func init() {
+	pauseCmd.Flags().BoolVar(&pauseSuspend, "suspend", false, "Suspend processes with Ctrl-Z instead of interrupting them")
+	rootCmd.AddCommand(pauseCmd)
+}
+
+// pausedPane is a pane paused by 'koh pause'
+type pausedPane struct {
+	Pane int `json:"pane"`
+	// Process is what was running in the pane, e.g. "node"
+	Process string `json:"process"`
+}
+
+// pauseResult is the machine-readable result of 'koh pause'
+type pauseResult struct {
+	Name    string       `json:"name"`
+	Suspend bool         `json:"suspend"`
+	Panes   []pausedPane `json:"panes"`
+}
+
+// pausablePanes returns the panes running something other than a shell.
+// The setup pane is only included when suspending, since interrupted panes
+// are resumed by re-sending their command and setup is never re-run.
+func pausablePanes(panes []tmux.PaneInfo, suspend bool) []pausedPane {
+	busy := []pausedPane{}
+	for _, pane := range panes {
+		if tmux.IsShell(pane.CurrentCommand) || (pane.Pane == 0 && !suspend) {
+			continue
+		}
+		busy = append(busy, pausedPane{Pane: pane.Pane, Process: pane.CurrentCommand})
+	}
+	return busy
+}
+
+// worktreeWindowCommonDir checks the preconditions shared by 'koh pause' and
+// 'koh resume' and returns the repository's common git directory
+func worktreeWindowCommonDir(worktreeName string) (string, error) {
+	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
+		return "", fmt.Errorf("invalid worktree name: %w", err)
+	}
+	if !tmux.IsInTmux() {
+		return "", fmt.Errorf("not in a tmux session\nPlease run this command from within a tmux session")
+	}
+	if !git.IsGitRepo() {
+		return "", fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	return git.GetCommonDir()
+}
+
+func runPause(cmd *cobra.Command, args []string) error {
+	worktreeName := args[0]
+	p := newPrinter(cmd)
+
+	commonDir, err := worktreeWindowCommonDir(worktreeName)
+	if err != nil {
+		return err
+	}
+
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return err
+	}
+	if s.Worktree(worktreeName).Paused != nil {
+		return fmt.Errorf("worktree %s is already paused\nUse 'koh resume %s' to resume it", worktreeName, worktreeName)
+	}
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	panes, err := tmux.ListWindowPanesWithContext(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+
+	key := "C-c"
+	if pauseSuspend {
+		key = "C-z"
+	}
+
+	result := pauseResult{Name: worktreeName, Suspend: pauseSuspend, Panes: pausablePanes(panes, pauseSuspend)}
+	for _, pane := range result.Panes {
+		if err := tmux.SendKeyToPaneWithContext(ctx, worktreeName, pane.Pane, key); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Panes) > 0 {
+		pause := &state.Pause{At: time.Now(), Suspend: pauseSuspend}
+		for _, pane := range result.Panes {
+			pause.Panes = append(pause.Panes, pane.Pane)
+		}
+		if err := state.Update(commonDir, func(s *state.State) error {
+			s.Worktree(worktreeName).Paused = pause
+			return nil
+		}); err != nil {
+			return fmt.Errorf("paused %s but failed to record it: %w", worktreeName, err)
+		}
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		if len(result.Panes) == 0 {
+			fprintln(w, styles.Muted.Render("Nothing is running in "+worktreeName))
+			return
+		}
+		for _, pane := range result.Panes {
+			fprintln(w, fmt.Sprintf("  %s pane %d %s", styles.IconBullet, pane.Pane, styles.Muted.Render("("+pane.Process+")")))
+		}
+		fprintln(w, styles.RenderSuccess(fmt.Sprintf("Paused %d pane(s) in %s", len(result.Panes), worktreeName)))
+		fprintln(w, styles.Muted.Render("Run 'koh resume "+worktreeName+"' to resume"))
+	})
+}
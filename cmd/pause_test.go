@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestPausablePanes(t *testing.T) {
+	panes := []tmux.PaneInfo{
+		{Pane: 0, CurrentCommand: "bash"},
+		{Pane: 1, CurrentCommand: "node"},
+		{Pane: 2, CurrentCommand: "zsh"},
+		{Pane: 3, CurrentCommand: "python3"},
+	}
+	setupRunning := []tmux.PaneInfo{
+		{Pane: 0, CurrentCommand: "npm"},
+		{Pane: 1, CurrentCommand: "node"},
+	}
+
+	tests := []struct {
+		name    string
+		panes   []tmux.PaneInfo
+		suspend bool
+		want    []int
+	}{
+		{name: "busy panes", panes: panes, want: []int{1, 3}},
+		{name: "setup pane is not interrupted", panes: setupRunning, want: []int{1}},
+		{name: "setup pane is suspended", panes: setupRunning, suspend: true, want: []int{0, 1}},
+		{name: "idle window", panes: []tmux.PaneInfo{{Pane: 0, CurrentCommand: "zsh"}}, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pausablePanes(tt.panes, tt.suspend)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected panes %v, got %+v", tt.want, got)
+			}
+			for i, pane := range tt.want {
+				if got[i].Pane != pane {
+					t.Errorf("Expected pane %d at %d, got %d", pane, i, got[i].Pane)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanResume(t *testing.T) {
+	last := map[int]string{0: "./setup.sh", 1: "npm run dev"}
+
+	interrupted := planResume(&state.Pause{Panes: []int{1, 2}}, last)
+	if len(interrupted) != 2 {
+		t.Fatalf("Expected 2 steps, got %+v", interrupted)
+	}
+	if interrupted[0].Action != paneSent || interrupted[0].Command != "npm run dev" {
+		t.Errorf("Expected 'npm run dev' to be sent to pane 1, got %+v", interrupted[0])
+	}
+	if interrupted[1].Action != paneSkipped {
+		t.Errorf("Expected pane 2 without a recorded command to be skipped, got %+v", interrupted[1])
+	}
+
+	suspended := planResume(&state.Pause{Suspend: true, Panes: []int{0, 2}}, last)
+	for _, step := range suspended {
+		if step.Action != paneSent || step.Command != "fg" {
+			t.Errorf("Expected fg to be sent to suspended pane %d, got %+v", step.Pane, step)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+// plumbingVersion is the version of the plumbing output formats. It only
+// changes when a change would break existing consumers: fields are added
+// without bumping it, but never renamed or removed.
+const plumbingVersion = 1
+
+var plumbingCmd = &cobra.Command{
+	Use:   "plumbing",
+	Short: "Stable machine-readable commands for editor plugins",
+	Long: `Commands for editor plugins and other tools that drive koh.
+
+Plumbing commands always print JSON, never prompt and never open a TUI.
+Their output carries a "version" field that is versioned separately from
+the rest of koh: within a version, fields are only ever added. Failures are
+printed as {"error": "..."} with a non-zero exit status.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		jsonOutput = true
+		rootCmd.PersistentPreRun(cmd, args)
+	},
+}
+
+var plumbingListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the repository's checkouts as JSON",
+	Long: `Print the main checkout and every koh worktree of the repository, with
+the ID of its tmux window when one is open.`,
+	Args: cobra.NoArgs,
+	RunE: runPlumbingList,
+}
+
+var plumbingSwitchCmd = &cobra.Command{
+	Use:   "switch [worktree-name]",
+	Short: "Switch to a worktree's window and print it as JSON",
+	Long: `Switch to the tmux window of a worktree, given by name or by the window ID
+'koh plumbing list' reported, creating the window when the worktree has
+none. Uncommitted changes in the current worktree are not checked.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if plumbingWindowID != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runPlumbingSwitch,
+}
+
+var plumbingVersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the plumbing format version",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return output.WriteJSON(cmd.OutOrStdout(), plumbingVersionResult{Version: plumbingVersion, Koh: Version})
+	},
+}
+
+var (
+	// plumbingWindowID selects the worktree to switch to by tmux window ID
+	plumbingWindowID string
+	// plumbingBackground creates a missing window without switching to it
+	plumbingBackground bool
+)
+
+func init() {
+	plumbingSwitchCmd.Flags().StringVar(&plumbingWindowID, "window-id", "", "Switch to the worktree whose window has this tmux ID (e.g. @3)")
+	plumbingSwitchCmd.Flags().BoolVar(&plumbingBackground, "background", false, "Create a missing window without switching to it")
+	plumbingCmd.AddCommand(plumbingListCmd, plumbingSwitchCmd, plumbingVersionCmd)
+	rootCmd.AddCommand(plumbingCmd)
+}
+
+// plumbingVersionResult is the output of 'koh plumbing version'
+type plumbingVersionResult struct {
+	Version int    `json:"version"`
+	Koh     string `json:"koh"`
+}
+
+// plumbingWorktree is a checkout in the output of 'koh plumbing list'
+type plumbingWorktree struct {
+	Name    string `json:"name"`
+	Branch  string `json:"branch"`
+	Path    string `json:"path"`
+	Current bool   `json:"current"`
+	Main    bool   `json:"main"`
+	// WindowID is the tmux ID of the worktree's window, "" when none is open
+	WindowID string `json:"window_id"`
+}
+
+// plumbingListResult is the output of 'koh plumbing list'
+type plumbingListResult struct {
+	Version int `json:"version"`
+	// Repository is the path of the repository's main working tree
+	Repository string             `json:"repository"`
+	Worktrees  []plumbingWorktree `json:"worktrees"`
+}
+
+// plumbingSwitchResult is the output of 'koh plumbing switch'
+type plumbingSwitchResult struct {
+	Version  int    `json:"version"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	WindowID string `json:"window_id"`
+	// Created is set when the window had to be created
+	Created bool `json:"created"`
+}
+
+// plumbingWindowIDs returns the window ID of each of the repository's
+// worktrees with an open window, or none outside tmux
+func plumbingWindowIDs(ctx context.Context) map[string]string {
+	if !tmux.IsInTmux() {
+		return nil
+	}
+	repoName, err := git.GetRepoName()
+	if err != nil {
+		return nil
+	}
+	ids, err := tmux.WorktreeWindowIDsWithContext(ctx, repoName)
+	if err != nil {
+		return nil
+	}
+	return ids
+}
+
+func runPlumbingList(cmd *cobra.Command, _ []string) error {
+	ctx := context.Background()
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository")
+	}
+	mainRepoRoot := filepath.Dir(info.CommonDir)
+
+	items, err := loadListItems(mainRepoRoot, info.TopLevel, true)
+	if err != nil {
+		return err
+	}
+
+	windowIDs := plumbingWindowIDs(ctx)
+	result := plumbingListResult{Version: plumbingVersion, Repository: mainRepoRoot, Worktrees: []plumbingWorktree{}}
+	for _, item := range items {
+		result.Worktrees = append(result.Worktrees, plumbingWorktree{
+			Name:     item.name,
+			Branch:   item.branch,
+			Path:     item.path,
+			Current:  item.isCurrent,
+			Main:     item.isMain,
+			WindowID: windowIDs[item.name],
+		})
+	}
+	return output.WriteJSON(cmd.OutOrStdout(), result)
+}
+
+// worktreeForWindow returns the worktree whose window has the given ID
+func worktreeForWindow(windowIDs map[string]string, windowID string) (string, error) {
+	for name, id := range windowIDs {
+		if id == windowID {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no koh window has ID %s", windowID)
+}
+
+func runPlumbingSwitch(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	if !tmux.IsInTmux() {
+		return fmt.Errorf("not in a tmux session")
+	}
+
+	var name string
+	if plumbingWindowID != "" {
+		var err error
+		if name, err = worktreeForWindow(plumbingWindowIDs(ctx), plumbingWindowID); err != nil {
+			return err
+		}
+	} else {
+		name = args[0]
+	}
+
+	p := output.New(cmd.ErrOrStderr(), output.JSON)
+	result, err := switchToWorktree(p, name, true, plumbingBackground)
+	if err != nil {
+		return err
+	}
+
+	return output.WriteJSON(cmd.OutOrStdout(), plumbingSwitchResult{
+		Version:  plumbingVersion,
+		Name:     result.Name,
+		Path:     result.Path,
+		WindowID: plumbingWindowIDs(ctx)[result.Name],
+		Created:  result.Created,
+	})
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorktreeForWindow(t *testing.T) {
+	windowIDs := map[string]string{"main": "@1", "feat-a": "@4"}
+
+	tests := []struct {
+		windowID string
+		want     string
+		wantErr  bool
+	}{
+		{windowID: "@4", want: "feat-a"},
+		{windowID: "@1", want: "main"},
+		{windowID: "@2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.windowID, func(t *testing.T) {
+			got, err := worktreeForWindow(windowIDs, tt.windowID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPlumbingList(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("TMUX", "")
+	t.Chdir(filepath.Join(repo, ".koh", "feat-a"))
+
+	var out bytes.Buffer
+	plumbingListCmd.SetOut(&out)
+	t.Cleanup(func() { plumbingListCmd.SetOut(nil) })
+	if err := runPlumbingList(plumbingListCmd, nil); err != nil {
+		t.Fatalf("runPlumbingList() failed: %v", err)
+	}
+
+	var result plumbingListResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out.String(), err)
+	}
+	if result.Version != plumbingVersion || result.Repository != repo {
+		t.Errorf("Expected version %d for %s, got %+v", plumbingVersion, repo, result)
+	}
+	if len(result.Worktrees) != 2 {
+		t.Fatalf("Expected the main checkout and feat-a, got %+v", result.Worktrees)
+	}
+	for _, w := range result.Worktrees {
+		if w.Main != (w.Name == mainCheckoutName) || w.Current != (w.Name == "feat-a") || w.WindowID != "" {
+			t.Errorf("Unexpected entry %+v", w)
+		}
+	}
+}
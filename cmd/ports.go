@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/ports"
+	"github.com/bshakr/koh/internal/state"
+)
+
+// portAvailable reports whether a port is free; tests replace it
+var portAvailable = ports.Available
+
+// reservePorts returns the ports of the worktree at worktreePath, reserving
+// them in the registry shared by all repositories when it has none yet. A
+// worktree gets no ports unless ports.count is set.
+func reservePorts(cfg *config.Config, worktreePath string) ([]int, error) {
+	start, count := cfg.Ports.Range()
+	if count == 0 {
+		return nil, nil
+	}
+
+	var reserved []int
+	err := state.UpdatePorts(func(r *state.Ports) error {
+		// Worktrees deleted without 'koh cleanup' give their ports back
+		for path := range r.Reserved {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				delete(r.Reserved, path)
+			}
+		}
+		var err error
+		reserved, err = ports.Reserve(r.Reserved, worktreePath, start, count, portAvailable)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve ports: %w", err)
+	}
+	return reserved, nil
+}
+
+// portsEnv reserves a worktree's ports like reservePorts and returns the
+// environment exposing them to its window's panes, warning when they can't
+// be reserved
+func portsEnv(p *output.Printer, cfg *config.Config, worktreePath string) []string {
+	reserved, err := reservePorts(cfg, worktreePath)
+	if err != nil {
+		p.Warn("%v", err)
+	}
+	return ports.Env(reserved)
+}
+
+// releasePorts gives a worktree's ports back
+func releasePorts(worktreePath string) error {
+	if r, err := state.LoadPorts(); err == nil && r.Reserved[worktreePath] == nil {
+		return nil
+	}
+	err := state.UpdatePorts(func(r *state.Ports) error {
+		delete(r.Reserved, worktreePath)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release ports: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/state"
+)
+
+func TestReservePorts(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	original := portAvailable
+	portAvailable = func(int) bool { return true }
+	t.Cleanup(func() { portAvailable = original })
+
+	cfg := &config.Config{Ports: &config.Ports{Count: 2}}
+	first, second, third := t.TempDir(), t.TempDir(), t.TempDir()
+
+	for _, tt := range []struct {
+		path string
+		want []int
+	}{
+		{first, []int{4000, 4001}},
+		{second, []int{4002, 4003}},
+		{first, []int{4000, 4001}},
+	} {
+		got, err := reservePorts(cfg, tt.path)
+		if err != nil {
+			t.Fatalf("reservePorts() failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Expected %v for %s, got %v", tt.want, tt.path, got)
+		}
+	}
+
+	// A worktree deleted by hand gives its ports back
+	if err := os.Remove(first); err != nil {
+		t.Fatalf("Failed to remove %s: %v", first, err)
+	}
+	if got, _ := reservePorts(cfg, third); !reflect.DeepEqual(got, []int{4000, 4001}) {
+		t.Errorf("Expected the ports of the deleted worktree, got %v", got)
+	}
+
+	if err := releasePorts(second); err != nil {
+		t.Fatalf("releasePorts() failed: %v", err)
+	}
+	r, err := state.LoadPorts()
+	if err != nil {
+		t.Fatalf("LoadPorts() failed: %v", err)
+	}
+	if want := map[string][]int{third: {4000, 4001}}; !reflect.DeepEqual(r.Reserved, want) {
+		t.Errorf("Expected %v to be left, got %v", want, r.Reserved)
+	}
+
+	if got, err := reservePorts(&config.Config{}, second); err != nil || got != nil {
+		t.Errorf("Expected no ports without ports.count, got %v (%v)", got, err)
+	}
+}
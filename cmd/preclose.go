@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+// preCloseTimeout is how long cleanup waits for programs to quit once
+// pre_close typed their keys
+const preCloseTimeout = 3 * time.Second
+
+// preClosePoll is how often cleanup checks whether they quit
+const preClosePoll = 100 * time.Millisecond
+
+// listWindowPanes and sendToPane reach tmux; tests replace them
+var (
+	listWindowPanes = tmux.ListWindowPanesWithContext
+	sendToPane      = tmux.SendToPaneWithContext
+)
+
+// preCloseTargets returns the keys to type into each pane, by pane number.
+// Entries naming a pane count its position from 0. The first matching entry
+// wins, and panes at a shell prompt are left alone so keys meant for an
+// editor never run as a command.
+func preCloseTargets(entries []config.PreClose, panes []tmux.PaneInfo) map[int]string {
+	targets := map[int]string{}
+	for i, pane := range panes {
+		if tmux.IsShell(pane.CurrentCommand) {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Keys != "" && entry.Matches(i, pane.CurrentCommand) {
+				targets[pane.Pane] = entry.Keys
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// runPreClose types the pre_close keys into a worktree window's panes and
+// waits up to preCloseTimeout for the programs to quit, so editors can save
+// their buffers and remove their swap files before the window is closed.
+// Programs that don't quit are reported and then closed like any other.
+func runPreClose(ctx context.Context, p *output.Printer, worktreeName string, entries []config.PreClose) {
+	if len(entries) == 0 {
+		return
+	}
+	panes, err := listWindowPanes(ctx, worktreeName)
+	if err != nil {
+		// No window to close
+		return
+	}
+	targets := preCloseTargets(entries, panes)
+	if len(targets) == 0 {
+		return
+	}
+
+	p.Step("pre_close")
+	// Pane numbers follow tmux's pane-base-index, so they aren't positions
+	// in panes
+	for _, pane := range panes {
+		keys, ok := targets[pane.Pane]
+		if !ok {
+			continue
+		}
+		p.Info("Typing %s into pane %d (%s)", keys, pane.Pane, pane.CurrentCommand)
+		if err := sendToPane(ctx, worktreeName, pane.Pane, keys); err != nil {
+			p.Warn("%v", err)
+		}
+	}
+
+	deadline := time.Now().Add(preCloseTimeout)
+	for {
+		panes, err = listWindowPanes(ctx, worktreeName)
+		if err != nil {
+			// The window closed along with the programs
+			return
+		}
+		remaining := preCloseTargets(entries, panes)
+		if len(remaining) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			for _, pane := range panes {
+				if _, ok := remaining[pane.Pane]; ok {
+					p.Warn("%s in pane %d didn't quit after pre_close; closing the window anyway", pane.CurrentCommand, pane.Pane)
+				}
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(preClosePoll):
+		}
+	}
+}
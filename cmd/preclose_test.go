@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestPreCloseTargets(t *testing.T) {
+	panes := []tmux.PaneInfo{
+		{Pane: 0, CurrentCommand: "zsh"},
+		{Pane: 1, CurrentCommand: "nvim"},
+		{Pane: 2, CurrentCommand: "node"},
+		{Pane: 3, CurrentCommand: "hx"},
+	}
+	entries := []config.PreClose{
+		{Pane: "0", Keys: "exit"},
+		{Pane: "hx", Keys: ":write-quit-all"},
+		{Pane: "editor", Keys: ":wqa"},
+		{Pane: "node"},
+	}
+
+	want := map[int]string{1: ":wqa", 3: ":write-quit-all"}
+	if got := preCloseTargets(entries, panes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// With pane-base-index 1, positions still count from 0
+	panes = []tmux.PaneInfo{{Pane: 1, CurrentCommand: "zsh"}, {Pane: 2, CurrentCommand: "node"}}
+	want = map[int]string{2: "q"}
+	if got := preCloseTargets([]config.PreClose{{Pane: "1", Keys: "q"}}, panes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRunPreClose(t *testing.T) {
+	oldList, oldSend := listWindowPanes, sendToPane
+	t.Cleanup(func() { listWindowPanes, sendToPane = oldList, oldSend })
+
+	panes := []tmux.PaneInfo{{Pane: 0, CurrentCommand: "zsh"}, {Pane: 1, CurrentCommand: "nvim"}}
+	listWindowPanes = func(context.Context, string) ([]tmux.PaneInfo, error) {
+		return panes, nil
+	}
+	var sent []string
+	sendToPane = func(_ context.Context, _ string, pane int, keys string) error {
+		sent = append(sent, keys)
+		// The editor quits back to the shell
+		panes = []tmux.PaneInfo{{Pane: 0, CurrentCommand: "zsh"}, {Pane: 1, CurrentCommand: "zsh"}}
+		return nil
+	}
+
+	p := output.New(io.Discard, output.Human)
+	runPreClose(context.Background(), p, "feat", []config.PreClose{{Pane: "editor", Keys: ":wqa"}})
+	if want := []string{":wqa"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("Expected %v to be typed, got %v", want, sent)
+	}
+
+	// Pane numbers start at tmux's pane-base-index
+	sent = nil
+	panes = []tmux.PaneInfo{{Pane: 1, CurrentCommand: "zsh"}, {Pane: 2, CurrentCommand: "nvim"}}
+	runPreClose(context.Background(), p, "feat", []config.PreClose{{Pane: "editor", Keys: ":wqa"}})
+	if want := []string{":wqa"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("Expected %v to be typed with pane-base-index 1, got %v", want, sent)
+	}
+
+	// Without a window there is nothing to type into
+	sent = nil
+	listWindowPanes = func(context.Context, string) ([]tmux.PaneInfo, error) {
+		return nil, errors.New("no tmux window found")
+	}
+	runPreClose(context.Background(), p, "feat", []config.PreClose{{Pane: "editor", Keys: ":wqa"}})
+	if len(sent) != 0 {
+		t.Errorf("Expected nothing to be typed without a window, got %v", sent)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a shell prompt segment for the current worktree",
+	Long: `Print a compact segment describing the current koh worktree, suitable for
+embedding in PS1, PROMPT or a starship custom module.
+
+The segment shows the worktree name, its branch, a dot when there are
+uncommitted changes and [read-only] for worktrees marked with
+'koh readonly'. Nothing is printed outside a koh worktree. The
+worktree list and which worktrees are dirty are read from koh's cache so
+the segment stays fast on large repositories; the dot may lag a change by
+up to 10 seconds.
+
+Examples:
+  # zsh
+  PROMPT='$(koh prompt --shell zsh) %~ %# '
+
+  # bash
+  PS1='$(koh prompt --shell bash) \w \$ '
+
+  # starship.toml
+  [custom.koh]
+  command = "koh prompt"
+  when = true`,
+	Args:         cobra.NoArgs,
+	RunE:         runPrompt,
+	SilenceUsage: true,
+}
+
+var (
+	promptPlain bool
+	promptShell string
+)
+
+func init() {
+	promptCmd.Flags().BoolVar(&promptPlain, "plain", false, "Output without colors")
+	promptCmd.Flags().StringVar(&promptShell, "shell", "", "Wrap escape sequences for the given shell (bash or zsh)")
+	rootCmd.AddCommand(promptCmd)
+}
+
+// promptSegment holds the data rendered into a prompt segment
+type promptSegment struct {
+	name     string
+	branch   string
+	dirty    bool
+	readonly bool
+}
+
+// ansiSequence matches SGR escape sequences emitted by lipgloss
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// renderPrompt renders a prompt segment. Styled output always uses ANSI
+// colors, since prompts are captured through a pipe where color detection
+// would otherwise disable them.
+func renderPrompt(seg promptSegment, plain bool, shell string) (string, error) {
+	parts := []string{
+		styles.IconTree + " " + seg.name,
+		styles.IconBranch + " " + seg.branch,
+	}
+	if seg.dirty {
+		parts = append(parts, styles.IconDirty)
+	}
+	if seg.readonly {
+		parts = append(parts, styles.MarkerReadonly.Label())
+	}
+
+	if plain {
+		return strings.Join(parts, " "), nil
+	}
+
+	r := lipgloss.NewRenderer(os.Stdout)
+	r.SetColorProfile(termenv.ANSI)
+
+	styled := []string{
+		r.NewStyle().Bold(true).Foreground(styles.Primary).Render(parts[0]),
+		r.NewStyle().Foreground(styles.Subtle).Render(parts[1]),
+	}
+	for _, part := range parts[2:] {
+		styled = append(styled, r.NewStyle().Foreground(styles.Warning).Render(part))
+	}
+	out := strings.Join(styled, " ")
+
+	// Shells need non-printing sequences marked so they can compute prompt width
+	switch shell {
+	case "":
+		return out, nil
+	case "bash":
+		return ansiSequence.ReplaceAllString(out, `\[$0\]`), nil
+	case "zsh":
+		return ansiSequence.ReplaceAllString(out, `%{$0%}`), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q (expected bash or zsh)", shell)
+	}
+}
+
+// promptDirty reports whether the worktree at path has uncommitted changes,
+// sharing the briefly cached dirty state of completions since a git status
+// on every prompt is too slow on large repositories
+func promptDirty(path string) bool {
+	worktrees, err := loadKohWorktrees(context.Background())
+	if err != nil {
+		dirty, _ := git.IsDirty(path)
+		return dirty
+	}
+	return dirtyWorktrees(worktrees)[path]
+}
+
+func runPrompt(cmd *cobra.Command, _ []string) error {
+	info, err := detectCurrentWorktree()
+	if err != nil {
+		// Outside a koh worktree the prompt segment is simply empty
+		return nil
+	}
+
+	out, err := renderPrompt(promptSegment{name: info.Name, branch: info.Branch, dirty: promptDirty(info.Path), readonly: isReadonly(info.Name)}, promptPlain, promptShell)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprint(cmd.OutOrStdout(), out)
+	return nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	seg := promptSegment{name: "auth-fix", branch: "feature/auth-fix", dirty: true}
+
+	t.Run("plain", func(t *testing.T) {
+		out, err := renderPrompt(seg, true, "")
+		if err != nil {
+			t.Fatalf("renderPrompt() failed: %v", err)
+		}
+		want := "⚘ auth-fix ⎇ feature/auth-fix ●"
+		if out != want {
+			t.Errorf("Got %q, want %q", out, want)
+		}
+	})
+
+	t.Run("plain clean", func(t *testing.T) {
+		clean := seg
+		clean.dirty = false
+		out, err := renderPrompt(clean, true, "")
+		if err != nil {
+			t.Fatalf("renderPrompt() failed: %v", err)
+		}
+		if strings.Contains(out, "●") {
+			t.Errorf("Clean worktree should not show dirty marker: %q", out)
+		}
+	})
+
+	t.Run("plain read-only", func(t *testing.T) {
+		readonly := seg
+		readonly.readonly = true
+		out, err := renderPrompt(readonly, true, "")
+		if err != nil {
+			t.Fatalf("renderPrompt() failed: %v", err)
+		}
+		if want := "⚘ auth-fix ⎇ feature/auth-fix ● [read-only]"; out != want {
+			t.Errorf("Got %q, want %q", out, want)
+		}
+	})
+
+	t.Run("styled always has colors", func(t *testing.T) {
+		out, err := renderPrompt(seg, false, "")
+		if err != nil {
+			t.Fatalf("renderPrompt() failed: %v", err)
+		}
+		if !strings.Contains(out, "\x1b[") {
+			t.Errorf("Expected ANSI escape sequences in styled output: %q", out)
+		}
+	})
+
+	t.Run("bash wrapping", func(t *testing.T) {
+		out, err := renderPrompt(seg, false, "bash")
+		if err != nil {
+			t.Fatalf("renderPrompt() failed: %v", err)
+		}
+		if !strings.Contains(out, `\[`+"\x1b[") {
+			t.Errorf("Expected escape sequences wrapped in \\[ \\]: %q", out)
+		}
+	})
+
+	t.Run("zsh wrapping", func(t *testing.T) {
+		out, err := renderPrompt(seg, false, "zsh")
+		if err != nil {
+			t.Fatalf("renderPrompt() failed: %v", err)
+		}
+		if !strings.Contains(out, "%{\x1b[") {
+			t.Errorf("Expected escape sequences wrapped in %%{ %%}: %q", out)
+		}
+	})
+
+	t.Run("unknown shell", func(t *testing.T) {
+		if _, err := renderPrompt(seg, false, "fish"); err == nil {
+			t.Error("Expected error for unsupported shell, got nil")
+		}
+	})
+}
+
+func TestPromptDirtyUsesCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	newDashboardRepo(t)
+	worktrees, err := loadKohWorktrees(context.Background())
+	if err != nil || len(worktrees) != 1 {
+		t.Fatalf("loadKohWorktrees() = %v, %v", worktrees, err)
+	}
+	path := worktrees[0].Path
+
+	if promptDirty(path) {
+		t.Fatal("Expected a fresh worktree to be clean")
+	}
+	testutil.WriteFile(t, path, "wip.txt", "unsaved")
+	if promptDirty(path) {
+		t.Error("Expected the cached dirty state to be reused")
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+	if err := cache.Forget(commonDir, cache.KindDirty); err != nil {
+		t.Fatalf("Forget() failed: %v", err)
+	}
+	if !promptDirty(path) {
+		t.Error("Expected the worktree to be dirty once the cache is dropped")
+	}
+}
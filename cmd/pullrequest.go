@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/styles"
+)
+
+// pullRequestCacheTTL keeps forge queries to at most one per repository per
+// interval, which avoids rate limits when status is polled
+const pullRequestCacheTTL = 2 * time.Minute
+
+// pullRequestQuery is the cached outcome of listing pull requests. A failed
+// query is cached too, so a repository gh can't query (no remote on a forge,
+// not logged in) isn't asked again on every status poll.
+type pullRequestQuery struct {
+	PullRequests []forge.PullRequest `json:"pull_requests"`
+	Failed       bool                `json:"failed,omitempty"`
+}
+
+// loadPullRequests returns the repository's recent pull requests, cached
+// briefly. Returns nil when gh is unavailable or the query fails, so callers
+// simply omit PR information.
+func loadPullRequests(ctx context.Context) []forge.PullRequest {
+	if !forge.Available() {
+		return nil
+	}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil
+	}
+
+	query, err := cache.Recent(commonDir, "pulls", pullRequestCacheTTL, func() (pullRequestQuery, error) {
+		prs, err := forge.ListPullRequests(ctx)
+		if err != nil {
+			// A cancelled query says nothing about the repository
+			if ctx.Err() != nil {
+				return pullRequestQuery{}, err
+			}
+			return pullRequestQuery{Failed: true}, nil
+		}
+		return pullRequestQuery{PullRequests: prs}, nil
+	})
+	if err != nil || query.Failed {
+		return nil
+	}
+	return query.PullRequests
+}
+
+// renderPullRequest renders a short PR summary such as "#12 open ✓ CI"
+func renderPullRequest(pr *forge.PullRequest) string {
+	marker := styles.MarkerPRClosed
+	switch pr.State {
+	case forge.StateOpen:
+		marker = styles.MarkerPROpen
+	case forge.StateMerged:
+		marker = styles.MarkerPRMerged
+	}
+	label := marker.RenderWith(fmt.Sprintf("#%d %s", pr.Number, pr.State))
+
+	switch pr.CI {
+	case forge.CIPassing:
+		label += " " + styles.MarkerCIPassing.Render()
+	case forge.CIFailing:
+		label += " " + styles.MarkerCIFailing.Render()
+	case forge.CIPending:
+		label += " " + styles.MarkerCIPending.Render()
+	}
+	return label
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestLoadPullRequestsCachesFailures(t *testing.T) {
+	testutil.NewRepo(t)
+
+	// A gh that counts its runs and always fails
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	gh := "#!/bin/sh\necho run >> " + calls + "\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx := context.Background()
+	for range 3 {
+		if prs := loadPullRequests(ctx); prs != nil {
+			t.Errorf("Expected no pull requests from a failing gh, got %v", prs)
+		}
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("Expected gh to run: %v", err)
+	}
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Errorf("Expected gh to run once while its failure is cached, got %d runs", runs)
+	}
+}
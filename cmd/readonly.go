@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/shell"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var readonlyCmd = &cobra.Command{
+	Use:   "readonly <worktree-name> [on|off]",
+	Short: "Mark a worktree as reference-only",
+	Long: `Mark a worktree as read-only, e.g. to keep a pristine checkout of a
+release branch around for reference. Without on or off, show whether the
+worktree is read-only.
+
+A read-only worktree is marked in 'koh list', 'koh status' and 'koh prompt',
+and 'koh switch' reminds you of it. Start new work from it with
+'koh new <name> --stack-on <worktree-name>' instead of changing it. 'koh cleanup'
+with --merged, --all or --interactive and 'koh advise' leave it alone; clean
+it up by name.
+
+Committing to it warns too, whether with 'koh wip' or plain 'git commit':
+marking it read-only points core.hooksPath of that worktree alone at a
+pre-commit hook that prints a warning and then runs the repository's own
+hooks. Turning read-only off removes the hook again.`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runReadonly,
+}
+
+func init() {
+	rootCmd.AddCommand(readonlyCmd)
+}
+
+// readonlyResult is the machine-readable result of 'koh readonly'
+type readonlyResult struct {
+	Name     string `json:"name"`
+	Readonly bool   `json:"readonly"`
+	// Changed is set when the command turned read-only on or off
+	Changed bool `json:"changed,omitempty"`
+}
+
+// parseReadonlyState maps "on" or "off" to whether the worktree is read-only
+func parseReadonlyState(arg string) (bool, error) {
+	switch arg {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid state %q (expected on or off)", arg)
+}
+
+// recordReadonly marks a worktree read-only, or clears the mark
+func recordReadonly(commonDir, name string, readonly bool) error {
+	return state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(name).Readonly = readonly
+		return nil
+	})
+}
+
+// isReadonly reports whether the worktree called name is marked read-only
+func isReadonly(name string) bool {
+	wt := loadRecordedWorktrees()[name]
+	return wt != nil && wt.Readonly
+}
+
+// keepReadonly reports whether a bulk cleanup keeps the worktree called
+// name because it's read-only, telling the user so. Only naming the
+// worktree cleans it up.
+func keepReadonly(p *output.Printer, recorded map[string]*state.Worktree, name string) bool {
+	if wt := recorded[name]; wt == nil || !wt.Readonly {
+		return false
+	}
+	p.Info("Keeping %s, which is read-only", name)
+	return true
+}
+
+// readonlyHooksDir is the directory, inside a worktree's own git directory,
+// holding the hooks of a read-only worktree
+const readonlyHooksDir = "koh-hooks"
+
+// readonlyPreCommit returns a pre-commit hook warning that the worktree
+// called name is read-only before running the repository's pre-commit hook
+// in hooksDir, if there is one
+func readonlyPreCommit(name, hooksDir string) string {
+	warning := fmt.Sprintf("koh: %s is read-only; start new work with 'koh new <name> --stack-on %s'", name, name)
+	return fmt.Sprintf(`#!/bin/sh
+# Installed by 'koh readonly %s on'; removed by 'koh readonly %s off'
+echo %s >&2
+hook=%s
+if [ -x "$hook" ]; then
+	exec "$hook" "$@"
+fi
+`, name, name, shell.Quote(warning), shell.Quote(filepath.Join(hooksDir, "pre-commit")))
+}
+
+// installReadonlyHook points core.hooksPath of the worktree at path, called
+// name, at a directory holding the warning pre-commit hook and links to the
+// repository's other hooks, so those keep running
+func installReadonlyHook(ctx context.Context, path, name string) error {
+	hooksDir, err := git.GitPathWithContext(ctx, path, "hooks")
+	if err != nil {
+		return err
+	}
+	dir, err := git.GitPathWithContext(ctx, path, readonlyHooksDir)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(hooksDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		hook := entry.Name()
+		if hook == "pre-commit" || strings.HasSuffix(hook, ".sample") || entry.IsDir() {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(hooksDir, hook), filepath.Join(dir, hook)); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pre-commit"), []byte(readonlyPreCommit(name, hooksDir)), 0o755); err != nil {
+		return err
+	}
+	return git.SetWorktreeConfigWithContext(ctx, path, "core.hooksPath", dir)
+}
+
+// removeReadonlyHook undoes installReadonlyHook for the worktree at path
+func removeReadonlyHook(ctx context.Context, path string) error {
+	if err := git.UnsetWorktreeConfigWithContext(ctx, path, "core.hooksPath"); err != nil {
+		return err
+	}
+	dir, err := git.GitPathWithContext(ctx, path, readonlyHooksDir)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// warnReadonlyCommit warns before koh commits to a read-only worktree
+func warnReadonlyCommit(p *output.Printer, name string) {
+	if isReadonly(name) {
+		p.Warn("%s is read-only; committing to it anyway (start new work with 'koh new <name> --stack-on %s')", name, name)
+	}
+}
+
+func runReadonly(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+
+	name := args[0]
+	if err := validation.ValidateWorktreeName(name); err != nil {
+		return fmt.Errorf("invalid worktree name: %w", err)
+	}
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	ctx := context.Background()
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	wt, ok := worktrees[name]
+	if !ok {
+		return fmt.Errorf("worktree %s does not exist\nRun 'koh list' to see your worktrees", name)
+	}
+
+	result := readonlyResult{Name: name, Readonly: isReadonly(name)}
+	if len(args) == 2 {
+		readonly, err := parseReadonlyState(args[1])
+		if err != nil {
+			return err
+		}
+		if readonly != result.Readonly {
+			if readonly {
+				err = installReadonlyHook(ctx, wt.Path, name)
+			} else {
+				err = removeReadonlyHook(ctx, wt.Path)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update the pre-commit hook: %w", err)
+			}
+			if err := recordReadonly(commonDir, name, readonly); err != nil {
+				return fmt.Errorf("failed to record read-only state: %w", err)
+			}
+			result.Readonly, result.Changed = readonly, true
+		}
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		switch {
+		case result.Readonly && result.Changed:
+			fprintln(w, styles.RenderSuccess(name+" is now read-only"))
+		case result.Changed:
+			fprintln(w, styles.RenderSuccess(name+" is no longer read-only"))
+		case result.Readonly:
+			fprintln(w, name+" is read-only")
+		default:
+			fprintln(w, name+" is not read-only")
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestParseReadonlyState(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    bool
+		wantErr bool
+	}{
+		{arg: "on", want: true},
+		{arg: "off", want: false},
+		{arg: "yes", wantErr: true},
+		{arg: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReadonlyState(tt.arg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Expected parseReadonlyState(%q) = %v (error %v), got %v (%v)", tt.arg, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestRunReadonly(t *testing.T) {
+	newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	var out bytes.Buffer
+	readonlyCmd.SetOut(&out)
+	t.Cleanup(func() { readonlyCmd.SetOut(nil) })
+
+	if err := runReadonly(readonlyCmd, []string{"feat-a", "on"}); err != nil {
+		t.Fatalf("runReadonly() failed: %v", err)
+	}
+	if !isReadonly("feat-a") {
+		t.Error("Expected feat-a to be read-only")
+	}
+	if !strings.Contains(out.String(), "feat-a is now read-only") {
+		t.Errorf("Expected confirmation, got %q", out.String())
+	}
+
+	// Listed with its mark
+	items, err := loadListItems("", "", false)
+	if err != nil {
+		t.Fatalf("loadListItems() failed: %v", err)
+	}
+	if len(items) != 1 || !items[0].readonly {
+		t.Errorf("Expected feat-a to be listed as read-only, got %+v", items)
+	}
+
+	out.Reset()
+	if err := runReadonly(readonlyCmd, []string{"feat-a", "off"}); err != nil {
+		t.Fatalf("runReadonly() failed: %v", err)
+	}
+	if isReadonly("feat-a") {
+		t.Error("Expected feat-a to no longer be read-only")
+	}
+
+	if err := runReadonly(readonlyCmd, []string{"missing", "on"}); err == nil {
+		t.Error("Expected an error for a worktree that doesn't exist")
+	}
+	if err := runReadonly(readonlyCmd, []string{"feat-a", "maybe"}); err == nil {
+		t.Error("Expected an error for an invalid state")
+	}
+}
+
+func TestReadonlyHook(t *testing.T) {
+	repo := newDashboardRepo(t)
+	worktree := filepath.Join(repo, ".koh", "feat-a")
+	// The repository's own hooks keep running
+	testutil.WriteFile(t, repo, filepath.Join(".git", "hooks", "pre-commit"), "#!/bin/sh\necho repo pre-commit\n")
+	testutil.WriteFile(t, repo, filepath.Join(".git", "hooks", "post-commit"), "#!/bin/sh\necho repo post-commit\n")
+	for _, hook := range []string{"pre-commit", "post-commit"} {
+		if err := os.Chmod(filepath.Join(repo, ".git", "hooks", hook), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	readonlyCmd.SetOut(&out)
+	t.Cleanup(func() { readonlyCmd.SetOut(nil) })
+
+	if err := runReadonly(readonlyCmd, []string{"feat-a", "on"}); err != nil {
+		t.Fatalf("runReadonly() failed: %v", err)
+	}
+	commit := testutil.Git(t, worktree, "commit", "--allow-empty", "-m", "change")
+	for _, want := range []string{"koh: feat-a is read-only", "repo pre-commit", "repo post-commit"} {
+		if !strings.Contains(commit, want) {
+			t.Errorf("Expected committing to print %q, got %q", want, commit)
+		}
+	}
+	// Other checkouts don't warn
+	if commit := testutil.Git(t, repo, "commit", "--allow-empty", "-m", "change"); strings.Contains(commit, "read-only") {
+		t.Errorf("Expected no warning in the main checkout, got %q", commit)
+	}
+
+	if err := runReadonly(readonlyCmd, []string{"feat-a", "off"}); err != nil {
+		t.Fatalf("runReadonly() failed: %v", err)
+	}
+	commit = testutil.Git(t, worktree, "commit", "--allow-empty", "-m", "change")
+	if strings.Contains(commit, "read-only") || !strings.Contains(commit, "repo pre-commit") {
+		t.Errorf("Expected only the repository's hooks after turning read-only off, got %q", commit)
+	}
+	if dir, err := git.GitPathWithContext(context.Background(), worktree, readonlyHooksDir); err != nil {
+		t.Fatalf("GitPathWithContext() failed: %v", err)
+	} else if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", dir, err)
+	}
+}
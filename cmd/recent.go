@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var recentCmd = &cobra.Command{
+	Use:   "recent [number]",
+	Short: "List recently used worktrees, or switch to one by number",
+	Long: `List the worktrees koh switched to most recently, newest first and
+numbered from 1, and switch to one with 'koh recent <number>'. Since the
+worktree you're in is usually number 1, 'koh recent 2' goes back to the
+one before it.
+
+A worktree counts as used when 'koh switch', 'koh list' or 'koh new'
+selects its window; windows opened in the background don't count.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRecent,
+}
+
+// recentLimit is how many worktrees are listed
+var recentLimit int
+
+func init() {
+	recentCmd.Flags().IntVarP(&recentLimit, "limit", "n", 9, "How many worktrees to list")
+	rootCmd.AddCommand(recentCmd)
+}
+
+// recentWorktree is a worktree in the 'koh recent' list
+type recentWorktree struct {
+	Number int       `json:"number"`
+	Name   string    `json:"name"`
+	UsedAt time.Time `json:"used_at"`
+	// Current is set for the worktree the current shell belongs to
+	Current bool `json:"current"`
+}
+
+// recentWorktrees returns the worktrees of live that were used, most
+// recently used first and numbered from 1
+func recentWorktrees(recorded map[string]*state.Worktree, live map[string]bool, current string) []recentWorktree {
+	recent := []recentWorktree{}
+	for name, wt := range recorded {
+		if live[name] && !wt.UsedAt.IsZero() {
+			recent = append(recent, recentWorktree{Name: name, UsedAt: wt.UsedAt, Current: name == current})
+		}
+	}
+	sort.Slice(recent, func(i, j int) bool {
+		if !recent[i].UsedAt.Equal(recent[j].UsedAt) {
+			return recent[i].UsedAt.After(recent[j].UsedAt)
+		}
+		return recent[i].Name < recent[j].Name
+	})
+	for i := range recent {
+		recent[i].Number = i + 1
+	}
+	return recent
+}
+
+// usedAgo describes how long ago a worktree was used, to the minute
+func usedAgo(t, now time.Time) string {
+	switch d := now.Sub(t); {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return commitAge(t, now)
+}
+
+// loadRecentWorktrees returns the repository's recently used worktrees
+func loadRecentWorktrees(ctx context.Context) ([]recentWorktree, error) {
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	live := map[string]bool{mainCheckoutName: true}
+	for _, wt := range worktrees {
+		live[filepath.Base(wt.Path)] = true
+	}
+
+	current, err := extractWorkTreeName()
+	if err != nil || !live[current] {
+		current = mainCheckoutName
+	}
+	return recentWorktrees(loadRecordedWorktrees(), live, current), nil
+}
+
+func runRecent(cmd *cobra.Command, args []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	if recentLimit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+	ctx := context.Background()
+	p := newPrinter(cmd)
+
+	recent, err := loadRecentWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if len(recent) > recentLimit {
+			recent = recent[:recentLimit]
+		}
+		return p.Result(recent, func(w io.Writer) {
+			if len(recent) == 0 {
+				fprintln(w, "No worktrees used yet; 'koh switch' records them")
+				return
+			}
+			now := time.Now()
+			for _, r := range recent {
+				name := r.Name
+				if r.Current {
+					name = styles.Active.Render(name + " (current)")
+				}
+				fprintln(w, fmt.Sprintf("%s  %s  %s", styles.Key.Render(fmt.Sprintf("%2d", r.Number)), name, styles.Muted.Render(usedAgo(r.UsedAt, now))))
+			}
+		})
+	}
+
+	number, err := strconv.Atoi(args[0])
+	if err != nil || number < 1 {
+		return fmt.Errorf("invalid number %q\nRun 'koh recent' to list the numbers", args[0])
+	}
+	if number > len(recent) {
+		return fmt.Errorf("only %d recently used worktree(s)\nRun 'koh recent' to list them", len(recent))
+	}
+	name := recent[number-1].Name
+
+	if err := checkDirtyBeforeSwitch(ctx, p, name); err != nil {
+		return err
+	}
+	result, err := switchToWorktree(p, name, false, false)
+	if err != nil {
+		return err
+	}
+	return p.Result(result, func(w io.Writer) {
+		if result.Created {
+			fprintln(w, "Session created successfully!")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/state"
+)
+
+func TestRecentWorktrees(t *testing.T) {
+	now := time.Date(2025, 6, 30, 14, 0, 0, 0, time.UTC)
+	recorded := map[string]*state.Worktree{
+		"auth":    {UsedAt: now.Add(-time.Hour)},
+		"billing": {UsedAt: now.Add(-time.Minute)},
+		"main":    {UsedAt: now.Add(-2 * time.Hour)},
+		"gone":    {UsedAt: now},
+		"never":   {CreatedAt: now},
+	}
+	live := map[string]bool{"auth": true, "billing": true, "main": true, "never": true}
+
+	got := recentWorktrees(recorded, live, "billing")
+	want := []recentWorktree{
+		{Number: 1, Name: "billing", UsedAt: now.Add(-time.Minute), Current: true},
+		{Number: 2, Name: "auth", UsedAt: now.Add(-time.Hour)},
+		{Number: 3, Name: "main", UsedAt: now.Add(-2 * time.Hour)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestUsedAgo(t *testing.T) {
+	now := time.Date(2025, 6, 30, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{30 * time.Hour, "1 day ago"},
+		{5 * 24 * time.Hour, "5 days ago"},
+	}
+
+	for _, tt := range tests {
+		if got := usedAgo(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("Expected usedAgo(%v) = %q, got %q", tt.ago, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Update windows and recorded state with the branches checked out",
+	Long: `Bring koh's view of each worktree in line with the branch actually checked
+out in it, e.g. after running 'git checkout' inside a worktree.
+
+koh windows are named after the worktree, and renamed to show the branch
+checked out when it isn't named after the worktree too, e.g.
+"myapp|review (feature/login)". The branch is also kept in the @koh_branch
+window option for use in tmux formats. 'koh status' refreshes them too. To
+refresh whenever you change windows, add the hook printed by
+'koh refresh --print-hook' to ~/.tmux.conf; it runs 'koh refresh --pane'
+with the pane you changed to, for the repository that pane is in.`,
+	Args: cobra.NoArgs,
+	RunE: runRefresh,
+}
+
+var (
+	// refreshPrintHook prints a tmux.conf snippet instead of refreshing
+	refreshPrintHook bool
+	// refreshPane refreshes the repository of a tmux pane, e.g. "%7", rather
+	// than the current directory's
+	refreshPane string
+)
+
+// refreshHook keeps the names of koh windows in line with their branches. The
+// pane is passed by its ID, which unlike its directory is safe in a shell
+// command.
+const refreshHook = `# Rename koh windows when the branch checked out in them changes
+set-hook -g session-window-changed 'run-shell -b "koh refresh --pane #{pane_id} >/dev/null 2>&1 || true"'`
+
+func init() {
+	refreshCmd.Flags().BoolVar(&refreshPrintHook, "print-hook", false, "Print a tmux.conf snippet that refreshes windows automatically")
+	refreshCmd.Flags().StringVar(&refreshPane, "pane", "", "Refresh the repository the given tmux pane (e.g. %7) is in")
+	rootCmd.AddCommand(refreshCmd)
+}
+
+// branchChange is a worktree whose checked out branch changed since koh last looked
+type branchChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// refreshResult is the machine-readable result of 'koh refresh'
+type refreshResult struct {
+	Changes []branchChange `json:"changes"`
+	// WindowsUpdated is how many windows were renamed for a new branch
+	WindowsUpdated int `json:"windows_updated"`
+}
+
+// recordBranches updates the branch recorded for each worktree koh manages,
+// returning the ones that changed and whether any record was updated.
+// Worktrees without a record are left alone, so they still show up as
+// created outside koh.
+func recordBranches(s *state.State, worktrees []git.Worktree) ([]branchChange, bool) {
+	changes := []branchChange{}
+	updated := false
+	for _, wt := range worktrees {
+		record := s.Worktrees[filepath.Base(wt.Path)]
+		if wt.Prunable || record == nil {
+			continue
+		}
+		branch := displayBranch(wt)
+		if record.Branch == branch {
+			continue
+		}
+		if record.Branch != "" {
+			changes = append(changes, branchChange{Name: filepath.Base(wt.Path), From: record.Branch, To: branch})
+		}
+		record.Branch = branch
+		updated = true
+	}
+	return changes, updated
+}
+
+// refreshBranches records the current branch of each worktree and renames
+// open windows to show it. The state file is only rewritten when a recorded branch
+// is out of date, since 'koh status' refreshes on every run.
+func refreshBranches(ctx context.Context, worktrees []git.Worktree) (refreshResult, error) {
+	result := refreshResult{}
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return result, err
+	}
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return result, err
+	}
+	changes, outdated := recordBranches(s, worktrees)
+	result.Changes = changes
+	if outdated {
+		if err := state.Update(commonDir, func(s *state.State) error {
+			result.Changes, _ = recordBranches(s, worktrees)
+			return nil
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if !tmux.IsInTmux() {
+		return result, nil
+	}
+	windows, err := tmux.WindowBranchesWithContext(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, wt := range worktrees {
+		name := filepath.Base(wt.Path)
+		tagged, open := windows[name]
+		if !open || tagged == displayBranch(wt) {
+			continue
+		}
+		if err := tmux.SetWindowBranchWithContext(ctx, name, displayBranch(wt)); err != nil {
+			return result, err
+		}
+		result.WindowsUpdated++
+	}
+	return result, nil
+}
+
+func runRefresh(cmd *cobra.Command, _ []string) error {
+	if refreshPrintHook {
+		fprintln(cmd.OutOrStdout(), refreshHook)
+		return nil
+	}
+
+	ctx := context.Background()
+	if refreshPane != "" {
+		dir, err := tmux.PanePathWithContext(ctx, refreshPane)
+		if err != nil {
+			return err
+		}
+		if err := os.Chdir(dir); err != nil {
+			return fmt.Errorf("failed to change to %s: %w", dir, err)
+		}
+	}
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := refreshBranches(ctx, worktrees)
+	if err != nil {
+		return err
+	}
+
+	return newPrinter(cmd).Result(result, func(w io.Writer) {
+		for _, c := range result.Changes {
+			fprintln(w, fmt.Sprintf("  %s %s %s %s", c.Name+":", c.From, styles.IconArrow, c.To))
+		}
+		if len(result.Changes) == 0 && result.WindowsUpdated == 0 {
+			fprintln(w, styles.Muted.Render("Everything is up to date"))
+			return
+		}
+		if result.WindowsUpdated > 0 {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Updated %d window(s)", result.WindowsUpdated)))
+		}
+	})
+}
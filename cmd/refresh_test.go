@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+)
+
+func TestRecordBranches(t *testing.T) {
+	s := &state.State{Worktrees: map[string]*state.Worktree{
+		"auth":    {Branch: "auth"},
+		"billing": {Branch: "billing"},
+		"new":     {},
+	}}
+	worktrees := []git.Worktree{
+		{Path: "/repo/.koh/auth", Branch: "auth-v2"},
+		{Path: "/repo/.koh/billing", Branch: "billing"},
+		{Path: "/repo/.koh/new", Branch: "new"},
+		{Path: "/repo/.koh/raw", Branch: "raw"},
+	}
+
+	changes, updated := recordBranches(s, worktrees)
+
+	if len(changes) != 1 || changes[0] != (branchChange{Name: "auth", From: "auth", To: "auth-v2"}) {
+		t.Errorf("Expected only auth to have changed, got %+v", changes)
+	}
+	if got := s.Worktrees["new"].Branch; got != "new" {
+		t.Errorf("Expected the branch of new to be recorded, got %q", got)
+	}
+	if s.Worktrees["raw"] != nil {
+		t.Errorf("Expected no record for a worktree created outside koh, got %+v", s.Worktrees["raw"])
+	}
+	if !updated {
+		t.Error("Expected recordBranches() to report updated records")
+	}
+
+	// Recording the same branches again has nothing to write
+	if changes, updated := recordBranches(s, worktrees); len(changes) != 0 || updated {
+		t.Errorf("Expected nothing to record the second time, got %+v (updated %v)", changes, updated)
+	}
+}
+
+func TestRefreshHook(t *testing.T) {
+	// The hook must leave the user's status line alone and never put a
+	// directory, which may contain quotes, in the shell command it runs
+	if strings.Contains(refreshHook, "window-status") {
+		t.Errorf("Expected the hook not to set window-status formats:\n%s", refreshHook)
+	}
+	if strings.Contains(refreshHook, "pane_current_path") || !strings.Contains(refreshHook, "koh refresh --pane #{pane_id}") {
+		t.Errorf("Expected the hook to pass the pane ID to koh refresh:\n%s", refreshHook)
+	}
+}
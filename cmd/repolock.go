@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/state"
+)
+
+// repoLockTimeout is how long a command waits for another koh process that
+// is adding or removing worktrees of the same repository
+const repoLockTimeout = 2 * time.Minute
+
+// lockRepo takes the repository lock (see state.LockRepo) for command, such
+// as "koh new feature", telling the user when it has to wait for another koh
+// process. Outside a repository there is nothing to lock, and commands
+// report that themselves.
+func lockRepo(p *output.Printer, command string) (func(), error) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return func() {}, nil
+	}
+	unlock, err := state.LockRepo(commonDir, command, repoLockTimeout, func(holder state.LockHolder) {
+		p.Info("Waiting for %s to finish", holder)
+	})
+	if errors.Is(err, state.ErrRepoLocked) {
+		return nil, fmt.Errorf("%w\nTry again once it has finished", err)
+	}
+	return unlock, err
+}
+
+// commandLine describes a command for the repository lock, e.g.
+// "koh cleanup feature"
+func commandLine(path string, args []string) string {
+	return strings.TrimSpace(path + " " + strings.Join(args, " "))
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <worktree-name>",
+	Short: "Resume the processes stopped by 'koh pause'",
+	Long: `Bring back the processes 'koh pause' stopped in a worktree's window.
+
+Interrupted panes get the command koh last sent to them again; panes
+suspended with 'koh pause --suspend' are continued with 'fg'.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
+
+// resumeResult is the machine-readable result of 'koh resume'
+type resumeResult struct {
+	Name  string        `json:"name"`
+	Panes []paneUpgrade `json:"panes"`
+}
+
+// planResume returns what to send to each paused pane. last maps panes to
+// the command koh last sent to them.
+func planResume(pause *state.Pause, last map[int]string) []paneUpgrade {
+	steps := []paneUpgrade{}
+	for _, pane := range pause.Panes {
+		step := paneUpgrade{Pane: pane, Action: paneSent}
+		switch {
+		case pause.Suspend:
+			step.Command = "fg"
+		case last[pane] != "":
+			step.Command = last[pane]
+		default:
+			step.Action = paneSkipped
+			step.Detail = "no recorded command"
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	worktreeName := args[0]
+	p := newPrinter(cmd)
+
+	commonDir, err := worktreeWindowCommonDir(worktreeName)
+	if err != nil {
+		return err
+	}
+
+	s, err := state.Load(commonDir)
+	if err != nil {
+		return err
+	}
+	wt := s.Worktree(worktreeName)
+	if wt.Paused == nil {
+		return fmt.Errorf("worktree %s is not paused", worktreeName)
+	}
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	result := resumeResult{Name: worktreeName, Panes: planResume(wt.Paused, wt.LastPaneCommands())}
+
+	exists, err := tmux.WindowExistsWithContext(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		for i, step := range result.Panes {
+			if step.Action != paneSent {
+				continue
+			}
+			if err := tmux.SendToPaneWithContext(ctx, worktreeName, step.Pane, step.Command); err != nil {
+				result.Panes[i].Action = paneSkipped
+				result.Panes[i].Detail = err.Error()
+			}
+		}
+	}
+
+	// A closed window has nothing left to resume; 'koh switch' starts afresh
+	if err := state.Update(commonDir, func(s *state.State) error {
+		s.Worktree(worktreeName).Paused = nil
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		if !exists {
+			fprintln(w, styles.Muted.Render(fmt.Sprintf("The window of %s was closed; use 'koh switch %s' to reopen it", worktreeName, worktreeName)))
+			return
+		}
+		for _, step := range result.Panes {
+			fprintln(w, renderPaneUpgrade(step, false))
+		}
+		fprintln(w, styles.RenderSuccess("Resumed "+worktreeName))
+	})
+}
@@ -9,23 +9,31 @@
 //   - switch: Switch to an existing worktree's tmux session
 //   - cleanup: Remove a worktree and close its tmux session
 //   - list: Display all koh-managed worktrees
+//   - status: Show the status of all koh worktrees
+//   - info: Show details about a single worktree
+//   - current: Show the worktree the current shell belongs to
+//   - exec: Run a command across worktrees and report a pass/fail matrix
 //   - init: Interactive configuration wizard
 //   - config: Display current configuration
+//   - doctor: Check the koh setup and fix safe problems
+//   - telemetry: Manage opt-in anonymous usage counters
 //
 // Each command is implemented in its own file (new.go, switch.go, cleanup.go, etc.).
 package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
+	"github.com/bshakr/koh/internal/cache"
 	"github.com/bshakr/koh/internal/config"
-	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
 	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/telemetry"
+	"github.com/bshakr/koh/internal/validation"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 )
@@ -38,6 +46,39 @@ var rootCmd = &cobra.Command{
 A tool for managing git worktrees with automatic tmux session setup.
 Creates isolated development environments with pre-configured panes.`,
 	Run: runRoot,
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		cache.Disabled = noCache
+		applyGlobalConfig()
+		if jsonOutput {
+			// Errors are reported as JSON by Execute, never as usage text
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		}
+	},
+}
+
+var (
+	// noCache disables the git query cache for the current invocation
+	noCache bool
+
+	// jsonOutput renders command results as JSON instead of styled text
+	jsonOutput bool
+
+	// assumeYes answers yes to every confirmation of a destructive operation
+	assumeYes bool
+)
+
+// newPrinter returns the output printer for a command, honoring --json
+func newPrinter(cmd *cobra.Command) *output.Printer {
+	return output.New(cmd.OutOrStdout(), printerFormat())
+}
+
+// printerFormat returns the output format selected by --json
+func printerFormat() output.Format {
+	if jsonOutput {
+		return output.JSON
+	}
+	return output.Human
 }
 
 func runRoot(_ *cobra.Command, _ []string) {
@@ -80,8 +121,9 @@ func runRoot(_ *cobra.Command, _ []string) {
 	fmt.Println(topBorder)
 	fmt.Println()
 
-	// Check if in git repo
-	if !git.IsGitRepo() {
+	// Gather the status from the cache so the dashboard shows up right away
+	status, err := loadDashboardStatus(context.Background())
+	if err != nil {
 		errorMsg := lipgloss.NewStyle().
 			Align(lipgloss.Center).
 			Width(terminalWidth).
@@ -98,45 +140,14 @@ func runRoot(_ *cobra.Command, _ []string) {
 		return
 	}
 
-	// Get repository info
-	repoName, _ := git.GetRepoName()
-
-	// Get worktree count and current worktree
-	worktreeCount := 0
-	currentWorktree := ""
-
-	var mainRepoRoot string
-	var currentWorktreePath string
-
-	if git.IsInWorktree() {
-		mainRepoRoot, _ = git.GetMainRepoRoot()
-		currentWorktreePath, _ = git.GetCurrentWorktreePath()
-		currentWorktree = filepath.Base(currentWorktreePath)
-	} else {
-		mainRepoRoot, _ = os.Getwd()
-		currentWorktree = "main"
-	}
-
-	// Count worktrees
-	if mainRepoRoot != "" {
-		kohDir := filepath.Join(mainRepoRoot, ".koh")
-		if _, err := os.Stat(kohDir); err == nil {
-			ctx := context.Background()
-			gitCmd := exec.CommandContext(ctx, "git", "worktree", "list")
-			output, err := gitCmd.Output()
-			if err == nil {
-				lines := strings.Split(string(output), "\n")
-				for _, line := range lines {
-					if strings.Contains(line, "/.koh/") {
-						worktreeCount++
-					}
-				}
-			}
-		}
+	worktreeCount := status.Worktrees
+	worktreesValue := fmt.Sprintf("%d active", worktreeCount)
+	if status.Refreshing {
+		worktreesValue += " " + styles.Muted.Render("(refreshing)")
 	}
 
 	// Check config status
-	configExists, _ := config.ConfigExists()
+	configExists := status.Configured
 	configStatus := styles.ErrorMessage.Render(styles.IconCross + " Not configured")
 	if configExists {
 		configStatus = styles.SuccessMessage.Render(styles.IconCheck + " Configured")
@@ -150,9 +161,9 @@ func runRoot(_ *cobra.Command, _ []string) {
 
 	var statusContent strings.Builder
 	statusContent.WriteString(styles.RenderKeyValue("Version", Version) + "\n")
-	statusContent.WriteString(styles.RenderKeyValue("Repository", repoName) + "\n")
-	statusContent.WriteString(styles.RenderKeyValue("Worktrees", fmt.Sprintf("%d active", worktreeCount)) + "\n")
-	statusContent.WriteString(styles.RenderKeyValue("Current", currentWorktree) + "\n")
+	statusContent.WriteString(styles.RenderKeyValue("Repository", status.RepoName) + "\n")
+	statusContent.WriteString(styles.RenderKeyValue("Worktrees", worktreesValue) + "\n")
+	statusContent.WriteString(styles.RenderKeyValue("Current", status.Current) + "\n")
 	statusContent.WriteString(styles.Key.Render("Config:") + " " + configStatus)
 
 	statusBox := lipgloss.NewStyle().
@@ -447,28 +458,21 @@ func runRoot(_ *cobra.Command, _ []string) {
 	fmt.Println()
 
 	// Context-aware tip with enhanced styling
-	var tip string
-	if !configExists {
-		tip = "💡 Tip: Run 'koh init' to set up your configuration first"
-	} else if worktreeCount == 0 {
-		tip = "💡 Tip: Run 'koh new feature-name' to create your first worktree"
-	} else {
-		tip = "💡 Tip: Use 'koh list' to see all your worktrees"
-	}
-
-	tipBox := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(styles.Warning).
-		Padding(0, 1).
-		Foreground(styles.Warning).
-		Render(tip)
+	if tip := nextHint(hintContext{command: "koh", configured: configExists, worktrees: worktreeCount}); tip != "" && showHints {
+		tipBox := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(styles.Warning).
+			Padding(0, 1).
+			Foreground(styles.Warning).
+			Render("💡 Tip: " + tip)
 
-	centeredTip := lipgloss.NewStyle().
-		Align(lipgloss.Center).
-		Width(terminalWidth).
-		Render(tipBox)
-	fmt.Println(centeredTip)
-	fmt.Println()
+		centeredTip := lipgloss.NewStyle().
+			Align(lipgloss.Center).
+			Width(terminalWidth).
+			Render(tipBox)
+		fmt.Println(centeredTip)
+		fmt.Println()
+	}
 
 	// Bottom decorative border
 	bottomBorder := lipgloss.NewStyle().
@@ -480,18 +484,85 @@ func runRoot(_ *cobra.Command, _ []string) {
 	fmt.Println()
 }
 
+// errSilentFailure makes koh exit non-zero without printing anything, for
+// commands whose result already explains the failure
+var errSilentFailure = errors.New("command failed")
+
+// silentError is errSilentFailure carrying the failure it stands for, so
+// telemetry can categorize it
+type silentError struct {
+	cause error
+}
+
+func (e *silentError) Error() string        { return e.cause.Error() }
+func (e *silentError) Unwrap() error        { return e.cause }
+func (e *silentError) Is(target error) bool { return target == errSilentFailure }
+
+// silentFailure returns an error that matches errSilentFailure and is
+// categorized like cause
+func silentFailure(cause error) error {
+	return &silentError{cause: cause}
+}
+
+// applyGlobalConfig applies settings from the global config. Problems with it
+// are reported by 'koh doctor'; until they're fixed the defaults apply.
+func applyGlobalConfig() {
+	g, err := config.LoadGlobal()
+	if err != nil {
+		return
+	}
+	if policy, err := g.ValidationPolicy(); err == nil {
+		validation.Active = policy
+	}
+	showHints = g.ShowHints()
+	sanitizeNames = g.SanitizeNames
+}
+
 // Execute runs the root command and handles any errors.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	args, err := commandLineArgs(os.Args[1:])
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	rootCmd.SetArgs(args)
+
+	executed, err := rootCmd.ExecuteC()
+	command := telemetryCommandName(executed)
+	telemetry.Record(command, err)
+	if err != nil && !errors.Is(err, errSilentFailure) {
+		if jsonOutput {
+			output.New(os.Stdout, output.JSON).Error(err)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	// The dashboard shows its own hint, and help needs none
+	if executed != nil && executed != rootCmd && !executed.Flags().Changed("help") {
+		printNextHint(os.Stderr, hintContext{command: command, err: err})
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// telemetryCommandName returns the name telemetry counts an invocation under,
+// e.g. "config validate". The dashboard (bare 'koh') is counted as "koh".
+func telemetryCommandName(c *cobra.Command) string {
+	if c == nil || c == rootCmd {
+		return rootCmd.Name()
+	}
+	return strings.TrimPrefix(c.CommandPath(), rootCmd.Name()+" ")
 }
 
 func init() {
 	// Customize help template to use our custom usage function
 	rootCmd.SetHelpTemplate(getCustomHelpTemplate())
 	rootCmd.SetUsageFunc(customUsageFunc)
+
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Bypass the cached git worktree data")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output results as JSON")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask before destructive operations")
 }
 
 // getCustomHelpTemplate returns a custom help template with enhanced styling
@@ -643,7 +714,7 @@ func customUsageFunc(cmd *cobra.Command) error {
 			}
 
 			switch c.Name() {
-			case "new", "switch", "list", "cleanup":
+			case "new", "switch", "list", "cleanup", "status", "info", "current", "prompt", "upgrade-window", "exec", "pause", "resume", "refresh", "snapshot", "advise", "legend", "which-window", "wip", "unwip", "stack", "base", "du":
 				worktreeCommands = append(worktreeCommands, c.Name()+"§"+c.Short)
 			case "init", "config":
 				configCommands = append(configCommands, c.Name()+"§"+c.Short)
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// scratchDirName is the directory in the worktree directory holding per-worktree scratch
+// directories. Name validation keeps worktrees from taking it.
+const scratchDirName = validation.ScratchName
+
+// scratchDir returns the scratch directory of a worktree
+func scratchDir(mainRepoRoot, worktreeName string) string {
+	return filepath.Join(config.WorktreeDir(mainRepoRoot), scratchDirName, worktreeName)
+}
+
+// ensureScratch creates a worktree's scratch directory and returns the
+// environment exposing it to the window's panes as KOH_SCRATCH
+func ensureScratch(mainRepoRoot, worktreeName string) ([]string, error) {
+	dir := scratchDir(mainRepoRoot, worktreeName)
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	return []string{"KOH_SCRATCH=" + dir}, nil
+}
+
+// removeScratch deletes a worktree's scratch directory and everything in it
+func removeScratch(mainRepoRoot, worktreeName string) error {
+	if err := os.RemoveAll(scratchDir(mainRepoRoot, worktreeName)); err != nil {
+		return fmt.Errorf("failed to remove scratch directory: %w", err)
+	}
+	return nil
+}
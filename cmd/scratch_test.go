@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScratchLifecycle(t *testing.T) {
+	root := t.TempDir()
+
+	env, err := ensureScratch(root, "feature")
+	if err != nil {
+		t.Fatalf("ensureScratch() failed: %v", err)
+	}
+
+	dir := filepath.Join(root, ".koh", "scratch", "feature")
+	if len(env) != 1 || env[0] != "KOH_SCRATCH="+dir {
+		t.Errorf("Expected KOH_SCRATCH=%s, got %v", dir, env)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("Expected scratch directory %s to exist: %v", dir, err)
+	}
+
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(filepath.Join(dir, "artifact"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write artifact: %v", err)
+	}
+	if err := removeScratch(root, "feature"); err != nil {
+		t.Fatalf("removeScratch() failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected scratch directory to be removed, got %v", err)
+	}
+
+	// Removing a scratch directory that doesn't exist is fine
+	if err := removeScratch(root, "never-created"); err != nil {
+		t.Errorf("Expected no error removing a missing scratch directory, got %v", err)
+	}
+}
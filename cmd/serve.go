@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve worktree commands to editors and status bars over a unix socket",
+	Long: `Serve a small JSON API over a unix socket, so editor plugins and status
+bars can list, create, switch to and clean up worktrees without starting a
+koh process for each request:
+
+  GET    /v1/worktrees                 list the koh worktrees
+  POST   /v1/worktrees                 create one: {"name": "feat", "base": "main"}
+  POST   /v1/worktrees/<name>/switch   switch to its window
+  DELETE /v1/worktrees/<name>          clean it up (?force=1 to drop changes)
+
+Responses are the same JSON documents 'koh list', 'koh new', 'koh switch'
+and 'koh cleanup' print with --json; failures are {"error": "..."}.
+
+The socket lives in koh's cache directory, one per repository; print its
+path with --socket-path. Progress messages and warnings of the commands
+requests run, e.g. the output of setup scripts, go to the server's stderr.
+
+Example:
+  curl --unix-socket "$(koh serve --socket-path)" http://koh/v1/worktrees`,
+	Args:         cobra.NoArgs,
+	RunE:         runServe,
+	SilenceUsage: true,
+}
+
+var (
+	// serveSocket overrides the socket to listen on
+	serveSocket string
+	// servePrintSocket prints the socket path instead of serving
+	servePrintSocket bool
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveSocket, "socket", "", "Unix socket to listen on (default: one per repository in the cache directory)")
+	serveCmd.Flags().BoolVar(&servePrintSocket, "socket-path", false, "Print the socket path and exit")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// serveCreateRequest is the body of a create request. The fields mirror the
+// 'koh new' flags of the same names.
+type serveCreateRequest struct {
+	Name       string `json:"name"`
+	Branch     string `json:"branch,omitempty"`
+	Base       string `json:"base,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+	NoTmux     bool   `json:"no_tmux,omitempty"`
+	Background bool   `json:"background,omitempty"`
+	Fetch      bool   `json:"fetch,omitempty"`
+}
+
+// kohServer answers API requests for the repository at mainRepoRoot
+type kohServer struct {
+	mainRepoRoot string
+	// mu serializes requests that change worktrees or windows
+	mu sync.Mutex
+}
+
+// routes returns the API's request handler
+func (s *kohServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/worktrees", s.handleList)
+	mux.HandleFunc("POST /v1/worktrees", s.handleCreate)
+	mux.HandleFunc("POST /v1/worktrees/{name}/switch", s.handleSwitch)
+	mux.HandleFunc("DELETE /v1/worktrees/{name}", s.handleCleanup)
+	return mux
+}
+
+// printer returns the printer commands run with for a request: results are
+// returned in the response rather than printed, and progress and warnings go
+// to stderr, as they do for any JSON printer. JSON mode also keeps commands
+// from prompting on the server's terminal.
+func (s *kohServer) printer() *output.Printer {
+	return output.New(io.Discard, output.JSON)
+}
+
+func (s *kohServer) handleList(w http.ResponseWriter, _ *http.Request) {
+	worktrees, err := loadListItems(s.mainRepoRoot, "", false)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeServeJSON(w, http.StatusOK, listEntries(worktrees))
+}
+
+func (s *kohServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req serveCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	if err := validation.ValidateWorktreeName(req.Name); err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("invalid worktree name: %w", err))
+		return
+	}
+	if req.NoTmux && req.Background {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("no_tmux and background can't be combined"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockRepo(s.printer(), "koh serve: create "+req.Name)
+	if err != nil {
+		writeServeError(w, http.StatusConflict, err)
+		return
+	}
+	defer unlock()
+
+	result, err := createWorktree(s.printer(), req.Name, newOptions{
+		branch:     req.Branch,
+		base:       req.Base,
+		profile:    req.Profile,
+		noTmux:     req.NoTmux,
+		background: req.Background,
+		fetch:      req.Fetch,
+	})
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeServeJSON(w, http.StatusCreated, result)
+}
+
+func (s *kohServer) handleSwitch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	background, _ := strconv.ParseBool(r.URL.Query().Get("background"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result *switchResult
+	var err error
+	if name == mainCheckoutName {
+		result, err = switchToMainCheckout(s.printer(), s.mainRepoRoot, true, background)
+	} else {
+		if status, err := s.checkWorktree(name); err != nil {
+			writeServeError(w, status, err)
+			return
+		}
+		result, err = switchToWorktree(s.printer(), name, true, background)
+	}
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeServeJSON(w, http.StatusOK, result)
+}
+
+func (s *kohServer) handleCleanup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == mainCheckoutName {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("the main checkout can't be cleaned up"))
+		return
+	}
+	if status, err := s.checkWorktree(name); err != nil {
+		writeServeError(w, status, err)
+		return
+	}
+
+	query := r.URL.Query()
+	force, _ := strconv.ParseBool(query.Get("force"))
+	cleanupCfg := loadCleanupConfig()
+	deleteRemote := cleanupCfg != nil && cleanupCfg.DeleteRemoteBranch
+	if value := query.Get("delete_remote_branch"); value != "" {
+		deleteRemote, _ = strconv.ParseBool(value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockRepo(s.printer(), "koh serve: cleanup "+name)
+	if err != nil {
+		writeServeError(w, http.StatusConflict, err)
+		return
+	}
+	defer unlock()
+
+	opts := cleanupOptions{cfg: cleanupCfg, deleteRemote: deleteRemote, force: force, forceCommands: force}
+	result, err := cleanupWorktree(r.Context(), s.printer(), s.mainRepoRoot, name, opts)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeServeJSON(w, http.StatusOK, result)
+}
+
+// checkWorktree checks that name is a koh worktree, returning the HTTP
+// status to answer with when it isn't
+func (s *kohServer) checkWorktree(name string) (int, error) {
+	if err := validation.ValidateWorktreeName(name); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid worktree name: %w", err)
+	}
+	if _, err := os.Stat(kohWorktreePath(s.mainRepoRoot, name)); err != nil {
+		return http.StatusNotFound, fmt.Errorf("worktree %s does not exist", worktreeLabel(s.mainRepoRoot, name))
+	}
+	return 0, nil
+}
+
+// writeServeJSON writes v as the JSON response body
+func writeServeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = output.WriteJSON(w, v)
+}
+
+// writeServeError writes err as a JSON error response
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
+
+// listenUnix listens on the unix socket at path, replacing a socket left
+// behind by a server that is gone. It fails when another server answers, and
+// never removes anything at path that isn't a socket.
+func listenUnix(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to check %s: %w", path, err)
+	case info.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	default:
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("koh is already serving on %s", path)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Only the user may talk to the server
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to restrict access to %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+func runServe(cmd *cobra.Command, _ []string) error {
+	p := newPrinter(cmd)
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	mainRepoRoot := filepath.Dir(info.CommonDir)
+
+	socket := serveSocket
+	if socket == "" {
+		if socket, err = cache.ServerSocket(info.CommonDir); err != nil {
+			return err
+		}
+	}
+	if servePrintSocket {
+		fprintln(cmd.OutOrStdout(), socket)
+		return nil
+	}
+
+	// Commands run from the main checkout, which outlives any worktree the
+	// server was started in
+	if err := os.Chdir(mainRepoRoot); err != nil {
+		return fmt.Errorf("failed to change to %s: %w", mainRepoRoot, err)
+	}
+
+	listener, err := listenUnix(socket)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           (&kohServer{mainRepoRoot: mainRepoRoot}).routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	p.Info("Serving %s on %s; press Ctrl+C to stop", filepath.Base(mainRepoRoot), socket)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeRoutes(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	handler := (&kohServer{mainRepoRoot: repo}).routes()
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/v1/worktrees", "")
+	var entries []listEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected a worktree list, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(entries) != 1 || entries[0].Name != "feat-a" {
+		t.Errorf("Expected feat-a, got %+v", entries)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{name: "malformed body", method: http.MethodPost, target: "/v1/worktrees", body: "{", want: http.StatusBadRequest},
+		{name: "invalid name", method: http.MethodPost, target: "/v1/worktrees", body: `{"name": "../x"}`, want: http.StatusBadRequest},
+		{name: "switch to missing worktree", method: http.MethodPost, target: "/v1/worktrees/nope/switch", want: http.StatusNotFound},
+		{name: "clean up missing worktree", method: http.MethodDelete, target: "/v1/worktrees/nope", want: http.StatusNotFound},
+		{name: "clean up main checkout", method: http.MethodDelete, target: "/v1/worktrees/main", want: http.StatusBadRequest},
+		{name: "unknown route", method: http.MethodGet, target: "/v1/windows", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := request(tt.method, tt.target, tt.body); rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	rec = request(http.MethodPost, "/v1/worktrees", `{"name": "feat-b", "no_tmux": true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected feat-b to be created, got %d: %s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".koh", "feat-b")); err != nil {
+		t.Errorf("Expected the worktree to exist: %v", err)
+	}
+
+	rec = request(http.MethodDelete, "/v1/worktrees/feat-b?force=1&delete_remote_branch=0", "")
+	var result cleanupResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected feat-b to be cleaned up, got %d: %s", rec.Code, rec.Body)
+	}
+	if !result.WorktreeRemoved {
+		t.Errorf("Expected the worktree to be removed, got %+v", result)
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	// t.TempDir() paths can exceed the length limit of socket paths on macOS
+	dir, err := os.MkdirTemp("", "koh")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "serve.sock")
+
+	// Anything but a socket is left alone
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := listenUnix(socket); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("Expected an error for a file that isn't a socket, got %v", err)
+	}
+	if err := os.Remove(socket); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	// A socket nobody listens on is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+	listener, err := listenUnix(socket)
+	if err != nil {
+		t.Fatalf("listenUnix() failed: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	if _, err := listenUnix(socket); err == nil || !strings.Contains(err.Error(), "already serving") {
+		t.Errorf("Expected an error while another server listens, got %v", err)
+	}
+}
+
+func TestServePrinterSendsProgressToStderr(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stderr
+	os.Stderr = w
+	t.Cleanup(func() { os.Stderr = original })
+
+	p := (&kohServer{}).printer()
+	p.Info("Creating feat")
+	p.Warn("Setup script failed")
+	_ = w.Close()
+	os.Stderr = original
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Creating feat", "Setup script failed"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q on stderr, got %q", want, data)
+		}
+	}
+}
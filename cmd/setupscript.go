@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/output"
+)
+
+// setupScriptCopies returns the paths of a relative setup script in the main
+// repository and in a worktree, and whether both exist with different
+// contents. A script missing from the worktree is copied from the main
+// repository when the window is created, so it doesn't count as different.
+func setupScriptCopies(mainRepoRoot, worktreePath, setupScript string) (mainPath, worktreeScript string, differs bool) {
+	if setupScript == "" || filepath.IsAbs(setupScript) {
+		return "", "", false
+	}
+	mainPath = filepath.Join(mainRepoRoot, setupScript)
+	worktreeScript = filepath.Join(worktreePath, setupScript)
+
+	//nolint:gosec // G304: the setup script path is validated against the repository
+	mainData, err := os.ReadFile(mainPath)
+	if err != nil {
+		return mainPath, worktreeScript, false
+	}
+	//nolint:gosec // G304: the setup script path is inside the worktree
+	worktreeData, err := os.ReadFile(worktreeScript)
+	if err != nil {
+		return mainPath, worktreeScript, false
+	}
+	return mainPath, worktreeScript, !bytes.Equal(mainData, worktreeData)
+}
+
+// diffSetupScript returns a unified diff from the main repository's copy of
+// the setup script to the worktree's
+func diffSetupScript(ctx context.Context, mainPath, worktreeScript string) string {
+	//nolint:gosec // G204: both paths are setup script copies koh resolved
+	cmd := exec.CommandContext(ctx, "git", "diff", "--no-index", "--no-color", "--", mainPath, worktreeScript)
+	// git diff --no-index exits 1 when the files differ
+	output, _ := cmd.Output()
+	return strings.TrimRight(string(output), "\n")
+}
+
+// Answers to the setup script prompt
+const (
+	setupScriptKeep = "k"
+	setupScriptMain = "m"
+	setupScriptSkip = "s"
+)
+
+// parseSetupScriptAnswer maps an answer to the setup script prompt to a
+// choice, keeping the worktree's copy by default
+func parseSetupScriptAnswer(answer string) (string, bool) {
+	switch strings.ToLower(answer) {
+	case "", "k", "keep":
+		return setupScriptKeep, true
+	case "m", "main":
+		return setupScriptMain, true
+	case "s", "skip":
+		return setupScriptSkip, true
+	}
+	return "", false
+}
+
+// confirmSetupScript checks the setup script a new window is about to run.
+// When the worktree's copy differs from the main repository's, the diff is
+// shown and the user picks which copy to run, or skips setup; the returned
+// configuration reflects the choice. Without a terminal to ask on, or unless
+// interactive is set, the worktree's copy runs as before, with a warning.
+func confirmSetupScript(ctx context.Context, p *output.Printer, mainRepoRoot, worktreePath string, cfg *config.Config, interactive bool) (*config.Config, error) {
+	mainPath, worktreeScript, differs := setupScriptCopies(mainRepoRoot, worktreePath, cfg.SetupScript)
+	if !differs {
+		return cfg, nil
+	}
+
+	if !interactive || p.IsJSON() || !stdinIsTerminal() {
+		p.Warn("%s in the worktree differs from the main repository's copy; running the worktree's copy", cfg.SetupScript)
+		return cfg, nil
+	}
+
+	p.Info("%s in the worktree differs from the main repository's copy:\n\n%s\n", cfg.SetupScript, diffSetupScript(ctx, mainPath, worktreeScript))
+	for {
+		answer, err := p.Prompt(stdin, "[k]eep the worktree's copy, replace it with the [m]ain repository's copy, or [s]kip setup? [K/m/s] ")
+		if err != nil {
+			return nil, fmt.Errorf("no answer to the setup script prompt: %w", err)
+		}
+		choice, ok := parseSetupScriptAnswer(answer)
+		if !ok {
+			continue
+		}
+
+		switch choice {
+		case setupScriptMain:
+			if err := replaceFile(mainPath, worktreeScript); err != nil {
+				return nil, fmt.Errorf("failed to replace setup script: %w", err)
+			}
+		case setupScriptSkip:
+			skipped := *cfg
+			skipped.SetupScript = ""
+			return &skipped, nil
+		}
+		return cfg, nil
+	}
+}
+
+// replaceFile overwrites dst with the contents and permissions of src
+func replaceFile(src, dst string) error {
+	//nolint:gosec // G304: src is the setup script in the main repository
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// runSetupScript runs the setup script in a new worktree and waits for it,
+// for 'koh new --no-tmux'. Its output goes to stderr so that --json and
+// --events-json output on stdout stays parseable.
+func runSetupScript(ctx context.Context, p *output.Printer, worktreePath, setupScript string, env []string) error {
+	if setupScript == "" {
+		return nil
+	}
+	if err := config.EnsureSetupScript(worktreePath, setupScript); err != nil {
+		return fmt.Errorf("failed to ensure setup script: %w", err)
+	}
+
+	p.Info("Running %s", setupScript)
+	c := execCommand(ctx, []string{setupScript})
+	c.Dir = worktreePath
+	c.Env = append(os.Environ(), env...)
+	c.Stdin = os.Stdin
+	c.Stdout = p.CommandOutput()
+	c.Stderr = p.CommandOutput()
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("setup script %s failed: %w", setupScript, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bshakr/koh/internal/output"
+)
+
+// writeScript writes a script to dir/setup.sh
+func writeScript(t *testing.T, dir, content string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, "setup.sh")
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestSetupScriptCopies(t *testing.T) {
+	mainRoot, worktree := t.TempDir(), t.TempDir()
+	writeScript(t, mainRoot, "npm ci\n", 0755)
+
+	if _, _, differs := setupScriptCopies(mainRoot, worktree, "setup.sh"); differs {
+		t.Error("Expected a script missing from the worktree not to count as different")
+	}
+
+	writeScript(t, worktree, "npm ci\n", 0755)
+	if _, _, differs := setupScriptCopies(mainRoot, worktree, "setup.sh"); differs {
+		t.Error("Expected identical copies not to differ")
+	}
+
+	writeScript(t, worktree, "npm install\n", 0755)
+	if _, _, differs := setupScriptCopies(mainRoot, worktree, "setup.sh"); !differs {
+		t.Error("Expected different copies to differ")
+	}
+	if _, _, differs := setupScriptCopies(mainRoot, worktree, ""); differs {
+		t.Error("Expected no setup script not to differ")
+	}
+}
+
+func TestParseSetupScriptAnswer(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{"", setupScriptKeep, true},
+		{"K", setupScriptKeep, true},
+		{"main", setupScriptMain, true},
+		{"s", setupScriptSkip, true},
+		{"yes", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseSetupScriptAnswer(tt.answer)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Expected parseSetupScriptAnswer(%q) = %q, %v, got %q, %v", tt.answer, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	src := writeScript(t, t.TempDir(), "new\n", 0755)
+	dst := writeScript(t, t.TempDir(), "old\n", 0644)
+
+	if err := replaceFile(src, dst); err != nil {
+		t.Fatalf("replaceFile() failed: %v", err)
+	}
+
+	//nolint:gosec // G304: Test file path
+	data, err := os.ReadFile(dst)
+	if err != nil || string(data) != "new\n" {
+		t.Errorf("Expected replaced content, got %q (%v)", data, err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected mode 0755, got %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestRunSetupScript(t *testing.T) {
+	p := output.New(io.Discard, output.Human)
+	ctx := context.Background()
+	worktree := t.TempDir()
+
+	writeScript(t, worktree, "#!/bin/sh\necho \"$KOH_SCRATCH\" > ran\n", 0o755)
+	if err := runSetupScript(ctx, p, worktree, "./setup.sh", []string{"KOH_SCRATCH=/scratch"}); err != nil {
+		t.Fatalf("runSetupScript() failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(worktree, "ran"))
+	if err != nil {
+		t.Fatalf("Expected the setup script to run in the worktree: %v", err)
+	}
+	if string(data) != "/scratch\n" {
+		t.Errorf("Expected the script to get the window environment, got %q", data)
+	}
+
+	writeScript(t, worktree, "#!/bin/sh\nexit 3\n", 0o755)
+	if err := runSetupScript(ctx, p, worktree, "./setup.sh", nil); err == nil {
+		t.Error("Expected a failing setup script to return an error")
+	}
+
+	if err := runSetupScript(ctx, p, worktree, "", nil); err != nil {
+		t.Errorf("Expected no setup script to be a no-op, got %v", err)
+	}
+}
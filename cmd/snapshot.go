@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/shell"
+	"github.com/bshakr/koh/internal/snapshot"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot <worktree-name>",
+	Short: "Save a worktree window's panes to restore them later",
+	Long: `Save what each pane of a worktree's window shows, including its
+scrollback, along with the command koh last sent to it.
+
+'koh snapshot restore' rebuilds the window from the snapshot after it was
+closed, e.g. after a reboot: each pane gets its saved text back and its
+command is run again. The setup script is never re-run.
+
+Snapshots are kept in the koh data directory (~/.local/share/koh by
+default); taking a new one replaces the worktree's previous snapshot.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runSnapshot,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <worktree-name>",
+	Short: "Rebuild a worktree window from its snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotRestore,
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+// snapshotResult is the machine-readable result of 'koh snapshot'
+type snapshotResult struct {
+	Name  string          `json:"name"`
+	Path  string          `json:"path"`
+	Panes []snapshot.Pane `json:"panes"`
+}
+
+// restoreResult is the machine-readable result of 'koh snapshot restore'
+type restoreResult struct {
+	Name     string        `json:"name"`
+	TakenAt  time.Time     `json:"taken_at"`
+	Panes    int           `json:"panes"`
+	Commands []paneUpgrade `json:"commands"`
+}
+
+// snapshotPanes describes the panes of a window for a snapshot. last maps
+// panes to the command koh last sent to them.
+func snapshotPanes(panes []tmux.PaneInfo, last map[int]string) []snapshot.Pane {
+	saved := make([]snapshot.Pane, 0, len(panes))
+	for _, pane := range panes {
+		saved = append(saved, snapshot.Pane{Pane: pane.Pane, Process: pane.CurrentCommand, Command: last[pane.Pane]})
+	}
+	return saved
+}
+
+// restorePlan returns the configuration that recreates a snapshot's pane
+// layout without running anything, and the commands to replay afterwards.
+// The setup pane's command is not replayed.
+func restorePlan(s *snapshot.Snapshot) (*config.Config, []tmux.PaneCommand) {
+	panes := 1
+	for _, pane := range s.Panes {
+		panes = max(panes, pane.Pane+1)
+	}
+
+	cfg := &config.Config{PaneCommands: make([]config.PaneCommand, panes-1)}
+	var replay []tmux.PaneCommand
+	for _, pane := range s.Panes {
+		if pane.Pane > 0 && pane.Command != "" {
+			replay = append(replay, tmux.PaneCommand{Pane: pane.Pane, Command: pane.Command})
+		}
+	}
+	return cfg, replay
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) error {
+	worktreeName := args[0]
+
+	commonDir, err := worktreeWindowCommonDir(worktreeName)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	panes, err := tmux.ListWindowPanesWithContext(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+
+	s := &snapshot.Snapshot{
+		Worktree:  worktreeName,
+		CreatedAt: time.Now(),
+		Panes:     snapshotPanes(panes, lastPaneCommands(worktreeName)),
+	}
+	for i := range s.Panes {
+		if s.Panes[i].Contents, err = tmux.CapturePaneWithContext(ctx, worktreeName, s.Panes[i].Pane); err != nil {
+			return err
+		}
+	}
+	if err := snapshot.Save(commonDir, s); err != nil {
+		return err
+	}
+
+	dir, err := snapshot.Dir(commonDir, worktreeName)
+	if err != nil {
+		return err
+	}
+	result := snapshotResult{Name: worktreeName, Path: dir, Panes: s.Panes}
+	return newPrinter(cmd).Result(result, func(w io.Writer) {
+		for _, pane := range result.Panes {
+			line := fmt.Sprintf("  pane %d  %s", pane.Pane, styles.Muted.Render(pane.Process))
+			if pane.Command != "" {
+				line += "  " + pane.Command
+			}
+			fprintln(w, line)
+		}
+		fprintln(w, styles.RenderSuccess(fmt.Sprintf("Saved snapshot of %s", worktreeName)))
+		fprintln(w, styles.Muted.Render("Run 'koh snapshot restore "+worktreeName+"' to rebuild the window"))
+	})
+}
+
+func runSnapshotRestore(cmd *cobra.Command, args []string) error {
+	worktreeName := args[0]
+
+	commonDir, err := worktreeWindowCommonDir(worktreeName)
+	if err != nil {
+		return err
+	}
+
+	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
+	if err != nil {
+		return fmt.Errorf("failed to get repository root: %w", err)
+	}
+	worktreePath := kohWorktreePath(mainRepoRoot, worktreeName)
+	if _, err := os.Stat(worktreePath); err != nil {
+		return fmt.Errorf("worktree %s does not exist", worktreeLabel(mainRepoRoot, worktreeName))
+	}
+
+	ctx := context.Background()
+	exists, err := tmux.WindowExistsWithContext(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the window of %s is open\nClose it before restoring its snapshot", worktreeName)
+	}
+
+	s, err := snapshot.Load(commonDir, worktreeName)
+	if err != nil {
+		return err
+	}
+
+	repoName, err := git.GetRepoName()
+	if err != nil {
+		return fmt.Errorf("failed to get repository name: %w", err)
+	}
+	p := newPrinter(cmd)
+	env, err := ensureScratch(mainRepoRoot, worktreeName)
+	if err != nil {
+		p.Warn("%v", err)
+	}
+	if loaded, err := config.Load(); err == nil {
+		env = append(env, portsEnv(p, loaded, worktreePath)...)
+		if profiled, err := worktreeProfileConfig(loaded, worktreeName); err == nil {
+			env = append(env, configEnv(profiled, worktreeName, worktreePath, env)...)
+		}
+	}
+
+	cfg, replay := restorePlan(s)
+	if err := tmux.CreateSessionWithContext(ctx, repoName, worktreeName, worktreePath, cfg, env...); err != nil {
+		return fmt.Errorf("failed to create tmux session: %w", err)
+	}
+	recordPaneIDs(ctx, worktreeName)
+
+	// Print the saved text first, so replayed commands start below it
+	for _, pane := range s.Panes {
+		if strings.TrimSpace(pane.Contents) == "" {
+			continue
+		}
+		path, err := snapshot.ContentsPath(commonDir, worktreeName, pane.Pane)
+		if err != nil {
+			return err
+		}
+		if err := tmux.SendToPaneWithContext(ctx, worktreeName, pane.Pane, "cat "+shell.Quote(path)); err != nil {
+			return err
+		}
+	}
+	for _, pc := range replay {
+		if err := tmux.SendToPaneWithContext(ctx, worktreeName, pc.Pane, pc.Command); err != nil {
+			return err
+		}
+	}
+	recordSentCommands(worktreeName, replay)
+
+	result := restoreResult{Name: worktreeName, TakenAt: s.CreatedAt, Panes: len(cfg.PaneCommands) + 1, Commands: []paneUpgrade{}}
+	for _, pc := range replay {
+		result.Commands = append(result.Commands, paneUpgrade{Pane: pc.Pane, Command: pc.Command, Action: paneSent})
+	}
+	return newPrinter(cmd).Result(result, func(w io.Writer) {
+		for _, step := range result.Commands {
+			fprintln(w, renderPaneUpgrade(step, false))
+		}
+		fprintln(w, styles.RenderSuccess(fmt.Sprintf("Restored %s from the snapshot taken %s", worktreeName, result.TakenAt.Format("2006-01-02 15:04"))))
+	})
+}
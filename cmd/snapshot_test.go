@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bshakr/koh/internal/snapshot"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestRestorePlan(t *testing.T) {
+	s := &snapshot.Snapshot{Panes: []snapshot.Pane{
+		{Pane: 0, Command: "./bin/setup"},
+		{Pane: 1, Command: "npm run dev"},
+		{Pane: 2},
+		{Pane: 3, Command: "vim"},
+	}}
+
+	cfg, replay := restorePlan(s)
+
+	if cfg.SetupScript != "" || len(cfg.PaneCommands) != 3 {
+		t.Errorf("Expected a layout of 4 panes without setup, got %+v", cfg)
+	}
+	if len(tmux.PaneCommands(cfg)) != 0 {
+		t.Errorf("Expected the layout to send no commands, got %+v", tmux.PaneCommands(cfg))
+	}
+	want := []tmux.PaneCommand{{Pane: 1, Command: "npm run dev"}, {Pane: 3, Command: "vim"}}
+	if len(replay) != len(want) {
+		t.Fatalf("Expected %d commands to replay, got %+v", len(want), replay)
+	}
+	for i := range want {
+		if replay[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], replay[i])
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var stackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "Stack worktrees on each other for stacked pull requests",
+	Long: `Record that a worktree's branch builds on another worktree's branch, for
+stacked pull requests where each branch is reviewed on top of the one below.
+
+Stack a new worktree with 'koh new <name> --stack-on <parent>', or an
+existing one with 'koh stack on <parent>'. 'koh stack status' shows the
+chain and which branches fell behind the one they're stacked on, and
+'koh stack restack' rebases them onto it, bottom up.
+
+When a worktree in the middle of a stack is cleaned up, the worktrees
+stacked on it move down onto its parent.`,
+}
+
+var stackOnCmd = &cobra.Command{
+	Use:   "on <parent> [worktree-name]",
+	Short: "Stack a worktree on another worktree's branch",
+	Long: `Record that a worktree, the current one by default, is stacked on the
+worktree named parent. The branch isn't changed; run 'koh stack restack'
+to rebase it onto the parent when it isn't based on it yet.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runStackOn,
+}
+
+var stackOffCmd = &cobra.Command{
+	Use:   "off [worktree-name]",
+	Short: "Take a worktree off the stack it's in",
+	Long: `Forget the parent of a worktree, the current one by default. Worktrees
+stacked on it stay stacked on it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStackOff,
+}
+
+var stackStatusCmd = &cobra.Command{
+	Use:   "status [worktree-name]",
+	Short: "Show the stack a worktree is in",
+	Long: `Show the stack the given or current worktree is in, from the bottom up,
+with the commits each branch adds on top of its parent. Branches whose
+parent has moved on are marked as needing a restack. Outside a stacked
+worktree, every stack is shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStackStatus,
+}
+
+var stackRestackCmd = &cobra.Command{
+	Use:   "restack [worktree-name]",
+	Short: "Rebase the worktrees of a stack onto their parents",
+	Long: `Rebase each worktree in the stack the given or current worktree is in
+onto the branch it's stacked on, bottom up, so every branch includes the
+latest commits of the ones below. Only the worktree's own commits are
+replayed, even when its parent was amended or rebased.
+
+Worktrees with uncommitted changes stop the restack. When a rebase stops
+on conflicts, resolve them in that worktree, run 'git rebase --continue',
+and run 'koh stack restack' again to carry on.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStackRestack,
+}
+
+func init() {
+	stackCmd.AddCommand(stackOnCmd, stackOffCmd, stackStatusCmd, stackRestackCmd)
+	rootCmd.AddCommand(stackCmd)
+}
+
+// stackEntry is a worktree in the output of 'koh stack status'
+type stackEntry struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	// Parent is the worktree this one is stacked on, "" at the bottom
+	Parent string `json:"parent,omitempty"`
+	// Depth is the position in the stack, 0 at the bottom
+	Depth   int  `json:"depth"`
+	Current bool `json:"current"`
+	// Ahead is the number of commits the branch adds on top of its parent
+	Ahead int `json:"ahead"`
+	// Behind is the number of commits of the parent the branch is missing
+	Behind       int  `json:"behind"`
+	NeedsRestack bool `json:"needs_restack"`
+}
+
+// stackOnResult is the machine-readable result of 'koh stack on' and 'koh stack off'
+type stackOnResult struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent"`
+}
+
+// stackRestackResult is the machine-readable result of 'koh stack restack'
+type stackRestackResult struct {
+	// Restacked are the worktrees that were rebased, bottom up
+	Restacked []string `json:"restacked"`
+	// UpToDate are the worktrees already based on their parent
+	UpToDate []string `json:"up_to_date"`
+}
+
+// stackMember is a worktree's place in a stack
+type stackMember struct {
+	name  string
+	depth int
+}
+
+// stackParents returns the parent of each stacked worktree
+func stackParents(recorded map[string]*state.Worktree) map[string]string {
+	parents := map[string]string{}
+	for name, wt := range recorded {
+		if wt != nil && wt.Parent != "" {
+			parents[name] = wt.Parent
+		}
+	}
+	return parents
+}
+
+// stackRoot returns the worktree at the bottom of the stack name is in
+func stackRoot(parents map[string]string, name string) string {
+	seen := map[string]bool{}
+	for parents[name] != "" && !seen[name] {
+		seen[name] = true
+		name = parents[name]
+	}
+	return name
+}
+
+// stackedOn reports whether name is stacked on ancestor, directly or not
+func stackedOn(parents map[string]string, name, ancestor string) bool {
+	seen := map[string]bool{}
+	for parent := parents[name]; parent != "" && !seen[parent]; parent = parents[parent] {
+		if parent == ancestor {
+			return true
+		}
+		seen[parent] = true
+	}
+	return false
+}
+
+// stackMembers returns the stack with root at the bottom, each worktree
+// followed by the ones stacked on it in name order
+func stackMembers(parents map[string]string, root string) []stackMember {
+	children := map[string][]string{}
+	for name, parent := range parents {
+		children[parent] = append(children[parent], name)
+	}
+
+	var members []stackMember
+	seen := map[string]bool{}
+	var walk func(name string, depth int)
+	walk = func(name string, depth int) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		members = append(members, stackMember{name: name, depth: depth})
+		sort.Strings(children[name])
+		for _, child := range children[name] {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	return members
+}
+
+// stackRoots returns the bottom worktree of every stack, in name order
+func stackRoots(parents map[string]string) []string {
+	seen := map[string]bool{}
+	var roots []string
+	for name := range parents {
+		if root := stackRoot(parents, name); !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// inStack reports whether name is part of a stack, at the bottom or above
+func inStack(parents map[string]string, name string) bool {
+	return parents[name] != "" || slices.Contains(stackRoots(parents), name)
+}
+
+// stackTarget returns the worktree named in args, or the current one
+func stackTarget(info *git.RepoInfo, args []string) (string, error) {
+	if len(args) > 0 {
+		if err := validation.ValidateWorktreeName(args[0]); err != nil {
+			return "", fmt.Errorf("invalid worktree name: %w", err)
+		}
+		return args[0], nil
+	}
+	if !info.InWorktree() {
+		return "", fmt.Errorf("not in a koh worktree\nRun this from a worktree, or name one")
+	}
+	return currentCheckoutName(info), nil
+}
+
+// stackWorktrees returns the koh worktrees of the repository by name
+func stackWorktrees(ctx context.Context) (map[string]git.Worktree, error) {
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]git.Worktree, len(worktrees))
+	for _, wt := range worktrees {
+		byName[filepath.Base(wt.Path)] = wt
+	}
+	return byName, nil
+}
+
+// stackedWorktree returns the worktree called name, which must have a
+// branch checked out to take part in a stack
+func stackedWorktree(worktrees map[string]git.Worktree, name string) (git.Worktree, error) {
+	wt, ok := worktrees[name]
+	if !ok {
+		return git.Worktree{}, fmt.Errorf("worktree %s does not exist", name)
+	}
+	if wt.Branch == "" {
+		return git.Worktree{}, fmt.Errorf("worktree %s has no branch checked out", name)
+	}
+	return wt, nil
+}
+
+// stackOnBase returns the branch a worktree stacked on parent starts from,
+// and the commit it points at. When parent's HEAD is detached, the branch
+// is "" and the commit is its HEAD.
+func stackOnBase(ctx context.Context, p *output.Printer, parent string) (branch, commit string, err error) {
+	if err := validation.ValidateWorktreeName(parent); err != nil {
+		return "", "", fmt.Errorf("invalid --stack-on worktree: %w", err)
+	}
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	parentWT, ok := worktrees[parent]
+	if !ok {
+		return "", "", fmt.Errorf("worktree %s does not exist\nRun 'koh list' to see the worktrees to start from", parent)
+	}
+	if dirty, _ := git.IsDirty(parentWT.Path); dirty {
+		p.Warn("%s has uncommitted changes, which the new branch won't include", parent)
+	}
+	rev := parentWT.Branch
+	if rev == "" {
+		rev = "HEAD"
+	}
+	commit, err = git.ResolveCommitWithContext(ctx, parentWT.Path, rev)
+	if err != nil {
+		return "", "", err
+	}
+	return parentWT.Branch, commit, nil
+}
+
+func runStackOn(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+	ctx := context.Background()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	parent := args[0]
+	name, err := stackTarget(info, args[1:])
+	if err != nil {
+		return err
+	}
+	if name == parent {
+		return fmt.Errorf("a worktree can't be stacked on itself")
+	}
+
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	wt, err := stackedWorktree(worktrees, name)
+	if err != nil {
+		return err
+	}
+	parentWT, err := stackedWorktree(worktrees, parent)
+	if err != nil {
+		return err
+	}
+	if stackedOn(stackParents(loadRecordedWorktrees()), parent, name) {
+		return fmt.Errorf("%s is stacked on %s, so %s can't be stacked on it", parent, name, name)
+	}
+
+	// The branch is based on the parent where their histories meet
+	base, err := git.MergeBaseWithContext(ctx, wt.Path, parentWT.Branch, wt.Branch)
+	if err != nil {
+		return err
+	}
+	if err := recordStackParent(info.CommonDir, name, parent, base); err != nil {
+		return err
+	}
+
+	return p.Result(stackOnResult{Name: name, Parent: parent}, func(w io.Writer) {
+		fprintln(w, styles.RenderSuccess(fmt.Sprintf("Stacked %s on %s", name, parent)))
+	})
+}
+
+func runStackOff(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+	ctx := context.Background()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	name, err := stackTarget(info, args)
+	if err != nil {
+		return err
+	}
+
+	parent := stackParents(loadRecordedWorktrees())[name]
+	if parent == "" {
+		return fmt.Errorf("%s is not stacked on another worktree", name)
+	}
+	if err := recordStackParent(info.CommonDir, name, "", ""); err != nil {
+		return err
+	}
+
+	return p.Result(stackOnResult{Name: name}, func(w io.Writer) {
+		fprintln(w, styles.RenderSuccess(fmt.Sprintf("Took %s off %s", name, parent)))
+	})
+}
+
+// recordStackParent records the worktree name is stacked on and the commit
+// of the parent's branch it is based on; an empty parent unstacks it
+func recordStackParent(commonDir, name, parent, parentHead string) error {
+	return state.Update(commonDir, func(s *state.State) error {
+		wt := s.Worktree(name)
+		wt.Parent = parent
+		wt.ParentHead = parentHead
+		return nil
+	})
+}
+
+// loadStackEntries describes the worktrees of a stack, bottom up
+func loadStackEntries(ctx context.Context, parents map[string]string, members []stackMember, worktrees map[string]git.Worktree, current string) []stackEntry {
+	entries := []stackEntry{}
+	for _, m := range members {
+		wt := worktrees[m.name]
+		entry := stackEntry{
+			Name:    m.name,
+			Branch:  displayBranch(wt),
+			Parent:  parents[m.name],
+			Depth:   m.depth,
+			Current: m.name == current,
+		}
+		if parentWT, ok := worktrees[entry.Parent]; ok && wt.Branch != "" && parentWT.Branch != "" {
+			if divergence, err := git.DivergenceWithContext(ctx, wt.Path, parentWT.Branch, wt.Branch); err == nil {
+				entry.Ahead = divergence.Ahead
+				entry.Behind = divergence.Behind
+				entry.NeedsRestack = divergence.Behind > 0
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func runStackStatus(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+	ctx := context.Background()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	current := currentCheckoutName(info)
+
+	parents := stackParents(loadRecordedWorktrees())
+	roots := stackRoots(parents)
+	switch {
+	case len(args) > 0:
+		name, err := stackTarget(info, args)
+		if err != nil {
+			return err
+		}
+		if !inStack(parents, name) {
+			return fmt.Errorf("%s is not in a stack", name)
+		}
+		roots = []string{stackRoot(parents, name)}
+	case inStack(parents, current):
+		roots = []string{stackRoot(parents, current)}
+	}
+
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	var members []stackMember
+	for _, root := range roots {
+		members = append(members, stackMembers(parents, root)...)
+	}
+	entries := loadStackEntries(ctx, parents, members, worktrees, current)
+
+	return p.Result(entries, func(w io.Writer) {
+		if len(parents) == 0 {
+			fprintln(w, styles.Muted.Render("No stacks yet; stack a worktree with 'koh new <name> --stack-on <parent>' or 'koh stack on <parent>'"))
+			return
+		}
+		for _, entry := range entries {
+			fprintln(w, renderStackEntry(entry))
+		}
+	})
+}
+
+// renderStackEntry renders a worktree as a line of the stack tree
+func renderStackEntry(entry stackEntry) string {
+	var line strings.Builder
+	if entry.Depth > 0 {
+		line.WriteString(strings.Repeat("   ", entry.Depth-1) + "└─ ")
+	}
+
+	if entry.Current {
+		line.WriteString(styles.MarkerCurrent.RenderWith(entry.Name))
+	} else {
+		line.WriteString(entry.Name)
+	}
+	line.WriteString(" " + styles.MarkerBranch.RenderWith(entry.Branch))
+	if entry.Parent != "" {
+		line.WriteString(" " + styles.Muted.Render(fmt.Sprintf("+%d", entry.Ahead)))
+	}
+	if entry.NeedsRestack {
+		line.WriteString(" " + styles.MarkerRestack.Render() + styles.Muted.Render(fmt.Sprintf(" %d behind %s", entry.Behind, entry.Parent)))
+	}
+	return line.String()
+}
+
+func runStackRestack(cmd *cobra.Command, args []string) error {
+	p := newPrinter(cmd)
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	name, err := stackTarget(info, args)
+	if err != nil {
+		return err
+	}
+
+	recorded := loadRecordedWorktrees()
+	parents := stackParents(recorded)
+	if !inStack(parents, name) {
+		return fmt.Errorf("%s is not in a stack", name)
+	}
+
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := stackRestackResult{Restacked: []string{}, UpToDate: []string{}}
+	for _, m := range stackMembers(parents, stackRoot(parents, name)) {
+		parent := parents[m.name]
+		if parent == "" {
+			continue
+		}
+		restacked, err := restackWorktree(ctx, p, info.CommonDir, worktrees, m.name, parent, recorded[m.name].ParentHead)
+		if err != nil {
+			return err
+		}
+		if restacked {
+			result.Restacked = append(result.Restacked, m.name)
+		} else {
+			result.UpToDate = append(result.UpToDate, m.name)
+		}
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		if len(result.Restacked) == 0 {
+			fprintln(w, styles.RenderSuccess("The stack is up to date"))
+			return
+		}
+		fprintln(w, styles.RenderSuccess("Restacked "+strings.Join(result.Restacked, ", ")))
+	})
+}
+
+// restackWorktree rebases the worktree called name onto the branch of the
+// worktree it is stacked on, replaying the commits after parentHead, the
+// parent commit it was last based on. It reports whether a rebase was
+// needed.
+func restackWorktree(ctx context.Context, p *output.Printer, commonDir string, worktrees map[string]git.Worktree, name, parent, parentHead string) (bool, error) {
+	wt, err := stackedWorktree(worktrees, name)
+	if err != nil {
+		return false, err
+	}
+	parentWT, err := stackedWorktree(worktrees, parent)
+	if err != nil {
+		return false, fmt.Errorf("%s is stacked on %s: %w", name, parent, err)
+	}
+
+	if git.RebaseInProgressWithContext(ctx, wt.Path) {
+		return false, fmt.Errorf("a rebase is in progress in %s\nFinish it with 'git rebase --continue' or 'git rebase --abort', then run 'koh stack restack' again", wt.Path)
+	}
+
+	onto, err := git.ResolveCommitWithContext(ctx, wt.Path, parentWT.Branch)
+	if err != nil {
+		return false, err
+	}
+	if git.IsAncestorWithContext(ctx, onto, wt.Branch) {
+		return false, recordStackParent(commonDir, name, parent, onto)
+	}
+
+	// Without a usable record, replay what isn't already on the parent
+	if parentHead == "" || !git.IsAncestorWithContext(ctx, parentHead, wt.Branch) {
+		if parentHead, err = git.MergeBaseWithContext(ctx, wt.Path, parentWT.Branch, wt.Branch); err != nil {
+			return false, err
+		}
+	}
+
+	dirty, err := git.IsDirty(wt.Path)
+	if err != nil {
+		return false, err
+	}
+	if dirty {
+		return false, fmt.Errorf("%s has uncommitted changes\nCommit or stash them, then run 'koh stack restack' again", name)
+	}
+
+	p.Info("Rebasing %s onto %s", wt.Branch, parentWT.Branch)
+	if err := git.RebaseOntoWithContext(ctx, wt.Path, onto, parentHead); err != nil {
+		return false, fmt.Errorf("rebasing %s onto %s stopped: %w\nResolve the conflicts in %s and run 'git rebase --continue', then run 'koh stack restack' again", wt.Branch, parentWT.Branch, err, wt.Path)
+	}
+	return true, recordStackParent(commonDir, name, parent, onto)
+}
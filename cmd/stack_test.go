@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+)
+
+func TestStackMembers(t *testing.T) {
+	parents := map[string]string{"b": "a", "c": "b", "d": "a", "y": "x"}
+
+	tests := []struct {
+		root string
+		want []stackMember
+	}{
+		{root: "a", want: []stackMember{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 1}}},
+		{root: "x", want: []stackMember{{"x", 0}, {"y", 1}}},
+		{root: "z", want: []stackMember{{"z", 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			if got := stackMembers(parents, tt.root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if roots := stackRoots(parents); !reflect.DeepEqual(roots, []string{"a", "x"}) {
+		t.Errorf("Expected roots [a x], got %v", roots)
+	}
+	if root := stackRoot(parents, "c"); root != "a" {
+		t.Errorf("Expected c to be in a's stack, got %q", root)
+	}
+}
+
+func TestStackedOn(t *testing.T) {
+	// A cycle from a hand-edited state file must not hang
+	parents := map[string]string{"b": "a", "c": "b", "p": "q", "q": "p"}
+
+	tests := []struct {
+		name     string
+		ancestor string
+		want     bool
+	}{
+		{name: "c", ancestor: "b", want: true},
+		{name: "c", ancestor: "a", want: true},
+		{name: "a", ancestor: "c", want: false},
+		{name: "b", ancestor: "c", want: false},
+		{name: "p", ancestor: "x", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" on "+tt.ancestor, func(t *testing.T) {
+			if got := stackedOn(parents, tt.name, tt.ancestor); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRestackWorktree(t *testing.T) {
+	repo := newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	ctx := context.Background()
+	p := output.New(io.Discard, output.Human)
+
+	parentPath := filepath.Join(repo, ".koh", "feat-a")
+	childPath := filepath.Join(repo, ".koh", "feat-b")
+	commit := func(dir, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, strings.ReplaceAll(message, " ", "-")), []byte(message), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		runGit(t, "-C", dir, "add", ".")
+		runGit(t, "-C", dir, "commit", "-q", "-m", message)
+	}
+	subjects := func(dir string) string {
+		out, err := exec.Command("git", "-C", dir, "log", "--format=%s").Output()
+		if err != nil {
+			t.Fatalf("git log failed: %v", err)
+		}
+		return strings.Join(strings.Fields(strings.ReplaceAll(string(out), " ", "_")), ",")
+	}
+
+	commit(parentPath, "a one")
+	runGit(t, "worktree", "add", "-q", "-b", "feat-b", childPath, "feat-a")
+	commit(childPath, "b one")
+	_, base, err := stackOnBase(ctx, p, "feat-a")
+	if err != nil {
+		t.Fatalf("stackOnBase() failed: %v", err)
+	}
+
+	// The parent is reworded and moves on
+	runGit(t, "-C", parentPath, "commit", "-q", "--amend", "-m", "a one amended")
+	commit(parentPath, "a two")
+
+	worktrees, err := stackWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("stackWorktrees() failed: %v", err)
+	}
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+
+	restacked, err := restackWorktree(ctx, p, commonDir, worktrees, "feat-b", "feat-a", base)
+	if err != nil || !restacked {
+		t.Fatalf("Expected feat-b to be restacked, got %v (%v)", restacked, err)
+	}
+	if got, want := subjects(childPath), "b_one,a_two,a_one_amended,init"; got != want {
+		t.Errorf("Expected only feat-b's own commits replayed (%s), got %s", want, got)
+	}
+
+	restacked, err = restackWorktree(ctx, p, commonDir, worktrees, "feat-b", "feat-a", base)
+	if err != nil || restacked {
+		t.Errorf("Expected feat-b to be up to date, got %v (%v)", restacked, err)
+	}
+
+	commit(parentPath, "a three")
+	if err := os.WriteFile(filepath.Join(childPath, "notes.txt"), []byte("todo"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := restackWorktree(ctx, p, commonDir, worktrees, "feat-b", "feat-a", ""); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Errorf("Expected uncommitted changes to stop the restack, got %v", err)
+	}
+}
+
+func TestForgetWorktreeMovesStackDown(t *testing.T) {
+	newDashboardRepo(t)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+
+	for _, link := range [][2]string{{"b", "a"}, {"c", "b"}, {"d", "b"}} {
+		if err := recordStackParent(commonDir, link[0], link[1], "base-"+link[0]); err != nil {
+			t.Fatalf("recordStackParent() failed: %v", err)
+		}
+	}
+	forgetWorktree("b")
+
+	recorded := loadRecordedWorktrees()
+	if want := map[string]string{"c": "a", "d": "a"}; !reflect.DeepEqual(stackParents(recorded), want) {
+		t.Errorf("Expected %v, got %v", want, stackParents(recorded))
+	}
+	if head := recorded["c"].ParentHead; head != "base-c" {
+		t.Errorf("Expected c to keep its base, got %q", head)
+	}
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/procs"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of all koh worktrees",
+	Long: `Show every koh worktree with its branch, whether it has uncommitted
+changes and whether its tmux window is open.
+
+When the GitHub CLI (gh) is installed, the state of each branch's pull request
+(open, draft, merged or closed) and its CI status are shown as well. PR data is
+cached for a couple of minutes to avoid rate limits.
+
+With --resources, the CPU and memory used by the processes running in each
+worktree's window are shown too, to spot forgotten dev servers.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+// statusResources shows the CPU and memory used by each worktree window
+var statusResources bool
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusResources, "resources", false, "Show CPU and memory used by each worktree window")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// worktreeStatus describes the state of a single koh worktree
+type worktreeStatus struct {
+	Name       string `json:"name"`
+	Branch     string `json:"branch"`
+	Path       string `json:"path"`
+	Dirty      bool   `json:"dirty"`
+	WindowOpen bool   `json:"window_open"`
+	Current    bool   `json:"current"`
+	// Tracking is how far the branch is ahead of and behind its upstream,
+	// nil when it has none
+	Tracking *git.Tracking `json:"tracking,omitempty"`
+	// Unmanaged is set for worktrees created with plain git, which koh has no record of
+	Unmanaged bool `json:"unmanaged,omitempty"`
+	// Paused is set while the worktree's processes are paused with 'koh pause'
+	Paused bool `json:"paused,omitempty"`
+	// Locked is set when the worktree is locked with 'git worktree lock'
+	Locked bool `json:"locked,omitempty"`
+	// Readonly is set when the worktree is marked with 'koh readonly'
+	Readonly bool `json:"readonly,omitempty"`
+	// Resources is what the processes in the worktree's window use, set with
+	// --resources when the window is open
+	Resources *procs.Usage `json:"resources,omitempty"`
+	// OverQuota is set when the worktree takes up more space than
+	// disk_quota.worktree allows
+	OverQuota bool `json:"over_quota,omitempty"`
+
+	PR *forge.PullRequest `json:"pr,omitempty"`
+}
+
+// collectWorktreeStatus gathers status for a worktree. windows maps worktree
+// names to open tmux windows and may be nil when not running inside tmux.
+func collectWorktreeStatus(wt git.Worktree, windows map[string]string, currentPath string) worktreeStatus {
+	name := filepath.Base(wt.Path)
+	dirty, _ := git.IsDirty(wt.Path)
+	_, windowOpen := windows[name]
+
+	return worktreeStatus{
+		Name:       name,
+		Branch:     displayBranch(wt),
+		Path:       wt.Path,
+		Dirty:      dirty,
+		WindowOpen: windowOpen,
+		Current:    currentPath != "" && wt.Path == currentPath,
+		Locked:     wt.Locked,
+		Tracking:   git.TrackingWithContext(context.Background(), wt.Path),
+	}
+}
+
+// loadWorktreeWindows returns open koh windows, or nil when not in tmux
+func loadWorktreeWindows(ctx context.Context) map[string]string {
+	if !tmux.IsInTmux() {
+		return nil
+	}
+	windows, err := tmux.ListWorktreeWindowsWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+	return windows
+}
+
+// renderTracking renders ahead/behind counts such as "↑2 ↓1", or "" when the
+// branch has no upstream or is in sync with it
+func renderTracking(t *git.Tracking) string {
+	if t == nil {
+		return ""
+	}
+	var parts []string
+	if t.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", t.Ahead))
+	}
+	if t.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", t.Behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatRSS renders a memory size given in kilobytes, e.g. "512 KB" or "1.2 GB"
+func formatRSS(kb int64) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%d MB", kb/1024)
+	default:
+		return fmt.Sprintf("%d KB", kb)
+	}
+}
+
+// renderUsage renders resource usage such as "cpu 12% mem 340 MB"
+func renderUsage(u procs.Usage) string {
+	return fmt.Sprintf("cpu %.0f%% mem %s", u.CPU, formatRSS(u.RSSKB))
+}
+
+// loadWindowUsage returns the resource usage of each worktree's window, or
+// nil when not in tmux or the process table can't be read
+func loadWindowUsage(ctx context.Context) map[string]procs.Usage {
+	if !tmux.IsInTmux() {
+		return nil
+	}
+	pids, err := tmux.WorktreePanePIDsWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+	table, err := procs.SnapshotWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	usage := make(map[string]procs.Usage, len(pids))
+	for name, panePIDs := range pids {
+		usage[name] = table.Sum(panePIDs)
+	}
+	return usage
+}
+
+// renderStatusLine renders a single worktree status line for humans
+func renderStatusLine(st worktreeStatus) string {
+	icon := styles.MarkerWorktree.Render()
+	name := st.Name
+	if st.Current {
+		icon = styles.MarkerCurrent.Render()
+		name = styles.Active.Render(st.Name)
+	}
+
+	parts := []string{
+		icon,
+		name,
+		styles.MarkerBranch.RenderWith(st.Branch),
+	}
+
+	if st.Dirty {
+		parts = append(parts, styles.MarkerDirty.Render())
+	} else {
+		parts = append(parts, styles.MarkerClean.Render())
+	}
+
+	if tracking := renderTracking(st.Tracking); tracking != "" {
+		parts = append(parts, styles.MarkerTracking.RenderWith(tracking))
+	}
+
+	if st.PR != nil {
+		parts = append(parts, renderPullRequest(st.PR))
+	}
+
+	if st.WindowOpen {
+		parts = append(parts, styles.MarkerWindow.Render())
+	}
+
+	if st.Resources != nil {
+		parts = append(parts, styles.Muted.Render(renderUsage(*st.Resources)))
+	}
+
+	if st.Paused {
+		parts = append(parts, styles.MarkerPaused.Render())
+	}
+
+	if st.Locked {
+		parts = append(parts, styles.MarkerLocked.Render())
+	}
+
+	if st.Readonly {
+		parts = append(parts, styles.MarkerReadonly.Render())
+	}
+
+	if st.Unmanaged {
+		parts = append(parts, styles.MarkerUnmanaged.Render())
+	}
+
+	if st.OverQuota {
+		parts = append(parts, styles.MarkerOverQuota.Render())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func runStatus(cmd *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+
+	ctx := context.Background()
+	worktrees, err := loadKohWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	if mainRepoRoot, err := git.GetMainRepoRootOrCwd(); err == nil {
+		maybeAutoFetch(mainRepoRoot)
+	}
+
+	var currentPath string
+	if git.IsInWorktree() {
+		currentPath, _ = git.GetCurrentWorktreePath()
+	}
+
+	// Keep window branches and recorded state in line with what's checked out
+	_, _ = refreshBranches(ctx, worktrees)
+
+	windows := loadWorktreeWindows(ctx)
+	var prs []forge.PullRequest
+	if len(worktrees) > 0 {
+		prs = loadPullRequests(ctx)
+	}
+
+	unmanaged, paused, readonly := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	if commonDir, err := git.GetCommonDir(); err == nil {
+		if s, err := loadInventoriedState(commonDir, worktrees); err == nil {
+			for _, name := range unmanagedWorktrees(s, config.WorktreeDir(filepath.Dir(commonDir)), worktrees) {
+				unmanaged[name] = true
+			}
+			for name, wt := range s.Worktrees {
+				paused[name] = wt.Paused != nil
+				readonly[name] = wt.Readonly
+			}
+		}
+	}
+
+	var usage map[string]procs.Usage
+	if statusResources {
+		if usage = loadWindowUsage(ctx); usage == nil {
+			newPrinter(cmd).Warn("Resource usage is only available inside tmux")
+		}
+	}
+
+	// Sizes are only measured when a quota is configured
+	var disk duResult
+	if worktreeQuota, totalQuota := loadDiskQuota(); worktreeQuota > 0 || totalQuota > 0 {
+		disk = newDuResult(worktrees, recentWorktreeUsage(ctx, worktrees), worktreeQuota, totalQuota)
+	}
+	overQuota := map[string]bool{}
+	for _, wt := range disk.Worktrees {
+		overQuota[wt.Name] = wt.OverQuota
+	}
+
+	statuses := []worktreeStatus{}
+	for _, wt := range worktrees {
+		st := collectWorktreeStatus(wt, windows, currentPath)
+		if wt.Branch != "" {
+			st.PR = forge.ForBranch(prs, wt.Branch)
+		}
+		st.Unmanaged = unmanaged[st.Name]
+		st.Paused = paused[st.Name]
+		st.Readonly = readonly[st.Name]
+		st.OverQuota = overQuota[st.Name]
+		if u, ok := usage[st.Name]; ok && st.WindowOpen {
+			st.Resources = &u
+		}
+		statuses = append(statuses, st)
+	}
+
+	return newPrinter(cmd).Result(statuses, func(w io.Writer) {
+		fprintln(w, "\n"+styles.RenderTitle(styles.IconTree+" Koh Worktree Status"))
+		if len(statuses) == 0 {
+			fprintln(w, styles.Muted.Render("No koh worktrees found"))
+			return
+		}
+		for _, st := range statuses {
+			fprintln(w, renderStatusLine(st))
+		}
+		if len(unmanaged) > 0 {
+			fprintln(w, "\n"+styles.Muted.Render("Run 'koh import <worktree-name>' to adopt a worktree created outside koh"))
+		}
+		if disk.OverTotal {
+			fprintln(w, "\n"+styles.WarningMessage.Render(totalQuotaWarning(disk)))
+		}
+		fprintln(w)
+	})
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bshakr/koh/internal/forge"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/procs"
+)
+
+func TestRenderStatusLine(t *testing.T) {
+	line := renderStatusLine(worktreeStatus{Name: "feature", Branch: "feature", Dirty: true, WindowOpen: true})
+	for _, want := range []string{"feature", "dirty", "window open"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected status line to contain %q, got %q", want, line)
+		}
+	}
+
+	clean := renderStatusLine(worktreeStatus{Name: "other", Branch: "other"})
+	if !strings.Contains(clean, "clean") || strings.Contains(clean, "window open") {
+		t.Errorf("Unexpected clean status line: %q", clean)
+	}
+}
+
+func TestWorktreeDetailsJSONIsFlat(t *testing.T) {
+	details := worktreeDetails{
+		worktreeStatus: worktreeStatus{Name: "feature", Branch: "feature", Path: "/repo/.koh/feature"},
+		Head:           "abc123",
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		t.Fatalf("Failed to marshal details: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal details: %v", err)
+	}
+	for _, key := range []string{"name", "branch", "path", "dirty", "window_open", "head"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("Expected top-level key %q in %s", key, data)
+		}
+	}
+}
+
+func TestRenderStatusLineWithPullRequest(t *testing.T) {
+	st := worktreeStatus{
+		Name:   "feature",
+		Branch: "feature",
+		PR:     &forge.PullRequest{Number: 42, State: forge.StateDraft, CI: forge.CIFailing},
+	}
+
+	line := renderStatusLine(st)
+	for _, want := range []string{"#42 draft", "CI"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected status line to contain %q, got %q", want, line)
+		}
+	}
+}
+
+func TestRenderStatusLineUnmanaged(t *testing.T) {
+	line := renderStatusLine(worktreeStatus{Name: "raw", Branch: "raw", Unmanaged: true})
+	if !strings.Contains(line, "created outside koh") {
+		t.Errorf("Expected status line to flag the unmanaged worktree, got %q", line)
+	}
+}
+
+func TestRenderTracking(t *testing.T) {
+	tests := []struct {
+		tracking *git.Tracking
+		want     string
+	}{
+		{nil, ""},
+		{&git.Tracking{}, ""},
+		{&git.Tracking{Ahead: 2}, "↑2"},
+		{&git.Tracking{Ahead: 2, Behind: 1}, "↑2 ↓1"},
+	}
+
+	for _, tt := range tests {
+		if got := renderTracking(tt.tracking); got != tt.want {
+			t.Errorf("Expected renderTracking(%+v) = %q, got %q", tt.tracking, tt.want, got)
+		}
+	}
+}
+
+func TestFormatRSS(t *testing.T) {
+	tests := []struct {
+		kb   int64
+		want string
+	}{
+		{512, "512 KB"},
+		{2048, "2 MB"},
+		{1536 * 1024, "1.5 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRSS(tt.kb); got != tt.want {
+			t.Errorf("Expected formatRSS(%d) = %q, got %q", tt.kb, tt.want, got)
+		}
+	}
+}
+
+func TestRenderStatusLineResources(t *testing.T) {
+	line := renderStatusLine(worktreeStatus{Name: "busy", Branch: "busy", Resources: &procs.Usage{CPU: 87.4, RSSKB: 300 * 1024}})
+	if !strings.Contains(line, "cpu 87% mem 300 MB") {
+		t.Errorf("Expected status line to show resource usage, got %q", line)
+	}
+}
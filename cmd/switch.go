@@ -3,13 +3,15 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/bshakr/koh/internal/config"
 	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
 	"github.com/bshakr/koh/internal/tmux"
 	"github.com/bshakr/koh/internal/validation"
 	"github.com/spf13/cobra"
@@ -19,83 +21,172 @@ var switchCmd = &cobra.Command{
 	Use:   "switch <worktree-name>",
 	Short: "Switch to an existing worktree's tmux session",
 	Long: `Switch to an existing git worktree's tmux session.
-If the tmux window doesn't exist, it will be created automatically according to your configuration.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runSwitch,
+If the tmux window doesn't exist, it will be created automatically according to your configuration.
+
+With --create, a missing worktree is created just like 'koh new' would,
+except that it doesn't stop to ask about a setup script that differs from
+the main repository's copy: the worktree's copy runs, with a warning.
+
+With --background, a missing window is created but the current window stays
+selected; a window that already exists is left alone.
+
+With --open, the worktree is also opened in your editor in a new pane of
+its window, unless a pane already runs it. Set open_editor in the global
+config to always do so, and editor to pick the editor ($VISUAL or $EDITOR
+by default).
+
+With --detach-others, the repository's other windows that haven't been
+touched for --idle-days days (no output, nothing typed and no commits) are
+moved to the background tmux session "koh-detached", processes and all;
+switching to one of their worktrees later brings its window back. Pause
+them first with 'koh pause' to also stop what runs inside. With
+--close-others, they are closed instead.
+
+Switching away from a worktree with uncommitted changes prints a warning.
+Set dirty_switch in the global config to "ask" to be offered to stash or
+commit them first, or to "ignore" to skip the check.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runSwitch,
 }
 
+var (
+	// switchCreate creates the worktree when it doesn't exist yet
+	switchCreate bool
+	// switchBackground creates a missing window without switching to it
+	switchBackground bool
+	// switchOpen opens the worktree in the editor in a new pane of its window
+	switchOpen bool
+	// switchDetachOthers moves the repository's idle windows to the detached session
+	switchDetachOthers bool
+	// switchCloseOthers closes the repository's idle windows
+	switchCloseOthers bool
+	// switchIdleDays is how many days untouched make a window idle
+	switchIdleDays int
+)
+
 func init() {
+	switchCmd.Flags().BoolVar(&switchCreate, "create", false, "Create the worktree if it doesn't exist")
+	switchCmd.Flags().BoolVar(&switchBackground, "background", false, "Create a missing window without switching to it")
+	switchCmd.Flags().StringVar(&repoDir, "repo", "", "With --create, the repository to create the worktree in")
+	switchCmd.Flags().BoolVar(&switchOpen, "open", false, "Open the worktree in your editor, in a new pane of its window (default from open_editor)")
+	switchCmd.Flags().BoolVar(&switchDetachOthers, "detach-others", false, "Move the repository's idle windows to the koh-detached tmux session")
+	switchCmd.Flags().BoolVar(&switchCloseOthers, "close-others", false, "Close the repository's idle windows")
+	switchCmd.Flags().IntVar(&switchIdleDays, "idle-days", 7, "Days untouched after which --detach-others or --close-others takes a window")
+	switchCmd.MarkFlagsMutuallyExclusive("detach-others", "close-others")
 	rootCmd.AddCommand(switchCmd)
 }
 
+// switchResult is the machine-readable result of 'koh switch'
+type switchResult struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Created bool   `json:"created"`
+	// Detached are the worktrees whose idle window --detach-others moved away
+	Detached []string `json:"detached,omitempty"`
+	// Closed are the worktrees whose idle window --close-others closed
+	Closed []string `json:"closed,omitempty"`
+}
+
 // switchToWorktree contains the core logic for switching to a worktree's tmux session.
 // This function is used by both the 'switch' command and the interactive 'list' command.
-func switchToWorktree(worktreeName string, quiet bool) error {
+// Progress messages are written through p unless quiet is set. With
+// background, the window is only created when missing and never selected;
+// otherwise the worktree is recorded as used (see 'koh recent').
+func switchToWorktree(p *output.Printer, worktreeName string, quiet, background bool) (result *switchResult, err error) {
+	defer func() {
+		if err == nil && !background {
+			recordUsed(worktreeName)
+		}
+	}()
+
 	// Validate worktree name for security
 	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
-		return fmt.Errorf("invalid worktree name: %w", err)
+		return nil, fmt.Errorf("invalid worktree name: %w", err)
 	}
 
 	// Check if we're in a tmux session
 	if !tmux.IsInTmux() {
-		return fmt.Errorf("not in a tmux session\nPlease run this command from within a tmux session")
+		return nil, fmt.Errorf("not in a tmux session\nPlease run this command from within a tmux session")
 	}
 
 	// Check if we're in a git repository
 	if !git.IsGitRepo() {
-		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+		return nil, fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
 	}
 
 	// Determine the main repo root
 	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
 	if err != nil {
-		return fmt.Errorf("failed to get repository root: %w", err)
+		return nil, fmt.Errorf("failed to get repository root: %w", err)
+	}
+
+	// A window moved away by --detach-others comes back when switched to
+	if repoName, err := git.GetRepoName(); err == nil {
+		if _, err := tmux.ReattachWindowWithContext(context.Background(), tmux.WindowName(repoName, worktreeName)); err != nil {
+			p.Warn("%v", err)
+		}
+	}
+
+	// The reserved name "main" addresses the repository's main checkout
+	if worktreeName == mainCheckoutName {
+		return switchToMainCheckout(p, mainRepoRoot, quiet, background)
 	}
 
 	// Check if worktree exists
-	worktreePath := filepath.Join(mainRepoRoot, ".koh", worktreeName)
+	worktreePath := kohWorktreePath(mainRepoRoot, worktreeName)
+	label := worktreeLabel(mainRepoRoot, worktreeName)
 	if _, err := os.Stat(worktreePath); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("worktree .koh/%s does not exist\nUse 'koh new %s' to create it", worktreeName, worktreeName)
+			return nil, fmt.Errorf("worktree %s does not exist\nUse 'koh new %s' to create it", label, worktreeName)
 		}
-		return fmt.Errorf("failed to check worktree path: %w", err)
+		return nil, fmt.Errorf("failed to check worktree path: %w", err)
 	}
 
 	// Check if tmux window already exists
 	exists, err := tmux.WindowExists(worktreeName)
 	if err != nil {
-		return fmt.Errorf("failed to check for existing tmux window: %w", err)
+		return nil, fmt.Errorf("failed to check for existing tmux window: %w", err)
 	}
 
+	if exists && background {
+		if !quiet {
+			p.Info("Window for %s already exists", label)
+		}
+		return &switchResult{Name: worktreeName, Path: worktreePath}, nil
+	}
 	if exists {
 		// Window exists, just switch to it
 		if !quiet {
-			fmt.Printf("Switching to existing session: .koh/%s\n", worktreeName)
+			p.Info("Switching to existing session: %s", label)
 		}
 		if err := tmux.SwitchToWindow(worktreeName); err != nil {
-			return fmt.Errorf("failed to switch to tmux window: %w", err)
+			return nil, fmt.Errorf("failed to switch to tmux window: %w", err)
 		}
-		return nil
+		return &switchResult{Name: worktreeName, Path: worktreePath}, nil
 	}
 
 	// Window doesn't exist, create it
 	if !quiet {
-		fmt.Printf("Creating new tmux session for existing worktree: .koh/%s\n", worktreeName)
+		p.Info("Creating new tmux session for existing worktree: %s", label)
 	}
 
 	// Check if config exists
 	exists, err = config.ConfigExists()
 	if err != nil {
-		return fmt.Errorf("failed to check for .kohconfig: %w", err)
+		return nil, fmt.Errorf("failed to check for .kohconfig: %w", err)
 	}
 	if !exists {
-		return fmt.Errorf("no .kohconfig found\nPlease run 'koh init' to set up your configuration first")
+		return nil, fmt.Errorf("no .kohconfig found\nPlease run 'koh init' to set up your configuration first")
 	}
 
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg, err = worktreeProfileConfig(cfg, worktreeName); err != nil {
+		return nil, err
 	}
 
 	// Set up context with cancellation for long-running operations
@@ -114,7 +205,7 @@ func switchToWorktree(worktreeName string, quiet bool) error {
 		go func() {
 			defer close(done)
 			<-sigChan
-			fmt.Println("\nOperation cancelled by user")
+			p.Info("\nOperation cancelled by user")
 			cancel()
 		}()
 		defer func() {
@@ -126,21 +217,151 @@ func switchToWorktree(worktreeName string, quiet bool) error {
 	// Get repository name
 	repoName, err := git.GetRepoName()
 	if err != nil {
-		return fmt.Errorf("failed to get repository name: %w", err)
+		return nil, fmt.Errorf("failed to get repository name: %w", err)
 	}
 
-	// Create tmux session with config and context
-	if err := tmux.CreateSessionWithContext(ctx, repoName, worktreeName, worktreePath, cfg); err != nil {
-		return fmt.Errorf("failed to create tmux session: %w", err)
+	env, err := ensureScratch(mainRepoRoot, worktreeName)
+	if err != nil {
+		p.Warn("%v", err)
 	}
+	env = append(env, portsEnv(p, cfg, worktreePath)...)
+	env = append(env, configEnv(cfg, worktreeName, worktreePath, env)...)
 
-	if !quiet {
-		fmt.Println("Session created successfully!")
+	if cfg, err = confirmSetupScript(ctx, p, mainRepoRoot, worktreePath, cfg, true); err != nil {
+		return nil, err
+	}
+
+	cfg, fileVars := renderWindowConfig(cfg, worktreeName, mainRepoRoot)
+	env = append(env, fileVars...)
+
+	// Create tmux session with config and context
+	createSession := tmux.CreateSessionWithContext
+	if background {
+		createSession = tmux.CreateBackgroundSessionWithContext
 	}
-	return nil
+	if err := createSession(ctx, repoName, worktreeName, worktreePath, cfg, env...); err != nil {
+		return nil, fmt.Errorf("failed to create tmux session: %w", err)
+	}
+	recordPaneIDs(ctx, worktreeName)
+	recordPaneCommands(worktreeName, cfg)
+
+	return &switchResult{Name: worktreeName, Path: worktreePath, Created: true}, nil
 }
 
-func runSwitch(_ *cobra.Command, args []string) error {
+func runSwitch(cmd *cobra.Command, args []string) error {
 	worktreeName := args[0]
-	return switchToWorktree(worktreeName, false)
+	p := newPrinter(cmd)
+	open := wantsOpenEditor(cmd, switchOpen)
+	if switchIdleDays < 1 {
+		return fmt.Errorf("--idle-days must be at least 1")
+	}
+
+	if !switchBackground {
+		if err := checkDirtyBeforeSwitch(context.Background(), p, worktreeName); err != nil {
+			return err
+		}
+	}
+
+	if switchCreate && worktreeName != mainCheckoutName {
+		if err := resolveNestedRepo(p, repoDir); err != nil {
+			return err
+		}
+		created, err := createIfMissing(p, commandLine(cmd.CommandPath(), args), worktreeName, open)
+		if err != nil {
+			return err
+		}
+		if created != nil {
+			result := switchResult{Name: created.Name, Path: created.Path, Created: true}
+			detachOthers(p, &result)
+			return p.Result(result, func(w io.Writer) {
+				fprintln(w, "Worktree setup complete!")
+			})
+		}
+	}
+
+	result, err := switchToWorktree(p, worktreeName, false, switchBackground)
+	if err != nil {
+		return err
+	}
+	if isReadonly(worktreeName) {
+		p.Warn("%s is read-only; start new work from it with 'koh new <name> --stack-on %s'", worktreeName, worktreeName)
+	}
+	if open {
+		if err := openEditorPane(context.Background(), p, worktreeName, result.Path); err != nil {
+			p.Warn("%v", err)
+		}
+	}
+	detachOthers(p, result)
+
+	return p.Result(result, func(w io.Writer) {
+		switch {
+		case result.Created && switchBackground:
+			fprintln(w, "Session created in the background")
+		case result.Created:
+			fprintln(w, "Session created successfully!")
+		}
+	})
+}
+
+// detachOthers handles --detach-others and --close-others after switching
+// to result's worktree
+func detachOthers(p *output.Printer, result *switchResult) {
+	if !switchDetachOthers && !switchCloseOthers {
+		return
+	}
+	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
+	if err != nil {
+		p.Warn("Failed to get repository root: %v", err)
+		return
+	}
+	idleFor := time.Duration(switchIdleDays) * 24 * time.Hour
+	gone := detachIdleWindows(context.Background(), p, mainRepoRoot, result.Name, idleFor, switchCloseOthers)
+	if switchCloseOthers {
+		result.Closed = gone
+	} else {
+		result.Detached = gone
+	}
+}
+
+// createIfMissing creates a worktree for 'koh switch --create' through the
+// 'koh new' pipeline, which selects the new window unless --background is
+// set, and returns nil when the worktree exists. Whether it's missing is
+// checked again under the repository lock, so a concurrent 'koh new' can't
+// create it in between. Nothing prompts while the lock is held, so other koh
+// commands never wait on an answer: the questions switching asks come first.
+func createIfMissing(p *output.Printer, command, worktreeName string, open bool) (*newResult, error) {
+	if missing, err := worktreeMissing(worktreeName); err != nil || !missing {
+		return nil, err
+	}
+
+	unlock, err := lockRepo(p, command)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if missing, err := worktreeMissing(worktreeName); err != nil || !missing {
+		return nil, err
+	}
+	return createWorktree(p, worktreeName, newOptions{background: switchBackground, open: open, noPrompt: true})
+}
+
+// worktreeMissing reports whether the named koh worktree doesn't exist yet
+func worktreeMissing(worktreeName string) (bool, error) {
+	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
+		return false, fmt.Errorf("invalid worktree name: %w", err)
+	}
+
+	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
+	if err != nil {
+		return false, fmt.Errorf("failed to get repository root: %w", err)
+	}
+
+	_, err = os.Stat(kohWorktreePath(mainRepoRoot, worktreeName))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check worktree path: %w", err)
+	}
+	return false, nil
 }
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"io"
+	"path/filepath"
 	"testing"
 
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/testutil"
+	"github.com/bshakr/koh/internal/tmux"
 	"github.com/bshakr/koh/internal/validation"
 )
 
@@ -96,3 +102,79 @@ func TestWorktreeNameValidationInSwitch(t *testing.T) {
 		})
 	}
 }
+
+func TestSwitchToWorktreeInTmux(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	repo := newDashboardRepo(t)
+	window := tmux.WindowName(filepath.Base(repo), "feat-a")
+	p := output.New(io.Discard, output.Human)
+
+	// In the background the window is created but not selected
+	result, err := switchToWorktree(p, "feat-a", true, true)
+	if err != nil {
+		t.Fatalf("switchToWorktree() failed: %v", err)
+	}
+	if !result.Created {
+		t.Error("Expected the window to be created")
+	}
+	if current := tm.CurrentWindow(t); current == window {
+		t.Errorf("Expected %s to stay in the background", window)
+	}
+	if wt := loadRecordedWorktrees()["feat-a"]; wt != nil && !wt.UsedAt.IsZero() {
+		t.Error("Expected a background switch not to count as use")
+	}
+
+	// Switching again selects the window instead of creating another
+	result, err = switchToWorktree(p, "feat-a", true, false)
+	if err != nil {
+		t.Fatalf("switchToWorktree() failed: %v", err)
+	}
+	if result.Created {
+		t.Error("Expected the existing window to be reused")
+	}
+	if current := tm.CurrentWindow(t); current != window {
+		t.Errorf("Expected window %s to be selected, got %s", window, current)
+	}
+	if wt := loadRecordedWorktrees()["feat-a"]; wt == nil || wt.UsedAt.IsZero() {
+		t.Error("Expected the switch to be recorded for 'koh recent'")
+	}
+	if windows := tm.Windows(t); len(windows) != 2 {
+		t.Errorf("Expected one window besides the first, got %v", windows)
+	}
+
+	// A detached window is brought back rather than created again
+	tm.Run(t, "select-window", "-t", tm.Session+":^")
+	if err := tmux.DetachWindowWithContext(context.Background(), window); err != nil {
+		t.Fatalf("DetachWindowWithContext() failed: %v", err)
+	}
+	result, err = switchToWorktree(p, "feat-a", true, false)
+	if err != nil {
+		t.Fatalf("switchToWorktree() failed: %v", err)
+	}
+	if result.Created || tm.CurrentWindow(t) != window {
+		t.Errorf("Expected the detached window to be brought back and selected, got created=%v", result.Created)
+	}
+
+	if _, err := switchToWorktree(p, "missing", true, false); err == nil {
+		t.Error("Expected an error for a worktree that doesn't exist")
+	}
+}
+
+func TestCreateIfMissing(t *testing.T) {
+	testutil.NewTmux(t)
+	repo := newDashboardRepo(t)
+	p := output.New(io.Discard, output.Human)
+
+	created, err := createIfMissing(p, "koh switch --create feat-a", "feat-a", false)
+	if err != nil || created != nil {
+		t.Fatalf("Expected an existing worktree to be left alone, got %+v (%v)", created, err)
+	}
+
+	created, err = createIfMissing(p, "koh switch --create feat-b", "feat-b", false)
+	if err != nil {
+		t.Fatalf("createIfMissing() failed: %v", err)
+	}
+	if created == nil || created.Path != filepath.Join(repo, ".koh", "feat-b") {
+		t.Errorf("Expected feat-b to be created, got %+v", created)
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Manage opt-in anonymous usage counters",
+	Long: `Manage opt-in anonymous usage counters.
+
+Telemetry is off unless you enable it. When enabled, koh counts how often each
+command runs and how often commands fail, by coarse error category. Arguments,
+paths, worktree names and error messages are never recorded. The counters stay
+on this machine: 'koh telemetry show --json' prints exactly what is stored, so
+you can share it with the maintainers if you'd like to, or use
+'koh telemetry export' to write it to a file to upload.`,
+	Args: cobra.NoArgs,
+	RunE: runTelemetryShow,
+}
+
+var telemetryEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Start counting command usage",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryEnable,
+}
+
+var telemetryDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop counting and delete recorded counters",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryDisable,
+}
+
+var telemetryShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show everything telemetry has recorded",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryShow,
+}
+
+var telemetryExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the recorded counters as JSON, to share them",
+	Long: `Write the recorded counters as JSON to file, or to stdout without one.
+The output is exactly what is stored, ready to attach to an issue.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTelemetryExport,
+}
+
+func init() {
+	rootCmd.AddCommand(telemetryCmd)
+	telemetryCmd.AddCommand(telemetryEnableCmd, telemetryDisableCmd, telemetryShowCmd, telemetryExportCmd)
+}
+
+// telemetryStatus is the machine-readable result of 'koh telemetry enable' and 'disable'
+type telemetryStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// telemetryResult is the machine-readable result of 'koh telemetry show'
+type telemetryResult struct {
+	Path string `json:"path"`
+	*telemetry.Counters
+}
+
+// renderCounts renders counters as an indented list, most frequent first
+func renderCounts(w io.Writer, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		fprintln(w, fmt.Sprintf("  %5d  %s", counts[k], k))
+	}
+}
+
+func runTelemetryEnable(cmd *cobra.Command, _ []string) error {
+	if err := telemetry.Enable(time.Now()); err != nil {
+		return err
+	}
+	return newPrinter(cmd).Result(telemetryStatus{Enabled: true}, func(w io.Writer) {
+		fprintln(w, styles.RenderSuccess("Telemetry enabled. Run 'koh telemetry show' to see what is recorded."))
+	})
+}
+
+func runTelemetryDisable(cmd *cobra.Command, _ []string) error {
+	if err := telemetry.Disable(); err != nil {
+		return err
+	}
+	return newPrinter(cmd).Result(telemetryStatus{Enabled: false}, func(w io.Writer) {
+		fprintln(w, styles.RenderSuccess("Telemetry disabled and recorded counters deleted"))
+	})
+}
+
+func runTelemetryExport(cmd *cobra.Command, args []string) error {
+	counters, err := telemetry.Load()
+	if err != nil {
+		return err
+	}
+	if !counters.Enabled {
+		return fmt.Errorf("telemetry is disabled, so there is nothing to export\nEnable it with 'koh telemetry enable'")
+	}
+
+	data, err := json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry: %w", err)
+	}
+	data = append(data, '\n')
+
+	if len(args) == 0 {
+		_, err := cmd.OutOrStdout().Write(data)
+		return err
+	}
+	//nolint:gosec // G306: 0644 is standard permission for user files
+	if err := os.WriteFile(args[0], data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", args[0], err)
+	}
+	newPrinter(cmd).Info("Exported telemetry to %s", args[0])
+	return nil
+}
+
+func runTelemetryShow(cmd *cobra.Command, _ []string) error {
+	path, err := telemetry.Path()
+	if err != nil {
+		return err
+	}
+	counters, err := telemetry.Load()
+	if err != nil {
+		return err
+	}
+
+	result := telemetryResult{Path: path, Counters: counters}
+	return newPrinter(cmd).Result(result, func(w io.Writer) {
+		if !counters.Enabled {
+			fprintln(w, styles.Muted.Render("Telemetry is disabled. Enable it with 'koh telemetry enable'."))
+			return
+		}
+
+		fprintln(w, "\n"+styles.RenderTitle("Telemetry"))
+		fprintln(w, styles.RenderKeyValue("Stored in", path))
+		fprintln(w, styles.RenderKeyValue("Enabled since", counters.EnabledAt.Format(time.RFC3339)))
+		fprintln(w, "\nCommands:")
+		renderCounts(w, counters.Commands)
+		fprintln(w, "\nErrors:")
+		renderCounts(w, counters.Errors)
+		fprintln(w)
+	})
+}
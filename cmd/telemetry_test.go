@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+func TestTelemetryExport(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	out := filepath.Join(t.TempDir(), "telemetry.json")
+
+	if err := runTelemetryExport(&cobra.Command{}, []string{out}); err == nil {
+		t.Error("Expected exporting to fail while telemetry is disabled")
+	}
+
+	if err := telemetry.Enable(time.Now()); err != nil {
+		t.Fatalf("Enable() failed: %v", err)
+	}
+	telemetry.Record("new", nil)
+	if err := runTelemetryExport(&cobra.Command{}, []string{out}); err != nil {
+		t.Fatalf("runTelemetryExport() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Expected the export to be written: %v", err)
+	}
+	var c telemetry.Counters
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("Expected the export to be JSON: %v", err)
+	}
+	if !c.Enabled || c.Commands["new"] != 1 {
+		t.Errorf("Expected the exported counters to match what's stored, got %+v", c)
+	}
+}
+
+func TestSilentFailureCategory(t *testing.T) {
+	err := silentFailure(errors.New("not in a tmux session"))
+	if !errors.Is(err, errSilentFailure) {
+		t.Error("Expected a silent failure to match errSilentFailure")
+	}
+	if got := telemetry.Category(err); got != "not_in_tmux" {
+		t.Errorf("Expected a silent failure to be categorized by its cause, got %q", got)
+	}
+}
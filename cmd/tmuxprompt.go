@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var tmuxPromptCmd = &cobra.Command{
+	Use:   "tmux-prompt",
+	Short: "Drive koh from tmux key bindings",
+	Long: `Commands meant to be bound to tmux keys. They ask for input in the tmux
+command prompt and report back in the status line, so koh can be used
+without a shell, e.g. in ~/.tmux.conf:
+
+  bind-key S run-shell "koh tmux-prompt switch"`,
+}
+
+var tmuxPromptSwitchCmd = &cobra.Command{
+	Use:   "switch [worktree-name]",
+	Short: "Ask for a worktree in the tmux prompt and switch to it",
+	Long: `Open the tmux command prompt to ask for a worktree name, then switch to
+that worktree's window, creating the worktree like 'koh new' when it
+doesn't exist yet.
+
+The repository is the one of the current pane, or --repo. Given a name,
+koh switches right away without asking. Failures are shown in the tmux
+status line.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTmuxPromptSwitch,
+}
+
+func init() {
+	tmuxPromptSwitchCmd.Flags().StringVar(&repoDir, "repo", "", repoFlagUsage)
+	tmuxPromptCmd.AddCommand(tmuxPromptSwitchCmd)
+	rootCmd.AddCommand(tmuxPromptCmd)
+}
+
+// tmuxPromptText is what the prompt of 'koh tmux-prompt switch' asks
+const tmuxPromptText = "koh switch (or create):"
+
+// tmuxPromptAnswer is where command-prompt puts the answer in a template,
+// escaped for the double-quoted run-shell argument it ends up in
+const tmuxPromptAnswer = "%%%"
+
+// tmuxPromptTemplate returns the command-prompt template that runs
+// 'koh tmux-prompt switch' with the answer in the repository at dir. The
+// answer is the user's own typing and koh validates it as a worktree name.
+func tmuxPromptTemplate(exe, dir string) string {
+	return `run-shell -b "` + strings.Join([]string{
+		tmux.QuoteShellCommand(exe), "tmux-prompt switch --repo", tmux.QuoteShellCommand(dir),
+		"--", tmux.QuoteShellCommand(tmuxPromptAnswer),
+	}, " ") + `"`
+}
+
+// tmuxPromptSwitch switches to the worktree called name, creating it when
+// it doesn't exist, and returns the message to show in the status line
+func tmuxPromptSwitch(p *output.Printer, name string) (string, error) {
+	missing := false
+	if name != mainCheckoutName {
+		var err error
+		if missing, err = worktreeMissing(name); err != nil {
+			return "", err
+		}
+	}
+	if missing {
+		unlock, err := lockRepo(p, "koh tmux-prompt switch "+name)
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+		if _, err := createWorktree(p, name, newOptions{}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created worktree %s", name), nil
+	}
+
+	result, err := switchToWorktree(p, name, true, false)
+	if err != nil {
+		return "", err
+	}
+	if result.Created {
+		return fmt.Sprintf("Opened the window of %s", name), nil
+	}
+	return "", nil
+}
+
+func runTmuxPromptSwitch(_ *cobra.Command, args []string) error {
+	if !tmux.IsInTmux() {
+		return fmt.Errorf("not in a tmux session\nBind it to a tmux key instead, e.g. bind-key S run-shell \"koh tmux-prompt switch\"")
+	}
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		dir := repoDir
+		if dir == "" {
+			var err error
+			if dir, err = tmux.CurrentPanePathWithContext(ctx); err != nil {
+				return err
+			}
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			exe = "koh"
+		}
+		return tmux.CommandPromptWithContext(ctx, tmuxPromptText, tmuxPromptTemplate(exe, dir))
+	}
+
+	// run-shell shows whatever a command prints in the pane, so progress is
+	// dropped and the outcome goes to the status line instead
+	p := output.New(io.Discard, output.Human)
+	message, err := func() (string, error) {
+		if err := resolveNestedRepo(p, repoDir); err != nil {
+			return "", err
+		}
+		return tmuxPromptSwitch(p, strings.TrimSpace(args[0]))
+	}()
+	if err != nil {
+		// Only the first line fits the status line
+		message, _, _ = strings.Cut("koh: "+err.Error(), "\n")
+	}
+	if message != "" {
+		_ = tmux.DisplayMessageWithContext(ctx, message)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/shell"
+	"github.com/bshakr/koh/internal/testutil"
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestTmuxPromptTemplate(t *testing.T) {
+	got := tmuxPromptTemplate("/usr/local/bin/koh", "/src/my app")
+	want := `run-shell -b "'/usr/local/bin/koh' tmux-prompt switch --repo '/src/my app' -- '%%%'"`
+	if got != want {
+		t.Errorf("Expected template %s, got %s", want, got)
+	}
+}
+
+func TestTmuxPromptTemplateRunsInTmux(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "args")
+	exe := filepath.Join(dir, "it's koh")
+	testutil.WriteFile(t, dir, "it's koh", "#!/bin/sh\nprintf '%s\\n' \"$@\" > "+shell.Quote(out+".tmp")+" && mv "+shell.Quote(out+".tmp")+" "+shell.Quote(out)+"\n")
+	if err := os.Chmod(exe, 0o755); err != nil {
+		t.Fatalf("Failed to make the stub executable: %v", err)
+	}
+
+	repo := `/src/"$HOME" #1 \ it's`
+	answer := `my "feat" \ x`
+	// command-prompt replaces %%% with the answer with " and \ escaped
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(answer)
+	command := strings.ReplaceAll(tmuxPromptTemplate(exe, repo), tmuxPromptAnswer, escaped)
+	testutil.WriteFile(t, dir, "command.conf", command+"\n")
+	tm.Run(t, "source-file", filepath.Join(dir, "command.conf"))
+
+	var data []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		var err error
+		if data, err = os.ReadFile(out); err == nil {
+			break
+		}
+	}
+	want := strings.Join([]string{"tmux-prompt", "switch", "--repo", repo, "--", answer}, "\n") + "\n"
+	if string(data) != want {
+		t.Errorf("Expected koh to be run with %q, got %q", want, data)
+	}
+}
+
+func TestTmuxPromptSwitch(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	repo := testutil.NewRepo(t)
+	testutil.WriteFile(t, repo, ".kohconfig", `{"pane_commands": []}`)
+	p := output.New(io.Discard, output.Human)
+	window := tmux.WindowName(filepath.Base(repo), "feat")
+
+	message, err := tmuxPromptSwitch(p, "feat")
+	if err != nil {
+		t.Fatalf("tmuxPromptSwitch() failed: %v", err)
+	}
+	if message != "Created worktree feat" || tm.CurrentWindow(t) != window {
+		t.Errorf("Expected feat to be created and selected, got %q in %s", message, tm.CurrentWindow(t))
+	}
+
+	// A worktree that exists is switched to
+	tm.Run(t, "select-window", "-t", tm.Session+":^")
+	if message, err = tmuxPromptSwitch(p, "feat"); err != nil {
+		t.Fatalf("tmuxPromptSwitch() failed: %v", err)
+	}
+	if message != "" || tm.CurrentWindow(t) != window {
+		t.Errorf("Expected a quiet switch to %s, got %q in %s", window, message, tm.CurrentWindow(t))
+	}
+
+	if _, err := tmuxPromptSwitch(p, "../escape"); err == nil || !strings.Contains(err.Error(), "invalid worktree name") {
+		t.Errorf("Expected an invalid name to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var unwipCmd = &cobra.Command{
+	Use:   "unwip",
+	Short: "Bring back the work in progress saved by 'koh wip'",
+	Long: `Turn the latest work in progress 'koh wip' saved in the worktree you're in
+back into uncommitted changes: a WIP commit is undone, keeping its changes,
+and a stash entry is popped.
+
+A WIP commit is only undone while it is still the latest commit, so work
+committed on top of it is never lost.`,
+	Args: cobra.NoArgs,
+	RunE: runUnwip,
+}
+
+func init() {
+	rootCmd.AddCommand(unwipCmd)
+}
+
+// restoreWIP turns a recorded WIP commit or stash back into uncommitted changes
+func restoreWIP(ctx context.Context, path string, wip state.WIP) error {
+	if wip.Stash {
+		stash, err := git.FindStashWithContext(ctx, path, wip.Commit)
+		if err != nil {
+			return err
+		}
+		if stash == "" {
+			return errWIPGone
+		}
+		return git.PopStashWithContext(ctx, path, stash)
+	}
+
+	head, err := git.HeadCommitWithContext(ctx, path)
+	if err != nil {
+		return err
+	}
+	if head != wip.Commit {
+		return fmt.Errorf("the WIP commit %s is no longer the latest commit\nUndo it yourself, e.g. with 'git revert %s'", shortCommit(wip.Commit), shortCommit(wip.Commit))
+	}
+	return git.UndoCommitWithContext(ctx, path)
+}
+
+// errWIPGone is returned when the stash entry 'koh wip' made was dropped
+var errWIPGone = errors.New("the stash entry was already popped or dropped")
+
+func runUnwip(cmd *cobra.Command, _ []string) error {
+	p := newPrinter(cmd)
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	name := currentCheckoutName(info)
+
+	s, err := state.Load(info.CommonDir)
+	if err != nil {
+		return err
+	}
+	saved := s.Worktree(name).WIP
+	if len(saved) == 0 {
+		return fmt.Errorf("no work in progress saved in %s\nSave some with 'koh wip'", name)
+	}
+	wip := saved[len(saved)-1]
+
+	restoreErr := restoreWIP(ctx, info.TopLevel, wip)
+	if restoreErr != nil && !errors.Is(restoreErr, errWIPGone) {
+		return restoreErr
+	}
+	_ = cache.Invalidate(info.CommonDir)
+
+	// The record goes once the changes are back, or when nothing is left to restore
+	if err := state.Update(info.CommonDir, func(s *state.State) error {
+		wt := s.Worktree(name)
+		if n := len(wt.WIP); n > 0 && wt.WIP[n-1].Commit == wip.Commit {
+			wt.WIP = wt.WIP[:n-1]
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("restored the changes in %s but failed to record it: %w", name, err)
+	}
+	if restoreErr != nil {
+		return fmt.Errorf("%w: %q in %s\nkoh no longer tracks it", restoreErr, wip.Message, name)
+	}
+
+	result := wipResult{Name: name, Stash: wip.Stash, Commit: wip.Commit, Message: wip.Message}
+	return p.Result(result, func(w io.Writer) {
+		fprintln(w, styles.RenderSuccess(fmt.Sprintf("Restored %q in %s as uncommitted changes", result.Message, result.Name)))
+		if len(saved) > 1 {
+			fprintln(w, styles.Muted.Render(fmt.Sprintf("%d more saved; run 'koh unwip' again for the next", len(saved)-1)))
+		}
+	})
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/output"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var upgradeWindowCmd = &cobra.Command{
+	Use:   "upgrade-window <worktree-name>",
+	Short: "Apply configuration changes to a worktree's open window",
+	Long: `Bring an open worktree window in line with the current .kohconfig
+without disturbing what is already running.
+
+Each configured pane command is compared with the command koh last sent to
+that pane:
+  - Panes missing from the window are added and get their command
+  - Idle panes (sitting at a shell prompt) that never got a command get it now
+  - Panes running something else, or whose command changed, are left alone
+
+The setup script is never re-run. Use --dry-run to only show the plan.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runUpgradeWindow,
+}
+
+// upgradeWindowDryRun shows the plan without changing the window
+var upgradeWindowDryRun bool
+
+func init() {
+	upgradeWindowCmd.Flags().BoolVar(&upgradeWindowDryRun, "dry-run", false, "Show what would change without touching the window")
+	rootCmd.AddCommand(upgradeWindowCmd)
+}
+
+// Actions taken for a pane by 'koh upgrade-window'
+const (
+	paneUnchanged = "unchanged"
+	paneAdded     = "added"
+	paneSent      = "sent"
+	paneSkipped   = "skipped"
+)
+
+// paneUpgrade is the planned (or applied) change for one configured pane
+type paneUpgrade struct {
+	Pane    int    `json:"pane"`
+	Command string `json:"command"`
+	Action  string `json:"action"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// upgradeWindowResult is the machine-readable result of 'koh upgrade-window'
+type upgradeWindowResult struct {
+	Name   string        `json:"name"`
+	DryRun bool          `json:"dry_run"`
+	Panes  []paneUpgrade `json:"panes"`
+}
+
+// planWindowUpgrade compares the configured pane commands with the window's
+// panes and the commands last sent to them, and decides what to do per pane
+func planWindowUpgrade(desired []tmux.PaneCommand, panes []tmux.PaneInfo, recorded map[int]string) []paneUpgrade {
+	byNumber := map[int]tmux.PaneInfo{}
+	for _, pane := range panes {
+		byNumber[pane.Pane] = pane
+	}
+
+	plan := []paneUpgrade{}
+	for _, pc := range desired {
+		step := paneUpgrade{Pane: pc.Pane, Command: pc.Command}
+		pane, open := byNumber[pc.Pane]
+
+		switch {
+		case !open:
+			step.Action = paneAdded
+		case recorded[pc.Pane] == pc.Command:
+			step.Action = paneUnchanged
+		case pc.Pane == 0:
+			step.Action = paneSkipped
+			step.Detail = "the setup script only runs when the window is created"
+		case recorded[pc.Pane] != "":
+			step.Action = paneSkipped
+			step.Detail = fmt.Sprintf("pane was started with %q and is left running", recorded[pc.Pane])
+		case !tmux.IsShell(pane.CurrentCommand):
+			step.Action = paneSkipped
+			step.Detail = fmt.Sprintf("pane is busy running %s", pane.CurrentCommand)
+		default:
+			step.Action = paneSent
+		}
+
+		plan = append(plan, step)
+	}
+	return plan
+}
+
+// worktreeWindowConfig returns the window configuration and directory of a
+// worktree, handling the main checkout, and the environment to set in panes
+// added to its window
+func worktreeWindowConfig(p *output.Printer, mainRepoRoot, worktreeName string) (*config.Config, string, []string, error) {
+	if worktreeName == mainCheckoutName {
+		checkout, mainPath := resolveMainCheckout(mainRepoRoot)
+		return checkout.WindowConfig().RenderPaneCommands(config.PaneTemplateData{}), mainPath, nil, nil
+	}
+
+	worktreePath := kohWorktreePath(mainRepoRoot, worktreeName)
+	if _, err := os.Stat(worktreePath); err != nil {
+		return nil, "", nil, fmt.Errorf("worktree %s does not exist", worktreeLabel(mainRepoRoot, worktreeName))
+	}
+
+	cfg, err := loadNewConfig(false)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if cfg, err = worktreeProfileConfig(cfg, worktreeName); err != nil {
+		return nil, "", nil, err
+	}
+
+	env, err := ensureScratch(mainRepoRoot, worktreeName)
+	if err != nil {
+		p.Warn("%v", err)
+	}
+	cfg, fileVars := renderWindowConfig(cfg, worktreeName, mainRepoRoot)
+	return cfg, worktreePath, append(env, fileVars...), nil
+}
+
+func runUpgradeWindow(cmd *cobra.Command, args []string) error {
+	worktreeName := args[0]
+	p := newPrinter(cmd)
+
+	if err := validation.ValidateWorktreeName(worktreeName); err != nil {
+		return fmt.Errorf("invalid worktree name: %w", err)
+	}
+	if !tmux.IsInTmux() {
+		return fmt.Errorf("not in a tmux session\nPlease run this command from within a tmux session")
+	}
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+
+	mainRepoRoot, err := git.GetMainRepoRootOrCwd()
+	if err != nil {
+		return fmt.Errorf("failed to get repository root: %w", err)
+	}
+
+	cfg, dir, env, err := worktreeWindowConfig(p, mainRepoRoot, worktreeName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	panes, err := tmux.ListWindowPanesWithContext(ctx, worktreeName)
+	if err != nil {
+		return fmt.Errorf("%w\nUse 'koh switch %s' to open it", err, worktreeName)
+	}
+
+	result := upgradeWindowResult{
+		Name:   worktreeName,
+		DryRun: upgradeWindowDryRun,
+		Panes:  planWindowUpgrade(tmux.PaneCommands(cfg), panes, lastPaneCommands(worktreeName)),
+	}
+
+	if !upgradeWindowDryRun {
+		var sent []tmux.PaneCommand
+		for i, step := range result.Panes {
+			if step.Action != paneAdded && step.Action != paneSent {
+				continue
+			}
+
+			pane := step.Pane
+			if step.Action == paneAdded {
+				if pane, err = tmux.AddPaneWithContext(ctx, worktreeName, dir, env...); err != nil {
+					return fmt.Errorf("failed to add pane: %w", err)
+				}
+				// Number it like the pane command it runs, so the next
+				// upgrade recognizes it
+				if pane, err = renumberPane(ctx, worktreeName, pane, step.Pane); err != nil {
+					p.Warn("%v", err)
+				}
+				result.Panes[i].Pane = pane
+			}
+			if err := tmux.SendToPaneWithContext(ctx, worktreeName, pane, step.Command); err != nil {
+				return err
+			}
+			sent = append(sent, tmux.PaneCommand{Pane: pane, Command: step.Command})
+		}
+		recordSentCommands(worktreeName, sent)
+	}
+
+	return p.Result(result, func(w io.Writer) {
+		if len(result.Panes) == 0 {
+			fprintln(w, styles.Muted.Render("No pane commands configured"))
+			return
+		}
+		for _, step := range result.Panes {
+			fprintln(w, renderPaneUpgrade(step, result.DryRun))
+		}
+	})
+}
+
+// renderPaneUpgrade renders one line of the upgrade plan for humans
+func renderPaneUpgrade(step paneUpgrade, dryRun bool) string {
+	var label string
+	switch step.Action {
+	case paneAdded:
+		label = styles.SuccessMessage.Render("+ add pane")
+		if !dryRun {
+			label = styles.SuccessMessage.Render("+ added pane")
+		}
+	case paneSent:
+		label = styles.SuccessMessage.Render(styles.IconArrow + " send")
+		if !dryRun {
+			label = styles.SuccessMessage.Render(styles.IconArrow + " sent")
+		}
+	case paneSkipped:
+		label = styles.WarningMessage.Render("- skipped")
+	default:
+		label = styles.Muted.Render(styles.IconCheck + " unchanged")
+	}
+
+	line := fmt.Sprintf("pane %d  %s  %s", step.Pane, label, step.Command)
+	if step.Detail != "" {
+		line += " " + styles.Muted.Render("("+step.Detail+")")
+	}
+	return line
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestPlanWindowUpgrade(t *testing.T) {
+	desired := []tmux.PaneCommand{
+		{Pane: 0, Command: "./bin/setup-v2"},
+		{Pane: 1, Command: "npm run dev"},
+		{Pane: 2, Command: "npm run worker"},
+		{Pane: 3, Command: "vim"},
+		{Pane: 4, Command: "htop"},
+		{Pane: 5, Command: "npm test -- --watch"},
+	}
+	panes := []tmux.PaneInfo{
+		{Pane: 0, CurrentCommand: "zsh"},
+		{Pane: 1, CurrentCommand: "node"},
+		{Pane: 2, CurrentCommand: "node"},
+		{Pane: 3, CurrentCommand: "zsh"},
+		{Pane: 4, CurrentCommand: "less"},
+	}
+	recorded := map[int]string{
+		0: "./bin/setup",
+		1: "npm run dev",
+		2: "npm run jobs",
+	}
+
+	plan := planWindowUpgrade(desired, panes, recorded)
+
+	want := []string{paneSkipped, paneUnchanged, paneSkipped, paneSent, paneSkipped, paneAdded}
+	if len(plan) != len(want) {
+		t.Fatalf("Expected %d steps, got %+v", len(want), plan)
+	}
+	for i, action := range want {
+		if plan[i].Action != action {
+			t.Errorf("pane %d: expected %s, got %s (%s)", plan[i].Pane, action, plan[i].Action, plan[i].Detail)
+		}
+	}
+}
+
+func TestPlanWindowUpgradeClosedPane(t *testing.T) {
+	desired := []tmux.PaneCommand{
+		{Pane: 0, Command: "./bin/setup"},
+		{Pane: 1, Command: "npm run dev"},
+		{Pane: 2, Command: "vim"},
+	}
+	// Pane 1 was closed; pane 3 was split off by hand
+	panes := []tmux.PaneInfo{
+		{Pane: 0, CurrentCommand: "zsh"},
+		{Pane: 2, CurrentCommand: "vim"},
+		{Pane: 3, CurrentCommand: "zsh"},
+	}
+	recorded := map[int]string{0: "./bin/setup", 1: "npm run dev", 2: "vim"}
+
+	plan := planWindowUpgrade(desired, panes, recorded)
+
+	want := []string{paneUnchanged, paneAdded, paneUnchanged}
+	for i, action := range want {
+		if plan[i].Action != action {
+			t.Errorf("pane %d: expected %s, got %s (%s)", plan[i].Pane, action, plan[i].Action, plan[i].Detail)
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
 
+	"github.com/bshakr/koh/internal/git"
 	"github.com/bshakr/koh/internal/styles"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -13,35 +20,124 @@ import (
 // go build -ldflags="-X github.com/bshakr/koh/cmd.Version=v1.0.0"
 var Version = "0.1.0"
 
+// Commit and BuildDate describe the build and are set with ldflags like
+// Version. Commit falls back to the VCS revision Go embeds in the binary.
+var (
+	Commit    = ""
+	BuildDate = ""
+)
+
+const (
+	// minGitVersion is the oldest git koh supports ('git worktree remove')
+	minGitVersion = "2.17"
+	// minTmuxVersion is the oldest tmux koh supports
+	minTmuxVersion = "3.0"
+)
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Display koh version",
-	Long:  `Display the current version of koh.`,
-	RunE:  runVersion,
+	Long: `Display the current version of koh.
+
+With --json, also prints the commit and build date of the binary and the
+minimum git and tmux versions it needs, for packaging and support scripts.`,
+	RunE: runVersion,
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
 
-func runVersion(cmd *cobra.Command, args []string) error {
-	// Get terminal width
-	terminalWidth := styles.GetTerminalWidth()
+// versionInfo is the machine-readable result of 'koh version'
+type versionInfo struct {
+	Version        string `json:"version"`
+	Commit         string `json:"commit,omitempty"`
+	BuildDate      string `json:"build_date,omitempty"`
+	MinGitVersion  string `json:"min_git_version"`
+	MinTmuxVersion string `json:"min_tmux_version"`
+}
+
+// buildVersionInfo describes the running binary
+func buildVersionInfo() versionInfo {
+	info := versionInfo{
+		Version:        Version,
+		Commit:         Commit,
+		BuildDate:      BuildDate,
+		MinGitVersion:  minGitVersion,
+		MinTmuxVersion: minTmuxVersion,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// toolVersionPattern matches the first dotted version number in tool output,
+// e.g. "2.43.0" in "git version 2.43.0" or "3.3" in "tmux 3.3a"
+var toolVersionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// parseToolVersion extracts the version number from "git --version" or
+// "tmux -V" output, returning "" when there is none (e.g. "tmux master")
+func parseToolVersion(output string) string {
+	return toolVersionPattern.FindString(output)
+}
+
+// gitAtLeast reports whether the installed git is at least min. A version
+// that can't be told, such as a development build, counts as recent enough.
+func gitAtLeast(ctx context.Context, min string) bool {
+	output, err := git.VersionWithContext(ctx)
+	if err != nil {
+		return false
+	}
+	version := parseToolVersion(output)
+	return version == "" || versionAtLeast(version, min)
+}
+
+// versionAtLeast reports whether the dotted version v is at least min
+func versionAtLeast(v, min string) bool {
+	have := strings.Split(v, ".")
+	want := strings.Split(min, ".")
+	for i := range want {
+		var h int
+		if i < len(have) {
+			h, _ = strconv.Atoi(have[i])
+		}
+		w, _ := strconv.Atoi(want[i])
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+func runVersion(cmd *cobra.Command, _ []string) error {
+	info := buildVersionInfo()
 
-	// Create version display
-	versionText := fmt.Sprintf("koh version %s", Version)
-	styledVersion := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(styles.Primary).
-		Render(versionText)
+	return newPrinter(cmd).Result(info, func(w io.Writer) {
+		// Get terminal width
+		terminalWidth := styles.GetTerminalWidth()
 
-	// Center the output
-	centered := lipgloss.NewStyle().
-		Align(lipgloss.Center).
-		Width(terminalWidth).
-		Render(styledVersion)
+		// Create version display
+		versionText := fmt.Sprintf("koh version %s", info.Version)
+		styledVersion := lipgloss.NewStyle().
+			Bold(true).
+			Foreground(styles.Primary).
+			Render(versionText)
 
-	fmt.Println("\n" + centered + "\n")
+		// Center the output
+		centered := lipgloss.NewStyle().
+			Align(lipgloss.Center).
+			Width(terminalWidth).
+			Render(styledVersion)
 
-	return nil
+		fprintln(w, "\n"+centered+"\n")
+	})
 }
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/paths"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep the worktree cache up to date as the repository changes",
+	Long: `Watch the repository for worktrees and branches being added, removed or
+moved, and refresh koh's cache right away. While it runs, 'koh list',
+'koh prompt' and the dashboard always find the cache current and never
+have to ask git for the worktree list themselves.
+
+Watching is opt-in. It runs in the foreground until interrupted; with
+--detach it runs in the background, logging to watch.log in koh's log
+directory, until stopped with 'koh watch --stop'. One watcher runs per
+repository.`,
+	Args:         cobra.NoArgs,
+	RunE:         runWatch,
+	SilenceUsage: true,
+}
+
+var (
+	// watchDetach runs the watcher in the background
+	watchDetach bool
+	// watchStop stops the repository's background watcher
+	watchStop bool
+)
+
+func init() {
+	watchCmd.Flags().BoolVar(&watchDetach, "detach", false, "Run the watcher in the background")
+	watchCmd.Flags().BoolVar(&watchStop, "stop", false, "Stop the watcher running for this repository")
+	watchCmd.MarkFlagsMutuallyExclusive("detach", "stop")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchResult is the machine-readable result of 'koh watch --detach' and
+// 'koh watch --stop'
+type watchResult struct {
+	PID     int  `json:"pid"`
+	Running bool `json:"running"`
+}
+
+// runningWatcher returns the process ID recorded in a watcher pidfile, or 0
+// when no watcher is running. A pidfile left behind by a watcher that was
+// killed doesn't count, even once its process ID is reused.
+func runningWatcher(pidFile string) int {
+	//nolint:gosec // G304: the pidfile lives in koh's own cache directory
+	f, err := os.Open(pidFile)
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 || !pidFileLocked(f, pid) {
+		return 0
+	}
+	return pid
+}
+
+// recordWatcher writes the current process to a watcher pidfile and locks
+// it, returning the function that removes it again
+func recordWatcher(pidFile string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(pidFile), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	//nolint:gosec // G304: the pidfile lives in koh's own cache directory
+	f, err := os.OpenFile(pidFile, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the watcher: %w", err)
+	}
+	locked, err := lockPIDFile(f)
+	if err != nil || !locked {
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", pidFile, err)
+		}
+		return nil, fmt.Errorf("a watcher is already running for this repository\nRun 'koh watch --stop' to stop it")
+	}
+	if err := f.Truncate(0); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to record the watcher: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to record the watcher: %w", err)
+	}
+	return func() {
+		_ = os.Remove(pidFile)
+		_ = f.Close()
+	}, nil
+}
+
+func runWatch(cmd *cobra.Command, _ []string) error {
+	p := newPrinter(cmd)
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+	mainRepoRoot := filepath.Dir(info.CommonDir)
+
+	pidFile, err := cache.WatcherPIDFile(info.CommonDir)
+	if err != nil {
+		return err
+	}
+	pid := runningWatcher(pidFile)
+
+	switch {
+	case watchStop:
+		if pid == 0 {
+			return fmt.Errorf("no watcher is running for this repository")
+		}
+		if err := stopProcess(pid); err != nil {
+			return fmt.Errorf("failed to stop the watcher (pid %d): %w", pid, err)
+		}
+		return p.Result(watchResult{PID: pid}, func(w io.Writer) {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Stopped the watcher (pid %d)", pid)))
+		})
+	case pid != 0:
+		return fmt.Errorf("a watcher is already running for this repository (pid %d)\nRun 'koh watch --stop' to stop it", pid)
+	case watchDetach:
+		pid, logFile, err := startDetachedWatcher(mainRepoRoot)
+		if err != nil {
+			return err
+		}
+		return p.Result(watchResult{PID: pid, Running: true}, func(w io.Writer) {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Watching %s in the background (pid %d)", filepath.Base(mainRepoRoot), pid)))
+			fprintln(w, styles.Muted.Render("Logging to "+logFile+"; run 'koh watch --stop' to stop it"))
+		})
+	}
+
+	// Git runs from the main checkout, which outlives any worktree the
+	// watcher was started in
+	if err := os.Chdir(mainRepoRoot); err != nil {
+		return fmt.Errorf("failed to change to %s: %w", mainRepoRoot, err)
+	}
+
+	removePIDFile, err := recordWatcher(pidFile)
+	if err != nil {
+		return err
+	}
+	defer removePIDFile()
+
+	refresh := func() {
+		if err := cache.Refresh(ctx, info.CommonDir); err != nil && ctx.Err() == nil {
+			p.Warn("Failed to refresh the cache: %v", err)
+		}
+	}
+	refresh()
+
+	p.Info("Watching %s for changes; press Ctrl+C to stop", filepath.Base(mainRepoRoot))
+	return watch.Run(ctx, info.CommonDir, config.WorktreeDir(mainRepoRoot), watch.DefaultDebounce, refresh)
+}
+
+// startDetachedWatcher starts 'koh watch' for the repository at
+// mainRepoRoot in the background, returning its process ID and log file
+func startDetachedWatcher(mainRepoRoot string) (int, string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, "", err
+	}
+
+	logDir, err := paths.LogDir()
+	if err != nil {
+		return 0, "", err
+	}
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return 0, "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFile := filepath.Join(logDir, "watch.log")
+	//nolint:gosec // G304: the log file lives in koh's own log directory
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open %s: %w", logFile, err)
+	}
+	defer func() { _ = log.Close() }()
+
+	//nolint:gosec // G204: runs koh's own executable
+	cmd := exec.Command(executable, "watch")
+	cmd.Dir = mainRepoRoot
+	cmd.Stdout = log
+	cmd.Stderr = log
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, "", fmt.Errorf("failed to start the watcher: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	return pid, logFile, cmd.Process.Release()
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// detachProcess is a no-op where processes don't share the terminal's session
+func detachProcess(_ *exec.Cmd) {}
+
+// processAlive reports whether a process with the given ID is running
+func processAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
+
+// stopProcess kills the process with the given ID
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// lockPIDFile doesn't lock where there is no flock; the process ID in the
+// pidfile is all there is to go on
+func lockPIDFile(_ *os.File) (bool, error) {
+	return true, nil
+}
+
+// pidFileLocked reports whether the watcher recorded in a pidfile is running
+func pidFileLocked(_ *os.File, pid int) bool {
+	return processAlive(pid)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestRunningWatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		contents string
+		want     int
+	}{
+		// The process is alive, but it isn't holding the pidfile's lock,
+		// as when a killed watcher's process ID was reused
+		{name: "stale", contents: strconv.Itoa(os.Getpid()) + "\n", want: 0},
+		{name: "garbage", contents: "not a pid", want: 0},
+		{name: "missing", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "stale" && runtime.GOOS == "windows" {
+				t.Skip("pidfiles aren't locked without flock")
+			}
+			pidFile := filepath.Join(dir, tt.name+".pid")
+			if tt.contents != "" {
+				if err := os.WriteFile(pidFile, []byte(tt.contents), 0o600); err != nil {
+					t.Fatalf("Failed to write pidfile: %v", err)
+				}
+			}
+			if got := runningWatcher(pidFile); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecordWatcher(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "watch", "watcher.pid")
+
+	remove, err := recordWatcher(pidFile)
+	if err != nil {
+		t.Fatalf("recordWatcher() failed: %v", err)
+	}
+	if got := runningWatcher(pidFile); got != os.Getpid() {
+		t.Errorf("Expected the recorded watcher %d to be running, got %d", os.Getpid(), got)
+	}
+	if runtime.GOOS != "windows" {
+		if _, err := recordWatcher(pidFile); err == nil {
+			t.Error("Expected a second watcher to be refused")
+		}
+	}
+
+	remove()
+	if got := runningWatcher(pidFile); got != 0 {
+		t.Errorf("Expected no watcher once removed, got %d", got)
+	}
+}
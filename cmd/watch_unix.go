@@ -0,0 +1,48 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess makes cmd run in its own session, so it outlives the
+// terminal that started it
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive reports whether a process with the given ID is running
+func processAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+// stopProcess asks the process with the given ID to exit
+func stopProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// lockPIDFile takes the lock a watcher holds on its pidfile for as long as it
+// runs, without waiting, and reports whether it got it. The system releases
+// the lock when the watcher exits, however it exits.
+func lockPIDFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pidFileLocked reports whether a running watcher holds the lock on its
+// pidfile, so the process ID in it still belongs to the watcher rather than
+// to a process that reused it
+func pidFileLocked(f *os.File, _ int) bool {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+	if err == nil {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}
+	return errors.Is(err, syscall.EWOULDBLOCK)
+}
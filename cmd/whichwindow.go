@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/bshakr/koh/internal/tmux"
+	"github.com/bshakr/koh/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var whichWindowCmd = &cobra.Command{
+	Use:   "which-window <worktree-name>",
+	Short: "Show the tmux window of a worktree",
+	Long: `Print the tmux identifiers of a worktree's window, so raw tmux commands
+such as swap-window, link-window or join-pane can target it.
+
+The window ID (e.g. @3) is unique across the tmux server and survives renames
+and moves, so it is the safest -t target:
+  tmux link-window -s "$(koh which-window feature --format '{{.ID}}')" -t other:
+
+Use --format with a Go template to pick fields (ID, Name, Index, SessionID,
+SessionName, Active, Panes). Exits with a non-zero status when the worktree
+has no open window.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeNames,
+	RunE:              runWhichWindow,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+// whichWindowFormat is a Go template used to render the window
+var whichWindowFormat string
+
+func init() {
+	whichWindowCmd.Flags().StringVar(&whichWindowFormat, "format", "", "Go template for output (fields: ID, Name, Index, SessionID, SessionName, Active, Panes)")
+	rootCmd.AddCommand(whichWindowCmd)
+}
+
+// formatWindow renders a window with a Go template
+func formatWindow(window *tmux.Window, format string) (string, error) {
+	tmpl, err := template.New("which-window").Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid format template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, window); err != nil {
+		return "", fmt.Errorf("failed to render format template: %w", err)
+	}
+	return b.String(), nil
+}
+
+func runWhichWindow(cmd *cobra.Command, args []string) error {
+	worktreeName := args[0]
+	if worktreeName != mainCheckoutName {
+		if err := validation.ValidateWorktreeName(worktreeName); err != nil {
+			return fmt.Errorf("invalid worktree name: %w", err)
+		}
+	}
+	if !tmux.IsInTmux() {
+		return fmt.Errorf("not in a tmux session\nPlease run this command from within a tmux session")
+	}
+
+	window, err := tmux.WindowInfoWithContext(context.Background(), worktreeName)
+	if err != nil {
+		return err
+	}
+	if window == nil {
+		return fmt.Errorf("%s has no open window\nUse 'koh switch %s' to open it", worktreeName, worktreeName)
+	}
+
+	if whichWindowFormat != "" {
+		out, err := formatWindow(window, whichWindowFormat)
+		if err != nil {
+			return err
+		}
+		fprintln(cmd.OutOrStdout(), out)
+		return nil
+	}
+
+	return newPrinter(cmd).Result(window, func(w io.Writer) {
+		fprintln(w, styles.RenderKeyValue("Window", window.ID))
+		fprintln(w, styles.RenderKeyValue("Name", window.Name))
+		fprintln(w, styles.RenderKeyValue("Index", strconv.Itoa(window.Index)))
+		fprintln(w, styles.RenderKeyValue("Session", fmt.Sprintf("%s (%s)", window.SessionName, window.SessionID)))
+		fprintln(w, styles.RenderKeyValue("Panes", strings.Join(window.Panes, " ")))
+	})
+}
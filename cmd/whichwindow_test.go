@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bshakr/koh/internal/tmux"
+)
+
+func TestFormatWindow(t *testing.T) {
+	window := &tmux.Window{ID: "@3", Name: "repo|feature", Index: 2, SessionID: "$1", SessionName: "work", Panes: []string{"%4", "%5"}}
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "{{.ID}}", want: "@3"},
+		{format: "{{.SessionName}}:{{.Index}}", want: "work:2"},
+		{format: "{{index .Panes 1}}", want: "%5"},
+		{format: "{{.ID", wantErr: true},
+		{format: "{{.Missing}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := formatWindow(window, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("formatWindow(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("formatWindow(%q): Expected %q, got %q", tt.format, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bshakr/koh/internal/cache"
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/signals"
+	"github.com/bshakr/koh/internal/state"
+	"github.com/bshakr/koh/internal/styles"
+	"github.com/spf13/cobra"
+)
+
+var wipCmd = &cobra.Command{
+	Use:   "wip",
+	Short: "Save the current worktree's uncommitted changes as work in progress",
+	Long: `Save every uncommitted change of the worktree you're in, untracked files
+included, in one step: as a WIP commit, or with --stash as a stash entry.
+Commit hooks are skipped.
+
+koh records what it saved, and 'koh unwip' brings the changes back as
+uncommitted changes. Saves stack: each 'koh unwip' undoes the latest one.`,
+	Args: cobra.NoArgs,
+	RunE: runWIP,
+}
+
+var (
+	// wipStash stashes the changes instead of committing them
+	wipStash bool
+	// wipNote is added to the WIP message
+	wipNote string
+)
+
+func init() {
+	wipCmd.Flags().BoolVar(&wipStash, "stash", false, "Stash the changes instead of committing them")
+	wipCmd.Flags().StringVarP(&wipNote, "message", "m", "", "Note added to the WIP message")
+	rootCmd.AddCommand(wipCmd)
+}
+
+// wipResult is the machine-readable result of 'koh wip' and 'koh unwip'
+type wipResult struct {
+	Name    string `json:"name"`
+	Stash   bool   `json:"stash"`
+	Commit  string `json:"commit"`
+	Message string `json:"message"`
+}
+
+// wipMessage is the message of stashes and commits koh makes to save work in
+// progress in a worktree
+const wipMessage = "WIP"
+
+// wipMessageFor returns the message of a WIP commit or stash, e.g. "WIP: fix login"
+func wipMessageFor(note string) string {
+	if note == "" {
+		return wipMessage
+	}
+	return wipMessage + ": " + note
+}
+
+// saveWIP commits or stashes the uncommitted changes of the checkout info
+// points at and records it for 'koh unwip'
+func saveWIP(ctx context.Context, info *git.RepoInfo, stash bool, message string) (*state.WIP, error) {
+	dirty, err := git.IsDirty(info.TopLevel)
+	if err != nil {
+		return nil, err
+	}
+	if !dirty {
+		return nil, fmt.Errorf("%s has no uncommitted changes to save", currentCheckoutName(info))
+	}
+
+	wip := state.WIP{At: time.Now(), Stash: stash, Message: message}
+	if stash {
+		wip.Commit, err = git.StashPushWithContext(ctx, info.TopLevel, message)
+	} else {
+		wip.Commit, err = git.CommitAllWithContext(ctx, info.TopLevel, message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = cache.Invalidate(info.CommonDir)
+
+	name := currentCheckoutName(info)
+	if err := state.Update(info.CommonDir, func(s *state.State) error {
+		wt := s.Worktree(name)
+		wt.WIP = append(wt.WIP, wip)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("saved the changes in %s but failed to record it: %w", name, err)
+	}
+	return &wip, nil
+}
+
+func runWIP(cmd *cobra.Command, _ []string) error {
+	p := newPrinter(cmd)
+
+	ctx, cleanup := signals.SetupCancellableContext()
+	defer cleanup()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("not in a git repository\nPlease run this command from within a git repository")
+	}
+
+	if !wipStash {
+		warnReadonlyCommit(p, currentCheckoutName(info))
+	}
+	wip, err := saveWIP(ctx, info, wipStash, wipMessageFor(wipNote))
+	if err != nil {
+		return err
+	}
+
+	result := wipResult{Name: currentCheckoutName(info), Stash: wip.Stash, Commit: wip.Commit, Message: wip.Message}
+	return p.Result(result, func(w io.Writer) {
+		if result.Stash {
+			fprintln(w, styles.RenderSuccess("Stashed the changes in "+result.Name))
+		} else {
+			fprintln(w, styles.RenderSuccess(fmt.Sprintf("Committed the changes in %s as %s", result.Name, shortCommit(result.Commit))))
+		}
+		fprintln(w, styles.Muted.Render("Run 'koh unwip' to bring them back"))
+	})
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
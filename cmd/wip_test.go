@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/state"
+)
+
+func TestWIPMessageFor(t *testing.T) {
+	if got := wipMessageFor(""); got != "WIP" {
+		t.Errorf("Expected %q, got %q", "WIP", got)
+	}
+	if got := wipMessageFor("fix login"); got != "WIP: fix login" {
+		t.Errorf("Expected %q, got %q", "WIP: fix login", got)
+	}
+}
+
+func TestSaveAndRestoreWIP(t *testing.T) {
+	repo := newDashboardRepo(t)
+	worktree := filepath.Join(repo, ".koh", "feat-a")
+	t.Chdir(worktree)
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	ctx := context.Background()
+
+	info, err := git.RepoInfoWithContext(ctx)
+	if err != nil {
+		t.Fatalf("RepoInfoWithContext() failed: %v", err)
+	}
+	notes := filepath.Join(worktree, "notes.txt")
+	saves := 0
+	makeDirty := func() {
+		saves++
+		if err := os.WriteFile(notes, []byte(fmt.Sprintf("todo %d", saves)), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	restored := func() bool {
+		dirty, err := git.IsDirty(worktree)
+		if err != nil {
+			t.Fatalf("IsDirty() failed: %v", err)
+		}
+		return dirty
+	}
+
+	if _, err := saveWIP(ctx, info, false, "WIP"); err == nil {
+		t.Error("Expected an error with nothing to save")
+	}
+
+	for _, stash := range []bool{false, true} {
+		makeDirty()
+		wip, err := saveWIP(ctx, info, stash, "WIP")
+		if err != nil {
+			t.Fatalf("saveWIP(stash=%v) failed: %v", stash, err)
+		}
+		if restored() {
+			t.Fatalf("Expected the changes to be saved away (stash=%v)", stash)
+		}
+		if err := restoreWIP(ctx, worktree, *wip); err != nil {
+			t.Fatalf("restoreWIP(stash=%v) failed: %v", stash, err)
+		}
+		if !restored() {
+			t.Errorf("Expected the changes to be back (stash=%v)", stash)
+		}
+		if err := os.Remove(notes); err != nil {
+			t.Fatalf("Failed to remove file: %v", err)
+		}
+	}
+
+	s, err := state.Load(info.CommonDir)
+	if err != nil {
+		t.Fatalf("state.Load() failed: %v", err)
+	}
+	if saved := s.Worktree("feat-a").WIP; len(saved) != 2 || saved[0].Stash || !saved[1].Stash {
+		t.Errorf("Expected a commit and a stash to be recorded, got %+v", saved)
+	}
+
+	t.Run("commit no longer the latest", func(t *testing.T) {
+		makeDirty()
+		wip, err := saveWIP(ctx, info, false, "WIP")
+		if err != nil {
+			t.Fatalf("saveWIP() failed: %v", err)
+		}
+		runGit(t, "commit", "-q", "--allow-empty", "-m", "more work")
+		if err := restoreWIP(ctx, worktree, *wip); err == nil {
+			t.Error("Expected an error when the WIP commit isn't the latest")
+		}
+	})
+
+	t.Run("stash dropped", func(t *testing.T) {
+		makeDirty()
+		wip, err := saveWIP(ctx, info, true, "WIP")
+		if err != nil {
+			t.Fatalf("saveWIP() failed: %v", err)
+		}
+		runGit(t, "stash", "drop", "-q")
+		if err := restoreWIP(ctx, worktree, *wip); !errors.Is(err, errWIPGone) {
+			t.Errorf("Expected errWIPGone, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bshakr/koh/internal/config"
+)
+
+// kohWorktreePath returns the path of the worktree called name, in the
+// repository's worktree directory (.koh unless worktree_dir says otherwise)
+func kohWorktreePath(mainRepoRoot, name string) string {
+	return filepath.Join(config.WorktreeDir(mainRepoRoot), name)
+}
+
+// inWorktreeDir reports whether path is a worktree directly inside the
+// worktree directory dir. Symlinks are resolved, since git records the path a
+// worktree was created with, e.g. through a symlinked home directory.
+func inWorktreeDir(dir, path string) bool {
+	if filepath.Dir(path) == dir {
+		return true
+	}
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	resolvedPath, err := filepath.EvalSymlinks(filepath.Dir(path))
+	return err == nil && resolvedPath == resolvedDir
+}
+
+// worktreeLabel returns how messages refer to the worktree called name: its
+// path relative to the repository root, e.g. ".koh/feature", or its full
+// path when the worktree directory is outside the repository
+func worktreeLabel(mainRepoRoot, name string) string {
+	path := kohWorktreePath(mainRepoRoot, name)
+	rel, err := filepath.Rel(mainRepoRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorktreeLabel(t *testing.T) {
+	repo := t.TempDir()
+	if got := worktreeLabel(repo, "feature"); got != filepath.Join(".koh", "feature") {
+		t.Errorf("Expected .koh/feature, got %s", got)
+	}
+
+	outside := filepath.Join(t.TempDir(), "trees")
+	if err := os.WriteFile(filepath.Join(repo, ".kohconfig"), []byte(`{"worktree_dir": "`+outside+`"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if got, want := worktreeLabel(repo, "feature"), filepath.Join(outside, "feature"); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestInWorktreeDir(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/repo/.koh/feature", want: true},
+		{path: "/repo/.koh", want: false},
+		{path: "/repo/.koh/scratch/feature", want: false},
+		{path: "/elsewhere/.koh/feature", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := inWorktreeDir("/repo/.koh", tt.path); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWorktreeDirOutsideRepo(t *testing.T) {
+	repo := newDashboardRepo(t)
+	trees, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".kohconfig"), []byte(`{"worktree_dir": "`+trees+`"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	runGit(t, "worktree", "add", "-q", "-b", "feat-b", filepath.Join(trees, "feat-b"))
+
+	worktrees, err := loadKohWorktrees(context.Background())
+	if err != nil {
+		t.Fatalf("loadKohWorktrees() failed: %v", err)
+	}
+	if len(worktrees) != 1 || worktrees[0].Path != filepath.Join(trees, "feat-b") {
+		t.Errorf("Expected only feat-b from the configured directory, got %+v", worktrees)
+	}
+
+	t.Chdir(filepath.Join(trees, "feat-b"))
+	current, err := detectCurrentWorktree()
+	if err != nil {
+		t.Fatalf("detectCurrentWorktree() failed: %v", err)
+	}
+	if current.Name != "feat-b" {
+		t.Errorf("Expected the current worktree to be feat-b, got %q", current.Name)
+	}
+}
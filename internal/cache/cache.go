@@ -0,0 +1,361 @@
+// Package cache stores the results of expensive git queries on disk.
+//
+// Commands that need to be fast (prompt, current, the dashboard, completion)
+// read worktree and branch data through this package instead of calling git
+// every time. Entries live in the user's cache directory, one file per
+// repository and kind of data, keyed by the repository's common git directory.
+//
+// Invalidation:
+// Each entry records a fingerprint built from modification times of the files
+// git touches when the cached data changes:
+//   - Worktrees: the repository HEAD, the .git/worktrees directory and every
+//     worktree's HEAD, so creating or removing a worktree or checking out a
+//     different branch changes the fingerprint
+//   - Refs: packed-refs and every directory under refs/heads and refs/remotes,
+//     which change whenever a branch is created, updated or fetched
+//
+// Stale entries are therefore never served. Commands that mutate worktrees
+// also call Invalidate explicitly.
+//
+// Data that lives outside the repository, such as pull request status from the
+// forge, has no fingerprint to check. It is cached with Recent, which serves
+// an entry only while it is younger than a short time-to-live.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/paths"
+)
+
+// Disabled bypasses the cache entirely when set (e.g. via --no-cache)
+var Disabled bool
+
+// Kinds of cached data, used as cache file suffixes
+const (
+	kindWorktrees = "worktrees"
+	kindRefs      = "refs"
+	// KindDiskUsage holds worktree sizes cached with Recent. Invalidate drops
+	// it too, so created worktrees are measured right away.
+	KindDiskUsage = "disk_usage"
+	// KindDirty holds which worktrees have uncommitted changes, cached with
+	// Recent for shell completions
+	KindDirty = "dirty"
+)
+
+// entry is the on-disk representation of cached data for a repository
+type entry[T any] struct {
+	Fingerprint string `json:"fingerprint"`
+	Items       []T    `json:"items"`
+}
+
+// Dir returns the directory where cache entries are stored (see paths.CacheDir)
+func Dir() (string, error) {
+	return paths.CacheDir()
+}
+
+// entryPath returns the cache file for a kind of data in the repository
+// with the given common dir
+func entryPath(commonDir, kind string) (string, error) {
+	return repoFile(commonDir, kind+".json")
+}
+
+// repoFile returns a file named name kept in the cache directory for the
+// repository with the given common dir
+func repoFile(commonDir, name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(commonDir))
+	return filepath.Join(dir, "git", hex.EncodeToString(sum[:8])+"-"+name), nil
+}
+
+// ServerSocket returns the unix socket the repository's 'koh serve' process
+// listens on
+func ServerSocket(commonDir string) (string, error) {
+	return repoFile(commonDir, "serve.sock")
+}
+
+// WatcherPIDFile returns the file holding the process ID of the repository's
+// 'koh watch' process
+func WatcherPIDFile(commonDir string) (string, error) {
+	return repoFile(commonDir, "watch.pid")
+}
+
+// stamper collects modification times of files into a fingerprint
+type stamper struct {
+	parts []string
+}
+
+// stamp records the modification time of path, or a placeholder when missing
+func (s *stamper) stamp(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		s.parts = append(s.parts, "-")
+		return
+	}
+	s.parts = append(s.parts, fmt.Sprintf("%d", info.ModTime().UnixNano()))
+}
+
+func (s *stamper) String() string {
+	return strings.Join(s.parts, ":")
+}
+
+// worktreesFingerprint summarizes the repository state that affects the worktree list
+func worktreesFingerprint(commonDir string) string {
+	var s stamper
+
+	s.stamp(filepath.Join(commonDir, "HEAD"))
+
+	worktreesDir := filepath.Join(commonDir, "worktrees")
+	s.stamp(worktreesDir)
+
+	entries, err := os.ReadDir(worktreesDir)
+	if err == nil {
+		for _, e := range entries {
+			s.parts = append(s.parts, e.Name())
+			s.stamp(filepath.Join(worktreesDir, e.Name(), "HEAD"))
+		}
+	}
+
+	return s.String()
+}
+
+// refsFingerprint summarizes the repository state that affects the branch list.
+// Git updates loose refs by renaming a lock file into place, which changes the
+// containing directory's modification time.
+func refsFingerprint(commonDir string) string {
+	var s stamper
+
+	s.stamp(filepath.Join(commonDir, "packed-refs"))
+
+	for _, root := range []string{"refs/heads", "refs/remotes"} {
+		_ = filepath.WalkDir(filepath.Join(commonDir, root), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				s.parts = append(s.parts, path)
+				s.stamp(path)
+			}
+			return nil
+		})
+	}
+
+	return s.String()
+}
+
+// load reads a cached entry, returning nil when missing or unreadable
+func load[T any](commonDir, kind string) *entry[T] {
+	path, err := entryPath(commonDir, kind)
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var e entry[T]
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil
+	}
+	return &e
+}
+
+// store writes a cached entry atomically
+func store[T any](commonDir, kind string, e *entry[T]) error {
+	path, err := entryPath(commonDir, kind)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	return writeFile(path, data)
+}
+
+// writeFile replaces the cache file at path atomically
+func writeFile(path string, data []byte) error {
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace cache file: %w", err)
+	}
+	return nil
+}
+
+// cached serves items from the cache when the fingerprint still matches,
+// otherwise fetches them and refreshes the cache. Cache write failures are
+// ignored since the fresh result is still correct.
+func cached[T any](commonDir, kind, fingerprint string, fetch func() ([]T, error)) ([]T, error) {
+	if Disabled {
+		return fetch()
+	}
+
+	if e := load[T](commonDir, kind); e != nil && e.Fingerprint == fingerprint {
+		return e.Items, nil
+	}
+	return refresh(commonDir, kind, fingerprint, fetch)
+}
+
+// refresh fetches items and stores them in the cache under fingerprint
+func refresh[T any](commonDir, kind, fingerprint string, fetch func() ([]T, error)) ([]T, error) {
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	_ = store(commonDir, kind, &entry[T]{Fingerprint: fingerprint, Items: items})
+	return items, nil
+}
+
+// Refresh queries git for the worktree and branch lists of the repository
+// with the given common git directory and stores them, whether or not the
+// cached entries still look valid. 'koh watch' calls it whenever the
+// repository changes, so other commands find the cache warm.
+// The git queries run in the current directory, which must belong to that repository.
+func Refresh(ctx context.Context, commonDir string) error {
+	// Fingerprints are taken before querying git, so changes made while the
+	// query runs make the entries stale rather than being missed
+	worktreesPrint, refsPrint := worktreesFingerprint(commonDir), refsFingerprint(commonDir)
+
+	if _, err := refresh(commonDir, kindWorktrees, worktreesPrint, func() ([]git.Worktree, error) {
+		return git.ListWorktreesWithContext(ctx)
+	}); err != nil {
+		return err
+	}
+	_, err := refresh(commonDir, kindRefs, refsPrint, func() ([]git.Ref, error) {
+		return git.ListBranchRefsWithContext(ctx)
+	})
+	return err
+}
+
+// Worktrees returns the worktree list for the repository with the given
+// common git directory, serving it from the cache when still valid.
+// The git query runs in the current directory, which must belong to that repository.
+func Worktrees(ctx context.Context, commonDir string) ([]git.Worktree, error) {
+	return cached(commonDir, kindWorktrees, worktreesFingerprint(commonDir), func() ([]git.Worktree, error) {
+		return git.ListWorktreesWithContext(ctx)
+	})
+}
+
+// PeekWorktrees returns the cached worktree list without running git, for
+// callers that would rather show slightly stale data right away. fresh
+// reports whether the entry still matches the repository; ok is false when
+// nothing is cached.
+func PeekWorktrees(commonDir string) (worktrees []git.Worktree, fresh, ok bool) {
+	if Disabled {
+		return nil, false, false
+	}
+	e := load[git.Worktree](commonDir, kindWorktrees)
+	if e == nil {
+		return nil, false, false
+	}
+	return e.Items, e.Fingerprint == worktreesFingerprint(commonDir), true
+}
+
+// Refs returns the local and remote-tracking branches for the repository with
+// the given common git directory, serving them from the cache when still valid.
+// The git query runs in the current directory, which must belong to that repository.
+func Refs(ctx context.Context, commonDir string) ([]git.Ref, error) {
+	return cached(commonDir, kindRefs, refsFingerprint(commonDir), func() ([]git.Ref, error) {
+		return git.ListBranchRefsWithContext(ctx)
+	})
+}
+
+// recentEntry is the on-disk representation of data cached with a time-to-live
+type recentEntry[T any] struct {
+	StoredAt time.Time `json:"stored_at"`
+	Value    T         `json:"value"`
+}
+
+// Recent returns the value cached under kind for the repository with the
+// given common dir if it was stored less than ttl ago, otherwise fetches it
+// and refreshes the cache. It is meant for data not derived from the
+// repository itself, where staleness can't be detected by a fingerprint.
+func Recent[T any](commonDir, kind string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	if Disabled {
+		return fetch()
+	}
+
+	path, err := entryPath(commonDir, kind)
+	if err != nil {
+		return fetch()
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var e recentEntry[T]
+		if json.Unmarshal(data, &e) == nil && time.Since(e.StoredAt) < ttl {
+			return e.Value, nil
+		}
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	if data, err := json.Marshal(recentEntry[T]{StoredAt: time.Now(), Value: value}); err == nil {
+		_ = writeFile(path, data)
+	}
+	return value, nil
+}
+
+// Invalidate removes all cached entries for a repository.
+// It is called by commands that create or remove worktrees or branches.
+func Invalidate(commonDir string) error {
+	for _, kind := range []string{kindWorktrees, kindRefs, KindDiskUsage, KindDirty} {
+		if err := Forget(commonDir, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forget removes the entry cached under kind for a repository, leaving the
+// others alone
+func Forget(commonDir, kind string) error {
+	path, err := entryPath(commonDir, kind)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestWorktreesFingerprintChangesWithHead(t *testing.T) {
+	commonDir := t.TempDir()
+	headPath := filepath.Join(commonDir, "HEAD")
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(headPath, []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write HEAD: %v", err)
+	}
+
+	before := worktreesFingerprint(commonDir)
+	if before != worktreesFingerprint(commonDir) {
+		t.Error("Fingerprint should be stable when nothing changes")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(headPath, later, later); err != nil {
+		t.Fatalf("Failed to touch HEAD: %v", err)
+	}
+	if before == worktreesFingerprint(commonDir) {
+		t.Error("Fingerprint should change when HEAD is modified")
+	}
+
+	if err := os.MkdirAll(filepath.Join(commonDir, "worktrees", "feature"), 0o755); err != nil {
+		t.Fatalf("Failed to create worktrees dir: %v", err)
+	}
+	withWorktree := worktreesFingerprint(commonDir)
+	if withWorktree == worktreesFingerprint(t.TempDir()) {
+		t.Error("Fingerprint should reflect worktree entries")
+	}
+}
+
+func TestWorktreesServesValidCacheAndInvalidates(t *testing.T) {
+	testutil.NewRepo(t)
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	worktrees, err := Worktrees(ctx, commonDir)
+	if err != nil {
+		t.Fatalf("Worktrees() failed: %v", err)
+	}
+	if len(worktrees) == 0 {
+		t.Fatal("Expected at least the main worktree")
+	}
+
+	// Replace the stored entry to prove later reads come from the cache
+	fake := []git.Worktree{{Path: "/cached", Branch: "cached"}}
+	if err := store(commonDir, kindWorktrees, &entry[git.Worktree]{Fingerprint: worktreesFingerprint(commonDir), Items: fake}); err != nil {
+		t.Fatalf("store() failed: %v", err)
+	}
+	cached, err := Worktrees(ctx, commonDir)
+	if err != nil {
+		t.Fatalf("Worktrees() failed: %v", err)
+	}
+	if len(cached) != 1 || cached[0].Path != "/cached" {
+		t.Errorf("Expected cached worktrees, got %+v", cached)
+	}
+
+	// Disabled bypasses the cache
+	Disabled = true
+	fresh, err := Worktrees(ctx, commonDir)
+	Disabled = false
+	if err != nil {
+		t.Fatalf("Worktrees() failed: %v", err)
+	}
+	if len(fresh) > 0 && fresh[0].Path == "/cached" {
+		t.Error("Disabled cache should not serve cached entries")
+	}
+
+	// Invalidate drops the entry
+	if err := Invalidate(commonDir); err != nil {
+		t.Fatalf("Invalidate() failed: %v", err)
+	}
+	if load[git.Worktree](commonDir, kindWorktrees) != nil {
+		t.Error("Expected no cache entry after Invalidate()")
+	}
+}
+
+func TestRefsFingerprintChangesWithBranches(t *testing.T) {
+	commonDir := t.TempDir()
+	headsDir := filepath.Join(commonDir, "refs", "heads")
+	if err := os.MkdirAll(headsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create refs dir: %v", err)
+	}
+
+	before := refsFingerprint(commonDir)
+
+	if err := os.MkdirAll(filepath.Join(headsDir, "feature"), 0o755); err != nil {
+		t.Fatalf("Failed to create nested refs dir: %v", err)
+	}
+	if before == refsFingerprint(commonDir) {
+		t.Error("Fingerprint should change when a ref directory is added")
+	}
+}
+
+func TestRecentExpiresAfterTTL(t *testing.T) {
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	first, err := Recent(commonDir, "test", time.Hour, fetch)
+	if err != nil {
+		t.Fatalf("Recent() failed: %v", err)
+	}
+	second, err := Recent(commonDir, "test", time.Hour, fetch)
+	if err != nil {
+		t.Fatalf("Recent() failed: %v", err)
+	}
+	if first != 1 || second != 1 {
+		t.Errorf("Expected cached value 1 twice, got %d and %d", first, second)
+	}
+
+	expired, err := Recent(commonDir, "test", 0, fetch)
+	if err != nil {
+		t.Fatalf("Recent() failed: %v", err)
+	}
+	if expired != 2 {
+		t.Errorf("Expected a fresh value after TTL, got %d", expired)
+	}
+}
+
+func TestInvalidateDropsDiskUsage(t *testing.T) {
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	if _, err := Recent(commonDir, KindDiskUsage, time.Hour, fetch); err != nil {
+		t.Fatalf("Recent() failed: %v", err)
+	}
+	if err := Invalidate(commonDir); err != nil {
+		t.Fatalf("Invalidate() failed: %v", err)
+	}
+	if got, _ := Recent(commonDir, KindDiskUsage, time.Hour, fetch); got != 2 {
+		t.Errorf("Expected sizes to be measured again after Invalidate(), got %d", got)
+	}
+}
+
+func TestForgetDropsOnlyItsKind(t *testing.T) {
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	for _, kind := range []string{KindDiskUsage, KindDirty} {
+		if _, err := Recent(commonDir, kind, time.Hour, fetch); err != nil {
+			t.Fatalf("Recent(%s) failed: %v", kind, err)
+		}
+	}
+	if err := Forget(commonDir, KindDiskUsage); err != nil {
+		t.Fatalf("Forget() failed: %v", err)
+	}
+	if got, _ := Recent(commonDir, KindDiskUsage, time.Hour, fetch); got != 3 {
+		t.Errorf("Expected sizes to be measured again after Forget(), got %d", got)
+	}
+	if got, _ := Recent(commonDir, KindDirty, time.Hour, fetch); got != 2 {
+		t.Errorf("Expected other kinds to stay cached after Forget(), got %d", got)
+	}
+}
+
+func TestRefreshOverwritesValidEntries(t *testing.T) {
+	testutil.NewRepo(t)
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+
+	fake := []git.Worktree{{Path: "/cached", Branch: "cached"}}
+	if err := store(commonDir, kindWorktrees, &entry[git.Worktree]{Fingerprint: worktreesFingerprint(commonDir), Items: fake}); err != nil {
+		t.Fatalf("store() failed: %v", err)
+	}
+
+	if err := Refresh(context.Background(), commonDir); err != nil {
+		t.Fatalf("Refresh() failed: %v", err)
+	}
+	worktrees, fresh, ok := PeekWorktrees(commonDir)
+	if !ok || !fresh {
+		t.Fatalf("Expected a fresh entry after Refresh(), got fresh=%v ok=%v", fresh, ok)
+	}
+	if len(worktrees) == 0 || worktrees[0].Path == "/cached" {
+		t.Errorf("Expected Refresh() to replace the cached worktrees, got %+v", worktrees)
+	}
+	if load[git.Ref](commonDir, kindRefs) == nil {
+		t.Error("Expected Refresh() to store the branch list")
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// usesName matches {{name}} in a branch_template
+var usesName = regexp.MustCompile(`\{\{-?\s*name\s*-?\}\}`)
+
+// parseBranchTemplate parses a branch_template. {{name}} is the worktree
+// name and {{user}} the git user's handle, looked up only when used.
+func parseBranchTemplate(text string, user func() (string, error)) (*template.Template, error) {
+	return template.New("branch").Funcs(template.FuncMap{
+		"name": func() string { return "" },
+		"user": user,
+	}).Parse(text)
+}
+
+// BranchName returns the branch 'koh new' creates for the worktree name,
+// expanding branch_template. Without a template the branch is named after
+// the worktree. user returns the git user's handle, see git.Identity.
+func (c *Config) BranchName(name string, user func() (string, error)) (string, error) {
+	if c == nil || c.BranchTemplate == "" {
+		return name, nil
+	}
+
+	tmpl, err := parseBranchTemplate(c.BranchTemplate, user)
+	if err != nil {
+		return "", fmt.Errorf("invalid branch_template %q: %w", c.BranchTemplate, err)
+	}
+	tmpl.Funcs(template.FuncMap{"name": func() string { return name }})
+
+	var branch strings.Builder
+	if err := tmpl.Execute(&branch, nil); err != nil {
+		return "", fmt.Errorf("failed to render branch_template %q: %w", c.BranchTemplate, err)
+	}
+	return branch.String(), nil
+}
+
+// lintBranchTemplate returns the problem with a branch_template, "" for none
+func lintBranchTemplate(text string) string {
+	if _, err := parseBranchTemplate(text, func() (string, error) { return "", nil }); err != nil {
+		return fmt.Sprintf("is not a valid template: %v", err)
+	}
+	if !usesName.MatchString(text) {
+		return "does not use {{name}}, so every worktree would get the same branch"
+	}
+	return ""
+}
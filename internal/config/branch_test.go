@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBranchName(t *testing.T) {
+	user := func() (string, error) { return "bshakr", nil }
+	noUser := func() (string, error) { return "", errors.New("no git identity") }
+
+	tests := []struct {
+		name    string
+		cfg     *Config
+		user    func() (string, error)
+		want    string
+		wantErr bool
+	}{
+		{name: "nil config", cfg: nil, user: noUser, want: "login-fix"},
+		{name: "no template", cfg: &Config{}, user: noUser, want: "login-fix"},
+		{name: "user and name", cfg: &Config{BranchTemplate: "{{user}}/{{name}}"}, user: user, want: "bshakr/login-fix"},
+		{name: "user not needed", cfg: &Config{BranchTemplate: "feature/{{name}}"}, user: noUser, want: "feature/login-fix"},
+		{name: "no identity", cfg: &Config{BranchTemplate: "{{user}}/{{name}}"}, user: noUser, wantErr: true},
+		{name: "invalid template", cfg: &Config{BranchTemplate: "{{user}/{{name}}"}, user: user, wantErr: true},
+		{name: "unknown function", cfg: &Config{BranchTemplate: "{{team}}/{{name}}"}, user: user, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.BranchName("login-fix", tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
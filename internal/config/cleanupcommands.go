@@ -0,0 +1,12 @@
+package config
+
+import "strings"
+
+// lintCleanupCommand checks a cleanup command, returning a message when it
+// can't be run
+func lintCleanupCommand(command string) string {
+	if strings.TrimSpace(command) == "" {
+		return "command is empty"
+	}
+	return ""
+}
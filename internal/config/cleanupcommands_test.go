@@ -0,0 +1,18 @@
+package config
+
+import "testing"
+
+func TestLintCleanupCommands(t *testing.T) {
+	cfg := &Config{CleanupCommands: []string{`dropdb "app_$KOH_WORKTREE"`, " ", "docker ps --format '{{.Names}}'"}}
+
+	warnings := cfg.Lint(t.TempDir())
+	want := []string{"cleanup_commands[1]"}
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %v", len(want), warnings)
+	}
+	for i, source := range want {
+		if warnings[i].Source != source {
+			t.Errorf("Expected a warning for %s, got %v", source, warnings[i])
+		}
+	}
+}
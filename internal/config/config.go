@@ -3,7 +3,24 @@
 // Configuration is stored in a .kohconfig file at the repository root.
 // The configuration includes:
 //   - setup_script: Path to a script that runs when creating a worktree
-//   - pane_commands: Commands to run in additional tmux panes
+//   - pane_commands: Commands to run in additional tmux panes, each optionally
+//     with a delay and retries
+//   - wait_for_setup: Start pane commands only once the setup script finished
+//   - main_checkout: Optional canonical checkout reachable as "main"
+//   - worktree_dir: Where worktrees are created instead of .koh (see WorktreeDir)
+//   - cleanup: Defaults for cleanup, such as deleting remote branches
+//   - auto_fetch: How often status commands fetch in the background (e.g. "15m")
+//   - fetch_before_new: Fetch from all remotes before 'koh new' creates a worktree
+//   - git_config: git settings such as user.email applied to each new worktree
+//   - env: Environment variables set in the panes of each worktree's window
+//   - profiles: Named alternatives to setup_script and pane_commands, picked
+//     with 'koh new --profile'
+//   - hooks: Commands run at points in a worktree's life, such as post_create
+//   - cleanup_commands: Commands run before 'koh cleanup' removes a worktree
+//   - disk_quota: Sizes above which worktrees are reported as too big
+//   - include: Shared configuration files merged in first, so many
+//     repositories can follow one convention (see LoadFile)
+//   - branch_template: How 'koh new' names branches, e.g. "{{user}}/{{name}}"
 //
 // The configuration file is JSON-formatted and can be created interactively
 // using the 'koh init' command or edited manually.
@@ -14,8 +31,12 @@
 //	  "setup_script": "./bin/setup",
 //	  "pane_commands": [
 //	    "vim",
-//	    "npm run dev"
-//	  ]
+//	    {"command": "npm run dev", "delay": "5s", "retries": 3}
+//	  ],
+//	  "main_checkout": {
+//	    "path": "~/src/myapp",
+//	    "pane_commands": ["vim"]
+//	  }
 //	}
 package config
 
@@ -24,23 +45,338 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bshakr/koh/internal/diskusage"
 	"github.com/bshakr/koh/internal/git"
 )
 
 // Config represents the koh configuration
 type Config struct {
-	SetupScript  string   `json:"setup_script"`
-	PaneCommands []string `json:"pane_commands"`
+	SetupScript  string        `json:"setup_script"`
+	PaneCommands []PaneCommand `json:"pane_commands"`
+	MainCheckout *MainCheckout `json:"main_checkout,omitempty"`
+	Cleanup      *Cleanup      `json:"cleanup,omitempty"`
+
+	// WorktreeDir is the directory worktrees are created in, relative to the
+	// repository root or absolute (e.g. "~/worktrees/myapp"). Defaults to
+	// DefaultWorktreeDir.
+	WorktreeDir string `json:"worktree_dir,omitempty"`
+
+	// WaitForSetup holds back pane commands until the setup script has
+	// finished, using tmux wait-for channels
+	WaitForSetup bool `json:"wait_for_setup,omitempty"`
+
+	// AutoFetch is a duration such as "15m". Commands that show ahead/behind
+	// counts start a background 'git fetch' when the last one is older.
+	AutoFetch string `json:"auto_fetch,omitempty"`
+
+	// FetchBeforeNew runs 'git fetch --all --prune' before 'koh new' creates
+	// a worktree, so the branch it starts from is current
+	FetchBeforeNew bool `json:"fetch_before_new,omitempty"`
+
+	// GitConfig maps git config keys (e.g. "user.email") to values set in
+	// each new worktree only, leaving the repository's other checkouts alone
+	GitConfig map[string]string `json:"git_config,omitempty"`
+
+	// Env maps environment variables such as DATABASE_URL to values set in
+	// every pane of a worktree's window and for its setup script and hooks
+	// (see ExpandEnv)
+	Env map[string]string `json:"env,omitempty"`
+
+	// CopyFiles are glob patterns, relative to the repository root, for
+	// files such as .env that new worktrees get copied from the main
+	// repository. Matching directories are copied with their contents.
+	CopyFiles []string `json:"copy_files,omitempty"`
+
+	// CopyFilesMode is CopyAll (the default) or CopyIgnoredOnly
+	CopyFilesMode string `json:"copy_files_mode,omitempty"`
+
+	// LinkDirs are directories, relative to the repository root, such as
+	// node_modules that new worktrees share with the main repository instead
+	// of installing their own dependencies
+	LinkDirs []string `json:"link_dirs,omitempty"`
+
+	// LinkMode is how LinkDirs are shared: "symlink" (the default),
+	// "hardlink" or "copy" (see the linkdir package)
+	LinkMode string `json:"link_mode,omitempty"`
+
+	// Profiles are named alternatives to SetupScript and PaneCommands (see ForProfile)
+	Profiles map[string]*Profile `json:"profiles,omitempty"`
+
+	Hooks *Hooks `json:"hooks,omitempty"`
+	// CleanupCommands are shell commands 'koh cleanup' runs before it removes
+	// a worktree, such as dropping the worktree's database. They find the
+	// worktree in $KOH_WORKTREE, $KOH_WORKTREE_PATH and $KOH_BRANCH.
+	CleanupCommands []string `json:"cleanup_commands,omitempty"`
+	// DiskQuota sets how much space worktrees may take up before koh warns
+	DiskQuota *DiskQuota `json:"disk_quota,omitempty"`
+	// Include lists configuration files merged in before this one, such as
+	// an organization's shared base. Relative paths are resolved against the
+	// directory of the file including them.
+	Include []string `json:"include,omitempty"`
+	// BranchTemplate names the branches 'koh new' creates, such as
+	// "{{user}}/{{name}}" (see BranchName)
+	BranchTemplate string `json:"branch_template,omitempty"`
+	// Ports reserves ports for each worktree's dev servers
+	Ports *Ports `json:"ports,omitempty"`
+	// SparsePaths are directories, relative to the repository root, that
+	// new worktrees check out with git sparse-checkout instead of the whole
+	// repository. Files at the top of the repository are always checked out.
+	SparsePaths []string `json:"sparse_paths,omitempty"`
+}
+
+// Hooks are shell commands koh runs at points in a worktree's life
+type Hooks struct {
+	// PostCreate runs in a new worktree, in order and outside tmux, once
+	// 'koh new' has finished setting it up
+	PostCreate []string `json:"post_create,omitempty"`
+}
+
+// PostCreateHooks returns the post_create commands. It is safe to call on a
+// nil Hooks.
+func (h *Hooks) PostCreateHooks() []string {
+	if h == nil {
+		return nil
+	}
+	return h.PostCreate
+}
+
+// DefaultWorktreeDir is where worktrees are created, relative to the
+// repository root, unless worktree_dir says otherwise
+const DefaultWorktreeDir = ".koh"
+
+// ResolveWorktreeDir returns the absolute directory worktrees of the
+// repository at repoRoot are created in. It is safe to call on a nil Config.
+func (c *Config) ResolveWorktreeDir(repoRoot string) string {
+	if c == nil || c.WorktreeDir == "" {
+		return filepath.Join(repoRoot, DefaultWorktreeDir)
+	}
+	return resolvePath(c.WorktreeDir, repoRoot)
+}
+
+// WorktreeDir returns the directory holding the worktrees of the repository
+// at repoRoot, as configured in its .kohconfig. Every command finds
+// worktrees through it. A missing or unreadable configuration means the
+// default, so repositories without a .kohconfig keep working.
+func WorktreeDir(repoRoot string) string {
+	cfg, err := LoadFile(filepath.Join(repoRoot, ".kohconfig"))
+	if err != nil {
+		return (*Config)(nil).ResolveWorktreeDir(repoRoot)
+	}
+	return cfg.ResolveWorktreeDir(repoRoot)
+}
+
+// resolvePath returns path as an absolute path, expanding a leading ~ to the
+// home directory and resolving relative paths against repoRoot
+func resolvePath(path, repoRoot string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoRoot, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Modes of copy_files
+const (
+	// CopyAll copies every matching file
+	CopyAll = "all"
+	// CopyIgnoredOnly copies only files git ignores in the main repository,
+	// so tracked files are never overwritten
+	CopyIgnoredOnly = "ignored-only"
+)
+
+// AutoFetchInterval returns the parsed auto_fetch interval, or 0 when
+// background fetching is disabled
+func (c *Config) AutoFetchInterval() (time.Duration, error) {
+	if c.AutoFetch == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(c.AutoFetch)
+	if err != nil {
+		return 0, fmt.Errorf("invalid auto_fetch %q: %w", c.AutoFetch, err)
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("invalid auto_fetch %q: must not be negative", c.AutoFetch)
+	}
+	return interval, nil
+}
+
+// DiskQuota sets sizes such as "2GB" above which 'koh status', 'koh new' and
+// 'koh du' warn that worktrees take up too much space
+type DiskQuota struct {
+	// Worktree is the most a single worktree should take up
+	Worktree string `json:"worktree,omitempty"`
+	// Total is the most all worktrees together should take up
+	Total string `json:"total,omitempty"`
+}
+
+// DefaultPortStart is the lowest port handed out to worktrees unless
+// ports.start says otherwise
+const DefaultPortStart = 4000
+
+// Ports sets how many ports each worktree gets. Its panes find them in
+// KOH_PORT, KOH_PORT_2 and so on.
+type Ports struct {
+	// Count is how many consecutive ports each worktree gets
+	Count int `json:"count"`
+	// Start is the lowest port handed out, DefaultPortStart when unset
+	Start int `json:"start,omitempty"`
+}
+
+// Range returns the lowest port handed out and the number of ports each
+// worktree gets, 0 when worktrees get none. It is safe to call on a nil
+// Ports.
+func (p *Ports) Range() (start, count int) {
+	if p == nil || p.Count <= 0 {
+		return 0, 0
+	}
+	start = p.Start
+	if start == 0 {
+		start = DefaultPortStart
+	}
+	return start, p.Count
+}
+
+// Limits returns the parsed quotas in bytes, 0 for the ones not set. It is
+// safe to call on a nil DiskQuota.
+func (q *DiskQuota) Limits() (worktree, total int64, err error) {
+	if q == nil {
+		return 0, 0, nil
+	}
+	if q.Worktree != "" {
+		if worktree, err = diskusage.ParseSize(q.Worktree); err != nil {
+			return 0, 0, fmt.Errorf("invalid disk_quota.worktree: %w", err)
+		}
+	}
+	if q.Total != "" {
+		if total, err = diskusage.ParseSize(q.Total); err != nil {
+			return 0, 0, fmt.Errorf("invalid disk_quota.total: %w", err)
+		}
+	}
+	return worktree, total, nil
+}
+
+// defaultProtectedBranches are never deleted from the remote by cleanup
+var defaultProtectedBranches = []string{"main", "master"}
+
+// Cleanup holds defaults for 'koh cleanup'
+type Cleanup struct {
+	// DeleteRemoteBranch deletes the worktree's branch from its remote after removal
+	DeleteRemoteBranch bool `json:"delete_remote_branch,omitempty"`
+
+	// ProtectedBranches are glob patterns (e.g. "release/*") for branches that
+	// are never deleted from the remote, in addition to main and master
+	ProtectedBranches []string `json:"protected_branches,omitempty"`
+
+	// PreClose are keys typed into panes before their window is closed, so
+	// programs such as editors can quit cleanly
+	PreClose []PreClose `json:"pre_close,omitempty"`
+}
+
+// PreClose types keys, such as ":wqa" for vim, into the panes running a
+// program before 'koh cleanup' interrupts them and closes the window
+type PreClose struct {
+	// Pane is the program running in the pane (e.g. "nvim"), "editor" for
+	// any of the common terminal editors, or the pane's position counted
+	// from 0
+	Pane string `json:"pane"`
+	// Keys are typed into the pane, followed by Enter
+	Keys string `json:"keys"`
+}
+
+// PreCloseEditor matches the panes running any of editors
+const PreCloseEditor = "editor"
+
+// editors are the programs PreCloseEditor matches
+var editors = []string{"vi", "vim", "nvim", "hx", "helix", "kak", "emacs", "nano", "micro"}
+
+// Matches reports whether keys go to the pane at position pane running
+// command, e.g. "nvim"
+func (pc PreClose) Matches(pane int, command string) bool {
+	program := filepath.Base(command)
+	switch {
+	case pc.Pane == PreCloseEditor:
+		return slices.Contains(editors, program)
+	case pc.Pane == program:
+		return true
+	default:
+		n, err := strconv.Atoi(pc.Pane)
+		return err == nil && n == pane
+	}
+}
+
+// PreCloseKeys returns the pre_close entries. It is safe to call on a nil
+// Cleanup.
+func (c *Cleanup) PreCloseKeys() []PreClose {
+	if c == nil {
+		return nil
+	}
+	return c.PreClose
+}
+
+// IsProtectedBranch reports whether branch matches a protected branch pattern.
+// It is safe to call on a nil Cleanup.
+func (c *Cleanup) IsProtectedBranch(branch string) bool {
+	patterns := defaultProtectedBranches
+	if c != nil {
+		patterns = append(append([]string{}, defaultProtectedBranches...), c.ProtectedBranches...)
+	}
+
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, branch); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MainCheckout designates the canonical checkout of the repository.
+// It is addressed as "main" by 'koh switch' and is never removed by cleanup.
+type MainCheckout struct {
+	// Path to the checkout, absolute or relative to the repository root.
+	// Defaults to the repository root when empty.
+	Path string `json:"path,omitempty"`
+
+	// SetupScript and PaneCommands describe the window created for the
+	// main checkout, independently of the worktree configuration
+	SetupScript  string        `json:"setup_script,omitempty"`
+	PaneCommands []PaneCommand `json:"pane_commands,omitempty"`
+}
+
+// ResolvePath returns the absolute path of the main checkout
+func (m *MainCheckout) ResolvePath(repoRoot string) string {
+	if m == nil || m.Path == "" {
+		return repoRoot
+	}
+	return resolvePath(m.Path, repoRoot)
+}
+
+// WindowConfig returns the configuration used to create the main checkout's window
+func (m *MainCheckout) WindowConfig() *Config {
+	if m == nil {
+		return &Config{}
+	}
+	return &Config{
+		SetupScript:  m.SetupScript,
+		PaneCommands: m.PaneCommands,
+	}
 }
 
 // DefaultConfig returns a configuration with default values
 func DefaultConfig() *Config {
 	return &Config{
 		SetupScript:  "./bin/setup",
-		PaneCommands: []string{},
+		PaneCommands: []PaneCommand{},
 	}
 }
 
@@ -92,16 +428,21 @@ func ConfigExists() (bool, error) {
 	return true, nil
 }
 
-// Load loads the configuration from disk
+// Load loads the configuration from disk, merged over the files it includes
 func Load() (*Config, error) {
-	configPath, err := ConfigPath()
+	configPath, err := existingConfigPath()
 	if err != nil {
 		return nil, err
 	}
+	return LoadFile(configPath)
+}
 
-	// If config doesn't exist, return an error
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("no .kohconfig found - run 'koh init' to set up configuration")
+// LoadLocal loads the configuration as .kohconfig has it, without the files
+// it includes, for changing it and saving it back
+func LoadLocal() (*Config, error) {
+	configPath, err := existingConfigPath()
+	if err != nil {
+		return nil, err
 	}
 
 	//nolint:gosec // G304: Reading config file from validated path is expected
@@ -118,6 +459,20 @@ func Load() (*Config, error) {
 	return &config, nil
 }
 
+// existingConfigPath returns the path to .kohconfig, failing when there is none
+func existingConfigPath() (string, error) {
+	configPath, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	// If config doesn't exist, return an error
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("no .kohconfig found - run 'koh init' to set up configuration")
+	}
+	return configPath, nil
+}
+
 // Save saves the configuration to disk
 func (c *Config) Save() error {
 	configPath, err := ConfigPath()
@@ -170,7 +525,7 @@ func Setup() error {
 		paneCommands = append(paneCommands, cmd)
 	}
 	if len(paneCommands) > 0 {
-		config.PaneCommands = paneCommands
+		config.PaneCommands = PlainPaneCommands(paneCommands)
 	}
 
 	// Save the configuration
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -39,12 +40,12 @@ func TestConfigSaveAndLoad(t *testing.T) {
 	// Create a test config
 	testConfig := &Config{
 		SetupScript: "./test/setup",
-		PaneCommands: []string{
+		PaneCommands: PlainPaneCommands([]string{
 			"nvim",
 			"./test/setup",
 			"./test/dev",
 			"test-cli",
-		},
+		}),
 	}
 
 	// Marshal and save manually (since Save() uses ConfigPath which needs git)
@@ -110,3 +111,196 @@ func TestConfigJSON(t *testing.T) {
 		t.Errorf("SetupScript mismatch after marshal/unmarshal")
 	}
 }
+
+func TestMainCheckoutResolvePath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("No home directory available")
+	}
+
+	tests := []struct {
+		name     string
+		checkout *MainCheckout
+		want     string
+	}{
+		{name: "nil defaults to repo root", checkout: nil, want: "/repo"},
+		{name: "empty path defaults to repo root", checkout: &MainCheckout{}, want: "/repo"},
+		{name: "absolute path", checkout: &MainCheckout{Path: "/src/app"}, want: "/src/app"},
+		{name: "relative path", checkout: &MainCheckout{Path: "../app-main"}, want: "/app-main"},
+		{name: "home path", checkout: &MainCheckout{Path: "~/src/app"}, want: filepath.Join(home, "src/app")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.checkout.ResolvePath("/repo"); got != tt.want {
+				t.Errorf("ResolvePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveWorktreeDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("No home directory available")
+	}
+
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{name: "nil defaults to .koh", cfg: nil, want: "/repo/.koh"},
+		{name: "unset defaults to .koh", cfg: &Config{}, want: "/repo/.koh"},
+		{name: "relative path", cfg: &Config{WorktreeDir: "build/trees"}, want: "/repo/build/trees"},
+		{name: "absolute path", cfg: &Config{WorktreeDir: "/worktrees/app"}, want: "/worktrees/app"},
+		{name: "home path", cfg: &Config{WorktreeDir: "~/worktrees/app"}, want: filepath.Join(home, "worktrees/app")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.ResolveWorktreeDir("/repo"); got != tt.want {
+				t.Errorf("ResolveWorktreeDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorktreeDir(t *testing.T) {
+	repo := t.TempDir()
+	if got, want := WorktreeDir(repo), filepath.Join(repo, ".koh"); got != want {
+		t.Errorf("Expected %s without a .kohconfig, got %s", want, got)
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, ".kohconfig"), []byte(`{"worktree_dir": "/worktrees/app"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if got := WorktreeDir(repo); got != "/worktrees/app" {
+		t.Errorf("Expected the configured directory, got %s", got)
+	}
+}
+
+func TestCleanupIsProtectedBranch(t *testing.T) {
+	var unset *Cleanup
+	if !unset.IsProtectedBranch("main") || !unset.IsProtectedBranch("master") {
+		t.Error("Expected main and master to be protected by default")
+	}
+	if unset.IsProtectedBranch("feature") {
+		t.Error("Expected feature branches to be unprotected by default")
+	}
+
+	cleanup := &Cleanup{ProtectedBranches: []string{"release/*", "develop"}}
+	for _, branch := range []string{"main", "develop", "release/1.2"} {
+		if !cleanup.IsProtectedBranch(branch) {
+			t.Errorf("Expected %q to be protected", branch)
+		}
+	}
+	if cleanup.IsProtectedBranch("feature/release") {
+		t.Error("Expected feature/release to be unprotected")
+	}
+}
+
+func TestPreCloseMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pane    string
+		at      int
+		command string
+		want    bool
+	}{
+		{name: "editor alias", pane: "editor", command: "nvim", want: true},
+		{name: "editor alias with path", pane: "editor", command: "/usr/bin/vim", want: true},
+		{name: "editor alias and a server", pane: "editor", command: "node", want: false},
+		{name: "program", pane: "hx", command: "hx", want: true},
+		{name: "other program", pane: "nvim", command: "vim", want: false},
+		{name: "position", pane: "1", at: 1, command: "emacs", want: true},
+		{name: "other position", pane: "1", at: 0, command: "emacs", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (PreClose{Pane: tt.pane}).Matches(tt.at, tt.command); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	var unset *Cleanup
+	if got := unset.PreCloseKeys(); got != nil {
+		t.Errorf("Expected no pre_close entries, got %v", got)
+	}
+}
+
+func TestAutoFetchInterval(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"15m", 15 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"15", 0, true},
+		{"-5m", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := (&Config{AutoFetch: tt.value}).AutoFetchInterval()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AutoFetchInterval(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Expected AutoFetchInterval(%q) = %v, got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestPortsRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		ports     *Ports
+		wantStart int
+		wantCount int
+	}{
+		{name: "unset"},
+		{name: "no ports", ports: &Ports{Start: 8000}},
+		{name: "default start", ports: &Ports{Count: 2}, wantStart: DefaultPortStart, wantCount: 2},
+		{name: "start", ports: &Ports{Count: 3, Start: 8000}, wantStart: 8000, wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, count := tt.ports.Range()
+			if start != tt.wantStart || count != tt.wantCount {
+				t.Errorf("Expected %d ports from %d, got %d from %d", tt.wantCount, tt.wantStart, count, start)
+			}
+		})
+	}
+}
+
+func TestDiskQuotaLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		quota        *DiskQuota
+		wantWorktree int64
+		wantTotal    int64
+		wantErr      bool
+	}{
+		{name: "unset"},
+		{name: "worktree only", quota: &DiskQuota{Worktree: "500MB"}, wantWorktree: 500 << 20},
+		{name: "both", quota: &DiskQuota{Worktree: "2GB", Total: "20GB"}, wantWorktree: 2 << 30, wantTotal: 20 << 30},
+		{name: "invalid", quota: &DiskQuota{Total: "lots"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worktree, total, err := tt.quota.Limits()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if worktree != tt.wantWorktree || total != tt.wantTotal {
+				t.Errorf("Expected %d and %d, got %d and %d", tt.wantWorktree, tt.wantTotal, worktree, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envName matches names environment variables can be exported under
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExpandEnv returns the env setting as sorted "KEY=value" entries. $VAR and
+// ${VAR} in values are expanded from vars, "KEY=value" entries such as
+// KOH_WORKTREE and KOH_PORT, and then from koh's own environment, so
+// "postgres://localhost/app_${KOH_WORKTREE}" gives each worktree a database
+// of its own. KOH_ names are left out, since koh sets those itself.
+func (c *Config) ExpandEnv(vars []string) []string {
+	own := make(map[string]string, len(vars))
+	for _, kv := range vars {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			own[key] = value
+		}
+	}
+	lookup := func(key string) string {
+		if value, ok := own[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}
+
+	env := make([]string, 0, len(c.Env))
+	for _, key := range sortedKeys(c.Env) {
+		if strings.HasPrefix(key, "KOH_") {
+			continue
+		}
+		env = append(env, key+"="+os.Expand(c.Env[key], lookup))
+	}
+	return env
+}
+
+// lintEnv checks the names of the env setting at source
+func lintEnv(source string, env map[string]string) []Warning {
+	var warnings []Warning
+	for _, key := range sortedKeys(env) {
+		switch {
+		case !envName.MatchString(key):
+			warnings = append(warnings, Warning{Source: source, Command: key, Message: fmt.Sprintf("%q is not an environment variable name such as \"DATABASE_URL\"", key)})
+		case strings.HasPrefix(key, "KOH_"):
+			warnings = append(warnings, Warning{Source: source, Command: key, Message: "KOH_ variables are set by koh and can't be overridden"})
+		}
+	}
+	return warnings
+}
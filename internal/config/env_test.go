@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("KOH_TEST_HOST", "db.local")
+	cfg := &Config{Env: map[string]string{
+		"DATABASE_URL": "postgres://${KOH_TEST_HOST}/app_${KOH_WORKTREE}",
+		"PORT":         "$KOH_PORT",
+		"RAILS_ENV":    "development",
+		"KOH_PORT":     "1",
+	}}
+
+	got := cfg.ExpandEnv([]string{"KOH_WORKTREE=feat", "KOH_PORT=4000"})
+	want := []string{
+		"DATABASE_URL=postgres://db.local/app_feat",
+		"PORT=4000",
+		"RAILS_ENV=development",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLintEnv(t *testing.T) {
+	got := lintEnv("env", map[string]string{"DATABASE_URL": "x", "1BAD": "x", "KOH_SCRATCH": "x"})
+	if len(got) != 2 || got[0].Command != "1BAD" || got[1].Command != "KOH_SCRATCH" {
+		t.Errorf("Expected warnings for 1BAD and KOH_SCRATCH, got %v", got)
+	}
+}
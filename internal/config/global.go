@@ -0,0 +1,182 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bshakr/koh/internal/paths"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// Global is the user's configuration shared by every repository, stored in
+// config.json in the config directory (see paths.ConfigDir).
+//
+// Example config.json:
+//
+//	{
+//	  "validation": "relaxed",
+//	  "list_enter": "menu",
+//	  "dirty_switch": "ask",
+//	  "editor": "nvim",
+//	  "aliases": {"kn": "new --base origin/main --profile backend"}
+//	}
+type Global struct {
+	// Validation is the worktree name validation policy: "strict",
+	// "standard" (the default) or "relaxed"
+	Validation string `json:"validation,omitempty"`
+
+	// ListEnter is what Enter does in 'koh list': ListEnterSwitch (the
+	// default) or ListEnterMenu
+	ListEnter string `json:"list_enter,omitempty"`
+
+	// DirtySwitch is what switching away from a worktree with uncommitted
+	// changes does: DirtySwitchWarn (the default), DirtySwitchAsk or
+	// DirtySwitchIgnore
+	DirtySwitch string `json:"dirty_switch,omitempty"`
+
+	// Editor is the command worktrees are opened in, e.g. "nvim" or
+	// "code --wait". Defaults to $VISUAL, then $EDITOR, then vi.
+	Editor string `json:"editor,omitempty"`
+
+	// OpenEditor opens the editor whenever 'koh new' or 'koh switch' is run,
+	// as if --open were passed
+	OpenEditor bool `json:"open_editor,omitempty"`
+
+	// SanitizeNames makes 'koh new' turn names it would reject, such as the
+	// branch name "feature/login", into worktree names such as
+	// "feature-login", creating the branch under the name as given
+	SanitizeNames bool `json:"sanitize_names,omitempty"`
+
+	// Hints turns off the suggestions of what to do next that koh prints
+	// after commands when set to false
+	Hints *bool `json:"hints,omitempty"`
+
+	// Aliases are commands of their own that stand for a koh command and its
+	// arguments, e.g. "kn": "new --base origin/main --profile backend" makes
+	// 'koh kn feature' run 'koh new --base origin/main --profile backend
+	// feature'. Arguments are split on whitespace, without quoting.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// Values of list_enter
+const (
+	// ListEnterSwitch switches to the selected worktree
+	ListEnterSwitch = "switch"
+	// ListEnterMenu opens the actions menu of the selected worktree
+	ListEnterMenu = "menu"
+)
+
+// Values of dirty_switch
+const (
+	// DirtySwitchWarn prints a warning and switches
+	DirtySwitchWarn = "warn"
+	// DirtySwitchAsk offers to stash or commit the changes before switching
+	DirtySwitchAsk = "ask"
+	// DirtySwitchIgnore switches without checking for changes
+	DirtySwitchIgnore = "ignore"
+)
+
+// GlobalPath returns the path of the global configuration file
+func GlobalPath() (string, error) {
+	dir, err := paths.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// LoadGlobal reads the global configuration. A missing file yields the defaults.
+func LoadGlobal() (*Global, error) {
+	path, err := GlobalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Global{}
+	//nolint:gosec // G304: Reading config file from the user's config directory is expected
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global config: %w", err)
+	}
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("failed to parse global config %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// ValidationPolicy returns the configured worktree name validation policy
+func (g *Global) ValidationPolicy() (validation.Policy, error) {
+	return validation.ParsePolicy(g.Validation)
+}
+
+// EnterOpensMenu reports whether Enter opens the actions menu in 'koh list'
+// rather than switching to the worktree
+func (g *Global) EnterOpensMenu() (bool, error) {
+	switch g.ListEnter {
+	case "", ListEnterSwitch:
+		return false, nil
+	case ListEnterMenu:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid list_enter %q (expected %q or %q)", g.ListEnter, ListEnterSwitch, ListEnterMenu)
+	}
+}
+
+// Alias returns the arguments the alias name expands to, or nil when there
+// is no such alias
+func (g *Global) Alias(name string) ([]string, error) {
+	value, ok := g.Aliases[name]
+	if !ok {
+		return nil, nil
+	}
+	args := strings.Fields(value)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("alias %q is empty", name)
+	}
+	return args, nil
+}
+
+// ValidateAliases checks that every alias has a usable name and expands to
+// a command
+func (g *Global) ValidateAliases() error {
+	names := make([]string, 0, len(g.Aliases))
+	for name := range g.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "" || strings.HasPrefix(name, "-") || strings.ContainsFunc(name, unicode.IsSpace) {
+			return fmt.Errorf("invalid alias name %q (expected a single word not starting with '-')", name)
+		}
+		if _, err := g.Alias(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShowHints reports whether koh suggests what to do next after commands
+func (g *Global) ShowHints() bool {
+	return g.Hints == nil || *g.Hints
+}
+
+// DirtySwitchMode returns the configured dirty_switch, DirtySwitchWarn when unset
+func (g *Global) DirtySwitchMode() (string, error) {
+	switch g.DirtySwitch {
+	case "":
+		return DirtySwitchWarn, nil
+	case DirtySwitchWarn, DirtySwitchAsk, DirtySwitchIgnore:
+		return g.DirtySwitch, nil
+	default:
+		return DirtySwitchWarn, fmt.Errorf("invalid dirty_switch %q (expected %q, %q or %q)", g.DirtySwitch, DirtySwitchWarn, DirtySwitchAsk, DirtySwitchIgnore)
+	}
+}
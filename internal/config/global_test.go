@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/validation"
+)
+
+func TestLoadGlobal(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KOH_CONFIG_DIR", dir)
+
+	g, err := LoadGlobal()
+	if err != nil {
+		t.Fatalf("LoadGlobal() failed without a file: %v", err)
+	}
+	if policy, err := g.ValidationPolicy(); err != nil || policy != validation.Standard {
+		t.Errorf("Expected the standard policy by default, got %q (%v)", policy, err)
+	}
+
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"validation": "relaxed"}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+	g, err = LoadGlobal()
+	if err != nil {
+		t.Fatalf("LoadGlobal() failed: %v", err)
+	}
+	if policy, err := g.ValidationPolicy(); err != nil || policy != validation.Relaxed {
+		t.Errorf("Expected the relaxed policy, got %q (%v)", policy, err)
+	}
+
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"validation": "lenient"}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+	if g, err = LoadGlobal(); err != nil {
+		t.Fatalf("LoadGlobal() failed: %v", err)
+	}
+	if _, err := g.ValidationPolicy(); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
+
+func TestGlobalEnterOpensMenu(t *testing.T) {
+	tests := []struct {
+		listEnter string
+		want      bool
+		wantErr   bool
+	}{
+		{listEnter: "", want: false},
+		{listEnter: ListEnterSwitch, want: false},
+		{listEnter: ListEnterMenu, want: true},
+		{listEnter: "palette", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listEnter, func(t *testing.T) {
+			got, err := (&Global{ListEnter: tt.listEnter}).EnterOpensMenu()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGlobalDirtySwitchMode(t *testing.T) {
+	tests := []struct {
+		dirtySwitch string
+		want        string
+		wantErr     bool
+	}{
+		{dirtySwitch: "", want: DirtySwitchWarn},
+		{dirtySwitch: DirtySwitchWarn, want: DirtySwitchWarn},
+		{dirtySwitch: DirtySwitchAsk, want: DirtySwitchAsk},
+		{dirtySwitch: DirtySwitchIgnore, want: DirtySwitchIgnore},
+		{dirtySwitch: "stash", want: DirtySwitchWarn, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dirtySwitch, func(t *testing.T) {
+			got, err := (&Global{DirtySwitch: tt.dirtySwitch}).DirtySwitchMode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGlobalShowHints(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		hints *bool
+		want  bool
+	}{
+		{hints: nil, want: true},
+		{hints: &on, want: true},
+		{hints: &off, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := (&Global{Hints: tt.hints}).ShowHints(); got != tt.want {
+			t.Errorf("Expected ShowHints() = %v for %v, got %v", tt.want, tt.hints, got)
+		}
+	}
+}
+
+func TestGlobalAlias(t *testing.T) {
+	g := &Global{Aliases: map[string]string{
+		"kn":    "new  --base origin/main --profile backend",
+		"blank": " ",
+	}}
+
+	args, err := g.Alias("kn")
+	if err != nil {
+		t.Fatalf("Alias() failed: %v", err)
+	}
+	if want := []string{"new", "--base", "origin/main", "--profile", "backend"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected %q, got %q", want, args)
+	}
+	if args, err := g.Alias("ls"); args != nil || err != nil {
+		t.Errorf("Expected no expansion for an unknown alias, got %q (%v)", args, err)
+	}
+	if _, err := g.Alias("blank"); err == nil {
+		t.Error("Expected an empty alias to be rejected")
+	}
+}
+
+func TestGlobalValidateAliases(t *testing.T) {
+	tests := []struct {
+		aliases map[string]string
+		wantErr bool
+	}{
+		{aliases: nil, wantErr: false},
+		{aliases: map[string]string{"kn": "new --profile backend"}, wantErr: false},
+		{aliases: map[string]string{"kn": ""}, wantErr: true},
+		{aliases: map[string]string{"-n": "new"}, wantErr: true},
+		{aliases: map[string]string{"k n": "new"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := (&Global{Aliases: tt.aliases}).ValidateAliases()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Expected error %v for %v, got %v", tt.wantErr, tt.aliases, err)
+		}
+	}
+}
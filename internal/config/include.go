@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth bounds how deeply included files may include others
+const maxIncludeDepth = 8
+
+// LoadFile loads the configuration at path, merged over the files it
+// includes. Included files are merged in order, each over its own includes,
+// and the file at path is merged last, so its settings override theirs.
+// Objects such as git_config and profiles are merged key by key; lists such
+// as pane_commands and plain values replace what an included file set.
+func LoadFile(path string) (*Config, error) {
+	merged, err := mergeFile(filepath.Clean(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge included config files: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return &config, nil
+}
+
+// mergeFile returns the configuration at path as a JSON object, merged over
+// the files it includes. chain lists the files including it, to catch
+// include cycles.
+func mergeFile(path string, chain []string) (map[string]interface{}, error) {
+	for _, including := range chain {
+		if including == path {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(chain, path), " -> "))
+		}
+	}
+	if len(chain) > maxIncludeDepth {
+		return nil, fmt.Errorf("includes nest deeper than %d files at %s", maxIncludeDepth, path)
+	}
+
+	//nolint:gosec // G304: reading the configuration and the files it includes is expected
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("failed to read %s, included from %s: %w", path, chain[len(chain)-1], err)
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var own map[string]interface{}
+	if err := json.Unmarshal(data, &own); err != nil {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("failed to parse %s, included from %s: %w", path, chain[len(chain)-1], err)
+		}
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	var includes struct {
+		Include []string `json:"include"`
+	}
+	if err := json.Unmarshal(data, &includes); err != nil {
+		return nil, fmt.Errorf("include in %s must be a list of paths: %w", path, err)
+	}
+
+	merged := map[string]interface{}{}
+	for _, include := range includes.Include {
+		if strings.Contains(include, "://") {
+			return nil, fmt.Errorf("include %q in %s: only local files can be included", include, path)
+		}
+		base, err := mergeFile(resolvePath(include, filepath.Dir(path)), append(chain[:len(chain):len(chain)], path))
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeObjects(merged, base)
+	}
+	// What included files include is their business
+	delete(merged, "include")
+	return mergeObjects(merged, own), nil
+}
+
+// mergeObjects merges over into base: objects present in both are merged
+// key by key, and any other value in over replaces the one in base
+func mergeObjects(base, over map[string]interface{}) map[string]interface{} {
+	for key, value := range over {
+		overObject, ok := value.(map[string]interface{})
+		baseObject, baseOK := base[key].(map[string]interface{})
+		if ok && baseOK {
+			base[key] = mergeObjects(baseObject, overObject)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
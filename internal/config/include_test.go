@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes a configuration file, creating its directory
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestLoadFileIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "shared", ".kohconfig.base"), `{
+		"setup_script": "./bin/setup",
+		"pane_commands": [{"command": "npm run dev", "delay": "5s"}, "vim"],
+		"worktree_dir": "~/worktrees",
+		"git_config": {"user.email": "dev@example.com", "commit.gpgsign": "true"},
+		"cleanup": {"delete_remote_branch": true}
+	}`)
+	repoConfig := filepath.Join(dir, "app", ".kohconfig")
+	writeConfigFile(t, repoConfig, `{
+		"include": ["../shared/.kohconfig.base"],
+		"pane_commands": [{"command": "make watch"}],
+		"git_config": {"user.email": "app@example.com"},
+		"cleanup": {"protected_branches": ["release"]}
+	}`)
+
+	cfg, err := LoadFile(repoConfig)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+
+	if cfg.SetupScript != "./bin/setup" || cfg.WorktreeDir != "~/worktrees" {
+		t.Errorf("Expected settings the repository doesn't set to come from the base, got %+v", cfg)
+	}
+	if want := []PaneCommand{{Command: "make watch"}}; !reflect.DeepEqual(cfg.PaneCommands, want) {
+		t.Errorf("Expected the repository's pane commands to replace the base's, got %+v", cfg.PaneCommands)
+	}
+	if want := map[string]string{"user.email": "app@example.com", "commit.gpgsign": "true"}; !reflect.DeepEqual(cfg.GitConfig, want) {
+		t.Errorf("Expected git_config to be merged key by key, got %v", cfg.GitConfig)
+	}
+	if cfg.Cleanup == nil || !cfg.Cleanup.DeleteRemoteBranch || !reflect.DeepEqual(cfg.Cleanup.ProtectedBranches, []string{"release"}) {
+		t.Errorf("Expected cleanup to be merged key by key, got %+v", cfg.Cleanup)
+	}
+	if !reflect.DeepEqual(cfg.Include, []string{"../shared/.kohconfig.base"}) {
+		t.Errorf("Expected the repository's own includes, got %v", cfg.Include)
+	}
+}
+
+func TestLoadFileIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "missing file",
+			files: map[string]string{".kohconfig": `{"include": ["base.json"]}`},
+			want:  "included from",
+		},
+		{
+			name:  "URL",
+			files: map[string]string{".kohconfig": `{"include": ["https://example.com/base.json"]}`},
+			want:  "only local files",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				".kohconfig": `{"include": ["a.json"]}`,
+				"a.json":     `{"include": ["b.json"]}`,
+				"b.json":     `{"include": ["a.json"]}`,
+			},
+			want: "include cycle",
+		},
+		{
+			name: "invalid included file",
+			files: map[string]string{
+				".kohconfig": `{"include": ["base.json"]}`,
+				"base.json":  `{`,
+			},
+			want: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeConfigFile(t, filepath.Join(dir, name), content)
+			}
+			_, err := LoadFile(filepath.Join(dir, ".kohconfig"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWorktreeDirFromInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "base.json"), `{"worktree_dir": ".worktrees"}`)
+	repo := filepath.Join(dir, "app")
+	writeConfigFile(t, filepath.Join(repo, ".kohconfig"), `{"include": ["../base.json"]}`)
+
+	if got, want := WorktreeDir(repo), filepath.Join(repo, ".worktrees"); got != want {
+		t.Errorf("Expected %s, relative to the repository, got %s", want, got)
+	}
+}
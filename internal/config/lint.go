@@ -0,0 +1,382 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bshakr/koh/internal/diskusage"
+	"github.com/bshakr/koh/internal/linkdir"
+	"github.com/bshakr/koh/internal/validation"
+)
+
+// Warning describes a likely problem with a configured command
+type Warning struct {
+	// Source names the setting, e.g. "setup_script" or "pane_commands[1]"
+	Source  string `json:"source"`
+	Command string `json:"command"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Source, w.Message)
+}
+
+// shellBuiltins are commands provided by the shell rather than found on PATH
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "alias": true, "bg": true, "builtin": true,
+	"case": true, "cd": true, "command": true, "echo": true, "eval": true,
+	"exec": true, "exit": true, "export": true, "false": true, "fg": true,
+	"for": true, "if": true, "printf": true, "pwd": true, "read": true,
+	"set": true, "source": true, "test": true, "time": true, "true": true,
+	"type": true, "ulimit": true, "umask": true, "unset": true, "until": true,
+	"wait": true, "while": true, "{": true, "(": true, "!": true,
+}
+
+// tmuxKeyName matches arguments tmux send-keys interprets as a key rather than text
+var tmuxKeyName = regexp.MustCompile(`^((C|M|S)-\S|Enter|Escape|Tab|BTab|Space|BSpace|Up|Down|Left|Right|Home|End|PageUp|PageDown|PPage|NPage|IC|DC|F[0-9]{1,2})$`)
+
+// maxPortCount is the most ports a worktree can reserve
+const maxPortCount = 100
+
+// gitConfigKey matches git config keys of the form section[.subsection].name
+var gitConfigKey = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*\.(.+\.)?[A-Za-z][A-Za-z0-9-]*$`)
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lint checks the configured commands for problems that would otherwise only
+// show up as a broken pane: scripts that don't exist or aren't executable,
+// executables missing from PATH, and text tmux send-keys would mangle.
+// Relative script paths are resolved against repoRoot.
+func (c *Config) Lint(repoRoot string) []Warning {
+	var warnings []Warning
+
+	check := func(source, command string) {
+		for _, msg := range lintCommand(command, repoRoot) {
+			warnings = append(warnings, Warning{Source: source, Command: command, Message: msg})
+		}
+	}
+
+	if c.SetupScript != "" {
+		check("setup_script", c.SetupScript)
+	}
+	for i, pane := range c.PaneCommands {
+		source := fmt.Sprintf("pane_commands[%d]", i)
+		check(source, pane.Command)
+		warnings = append(warnings, lintPaneOptions(source, pane)...)
+	}
+
+	if c.WaitForSetup && c.SetupScript == "" {
+		warnings = append(warnings, Warning{Source: "wait_for_setup", Message: "has no effect without a setup_script"})
+	}
+
+	if _, err := c.AutoFetchInterval(); err != nil {
+		warnings = append(warnings, Warning{Source: "auto_fetch", Command: c.AutoFetch, Message: "is not a duration such as \"15m\" or \"1h\""})
+	}
+
+	for _, key := range sortedKeys(c.GitConfig) {
+		if !gitConfigKey.MatchString(key) {
+			warnings = append(warnings, Warning{Source: "git_config", Command: key, Message: fmt.Sprintf("%q is not a git config key such as \"user.email\"", key)})
+		}
+	}
+
+	warnings = append(warnings, lintEnv("env", c.Env)...)
+
+	for _, pattern := range c.CopyFiles {
+		if msg := lintCopyPattern(pattern); msg != "" {
+			warnings = append(warnings, Warning{Source: "copy_files", Command: pattern, Message: msg})
+		}
+	}
+	switch c.CopyFilesMode {
+	case "", CopyAll, CopyIgnoredOnly:
+	default:
+		warnings = append(warnings, Warning{Source: "copy_files_mode", Command: c.CopyFilesMode, Message: fmt.Sprintf("must be %q or %q", CopyAll, CopyIgnoredOnly)})
+	}
+
+	for _, dir := range c.LinkDirs {
+		if msg := lintLinkDir(dir); msg != "" {
+			warnings = append(warnings, Warning{Source: "link_dirs", Command: dir, Message: msg})
+		} else if err := validation.ValidatePathWithinRepository(filepath.Join(repoRoot, dir), repoRoot); err != nil {
+			warnings = append(warnings, Warning{Source: "link_dirs", Command: dir, Message: err.Error()})
+		}
+	}
+	switch c.LinkMode {
+	case "", linkdir.Symlink, linkdir.Hardlink, linkdir.Copy:
+	default:
+		warnings = append(warnings, Warning{Source: "link_mode", Command: c.LinkMode, Message: fmt.Sprintf("must be %q, %q or %q", linkdir.Symlink, linkdir.Hardlink, linkdir.Copy)})
+	}
+
+	if c.DiskQuota != nil {
+		for _, quota := range []struct{ source, size string }{
+			{"disk_quota.worktree", c.DiskQuota.Worktree},
+			{"disk_quota.total", c.DiskQuota.Total},
+		} {
+			if _, err := diskusage.ParseSize(quota.size); quota.size != "" && err != nil {
+				warnings = append(warnings, Warning{Source: quota.source, Command: quota.size, Message: "is not a size such as \"500MB\" or \"2GB\""})
+			}
+		}
+	}
+
+	for _, dir := range c.SparsePaths {
+		if msg := lintSparsePath(dir); msg != "" {
+			warnings = append(warnings, Warning{Source: "sparse_paths", Command: dir, Message: msg})
+		}
+	}
+
+	if c.Ports != nil {
+		if c.Ports.Count < 0 || c.Ports.Count > maxPortCount {
+			warnings = append(warnings, Warning{Source: "ports.count", Command: strconv.Itoa(c.Ports.Count), Message: fmt.Sprintf("must be between 0 and %d", maxPortCount)})
+		}
+		if c.Ports.Start != 0 && (c.Ports.Start < 1024 || c.Ports.Start > 65535) {
+			warnings = append(warnings, Warning{Source: "ports.start", Command: strconv.Itoa(c.Ports.Start), Message: "must be a port between 1024 and 65535"})
+		}
+	}
+
+	for i, pc := range c.Cleanup.PreCloseKeys() {
+		source := fmt.Sprintf("cleanup.pre_close[%d]", i)
+		if strings.TrimSpace(pc.Pane) == "" {
+			warnings = append(warnings, Warning{Source: source, Message: "has no pane, such as \"editor\", \"nvim\" or 0"})
+		}
+		if pc.Keys == "" {
+			warnings = append(warnings, Warning{Source: source, Command: pc.Pane, Message: "has no keys to type"})
+		}
+	}
+
+	if c.BranchTemplate != "" {
+		if msg := lintBranchTemplate(c.BranchTemplate); msg != "" {
+			warnings = append(warnings, Warning{Source: "branch_template", Command: c.BranchTemplate, Message: msg})
+		}
+	}
+
+	if c.WorktreeDir != "" {
+		if msg := lintWorktreeDir(c.ResolveWorktreeDir(repoRoot), repoRoot); msg != "" {
+			warnings = append(warnings, Warning{Source: "worktree_dir", Command: c.WorktreeDir, Message: msg})
+		}
+	}
+
+	for i, command := range c.Hooks.PostCreateHooks() {
+		source := fmt.Sprintf("hooks.post_create[%d]", i)
+		if strings.TrimSpace(command) == "" {
+			warnings = append(warnings, Warning{Source: source, Command: command, Message: "command is empty"})
+		} else if msg := lintHookScript(command, repoRoot); msg != "" {
+			warnings = append(warnings, Warning{Source: source, Command: command, Message: msg})
+		} else if msg := lintExecutable(command, repoRoot); msg != "" {
+			warnings = append(warnings, Warning{Source: source, Command: command, Message: msg})
+		}
+	}
+
+	for i, command := range c.CleanupCommands {
+		if msg := lintCleanupCommand(command); msg != "" {
+			warnings = append(warnings, Warning{Source: fmt.Sprintf("cleanup_commands[%d]", i), Command: command, Message: msg})
+		}
+	}
+
+	for _, name := range c.ProfileNames() {
+		profile := c.Profiles[name]
+		if profile == nil {
+			continue
+		}
+		if profile.SetupScript != "" {
+			check("profiles."+name+".setup_script", profile.SetupScript)
+		}
+		for i, pane := range profile.PaneCommands {
+			source := fmt.Sprintf("profiles.%s.pane_commands[%d]", name, i)
+			check(source, pane.Command)
+			warnings = append(warnings, lintPaneOptions(source, pane)...)
+		}
+		warnings = append(warnings, lintEnv("profiles."+name+".env", profile.Env)...)
+	}
+
+	if c.MainCheckout != nil {
+		checkoutRoot := c.MainCheckout.ResolvePath(repoRoot)
+		for i, pane := range c.MainCheckout.PaneCommands {
+			source := fmt.Sprintf("main_checkout.pane_commands[%d]", i)
+			for _, msg := range lintCommand(pane.Command, checkoutRoot) {
+				warnings = append(warnings, Warning{Source: source, Command: pane.Command, Message: msg})
+			}
+			warnings = append(warnings, lintPaneOptions(source, pane)...)
+		}
+	}
+
+	return warnings
+}
+
+// lintPaneOptions returns the problems found in a pane command's delay and
+// retries
+func lintPaneOptions(source string, pane PaneCommand) []Warning {
+	var warnings []Warning
+	if _, err := pane.DelayDuration(); err != nil {
+		warnings = append(warnings, Warning{Source: source, Command: pane.Command, Message: fmt.Sprintf("delay %q is not a duration such as \"5s\"", pane.Delay)})
+	}
+	if pane.Retries < 0 {
+		warnings = append(warnings, Warning{Source: source, Command: pane.Command, Message: "retries must not be negative"})
+	}
+	return warnings
+}
+
+// lintCopyPattern returns the problem with a copy_files pattern, if any
+func lintCopyPattern(pattern string) string {
+	switch {
+	case strings.TrimSpace(pattern) == "":
+		return "pattern is empty"
+	case filepath.IsAbs(pattern):
+		return "must be relative to the repository root"
+	case pattern == ".." || strings.HasPrefix(filepath.Clean(pattern), ".."+string(filepath.Separator)):
+		return "must not point outside the repository"
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return "is not a valid glob pattern"
+	}
+	return ""
+}
+
+// lintSparsePath checks a sparse_paths entry. Sparse checkouts use cone
+// mode, which takes directories in the repository rather than patterns.
+func lintSparsePath(dir string) string {
+	switch {
+	case strings.TrimSpace(dir) == "":
+		return "is empty"
+	case strings.ContainsAny(dir, "*?[!"):
+		return fmt.Sprintf("%q is a pattern; list directories such as \"services/api\" instead", dir)
+	case filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../"):
+		return fmt.Sprintf("%q is outside the repository; paths are relative to its root", dir)
+	}
+	return ""
+}
+
+// lintLinkDir checks a link_dirs entry, which names a directory inside the
+// repository
+func lintLinkDir(dir string) string {
+	switch {
+	case strings.TrimSpace(dir) == "":
+		return "is empty"
+	case strings.ContainsAny(dir, "*?["):
+		return fmt.Sprintf("%q is a pattern; list directories such as \"node_modules\" instead", dir)
+	case filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(filepath.Clean(dir), ".."+string(filepath.Separator)):
+		return fmt.Sprintf("%q is outside the repository; paths are relative to its root", dir)
+	case filepath.Clean(dir) == "." || filepath.Clean(dir) == ".git" || strings.HasPrefix(filepath.Clean(dir), ".git"+string(filepath.Separator)):
+		return fmt.Sprintf("%q can't be shared; name a dependency directory such as \"node_modules\"", dir)
+	}
+	return ""
+}
+
+// lintWorktreeDir returns the problem with the resolved worktree directory
+// dir, if any. Worktrees can't live at the repository root or inside .git.
+func lintWorktreeDir(dir, repoRoot string) string {
+	rel, err := filepath.Rel(repoRoot, dir)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case rel == ".":
+		return "must not be the repository root"
+	case rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)):
+		return "must not be inside .git"
+	}
+	return ""
+}
+
+// lintCommand returns the problems found in a single command
+func lintCommand(command, dir string) []string {
+	var problems []string
+
+	switch {
+	case strings.TrimSpace(command) == "":
+		return []string{"command is empty"}
+	case strings.ContainsAny(command, "\n\r"):
+		problems = append(problems, "contains a line break, which tmux sends as Enter in the middle of the command")
+	case strings.HasSuffix(strings.TrimSpace(command), ";") && !strings.HasSuffix(strings.TrimSpace(command), `\;`):
+		problems = append(problems, "ends with ';', which tmux treats as a command separator and drops")
+	case strings.HasPrefix(command, "-"):
+		problems = append(problems, "starts with '-', which tmux send-keys parses as a flag")
+	case tmuxKeyName.MatchString(command):
+		problems = append(problems, fmt.Sprintf("%q is a tmux key name and would be sent as a key press", command))
+	}
+	if strings.Contains(command, "\t") {
+		problems = append(problems, "contains a tab, which triggers shell completion when sent")
+	}
+
+	if msg := lintExecutable(command, dir); msg != "" {
+		problems = append(problems, msg)
+	}
+	return problems
+}
+
+// lintHookScript checks that a hook naming a script by a relative path runs
+// one inside the repository, where koh runs it
+func lintHookScript(command, repoRoot string) string {
+	script := ScriptPath(command)
+	if script == "" || filepath.IsAbs(script) {
+		return ""
+	}
+	if err := validation.ValidatePathWithinRepository(filepath.Join(repoRoot, script), repoRoot); err != nil {
+		return fmt.Sprintf("script %s: %v", script, err)
+	}
+	return ""
+}
+
+// commandProgram returns the program a command runs, skipping leading
+// environment assignments such as FOO=bar, or "" when it can't be told
+// without a shell
+func commandProgram(command string) string {
+	for _, field := range strings.Fields(command) {
+		if strings.Contains(field, "=") && !strings.HasPrefix(field, "=") {
+			continue
+		}
+		if strings.ContainsAny(field, "$`'\"~*?") {
+			return ""
+		}
+		return field
+	}
+	return ""
+}
+
+// ScriptPath returns the script a command runs when it names one by path,
+// such as "./bin/setup", and "" when it runs a program from PATH
+func ScriptPath(command string) string {
+	if program := commandProgram(command); strings.Contains(program, "/") {
+		return program
+	}
+	return ""
+}
+
+// lintExecutable checks that the program a command runs exists
+func lintExecutable(command, dir string) string {
+	program := commandProgram(command)
+	if program == "" || shellBuiltins[program] {
+		return ""
+	}
+
+	if strings.Contains(program, "/") {
+		scriptPath := program
+		if !filepath.IsAbs(scriptPath) {
+			scriptPath = filepath.Join(dir, scriptPath)
+		}
+		info, err := os.Stat(scriptPath)
+		if err != nil {
+			return fmt.Sprintf("script %s does not exist", program)
+		}
+		if info.Mode()&0111 == 0 {
+			return fmt.Sprintf("script %s is not executable (run 'chmod +x %s')", program, program)
+		}
+		return ""
+	}
+
+	if _, err := exec.LookPath(program); err != nil {
+		return fmt.Sprintf("command %q not found on PATH", program)
+	}
+	return ""
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	//nolint:gosec // G306: Test file - executable script needs 0755
+	if err := os.WriteFile(filepath.Join(dir, "setup"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(filepath.Join(dir, "noexec"), []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"existing script", "./setup", ""},
+		{"builtin", "cd web && echo ready", ""},
+		{"env assignment", "FOO=bar sh -c true", ""},
+		{"missing script", "./bin/missing", "does not exist"},
+		{"script not executable", "./noexec --flag", "not executable"},
+		{"missing executable", "definitely-not-a-real-command-xyz", "not found on PATH"},
+		{"trailing semicolon", "sh -c true;", "command separator"},
+		{"line break", "sh\nvim", "line break"},
+		{"key name", "Enter", "key name"},
+		{"leading dash", "-v", "flag"},
+		{"empty", "  ", "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := lintCommand(tt.command, dir)
+			if tt.want == "" {
+				if len(problems) != 0 {
+					t.Errorf("Expected no problems, got %v", problems)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(problems, "\n"), tt.want) {
+				t.Errorf("Expected a problem containing %q, got %v", tt.want, problems)
+			}
+		})
+	}
+}
+
+func TestConfigLintSources(t *testing.T) {
+	cfg := &Config{
+		SetupScript:  "./missing-setup",
+		PaneCommands: PlainPaneCommands([]string{"sh", "missing-command-xyz"}),
+		MainCheckout: &MainCheckout{PaneCommands: PlainPaneCommands([]string{"Escape"})},
+		GitConfig:    map[string]string{"user.email": "work@example.com", "email": "oops"},
+		Profiles: map[string]*Profile{
+			"backend": {SetupScript: "./missing-backend-setup", PaneCommands: PlainPaneCommands([]string{"sh"})},
+		},
+		Hooks: &Hooks{PostCreate: []string{"echo created; exit 0", "./missing-hook", ""}},
+	}
+
+	warnings := cfg.Lint(t.TempDir())
+
+	sources := map[string]bool{}
+	for _, w := range warnings {
+		sources[w.Source] = true
+	}
+	for _, want := range []string{"setup_script", "pane_commands[1]", "main_checkout.pane_commands[0]", "git_config", "profiles.backend.setup_script", "hooks.post_create[1]", "hooks.post_create[2]"} {
+		if !sources[want] {
+			t.Errorf("Expected a warning for %s, got %v", want, warnings)
+		}
+	}
+	if sources["pane_commands[0]"] || sources["profiles.backend.pane_commands[0]"] || sources["hooks.post_create[0]"] {
+		t.Errorf("Expected no warning for a valid command, got %v", warnings)
+	}
+	for _, w := range warnings {
+		if w.Source == "git_config" && w.Command != "email" {
+			t.Errorf("Expected only the invalid git_config key to be flagged, got %v", w)
+		}
+	}
+}
+
+func TestConfigLintWaitForSetup(t *testing.T) {
+	cfg := &Config{PaneCommands: PlainPaneCommands([]string{"sh"}), WaitForSetup: true}
+
+	warnings := cfg.Lint(t.TempDir())
+	if len(warnings) != 1 || warnings[0].Source != "wait_for_setup" {
+		t.Errorf("Expected a wait_for_setup warning without a setup script, got %v", warnings)
+	}
+}
+
+func TestLintPaneOptions(t *testing.T) {
+	cfg := &Config{PaneCommands: []PaneCommand{
+		{Command: "sh", Delay: "soon"},
+		{Command: "sh", Retries: -1},
+		{Command: "sh", Delay: "5s", Retries: 3},
+		// Braces other than the placeholders belong to the command
+		{Command: "sh -c 'echo {{.ID}}'"},
+		{Command: "sh {{.File}}:{{.Line}}"},
+	}}
+
+	warnings := cfg.Lint(t.TempDir())
+	want := []string{"pane_commands[0]", "pane_commands[1]"}
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %v", len(want), warnings)
+	}
+	for i, source := range want {
+		if warnings[i].Source != source {
+			t.Errorf("Expected a warning for %s, got %v", source, warnings[i])
+		}
+	}
+}
+
+func TestLintWorktreeDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{dir: ".", want: true},
+		{dir: ".git/trees", want: true},
+		{dir: ".github-trees", want: false},
+		{dir: "../trees", want: false},
+		{dir: "/worktrees/app", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			cfg := &Config{WorktreeDir: tt.dir}
+			warned := false
+			for _, w := range cfg.Lint("/repo") {
+				warned = warned || w.Source == "worktree_dir"
+			}
+			if warned != tt.want {
+				t.Errorf("Expected warning %v, got %v", tt.want, warned)
+			}
+		})
+	}
+}
+
+func TestLintCopyFiles(t *testing.T) {
+	cfg := &Config{
+		CopyFiles:     []string{".env*", "config/master.key", "/etc/passwd", "../secrets", "[", ""},
+		CopyFilesMode: "tracked",
+	}
+
+	var sources []string
+	for _, w := range cfg.Lint(t.TempDir()) {
+		sources = append(sources, w.Source+" "+w.Command)
+	}
+	want := []string{"copy_files /etc/passwd", "copy_files ../secrets", "copy_files [", "copy_files ", "copy_files_mode tracked"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("Expected warnings %v, got %v", want, sources)
+	}
+}
+
+func TestLintDiskQuota(t *testing.T) {
+	cfg := &Config{DiskQuota: &DiskQuota{Worktree: "2GB", Total: "lots"}}
+
+	var sources []string
+	for _, w := range cfg.Lint(t.TempDir()) {
+		sources = append(sources, w.Source+" "+w.Command)
+	}
+	want := []string{"disk_quota.total lots"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("Expected warnings %v, got %v", want, sources)
+	}
+}
+
+func TestLintBranchTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		want     bool
+	}{
+		{template: "{{user}}/{{name}}", want: false},
+		{template: "feature/{{ name }}", want: false},
+		{template: "{{user}}/work", want: true},
+		{template: "{{user}/{{name}}", want: true},
+		{template: "{{team}}/{{name}}", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			warnings := (&Config{BranchTemplate: tt.template}).Lint(t.TempDir())
+			if got := len(warnings) > 0; got != tt.want {
+				t.Errorf("Expected a warning: %v, got %v", tt.want, warnings)
+			}
+		})
+	}
+}
+
+func TestLintPreClose(t *testing.T) {
+	cfg := &Config{Cleanup: &Cleanup{PreClose: []PreClose{
+		{Pane: "editor", Keys: ":wqa"},
+		{Pane: "", Keys: ":wqa"},
+		{Pane: "nvim"},
+	}}}
+
+	var sources []string
+	for _, w := range cfg.Lint(t.TempDir()) {
+		sources = append(sources, w.Source)
+	}
+	want := []string{"cleanup.pre_close[1]", "cleanup.pre_close[2]"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("Expected warnings %v, got %v", want, sources)
+	}
+}
+
+func TestLintPorts(t *testing.T) {
+	tests := []struct {
+		ports Ports
+		want  []string
+	}{
+		{ports: Ports{Count: 2}, want: nil},
+		{ports: Ports{Count: 2, Start: 8000}, want: nil},
+		{ports: Ports{Count: -1, Start: 80}, want: []string{"ports.count", "ports.start"}},
+		{ports: Ports{Count: 1000}, want: []string{"ports.count"}},
+	}
+
+	for _, tt := range tests {
+		var sources []string
+		for _, w := range (&Config{Ports: &tt.ports}).Lint(t.TempDir()) {
+			sources = append(sources, w.Source)
+		}
+		if !reflect.DeepEqual(sources, tt.want) {
+			t.Errorf("Expected warnings for %v with %+v, got %v", tt.want, tt.ports, sources)
+		}
+	}
+}
+
+func TestLintSparsePath(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{dir: "services/api", want: false},
+		{dir: "docs/", want: false},
+		{dir: "", want: true},
+		{dir: "services/*", want: true},
+		{dir: "!docs", want: true},
+		{dir: "/etc", want: true},
+		{dir: "../other", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := lintSparsePath(tt.dir) != ""; got != tt.want {
+			t.Errorf("Expected a warning for %q: %v, got %v", tt.dir, tt.want, got)
+		}
+	}
+}
+
+func TestLintLinkDirs(t *testing.T) {
+	cfg := &Config{
+		LinkDirs: []string{"node_modules", "web/node_modules", ".venv", "", "*/node_modules", "../shared", ".", ".git/hooks"},
+		LinkMode: "reflink",
+	}
+
+	var got []string
+	for _, w := range cfg.Lint(t.TempDir()) {
+		got = append(got, w.Source+" "+w.Command)
+	}
+	want := []string{"link_dirs ", "link_dirs */node_modules", "link_dirs ../shared", "link_dirs .", "link_dirs .git/hooks", "link_mode reflink"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected warnings %v, got %v", want, got)
+	}
+}
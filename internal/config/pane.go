@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/shell"
+)
+
+// PaneCommand is an entry of pane_commands. It is written either as a plain
+// command string or, to hold a flaky command back, as an object:
+//
+//	{"command": "npm run dev", "delay": "5s", "retries": 3}
+type PaneCommand struct {
+	Command string `json:"command"`
+
+	// Delay is a duration such as "5s" to wait before running the command,
+	// and between retries
+	Delay string `json:"delay,omitempty"`
+
+	// Retries is how many more times the command runs when it fails
+	Retries int `json:"retries,omitempty"`
+}
+
+// PlainPaneCommands returns pane commands without delays or retries
+func PlainPaneCommands(commands []string) []PaneCommand {
+	panes := make([]PaneCommand, 0, len(commands))
+	for _, command := range commands {
+		panes = append(panes, PaneCommand{Command: command})
+	}
+	return panes
+}
+
+// UnmarshalJSON accepts a command string or an object
+func (p *PaneCommand) UnmarshalJSON(data []byte) error {
+	var command string
+	if err := json.Unmarshal(data, &command); err == nil {
+		*p = PaneCommand{Command: command}
+		return nil
+	}
+
+	// A distinct type keeps json from calling UnmarshalJSON again
+	type object PaneCommand
+	var o object
+	if err := json.Unmarshal(data, &o); err != nil {
+		return fmt.Errorf("pane command must be a string or an object with a command: %w", err)
+	}
+	*p = PaneCommand(o)
+	return nil
+}
+
+// MarshalJSON writes a plain command string unless a delay or retries are set
+func (p PaneCommand) MarshalJSON() ([]byte, error) {
+	if p.Delay == "" && p.Retries == 0 {
+		return json.Marshal(p.Command)
+	}
+	type object PaneCommand
+	return json.Marshal(object(p))
+}
+
+// DelayDuration returns the parsed delay, or 0 when there is none
+func (p PaneCommand) DelayDuration() (time.Duration, error) {
+	if p.Delay == "" {
+		return 0, nil
+	}
+	delay, err := time.ParseDuration(p.Delay)
+	if err != nil {
+		return 0, fmt.Errorf("invalid delay %q: %w", p.Delay, err)
+	}
+	if delay < 0 {
+		return 0, fmt.Errorf("invalid delay %q: must not be negative", p.Delay)
+	}
+	return delay, nil
+}
+
+// String returns the command followed by its delay and retries, if any
+func (p PaneCommand) String() string {
+	var options []string
+	if p.Delay != "" {
+		options = append(options, "delay "+p.Delay)
+	}
+	switch {
+	case p.Retries == 1:
+		options = append(options, "1 retry")
+	case p.Retries > 1:
+		options = append(options, fmt.Sprintf("%d retries", p.Retries))
+	}
+	if len(options) == 0 {
+		return p.Command
+	}
+	return fmt.Sprintf("%s (%s)", p.Command, strings.Join(options, ", "))
+}
+
+// PaneTemplateData is what pane commands can refer to with the placeholders
+// {{.File}} and {{.Line}}, e.g. "nvim +{{.Line}} {{.File}}"
+type PaneTemplateData struct {
+	// File is the file passed with 'koh new --file', relative to the worktree
+	File string
+	// Line is the line passed with the file, 0 when none
+	Line int
+}
+
+// Render returns the pane command with {{.File}} and {{.Line}} filled in.
+// Anything else in braces, such as docker's --format '{{.ID}}', is left for
+// the command itself. The file is quoted for the shell, and left out when
+// there is none.
+func (p PaneCommand) Render(data PaneTemplateData) PaneCommand {
+	file := ""
+	if data.File != "" {
+		file = shell.Quote(data.File)
+	}
+	p.Command = strings.NewReplacer(
+		"{{.File}}", file,
+		"{{.Line}}", strconv.Itoa(data.Line),
+	).Replace(p.Command)
+	return p
+}
+
+// RenderPaneCommands returns a copy of the config with the placeholders of
+// its pane commands filled in
+func (c *Config) RenderPaneCommands(data PaneTemplateData) *Config {
+	rendered := *c
+	rendered.PaneCommands = make([]PaneCommand, len(c.PaneCommands))
+	for i, pane := range c.PaneCommands {
+		rendered.PaneCommands[i] = pane.Render(data)
+	}
+	return &rendered
+}
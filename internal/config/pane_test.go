@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPaneCommandJSON(t *testing.T) {
+	var cfg Config
+	data := `{"pane_commands": ["vim", {"command": "npm run dev", "delay": "5s", "retries": 3}, ""]}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := []PaneCommand{
+		{Command: "vim"},
+		{Command: "npm run dev", Delay: "5s", Retries: 3},
+		{Command: ""},
+	}
+	if len(cfg.PaneCommands) != len(want) {
+		t.Fatalf("Expected %d pane commands, got %d", len(want), len(cfg.PaneCommands))
+	}
+	for i := range want {
+		if cfg.PaneCommands[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], cfg.PaneCommands[i])
+		}
+	}
+
+	out, err := json.Marshal(cfg.PaneCommands)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := string(out); got != `["vim",{"command":"npm run dev","delay":"5s","retries":3},""]` {
+		t.Errorf("Expected plain commands to stay strings, got %s", got)
+	}
+}
+
+func TestPaneCommandJSONInvalid(t *testing.T) {
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"pane_commands": [42]}`), &cfg); err == nil {
+		t.Error("Expected an error for a pane command that is neither a string nor an object")
+	}
+}
+
+func TestPaneCommandDelayDuration(t *testing.T) {
+	tests := []struct {
+		delay   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{delay: "", want: 0},
+		{delay: "5s", want: 5 * time.Second},
+		{delay: "1.5s", want: 1500 * time.Millisecond},
+		{delay: "five", wantErr: true},
+		{delay: "-1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := PaneCommand{Command: "x", Delay: tt.delay}.DelayDuration()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DelayDuration(%q) error = %v, wantErr %v", tt.delay, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("DelayDuration(%q): Expected %v, got %v", tt.delay, tt.want, got)
+		}
+	}
+}
+
+func TestPaneCommandString(t *testing.T) {
+	tests := []struct {
+		pane PaneCommand
+		want string
+	}{
+		{PaneCommand{Command: "vim"}, "vim"},
+		{PaneCommand{Command: "npm run dev", Delay: "5s"}, "npm run dev (delay 5s)"},
+		{PaneCommand{Command: "npm run dev", Retries: 1}, "npm run dev (1 retry)"},
+		{PaneCommand{Command: "npm run dev", Delay: "5s", Retries: 3}, "npm run dev (delay 5s, 3 retries)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pane.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestPaneCommandRender(t *testing.T) {
+	data := PaneTemplateData{File: "app/models/user.rb", Line: 42}
+	tests := []struct {
+		command string
+		data    PaneTemplateData
+		want    string
+	}{
+		{command: "npm run dev", data: data, want: "npm run dev"},
+		{command: "nvim {{.File}}", data: data, want: "nvim 'app/models/user.rb'"},
+		{command: "nvim +{{.Line}} {{.File}}", data: data, want: "nvim +42 'app/models/user.rb'"},
+		{command: "nvim {{.File}}", data: PaneTemplateData{File: "notes/it's $HOME.md"}, want: `nvim 'notes/it'\''s $HOME.md'`},
+		{command: "nvim +{{.Line}} {{.File}}", want: "nvim +0 "},
+		{command: "docker ps --format '{{.ID}}'", data: data, want: "docker ps --format '{{.ID}}'"},
+		{command: "nvim {{.File", data: data, want: "nvim {{.File"},
+	}
+
+	for _, tt := range tests {
+		got := PaneCommand{Command: tt.command, Retries: 2}.Render(tt.data)
+		if got.Command != tt.want || got.Retries != 2 {
+			t.Errorf("Render(%q): Expected %q with its options kept, got %+v", tt.command, tt.want, got)
+		}
+	}
+}
+
+func TestRenderPaneCommands(t *testing.T) {
+	cfg := &Config{SetupScript: "./bin/setup", PaneCommands: PlainPaneCommands([]string{"nvim {{.File}}", "npm run dev"})}
+
+	rendered := cfg.RenderPaneCommands(PaneTemplateData{File: "main.go"})
+	if rendered.PaneCommands[0].Command != "nvim 'main.go'" || rendered.SetupScript != "./bin/setup" {
+		t.Errorf("Expected the placeholder to be filled in, got %+v", rendered)
+	}
+	if cfg.PaneCommands[0].Command != "nvim {{.File}}" {
+		t.Errorf("Expected the original config to be unchanged, got %+v", cfg.PaneCommands)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a named alternative setup for worktrees that need different
+// tooling, e.g. "backend" and "frontend", picked with 'koh new --profile'
+type Profile struct {
+	SetupScript  string        `json:"setup_script,omitempty"`
+	PaneCommands []PaneCommand `json:"pane_commands,omitempty"`
+	// Env overrides and adds to the top-level env, variable by variable
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// ProfileNames returns the names of the configured profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForProfile returns the configuration for worktrees created with the named
+// profile: the profile's setup_script and pane_commands replace the top-level
+// ones, its env is merged over the top-level one, and settings the profile
+// leaves out are inherited. An empty name returns c itself.
+func (c *Config) ForProfile(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	profile := c.Profiles[name]
+	if profile == nil {
+		if len(c.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q\n.kohconfig defines no profiles", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q\nProfiles in .kohconfig: %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	cfg := *c
+	if profile.SetupScript != "" {
+		cfg.SetupScript = profile.SetupScript
+	}
+	if profile.PaneCommands != nil {
+		cfg.PaneCommands = profile.PaneCommands
+	}
+	if len(profile.Env) > 0 {
+		cfg.Env = make(map[string]string, len(c.Env)+len(profile.Env))
+		for key, value := range c.Env {
+			cfg.Env[key] = value
+		}
+		for key, value := range profile.Env {
+			cfg.Env[key] = value
+		}
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestForProfileEnv(t *testing.T) {
+	cfg := &Config{
+		Env: map[string]string{"DATABASE_URL": "postgres:///app", "RAILS_ENV": "development"},
+		Profiles: map[string]*Profile{
+			"test": {Env: map[string]string{"RAILS_ENV": "test", "CI": "1"}},
+		},
+	}
+
+	got, err := cfg.ForProfile("test")
+	if err != nil {
+		t.Fatalf("ForProfile() failed: %v", err)
+	}
+	want := map[string]string{"DATABASE_URL": "postgres:///app", "RAILS_ENV": "test", "CI": "1"}
+	if !reflect.DeepEqual(got.Env, want) {
+		t.Errorf("Expected env %v, got %v", want, got.Env)
+	}
+	if cfg.Env["RAILS_ENV"] != "development" {
+		t.Error("Expected the top-level env to be left alone")
+	}
+}
+
+func TestForProfile(t *testing.T) {
+	cfg := &Config{
+		SetupScript:  "./bin/setup",
+		PaneCommands: PlainPaneCommands([]string{"vim"}),
+		WaitForSetup: true,
+		Profiles: map[string]*Profile{
+			"backend":  {SetupScript: "./bin/setup-api", PaneCommands: PlainPaneCommands([]string{"vim", "rails s"})},
+			"frontend": {PaneCommands: PlainPaneCommands([]string{"npm run dev"})},
+			"quiet":    {PaneCommands: []PaneCommand{}},
+		},
+	}
+
+	tests := []struct {
+		profile     string
+		wantSetup   string
+		wantPanes   []string
+		errContains string
+	}{
+		{profile: "", wantSetup: "./bin/setup", wantPanes: []string{"vim"}},
+		{profile: "backend", wantSetup: "./bin/setup-api", wantPanes: []string{"vim", "rails s"}},
+		{profile: "frontend", wantSetup: "./bin/setup", wantPanes: []string{"npm run dev"}},
+		{profile: "quiet", wantSetup: "./bin/setup", wantPanes: []string{}},
+		{profile: "mobile", errContains: "backend, frontend, quiet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			got, err := cfg.ForProfile(tt.profile)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ForProfile() failed: %v", err)
+			}
+
+			panes := []string{}
+			for _, pane := range got.PaneCommands {
+				panes = append(panes, pane.Command)
+			}
+			if got.SetupScript != tt.wantSetup || !slices.Equal(panes, tt.wantPanes) {
+				t.Errorf("Expected %s with panes %v, got %s with panes %v", tt.wantSetup, tt.wantPanes, got.SetupScript, panes)
+			}
+			if !got.WaitForSetup {
+				t.Error("Expected settings outside the profile to be inherited")
+			}
+		})
+	}
+
+	if cfg.SetupScript != "./bin/setup" || len(cfg.PaneCommands) != 1 {
+		t.Errorf("Expected ForProfile() to leave the config alone, got %+v", cfg)
+	}
+}
+
+func TestForProfileWithoutProfiles(t *testing.T) {
+	_, err := (&Config{}).ForProfile("backend")
+	if err == nil || !strings.Contains(err.Error(), "defines no profiles") {
+		t.Errorf("Expected an error about missing profiles, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/fsutil"
+	"github.com/bshakr/koh/internal/git"
+)
+
+// EnsureSetupScript checks if the setup script exists in the worktree.
+// If not, it looks for it in the main repo root and copies it to the worktree.
+// Returns an error if the script cannot be found or copied.
+func EnsureSetupScript(worktreePath, setupScript string) error {
+	// If setup script is empty, nothing to do
+	if setupScript == "" {
+		return nil
+	}
+
+	// Check if the setup script path is absolute
+	var scriptPath string
+	if filepath.IsAbs(setupScript) {
+		scriptPath = setupScript
+	} else {
+		scriptPath = filepath.Join(worktreePath, setupScript)
+	}
+
+	// Check if the script exists in the worktree
+	if _, err := os.Stat(scriptPath); err == nil {
+		// Script exists in worktree, nothing to do
+		return nil
+	}
+
+	// Script doesn't exist in worktree, try to copy from main repo
+	// Get the main repo root
+	mainRepoRoot, err := git.GetMainRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get main repo root: %w", err)
+	}
+
+	// Check if the script exists in the main repo root
+	mainRepoScriptPath := filepath.Join(mainRepoRoot, setupScript)
+	if _, err := os.Stat(mainRepoScriptPath); os.IsNotExist(err) {
+		// Script doesn't exist in main repo either
+		return fmt.Errorf("setup script not found in worktree or main repo: %s", setupScript)
+	}
+
+	// Copy the script from main repo to worktree
+	if err := fsutil.CopyFile(mainRepoScriptPath, scriptPath); err != nil {
+		return fmt.Errorf("failed to copy setup script from main repo: %w", err)
+	}
+
+	return nil
+}
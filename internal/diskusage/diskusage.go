@@ -0,0 +1,119 @@
+// Package diskusage measures how much space worktrees take up.
+//
+// Sizes are the apparent sizes of the files in a directory tree, summed
+// without following symlinks, so they are comparable across platforms and
+// close to what 'du' reports for the node_modules-style trees that fill up
+// small disks.
+package diskusage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Usage is the space a directory tree takes up
+type Usage struct {
+	// Bytes is the summed size of the files in the tree
+	Bytes int64 `json:"bytes"`
+	// Largest is the biggest entry directly inside the tree, such as
+	// node_modules, "" for an empty tree
+	Largest string `json:"largest,omitempty"`
+	// LargestBytes is the size of Largest
+	LargestBytes int64 `json:"largest_bytes,omitempty"`
+}
+
+// MeasureWithContext returns the space the tree at root takes up. Files that
+// vanish or can't be read while it walks are skipped.
+func MeasureWithContext(ctx context.Context, root string) (Usage, error) {
+	var usage Usage
+	top := map[string]int64{}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Unreadable directories count as empty
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		usage.Bytes += info.Size()
+		if rel, err := filepath.Rel(root, path); err == nil {
+			top[strings.SplitN(rel, string(filepath.Separator), 2)[0]] += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to measure %s: %w", root, err)
+	}
+
+	for name, bytes := range top {
+		if bytes > usage.LargestBytes || (bytes == usage.LargestBytes && name < usage.Largest) {
+			usage.Largest, usage.LargestBytes = name, bytes
+		}
+	}
+	return usage, nil
+}
+
+// units are the size suffixes ParseSize accepts, in powers of 1024
+var units = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+}
+
+// ParseSize parses a size such as "500MB", "2GB", "1.5G" or "4096" (bytes).
+// Units are powers of 1024 and case doesn't matter.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(trimmed)
+	}
+
+	number, err := strconv.ParseFloat(trimmed[:i], 64)
+	unit, ok := units[strings.ToLower(strings.TrimSpace(trimmed[i:]))]
+	if err != nil || !ok || number < 0 {
+		return 0, fmt.Errorf("%q is not a size such as \"500MB\" or \"2GB\"", s)
+	}
+	return int64(number * float64(unit)), nil
+}
+
+// Format renders a size in bytes for people, e.g. "340 MB" or "1.2 GB"
+func Format(bytes int64) string {
+	switch {
+	case bytes >= 1<<40:
+		return fmt.Sprintf("%.1f TB", float64(bytes)/(1<<40))
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%d MB", bytes/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%d KB", bytes/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
@@ -0,0 +1,99 @@
+package diskusage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMeasureWithContext(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]int{
+		"README.md":                   100,
+		"node_modules/a/index.js":     3000,
+		"node_modules/b/package.json": 1000,
+		"src/main.go":                 500,
+	}
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	// Symlinks aren't followed, so what they point to isn't counted twice
+	if err := os.Symlink(filepath.Join(root, "node_modules"), filepath.Join(root, "modules")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	usage, err := MeasureWithContext(context.Background(), root)
+	if err != nil {
+		t.Fatalf("MeasureWithContext() failed: %v", err)
+	}
+	want := Usage{Bytes: 4600, Largest: "node_modules", LargestBytes: 4000}
+	if usage != want {
+		t.Errorf("Expected %+v, got %+v", want, usage)
+	}
+
+	if _, err := MeasureWithContext(context.Background(), filepath.Join(root, "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := MeasureWithContext(ctx, root); err == nil {
+		t.Error("Expected an error with a cancelled context")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "4096", want: 4096},
+		{in: "500MB", want: 500 << 20},
+		{in: "2GB", want: 2 << 30},
+		{in: "1.5g", want: 3 << 29},
+		{in: "10 KB", want: 10 << 10},
+		{in: "1T", want: 1 << 40},
+		{in: "", wantErr: true},
+		{in: "lots", wantErr: true},
+		{in: "5XB", wantErr: true},
+		{in: "-1GB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{bytes: 512, want: "512 B"},
+		{bytes: 2048, want: "2 KB"},
+		{bytes: 340 << 20, want: "340 MB"},
+		{bytes: 1288490189, want: "1.2 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(tt.bytes); got != tt.want {
+			t.Errorf("Expected Format(%d) to be %q, got %q", tt.bytes, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package forge queries the code hosting service (forge) for pull request
+// information about worktree branches.
+//
+// The GitHub CLI (gh) is used when it is installed and authenticated; koh
+// never talks to the forge API directly or handles tokens itself. When gh is
+// unavailable every query reports ErrUnavailable and callers simply omit
+// forge information from their output.
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrUnavailable is returned when the gh CLI is not installed
+var ErrUnavailable = errors.New("gh CLI not available")
+
+// PR states reported by PullRequest.State
+const (
+	StateOpen   = "open"
+	StateDraft  = "draft"
+	StateMerged = "merged"
+	StateClosed = "closed"
+)
+
+// CI states reported by PullRequest.CI
+const (
+	CIPassing = "passing"
+	CIFailing = "failing"
+	CIPending = "pending"
+)
+
+// PullRequest summarizes a pull request for a branch
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Branch string `json:"branch"`
+	State  string `json:"state"`
+	CI     string `json:"ci,omitempty"`
+	URL    string `json:"url"`
+	// HeadSHA is the commit the PR's branch pointed at when last pushed
+	HeadSHA string `json:"head_sha"`
+	// Base is the branch the PR merges into
+	Base string `json:"base,omitempty"`
+	// CrossRepository is set when the PR's branch lives in a fork. It is
+	// only filled in by GetPullRequest.
+	CrossRepository bool `json:"cross_repository,omitempty"`
+}
+
+// ghPullRequest is the subset of 'gh pr list --json' output koh uses
+type ghPullRequest struct {
+	Number            int       `json:"number"`
+	Title             string    `json:"title"`
+	State             string    `json:"state"`
+	IsDraft           bool      `json:"isDraft"`
+	URL               string    `json:"url"`
+	HeadRefName       string    `json:"headRefName"`
+	HeadRefOid        string    `json:"headRefOid"`
+	BaseRefName       string    `json:"baseRefName"`
+	StatusCheckRollup []ghCheck `json:"statusCheckRollup"`
+	IsCrossRepository bool      `json:"isCrossRepository"`
+}
+
+// ghFields are the fields requested from gh for every pull request
+const ghFields = "number,title,state,isDraft,url,headRefName,headRefOid,baseRefName,statusCheckRollup"
+
+// ghCheck is either a check run (status/conclusion) or a commit status (state)
+type ghCheck struct {
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	State      string `json:"state"`
+}
+
+// Available reports whether the gh CLI is installed
+func Available() bool {
+	_, err := exec.LookPath("gh")
+	return err == nil
+}
+
+// ListPullRequests returns recent pull requests of the current repository in
+// any state, most recent first
+func ListPullRequests(ctx context.Context) ([]PullRequest, error) {
+	if !Available() {
+		return nil, ErrUnavailable
+	}
+
+	cmd := exec.CommandContext(ctx, "gh", "pr", "list",
+		"--state", "all",
+		"--limit", "200",
+		"--json", ghFields)
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	return parsePullRequests(output)
+}
+
+// parsePullRequests converts 'gh pr list --json' output into pull requests
+func parsePullRequests(data []byte) ([]PullRequest, error) {
+	var raw []ghPullRequest
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse pull requests: %w", err)
+	}
+
+	prs := make([]PullRequest, 0, len(raw))
+	for _, r := range raw {
+		prs = append(prs, r.pullRequest())
+	}
+	return prs, nil
+}
+
+// pullRequest converts gh output for a single pull request
+func (r ghPullRequest) pullRequest() PullRequest {
+	return PullRequest{
+		Number:          r.Number,
+		Title:           r.Title,
+		Branch:          r.HeadRefName,
+		State:           prState(r.State, r.IsDraft),
+		CI:              rollupCI(r.StatusCheckRollup),
+		URL:             r.URL,
+		HeadSHA:         r.HeadRefOid,
+		Base:            r.BaseRefName,
+		CrossRepository: r.IsCrossRepository,
+	}
+}
+
+// GetPullRequest returns the pull request with the given number in the
+// current repository
+func GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	if !Available() {
+		return nil, ErrUnavailable
+	}
+
+	//nolint:gosec // G204: number is an integer
+	cmd := exec.CommandContext(ctx, "gh", "pr", "view", strconv.Itoa(number), "--json", ghFields+",isCrossRepository")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("failed to look up pull request #%d: %s", number, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to look up pull request #%d: %w", number, err)
+	}
+
+	var raw ghPullRequest
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse pull request: %w", err)
+	}
+	pr := raw.pullRequest()
+	return &pr, nil
+}
+
+// prState maps gh's PR state to koh's state names
+func prState(state string, draft bool) string {
+	switch strings.ToUpper(state) {
+	case "MERGED":
+		return StateMerged
+	case "CLOSED":
+		return StateClosed
+	default:
+		if draft {
+			return StateDraft
+		}
+		return StateOpen
+	}
+}
+
+// rollupCI reduces all checks of a PR to a single CI state: failing if any
+// check failed, pending if any is still running, passing otherwise
+func rollupCI(checks []ghCheck) string {
+	if len(checks) == 0 {
+		return ""
+	}
+
+	pending := false
+	for _, c := range checks {
+		result := strings.ToUpper(c.Conclusion)
+		if result == "" {
+			result = strings.ToUpper(c.State)
+		}
+
+		switch result {
+		case "FAILURE", "ERROR", "TIMED_OUT", "CANCELLED", "ACTION_REQUIRED", "STARTUP_FAILURE":
+			return CIFailing
+		case "SUCCESS", "NEUTRAL", "SKIPPED":
+		default:
+			// Empty conclusion on an in-progress run, or PENDING/EXPECTED status
+			pending = true
+		}
+	}
+
+	if pending {
+		return CIPending
+	}
+	return CIPassing
+}
+
+// ForBranch returns the most relevant pull request for a branch: an open one
+// if present, otherwise the most recent. Returns nil when there is none.
+func ForBranch(prs []PullRequest, branch string) *PullRequest {
+	var found *PullRequest
+	for i := range prs {
+		if prs[i].Branch != branch {
+			continue
+		}
+		if prs[i].State == StateOpen || prs[i].State == StateDraft {
+			return &prs[i]
+		}
+		if found == nil {
+			found = &prs[i]
+		}
+	}
+	return found
+}
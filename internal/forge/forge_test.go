@@ -0,0 +1,137 @@
+package forge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParsePullRequests(t *testing.T) {
+	data := []byte(`[
+  {"number": 12, "title": "Login", "state": "OPEN", "isDraft": false, "url": "https://example.com/12", "headRefName": "login",
+   "statusCheckRollup": [{"status": "COMPLETED", "conclusion": "SUCCESS"}, {"state": "SUCCESS"}]},
+  {"number": 11, "title": "WIP", "state": "OPEN", "isDraft": true, "url": "https://example.com/11", "headRefName": "wip",
+   "statusCheckRollup": [{"status": "IN_PROGRESS", "conclusion": ""}]},
+  {"number": 10, "title": "Old", "state": "MERGED", "isDraft": false, "url": "https://example.com/10", "headRefName": "old", "baseRefName": "develop",
+   "statusCheckRollup": [{"status": "COMPLETED", "conclusion": "FAILURE"}]}
+]`)
+
+	prs, err := parsePullRequests(data)
+	if err != nil {
+		t.Fatalf("parsePullRequests() failed: %v", err)
+	}
+	if len(prs) != 3 {
+		t.Fatalf("Expected 3 pull requests, got %d", len(prs))
+	}
+
+	tests := []struct {
+		state string
+		ci    string
+	}{
+		{StateOpen, CIPassing},
+		{StateDraft, CIPending},
+		{StateMerged, CIFailing},
+	}
+	for i, tt := range tests {
+		if prs[i].State != tt.state || prs[i].CI != tt.ci {
+			t.Errorf("prs[%d] = state %q ci %q, want state %q ci %q", i, prs[i].State, prs[i].CI, tt.state, tt.ci)
+		}
+	}
+	if prs[2].Base != "develop" {
+		t.Errorf("Expected base %q, got %q", "develop", prs[2].Base)
+	}
+}
+
+func TestRollupCINoChecks(t *testing.T) {
+	if got := rollupCI(nil); got != "" {
+		t.Errorf("Expected empty CI state without checks, got %q", got)
+	}
+}
+
+func TestForBranchPrefersOpen(t *testing.T) {
+	prs := []PullRequest{
+		{Number: 3, Branch: "feature", State: StateClosed},
+		{Number: 2, Branch: "feature", State: StateOpen},
+		{Number: 1, Branch: "other", State: StateMerged},
+	}
+
+	if pr := ForBranch(prs, "feature"); pr == nil || pr.Number != 2 {
+		t.Errorf("Expected open PR #2, got %+v", pr)
+	}
+	if pr := ForBranch(prs, "other"); pr == nil || pr.Number != 1 {
+		t.Errorf("Expected most recent PR #1, got %+v", pr)
+	}
+	if pr := ForBranch(prs, "missing"); pr != nil {
+		t.Errorf("Expected no PR, got %+v", pr)
+	}
+}
+
+func TestGhPullRequestCrossRepository(t *testing.T) {
+	var raw ghPullRequest
+	if err := json.Unmarshal([]byte(`{"number": 7, "headRefName": "main", "state": "OPEN", "isCrossRepository": true}`), &raw); err != nil {
+		t.Fatal(err)
+	}
+	pr := raw.pullRequest()
+	if !pr.CrossRepository || pr.Branch != "main" || pr.State != StateOpen {
+		t.Errorf("Expected an open pull request from a fork, got %+v", pr)
+	}
+}
+
+func TestParseProtection(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch ghBranch
+		rules  string
+		want   Protection
+	}{
+		{name: "unprotected", want: Protection{}},
+		{name: "classic protection", branch: ghBranch{Protected: true}, rules: `[]`, want: Protection{Protected: true, NoDeletion: true}},
+		{name: "deletion rule", rules: `[{"type": "deletion"}, {"type": "pull_request"}]`, want: Protection{Protected: true, NoDeletion: true}},
+		{name: "other rules", rules: `[{"type": "required_linear_history"}]`, want: Protection{Protected: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseProtection(tt.branch, []byte(tt.rules)); *got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestLatestRuns(t *testing.T) {
+	data := []byte(`[
+  {"databaseId": 5, "workflowName": "test", "status": "in_progress", "conclusion": "", "headSha": "b2", "url": "https://example.com/5"},
+  {"databaseId": 4, "workflowName": "lint", "status": "completed", "conclusion": "success", "headSha": "b2", "url": "https://example.com/4"},
+  {"databaseId": 3, "workflowName": "test", "status": "completed", "conclusion": "failure", "headSha": "b2", "url": "https://example.com/3"},
+  {"databaseId": 2, "workflowName": "deploy", "status": "completed", "conclusion": "failure", "headSha": "a1", "url": "https://example.com/2"}
+]`)
+
+	runs, err := parseRuns(data)
+	if err != nil {
+		t.Fatalf("parseRuns() failed: %v", err)
+	}
+	latest := LatestRuns(runs)
+	var ids []int64
+	for _, r := range latest {
+		ids = append(ids, r.ID)
+	}
+	if want := []int64{5, 4}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected runs %v, got %v", want, ids)
+	}
+
+	tests := []struct {
+		name string
+		runs []Run
+		want string
+	}{
+		{name: "re-run still running", runs: latest, want: CIPending},
+		{name: "failed", runs: runs, want: CIFailing},
+		{name: "passed", runs: latest[1:], want: CIPassing},
+		{name: "no runs", runs: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := RollupRuns(tt.runs); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// Protection is whether the forge protects a branch and forbids deleting
+// it. Required reviews and status checks aren't looked up, since koh never
+// merges or force-pushes.
+type Protection struct {
+	// Protected is set when branch protection or a ruleset applies
+	Protected bool `json:"protected"`
+	// NoDeletion is set when the forge rejects deleting the branch
+	NoDeletion bool `json:"no_deletion,omitempty"`
+}
+
+// ghBranch is the subset of the GitHub branch API koh uses
+type ghBranch struct {
+	Protected bool `json:"protected"`
+}
+
+// ghRule is a rule of a ruleset that applies to a branch
+type ghRule struct {
+	Type string `json:"type"`
+}
+
+// GetBranchProtection returns the protection of a branch of the current
+// repository, for 'koh cleanup --delete-remote-branch'. A branch under classic branch protection can't be deleted
+// unless an admin allowed it, which gh can't tell without admin rights, so
+// every protected branch is reported as not deletable.
+func GetBranchProtection(ctx context.Context, branch string) (*Protection, error) {
+	if !Available() {
+		return nil, ErrUnavailable
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(branch, "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	escaped := strings.Join(segments, "/")
+
+	//nolint:gosec // G204: the branch is escaped into the API path
+	output, err := exec.CommandContext(ctx, "gh", "api", "repos/{owner}/{repo}/branches/"+escaped).Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to look up protection of %s: %w", branch, err)
+	}
+	var b ghBranch
+	if err := json.Unmarshal(output, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse branch %s: %w", branch, err)
+	}
+
+	// Rulesets are listed separately; servers without them just fail here
+	//nolint:gosec // G204: the branch is escaped into the API path
+	rules, _ := exec.CommandContext(ctx, "gh", "api", "repos/{owner}/{repo}/rules/branches/"+escaped).Output()
+	return parseProtection(b, rules), nil
+}
+
+// parseProtection combines a branch with the rules that apply to it, as
+// returned by the rules API (nil when unavailable)
+func parseProtection(b ghBranch, rules []byte) *Protection {
+	p := &Protection{Protected: b.Protected, NoDeletion: b.Protected}
+
+	var parsed []ghRule
+	if json.Unmarshal(rules, &parsed) == nil {
+		for _, rule := range parsed {
+			p.Protected = true
+			if rule.Type == "deletion" {
+				p.NoDeletion = true
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,116 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Run is a CI workflow run for a branch
+type Run struct {
+	ID       int64  `json:"id"`
+	Workflow string `json:"workflow"`
+	// State is CIPassing, CIFailing or CIPending
+	State   string `json:"state"`
+	HeadSHA string `json:"head_sha"`
+	URL     string `json:"url"`
+}
+
+// ghRun is the subset of 'gh run list --json' output koh uses
+type ghRun struct {
+	DatabaseID   int64  `json:"databaseId"`
+	WorkflowName string `json:"workflowName"`
+	Status       string `json:"status"`
+	Conclusion   string `json:"conclusion"`
+	HeadSha      string `json:"headSha"`
+	URL          string `json:"url"`
+}
+
+// ListRuns returns the recent CI runs of a branch, most recent first
+func ListRuns(ctx context.Context, branch string) ([]Run, error) {
+	if !Available() {
+		return nil, ErrUnavailable
+	}
+
+	//nolint:gosec // G204: the branch is passed as a single argument
+	cmd := exec.CommandContext(ctx, "gh", "run", "list",
+		"--branch", branch,
+		"--limit", "50",
+		"--json", "databaseId,workflowName,status,conclusion,headSha,url")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("failed to list CI runs: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to list CI runs: %w", err)
+	}
+	return parseRuns(output)
+}
+
+// parseRuns converts 'gh run list --json' output into runs
+func parseRuns(data []byte) ([]Run, error) {
+	var raw []ghRun
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse CI runs: %w", err)
+	}
+
+	runs := make([]Run, 0, len(raw))
+	for _, r := range raw {
+		runs = append(runs, Run{
+			ID:       r.DatabaseID,
+			Workflow: r.WorkflowName,
+			State:    rollupCI([]ghCheck{{Status: r.Status, Conclusion: r.Conclusion}}),
+			HeadSHA:  r.HeadSha,
+			URL:      r.URL,
+		})
+	}
+	return runs, nil
+}
+
+// LatestRuns returns the runs of the most recently tested commit in runs
+// (most recent first), one per workflow
+func LatestRuns(runs []Run) []Run {
+	if len(runs) == 0 {
+		return nil
+	}
+	head := runs[0].HeadSHA
+	seen := map[string]bool{}
+	var latest []Run
+	for _, r := range runs {
+		if r.HeadSHA != head || seen[r.Workflow] {
+			continue
+		}
+		seen[r.Workflow] = true
+		latest = append(latest, r)
+	}
+	return latest
+}
+
+// RollupRuns reduces runs to a single CI state like a pull request's
+// checks: failing if any run failed, pending if any is still running,
+// passing otherwise and "" without runs
+func RollupRuns(runs []Run) string {
+	pending := false
+	for _, r := range runs {
+		switch r.State {
+		case CIFailing:
+			return CIFailing
+		case CIPending:
+			pending = true
+		}
+	}
+	switch {
+	case len(runs) == 0:
+		return ""
+	case pending:
+		return CIPending
+	}
+	return CIPassing
+}
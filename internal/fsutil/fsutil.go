@@ -0,0 +1,47 @@
+// Package fsutil holds file system helpers shared by the packages that set
+// up worktrees, such as copying files from the main checkout into one.
+package fsutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyFile copies the regular file src to dst, creating the directories
+// leading to dst and keeping src's permissions. An existing dst is
+// overwritten.
+func CopyFile(src, dst string) error {
+	//nolint:gosec // G304: callers copy files they found in the repository
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	//nolint:gosec // G304: dst is inside the worktree being set up
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	// The mode given to OpenFile is masked by the umask and ignored for a
+	// file that already exists
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,47 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "run.sh")
+	//nolint:gosec // G306: Test script needs to be executable
+	if err := os.WriteFile(src, []byte("echo hi\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "worktree", "bin", "run.sh")
+	if err := CopyFile(src, dst); err != nil {
+		t.Fatalf("CopyFile() failed: %v", err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("Expected the copy to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("Expected permissions 0700 to be kept, got %v", info.Mode().Perm())
+	}
+
+	// Copying over an existing file replaces its contents and permissions
+	if err := os.WriteFile(dst, []byte("a much longer old script\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyFile(src, dst); err != nil {
+		t.Fatalf("CopyFile() failed: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || string(data) != "echo hi\n" {
+		t.Errorf("Expected the copy to replace the old contents, got %q (%v)", data, err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected permissions 0700 on the replaced file, got %v (%v)", info, err)
+	}
+
+	if err := CopyFile(filepath.Join(dir, "missing"), filepath.Join(dir, "copy")); err == nil {
+		t.Error("Expected an error for a missing source file")
+	}
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// Identity is who commits in the repository, as configured in git
+type Identity struct {
+	// Name and Email are user.name and user.email
+	Name  string
+	Email string
+	// GitHubUser is github.user, set by some tools and by hand
+	GitHubUser string
+}
+
+// IdentityWithContext reads the identity git commits with in the current
+// repository, including global and system settings
+func IdentityWithContext(ctx context.Context) Identity {
+	return Identity{
+		Name:       configValue(ctx, "user.name"),
+		Email:      configValue(ctx, "user.email"),
+		GitHubUser: configValue(ctx, "github.user"),
+	}
+}
+
+// configValue returns a git config value, "" when it isn't set
+func configValue(ctx context.Context, key string) string {
+	output, err := exec.CommandContext(ctx, "git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Handle returns a short name for the identity that fits in a branch name,
+// such as "bshakr": github.user when set, otherwise the part of user.email
+// before the @, otherwise user.name in lower case with dashes for spaces
+func (id Identity) Handle() (string, error) {
+	candidates := []string{id.GitHubUser}
+	if local, _, ok := strings.Cut(id.Email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+	candidates = append(candidates, strings.ToLower(strings.Join(strings.Fields(id.Name), "-")))
+
+	for _, candidate := range candidates {
+		if handle := branchSafe(candidate); handle != "" {
+			return handle, nil
+		}
+	}
+	return "", fmt.Errorf("no git identity to name branches after\nSet one with 'git config --global github.user <name>' or 'git config --global user.email <email>'")
+}
+
+// branchSafe drops the characters git doesn't allow in branch names, and
+// dots and dashes at the ends
+func branchSafe(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Trim(strings.ReplaceAll(b.String(), "..", "."), ".-")
+}
+
+// CheckBranchNameWithContext reports whether name is a valid branch name
+func CheckBranchNameWithContext(ctx context.Context, name string) error {
+	if err := exec.CommandContext(ctx, "git", "check-ref-format", "--branch", name).Run(); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", name)
+	}
+	return nil
+}
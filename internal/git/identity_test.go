@@ -0,0 +1,68 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestIdentityHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      Identity
+		want    string
+		wantErr bool
+	}{
+		{name: "github user", id: Identity{Name: "Bassem Shakr", Email: "bassem@example.com", GitHubUser: "bshakr"}, want: "bshakr"},
+		{name: "email", id: Identity{Name: "Bassem Shakr", Email: "bassem@example.com"}, want: "bassem"},
+		{name: "name", id: Identity{Name: "Bassem  Shakr"}, want: "bassem-shakr"},
+		{name: "unsafe characters", id: Identity{Email: "~b:s?h[a]k^r*@example.com"}, want: "bshakr"},
+		{name: "none", id: Identity{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.id.Handle()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIdentityWithContext(t *testing.T) {
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "github.user", "tester"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	t.Chdir(repo)
+	want := Identity{Name: "Test User", Email: "test@example.com", GitHubUser: "tester"}
+	if got := IdentityWithContext(context.Background()); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestCheckBranchNameWithContext(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"login-fix", "bshakr/login-fix"} {
+		if err := CheckBranchNameWithContext(ctx, name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"bshakr//login-fix", "/login-fix", "login fix", "-login"} {
+		if err := CheckBranchNameWithContext(ctx, name); err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
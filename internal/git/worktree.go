@@ -16,12 +16,17 @@
 package git
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // IsGitRepo checks if the current directory is in a git repository
@@ -37,9 +42,44 @@ func CreateWorktree(path string) error {
 	return CreateWorktreeWithContext(context.Background(), path)
 }
 
+// AddOption changes how a worktree is created
+type AddOption string
+
+// NoCheckout creates a worktree without checking out any files, such as
+// for SparseCheckoutWithContext to check out only some
+const NoCheckout AddOption = "--no-checkout"
+
 // CreateWorktreeWithContext creates a new git worktree at the specified path with cancellation support
-func CreateWorktreeWithContext(ctx context.Context, path string) error {
-	cmd := exec.CommandContext(ctx, "git", "worktree", "add", path)
+func CreateWorktreeWithContext(ctx context.Context, path string, opts ...AddOption) error {
+	return addWorktree(ctx, opts, path)
+}
+
+// CreateWorktreeForBranchWithContext creates a new git worktree at the
+// specified path with an existing local branch checked out
+func CreateWorktreeForBranchWithContext(ctx context.Context, path, branch string, opts ...AddOption) error {
+	return addWorktree(ctx, opts, "--", path, branch)
+}
+
+// CreateWorktreeFromRefWithContext creates a new git worktree at the
+// specified path on a new branch started from ref (a branch, tag or commit)
+func CreateWorktreeFromRefWithContext(ctx context.Context, path, branch, ref string, opts ...AddOption) error {
+	return addWorktree(ctx, opts, "-b", branch, "--", path, ref)
+}
+
+// CreateWorktreeTrackingWithContext creates a new git worktree at the
+// specified path on a new branch that tracks remoteRef (e.g. "origin/feature")
+func CreateWorktreeTrackingWithContext(ctx context.Context, path, branch, remoteRef string, opts ...AddOption) error {
+	return addWorktree(ctx, opts, "--track", "-b", branch, "--", path, remoteRef)
+}
+
+// addWorktree runs "git worktree add" with opts and args
+func addWorktree(ctx context.Context, opts []AddOption, args ...string) error {
+	cmdArgs := []string{"worktree", "add"}
+	for _, opt := range opts {
+		cmdArgs = append(cmdArgs, string(opt))
+	}
+	//nolint:gosec // G204: Arguments are paths and refs validated by the caller
+	cmd := exec.CommandContext(ctx, "git", append(cmdArgs, args...)...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		if ctx.Err() == context.Canceled {
@@ -50,6 +90,28 @@ func CreateWorktreeWithContext(ctx context.Context, path string) error {
 	return nil
 }
 
+// SparseCheckoutWithContext limits the worktree at path to dirs with git
+// sparse-checkout in cone mode and checks them out, along with the files at
+// the top of the repository. The worktree must have been created with
+// NoCheckout, so files outside dirs are never written.
+func SparseCheckoutWithContext(ctx context.Context, path string, dirs []string) error {
+	for _, args := range [][]string{
+		{"sparse-checkout", "init", "--cone"},
+		append([]string{"sparse-checkout", "set", "--"}, dirs...),
+		{"read-tree", "-mu", "HEAD"},
+	} {
+		//nolint:gosec // G204: dirs come from the user's .kohconfig
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", path}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if ctx.Err() == context.Canceled {
+				return fmt.Errorf("operation cancelled")
+			}
+			return fmt.Errorf("git %s failed: %s", strings.Join(args[:2], " "), strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
 // RemoveWorktree removes a git worktree at the specified path
 func RemoveWorktree(path string) error {
 	return RemoveWorktreeWithContext(context.Background(), path)
@@ -113,7 +175,10 @@ func GetMainRepoRoot() (string, error) {
 		return "", fmt.Errorf("failed to get git common dir: %w", err)
 	}
 
-	commonDir := strings.TrimSpace(string(output))
+	commonDir, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git common dir: %w", err)
+	}
 	// The common dir is .git, so we need to go up one level
 	mainRepoRoot := filepath.Dir(commonDir)
 	return mainRepoRoot, nil
@@ -147,3 +212,1053 @@ func GetCurrentWorktreePath() (string, error) {
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+// GetCurrentBranch returns the branch checked out in the current worktree.
+// Returns "HEAD" when the worktree is in detached HEAD state.
+func GetCurrentBranch() (string, error) {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsDirty reports whether the worktree at path has uncommitted changes,
+// including untracked files.
+func IsDirty(path string) (bool, error) {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "status", "--porcelain")
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// Tracking is how far a branch has diverged from its upstream
+type Tracking struct {
+	Ahead  int `json:"ahead"`
+	Behind int `json:"behind"`
+}
+
+// TrackingWithContext returns how many commits the worktree at path is ahead
+// of and behind its upstream branch, or nil when it has no upstream
+func TrackingWithContext(ctx context.Context, path string) *Tracking {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "rev-list", "--left-right", "--count", "@{upstream}...HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return parseTracking(string(output))
+}
+
+// parseTracking parses "<behind>\t<ahead>" from rev-list --left-right --count
+func parseTracking(output string) *Tracking {
+	var t Tracking
+	if _, err := fmt.Sscanf(strings.TrimSpace(output), "%d %d", &t.Behind, &t.Ahead); err != nil {
+		return nil
+	}
+	return &t
+}
+
+// StartBackgroundFetch starts 'git fetch --all' in dir without waiting for
+// it, so callers aren't slowed down. The fetch never prompts for credentials
+// and its output is discarded; it keeps running after koh exits.
+func StartBackgroundFetch(dir string) error {
+	cmd := exec.Command("git", "-C", dir, "fetch", "--all", "--quiet")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start git fetch: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// FetchAllWithContext runs 'git fetch --all --prune', so remote-tracking
+// branches are current and those deleted on the remote are gone. git's
+// output, with a progress meter when progress is a terminal, is written to
+// progress. The fetch never prompts for credentials.
+func FetchAllWithContext(ctx context.Context, progress io.Writer) error {
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--all", "--prune")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = progress
+	cmd.Stderr = progress
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+	return nil
+}
+
+// Worktree describes a single entry from "git worktree list"
+type Worktree struct {
+	Path     string `json:"path"`
+	Head     string `json:"head"`
+	Branch   string `json:"branch"`
+	Detached bool   `json:"detached"`
+	Bare     bool   `json:"bare"`
+	Locked   bool   `json:"locked"`
+	Prunable bool   `json:"prunable"`
+}
+
+// ListWorktrees returns all worktrees of the current repository
+func ListWorktrees() ([]Worktree, error) {
+	return ListWorktreesWithContext(context.Background())
+}
+
+// ListWorktreesWithContext returns all worktrees of the current repository with cancellation support
+func ListWorktreesWithContext(ctx context.Context) ([]Worktree, error) {
+	cmd := exec.CommandContext(ctx, "git", "worktree", "list", "--porcelain")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	return parseWorktreeList(string(output)), nil
+}
+
+// parseWorktreeList parses the output of "git worktree list --porcelain".
+// Entries are separated by blank lines and each line is an attribute.
+func parseWorktreeList(output string) []Worktree {
+	var worktrees []Worktree
+	var current *Worktree
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if current != nil {
+				worktrees = append(worktrees, *current)
+				current = nil
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "worktree":
+			if current != nil {
+				worktrees = append(worktrees, *current)
+			}
+			current = &Worktree{Path: value}
+		case "HEAD":
+			if current != nil {
+				current.Head = value
+			}
+		case "branch":
+			if current != nil {
+				current.Branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		case "detached":
+			if current != nil {
+				current.Detached = true
+			}
+		case "bare":
+			if current != nil {
+				current.Bare = true
+			}
+		case "locked":
+			if current != nil {
+				current.Locked = true
+			}
+		case "prunable":
+			if current != nil {
+				current.Prunable = true
+			}
+		}
+	}
+
+	if current != nil {
+		worktrees = append(worktrees, *current)
+	}
+
+	return worktrees
+}
+
+// GetCommonDir returns the absolute path of the repository's common git
+// directory (the main .git directory shared by all worktrees)
+func GetCommonDir() (string, error) {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--git-common-dir")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git common dir: %w", err)
+	}
+
+	commonDir, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git common dir: %w", err)
+	}
+	return commonDir, nil
+}
+
+// RepoInfo locates the current directory's repository
+type RepoInfo struct {
+	// TopLevel is the root of the current worktree or main checkout
+	TopLevel string
+	// GitDir is the current worktree's git directory
+	GitDir string
+	// CommonDir is the git directory shared by all worktrees
+	CommonDir string
+}
+
+// InWorktree reports whether the current directory is in a linked worktree
+// rather than the main checkout
+func (r *RepoInfo) InWorktree() bool {
+	return r.GitDir != r.CommonDir
+}
+
+// RepoInfoWithContext locates the current repository with a single git call,
+// for commands where every process spawned counts. It fails outside a
+// repository's work tree.
+func RepoInfoWithContext(ctx context.Context) (*RepoInfo, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel", "--git-dir", "--git-common-dir")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("not in a git repository: %w", err)
+	}
+	return parseRepoInfo(string(output))
+}
+
+// RepoInfoAtWithContext locates the repository whose work tree contains dir
+func RepoInfoAtWithContext(ctx context.Context, dir string) (*RepoInfo, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel", "--git-dir", "--git-common-dir")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", dir, err)
+	}
+	info, err := parseRepoInfo(string(output))
+	if err != nil {
+		return nil, err
+	}
+	// git prints the directories relative to dir, not to the current directory
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	for i, field := range []*string{&info.TopLevel, &info.GitDir, &info.CommonDir} {
+		if line := strings.TrimSpace(lines[i]); !filepath.IsAbs(line) {
+			*field = filepath.Join(dir, line)
+		}
+	}
+	return info, nil
+}
+
+// SuperprojectWithContext returns the work tree of the repository the
+// current repository is a submodule of, or "" when it isn't a submodule
+func SuperprojectWithContext(ctx context.Context) string {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--show-superproject-working-tree")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// parseRepoInfo parses the output of 'git rev-parse --show-toplevel
+// --git-dir --git-common-dir', making the directories absolute
+func parseRepoInfo(output string) (*RepoInfo, error) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) != 3 {
+		return nil, fmt.Errorf("unexpected output from git rev-parse: %q", output)
+	}
+
+	var dirs [3]string
+	for i, line := range lines {
+		dir, err := filepath.Abs(strings.TrimSpace(line))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", line, err)
+		}
+		dirs[i] = dir
+	}
+	return &RepoInfo{TopLevel: dirs[0], GitDir: dirs[1], CommonDir: dirs[2]}, nil
+}
+
+// Ref is a local or remote-tracking branch
+type Ref struct {
+	// Name is the short ref name, e.g. "main" or "origin/main"
+	Name string `json:"name"`
+	// Remote is true for remote-tracking branches
+	Remote bool `json:"remote"`
+}
+
+// ListBranchRefsWithContext returns all local and remote-tracking branches
+func ListBranchRefsWithContext(ctx context.Context) ([]Ref, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	return parseBranchRefs(string(output)), nil
+}
+
+// BranchUpstreamsWithContext returns the remote-tracking branch each local
+// branch tracks, e.g. "upstream/main" for "main". Branches without an
+// upstream are left out.
+func BranchUpstreamsWithContext(ctx context.Context) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref", "--format=%(refname) %(upstream:short)", "refs/heads")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list branch upstreams: %w", err)
+	}
+
+	upstreams := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		ref, upstream, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && upstream != "" {
+			upstreams[strings.TrimPrefix(ref, "refs/heads/")] = upstream
+		}
+	}
+	return upstreams, nil
+}
+
+// parseBranchRefs parses full ref names from "git for-each-ref".
+// Symbolic remote HEAD refs (e.g. origin/HEAD) are skipped.
+func parseBranchRefs(output string) []Ref {
+	var refs []Ref
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "refs/heads/"):
+			refs = append(refs, Ref{Name: strings.TrimPrefix(line, "refs/heads/")})
+		case strings.HasPrefix(line, "refs/remotes/"):
+			name := strings.TrimPrefix(line, "refs/remotes/")
+			if strings.HasSuffix(name, "/HEAD") {
+				continue
+			}
+			refs = append(refs, Ref{Name: name, Remote: true})
+		}
+	}
+	return refs
+}
+
+// Branch is a local or remote-tracking branch with its latest commit
+type Branch struct {
+	Ref
+	// Subject is the first line of the latest commit's message
+	Subject     string    `json:"subject"`
+	CommittedAt time.Time `json:"committed_at"`
+}
+
+// ListBranchesWithContext returns all local and remote-tracking branches,
+// most recently committed first
+func ListBranchesWithContext(ctx context.Context) ([]Branch, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref", "--sort=-committerdate",
+		"--format=%(refname)%00%(committerdate:unix)%00%(subject)", "refs/heads", "refs/remotes")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	return parseBranches(string(output)), nil
+}
+
+// parseBranches parses "<refname>\0<unix time>\0<subject>" lines from
+// "git for-each-ref". Symbolic remote HEAD refs are skipped.
+func parseBranches(output string) []Branch {
+	var branches []Branch
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		refs := parseBranchRefs(fields[0])
+		if len(refs) == 0 {
+			continue
+		}
+		branch := Branch{Ref: refs[0], Subject: fields[2]}
+		if unix, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			branch.CommittedAt = time.Unix(unix, 0)
+		}
+		branches = append(branches, branch)
+	}
+	return branches
+}
+
+// CheckBranchRef validates that name refers to one of the given branches.
+// When only a remote-tracking branch of that name exists, the error suggests
+// using it (e.g. "origin/feature") instead.
+func CheckBranchRef(refs []Ref, name string) error {
+	var suggestions []string
+	for _, ref := range refs {
+		if ref.Name == name {
+			return nil
+		}
+		if ref.Remote {
+			if _, branch, ok := strings.Cut(ref.Name, "/"); ok && branch == name {
+				suggestions = append(suggestions, ref.Name)
+			}
+		}
+	}
+
+	if len(suggestions) > 0 {
+		return fmt.Errorf("branch %q does not exist locally\nDid you mean '%s'?", name, strings.Join(suggestions, "' or '"))
+	}
+	return fmt.Errorf("branch %q does not exist", name)
+}
+
+// ListRemotesWithContext returns the names of the repository's remotes
+func ListRemotesWithContext(ctx context.Context) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "remote")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// SplitRemoteRef splits a remote branch such as "origin/feature/login" into
+// its remote and branch. Remote names may contain slashes, so the longest
+// remote that prefixes ref wins.
+func SplitRemoteRef(remotes []string, ref string) (remote, branch string, ok bool) {
+	for _, r := range remotes {
+		if strings.HasPrefix(ref, r+"/") && len(r) > len(remote) && len(ref) > len(r)+1 {
+			remote, branch, ok = r, ref[len(r)+1:], true
+		}
+	}
+	return remote, branch, ok
+}
+
+// FetchBranchWithContext fetches branch from remote, creating or updating its
+// remote-tracking branch
+func FetchBranchWithContext(ctx context.Context, remote, branch string) error {
+	refspec := fmt.Sprintf("refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch)
+	return fetchRefspec(ctx, remote, refspec, branch)
+}
+
+// FetchPullRequestWithContext fetches the head of a GitHub pull request from
+// remote into a new local branch. This works for pull requests from forks,
+// whose branches aren't on any configured remote.
+func FetchPullRequestWithContext(ctx context.Context, remote string, number int, branch string) error {
+	refspec := fmt.Sprintf("refs/pull/%d/head:refs/heads/%s", number, branch)
+	return fetchRefspec(ctx, remote, refspec, fmt.Sprintf("pull request #%d", number))
+}
+
+// FetchPullRequestHeadWithContext fetches the head of a GitHub pull request
+// from remote without storing it in a branch and returns its commit, e.g. to
+// bring a branch fetched earlier up to date
+func FetchPullRequestHeadWithContext(ctx context.Context, remote string, number int) (string, error) {
+	if err := fetchRefspec(ctx, remote, fmt.Sprintf("refs/pull/%d/head", number), fmt.Sprintf("pull request #%d", number)); err != nil {
+		return "", err
+	}
+	return revParseWithContext(ctx, ".", "FETCH_HEAD^{commit}")
+}
+
+// FastForwardBranchWithContext moves branch from the commit from to its
+// descendant to. It fails if branch no longer points at from. The branch
+// must not be checked out, since its worktree would be left behind.
+func FastForwardBranchWithContext(ctx context.Context, branch, from, to string) error {
+	if !IsAncestorWithContext(ctx, from, to) {
+		return fmt.Errorf("can't fast-forward %s: %s is not a descendant of it", branch, to)
+	}
+	cmd := exec.CommandContext(ctx, "git", "update-ref", "-m", "koh: fast-forward", "refs/heads/"+branch, to, from)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("failed to fast-forward %s: %s", branch, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// fetchRefspec runs "git fetch" for a single refspec; what names the fetched
+// ref in errors
+func fetchRefspec(ctx context.Context, remote, refspec, what string) error {
+	//nolint:gosec // G204: remote is a configured remote and refspec is built from ref names
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--quiet", "--", remote, refspec)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("failed to fetch %s from %s: %s", what, remote, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// VerifyCommitWithContext reports whether ref resolves to a commit
+// (a branch, tag, commit hash or any other revision expression)
+func VerifyCommitWithContext(ctx context.Context, ref string) bool {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	return cmd.Run() == nil
+}
+
+// BranchExistsWithContext reports whether branch is a local branch
+func BranchExistsWithContext(ctx context.Context, branch string) bool {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return cmd.Run() == nil
+}
+
+// DeleteBranchWithContext deletes a local branch, merged or not
+func DeleteBranchWithContext(ctx context.Context, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "branch", "-D", "--", branch)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// BranchUpstreamWithContext returns the remote a branch tracks and the name
+// of the branch there, from branch.<name>.remote and branch.<name>.merge.
+// ok is false when the branch has no upstream on a remote.
+func BranchUpstreamWithContext(ctx context.Context, branch string) (remote, remoteBranch string, ok bool) {
+	remote = branchConfig(ctx, branch, "remote")
+	merge := branchConfig(ctx, branch, "merge")
+	// A remote of "." tracks another local branch
+	if remote == "" || remote == "." || merge == "" {
+		return "", "", false
+	}
+	return remote, strings.TrimPrefix(merge, "refs/heads/"), true
+}
+
+// branchConfig returns branch.<branch>.<key>, or "" when it isn't set
+func branchConfig(ctx context.Context, branch, key string) string {
+	//nolint:gosec // G204: git config lookup with branch name is safe
+	output, err := exec.CommandContext(ctx, "git", "config", "--get", "branch."+branch+"."+key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// DeleteRemoteBranchWithContext deletes a branch from a remote with cancellation support
+func DeleteRemoteBranchWithContext(ctx context.Context, remote, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "push", remote, "--delete", branch)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// GetDefaultBranchWithContext returns the branch merged work lands on. The
+// remote's default branch (e.g. "origin/main") is preferred since merges done
+// on the forge reach it first; otherwise a local main or master is used.
+func GetDefaultBranchWithContext(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	if output, err := cmd.Output(); err == nil {
+		if branch := strings.TrimSpace(string(output)); branch != "" {
+			return branch, nil
+		}
+	}
+
+	for _, branch := range []string{"main", "master"} {
+		if VerifyCommitWithContext(ctx, "refs/heads/"+branch) {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the default branch")
+}
+
+// MergedBranchesWithContext returns the local branches whose tips are reachable
+// from into. Only branches that have had commits of their own count (e.g. they
+// were merged, possibly fast-forward), so a freshly created worktree branch is
+// never reported as merged, even once into has moved on past it.
+func MergedBranchesWithContext(ctx context.Context, into string) (map[string]bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref", "--merged", into, "--format=%(refname:short)", "refs/heads")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list branches merged into %s: %w", into, err)
+	}
+
+	merged := make(map[string]bool)
+	for _, branch := range parseMergedBranches(string(output)) {
+		if branchHasHistory(ctx, branch) {
+			merged[branch] = true
+		}
+	}
+	return merged, nil
+}
+
+// parseMergedBranches parses the branch names listed by for-each-ref
+func parseMergedBranches(output string) []string {
+	var branches []string
+	for _, line := range strings.Split(output, "\n") {
+		if branch := strings.TrimSpace(line); branch != "" {
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
+
+// branchHasHistory reports whether a branch has moved since it was created,
+// judged by its reflog having more than the creation entry
+func branchHasHistory(ctx context.Context, branch string) bool {
+	cmd := exec.CommandContext(ctx, "git", "reflog", "show", "--format=%H", "refs/heads/"+branch, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return len(strings.Fields(string(output))) > 1
+}
+
+// IsAncestorWithContext reports whether ancestor is reachable from descendant.
+// It returns false when either commit is unknown locally.
+func IsAncestorWithContext(ctx context.Context, ancestor, descendant string) bool {
+	cmd := exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", ancestor, descendant)
+	return cmd.Run() == nil
+}
+
+// LastCommitTimeWithContext returns when the commit checked out in the
+// worktree at path was made
+func LastCommitTimeWithContext(ctx context.Context, path string) (time.Time, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "log", "-1", "--format=%ct")
+	output, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last commit: %w", err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit time: %w", err)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// MergeTreeVersion is the oldest git MergeConflictsWithContext works with
+const MergeTreeVersion = "2.38"
+
+// VersionWithContext returns the output of 'git --version', e.g.
+// "git version 2.43.0"
+func VersionWithContext(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git version: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// MergeConflictsWithContext reports whether merging branch into base would
+// conflict, without touching any worktree. It needs git MergeTreeVersion or
+// later.
+func MergeConflictsWithContext(ctx context.Context, base, branch string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "merge-tree", "--write-tree", "--name-only", "--no-messages", "--end-of-options", base, branch)
+	output, err := cmd.Output()
+	if err == nil {
+		return false, nil
+	}
+	// merge-tree exits 1 when the merge has conflicts, printing the resulting
+	// tree, but also when it can't merge at all, printing nothing
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(bytes.TrimSpace(output)) > 0 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to check %s for conflicts with %s: %w", branch, base, err)
+}
+
+// ApplyStashWithContext applies a stash entry (e.g. "stash@{0}") to the
+// worktree at path, keeping the stash. Stashes are shared by all worktrees
+// of a repository.
+func ApplyStashWithContext(ctx context.Context, path, stash string) error {
+	if strings.HasPrefix(stash, "-") {
+		return fmt.Errorf("invalid stash %q", stash)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "stash", "apply", stash)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Stash is an entry of the repository's stash
+type Stash struct {
+	// Ref names the entry, e.g. "stash@{0}" for the latest
+	Ref       string    `json:"ref"`
+	Commit    string    `json:"commit"`
+	CreatedAt time.Time `json:"created_at"`
+	// Message is git's description, e.g. "On main: try the new parser"
+	Message string `json:"message"`
+}
+
+// ListStashesWithContext returns the repository's stash entries, latest first
+func ListStashesWithContext(ctx context.Context) ([]Stash, error) {
+	cmd := exec.CommandContext(ctx, "git", "stash", "list", "--format=%gd%x00%H%x00%ct%x00%gs")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to list stashes: %w", err)
+	}
+
+	return parseStashes(string(output)), nil
+}
+
+// parseStashes parses "<ref>\0<commit>\0<unix time>\0<message>" lines from
+// "git stash list"
+func parseStashes(output string) []Stash {
+	var stashes []Stash
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		stash := Stash{Ref: fields[0], Commit: fields[1], Message: fields[3]}
+		if unix, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			stash.CreatedAt = time.Unix(unix, 0)
+		}
+		stashes = append(stashes, stash)
+	}
+	return stashes
+}
+
+// StashPushWithContext stashes the uncommitted changes of the worktree at
+// path, untracked files included, under the given message. It returns the
+// commit of the new stash entry; the worktree must have changes to stash.
+func StashPushWithContext(ctx context.Context, path, message string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "stash", "push", "--include-untracked", "-m", message)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return "", fmt.Errorf("operation cancelled")
+		}
+		return "", fmt.Errorf("failed to stash changes: %s", strings.TrimSpace(string(output)))
+	}
+	return revParseWithContext(ctx, path, "refs/stash")
+}
+
+// FindStashWithContext returns the stash entry (e.g. "stash@{1}") whose
+// commit is commit, or "" when it was dropped
+func FindStashWithContext(ctx context.Context, path, commit string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "stash", "list", "--format=%H")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list stashes: %w", err)
+	}
+	for i, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == commit {
+			return fmt.Sprintf("stash@{%d}", i), nil
+		}
+	}
+	return "", nil
+}
+
+// PopStashWithContext applies a stash entry to the worktree at path and
+// drops it. On conflicts git keeps the entry.
+func PopStashWithContext(ctx context.Context, path, stash string) error {
+	if strings.HasPrefix(stash, "-") {
+		return fmt.Errorf("invalid stash %q", stash)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "stash", "pop", stash)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pop %s: %s", stash, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// CommitAllWithContext commits every change in the worktree at path,
+// untracked files included, and returns the new commit. Commit hooks are
+// skipped since the commit is a checkpoint rather than finished work.
+func CommitAllWithContext(ctx context.Context, path, message string) (string, error) {
+	add := exec.CommandContext(ctx, "git", "-C", path, "add", "--all")
+	if output, err := add.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to stage changes: %s", strings.TrimSpace(string(output)))
+	}
+
+	commit := exec.CommandContext(ctx, "git", "-C", path, "commit", "--no-verify", "-m", message)
+	if output, err := commit.CombinedOutput(); err != nil {
+		if ctx.Err() == context.Canceled {
+			return "", fmt.Errorf("operation cancelled")
+		}
+		return "", fmt.Errorf("failed to commit changes: %s", strings.TrimSpace(string(output)))
+	}
+	return HeadCommitWithContext(ctx, path)
+}
+
+// UndoCommitWithContext removes the last commit of the worktree at path and
+// leaves its changes in the working tree, unstaged
+func UndoCommitWithContext(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "reset", "--mixed", "--quiet", "HEAD~1")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to undo the last commit: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// HeadCommitWithContext returns the commit checked out in the worktree at path
+func HeadCommitWithContext(ctx context.Context, path string) (string, error) {
+	return revParseWithContext(ctx, path, "HEAD")
+}
+
+// ResolveCommitWithContext resolves ref, e.g. a branch name, to a commit in
+// the worktree at path
+func ResolveCommitWithContext(ctx context.Context, path, ref string) (string, error) {
+	return revParseWithContext(ctx, path, ref)
+}
+
+// DivergenceWithContext returns how many commits head has that base doesn't
+// (Ahead) and base has that head doesn't (Behind)
+func DivergenceWithContext(ctx context.Context, path, base, head string) (*Tracking, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "rev-list", "--left-right", "--count", "--end-of-options", base+"..."+head)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", head, base, err)
+	}
+	t := parseTracking(string(output))
+	if t == nil {
+		return nil, fmt.Errorf("unexpected rev-list output %q", strings.TrimSpace(string(output)))
+	}
+	return t, nil
+}
+
+// MergeBaseWithContext returns the best common ancestor of a and b
+func MergeBaseWithContext(ctx context.Context, path, a, b string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "merge-base", "--end-of-options", a, b)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s and %s have no common ancestor: %w", a, b, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RebaseOntoWithContext rebases the branch checked out in the worktree at
+// path onto onto, replaying the commits after upstream. A rebase that stops
+// on conflicts is left in progress for the user to resolve.
+func RebaseOntoWithContext(ctx context.Context, path, onto, upstream string) error {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "rebase", "--onto", onto, "--end-of-options", upstream)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RebaseInProgressWithContext reports whether a rebase is stopped in the
+// worktree at path
+func RebaseInProgressWithContext(ctx context.Context, path string) bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		gitPath, err := GitPathWithContext(ctx, path, dir)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(gitPath); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GitPathWithContext returns the absolute path of name inside the git
+// directory of the worktree at path, e.g. "hooks", taking per-worktree
+// paths and settings such as core.hooksPath into account
+func GitPathWithContext(ctx context.Context, path, name string) (string, error) {
+	// --git-path prints a path relative to the worktree unless it lies
+	// elsewhere; --path-format=absolute would need git 2.31
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "rev-parse", "--git-path", name)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s in the git directory of %s: %w", name, path, err)
+	}
+	gitPath := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitPath) {
+		gitPath = filepath.Join(path, gitPath)
+	}
+	return gitPath, nil
+}
+
+// revParseWithContext resolves ref to a commit in the worktree at path
+func revParseWithContext(ctx context.Context, path, ref string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "rev-parse", "--verify", "--end-of-options", ref)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ApplyPatchWithContext applies a patch file to the worktree at path.
+// patchFile must be an absolute path since git runs inside the worktree.
+func ApplyPatchWithContext(ctx context.Context, path, patchFile string) error {
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "apply", "--", patchFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// IsIgnoredWithContext reports whether path (relative to dir) is ignored by
+// .gitignore, info/exclude or the global excludes file
+func IsIgnoredWithContext(ctx context.Context, dir, path string) bool {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "check-ignore", "-q", "--", path)
+	return cmd.Run() == nil
+}
+
+// IgnoredPathsWithContext returns which of paths (relative to dir) are
+// ignored. Tracked files are never ignored, even when a pattern matches them.
+func IgnoredPathsWithContext(ctx context.Context, dir string, paths []string) (map[string]bool, error) {
+	ignored := map[string]bool{}
+	if len(paths) == 0 {
+		return ignored, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "check-ignore", "-z", "--stdin")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	output, err := cmd.Output()
+	// check-ignore exits with 1 when no path is ignored
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("operation cancelled")
+		}
+		return nil, fmt.Errorf("failed to check ignored files: %w", err)
+	}
+
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			ignored[path] = true
+		}
+	}
+	return ignored, nil
+}
+
+// AddExclude appends pattern to the repository's info/exclude file, which
+// ignores paths locally without touching .gitignore
+func AddExclude(commonDir, pattern string) error {
+	infoDir := filepath.Join(commonDir, "info")
+	//nolint:gosec // G301: 0755 matches the permissions git uses for .git/info
+	if err := os.MkdirAll(infoDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", infoDir, err)
+	}
+
+	path := filepath.Join(infoDir, "exclude")
+	//nolint:gosec // G304: path is inside the repository's git directory
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	entry := pattern + "\n"
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		entry = "\n" + entry
+	}
+
+	//nolint:gosec // G302,G304: 0644 is standard for git's info/exclude
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := f.WriteString(entry); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// PruneWorktreesWithContext removes administrative data for worktrees whose
+// directories no longer exist
+func PruneWorktreesWithContext(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "git", "worktree", "prune")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("failed to prune worktrees: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// MoveWorktreeWithContext moves the worktree at path to newPath, keeping its
+// files, untracked ones included, as they are
+func MoveWorktreeWithContext(ctx context.Context, path, newPath string) error {
+	cmd := exec.CommandContext(ctx, "git", "worktree", "move", path, newPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		return fmt.Errorf("failed to move worktree: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// SetWorktreeConfigWithContext sets a git config key for the worktree at path
+// only. It enables extensions.worktreeConfig, which makes git read each
+// worktree's config.worktree file, so the setting doesn't leak into the main
+// checkout or other worktrees.
+func SetWorktreeConfigWithContext(ctx context.Context, path, key, value string) error {
+	if strings.HasPrefix(key, "-") {
+		return fmt.Errorf("invalid config key %q", key)
+	}
+
+	for _, args := range [][]string{
+		{"-C", path, "config", "extensions.worktreeConfig", "true"},
+		{"-C", path, "config", "--worktree", "--", key, value},
+	} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			if ctx.Err() == context.Canceled {
+				return fmt.Errorf("operation cancelled")
+			}
+			return fmt.Errorf("failed to set %s: %s", key, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// UnsetWorktreeConfigWithContext removes a git config key set for the
+// worktree at path with SetWorktreeConfigWithContext. A key that isn't set is
+// not an error.
+func UnsetWorktreeConfigWithContext(ctx context.Context, path, key string) error {
+	if strings.HasPrefix(key, "-") {
+		return fmt.Errorf("invalid config key %q", key)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", path, "config", "--worktree", "--unset", "--", key)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("operation cancelled")
+		}
+		// git config exits with 5 when the key isn't set
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 5 {
+			return nil
+		}
+		return fmt.Errorf("failed to unset %s: %s", key, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -3,15 +3,18 @@ package git
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/bshakr/koh/internal/testutil"
 )
 
 func TestCreateWorktreeWithContext(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	// Create a temporary directory for the worktree
 	tempDir, err := os.MkdirTemp("", "ko-test-worktree-*")
@@ -45,9 +48,7 @@ func TestCreateWorktreeWithContext(t *testing.T) {
 }
 
 func TestCreateWorktreeWithContextCancellation(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	// Create a temporary directory for the worktree
 	tempDir, err := os.MkdirTemp("", "ko-test-worktree-*")
@@ -81,9 +82,7 @@ func TestCreateWorktreeWithContextCancellation(t *testing.T) {
 }
 
 func TestCreateWorktreeWithContextTimeout(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	// Create a temporary directory for the worktree
 	tempDir, err := os.MkdirTemp("", "ko-test-worktree-*")
@@ -119,9 +118,7 @@ func TestCreateWorktreeWithContextTimeout(t *testing.T) {
 }
 
 func TestRemoveWorktreeWithContext(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	// Create a temporary directory for the worktree
 	tempDir, err := os.MkdirTemp("", "ko-test-worktree-*")
@@ -155,9 +152,7 @@ func TestRemoveWorktreeWithContext(t *testing.T) {
 }
 
 func TestRemoveWorktreeWithContextCancellation(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	// Create a temporary directory for the worktree
 	tempDir, err := os.MkdirTemp("", "ko-test-worktree-*")
@@ -197,3 +192,383 @@ func TestRemoveWorktreeWithContextCancellation(t *testing.T) {
 		t.Logf("Got error: %v (might complete before cancellation)", err)
 	}
 }
+
+func TestSetWorktreeConfigWithContext(t *testing.T) {
+	repo := t.TempDir()
+	worktree := filepath.Join(t.TempDir(), "wt")
+	for _, args := range [][]string{
+		{"init", "-q", repo},
+		{"-C", repo, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"-C", repo, "worktree", "add", "-q", "--detach", worktree},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	ctx := context.Background()
+	if err := SetWorktreeConfigWithContext(ctx, worktree, "user.email", "work@example.com"); err != nil {
+		t.Fatalf("SetWorktreeConfigWithContext() failed: %v", err)
+	}
+
+	get := func(dir string) string {
+		output, _ := exec.Command("git", "-C", dir, "config", "--get", "user.email").Output()
+		return strings.TrimSpace(string(output))
+	}
+	if got := get(worktree); got != "work@example.com" {
+		t.Errorf("Expected user.email in worktree to be work@example.com, got %q", got)
+	}
+	if got := get(repo); got == "work@example.com" {
+		t.Error("Expected user.email to be set for the worktree only, but the main checkout has it too")
+	}
+
+	if err := SetWorktreeConfigWithContext(ctx, worktree, "--global", "x"); err == nil {
+		t.Error("Expected an error for a key that looks like a flag")
+	}
+
+	// Unsetting removes the key, and unsetting it again is no error
+	for range 2 {
+		if err := UnsetWorktreeConfigWithContext(ctx, worktree, "user.email"); err != nil {
+			t.Fatalf("UnsetWorktreeConfigWithContext() failed: %v", err)
+		}
+	}
+	if got := get(worktree); got == "work@example.com" {
+		t.Error("Expected user.email to be unset in the worktree")
+	}
+}
+
+func TestMergeConflictsWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	testutil.WriteFile(t, repo, "a.txt", "base\n")
+	testutil.Git(t, repo, "add", ".")
+	testutil.Git(t, repo, "commit", "-q", "-m", "base")
+	testutil.Git(t, repo, "branch", "clean")
+	testutil.Git(t, repo, "branch", "conflicting")
+
+	testutil.WriteFile(t, repo, "a.txt", "main\n")
+	testutil.Git(t, repo, "commit", "-q", "-am", "main")
+	testutil.Git(t, repo, "checkout", "-q", "clean")
+	testutil.WriteFile(t, repo, "b.txt", "clean\n")
+	testutil.Git(t, repo, "add", ".")
+	testutil.Git(t, repo, "commit", "-q", "-m", "clean")
+	testutil.Git(t, repo, "checkout", "-q", "conflicting")
+	testutil.WriteFile(t, repo, "a.txt", "conflicting\n")
+	testutil.Git(t, repo, "commit", "-q", "-am", "conflicting")
+
+	ctx := context.Background()
+	if conflicts, err := MergeConflictsWithContext(ctx, "main", "clean"); err != nil || conflicts {
+		t.Errorf("Expected no conflicts, got %v (%v)", conflicts, err)
+	}
+	if conflicts, err := MergeConflictsWithContext(ctx, "main", "conflicting"); err != nil || !conflicts {
+		t.Errorf("Expected conflicts, got %v (%v)", conflicts, err)
+	}
+	if _, err := MergeConflictsWithContext(ctx, "main", "missing"); err == nil {
+		t.Error("Expected an error for an unknown branch")
+	}
+
+	when, err := LastCommitTimeWithContext(ctx, repo)
+	if err != nil {
+		t.Fatalf("LastCommitTimeWithContext() failed: %v", err)
+	}
+	if time.Since(when) > time.Hour {
+		t.Errorf("Expected a recent commit time, got %v", when)
+	}
+}
+
+func TestCreateWorktreeForBranchWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	testutil.Git(t, repo, "branch", "existing")
+
+	ctx := context.Background()
+	worktreePath := filepath.Join(repo, ".koh", "other-name")
+	if err := CreateWorktreeForBranchWithContext(ctx, worktreePath, "existing"); err != nil {
+		t.Fatalf("CreateWorktreeForBranchWithContext() failed: %v", err)
+	}
+	if branch := testutil.Git(t, worktreePath, "branch", "--show-current"); branch != "existing" {
+		t.Errorf("Expected branch existing to be checked out, got %q", branch)
+	}
+	if branches := testutil.Git(t, repo, "branch", "--list", "other-name"); branches != "" {
+		t.Errorf("Expected no branch named after the worktree, got %q", branches)
+	}
+
+	// A branch can only be checked out in one worktree
+	if err := CreateWorktreeForBranchWithContext(ctx, filepath.Join(repo, ".koh", "again"), "existing"); err == nil {
+		t.Error("Expected an error for a branch checked out elsewhere")
+	}
+}
+
+func TestCreateWorktreeFromRefWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	testutil.Git(t, repo, "tag", "v1")
+	first := testutil.Git(t, repo, "rev-parse", "HEAD")
+	testutil.Git(t, repo, "commit", "-q", "--allow-empty", "-m", "second")
+
+	ctx := context.Background()
+	worktreePath := filepath.Join(repo, ".koh", "hotfix")
+	if err := CreateWorktreeFromRefWithContext(ctx, worktreePath, "hotfix", "v1"); err != nil {
+		t.Fatalf("CreateWorktreeFromRefWithContext() failed: %v", err)
+	}
+	if branch := testutil.Git(t, worktreePath, "branch", "--show-current"); branch != "hotfix" {
+		t.Errorf("Expected a new branch hotfix, got %q", branch)
+	}
+	if head := testutil.Git(t, worktreePath, "rev-parse", "HEAD"); head != first {
+		t.Errorf("Expected the branch to start at v1 (%s), got %s", first, head)
+	}
+
+	if err := CreateWorktreeFromRefWithContext(ctx, filepath.Join(repo, ".koh", "other"), "other", "missing"); err == nil {
+		t.Error("Expected an error for an unknown ref")
+	}
+}
+
+func TestIgnoredPathsWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	testutil.WriteFile(t, repo, ".gitignore", "*.env\n")
+	testutil.WriteFile(t, repo, "tracked.env", "tracked\n")
+	testutil.Git(t, repo, "add", ".")
+	testutil.Git(t, repo, "add", "-f", "tracked.env")
+	testutil.Git(t, repo, "commit", "-q", "-m", "base")
+	testutil.WriteFile(t, repo, "local.env", "secret\n")
+	testutil.WriteFile(t, repo, "notes.txt", "notes\n")
+
+	ctx := context.Background()
+	ignored, err := IgnoredPathsWithContext(ctx, repo, []string{"local.env", "tracked.env", "notes.txt"})
+	if err != nil {
+		t.Fatalf("IgnoredPathsWithContext() failed: %v", err)
+	}
+	if !ignored["local.env"] || ignored["tracked.env"] || ignored["notes.txt"] {
+		t.Errorf("Expected only local.env to be ignored, got %v", ignored)
+	}
+
+	// None ignored makes check-ignore exit with 1, which isn't an error
+	ignored, err = IgnoredPathsWithContext(ctx, repo, []string{"notes.txt"})
+	if err != nil || len(ignored) != 0 {
+		t.Errorf("Expected nothing ignored, got %v (%v)", ignored, err)
+	}
+}
+
+func TestCreateWorktreeTrackingWithContext(t *testing.T) {
+	origin := testutil.NewRepo(t)
+	repo := filepath.Join(t.TempDir(), "clone")
+	testutil.Git(t, origin, "clone", "-q", origin, repo)
+	testutil.Git(t, origin, "checkout", "-q", "-b", "feature/login")
+	testutil.Git(t, origin, "commit", "-q", "--allow-empty", "-m", "login")
+
+	t.Chdir(repo)
+	ctx := context.Background()
+	remotes, err := ListRemotesWithContext(ctx)
+	if err != nil || len(remotes) != 1 || remotes[0] != "origin" {
+		t.Fatalf("Expected the origin remote, got %v (%v)", remotes, err)
+	}
+
+	if err := FetchBranchWithContext(ctx, "origin", "feature/login"); err != nil {
+		t.Fatalf("FetchBranchWithContext() failed: %v", err)
+	}
+	if err := FetchBranchWithContext(ctx, "origin", "missing"); err == nil {
+		t.Error("Expected an error fetching a missing branch")
+	}
+
+	worktreePath := filepath.Join(repo, ".koh", "feature-login")
+	if err := CreateWorktreeTrackingWithContext(ctx, worktreePath, "feature/login", "origin/feature/login"); err != nil {
+		t.Fatalf("CreateWorktreeTrackingWithContext() failed: %v", err)
+	}
+	if upstream := testutil.Git(t, worktreePath, "rev-parse", "--abbrev-ref", "@{upstream}"); upstream != "origin/feature/login" {
+		t.Errorf("Expected the branch to track origin/feature/login, got %q", upstream)
+	}
+
+	// GitHub keeps the head of every pull request, including forks', at refs/pull/<n>/head
+	testutil.Git(t, origin, "update-ref", "refs/pull/7/head", "HEAD")
+	if err := FetchPullRequestWithContext(ctx, "origin", 7, "pr-7"); err != nil {
+		t.Fatalf("FetchPullRequestWithContext() failed: %v", err)
+	}
+	if head, want := testutil.Git(t, repo, "rev-parse", "pr-7"), testutil.Git(t, origin, "rev-parse", "HEAD"); head != want {
+		t.Errorf("Expected pr-7 at %s, got %s", want, head)
+	}
+	if err := FetchPullRequestWithContext(ctx, "origin", 8, "pr-8"); err == nil {
+		t.Error("Expected an error fetching a missing pull request")
+	}
+
+	// A newer head is fetched without a branch and pr-7 fast-forwarded to it
+	previous := testutil.Git(t, repo, "rev-parse", "pr-7")
+	testutil.Git(t, origin, "commit", "-q", "--allow-empty", "-m", "review fixes")
+	testutil.Git(t, origin, "update-ref", "refs/pull/7/head", "HEAD")
+	head, err := FetchPullRequestHeadWithContext(ctx, "origin", 7)
+	if want := testutil.Git(t, origin, "rev-parse", "HEAD"); err != nil || head != want {
+		t.Fatalf("Expected the head %s, got %q (%v)", want, head, err)
+	}
+	if err := FastForwardBranchWithContext(ctx, "pr-7", head, previous); err == nil {
+		t.Error("Expected an error moving pr-7 backwards")
+	}
+	if err := FastForwardBranchWithContext(ctx, "pr-7", previous, head); err != nil {
+		t.Fatalf("FastForwardBranchWithContext() failed: %v", err)
+	}
+	if got := testutil.Git(t, repo, "rev-parse", "pr-7"); got != head {
+		t.Errorf("Expected pr-7 at %s, got %s", head, got)
+	}
+	// pr-7 no longer points at previous
+	if err := FastForwardBranchWithContext(ctx, "pr-7", previous, head); err == nil {
+		t.Error("Expected an error fast-forwarding from a commit the branch left")
+	}
+}
+
+func TestFetchAllWithContext(t *testing.T) {
+	origin := testutil.NewRepo(t)
+	repo := filepath.Join(t.TempDir(), "clone")
+	testutil.Git(t, origin, "branch", "stale")
+	testutil.Git(t, origin, "clone", "-q", origin, repo)
+	testutil.Git(t, origin, "commit", "-q", "--allow-empty", "-m", "newer")
+	testutil.Git(t, origin, "branch", "-D", "stale")
+
+	t.Chdir(repo)
+	var progress strings.Builder
+	if err := FetchAllWithContext(context.Background(), &progress); err != nil {
+		t.Fatalf("FetchAllWithContext() failed: %v\n%s", err, progress.String())
+	}
+	if head, want := testutil.Git(t, repo, "rev-parse", "origin/main"), testutil.Git(t, origin, "rev-parse", "main"); head != want {
+		t.Errorf("Expected origin/main at %s, got %s", want, head)
+	}
+	if refs := testutil.Git(t, repo, "branch", "-r"); strings.Contains(refs, "origin/stale") {
+		t.Errorf("Expected origin/stale to be pruned, got %q", refs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := FetchAllWithContext(ctx, &progress); err == nil {
+		t.Error("Expected an error with a cancelled context")
+	}
+}
+
+func TestDeleteBranchWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	testutil.Git(t, repo, "checkout", "-q", "-b", "unmerged")
+	testutil.Git(t, repo, "commit", "-q", "--allow-empty", "-m", "only here")
+	testutil.Git(t, repo, "checkout", "-q", "main")
+
+	ctx := context.Background()
+	if !BranchExistsWithContext(ctx, "unmerged") {
+		t.Fatal("Expected branch unmerged to exist")
+	}
+	if err := DeleteBranchWithContext(ctx, "unmerged"); err != nil {
+		t.Fatalf("DeleteBranchWithContext() failed: %v", err)
+	}
+	if BranchExistsWithContext(ctx, "unmerged") {
+		t.Error("Expected the unmerged branch to be deleted")
+	}
+	if err := DeleteBranchWithContext(ctx, "missing"); err == nil {
+		t.Error("Expected an error deleting a branch that doesn't exist")
+	}
+}
+
+func TestSparseCheckoutWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	for _, name := range []string{"README.md", "services/api/main.go", "services/web/index.js", "docs/guide.md"} {
+		testutil.WriteFile(t, repo, name, name)
+	}
+	testutil.Git(t, repo, "add", ".")
+	testutil.Git(t, repo, "commit", "-q", "-m", "files")
+
+	ctx := context.Background()
+	worktreePath := filepath.Join(repo, ".koh", "sparse")
+	if err := CreateWorktreeFromRefWithContext(ctx, worktreePath, "sparse", "HEAD", NoCheckout); err != nil {
+		t.Fatalf("CreateWorktreeFromRefWithContext() failed: %v", err)
+	}
+	if err := SparseCheckoutWithContext(ctx, worktreePath, []string{"services/api"}); err != nil {
+		t.Fatalf("SparseCheckoutWithContext() failed: %v", err)
+	}
+
+	for name, want := range map[string]bool{
+		"README.md":             true,
+		"services/api/main.go":  true,
+		"services/web/index.js": false,
+		"docs/guide.md":         false,
+	} {
+		_, err := os.Stat(filepath.Join(worktreePath, name))
+		if got := err == nil; got != want {
+			t.Errorf("Expected %s checked out: %v, got %v", name, want, got)
+		}
+	}
+	if status := testutil.Git(t, worktreePath, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected a clean worktree, got %q", status)
+	}
+
+	if err := SparseCheckoutWithContext(ctx, filepath.Join(repo, "missing"), []string{"docs"}); err == nil {
+		t.Error("Expected an error for a worktree that doesn't exist")
+	}
+}
+
+func TestMergedBranchesWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	commit := func(message string) {
+		testutil.Git(t, repo, "commit", "-q", "--allow-empty", "-m", message)
+	}
+
+	testutil.Git(t, repo, "branch", "fresh")
+	testutil.Git(t, repo, "branch", "behind")
+	testutil.Git(t, repo, "checkout", "-q", "-b", "feature")
+	commit("feature work")
+	testutil.Git(t, repo, "checkout", "-q", "main")
+	testutil.Git(t, repo, "merge", "-q", "--ff-only", "feature")
+	commit("main moves on")
+	testutil.Git(t, repo, "checkout", "-q", "-b", "open", "main")
+	commit("open work")
+	testutil.Git(t, repo, "checkout", "-q", "main")
+
+	merged, err := MergedBranchesWithContext(context.Background(), "main")
+	if err != nil {
+		t.Fatalf("MergedBranchesWithContext() failed: %v", err)
+	}
+	if !merged["feature"] {
+		t.Error("Expected the fast-forward merged feature to be merged")
+	}
+	// fresh and behind never had commits of their own; main has moved past them
+	for _, branch := range []string{"fresh", "behind", "open"} {
+		if merged[branch] {
+			t.Errorf("Expected %s not to be merged", branch)
+		}
+	}
+}
+
+func TestBranchUpstreamsWithContext(t *testing.T) {
+	origin := testutil.NewRepo(t)
+	repo := filepath.Join(t.TempDir(), "clone")
+	testutil.Git(t, origin, "clone", "-q", "-o", "upstream", origin, repo)
+	testutil.Git(t, repo, "branch", "local")
+
+	t.Chdir(repo)
+	upstreams, err := BranchUpstreamsWithContext(context.Background())
+	if err != nil {
+		t.Fatalf("BranchUpstreamsWithContext() failed: %v", err)
+	}
+	if want := map[string]string{"main": "upstream/main"}; !reflect.DeepEqual(upstreams, want) {
+		t.Errorf("Expected %v, got %v", want, upstreams)
+	}
+}
+
+func TestBranchUpstreamWithContext(t *testing.T) {
+	origin := testutil.NewRepo(t)
+	testutil.Git(t, origin, "branch", "feature")
+	repo := filepath.Join(t.TempDir(), "clone")
+	testutil.Git(t, origin, "clone", "-q", "-o", "upstream", origin, repo)
+	testutil.Git(t, repo, "branch", "-q", "--track", "renamed", "upstream/feature")
+	testutil.Git(t, repo, "branch", "-q", "local")
+	testutil.Git(t, repo, "branch", "-q", "--track", "follows-main", "main")
+
+	t.Chdir(repo)
+	ctx := context.Background()
+	tests := []struct {
+		branch, remote, remoteBranch string
+		ok                           bool
+	}{
+		{"main", "upstream", "main", true},
+		{"renamed", "upstream", "feature", true},
+		{"local", "", "", false},
+		{"follows-main", "", "", false},
+		{"missing", "", "", false},
+	}
+	for _, tt := range tests {
+		remote, remoteBranch, ok := BranchUpstreamWithContext(ctx, tt.branch)
+		if remote != tt.remote || remoteBranch != tt.remoteBranch || ok != tt.ok {
+			t.Errorf("BranchUpstreamWithContext(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.branch, remote, remoteBranch, ok, tt.remote, tt.remoteBranch, tt.ok)
+		}
+	}
+}
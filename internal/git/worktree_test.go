@@ -1,24 +1,30 @@
 package git
 
 import (
+	"context"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/bshakr/koh/internal/testutil"
 )
 
 func TestIsGitRepo(t *testing.T) {
-	// This test assumes we're running in a git repo
-	// If not in a git repo, this test will fail
-	result := IsGitRepo()
-	if !result {
-		t.Skip("Not in a git repository, skipping test")
+	testutil.NewRepo(t)
+	if !IsGitRepo() {
+		t.Error("Expected IsGitRepo() to be true in a repository")
+	}
+
+	t.Chdir(t.TempDir())
+	if IsGitRepo() {
+		t.Error("Expected IsGitRepo() to be false outside a repository")
 	}
 }
 
 func TestGetRepoName(t *testing.T) {
-	// Only test if we're in a git repo
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	name, err := GetRepoName()
 	if err != nil {
@@ -33,9 +39,7 @@ func TestGetRepoName(t *testing.T) {
 }
 
 func TestIsInWorktree(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	// Test should work whether we're in a worktree or not
 	result := IsInWorktree()
@@ -43,9 +47,7 @@ func TestIsInWorktree(t *testing.T) {
 }
 
 func TestGetMainRepoRoot(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	root, err := GetMainRepoRoot()
 	if err != nil {
@@ -65,9 +67,7 @@ func TestGetMainRepoRoot(t *testing.T) {
 }
 
 func TestGetCurrentWorktreePath(t *testing.T) {
-	if !IsGitRepo() {
-		t.Skip("Not in a git repository, skipping test")
-	}
+	testutil.NewRepo(t)
 
 	path, err := GetCurrentWorktreePath()
 	if err != nil {
@@ -85,3 +85,223 @@ func TestGetCurrentWorktreePath(t *testing.T) {
 
 	t.Logf("Current worktree path: %s", path)
 }
+
+func TestParseWorktreeList(t *testing.T) {
+	output := `worktree /repo
+HEAD 1111111111111111111111111111111111111111
+branch refs/heads/main
+
+worktree /repo/.koh/feature
+HEAD 2222222222222222222222222222222222222222
+branch refs/heads/feature/login
+locked
+
+worktree /repo/.koh/detached
+HEAD 3333333333333333333333333333333333333333
+detached
+prunable gitdir file points to non-existent location
+`
+
+	worktrees := parseWorktreeList(output)
+	if len(worktrees) != 3 {
+		t.Fatalf("Expected 3 worktrees, got %d: %+v", len(worktrees), worktrees)
+	}
+
+	if worktrees[0].Path != "/repo" || worktrees[0].Branch != "main" {
+		t.Errorf("Unexpected main worktree: %+v", worktrees[0])
+	}
+
+	if worktrees[1].Branch != "feature/login" || !worktrees[1].Locked {
+		t.Errorf("Unexpected feature worktree: %+v", worktrees[1])
+	}
+
+	if !worktrees[2].Detached || !worktrees[2].Prunable || worktrees[2].Branch != "" {
+		t.Errorf("Unexpected detached worktree: %+v", worktrees[2])
+	}
+}
+
+func TestParseBranchRefs(t *testing.T) {
+	output := "refs/heads/main\nrefs/heads/feature/login\nrefs/remotes/origin/HEAD\nrefs/remotes/origin/main\nrefs/remotes/origin/only-remote\n"
+
+	refs := parseBranchRefs(output)
+	want := []Ref{
+		{Name: "main"},
+		{Name: "feature/login"},
+		{Name: "origin/main", Remote: true},
+		{Name: "origin/only-remote", Remote: true},
+	}
+	if len(refs) != len(want) {
+		t.Fatalf("Expected %d refs, got %d: %+v", len(want), len(refs), refs)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Errorf("refs[%d] = %+v, want %+v", i, refs[i], want[i])
+		}
+	}
+}
+
+func TestParseBranches(t *testing.T) {
+	output := "refs/heads/feature\x001700000000\x00Add login\n" +
+		"refs/remotes/origin/HEAD\x001700000000\x00Initial\n" +
+		"refs/remotes/origin/main\x001600000000\x00Initial\n" +
+		"garbage\n"
+
+	branches := parseBranches(output)
+	if len(branches) != 2 {
+		t.Fatalf("Expected 2 branches, got %+v", branches)
+	}
+	if branches[0].Name != "feature" || branches[0].Remote || branches[0].Subject != "Add login" || branches[0].CommittedAt.Unix() != 1700000000 {
+		t.Errorf("Unexpected local branch: %+v", branches[0])
+	}
+	if branches[1].Name != "origin/main" || !branches[1].Remote {
+		t.Errorf("Unexpected remote branch: %+v", branches[1])
+	}
+}
+
+func TestParseStashes(t *testing.T) {
+	output := "stash@{0}\x00aaa\x001700000000\x00On main: try the parser\n" +
+		"stash@{1}\x00bbb\x001600000000\x00WIP on feature: 1234567 Add login\n" +
+		"garbage\n"
+
+	stashes := parseStashes(output)
+	if len(stashes) != 2 {
+		t.Fatalf("Expected 2 stashes, got %+v", stashes)
+	}
+	if stashes[0].Ref != "stash@{0}" || stashes[0].Commit != "aaa" || stashes[0].Message != "On main: try the parser" || stashes[0].CreatedAt.Unix() != 1700000000 {
+		t.Errorf("Unexpected latest stash: %+v", stashes[0])
+	}
+	if stashes[1].Ref != "stash@{1}" || stashes[1].Message != "WIP on feature: 1234567 Add login" {
+		t.Errorf("Unexpected older stash: %+v", stashes[1])
+	}
+}
+
+func TestCheckBranchRef(t *testing.T) {
+	refs := []Ref{
+		{Name: "main"},
+		{Name: "origin/main", Remote: true},
+		{Name: "origin/only-remote", Remote: true},
+	}
+
+	if err := CheckBranchRef(refs, "main"); err != nil {
+		t.Errorf("Expected local branch to be valid, got %v", err)
+	}
+	if err := CheckBranchRef(refs, "origin/only-remote"); err != nil {
+		t.Errorf("Expected remote branch to be valid, got %v", err)
+	}
+
+	err := CheckBranchRef(refs, "only-remote")
+	if err == nil || !strings.Contains(err.Error(), "origin/only-remote") {
+		t.Errorf("Expected suggestion for remote branch, got %v", err)
+	}
+
+	err = CheckBranchRef(refs, "missing")
+	if err == nil || strings.Contains(err.Error(), "Did you mean") {
+		t.Errorf("Expected plain error for missing branch, got %v", err)
+	}
+}
+
+func TestSplitRemoteRef(t *testing.T) {
+	remotes := []string{"origin", "upstream", "team/shared"}
+	tests := []struct {
+		ref        string
+		wantRemote string
+		wantBranch string
+		wantOK     bool
+	}{
+		{ref: "origin/feature", wantRemote: "origin", wantBranch: "feature", wantOK: true},
+		{ref: "origin/feature/login", wantRemote: "origin", wantBranch: "feature/login", wantOK: true},
+		{ref: "team/shared/fix", wantRemote: "team/shared", wantBranch: "fix", wantOK: true},
+		{ref: "fork/feature"},
+		{ref: "origin/"},
+		{ref: "feature"},
+	}
+
+	for _, tt := range tests {
+		remote, branch, ok := SplitRemoteRef(remotes, tt.ref)
+		if remote != tt.wantRemote || branch != tt.wantBranch || ok != tt.wantOK {
+			t.Errorf("SplitRemoteRef(%q): Expected %q %q %v, got %q %q %v", tt.ref, tt.wantRemote, tt.wantBranch, tt.wantOK, remote, branch, ok)
+		}
+	}
+}
+
+func TestParseMergedBranches(t *testing.T) {
+	got := parseMergedBranches("feature\nfresh\n\nmain\n")
+	if want := []string{"feature", "fresh", "main"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestParseTracking(t *testing.T) {
+	tests := []struct {
+		output string
+		want   *Tracking
+	}{
+		{"0\t0\n", &Tracking{}},
+		{"3\t1\n", &Tracking{Ahead: 1, Behind: 3}},
+		{"", nil},
+		{"garbage", nil},
+	}
+
+	for _, tt := range tests {
+		got := parseTracking(tt.output)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("Expected parseTracking(%q) = %+v, got %+v", tt.output, tt.want, got)
+		}
+	}
+}
+
+func TestParseRepoInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		wantWorktree bool
+		wantErr      bool
+	}{
+		{name: "main checkout", output: "/repo\n/repo/.git\n/repo/.git\n"},
+		{name: "linked worktree", output: "/repo/.koh/feature\n/repo/.git/worktrees/feature\n/repo/.git\n", wantWorktree: true},
+		{name: "missing lines", output: "/repo\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseRepoInfo(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if info.InWorktree() != tt.wantWorktree {
+				t.Errorf("Expected InWorktree() to be %v for %+v", tt.wantWorktree, info)
+			}
+		})
+	}
+
+	// Relative directories, as git prints for the main checkout, are made absolute
+	info, err := parseRepoInfo("/repo\n.git\n.git\n")
+	if err != nil {
+		t.Fatalf("parseRepoInfo() failed: %v", err)
+	}
+	if !filepath.IsAbs(info.GitDir) || info.InWorktree() {
+		t.Errorf("Expected absolute directories of the main checkout, got %+v", info)
+	}
+}
+
+func TestRepoInfoWithContext(t *testing.T) {
+	repo := testutil.NewRepo(t)
+
+	info, err := RepoInfoWithContext(context.Background())
+	if err != nil {
+		t.Fatalf("RepoInfoWithContext() failed: %v", err)
+	}
+	commonDir, err := GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() failed: %v", err)
+	}
+	if info.CommonDir != commonDir {
+		t.Errorf("Expected common dir %s, got %s", commonDir, info.CommonDir)
+	}
+	if info.TopLevel != repo {
+		t.Errorf("Expected the top level to be %s, got %s", repo, info.TopLevel)
+	}
+}
@@ -0,0 +1,121 @@
+// Package linkdir shares dependency directories such as node_modules
+// between a repository and its worktrees, so each worktree doesn't have to
+// install its own.
+//
+// A directory is shared in one of three modes. A symlink makes the worktree
+// use the repository's directory itself, so installing into one installs
+// into both. Hard links give the worktree a directory of its own whose files
+// share their contents with the repository's until a package manager
+// replaces them. A copy shares nothing and takes up the full space again.
+//
+// Whenever a mode isn't possible, say hard links across file systems or
+// symlinks where the file system has none, linkdir copies instead. A
+// directory that can't be shared at all is removed again, so nothing half
+// made is left behind.
+package linkdir
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bshakr/koh/internal/fsutil"
+)
+
+// Modes of sharing a directory
+const (
+	// Symlink makes the destination a symlink to the source
+	Symlink = "symlink"
+	// Hardlink recreates the source's directories and hard-links its files
+	Hardlink = "hardlink"
+	// Copy copies the source
+	Copy = "copy"
+)
+
+// Link shares the directory src at dst in mode ("" means Symlink) and
+// returns the mode it ended up using, which is Copy when mode wasn't
+// possible for any of the files. dst must not exist yet.
+func Link(src, dst, mode string) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", src)
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", dst)
+	}
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case "", Symlink:
+		if err := os.Symlink(src, dst); err == nil {
+			return Symlink, nil
+		}
+		mode = Copy
+	case Hardlink, Copy:
+	default:
+		return "", fmt.Errorf("unknown mode %q", mode)
+	}
+
+	used, err := replicate(src, dst, mode == Hardlink)
+	if err != nil {
+		_ = os.RemoveAll(dst)
+		return "", err
+	}
+	return used, nil
+}
+
+// replicate recreates the tree at src at dst, hard-linking its files with
+// hardlink and copying them otherwise. Symlinks are recreated as they are,
+// so relative ones such as node_modules/.bin keep working. Once a hard link
+// fails, the remaining files are copied.
+func replicate(src, dst string, hardlink bool) (string, error) {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return os.Mkdir(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			if hardlink {
+				if os.Link(path, target) == nil {
+					return nil
+				}
+				hardlink = false
+			}
+			return fsutil.CopyFile(path, target)
+		}
+		// Sockets, pipes and devices have no place in a dependency directory
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if hardlink {
+		return Hardlink, nil
+	}
+	return Copy, nil
+}
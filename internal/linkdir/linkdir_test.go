@@ -0,0 +1,108 @@
+package linkdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newSource creates a dependency directory with a nested file and a
+// relative symlink, like node_modules/.bin
+func newSource(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "node_modules")
+	if err := os.MkdirAll(filepath.Join(src, "left-pad", "lib"), 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", src, err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "left-pad", "lib", "index.js"), []byte("module.exports = 1\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write index.js: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(src, ".bin"), 0o755); err != nil {
+		t.Fatalf("Failed to create .bin: %v", err)
+	}
+	if err := os.Symlink("../left-pad/lib/index.js", filepath.Join(src, ".bin", "left-pad")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	return src
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		want string
+	}{
+		{name: "default", mode: "", want: Symlink},
+		{name: "symlink", mode: Symlink, want: Symlink},
+		{name: "hardlink", mode: Hardlink, want: Hardlink},
+		{name: "copy", mode: Copy, want: Copy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newSource(t)
+			dst := filepath.Join(t.TempDir(), "worktree", "node_modules")
+
+			used, err := Link(src, dst, tt.mode)
+			if err != nil {
+				t.Fatalf("Link() failed: %v", err)
+			}
+			if used != tt.want {
+				t.Errorf("Expected mode %s, got %s", tt.want, used)
+			}
+
+			// The symlink inside resolves within the shared directory
+			data, err := os.ReadFile(filepath.Join(dst, ".bin", "left-pad"))
+			if err != nil || string(data) != "module.exports = 1\n" {
+				t.Errorf("Expected the file through .bin, got %q (%v)", data, err)
+			}
+
+			srcInfo, _ := os.Stat(filepath.Join(src, "left-pad", "lib", "index.js"))
+			dstInfo, _ := os.Stat(filepath.Join(dst, "left-pad", "lib", "index.js"))
+			if shared := os.SameFile(srcInfo, dstInfo); shared == (tt.want == Copy) {
+				t.Errorf("Expected the file to be shared: %v, got %v", tt.want != Copy, shared)
+			}
+		})
+	}
+}
+
+func TestLinkRefusals(t *testing.T) {
+	src := newSource(t)
+	existing := t.TempDir()
+
+	tests := []struct {
+		name     string
+		src, dst string
+		mode     string
+	}{
+		{name: "missing source", src: filepath.Join(src, "missing"), dst: filepath.Join(existing, "a")},
+		{name: "source is a file", src: filepath.Join(src, "left-pad", "lib", "index.js"), dst: filepath.Join(existing, "b")},
+		{name: "destination exists", src: src, dst: existing},
+		{name: "unknown mode", src: src, dst: filepath.Join(existing, "c"), mode: "reflink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Link(tt.src, tt.dst, tt.mode); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLinkRemovesPartialCopy(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read unreadable files")
+	}
+	src := newSource(t)
+	unreadable := filepath.Join(src, "left-pad", "secret")
+	if err := os.WriteFile(unreadable, nil, 0o000); err != nil {
+		t.Fatalf("Failed to write %s: %v", unreadable, err)
+	}
+	dst := filepath.Join(t.TempDir(), "node_modules")
+
+	if _, err := Link(src, dst, Copy); err == nil {
+		t.Fatal("Expected an error for an unreadable file")
+	}
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", dst, err)
+	}
+}
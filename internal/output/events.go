@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Event types streamed by a Printer
+const (
+	EventStepStarted  = "step_started"
+	EventStepFinished = "step_finished"
+	EventInfo         = "info"
+	EventWarning      = "warning"
+	EventError        = "error"
+	EventResult       = "result"
+)
+
+// Event is a progress event. Streams hold one JSON-encoded event per line,
+// so wrappers and editor integrations can render their own progress.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// Step is the step the event belongs to, e.g. "create_worktree"
+	Step string `json:"step,omitempty"`
+	// Worktree is set by commands that act on several worktrees
+	Worktree string      `json:"worktree,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+}
+
+// StreamEvents makes the printer write progress events to w, in addition
+// to its regular output
+func (p *Printer) StreamEvents(w io.Writer) {
+	p.events = json.NewEncoder(w)
+}
+
+// Observe makes the printer pass every progress event to fn as it happens,
+// for progress views running in the same process
+func (p *Printer) Observe(fn func(Event)) {
+	p.observer = fn
+}
+
+// emit writes an event when events are streamed or observed
+func (p *Printer) emit(typ, message string, result interface{}) {
+	if p.events == nil && p.observer == nil {
+		return
+	}
+	event := Event{Time: time.Now(), Type: typ, Step: p.step, Worktree: p.worktree, Message: message, Result: result}
+	if p.observer != nil {
+		p.observer(event)
+	}
+	if p.events != nil {
+		_ = p.events.Encode(event)
+	}
+}
+
+// Step starts a named step of a command, finishing the previous one. Steps
+// only show up in the event stream; use Info for messages meant for people.
+func (p *Printer) Step(step string) {
+	p.finishStep()
+	p.step = step
+	p.emit(EventStepStarted, "", nil)
+}
+
+// ForWorktree finishes the current step and tags the following events with
+// the worktree they concern, for commands that act on several worktrees
+func (p *Printer) ForWorktree(name string) {
+	p.finishStep()
+	p.worktree = name
+}
+
+// finishStep finishes the current step, if any
+func (p *Printer) finishStep() {
+	if p.step != "" {
+		p.emit(EventStepFinished, "", nil)
+		p.step = ""
+	}
+}
+
+// Fail reports the error that ended a command in the event stream. The
+// current step is left unfinished.
+func (p *Printer) Fail(err error) {
+	p.emit(EventError, err.Error(), nil)
+	p.step = ""
+}
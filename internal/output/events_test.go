@@ -0,0 +1,151 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// decodeEvents parses a stream of JSON lines
+func decodeEvents(t *testing.T, stream *bytes.Buffer) []Event {
+	t.Helper()
+	var events []Event
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Event is not a line of JSON: %v (%q)", err, scanner.Text())
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestPrinterEvents(t *testing.T) {
+	var out, stream bytes.Buffer
+	p := &Printer{out: &out, errOut: io.Discard, format: Human}
+	p.StreamEvents(&stream)
+
+	p.Step("create_worktree")
+	p.Info("Creating %s", "feature")
+	p.Step("create_window")
+	p.Warn("no tmux")
+	if err := p.Result(testResult{Name: "feature"}, func(w io.Writer) {}); err != nil {
+		t.Fatalf("Result() failed: %v", err)
+	}
+
+	want := []Event{
+		{Type: EventStepStarted, Step: "create_worktree"},
+		{Type: EventInfo, Step: "create_worktree", Message: "Creating feature"},
+		{Type: EventStepFinished, Step: "create_worktree"},
+		{Type: EventStepStarted, Step: "create_window"},
+		{Type: EventWarning, Step: "create_window", Message: "no tmux"},
+		{Type: EventStepFinished, Step: "create_window"},
+		{Type: EventResult},
+	}
+	events := decodeEvents(t, &stream)
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i, e := range events {
+		if e.Type != want[i].Type || e.Step != want[i].Step || e.Message != want[i].Message {
+			t.Errorf("Event %d: Expected %+v, got %+v", i, want[i], e)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("Event %d has no time", i)
+		}
+	}
+	if result, ok := events[6].Result.(map[string]interface{}); !ok || result["name"] != "feature" {
+		t.Errorf("Expected the result in the result event, got %+v", events[6].Result)
+	}
+	if out.String() != "Creating feature\nWarning: no tmux\n" {
+		t.Errorf("Expected regular output to be unchanged, got %q", out.String())
+	}
+}
+
+func TestPrinterFail(t *testing.T) {
+	var stream bytes.Buffer
+	p := &Printer{out: io.Discard, errOut: io.Discard, format: Human}
+	p.StreamEvents(&stream)
+
+	p.Step("create_worktree")
+	p.Fail(errors.New("boom"))
+
+	events := decodeEvents(t, &stream)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", events)
+	}
+	if e := events[1]; e.Type != EventError || e.Step != "create_worktree" || e.Message != "boom" {
+		t.Errorf("Expected an error event for the step, got %+v", e)
+	}
+}
+
+func TestPrinterWithoutEvents(t *testing.T) {
+	var out bytes.Buffer
+	p := &Printer{out: &out, errOut: io.Discard, format: Human}
+
+	p.Step("create_worktree")
+	p.Info("Creating feature")
+	p.Fail(errors.New("boom"))
+
+	if out.String() != "Creating feature\n" {
+		t.Errorf("Expected steps to print nothing, got %q", out.String())
+	}
+}
+
+func TestPrinterForWorktree(t *testing.T) {
+	var stream bytes.Buffer
+	p := &Printer{out: io.Discard, errOut: io.Discard, format: Human}
+	p.StreamEvents(&stream)
+
+	p.ForWorktree("feat-a")
+	p.Step("create_worktree")
+	p.Fail(errors.New("boom"))
+	p.ForWorktree("feat-b")
+	p.Step("create_worktree")
+	p.ForWorktree("")
+	if err := p.Result(testResult{Name: "feat-b"}, func(w io.Writer) {}); err != nil {
+		t.Fatalf("Result() failed: %v", err)
+	}
+
+	want := []Event{
+		{Type: EventStepStarted, Step: "create_worktree", Worktree: "feat-a"},
+		{Type: EventError, Step: "create_worktree", Worktree: "feat-a", Message: "boom"},
+		{Type: EventStepStarted, Step: "create_worktree", Worktree: "feat-b"},
+		{Type: EventStepFinished, Step: "create_worktree", Worktree: "feat-b"},
+		{Type: EventResult},
+	}
+	events := decodeEvents(t, &stream)
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i, e := range events {
+		if e.Type != want[i].Type || e.Step != want[i].Step || e.Worktree != want[i].Worktree || e.Message != want[i].Message {
+			t.Errorf("Event %d: Expected %+v, got %+v", i, want[i], e)
+		}
+	}
+}
+
+func TestPrinterObserve(t *testing.T) {
+	p := &Printer{out: io.Discard, errOut: io.Discard, format: Human}
+	var got []string
+	p.Observe(func(e Event) { got = append(got, e.Type+":"+e.Step+":"+e.Message) })
+
+	p.Step("create_worktree")
+	p.Info("Creating feature")
+	p.Step("copy_files")
+
+	want := []string{
+		"step_started:create_worktree:",
+		"info:create_worktree:Creating feature",
+		"step_finished:create_worktree:",
+		"step_started:copy_files:",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected events %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,162 @@
+// Package output provides a shared abstraction for command output.
+//
+// Every command writes through a Printer, which renders results either for
+// humans (styled text) or as JSON for scripts. In JSON mode:
+//   - The final result is written to stdout as a single JSON document
+//   - Progress and warning messages go to stderr so stdout stays parseable
+//   - Errors are reported as {"error": "..."} by the root command
+//
+// Commands build a result value (a struct with JSON tags) and pass a
+// function that renders the human form, so both formats stay in sync.
+//
+// Commands with several steps, such as creating a worktree, can also stream
+// progress events as newline-delimited JSON (see StreamEvents).
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Format selects how command results are rendered
+type Format string
+
+const (
+	// Human renders styled, human-readable text
+	Human Format = "human"
+	// JSON renders machine-readable JSON
+	JSON Format = "json"
+)
+
+// Printer writes command output in the selected format
+type Printer struct {
+	out    io.Writer
+	errOut io.Writer
+	format Format
+
+	// events receives progress events when they are streamed
+	events *json.Encoder
+	// observer receives progress events in-process (see Observe)
+	observer func(Event)
+	// commandOut receives the output of commands steps run, stderr when nil
+	commandOut io.Writer
+	// prompter answers prompts instead of the terminal (see SetPrompter)
+	prompter func(question string) (string, error)
+	// step is the step in progress, if any
+	step string
+	// worktree is the worktree events concern (see ForWorktree)
+	worktree string
+}
+
+// New creates a Printer writing results to out in the given format.
+// Progress messages in JSON mode are written to stderr.
+func New(out io.Writer, format Format) *Printer {
+	return &Printer{out: out, errOut: os.Stderr, format: format}
+}
+
+// IsJSON reports whether the printer renders JSON
+func (p *Printer) IsJSON() bool {
+	return p.format == JSON
+}
+
+// progress returns the writer used for progress and warning messages
+func (p *Printer) progress() io.Writer {
+	if p.IsJSON() {
+		return p.errOut
+	}
+	return p.out
+}
+
+// Info prints a progress message
+func (p *Printer) Info(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	_, _ = fmt.Fprintln(p.progress(), message)
+	p.emit(EventInfo, message, nil)
+}
+
+// Warn prints a warning message
+func (p *Printer) Warn(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	_, _ = fmt.Fprintln(p.progress(), "Warning: "+message)
+	p.emit(EventWarning, message, nil)
+}
+
+// Prompt asks a question where progress messages go and returns the
+// answer read from in, trimmed. Reading fails with io.EOF when in is closed.
+// A prompter set with SetPrompter answers instead.
+func (p *Printer) Prompt(in io.Reader, format string, args ...interface{}) (string, error) {
+	if p.prompter != nil {
+		answer, err := p.prompter(fmt.Sprintf(format, args...))
+		return strings.TrimSpace(answer), err
+	}
+	_, _ = fmt.Fprintf(p.progress(), format, args...)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// SetPrompter makes fn answer the printer's prompts, for progress views that
+// own the terminal
+func (p *Printer) SetPrompter(fn func(question string) (string, error)) {
+	p.prompter = fn
+}
+
+// CommandOutput returns where the output of commands run by a step goes:
+// stderr, so that JSON on stdout stays parseable, unless SetCommandOutput
+// redirected it
+func (p *Printer) CommandOutput() io.Writer {
+	if p.commandOut != nil {
+		return p.commandOut
+	}
+	return p.errOut
+}
+
+// SetCommandOutput sends the output of commands run by a step to w
+func (p *Printer) SetCommandOutput(w io.Writer) {
+	p.commandOut = w
+}
+
+// Result renders the final result of a command. In JSON mode v is encoded
+// to stdout; otherwise human is called to render the styled form.
+func (p *Printer) Result(v interface{}, human func(w io.Writer)) error {
+	p.finishStep()
+	p.emit(EventResult, "", v)
+
+	if p.IsJSON() {
+		return WriteJSON(p.out, v)
+	}
+	if human != nil {
+		human(p.out)
+	}
+	return nil
+}
+
+// Error renders an error. In JSON mode it is written as {"error": "..."}
+// to stdout; otherwise the message is written as plain text.
+func (p *Printer) Error(err error) {
+	if p.IsJSON() {
+		_ = WriteJSON(p.out, struct {
+			Error string `json:"error"`
+		}{Error: err.Error()})
+		return
+	}
+	_, _ = fmt.Fprintln(p.out, err)
+}
+
+// WriteJSON writes v as indented JSON followed by a newline
+func WriteJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	if _, err := fmt.Fprintln(w, string(data)); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
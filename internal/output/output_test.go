@@ -0,0 +1,140 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type testResult struct {
+	Name string `json:"name"`
+}
+
+func TestPrinterHuman(t *testing.T) {
+	var out, errOut bytes.Buffer
+	p := &Printer{out: &out, errOut: &errOut, format: Human}
+
+	p.Info("Creating %s", "feature")
+	p.Warn("something odd")
+	err := p.Result(testResult{Name: "feature"}, func(w io.Writer) {
+		_, _ = io.WriteString(w, "done\n")
+	})
+	if err != nil {
+		t.Fatalf("Result() failed: %v", err)
+	}
+
+	want := "Creating feature\nWarning: something odd\ndone\n"
+	if out.String() != want {
+		t.Errorf("Got %q, want %q", out.String(), want)
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("Expected nothing on stderr in human mode, got %q", errOut.String())
+	}
+}
+
+func TestPrinterJSON(t *testing.T) {
+	var out, errOut bytes.Buffer
+	p := &Printer{out: &out, errOut: &errOut, format: JSON}
+
+	p.Info("Creating %s", "feature")
+	err := p.Result(testResult{Name: "feature"}, func(w io.Writer) {
+		t.Error("Human renderer should not be called in JSON mode")
+	})
+	if err != nil {
+		t.Fatalf("Result() failed: %v", err)
+	}
+
+	var decoded testResult
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("stdout is not valid JSON: %v (%q)", err, out.String())
+	}
+	if decoded.Name != "feature" {
+		t.Errorf("Unexpected result: %+v", decoded)
+	}
+	if !strings.Contains(errOut.String(), "Creating feature") {
+		t.Errorf("Expected progress on stderr, got %q", errOut.String())
+	}
+}
+
+func TestPrinterPrompt(t *testing.T) {
+	var out bytes.Buffer
+	p := &Printer{out: &out, errOut: &out, format: Human}
+
+	answer, err := p.Prompt(strings.NewReader("  yes \nignored\n"), "Continue %s? ", "now")
+	if err != nil {
+		t.Fatalf("Prompt() failed: %v", err)
+	}
+	if answer != "yes" {
+		t.Errorf("Expected answer %q, got %q", "yes", answer)
+	}
+	if out.String() != "Continue now? " {
+		t.Errorf("Expected the question to be printed, got %q", out.String())
+	}
+
+	if _, err := p.Prompt(strings.NewReader(""), "Continue? "); err == nil {
+		t.Error("Expected an error when there is no input")
+	}
+}
+
+func TestPrinterErrorJSON(t *testing.T) {
+	var out bytes.Buffer
+	p := &Printer{out: &out, errOut: io.Discard, format: JSON}
+
+	p.Error(errors.New("boom"))
+
+	var decoded map[string]string
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Error output is not valid JSON: %v", err)
+	}
+	if decoded["error"] != "boom" {
+		t.Errorf("Unexpected error payload: %v", decoded)
+	}
+}
+
+func TestWriteScriptFilterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteScriptFilter(&out, nil); err != nil {
+		t.Fatalf("WriteScriptFilter() failed: %v", err)
+	}
+
+	// Launchers expect an items array even when there are no results
+	if got := strings.TrimSpace(out.String()); got != "{\n  \"items\": []\n}" {
+		t.Errorf("Unexpected script filter output: %q", got)
+	}
+}
+
+func TestPrinterPrompter(t *testing.T) {
+	var out bytes.Buffer
+	p := &Printer{out: &out, errOut: &out, format: Human}
+	var asked string
+	p.SetPrompter(func(question string) (string, error) {
+		asked = question
+		return " y ", nil
+	})
+
+	answer, err := p.Prompt(strings.NewReader(""), "Continue %s? ", "now")
+	if err != nil {
+		t.Fatalf("Prompt() failed: %v", err)
+	}
+	if asked != "Continue now? " || answer != "y" {
+		t.Errorf("Expected the prompter to answer %q with %q, got %q and %q", "Continue now? ", "y", asked, answer)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing to be printed, got %q", out.String())
+	}
+}
+
+func TestPrinterCommandOutput(t *testing.T) {
+	var errOut, commands bytes.Buffer
+	p := &Printer{out: io.Discard, errOut: &errOut, format: Human}
+	if p.CommandOutput() != &errOut {
+		t.Error("Expected command output to go to stderr by default")
+	}
+	p.SetCommandOutput(&commands)
+	if p.CommandOutput() != &commands {
+		t.Error("Expected SetCommandOutput to redirect command output")
+	}
+}
@@ -0,0 +1,27 @@
+package output
+
+import "io"
+
+// ScriptFilterItem is a single result for a launcher such as Alfred or Raycast
+type ScriptFilterItem struct {
+	UID          string `json:"uid"`
+	Title        string `json:"title"`
+	Subtitle     string `json:"subtitle,omitempty"`
+	Arg          string `json:"arg"`
+	Autocomplete string `json:"autocomplete,omitempty"`
+}
+
+// ScriptFilter is the "script filter" JSON document launchers read: Alfred
+// consumes it natively, and Raycast script commands and extensions can parse
+// the same shape. Selecting an item passes its Arg to the launcher's action.
+type ScriptFilter struct {
+	Items []ScriptFilterItem `json:"items"`
+}
+
+// WriteScriptFilter writes items as a script filter document
+func WriteScriptFilter(w io.Writer, items []ScriptFilterItem) error {
+	if items == nil {
+		items = []ScriptFilterItem{}
+	}
+	return WriteJSON(w, ScriptFilter{Items: items})
+}
@@ -0,0 +1,91 @@
+// Package paths resolves the user-level directories koh keeps files in.
+//
+// Locations follow the XDG Base Directory specification on every platform,
+// so they are predictable and easy to back up or clean:
+//   - Config: $XDG_CONFIG_HOME/koh (~/.config/koh), global configuration
+//   - State:  $XDG_STATE_HOME/koh (~/.local/state/koh), what koh recorded
+//     about worktrees, and telemetry counters
+//   - Cache:  $XDG_CACHE_HOME/koh (~/.cache/koh), data that can be recomputed
+//   - Data:   $XDG_DATA_HOME/koh (~/.local/share/koh), snapshots and archives
+//   - Logs:   the logs directory inside the state directory
+//
+// Each location can be overridden with its own environment variable, e.g.
+// KOH_STATE_DIR, which tests use to stay out of the user's home directory.
+// As the specification requires, relative XDG values are ignored.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// location describes how one directory is resolved
+type location struct {
+	// override is the koh-specific environment variable
+	override string
+	// xdg is the XDG environment variable
+	xdg string
+	// fallback is the default below the home directory when xdg is unset
+	fallback string
+}
+
+var (
+	config = location{override: "KOH_CONFIG_DIR", xdg: "XDG_CONFIG_HOME", fallback: ".config"}
+	state  = location{override: "KOH_STATE_DIR", xdg: "XDG_STATE_HOME", fallback: filepath.Join(".local", "state")}
+	cache  = location{override: "KOH_CACHE_DIR", xdg: "XDG_CACHE_HOME", fallback: ".cache"}
+	data   = location{override: "KOH_DATA_DIR", xdg: "XDG_DATA_HOME", fallback: filepath.Join(".local", "share")}
+)
+
+// resolve returns the directory for a location
+func (l location) resolve() (string, error) {
+	if dir := os.Getenv(l.override); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv(l.xdg); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "koh"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, l.fallback, "koh"), nil
+}
+
+// ConfigDir returns the directory for global configuration.
+// KOH_CONFIG_DIR overrides it.
+func ConfigDir() (string, error) {
+	return config.resolve()
+}
+
+// StateDir returns the directory for recorded state.
+// KOH_STATE_DIR overrides it.
+func StateDir() (string, error) {
+	return state.resolve()
+}
+
+// CacheDir returns the directory for cached data.
+// KOH_CACHE_DIR overrides it.
+func CacheDir() (string, error) {
+	return cache.resolve()
+}
+
+// DataDir returns the directory for data such as snapshots and archives.
+// KOH_DATA_DIR overrides it.
+func DataDir() (string, error) {
+	return data.resolve()
+}
+
+// LogDir returns the directory for logs.
+// KOH_LOG_DIR overrides the default of the logs directory in StateDir.
+func LogDir() (string, error) {
+	if dir := os.Getenv("KOH_LOG_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs"), nil
+}
@@ -0,0 +1,48 @@
+package paths
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"KOH_CONFIG_DIR", "KOH_STATE_DIR", "KOH_CACHE_DIR", "KOH_DATA_DIR", "KOH_LOG_DIR",
+		"XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME"} {
+		t.Setenv(env, "")
+	}
+
+	tests := []struct {
+		name string
+		dir  func() (string, error)
+		env  map[string]string
+		want string
+	}{
+		{name: "config default", dir: ConfigDir, want: filepath.Join(home, ".config", "koh")},
+		{name: "config xdg", dir: ConfigDir, env: map[string]string{"XDG_CONFIG_HOME": "/xdg/config"}, want: "/xdg/config/koh"},
+		{name: "config override", dir: ConfigDir, env: map[string]string{"XDG_CONFIG_HOME": "/xdg/config", "KOH_CONFIG_DIR": "/koh/config"}, want: "/koh/config"},
+		{name: "relative xdg is ignored", dir: ConfigDir, env: map[string]string{"XDG_CONFIG_HOME": "relative"}, want: filepath.Join(home, ".config", "koh")},
+		{name: "state default", dir: StateDir, want: filepath.Join(home, ".local", "state", "koh")},
+		{name: "state override", dir: StateDir, env: map[string]string{"KOH_STATE_DIR": "/koh/state"}, want: "/koh/state"},
+		{name: "cache xdg", dir: CacheDir, env: map[string]string{"XDG_CACHE_HOME": "/xdg/cache"}, want: "/xdg/cache/koh"},
+		{name: "data default", dir: DataDir, want: filepath.Join(home, ".local", "share", "koh")},
+		{name: "logs follow state", dir: LogDir, env: map[string]string{"KOH_STATE_DIR": "/koh/state"}, want: "/koh/state/logs"},
+		{name: "logs override", dir: LogDir, env: map[string]string{"KOH_LOG_DIR": "/koh/logs"}, want: "/koh/logs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := tt.dir()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package ports hands out ports to worktrees, so dev servers started in
+// different worktrees don't fight over the same one.
+//
+// Each worktree gets a block of consecutive ports. Blocks are taken in order
+// from a start port, skipping blocks another worktree holds or something
+// already listens on, and a worktree keeps its block until it is released,
+// so its servers come back on the same ports every time its window is
+// recreated.
+package ports
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// maxPort is the highest TCP port
+const maxPort = 65535
+
+// Reserve returns the ports of the worktree at path, reserving them in
+// reserved (worktree paths to ports) when it has none yet: the first block
+// of count ports from start on that no other worktree holds and in which
+// available reports every port free. A worktree that holds a block of a
+// different size gets a new one.
+func Reserve(reserved map[string][]int, path string, start, count int, available func(port int) bool) ([]int, error) {
+	if held := reserved[path]; len(held) == count {
+		return held, nil
+	}
+	delete(reserved, path)
+
+	taken := make(map[int]bool)
+	for _, held := range reserved {
+		for _, port := range held {
+			taken[port] = true
+		}
+	}
+
+	for first := start; first+count-1 <= maxPort; first += count {
+		block := make([]int, count)
+		free := true
+		for i := range block {
+			block[i] = first + i
+			if taken[block[i]] || !available(block[i]) {
+				free = false
+				break
+			}
+		}
+		if free {
+			reserved[path] = block
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("no %d free ports left from %d on", count, start)
+}
+
+// Available reports whether a port is free to listen on
+func Available(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+// Env returns the environment exposing ports to a worktree's panes: the
+// first as KOH_PORT, the next as KOH_PORT_2, KOH_PORT_3 and so on
+func Env(ports []int) []string {
+	env := make([]string, 0, len(ports))
+	for i, port := range ports {
+		name := "KOH_PORT"
+		if i > 0 {
+			name = fmt.Sprintf("KOH_PORT_%d", i+1)
+		}
+		env = append(env, fmt.Sprintf("%s=%d", name, port))
+	}
+	return env
+}
+
+// Format returns ports for display, such as "4000-4002" or "4000"
+func Format(ports []int) string {
+	switch len(ports) {
+	case 0:
+		return ""
+	case 1:
+		return strconv.Itoa(ports[0])
+	}
+	return fmt.Sprintf("%d-%d", ports[0], ports[len(ports)-1])
+}
@@ -0,0 +1,79 @@
+package ports
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	all := func(int) bool { return true }
+	reserved := map[string][]int{"/a": {4000, 4001}}
+
+	tests := []struct {
+		name      string
+		path      string
+		count     int
+		available func(int) bool
+		want      []int
+	}{
+		{name: "keeps its ports", path: "/a", count: 2, available: func(int) bool { return false }, want: []int{4000, 4001}},
+		{name: "next free block", path: "/b", count: 2, available: all, want: []int{4002, 4003}},
+		{name: "skips ports in use", path: "/c", count: 2, available: func(port int) bool { return port != 4005 }, want: []int{4006, 4007}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reserve(reserved, tt.path, 4000, tt.count, tt.available)
+			if err != nil {
+				t.Fatalf("Reserve() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if !reflect.DeepEqual(reserved[tt.path], tt.want) {
+				t.Errorf("Expected %v to be reserved, got %v", tt.want, reserved[tt.path])
+			}
+		})
+	}
+
+	// A different count moves the worktree to a block of the new size
+	got, err := Reserve(reserved, "/a", 4000, 3, all)
+	if err != nil {
+		t.Fatalf("Reserve() failed: %v", err)
+	}
+	if want := []int{4009, 4010, 4011}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := Reserve(map[string][]int{}, "/d", 65534, 2, func(int) bool { return false }); err == nil {
+		t.Error("Expected an error when no ports are free")
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	if Available(port) {
+		t.Errorf("Expected port %d to be in use", port)
+	}
+	_ = l.Close()
+	if !Available(port) {
+		t.Errorf("Expected port %d to be free once closed", port)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	if got := strings.Join(Env([]int{4000, 4001, 4002}), " "); got != "KOH_PORT=4000 KOH_PORT_2=4001 KOH_PORT_3=4002" {
+		t.Errorf("Unexpected environment: %s", got)
+	}
+	if got := Format([]int{4000, 4001, 4002}); got != "4000-4002" {
+		t.Errorf("Expected 4000-4002, got %s", got)
+	}
+	if got := Format([]int{4000}); got != "4000" {
+		t.Errorf("Expected 4000, got %s", got)
+	}
+}
@@ -0,0 +1,94 @@
+// Package procs measures the CPU and memory used by trees of processes.
+//
+// It reads a snapshot of the process table with ps, which works the same on
+// Linux and macOS, so koh can show what each worktree window is running.
+package procs
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Usage is the resource usage of a group of processes
+type Usage struct {
+	// CPU is the summed CPU percentage as reported by ps, so it can exceed
+	// 100 on machines with several cores
+	CPU float64 `json:"cpu_percent"`
+	// RSSKB is the summed resident memory in kilobytes
+	RSSKB int64 `json:"rss_kb"`
+	// Processes is the number of processes counted
+	Processes int `json:"processes"`
+}
+
+// process is one row of the process table
+type process struct {
+	pid, ppid int
+	cpu       float64
+	rssKB     int64
+}
+
+// Table is a snapshot of the process table
+type Table struct {
+	procs    map[int]process
+	children map[int][]int
+}
+
+// SnapshotWithContext reads the current process table with ps
+func SnapshotWithContext(ctx context.Context) (*Table, error) {
+	output, err := exec.CommandContext(ctx, "ps", "-A", "-o", "pid=,ppid=,%cpu=,rss=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	return parseTable(string(output)), nil
+}
+
+// parseTable parses ps output with pid, ppid, %cpu and rss columns. Rows
+// that don't parse are skipped.
+func parseTable(output string) *Table {
+	t := &Table{procs: make(map[int]process), children: make(map[int][]int)}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		cpu, err3 := strconv.ParseFloat(strings.Replace(fields[2], ",", ".", 1), 64)
+		rss, err4 := strconv.ParseInt(fields[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		t.procs[pid] = process{pid: pid, ppid: ppid, cpu: cpu, rssKB: rss}
+		t.children[ppid] = append(t.children[ppid], pid)
+	}
+	return t
+}
+
+// Sum returns the usage of the given processes and all their descendants.
+// Processes that have exited since the snapshot are ignored.
+func (t *Table) Sum(roots []int) Usage {
+	var u Usage
+	seen := make(map[int]bool)
+	queue := append([]int(nil), roots...)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+
+		p, ok := t.procs[pid]
+		if !ok {
+			continue
+		}
+		u.CPU += p.cpu
+		u.RSSKB += p.rssKB
+		u.Processes++
+		queue = append(queue, t.children[pid]...)
+	}
+	return u
+}
@@ -0,0 +1,48 @@
+package procs
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+const psOutput = `    1     0  0.0  1000
+  100     1  1.5  2048
+  101   100 20.0 40960
+  102   101  5,5  1024
+  200     1 50.0 99999
+  garbage
+`
+
+func TestSum(t *testing.T) {
+	table := parseTable(psOutput)
+
+	tests := []struct {
+		name  string
+		roots []int
+		want  Usage
+	}{
+		{name: "tree", roots: []int{100}, want: Usage{CPU: 27, RSSKB: 44032, Processes: 3}},
+		{name: "subtree", roots: []int{101}, want: Usage{CPU: 25.5, RSSKB: 41984, Processes: 2}},
+		{name: "overlapping roots are counted once", roots: []int{100, 102}, want: Usage{CPU: 27, RSSKB: 44032, Processes: 3}},
+		{name: "exited process", roots: []int{999}, want: Usage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := table.Sum(tt.roots); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSnapshotIncludesSelf(t *testing.T) {
+	table, err := SnapshotWithContext(context.Background())
+	if err != nil {
+		t.Skipf("ps not available: %v", err)
+	}
+	if u := table.Sum([]int{os.Getpid()}); u.Processes == 0 || u.RSSKB == 0 {
+		t.Errorf("Expected the test process in the snapshot, got %+v", u)
+	}
+}
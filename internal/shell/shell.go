@@ -0,0 +1,14 @@
+// Package shell quotes words for the POSIX shell commands koh builds, such
+// as pane commands, the scripts it sends to tmux and the commands it prints
+// for launchers and hooks.
+package shell
+
+import "strings"
+
+// Quote quotes s as a single word for POSIX shells. s is always quoted, even
+// when the shell would take it literally, so the result stays one word if it
+// is used as a template whose placeholders are filled in later, such as
+// tmux's %%% in command-prompt.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
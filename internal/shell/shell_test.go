@@ -0,0 +1,19 @@
+package shell
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"main.go":        "'main.go'",
+		"":               "''",
+		"my file.go":     "'my file.go'",
+		"/tmp/it's here": `'/tmp/it'\''s here'`,
+		"$(rm -rf ~)":    "'$(rm -rf ~)'",
+		"a;b":            "'a;b'",
+	}
+	for s, want := range tests {
+		if got := Quote(s); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package snapshot saves the panes of a worktree window so the window can be
+// rebuilt later, e.g. after a reboot.
+//
+// A snapshot records, for each pane, what was running and the command koh
+// last sent to it, plus the text the pane showed including its scrollback.
+// Snapshots live in the user's data directory (see paths.DataDir), one
+// directory per repository and worktree, keyed by the repository's common
+// git directory like the state store:
+//
+//	snapshots/<repo>/<worktree>/snapshot.json
+//	snapshots/<repo>/<worktree>/pane-<n>.txt
+//
+// Taking a snapshot replaces the previous one of the same worktree, which is
+// kept if the new one can't be written.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bshakr/koh/internal/paths"
+)
+
+// Snapshot is a saved worktree window
+type Snapshot struct {
+	Worktree  string    `json:"worktree"`
+	CreatedAt time.Time `json:"created_at"`
+	Panes     []Pane    `json:"panes"`
+}
+
+// Pane is a saved pane of a worktree window
+type Pane struct {
+	// Pane is the pane's position in the window, counted from 0
+	Pane int `json:"pane"`
+	// Process is the program that was running in the foreground, e.g. "node"
+	Process string `json:"process"`
+	// Command is the command koh last sent to the pane, "" when none
+	Command string `json:"command,omitempty"`
+	// Contents is the text the pane showed, stored next to the snapshot
+	Contents string `json:"-"`
+}
+
+// Dir returns the directory a worktree's snapshot is stored in
+func Dir(commonDir, worktreeName string) (string, error) {
+	dir, err := paths.DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(commonDir))
+	return filepath.Join(dir, "snapshots", hex.EncodeToString(sum[:8]), worktreeName), nil
+}
+
+// ContentsPath returns the file the contents of a saved pane are stored in
+func ContentsPath(commonDir, worktreeName string, pane int) (string, error) {
+	dir, err := Dir(commonDir, worktreeName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, contentsFile(pane)), nil
+}
+
+// contentsFile is the name of the file the contents of a saved pane are
+// stored in, inside the snapshot's directory
+func contentsFile(pane int) string {
+	return fmt.Sprintf("pane-%d.txt", pane)
+}
+
+// writeFile writes the files of a snapshot; tests replace it to fail a save
+var writeFile = os.WriteFile
+
+// Save writes a snapshot, replacing the worktree's previous one. The new
+// snapshot is written to a temporary directory next to the old one and only
+// moved into place once complete, so a failed save leaves the previous
+// snapshot intact.
+func Save(commonDir string, s *Snapshot) error {
+	dir, err := Dir(commonDir, s.Worktree)
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+s.Worktree+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	for _, pane := range s.Panes {
+		// Pane contents can include secrets printed to the terminal
+		if err := writeFile(filepath.Join(tmp, contentsFile(pane.Pane)), []byte(pane.Contents), 0600); err != nil {
+			return fmt.Errorf("failed to write pane contents: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := writeFile(filepath.Join(tmp, "snapshot.json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// A directory can't be renamed over another, so the previous snapshot
+	// steps aside first and comes back if the new one can't take its place
+	previous := tmp + ".previous"
+	if err := os.Rename(dir, previous); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace previous snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		_ = os.Rename(previous, dir)
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	_ = os.RemoveAll(previous)
+	return nil
+}
+
+// Remove deletes the snapshot of a worktree, if any
+func Remove(commonDir, worktreeName string) error {
+	dir, err := Dir(commonDir, worktreeName)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads the snapshot of a worktree, including the contents of its panes
+func Load(commonDir, worktreeName string) (*Snapshot, error) {
+	dir, err := Dir(commonDir, worktreeName)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:gosec // G304: Reading a snapshot from the user's data directory is expected
+	data, err := os.ReadFile(filepath.Join(dir, "snapshot.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot of %s found\nUse 'koh snapshot %s' to take one", worktreeName, worktreeName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	for i := range s.Panes {
+		path, err := ContentsPath(commonDir, worktreeName, s.Panes[i].Pane)
+		if err != nil {
+			return nil, err
+		}
+		//nolint:gosec // G304: Reading a snapshot from the user's data directory is expected
+		contents, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read pane contents: %w", err)
+		}
+		s.Panes[i].Contents = string(contents)
+	}
+	return s, nil
+}
@@ -0,0 +1,137 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("KOH_DATA_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	s := &Snapshot{
+		Worktree:  "feature",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Panes: []Pane{
+			{Pane: 0, Process: "bash", Command: "./bin/setup", Contents: "setup done\n"},
+			{Pane: 1, Process: "node", Command: "npm run dev", Contents: "listening on :3000\n"},
+		},
+	}
+	if err := Save(commonDir, s); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	loaded, err := Load(commonDir, "feature")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !loaded.CreatedAt.Equal(s.CreatedAt) || len(loaded.Panes) != 2 {
+		t.Fatalf("Expected the saved snapshot, got %+v", loaded)
+	}
+	for i, pane := range s.Panes {
+		if loaded.Panes[i] != pane {
+			t.Errorf("Expected pane %+v, got %+v", pane, loaded.Panes[i])
+		}
+	}
+
+	path, err := ContentsPath(commonDir, "feature", 1)
+	if err != nil {
+		t.Fatalf("ContentsPath() failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected pane contents readable by the user only, got %v (%v)", info, err)
+	}
+}
+
+func TestSaveReplacesPreviousSnapshot(t *testing.T) {
+	t.Setenv("KOH_DATA_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	first := &Snapshot{Worktree: "feature", Panes: []Pane{{Pane: 0}, {Pane: 1}, {Pane: 2}}}
+	second := &Snapshot{Worktree: "feature", Panes: []Pane{{Pane: 0}}}
+	if err := Save(commonDir, first); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := Save(commonDir, second); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	path, err := ContentsPath(commonDir, "feature", 2)
+	if err != nil {
+		t.Fatalf("ContentsPath() failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected contents of the previous snapshot to be removed, got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	t.Setenv("KOH_DATA_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	if err := Save(commonDir, &Snapshot{Worktree: "feature", Panes: []Pane{{Pane: 0}}}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := Remove(commonDir, "feature"); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if _, err := Load(commonDir, "feature"); err == nil {
+		t.Error("Expected the snapshot to be gone")
+	}
+	if err := Remove(commonDir, "feature"); err != nil {
+		t.Errorf("Expected removing a missing snapshot to succeed, got %v", err)
+	}
+}
+
+func TestLoadMissingSnapshot(t *testing.T) {
+	t.Setenv("KOH_DATA_DIR", t.TempDir())
+
+	if _, err := Load("/repo/.git", "feature"); err == nil {
+		t.Error("Expected an error for a worktree without a snapshot")
+	}
+}
+
+func TestFailedSaveKeepsPreviousSnapshot(t *testing.T) {
+	t.Setenv("KOH_DATA_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	first := &Snapshot{Worktree: "feature", Panes: []Pane{{Pane: 0, Process: "vim", Contents: "first\n"}}}
+	if err := Save(commonDir, first); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	original := writeFile
+	writeFile = func(name string, data []byte, perm os.FileMode) error {
+		if filepath.Base(name) == "snapshot.json" {
+			return errors.New("disk full")
+		}
+		return original(name, data, perm)
+	}
+	t.Cleanup(func() { writeFile = original })
+
+	second := &Snapshot{Worktree: "feature", Panes: []Pane{{Pane: 0, Process: "node", Contents: "second\n"}}}
+	if err := Save(commonDir, second); err == nil {
+		t.Fatal("Expected Save() to fail")
+	}
+
+	loaded, err := Load(commonDir, "feature")
+	if err != nil {
+		t.Fatalf("Expected the previous snapshot to survive, got %v", err)
+	}
+	if len(loaded.Panes) != 1 || loaded.Panes[0] != first.Panes[0] {
+		t.Errorf("Expected the previous snapshot, got %+v", loaded.Panes)
+	}
+
+	// Nothing is left behind next to the snapshot
+	dir, err := Dir(commonDir, "feature")
+	if err != nil {
+		t.Fatalf("Dir() failed: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the snapshot directory, got %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package state
+
+import "os"
+
+// tryLock doesn't lock on platforms without flock. Writes are still atomic,
+// but concurrent updates may overwrite each other.
+func tryLock(_ *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile releases the lock taken by tryLock
+func unlockFile(_ *os.File) error {
+	return nil
+}
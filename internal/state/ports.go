@@ -0,0 +1,88 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Ports is the registry of ports reserved for worktrees. Ports are shared by
+// every repository on the machine, so unlike State there is a single
+// registry, ports.json in the state directory.
+type Ports struct {
+	// Version is the schema version the registry was written with
+	Version int `json:"version"`
+	// Reserved maps the paths of worktrees to their ports
+	Reserved map[string][]int `json:"reserved"`
+}
+
+// portsPath returns the path of the port registry
+func portsPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ports.json"), nil
+}
+
+// LoadPorts reads the port registry. Without one, no ports are reserved.
+func LoadPorts() (*Ports, error) {
+	path, err := portsPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadPorts(path)
+}
+
+// loadPorts reads the port registry at path
+func loadPorts(path string) (*Ports, error) {
+	p := &Ports{Version: CurrentVersion, Reserved: make(map[string][]int)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port registry: %w", err)
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse port registry: %w", err)
+	}
+	if p.Version > CurrentVersion {
+		return nil, fmt.Errorf("port registry was written by a newer version of koh (schema %d, this koh reads up to %d); upgrade koh", p.Version, CurrentVersion)
+	}
+	if p.Reserved == nil {
+		p.Reserved = make(map[string][]int)
+	}
+	return p, nil
+}
+
+// UpdatePorts loads the port registry, applies fn and saves the result,
+// holding the lock throughout so two worktrees never get the same ports
+func UpdatePorts(fn func(p *Ports) error) error {
+	path, err := portsPath()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	p, err := loadPorts(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(p); err != nil {
+		return err
+	}
+
+	p.Version = CurrentVersion
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal port registry: %w", err)
+	}
+	return writeFile(path, data)
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrRepoLocked is returned by LockRepo when another koh process kept the
+// repository locked for longer than the caller was willing to wait
+var ErrRepoLocked = errors.New("another koh process is changing this repository")
+
+// LockHolder describes the koh process holding a repository's lock
+type LockHolder struct {
+	PID int `json:"pid"`
+	// Command is what the process is doing, e.g. "koh new feature"
+	Command string    `json:"command"`
+	Since   time.Time `json:"since"`
+}
+
+func (h LockHolder) String() string {
+	if h.PID == 0 {
+		return "another koh process"
+	}
+	return fmt.Sprintf("%s (pid %d, since %s)", h.Command, h.PID, h.Since.Format("15:04:05"))
+}
+
+// LockRepo takes the lock koh commands hold while they add or remove a
+// repository's worktrees, so two of them never race on the same worktree
+// directory or window name, and returns the function that releases it.
+// command describes the caller to processes that have to wait for it.
+//
+// While another process holds the lock, waiting is called once with its
+// holder and LockRepo retries for up to timeout before returning
+// ErrRepoLocked. The lock is an flock, which the system releases when its
+// process exits however it exits, so a koh that crashed or was killed never
+// leaves a stale lock behind; the holder written to the lock file only
+// describes the current holder and is overwritten by the next one.
+func LockRepo(commonDir, command string, timeout time.Duration, waiting func(LockHolder)) (func(), error) {
+	statePath, err := filePath(commonDir)
+	if err != nil {
+		return nil, err
+	}
+	path := statePath[:len(statePath)-len(filepath.Ext(statePath))] + ".repo.lock"
+
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	//nolint:gosec // G304: the lock file lives in the state directory
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository lock: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	notified := false
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock repository: %w", err)
+		}
+		if locked {
+			break
+		}
+
+		holder := readLockHolder(f)
+		if !notified && waiting != nil {
+			waiting(holder)
+			notified = true
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("%w: %s", ErrRepoLocked, holder)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	writeLockHolder(f, LockHolder{PID: os.Getpid(), Command: command, Since: time.Now()})
+	return func() {
+		_ = f.Truncate(0)
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// readLockHolder reads the holder recorded in a lock file, which is zero
+// when the file is empty or being written
+func readLockHolder(f *os.File) LockHolder {
+	var holder LockHolder
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return holder
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return holder
+	}
+	_ = json.Unmarshal(data, &holder)
+	return holder
+}
+
+// writeLockHolder records the holder of a lock file. Failures only make
+// waiting processes less informative, so they are ignored.
+func writeLockHolder(f *os.File, holder LockHolder) {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return
+	}
+	if err := f.Truncate(0); err != nil {
+		return
+	}
+	_, _ = f.WriteAt(data, 0)
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package state
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockRepo(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	unlock, err := LockRepo(commonDir, "koh new feature", time.Second, nil)
+	if err != nil {
+		t.Fatalf("LockRepo() failed: %v", err)
+	}
+
+	// A second holder waits for the first and gives up after the timeout
+	var waitedFor LockHolder
+	_, err = LockRepo(commonDir, "koh cleanup feature", 200*time.Millisecond, func(h LockHolder) {
+		waitedFor = h
+	})
+	if !errors.Is(err, ErrRepoLocked) || !strings.Contains(err.Error(), "koh new feature") {
+		t.Errorf("Expected the lock to be held by koh new feature, got %v", err)
+	}
+	if waitedFor.PID != os.Getpid() || waitedFor.Command != "koh new feature" {
+		t.Errorf("Expected to be told who holds the lock, got %+v", waitedFor)
+	}
+
+	// Other repositories have locks of their own
+	unlockOther, err := LockRepo("/other/.git", "koh new feature", 0, nil)
+	if err != nil {
+		t.Fatalf("Expected another repository's lock to be free, got %v", err)
+	}
+	unlockOther()
+
+	unlock()
+	unlock, err = LockRepo(commonDir, "koh cleanup feature", 0, nil)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be free, got %v", err)
+	}
+	unlock()
+}
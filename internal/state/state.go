@@ -0,0 +1,369 @@
+// Package state persists metadata koh records about worktrees.
+//
+// Unlike the cache, state can't be recomputed from git or tmux: it records
+// what koh did, such as the commands it sent to each pane. Each repository
+// has one state file in the user's state directory, keyed by the repository's
+// common git directory.
+//
+// Several koh processes may update the same file at once, so Update holds an
+// exclusive lock while it reads and writes, and writes replace the file
+// atomically. Files carry a schema version; older ones are migrated when
+// read, and ones written by a newer koh are refused rather than overwritten.
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bshakr/koh/internal/paths"
+)
+
+// maxPaneCommands bounds the command history kept per worktree
+const maxPaneCommands = 20
+
+// CurrentVersion is the schema version of the state files koh writes. Files
+// from before versioning have no version and count as version 1.
+const CurrentVersion = 1
+
+// migrations upgrade a decoded state file by one version: migrations[i]
+// turns version i+1 into version i+2. Bump CurrentVersion with each one.
+var migrations = []func(doc map[string]any) error{}
+
+// lockTimeout is how long Update waits for another process to release the state
+const lockTimeout = 5 * time.Second
+
+// State is the recorded metadata for a repository
+type State struct {
+	// Version is the schema version the state was written with
+	Version int `json:"version"`
+
+	Worktrees map[string]*Worktree `json:"worktrees"`
+
+	// LastFetch is when koh last started a background fetch (see auto_fetch)
+	LastFetch time.Time `json:"last_fetch,omitzero"`
+
+	// Inventoried is set once the worktrees koh created before it kept
+	// records have been recorded, so they aren't taken for strangers
+	Inventoried bool `json:"inventoried,omitempty"`
+}
+
+// Worktree is the recorded metadata for a single worktree. A worktree has an
+// entry once koh created or adopted it, or found it in the worktree directory
+// when it first took inventory.
+type Worktree struct {
+	// CreatedAt is when koh created the worktree, zero for adopted worktrees
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// AdoptedAt is when a worktree created outside koh was adopted
+	AdoptedAt time.Time `json:"adopted_at,omitzero"`
+	// UsedAt is when koh last switched to the worktree's window
+	UsedAt time.Time `json:"used_at,omitzero"`
+	// Branch is the branch koh last saw checked out in the worktree
+	Branch string `json:"branch,omitempty"`
+	// File is the file the worktree was opened at with 'koh new --file',
+	// e.g. "app/models/user.rb:42"
+	File string `json:"file,omitempty"`
+	// Profile is the .kohconfig profile the worktree was created with, so its
+	// window is recreated with the same setup
+	Profile string `json:"profile,omitempty"`
+
+	// PaneCommands is the history of commands sent to the worktree's panes, oldest first
+	PaneCommands []PaneCommand `json:"pane_commands,omitempty"`
+	// PaneIDs are the tmux pane IDs (e.g. "%7") of the worktree's window,
+	// indexed by pane number, so panes keep their number when the user
+	// rearranges the window. Numbers of closed panes are "".
+	PaneIDs []string `json:"pane_ids,omitempty"`
+
+	// Pinned worktrees are listed first by 'koh list'
+	Pinned bool `json:"pinned,omitempty"`
+	// Note is a free-form reminder attached in 'koh list'
+	Note string `json:"note,omitempty"`
+	// Readonly marks a reference-only worktree (see 'koh readonly')
+	Readonly bool `json:"readonly,omitempty"`
+
+	// Paused is set while the worktree's processes are paused with 'koh pause'
+	Paused *Pause `json:"paused,omitempty"`
+
+	// WIP is the work in progress saved with 'koh wip', oldest first
+	WIP []WIP `json:"wip,omitempty"`
+
+	// Parent is the worktree this one is stacked on (see 'koh stack')
+	Parent string `json:"parent,omitempty"`
+	// ParentHead is the commit of the parent's branch this worktree's branch
+	// was last based on, so a restack replays only the worktree's own commits
+	ParentHead string `json:"parent_head,omitempty"`
+
+	// Base is the branch the worktree's branch targets, e.g. "release/1.2",
+	// when it isn't the default branch (see 'koh base')
+	Base string `json:"base,omitempty"`
+}
+
+// WIP records work in progress 'koh wip' saved, so 'koh unwip' can bring it back
+type WIP struct {
+	At time.Time `json:"at"`
+	// Stash is set when the changes were stashed rather than committed
+	Stash bool `json:"stash,omitempty"`
+	// Commit is the WIP commit, or the commit of the stash entry
+	Commit  string `json:"commit"`
+	Message string `json:"message"`
+}
+
+// Pause records how a worktree's processes were paused, so 'koh resume' can
+// bring them back
+type Pause struct {
+	At time.Time `json:"at"`
+	// Suspend is set when processes were stopped with Ctrl-Z rather than
+	// interrupted with Ctrl-C
+	Suspend bool `json:"suspend,omitempty"`
+	// Panes are the positions of the panes that were paused
+	Panes []int `json:"panes"`
+}
+
+// PaneCommand is a command koh sent to a pane
+type PaneCommand struct {
+	// Pane is the pane's position in the window, counted from 0
+	Pane    int       `json:"pane"`
+	Command string    `json:"command"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Dir returns the directory where state files are stored (see paths.StateDir)
+func Dir() (string, error) {
+	return paths.StateDir()
+}
+
+// filePath returns the state file for the repository with the given common dir
+func filePath(commonDir string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(commonDir))
+	return filepath.Join(dir, "repos", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Load reads the state of the repository with the given common dir.
+// A repository without recorded state yields an empty State.
+func Load(commonDir string) (*State, error) {
+	path, err := filePath(commonDir)
+	if err != nil {
+		return nil, err
+	}
+	return load(path)
+}
+
+// load reads and migrates the state file at path
+func load(path string) (*State, error) {
+	s := &State{Version: CurrentVersion, Worktrees: make(map[string]*Worktree)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	if data, err = migrate(data, migrations); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	if s.Worktrees == nil {
+		s.Worktrees = make(map[string]*Worktree)
+	}
+	return s, nil
+}
+
+// migrate upgrades an encoded state file by running the steps it hasn't had
+// yet, up to version len(steps)+1
+func migrate(data []byte, steps []func(doc map[string]any) error) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	version := 1
+	if v, ok := doc["version"].(float64); ok {
+		version = int(v)
+	}
+	target := len(steps) + 1
+	if version > target {
+		return nil, fmt.Errorf("state was written by a newer version of koh (schema %d, this koh reads up to %d); upgrade koh", version, target)
+	}
+	if version == target {
+		return data, nil
+	}
+
+	for ; version < target; version++ {
+		if err := steps[version-1](doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate state from schema %d: %w", version, err)
+		}
+	}
+	doc["version"] = target
+	return json.Marshal(doc)
+}
+
+// Save writes the state of the repository with the given common dir
+func Save(commonDir string, s *State) error {
+	path, err := filePath(commonDir)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return save(path, s)
+}
+
+// save replaces the state file at path atomically, so readers never see a
+// partly written file
+func save(path string, s *State) error {
+	s.Version = CurrentVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return writeFile(path, data)
+}
+
+// writeFile replaces the file at path with data atomically
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	// State can't be recomputed, so make sure it reached the disk before it
+	// replaces the old file
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	//nolint:gosec // G302: 0644 is standard permission for user files
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
+
+// lock takes an exclusive lock on the state file at path, shared by every
+// koh process, and returns the function that releases it
+func lock(path string) (func(), error) {
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	//nolint:gosec // G304: the lock file lives in the state directory
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock state: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("state is locked by another koh process (%s)", f.Name())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// Update loads the repository's state, applies fn and saves the result,
+// holding the lock throughout so concurrent updates aren't lost
+func Update(commonDir string, fn func(s *State) error) error {
+	path, err := filePath(commonDir)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return save(path, s)
+}
+
+// Worktree returns the recorded metadata for a worktree, creating it if needed
+func (s *State) Worktree(name string) *Worktree {
+	wt, ok := s.Worktrees[name]
+	if !ok {
+		wt = &Worktree{}
+		s.Worktrees[name] = wt
+	}
+	return wt
+}
+
+// RecordPaneCommand adds a command to the worktree's history. Sending the
+// same command to the same pane again moves it to the end instead of
+// duplicating it, and only the most recent commands are kept.
+func (w *Worktree) RecordPaneCommand(pane int, command string, at time.Time) {
+	history := w.PaneCommands[:0]
+	for _, pc := range w.PaneCommands {
+		if pc.Pane != pane || pc.Command != command {
+			history = append(history, pc)
+		}
+	}
+	history = append(history, PaneCommand{Pane: pane, Command: command, SentAt: at})
+
+	if len(history) > maxPaneCommands {
+		history = history[len(history)-maxPaneCommands:]
+	}
+	w.PaneCommands = history
+}
+
+// LastPaneCommands returns the most recent command sent to each pane
+func (w *Worktree) LastPaneCommands() map[int]string {
+	last := make(map[int]string)
+	for _, pc := range w.PaneCommands {
+		last[pc.Pane] = pc.Command
+	}
+	return last
+}
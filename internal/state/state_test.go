@@ -0,0 +1,247 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadMissingStateIsEmpty(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+
+	s, err := Load("/repo/.git")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(s.Worktrees) != 0 {
+		t.Errorf("Expected no worktrees, got %v", s.Worktrees)
+	}
+}
+
+func TestUpdatePersistsState(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	err := Update(commonDir, func(s *State) error {
+		s.Worktree("feature").RecordPaneCommand(1, "npm run dev", time.Now())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	s, err := Load(commonDir)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	history := s.Worktree("feature").PaneCommands
+	if len(history) != 1 || history[0].Command != "npm run dev" || history[0].Pane != 1 {
+		t.Errorf("Expected recorded pane command, got %+v", history)
+	}
+
+	// Other repositories don't share state
+	other, err := Load("/other/.git")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(other.Worktrees) != 0 {
+		t.Errorf("Expected no state for another repository, got %v", other.Worktrees)
+	}
+}
+
+func TestRecordPaneCommand(t *testing.T) {
+	var wt Worktree
+	now := time.Now()
+
+	wt.RecordPaneCommand(1, "npm run dev", now)
+	wt.RecordPaneCommand(2, "npm test", now)
+	wt.RecordPaneCommand(1, "npm run dev", now.Add(time.Minute))
+
+	if len(wt.PaneCommands) != 2 {
+		t.Fatalf("Expected duplicates to be merged, got %+v", wt.PaneCommands)
+	}
+	if last := wt.PaneCommands[1]; last.Command != "npm run dev" || !last.SentAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected re-sent command to move to the end, got %+v", last)
+	}
+
+	for i := 0; i < maxPaneCommands+5; i++ {
+		wt.RecordPaneCommand(0, fmt.Sprintf("cmd %d", i), now)
+	}
+	if len(wt.PaneCommands) != maxPaneCommands {
+		t.Errorf("Expected history to be capped at %d, got %d", maxPaneCommands, len(wt.PaneCommands))
+	}
+}
+
+func TestLastPaneCommands(t *testing.T) {
+	var wt Worktree
+	now := time.Now()
+	wt.RecordPaneCommand(1, "npm run dev", now)
+	wt.RecordPaneCommand(1, "npm run dev -- --port 4000", now)
+	wt.RecordPaneCommand(2, "vim", now)
+
+	last := wt.LastPaneCommands()
+	if last[1] != "npm run dev -- --port 4000" || last[2] != "vim" {
+		t.Errorf("Unexpected last pane commands: %v", last)
+	}
+}
+
+// writeStateFile writes a raw state file for commonDir
+func writeStateFile(t *testing.T, commonDir, data string) string {
+	t.Helper()
+	path, err := filePath(commonDir)
+	if err != nil {
+		t.Fatalf("filePath() failed: %v", err)
+	}
+	//nolint:gosec // G301: Test directory
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create state directory: %v", err)
+	}
+	//nolint:gosec // G306: Test file - 0644 is acceptable for temp test files
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	return path
+}
+
+func TestMigrationsMatchVersion(t *testing.T) {
+	if len(migrations) != CurrentVersion-1 {
+		t.Errorf("Expected %d migrations for schema %d, got %d", CurrentVersion-1, CurrentVersion, len(migrations))
+	}
+}
+
+func TestSaveRecordsVersion(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	if err := Update(commonDir, func(s *State) error { s.Worktree("feature"); return nil }); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	path, _ := filePath(commonDir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read state: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("State is not JSON: %v", err)
+	}
+	if doc["version"] != float64(CurrentVersion) {
+		t.Errorf("Expected version %d, got %v", CurrentVersion, doc["version"])
+	}
+
+	// Only the state file and its lock are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read state directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected no temporary files, got %v", entries)
+	}
+}
+
+func TestLoadVersions(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	// Files from before versioning are version 1
+	writeStateFile(t, commonDir, `{"worktrees": {"feature": {"branch": "feature"}}}`)
+	s, err := Load(commonDir)
+	if err != nil {
+		t.Fatalf("Load() failed for an unversioned file: %v", err)
+	}
+	if s.Version != CurrentVersion || s.Worktree("feature").Branch != "feature" {
+		t.Errorf("Expected the unversioned state to load, got %+v", s)
+	}
+
+	// Files from a newer koh are refused and left alone
+	newer := fmt.Sprintf(`{"version": %d, "worktrees": {}}`, CurrentVersion+1)
+	path := writeStateFile(t, commonDir, newer)
+	if _, err := Load(commonDir); err == nil {
+		t.Error("Expected Load() to refuse state from a newer koh")
+	}
+	if err := Update(commonDir, func(s *State) error { return nil }); err == nil {
+		t.Error("Expected Update() to refuse state from a newer koh")
+	}
+	if data, _ := os.ReadFile(path); string(data) != newer {
+		t.Errorf("Expected state from a newer koh to be left alone, got %s", data)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	steps := []func(doc map[string]any) error{
+		// Version 2 renamed "repos" to "worktrees"
+		func(doc map[string]any) error {
+			doc["worktrees"] = doc["repos"]
+			delete(doc, "repos")
+			return nil
+		},
+		// Version 3 added a fetch time
+		func(doc map[string]any) error {
+			doc["last_fetch"] = "2024-01-01T00:00:00Z"
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "unversioned", data: `{"repos": {"a": {}}}`, want: `{"last_fetch":"2024-01-01T00:00:00Z","version":3,"worktrees":{"a":{}}}`},
+		{name: "version 2", data: `{"version": 2, "worktrees": {}}`, want: `{"last_fetch":"2024-01-01T00:00:00Z","version":3,"worktrees":{}}`},
+		{name: "current", data: `{"version": 3, "worktrees": {}}`, want: `{"version": 3, "worktrees": {}}`},
+		{name: "newer", data: `{"version": 4, "worktrees": {}}`, wantErr: true},
+		{name: "not JSON", data: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrate([]byte(tt.data), steps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateIsSerialized(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	commonDir := "/repo/.git"
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Update(commonDir, func(s *State) error {
+				s.Worktree(fmt.Sprintf("feature-%d", i))
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+
+	s, err := Load(commonDir)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(s.Worktrees) != writers {
+		t.Errorf("Expected all %d concurrent updates to be kept, got %d", writers, len(s.Worktrees))
+	}
+}
@@ -0,0 +1,97 @@
+package styles
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Marker is an icon or label koh shows next to worktrees, with the style it
+// is drawn in and what it means. Worktree views render markers from here and
+// 'koh legend' explains them, so the two can't drift apart.
+type Marker struct {
+	// Name is a stable key, e.g. "dirty"
+	Name string `json:"name"`
+	Icon string `json:"icon,omitempty"`
+	// Text is shown after the icon; markers with variable text such as
+	// pull request numbers show an example
+	Text    string         `json:"text,omitempty"`
+	Meaning string         `json:"meaning"`
+	Style   lipgloss.Style `json:"-"`
+}
+
+// Label returns the icon and text of the marker
+func (m Marker) Label() string {
+	return m.LabelWith(m.Text)
+}
+
+// LabelWith returns the icon of the marker followed by text
+func (m Marker) LabelWith(text string) string {
+	return strings.TrimSpace(m.Icon + " " + text)
+}
+
+// Render renders the marker in its style
+func (m Marker) Render() string {
+	return m.Style.Render(m.Label())
+}
+
+// RenderWith renders the marker's icon followed by text, in its style
+func (m Marker) RenderWith(text string) string {
+	return m.Style.Render(m.LabelWith(text))
+}
+
+// Markers shown next to worktrees
+var (
+	MarkerCurrent   = Marker{Name: "current", Icon: IconCurrent, Meaning: "The worktree you are in", Style: Active}
+	MarkerWorktree  = Marker{Name: "worktree", Icon: IconBullet, Meaning: "A koh worktree", Style: Muted}
+	MarkerMain      = Marker{Name: "main", Icon: IconMain, Meaning: "The main checkout of the repository", Style: Active}
+	MarkerBranch    = Marker{Name: "branch", Icon: IconBranch, Text: "feature", Meaning: "The branch checked out in the worktree", Style: Muted}
+	MarkerClean     = Marker{Name: "clean", Icon: IconCheck, Text: "clean", Meaning: "No uncommitted changes", Style: SuccessMessage}
+	MarkerDirty     = Marker{Name: "dirty", Icon: IconDirty, Text: "dirty", Meaning: "Uncommitted changes, including untracked files", Style: WarningMessage}
+	MarkerTracking  = Marker{Name: "tracking", Text: "↑2 ↓1", Meaning: "Commits ahead of and behind the upstream branch", Style: Muted}
+	MarkerPROpen    = Marker{Name: "pr_open", Text: "#12 open", Meaning: "An open pull request for the branch", Style: Active}
+	MarkerPRMerged  = Marker{Name: "pr_merged", Text: "#12 merged", Meaning: "The branch's pull request was merged", Style: HighlightStyle}
+	MarkerPRClosed  = Marker{Name: "pr_closed", Text: "#12 draft", Meaning: "A draft or closed pull request", Style: Muted}
+	MarkerCIPassing = Marker{Name: "ci_passing", Icon: IconCheck, Text: "CI", Meaning: "CI checks are passing", Style: SuccessMessage}
+	MarkerCIFailing = Marker{Name: "ci_failing", Icon: IconCross, Text: "CI", Meaning: "CI checks are failing", Style: ErrorMessage}
+	MarkerCIPending = Marker{Name: "ci_pending", Icon: IconDirty, Text: "CI", Meaning: "CI checks are still running", Style: WarningMessage}
+	MarkerHere      = Marker{Name: "here", Text: "[current]", Meaning: "The worktree you are in, in 'koh list' and 'koh cleanup --interactive'", Style: Muted}
+	MarkerCheckout  = Marker{Name: "main_checkout", Text: "[main checkout]", Meaning: "The main checkout, listed alongside the worktrees", Style: Muted}
+	MarkerWindow    = Marker{Name: "window_open", Text: "[window open]", Meaning: "The worktree's tmux window is open", Style: Muted}
+	MarkerPaused    = Marker{Name: "paused", Text: "[paused]", Meaning: "Processes were paused with 'koh pause'", Style: Muted}
+	MarkerMerged    = Marker{Name: "merged", Text: "[merged]", Meaning: "The branch is merged into the default branch; 'koh cleanup --merged' removes it", Style: SuccessMessage}
+	MarkerReadonly  = Marker{Name: "readonly", Text: "[read-only]", Meaning: "Marked with 'koh readonly'; start new work from it with 'koh new --stack-on'", Style: WarningMessage}
+	MarkerPinned    = Marker{Name: "pinned", Text: "[pinned]", Meaning: "Pinned from the actions menu of 'koh list'; listed first", Style: Active}
+	MarkerLocked    = Marker{Name: "locked", Text: "[locked]", Meaning: "Locked with 'git worktree lock'; git won't remove or prune it", Style: WarningMessage}
+	MarkerRestack   = Marker{Name: "needs_restack", Text: "[needs restack]", Meaning: "The worktree it's stacked on has moved on; 'koh stack restack' rebases it", Style: WarningMessage}
+	MarkerUnmanaged = Marker{Name: "unmanaged", Text: "[created outside koh]", Meaning: "No koh record, e.g. created with plain git; 'koh import' adopts it", Style: WarningMessage}
+	MarkerOverQuota = Marker{Name: "over_quota", Text: "[over disk quota]", Meaning: "Takes up more space than disk_quota.worktree in .kohconfig; 'koh du' shows what", Style: WarningMessage}
+)
+
+// Legend lists every marker in the order they are explained
+var Legend = []Marker{
+	MarkerCurrent,
+	MarkerWorktree,
+	MarkerMain,
+	MarkerBranch,
+	MarkerClean,
+	MarkerDirty,
+	MarkerTracking,
+	MarkerPROpen,
+	MarkerPRMerged,
+	MarkerPRClosed,
+	MarkerCIPassing,
+	MarkerCIFailing,
+	MarkerCIPending,
+	MarkerHere,
+	MarkerCheckout,
+	MarkerWindow,
+	MarkerPaused,
+	MarkerMerged,
+	MarkerReadonly,
+	MarkerPinned,
+	MarkerLocked,
+	MarkerRestack,
+	MarkerUnmanaged,
+	MarkerOverQuota,
+}
@@ -72,6 +72,11 @@ var (
 			Foreground(Error).
 			Bold(true)
 
+	// Warning message
+	WarningMessage = lipgloss.NewStyle().
+			Foreground(Warning).
+			Bold(true)
+
 	// Subtle/muted text
 	Muted = lipgloss.NewStyle().
 		Foreground(Subtle)
@@ -119,6 +124,8 @@ const (
 	IconConfig  = "⚙"
 	IconBranch  = "⎇"
 	IconTree    = "⚘"
+	IconDirty   = "●"
+	IconMain    = "⌂"
 )
 
 // RenderTitle renders text with the Title style.
@@ -0,0 +1,169 @@
+// Package telemetry keeps opt-in, anonymous usage counters.
+//
+// Nothing is recorded until the user runs 'koh telemetry enable', and nothing
+// is sent anywhere automatically: counters are stored in a single JSON file in
+// the user's state directory that 'koh telemetry show' prints in full, and
+// users who want to share them upload that output themselves. Only the
+// name of each command run and a coarse category of each error are counted;
+// no arguments, paths, worktree names or error messages are stored.
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bshakr/koh/internal/paths"
+)
+
+// Counters is everything telemetry records
+type Counters struct {
+	Enabled   bool      `json:"enabled"`
+	EnabledAt time.Time `json:"enabled_at"`
+	// Commands counts invocations per command, e.g. "new" or "config validate"
+	Commands map[string]int `json:"commands"`
+	// Errors counts failed invocations per error category
+	Errors map[string]int `json:"errors"`
+}
+
+// Path returns the file telemetry counters are stored in
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "telemetry.json"), nil
+}
+
+// Load reads the counters. Missing counters mean telemetry is disabled.
+func Load() (*Counters, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Counters{}
+	//nolint:gosec // G304: path is inside koh's state directory
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry: %w", err)
+	}
+	return c, nil
+}
+
+// Enable starts recording. Counters recorded before are kept.
+func Enable(now time.Time) error {
+	c, err := Load()
+	if err != nil {
+		return err
+	}
+	if c.Enabled {
+		return nil
+	}
+	c.Enabled = true
+	c.EnabledAt = now
+	return save(c)
+}
+
+// Disable stops recording and deletes everything recorded
+func Disable() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete telemetry: %w", err)
+	}
+	return nil
+}
+
+// Record counts an invocation of command and, when err is non-nil, its
+// error category. It does nothing unless telemetry is enabled, and never
+// fails the command: errors are ignored.
+func Record(command string, err error) {
+	c, loadErr := Load()
+	if loadErr != nil || !c.Enabled {
+		return
+	}
+
+	if c.Commands == nil {
+		c.Commands = make(map[string]int)
+	}
+	c.Commands[command]++
+	if err != nil {
+		if c.Errors == nil {
+			c.Errors = make(map[string]int)
+		}
+		c.Errors[Category(err)]++
+	}
+	_ = save(c)
+}
+
+// Category maps an error to a coarse category, so no error text is stored
+func Category(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return "cancelled"
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "cancelled"):
+		return "cancelled"
+	case strings.Contains(msg, "unknown flag"), strings.Contains(msg, "unknown command"),
+		strings.Contains(msg, "accepts"), strings.Contains(msg, "requires"),
+		strings.Contains(msg, "invalid argument"), strings.Contains(msg, "if any flags in the group"):
+		return "usage"
+	case strings.Contains(msg, "not in a git repository"):
+		return "not_in_repository"
+	case strings.Contains(msg, "not in a tmux session"):
+		return "not_in_tmux"
+	case strings.Contains(msg, "doctor found"):
+		return "doctor"
+	case strings.Contains(msg, "ci checks"):
+		return "ci"
+	case strings.Contains(msg, "exited non-zero"):
+		return "exec"
+	case strings.Contains(msg, "config"):
+		return "config"
+	case strings.Contains(msg, "tmux"):
+		return "tmux"
+	case strings.Contains(msg, "worktree"), strings.Contains(msg, "branch"), strings.Contains(msg, "git"):
+		return "git"
+	}
+	return "other"
+}
+
+// save writes the counters
+func save(c *Counters) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	//nolint:gosec // G301: 0755 is standard permission for user directories
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry: %w", err)
+	}
+
+	//nolint:gosec // G306: 0644 is standard permission for user files
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write telemetry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecordOnlyWhenEnabled(t *testing.T) {
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+
+	Record("new", nil)
+	path, err := Path()
+	if err != nil {
+		t.Fatalf("Path() error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected nothing to be recorded while disabled, got %v", err)
+	}
+
+	if err := Enable(time.Now()); err != nil {
+		t.Fatalf("Enable() error: %v", err)
+	}
+	Record("new", nil)
+	Record("new", errors.New("not in a tmux session"))
+	Record("list", nil)
+
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if c.Commands["new"] != 2 || c.Commands["list"] != 1 {
+		t.Errorf("Unexpected command counts: %v", c.Commands)
+	}
+	if c.Errors["not_in_tmux"] != 1 || len(c.Errors) != 1 {
+		t.Errorf("Unexpected error counts: %v", c.Errors)
+	}
+
+	if err := Disable(); err != nil {
+		t.Fatalf("Disable() error: %v", err)
+	}
+	c, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if c.Enabled || len(c.Commands) != 0 {
+		t.Errorf("Expected Disable to delete all counters, got %+v", c)
+	}
+}
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("wrapped: %w", context.Canceled), "cancelled"},
+		{errors.New("operation cancelled"), "cancelled"},
+		{errors.New("unknown flag: --nope"), "usage"},
+		{errors.New("accepts 1 arg(s), received 0"), "usage"},
+		{errors.New("not in a git repository"), "not_in_repository"},
+		{errors.New("not in a tmux session"), "not_in_tmux"},
+		{errors.New("doctor found 2 problem(s)"), "doctor"},
+		{errors.New("ci checks are not safe to merge"), "ci"},
+		{errors.New("command exited non-zero in 3 worktree(s)"), "exec"},
+		{errors.New("failed to load config: bad json"), "config"},
+		{errors.New("failed to create tmux window: exit status 1"), "tmux"},
+		{errors.New("failed to create worktree: exit status 128"), "git"},
+		{errors.New("something else"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := Category(tt.err); got != tt.want {
+			t.Errorf("Expected Category(%q) = %q, got %q", tt.err, tt.want, got)
+		}
+	}
+}
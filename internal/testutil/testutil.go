@@ -0,0 +1,82 @@
+// Package testutil provides throwaway git repositories and tmux servers for
+// tests.
+//
+// Tests that used to depend on the machine they ran on (the repository the
+// tests were started in, or a tmux session around `go test`) get an
+// isolated environment of their own instead, so they run the same on a
+// laptop and on CI and never touch the user's real repositories, windows
+// or koh state.
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// NewRepo creates a git repository with one empty commit on main in a
+// temporary directory, changes into it and returns its path.
+//
+// The user's git configuration is ignored and commits are made as
+// koh <koh@example.com>. koh's cache, state and global config directories
+// point into temporary directories for the rest of the test.
+func NewRepo(t testing.TB) string {
+	t.Helper()
+	Isolate(t)
+
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	t.Chdir(repo)
+
+	Git(t, repo, "init", "-q", "-b", "main")
+	Git(t, repo, "commit", "-q", "--allow-empty", "-m", "init")
+	return repo
+}
+
+// Isolate points git's global configuration and koh's cache, state and
+// global config directories at empty temporary ones for the rest of the test
+func Isolate(t testing.TB) {
+	t.Helper()
+	globalConfig := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(globalConfig, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "koh")
+	t.Setenv("GIT_AUTHOR_EMAIL", "koh@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "koh")
+	t.Setenv("GIT_COMMITTER_EMAIL", "koh@example.com")
+
+	t.Setenv("KOH_CACHE_DIR", t.TempDir())
+	t.Setenv("KOH_STATE_DIR", t.TempDir())
+	t.Setenv("KOH_CONFIG_DIR", t.TempDir())
+}
+
+// Git runs git in dir and returns its output without surrounding space,
+// failing the test when git fails
+func Git(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// WriteFile writes content to name inside dir, creating the directories
+// leading to it
+func WriteFile(t testing.TB, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,62 @@
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRepo(t *testing.T) {
+	repo := NewRepo(t)
+
+	if cwd, _ := os.Getwd(); cwd != repo {
+		t.Errorf("Expected to be in %s, got %s", repo, cwd)
+	}
+	if branch := Git(t, repo, "branch", "--show-current"); branch != "main" {
+		t.Errorf("Expected branch main, got %q", branch)
+	}
+	if author := Git(t, repo, "log", "-1", "--format=%an <%ae>"); author != "koh <koh@example.com>" {
+		t.Errorf("Expected commits by koh, got %q", author)
+	}
+	for _, key := range []string{"KOH_CACHE_DIR", "KOH_STATE_DIR", "KOH_CONFIG_DIR"} {
+		if os.Getenv(key) == "" {
+			t.Errorf("Expected %s to be set", key)
+		}
+	}
+
+	WriteFile(t, repo, filepath.Join("a", "b.txt"), "b")
+	if content, err := os.ReadFile(filepath.Join(repo, "a", "b.txt")); err != nil || string(content) != "b" {
+		t.Errorf("Expected a/b.txt to be written, got %q (%v)", content, err)
+	}
+}
+
+func TestNewTmux(t *testing.T) {
+	tm := NewTmux(t)
+
+	// Plain tmux commands reach the test server through TMUX
+	output, err := exec.Command("tmux", "display-message", "-p", "#{session_name}").Output()
+	if err != nil {
+		t.Fatalf("tmux failed: %v", err)
+	}
+	if session := strings.TrimSpace(string(output)); session != tm.Session {
+		t.Errorf("Expected session %s, got %s", tm.Session, session)
+	}
+
+	if err := exec.Command("tmux", "new-window", "-d", "-n", "other").Run(); err != nil {
+		t.Fatalf("tmux new-window failed: %v", err)
+	}
+	if windows := tm.Windows(t); len(windows) != 2 || windows[1] != "other" {
+		t.Errorf("Expected a second window named other, got %v", windows)
+	}
+	if current := tm.CurrentWindow(t); current == "other" {
+		t.Error("Expected the first window to stay selected")
+	}
+	if panes := tm.PaneCount(t, "other"); panes != 1 {
+		t.Errorf("Expected 1 pane, got %d", panes)
+	}
+
+	tm.Run(t, "send-keys", "-t", "=koh-test:=other", "echo koh-$((40+2))", "C-m")
+	tm.WaitForText(t, "=koh-test:=other", "koh-42")
+}
@@ -0,0 +1,135 @@
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tmuxName is the socket name (as in tmux -L) and the session name of the
+// test servers
+const tmuxName = "koh-test"
+
+// waitTimeout is how long WaitForText waits for text to show up in a pane
+const waitTimeout = 10 * time.Second
+
+// Tmux is a tmux server of a single test, with one detached session that
+// koh takes as the session it runs in
+type Tmux struct {
+	// Socket is the path of the server's socket
+	Socket string
+	// Session is the name of the session
+	Session string
+}
+
+// NewTmux starts a tmux server with a detached session and points TMUX at
+// it for the rest of the test, so koh's tmux commands act on that session as
+// if koh ran in one of its panes. The server is killed when the test ends,
+// and the test is skipped when tmux isn't installed.
+//
+// Each server has a socket directory of its own, so tests in packages that
+// run in parallel never share a server, and neither the user's tmux.conf nor
+// their shell's startup files are read: panes run /bin/sh.
+func NewTmux(t testing.TB) *Tmux {
+	t.Helper()
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed, skipping test")
+	}
+
+	// Socket paths are limited to about 100 bytes, which t.TempDir() can
+	// exceed on macOS
+	dir, err := os.MkdirTemp("", "koh-tmux-")
+	if err != nil {
+		t.Fatalf("Failed to create the tmux socket directory: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "TMUX=") && !strings.HasPrefix(kv, "TMUX_PANE=") {
+			env = append(env, kv)
+		}
+	}
+	start := exec.Command("tmux", "-L", tmuxName, "-f", os.DevNull,
+		"new-session", "-d", "-s", tmuxName, "-x", "200", "-y", "50")
+	start.Env = append(env, "TMUX_TMPDIR="+dir, "SHELL=/bin/sh")
+	if output, err := start.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to start tmux: %v\n%s", err, output)
+	}
+
+	tm := &Tmux{Session: tmuxName}
+	query := exec.Command("tmux", "-L", tmuxName, "display-message", "-p", "-t", tmuxName+":",
+		"#{pid} #{session_id} #{pane_id} #{socket_path}")
+	query.Env = start.Env
+	output, err := query.Output()
+	if err != nil {
+		t.Fatalf("Failed to query tmux: %v", err)
+	}
+	// The socket path goes last as the only field that may contain spaces
+	fields := strings.SplitN(strings.TrimSpace(string(output)), " ", 4)
+	if len(fields) != 4 {
+		t.Fatalf("Unexpected tmux output: %q", output)
+	}
+	tm.Socket = fields[3]
+	t.Cleanup(func() {
+		_ = exec.Command("tmux", "-S", tm.Socket, "kill-server").Run()
+	})
+
+	// New windows get this size while no client is attached
+	tm.Run(t, "set-option", "-g", "default-size", "200x50")
+
+	t.Setenv("TMUX", strings.Join([]string{tm.Socket, fields[0], strings.TrimPrefix(fields[1], "$")}, ","))
+	t.Setenv("TMUX_PANE", fields[2])
+	return tm
+}
+
+// Run runs a tmux command on the server and returns its output without the
+// trailing newline, failing the test when tmux fails
+func (tm *Tmux) Run(t testing.TB, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("tmux", append([]string{"-S", tm.Socket}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("tmux %v failed: %v\n%s", args, err, output)
+	}
+	return strings.TrimRight(string(output), "\n")
+}
+
+// Windows returns the names of the session's windows, in order
+func (tm *Tmux) Windows(t testing.TB) []string {
+	t.Helper()
+	return strings.Split(tm.Run(t, "list-windows", "-t", "="+tm.Session, "-F", "#{window_name}"), "\n")
+}
+
+// CurrentWindow returns the name of the session's selected window
+func (tm *Tmux) CurrentWindow(t testing.TB) string {
+	t.Helper()
+	return tm.Run(t, "display-message", "-p", "-t", "="+tm.Session+":", "#{window_name}")
+}
+
+// PaneCount returns the number of panes in a window, by name
+func (tm *Tmux) PaneCount(t testing.TB, window string) int {
+	t.Helper()
+	return len(strings.Split(tm.Run(t, "list-panes", "-t", "="+tm.Session+":="+window, "-F", "#{pane_id}"), "\n"))
+}
+
+// WaitForText waits until a pane shows text, such as the output of the
+// command typed into it, and fails the test when it doesn't within ten
+// seconds. target is any tmux pane target, such as a pane ID.
+func (tm *Tmux) WaitForText(t testing.TB, target, text string) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		screen := tm.Run(t, "capture-pane", "-p", "-t", target)
+		if strings.Contains(screen, text) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected pane %s to show %q, got:\n%s", target, text, screen)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,131 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetachedSession is the background tmux session windows are moved to by
+// DetachWindowWithContext. It is created on demand and disappears when its
+// last window is closed or moved back.
+const DetachedSession = "koh-detached"
+
+// WindowActivity is when a koh window last showed any output, which includes
+// the echo of anything typed into it
+type WindowActivity struct {
+	Worktree string
+	Activity time.Time
+	// Active is set for the current window of the session
+	Active bool
+}
+
+// WindowActivitiesWithContext returns the activity of the windows of a
+// repository in the current tmux session
+func WindowActivitiesWithContext(ctx context.Context, repoName string) ([]WindowActivity, error) {
+	cmd := exec.CommandContext(ctx, "tmux", "list-windows", "-F", windowNameFormat+"\t#{window_activity}\t#{window_active}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tmux windows: %w", err)
+	}
+	return parseWindowActivities(string(output), repoName), nil
+}
+
+// parseWindowActivities parses "name<TAB>activity<TAB>active" lines from
+// list-windows, keeping the windows of repoName
+func parseWindowActivities(output, repoName string) []WindowActivity {
+	var windows []WindowActivity
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		worktree, ok := strings.CutPrefix(fields[0], repoName+"|")
+		if !ok || strings.Contains(worktree, "|") {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		windows = append(windows, WindowActivity{Worktree: worktree, Activity: time.Unix(seconds, 0), Active: fields[2] == "1"})
+	}
+	return windows
+}
+
+// DetachWindowWithContext moves the window named windowName (see
+// WindowName) out of the current session into DetachedSession, with its
+// processes still running. The full name keeps a worktree of the same name
+// in another repository in place.
+func DetachWindowWithContext(ctx context.Context, windowName string) error {
+	windowID, err := findWindowByName(ctx, windowName)
+	if err != nil {
+		return err
+	}
+	if windowID == "" {
+		return fmt.Errorf("no tmux window found: %s", windowName)
+	}
+
+	// A new session comes with a shell window of its own, which is closed
+	// once the worktree's window has joined it
+	placeholder := ""
+	if err := runTmuxCmdWithContext(ctx, "has-session", "-t", "="+DetachedSession); err != nil {
+		cmd := exec.CommandContext(ctx, "tmux", "new-session", "-d", "-s", DetachedSession, "-P", "-F", "#{window_id}")
+		output, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to create the %s session: %w", DetachedSession, err)
+		}
+		placeholder = strings.TrimSpace(string(output))
+	}
+
+	if err := runTmuxCmdWithContext(ctx, "move-window", "-d", "-s", windowID, "-t", DetachedSession+":"); err != nil {
+		return fmt.Errorf("failed to move window %s: %w", windowName, err)
+	}
+	if placeholder != "" {
+		_ = runTmuxCmdWithContext(ctx, "kill-window", "-t", placeholder)
+	}
+	return nil
+}
+
+// findDetachedWindow returns the ID of the window named windowName in
+// DetachedSession, or "" when there is none
+func findDetachedWindow(ctx context.Context, windowName string) string {
+	cmd := exec.CommandContext(ctx, "tmux", "list-windows", "-t", "="+DetachedSession, "-F", "#{window_id}\t"+windowNameFormat)
+	output, err := cmd.Output()
+	if err != nil {
+		// The session doesn't exist while nothing is detached
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		id, name, ok := strings.Cut(line, "\t")
+		if ok && name == windowName {
+			return id
+		}
+	}
+	return ""
+}
+
+// ReattachWindowWithContext moves the window named windowName (see
+// WindowName) back from DetachedSession into the current session without
+// selecting it, and reports whether it was detached
+func ReattachWindowWithContext(ctx context.Context, windowName string) (bool, error) {
+	windowID := findDetachedWindow(ctx, windowName)
+	if windowID == "" {
+		return false, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "tmux", "display-message", "-p", "#{session_id}")
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the current tmux session: %w", err)
+	}
+	sessionID := strings.TrimSpace(string(output))
+
+	if err := runTmuxCmdWithContext(ctx, "move-window", "-d", "-s", windowID, "-t", sessionID+":"); err != nil {
+		return false, fmt.Errorf("failed to move window %s back: %w", windowName, err)
+	}
+	return true, nil
+}
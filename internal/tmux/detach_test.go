@@ -0,0 +1,82 @@
+package tmux
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestParseWindowActivities(t *testing.T) {
+	output := "repo|one\t1700000000\t1\nrepo|two\t1700000100\t0\nother|three\t1700000200\t0\nrepo\t1700000300\t0\nrepo|bad\tsoon\t0\n"
+	want := []WindowActivity{
+		{Worktree: "one", Activity: time.Unix(1700000000, 0), Active: true},
+		{Worktree: "two", Activity: time.Unix(1700000100, 0)},
+	}
+	if got := parseWindowActivities(output, "repo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestDetachWindow(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	ctx := context.Background()
+	cfg := &config.Config{}
+	for _, name := range []string{"kept", "idle"} {
+		if err := CreateBackgroundSessionWithContext(ctx, "test-repo", name, "/tmp", cfg); err != nil {
+			t.Fatalf("CreateBackgroundSessionWithContext() failed: %v", err)
+		}
+	}
+
+	// The same worktree name in another repository stays put
+	if err := CreateBackgroundSessionWithContext(ctx, "other-repo", "idle", "/tmp", cfg); err != nil {
+		t.Fatalf("CreateBackgroundSessionWithContext() failed: %v", err)
+	}
+
+	if err := DetachWindowWithContext(ctx, WindowName("test-repo", "idle")); err != nil {
+		t.Fatalf("DetachWindowWithContext() failed: %v", err)
+	}
+	if windows := tm.Windows(t); !slices.Contains(windows, "other-repo|idle") {
+		t.Errorf("Expected the other repository's window to stay, got %v", windows)
+	}
+	if windows := tm.Windows(t); slices.Contains(windows, "test-repo|idle") || !slices.Contains(windows, "test-repo|kept") {
+		t.Errorf("Expected only the idle window to leave the session, got %v", windows)
+	}
+	detached := tm.Run(t, "list-windows", "-t", DetachedSession, "-F", "#{window_name}")
+	if detached != "test-repo|idle" {
+		t.Errorf("Expected the idle window alone in %s, got %q", DetachedSession, detached)
+	}
+
+	back, err := ReattachWindowWithContext(ctx, "test-repo|idle")
+	if err != nil || !back {
+		t.Fatalf("Expected the window to be reattached, got %v (%v)", back, err)
+	}
+	if windows := tm.Windows(t); !slices.Contains(windows, "test-repo|idle") {
+		t.Errorf("Expected the idle window back in the session, got %v", windows)
+	}
+	if back, err := ReattachWindowWithContext(ctx, "test-repo|kept"); err != nil || back {
+		t.Errorf("Expected nothing to reattach, got %v (%v)", back, err)
+	}
+}
+
+func TestCloseDetachedWindow(t *testing.T) {
+	testutil.NewTmux(t)
+	ctx := context.Background()
+	if err := CreateBackgroundSessionWithContext(ctx, "test-repo", "idle", "/tmp", &config.Config{}); err != nil {
+		t.Fatalf("CreateBackgroundSessionWithContext() failed: %v", err)
+	}
+	if err := DetachWindowWithContext(ctx, WindowName("test-repo", "idle")); err != nil {
+		t.Fatalf("DetachWindowWithContext() failed: %v", err)
+	}
+
+	if err := CloseWindow(WindowName("test-repo", "idle"), "idle"); err != nil {
+		t.Fatalf("CloseWindow() failed: %v", err)
+	}
+	if findDetachedWindow(ctx, "test-repo|idle") != "" {
+		t.Error("Expected the detached window to be closed")
+	}
+}
@@ -0,0 +1,75 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// RecordedPaneIDs returns the IDs (e.g. "%7") of the panes koh created for a
+// worktree's window, indexed by pane number, or nil when none were recorded.
+// koh sets it to read its state store; while it is nil, panes are numbered
+// by their position in the window.
+var RecordedPaneIDs func(worktreeName string) []string
+
+// recordedPaneIDs calls RecordedPaneIDs when it is set
+func recordedPaneIDs(worktreeName string) []string {
+	if RecordedPaneIDs == nil {
+		return nil
+	}
+	return RecordedPaneIDs(worktreeName)
+}
+
+// PaneIDsWithContext returns the pane IDs of a worktree's window, indexed by
+// pane number. Panes koh recorded keep their number wherever they were moved
+// to, so a command sent to "pane 1" reaches the pane that was created as pane
+// 1 even after the user rearranged the window. Other panes, such as ones
+// split off by hand, are numbered after them in position order. A recorded
+// pane that was closed leaves its number empty ("").
+func PaneIDsWithContext(ctx context.Context, worktreeName string) ([]string, error) {
+	windowID, _, err := findWindowByWorktree(ctx, worktreeName)
+	if err != nil {
+		return nil, err
+	}
+	if windowID == "" {
+		return nil, fmt.Errorf("no tmux window found for worktree: %s", worktreeName)
+	}
+
+	live, err := getPanesForWindow(ctx, windowID)
+	if err != nil {
+		return nil, err
+	}
+	return numberPanes(recordedPaneIDs(worktreeName), live), nil
+}
+
+// numberPanes numbers the live panes of a window, given in position order,
+// by the recorded IDs (see PaneIDsWithContext). Without recorded IDs the
+// numbers are the positions.
+func numberPanes(recorded, live []string) []string {
+	isLive := make(map[string]bool, len(live))
+	for _, id := range live {
+		isLive[id] = true
+	}
+	// IDs recorded for an earlier window of the worktree mean nothing now
+	if !slices.ContainsFunc(recorded, func(id string) bool { return isLive[id] }) {
+		return live
+	}
+
+	numbered := make([]string, 0, len(live))
+	taken := map[string]bool{}
+	for _, id := range recorded {
+		if id == "" || !isLive[id] || taken[id] {
+			numbered = append(numbered, "")
+			continue
+		}
+		numbered = append(numbered, id)
+		taken[id] = true
+	}
+
+	for _, id := range live {
+		if !taken[id] {
+			numbered = append(numbered, id)
+		}
+	}
+	return numbered
+}
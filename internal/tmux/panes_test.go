@@ -0,0 +1,56 @@
+package tmux
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestNumberPanes(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded []string
+		live     []string
+		want     []string
+	}{
+		{name: "nothing recorded", live: []string{"%1", "%2"}, want: []string{"%1", "%2"}},
+		{name: "unchanged", recorded: []string{"%1", "%2", "%3"}, live: []string{"%1", "%2", "%3"}, want: []string{"%1", "%2", "%3"}},
+		{name: "swapped", recorded: []string{"%1", "%2", "%3"}, live: []string{"%1", "%3", "%2"}, want: []string{"%1", "%2", "%3"}},
+		{name: "split by hand", recorded: []string{"%1", "%2"}, live: []string{"%1", "%9", "%2"}, want: []string{"%1", "%2", "%9"}},
+		{name: "closed", recorded: []string{"%1", "%2", "%3"}, live: []string{"%1", "%3", "%9"}, want: []string{"%1", "", "%3", "%9"}},
+		{name: "earlier window", recorded: []string{"%1", "%2"}, live: []string{"%5", "%6"}, want: []string{"%5", "%6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numberPanes(tt.recorded, tt.live); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSendToPaneAfterSwap(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	ctx := context.Background()
+	cfg := &config.Config{PaneCommands: config.PlainPaneCommands([]string{"true", "true"})}
+	if err := CreateSessionWithContext(ctx, "test-repo", "swapped", "/tmp", cfg); err != nil {
+		t.Fatalf("CreateSessionWithContext() failed: %v", err)
+	}
+	ids, err := PaneIDsWithContext(ctx, "swapped")
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("Expected 3 panes, got %v (%v)", ids, err)
+	}
+	original := RecordedPaneIDs
+	RecordedPaneIDs = func(string) []string { return ids }
+	t.Cleanup(func() { RecordedPaneIDs = original })
+
+	tm.Run(t, "swap-pane", "-s", ids[1], "-t", ids[2])
+	if err := SendToPaneWithContext(ctx, "swapped", 1, "echo koh-pane-one"); err != nil {
+		t.Fatalf("SendToPaneWithContext() failed: %v", err)
+	}
+	tm.WaitForText(t, ids[1], "koh-pane-one")
+}
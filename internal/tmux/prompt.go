@@ -0,0 +1,56 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/bshakr/koh/internal/shell"
+)
+
+// CurrentPanePathWithContext returns the directory the shell of the current
+// pane is in
+func CurrentPanePathWithContext(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "tmux", "display-message", "-p", "#{pane_current_path}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current pane's directory: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// PanePathWithContext returns the directory the shell of the pane with the
+// given ID (e.g. "%7") is in
+func PanePathWithContext(ctx context.Context, paneID string) (string, error) {
+	cmd := exec.CommandContext(ctx, "tmux", "display-message", "-p", "-t", paneID, "#{pane_current_path}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the directory of pane %s: %w", paneID, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CommandPromptWithContext opens the tmux command prompt with the given
+// prompt text. When the user answers, tmux runs template with %% replaced by
+// the answer, and %%% by the answer escaped for use in double quotes.
+func CommandPromptWithContext(ctx context.Context, prompt, template string) error {
+	if err := runTmuxCmdWithContext(ctx, "command-prompt", "-p", prompt, template); err != nil {
+		return fmt.Errorf("failed to open the tmux prompt: %w", err)
+	}
+	return nil
+}
+
+// DisplayMessageWithContext shows a message in the status line of the
+// current client
+func DisplayMessageWithContext(ctx context.Context, message string) error {
+	// display-message expands formats, so # is escaped as ##
+	return runTmuxCmdWithContext(ctx, "display-message", strings.ReplaceAll(message, "#", "##"))
+}
+
+// QuoteShellCommand quotes s as a single word of a shell command that is in
+// turn inside a double-quoted tmux argument, such as the command of a
+// run-shell in a command-prompt template
+func QuoteShellCommand(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "#", "##").Replace(shell.Quote(s))
+}
@@ -0,0 +1,32 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bshakr/koh/internal/testutil"
+)
+
+func TestQuoteShellCommand(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	words := []string{
+		"/usr/local/bin/koh",
+		"/Users/me/My Projects/app",
+		`it's "quoted" $HOME \n #{pane_id}`,
+	}
+
+	for _, word := range words {
+		// tmux parses the double-quoted argument, then sh the command
+		dir := t.TempDir()
+		file, out := filepath.Join(dir, "command.conf"), filepath.Join(dir, "out")
+		command := `run-shell "printf '%s' ` + QuoteShellCommand(word) + ` > ` + QuoteShellCommand(out) + `"` + "\n"
+		if err := os.WriteFile(file, []byte(command), 0600); err != nil {
+			t.Fatal(err)
+		}
+		tm.Run(t, "source-file", file)
+		if got, err := os.ReadFile(out); err != nil || string(got) != word {
+			t.Errorf("Expected %q to come through as it is, got %q (%v)", word, got, err)
+		}
+	}
+}
@@ -37,15 +37,15 @@ package tmux
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bshakr/koh/internal/config"
-	"github.com/bshakr/koh/internal/git"
+	"github.com/bshakr/koh/internal/shell"
 )
 
 // IsInTmux checks if the current session is running inside tmux
@@ -53,97 +53,98 @@ func IsInTmux() bool {
 	return os.Getenv("TMUX") != ""
 }
 
-// ensureSetupScript checks if the setup script exists in the worktree.
-// If not, it looks for it in the main repo root and copies it to the worktree.
-// Returns an error if the script cannot be found or copied.
-func ensureSetupScript(worktreePath, setupScript string) error {
-	// If setup script is empty, nothing to do
-	if setupScript == "" {
-		return nil
-	}
-
-	// Check if the setup script path is absolute
-	var scriptPath string
-	if filepath.IsAbs(setupScript) {
-		scriptPath = setupScript
-	} else {
-		scriptPath = filepath.Join(worktreePath, setupScript)
-	}
-
-	// Check if the script exists in the worktree
-	if _, err := os.Stat(scriptPath); err == nil {
-		// Script exists in worktree, nothing to do
-		return nil
-	}
+// WindowName returns the tmux window name koh uses for a worktree
+func WindowName(repoName, worktreeName string) string {
+	return fmt.Sprintf("%s|%s", repoName, worktreeName)
+}
 
-	// Script doesn't exist in worktree, try to copy from main repo
-	// Get the main repo root
-	mainRepoRoot, err := git.GetMainRepoRoot()
-	if err != nil {
-		return fmt.Errorf("failed to get main repo root: %w", err)
-	}
+// PaneCommand is a command sent to a pane of a worktree window.
+// Pane is the pane's position in the window, counted from 0 regardless of
+// tmux's pane-base-index.
+type PaneCommand struct {
+	Pane    int
+	Command string
+}
 
-	// Check if the script exists in the main repo root
-	mainRepoScriptPath := filepath.Join(mainRepoRoot, setupScript)
-	if _, err := os.Stat(mainRepoScriptPath); os.IsNotExist(err) {
-		// Script doesn't exist in main repo either
-		return fmt.Errorf("setup script not found in worktree or main repo: %s", setupScript)
+// PaneCommands returns the commands CreateSession sends for a config.
+// The mapping is:
+// - cfg.SetupScript -> pane 0 (when set)
+// - cfg.PaneCommands[n] -> pane n+1 (empty commands leave the pane at a prompt)
+//
+// Pane commands with a delay or retries are wrapped to wait and retry.
+func PaneCommands(cfg *config.Config) []PaneCommand {
+	var commands []PaneCommand
+	if cfg.SetupScript != "" {
+		commands = append(commands, PaneCommand{Pane: 0, Command: cfg.SetupScript})
 	}
-
-	// Copy the script from main repo to worktree
-	if err := copyFile(mainRepoScriptPath, scriptPath); err != nil {
-		return fmt.Errorf("failed to copy setup script from main repo: %w", err)
+	for i, pane := range cfg.PaneCommands {
+		if pane.Command != "" {
+			commands = append(commands, PaneCommand{Pane: i + 1, Command: paneCommandLine(pane)})
+		}
 	}
-
-	return nil
+	return commands
 }
 
-// copyFile copies a file from src to dst
-func copyFile(src, dst string) error {
-	// Open source file
-	//nolint:gosec // G304: Opening user-specified setup script is expected
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+// defaultRetryWait is how long a failed pane command waits before its next
+// attempt when it has no delay
+const defaultRetryWait = time.Second
+
+// paneCommandLine returns the line typed into a pane for a pane command. A
+// delay becomes a sleep before the command. Retries run the command in a
+// POSIX sh loop, so they work whatever the user's shell, waiting the delay
+// between attempts.
+func paneCommandLine(pane config.PaneCommand) string {
+	// An invalid delay is reported by lint and ignored here
+	delay, _ := pane.DelayDuration()
+
+	if pane.Retries <= 0 {
+		if delay == 0 {
+			return pane.Command
+		}
+		return "sleep " + sleepSeconds(delay) + " && " + pane.Command
 	}
-	defer func() {
-		_ = sourceFile.Close() // Ignore error in defer
-	}()
 
-	// Get source file info to preserve permissions
-	sourceInfo, err := sourceFile.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat source file: %w", err)
+	wait := delay
+	if wait == 0 {
+		wait = defaultRetryWait
 	}
-
-	// Create destination directory if it doesn't exist
-	dstDir := filepath.Dir(dst)
-	//nolint:gosec // G301: 0755 is standard permission for directories
-	if err := os.MkdirAll(dstDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
+	script := fmt.Sprintf(`n=0; until %s; do n=$((n+1)); [ "$n" -gt %d ] && exit 1; echo "koh: retrying ($n/%d)"; sleep %s; done`,
+		pane.Command, pane.Retries, pane.Retries, sleepSeconds(wait))
+	if delay > 0 {
+		script = "sleep " + sleepSeconds(delay) + "; " + script
 	}
+	return "sh -c " + shell.Quote(script)
+}
 
-	// Create destination file
-	//nolint:gosec // G304: Creating file in validated worktree path is expected
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer func() {
-		_ = destFile.Close() // Ignore error in defer
-	}()
+// sleepSeconds formats d as an argument to sleep(1)
+func sleepSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
 
-	// Copy the file content
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
-	}
+// setupChannel returns the tmux wait-for channel the setup script of a window
+// signals for the pane at position pane
+func setupChannel(windowID string, pane int) string {
+	return fmt.Sprintf("koh-%s-%d", windowID, pane)
+}
 
-	// Preserve file permissions
-	if err := os.Chmod(dst, sourceInfo.Mode()); err != nil {
-		return fmt.Errorf("failed to set file permissions: %w", err)
+// sequencedPaneCommands returns the commands to type into a new window's
+// panes. With wait_for_setup, the setup script signals a tmux wait-for channel
+// per pane when it finishes (whether or not it succeeds) and each pane command
+// waits on its channel first. A signal sent before anyone waits is kept for a
+// single waiter only, hence one channel per pane.
+func sequencedPaneCommands(cfg *config.Config, windowID string) []PaneCommand {
+	commands := PaneCommands(cfg)
+	if !cfg.WaitForSetup || cfg.SetupScript == "" || len(commands) < 2 {
+		return commands
 	}
 
-	return nil
+	for i := range commands[1:] {
+		pc := &commands[i+1]
+		channel := setupChannel(windowID, pc.Pane)
+		commands[0].Command += "; tmux wait-for -S " + channel
+		pc.Command = "tmux wait-for " + channel + " && " + pc.Command
+	}
+	return commands
 }
 
 // CreateSession creates a new tmux window with dynamically created panes based on the provided config
@@ -151,133 +152,313 @@ func CreateSession(repoName, worktreeName, worktreePath string, cfg *config.Conf
 	return CreateSessionWithContext(context.Background(), repoName, worktreeName, worktreePath, cfg)
 }
 
-// CreateSessionWithContext creates a new tmux window with dynamically created panes based on config
-func CreateSessionWithContext(ctx context.Context, repoName, worktreeName, worktreePath string, cfg *config.Config) error {
+// CreateSessionWithContext creates a new tmux window with dynamically created panes based on config.
+// env holds "KEY=value" entries set in the environment of every pane.
+func CreateSessionWithContext(ctx context.Context, repoName, worktreeName, worktreePath string, cfg *config.Config, env ...string) error {
+	return createSession(ctx, repoName, worktreeName, worktreePath, cfg, false, env)
+}
+
+// CreateBackgroundSessionWithContext creates the window like
+// CreateSessionWithContext, but leaves the current window selected
+func CreateBackgroundSessionWithContext(ctx context.Context, repoName, worktreeName, worktreePath string, cfg *config.Config, env ...string) error {
+	return createSession(ctx, repoName, worktreeName, worktreePath, cfg, true, env)
+}
+
+// createSession creates a worktree window, selecting it unless background is set
+func createSession(ctx context.Context, repoName, worktreeName, worktreePath string, cfg *config.Config, background bool, env []string) error {
 	if !IsInTmux() {
 		return fmt.Errorf("not in a tmux session")
 	}
 
 	// Ensure the setup script is available (copy from main repo if needed)
-	if err := ensureSetupScript(worktreePath, cfg.SetupScript); err != nil {
+	if err := config.EnsureSetupScript(worktreePath, cfg.SetupScript); err != nil {
 		return fmt.Errorf("failed to ensure setup script: %w", err)
 	}
 
-	// Get the pane base index from tmux configuration
-	paneBaseIndex, err := getPaneBaseIndex(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get pane base index: %w", err)
-	}
+	windowName := WindowName(repoName, worktreeName)
 
-	windowName := fmt.Sprintf("%s|%s", repoName, worktreeName)
+	var envArgs []string
+	for _, kv := range env {
+		envArgs = append(envArgs, "-e", kv)
+	}
 
-	// Create new tmux window with setup script
+	// Create new tmux window, remembering its ID for all later targeting.
+	// Splitting and selecting panes of another window doesn't change the
+	// current window, so -d is all it takes to stay in the background.
+	args := []string{"new-window", "-P", "-F", "#{window_id}", "-n", windowName, "-c", worktreePath}
+	if background {
+		args = append(args, "-d")
+	}
+	args = append(args, envArgs...)
 	//nolint:gosec // G204: tmux commands with validated parameters are safe
-	cmd := exec.CommandContext(ctx, "tmux", "new-window", "-n", windowName, "-c", worktreePath)
-	if err := cmd.Run(); err != nil {
+	cmd := exec.CommandContext(ctx, "tmux", args...)
+	output, err := cmd.Output()
+	if err != nil {
 		if ctx.Err() == context.Canceled {
 			return fmt.Errorf("operation cancelled")
 		}
 		return fmt.Errorf("failed to create tmux window: %w", err)
 	}
+	windowID := strings.TrimSpace(string(output))
 
 	// Create panes dynamically based on pane_commands
-	// Layout strategy:
-	// - Pane 0 (baseIndex): Setup (always)
-	// - Pane 1 (baseIndex+1): First command - side by side with setup (vertical split)
-	// - Pane 2 (baseIndex+2): Second command - under setup (split pane 0 horizontally)
-	// - Pane 3 (baseIndex+3): Third command - under first command (split pane 1 horizontally)
-	// - Pane 4 (baseIndex+4): Fourth command - under second command (split pane 2 horizontally)
+	// Layout strategy (positions in the window, counted from 0):
+	// - Pane 0: Setup (always)
+	// - Pane 1: First command - side by side with setup (vertical split)
+	// - Pane 2: Second command - under setup (split pane 0 horizontally)
+	// - Pane 3: Third command - under first command (split pane 1 horizontally)
+	// - Pane 4: Fourth command - under second command (split pane 2 horizontally)
 	// - Continue pattern: each new pane splits the pane created 2 steps before
 	numPaneCommands := len(cfg.PaneCommands)
 
 	// If there are pane commands, create additional panes
 	if numPaneCommands > 0 {
 		// First pane command: split vertically to create side-by-side layout (setup | command1)
-		if err := runTmuxCmdWithContext(ctx, "split-window", "-h", "-c", worktreePath); err != nil {
+		if err := runTmuxCmdWithContext(ctx, append([]string{"split-window", "-h", "-t", windowID, "-c", worktreePath}, envArgs...)...); err != nil {
 			return err
 		}
 
 		// Additional pane commands: split existing panes horizontally
 		// Pattern: split pane (i-1) to create pane (i+1)
 		for i := 1; i < numPaneCommands; i++ {
-			// For second command (i=1): split pane 0 (setup)
-			// For third command (i=2): split pane 1 (first command)
-			// For fourth command (i=3): split pane 2 (second command)
-			// General formula: target pane index = paneBaseIndex + (i - 1)
-			targetPaneIdx := paneBaseIndex + (i - 1)
-			targetPane := fmt.Sprintf("%d", targetPaneIdx)
-
-			// Select the target pane and split horizontally
-			if err := runTmuxCmdWithContext(ctx, "select-pane", "-t", targetPane); err != nil {
+			// Positions shift as panes are inserted, so look up the pane ID each time
+			panes, err := getPanesForWindow(ctx, windowID)
+			if err != nil {
 				return err
 			}
-			if err := runTmuxCmdWithContext(ctx, "split-window", "-v", "-c", worktreePath); err != nil {
+			if err := runTmuxCmdWithContext(ctx, append([]string{"split-window", "-v", "-t", panes[i-1], "-c", worktreePath}, envArgs...)...); err != nil {
 				return err
 			}
 		}
 	}
 
-	// Send commands to panes
-	// Pane 0: Setup script (always)
-	if cfg.SetupScript != "" {
-		if err := sendKeysWithContext(ctx, paneBaseIndex, cfg.SetupScript); err != nil {
-			return err
-		}
+	// Send commands to panes by pane ID, independent of pane-base-index
+	panes, err := getPanesForWindow(ctx, windowID)
+	if err != nil {
+		return err
 	}
-
-	// Panes 1+: Pane commands
-	// The pane mapping is:
-	// - cfg.PaneCommands[0] -> pane baseIndex+1
-	// - cfg.PaneCommands[1] -> pane baseIndex+2
-	// - cfg.PaneCommands[n] -> pane baseIndex+n+1
-	for i, cmd := range cfg.PaneCommands {
-		paneIdx := paneBaseIndex + i + 1
-		if err := sendKeysWithContext(ctx, paneIdx, cmd); err != nil {
+	for _, pc := range sequencedPaneCommands(cfg, windowID) {
+		if pc.Pane >= len(panes) {
+			return fmt.Errorf("window %s has no pane %d", windowName, pc.Pane)
+		}
+		if err := sendKeysToTargetWithContext(ctx, panes[pc.Pane], pc.Command); err != nil {
 			return err
 		}
 	}
 
 	// Focus on the first pane (setup)
-	firstPane := fmt.Sprintf("%d", paneBaseIndex)
-	if err := runTmuxCmdWithContext(ctx, "select-pane", "-t", firstPane); err != nil {
+	if err := runTmuxCmdWithContext(ctx, "select-pane", "-t", panes[0]); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// findWindowByWorktree returns the window index and name for a given worktree.
-// Returns empty strings if not found. This is a helper function to avoid code duplication.
-func findWindowByWorktree(ctx context.Context, worktreeName string) (index, name string, err error) {
-	cmd := exec.CommandContext(ctx, "tmux", "list-windows", "-F", "#{window_index}:#{window_name}")
-	output, err := cmd.Output()
+// findWindowByWorktree returns the window ID (e.g. "@3") and name for a given worktree.
+// Returns empty strings if not found. Windows are always targeted by ID rather
+// than index, so base-index and renumber-windows settings don't matter.
+func findWindowByWorktree(ctx context.Context, worktreeName string) (id, name string, err error) {
+	windows, err := listKohWindows(ctx)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to list tmux windows: %w", err)
+		return "", "", err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		// Parse the line format: "index:window_name"
-		parts := strings.Split(line, ":")
-		if len(parts) >= 2 {
-			windowName := parts[1]
-			// Expected window name format: "repo-name|worktree-name"
-			// Use exact match on the worktree part to avoid substring issues
-			nameParts := strings.Split(windowName, "|")
-			if len(nameParts) == 2 && nameParts[1] == worktreeName {
-				return parts[0], windowName, nil
-			}
+	for _, w := range windows {
+		if w.worktree == worktreeName {
+			return w.id, w.name, nil
 		}
 	}
 	return "", "", nil
 }
 
-// getPanesForWindow returns all pane IDs for a given window index
-func getPanesForWindow(ctx context.Context, windowIndex string) ([]string, error) {
-	cmd := exec.CommandContext(ctx, "tmux", "list-panes", "-t", windowIndex, "-F", "#{pane_id}")
+// findWindowByName returns the ID of the window named windowName (see
+// WindowName) in the current session, or "" when there is none
+func findWindowByName(ctx context.Context, windowName string) (string, error) {
+	windows, err := listKohWindows(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, w := range windows {
+		if w.name == windowName {
+			return w.id, nil
+		}
+	}
+	return "", nil
+}
+
+// kohWindow is a tmux window whose name follows koh's "repo|worktree" format
+type kohWindow struct {
+	id       string
+	name     string
+	worktree string
+	// branch is the window's @koh_branch option, "" when unset
+	branch string
+}
+
+// listKohWindows returns all windows in the current tmux session named after a worktree
+func listKohWindows(ctx context.Context) ([]kohWindow, error) {
+	cmd := exec.CommandContext(ctx, "tmux", "list-windows", "-F", "#{window_id}\t"+windowNameFormat+"\t#{"+BranchOption+"}")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list panes for window %s: %w", windowIndex, err)
+		return nil, fmt.Errorf("failed to list tmux windows: %w", err)
+	}
+
+	return parseKohWindows(string(output)), nil
+}
+
+// parseKohWindows parses "id<TAB>name[<TAB>branch]" lines from list-windows
+func parseKohWindows(output string) []kohWindow {
+	var windows []kohWindow
+	for _, line := range strings.Split(output, "\n") {
+		id, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		windowName, branch, _ := strings.Cut(rest, "\t")
+		// Expected window name format: "repo-name|worktree-name"
+		// Use exact match on the worktree part to avoid substring issues
+		nameParts := strings.Split(windowName, "|")
+		if len(nameParts) == 2 {
+			windows = append(windows, kohWindow{id: id, name: windowName, worktree: nameParts[1], branch: branch})
+		}
+	}
+	return windows
+}
+
+// ListWorktreeWindowsWithContext returns the names of worktrees that have a
+// koh window in the current tmux session, mapped to the window name
+func ListWorktreeWindowsWithContext(ctx context.Context) (map[string]string, error) {
+	windows, err := listKohWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(windows))
+	for _, w := range windows {
+		result[w.worktree] = w.name
+	}
+	return result, nil
+}
+
+// WorktreeWindowIDsWithContext returns the IDs (e.g. "@3") of the windows
+// of repoName in the current tmux session, keyed by worktree name
+func WorktreeWindowIDsWithContext(ctx context.Context, repoName string) (map[string]string, error) {
+	windows, err := listKohWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(windows))
+	for _, w := range windows {
+		if w.name == WindowName(repoName, w.worktree) {
+			result[w.worktree] = w.id
+		}
+	}
+	return result, nil
+}
+
+// BranchOption is the tmux window option koh sets to the branch checked out
+// in a window's worktree, for use in formats such as window-status-format
+const BranchOption = "@koh_branch"
+
+// nameOption is the window option holding a koh window's WindowName once the
+// window is renamed to show its branch, so koh still finds it by that name
+const nameOption = "@koh_window"
+
+// windowNameFormat expands to the WindowName of a koh window, whether or not
+// it was renamed to show its branch
+const windowNameFormat = "#{?" + nameOption + ",#{" + nameOption + "},#{window_name}}"
+
+// windowLabel returns what the window named windowName (see WindowName) of
+// worktreeName is shown as: its name, followed by the branch checked out when
+// that isn't named after the worktree, e.g. "myapp|review (feature/login)"
+func windowLabel(windowName, worktreeName, branch string) string {
+	if branch == "" || branch == worktreeName {
+		return windowName
+	}
+	return fmt.Sprintf("%s (%s)", windowName, branch)
+}
+
+// WindowBranchesWithContext returns the branch recorded on each koh window,
+// keyed by worktree name
+func WindowBranchesWithContext(ctx context.Context) (map[string]string, error) {
+	windows, err := listKohWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(windows))
+	for _, w := range windows {
+		result[w.worktree] = w.branch
+	}
+	return result, nil
+}
+
+// SetWindowBranchWithContext records branch on a worktree's window and
+// renames the window to show it (see windowLabel)
+func SetWindowBranchWithContext(ctx context.Context, worktreeName, branch string) error {
+	windowID, windowName, err := findWindowByWorktree(ctx, worktreeName)
+	if err != nil {
+		return err
+	}
+	if windowID == "" {
+		return fmt.Errorf("no tmux window found for worktree: %s", worktreeName)
+	}
+	if err := runTmuxCmdWithContext(ctx, "set-option", "-w", "-t", windowID, BranchOption, branch); err != nil {
+		return err
+	}
+	if err := runTmuxCmdWithContext(ctx, "set-option", "-w", "-t", windowID, nameOption, windowName); err != nil {
+		return err
+	}
+	return runTmuxCmdWithContext(ctx, "rename-window", "-t", windowID, windowLabel(windowName, worktreeName, branch))
+}
+
+// WorktreePanePIDsWithContext returns the PIDs of the processes started in
+// each pane (usually the shell) of every koh window, keyed by worktree name
+func WorktreePanePIDsWithContext(ctx context.Context) (map[string][]int, error) {
+	windows, err := listKohWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "tmux", "list-panes", "-s", "-F", "#{window_id}\t#{pane_pid}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list panes: %w", err)
+	}
+
+	byWindow := parsePanePIDs(string(output))
+	result := make(map[string][]int, len(windows))
+	for _, w := range windows {
+		result[w.worktree] = append(result[w.worktree], byWindow[w.id]...)
+	}
+	return result, nil
+}
+
+// parsePanePIDs parses "window_id<TAB>pane_pid" lines from list-panes into
+// pane PIDs keyed by window ID
+func parsePanePIDs(output string) map[string][]int {
+	pids := make(map[string][]int)
+	for _, line := range strings.Split(output, "\n") {
+		windowID, pidText, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(pidText))
+		if err != nil {
+			continue
+		}
+		pids[windowID] = append(pids[windowID], pid)
+	}
+	return pids
+}
+
+// getPanesForWindow returns all pane IDs for a given window, in position order
+func getPanesForWindow(ctx context.Context, window string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "tmux", "list-panes", "-t", window, "-F", "#{pane_id}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list panes for window %s: %w", window, err)
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
@@ -301,22 +482,34 @@ func sendCtrlCToPane(ctx context.Context, paneID string) error {
 	return nil
 }
 
-// CloseWindow closes a tmux window by name.
+// CloseWindow closes a tmux window by name. With a windowName (see
+// WindowName) only the window of that repository is closed, including one
+// detached with DetachWindowWithContext; without one, the window of
+// worktreeName in the current session is.
 // Note: Uses context.Background() to ensure cleanup completes even if caller's context is cancelled.
 // This is intentional - we want the window and its processes to be properly cleaned up.
-func CloseWindow(_ /* windowName */, worktreeName string) error {
+func CloseWindow(windowName, worktreeName string) error {
 	ctx := context.Background()
-	index, _, err := findWindowByWorktree(ctx, worktreeName)
+	var windowID string
+	var err error
+	if windowName != "" {
+		windowID, err = findWindowByName(ctx, windowName)
+	} else {
+		windowID, _, err = findWindowByWorktree(ctx, worktreeName)
+	}
 	if err != nil {
 		return err
 	}
+	if windowID == "" && windowName != "" {
+		windowID = findDetachedWindow(ctx, windowName)
+	}
 
-	if index == "" {
+	if windowID == "" {
 		return fmt.Errorf("no tmux window found for worktree: %s", worktreeName)
 	}
 
 	// Get all panes in the window
-	panes, err := getPanesForWindow(ctx, index)
+	panes, err := getPanesForWindow(ctx, windowID)
 	if err != nil {
 		return fmt.Errorf("failed to get panes for window: %w", err)
 	}
@@ -325,7 +518,7 @@ func CloseWindow(_ /* windowName */, worktreeName string) error {
 	for _, paneID := range panes {
 		if err := sendCtrlCToPane(ctx, paneID); err != nil {
 			// Log the error but continue with other panes
-			fmt.Fprintf(os.Stderr, "Warning: failed to send Ctrl-C to pane %s in window %s: %v\n", paneID, index, err)
+			fmt.Fprintf(os.Stderr, "Warning: failed to send Ctrl-C to pane %s in window %s: %v\n", paneID, windowID, err)
 		}
 	}
 
@@ -338,7 +531,7 @@ func CloseWindow(_ /* windowName */, worktreeName string) error {
 
 	// Kill the window
 	//nolint:gosec // G204: tmux commands with validated parameters are safe
-	cmd := exec.CommandContext(ctx, "tmux", "kill-window", "-t", index)
+	cmd := exec.CommandContext(ctx, "tmux", "kill-window", "-t", windowID)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to close tmux window: %w", err)
 	}
@@ -346,6 +539,239 @@ func CloseWindow(_ /* windowName */, worktreeName string) error {
 	return nil
 }
 
+// paneTarget returns the pane ID (e.g. "%7") of a pane of a worktree's
+// window, by its number (see PaneIDsWithContext). Targeting by ID keeps this
+// correct whatever the user's base-index, pane-base-index and
+// renumber-windows settings are.
+func paneTarget(ctx context.Context, worktreeName string, pane int) (string, error) {
+	ids, err := PaneIDsWithContext(ctx, worktreeName)
+	if err != nil {
+		return "", err
+	}
+	if pane < 0 || pane >= len(ids) || ids[pane] == "" {
+		return "", fmt.Errorf("window for worktree %s has no pane %d", worktreeName, pane)
+	}
+	return ids[pane], nil
+}
+
+// SendToPaneWithContext types a command into a pane (counted from 0) of a
+// worktree's window and presses Enter.
+//
+// Security: command must come from .kohconfig or koh's record of commands it
+// sent from there, so it falls under the same trust model as CreateSession.
+func SendToPaneWithContext(ctx context.Context, worktreeName string, pane int, command string) error {
+	target, err := paneTarget(ctx, worktreeName, pane)
+	if err != nil {
+		return err
+	}
+
+	//nolint:gosec // G204: tmux commands with validated parameters are safe
+	cmd := exec.CommandContext(ctx, "tmux", "send-keys", "-t", target, command, "C-m")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to send keys to pane %s: %w", target, err)
+	}
+	return nil
+}
+
+// CapturePaneWithContext returns the text of a pane (counted from 0) of a
+// worktree's window, including its scrollback, without trailing blank lines
+func CapturePaneWithContext(ctx context.Context, worktreeName string, pane int) (string, error) {
+	target, err := paneTarget(ctx, worktreeName, pane)
+	if err != nil {
+		return "", err
+	}
+
+	//nolint:gosec // G204: tmux commands with validated parameters are safe
+	cmd := exec.CommandContext(ctx, "tmux", "capture-pane", "-p", "-J", "-S", "-", "-t", target)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to capture pane %s: %w", target, err)
+	}
+	return strings.TrimRight(string(output), "\n ") + "\n", nil
+}
+
+// SendKeyToPaneWithContext presses a key such as "C-c" or "C-z" in a pane
+// (counted from 0) of a worktree's window, without pressing Enter
+func SendKeyToPaneWithContext(ctx context.Context, worktreeName string, pane int, key string) error {
+	target, err := paneTarget(ctx, worktreeName, pane)
+	if err != nil {
+		return err
+	}
+
+	//nolint:gosec // G204: tmux commands with validated parameters are safe
+	cmd := exec.CommandContext(ctx, "tmux", "send-keys", "-t", target, key)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to send %s to pane %s: %w", key, target, err)
+	}
+	return nil
+}
+
+// RerunPaneCommandWithContext interrupts whatever runs in a pane of a
+// worktree's window (pane counted from 0) and sends command to it again
+func RerunPaneCommandWithContext(ctx context.Context, worktreeName string, pane int, command string) error {
+	target, err := paneTarget(ctx, worktreeName, pane)
+	if err != nil {
+		return err
+	}
+	if err := sendCtrlCToPane(ctx, target); err != nil {
+		return err
+	}
+	return SendToPaneWithContext(ctx, worktreeName, pane, command)
+}
+
+// PaneInfo describes a pane of a worktree window
+type PaneInfo struct {
+	// Pane is the pane's position in the window, counted from 0
+	Pane int
+	// CurrentCommand is the program running in the foreground, e.g. "zsh" or "npm"
+	CurrentCommand string
+}
+
+// ListWindowPanesWithContext returns the panes of a worktree's window in
+// order of their numbers (see PaneIDsWithContext)
+func ListWindowPanesWithContext(ctx context.Context, worktreeName string) ([]PaneInfo, error) {
+	windowID, _, err := findWindowByWorktree(ctx, worktreeName)
+	if err != nil {
+		return nil, err
+	}
+	if windowID == "" {
+		return nil, fmt.Errorf("no tmux window found for worktree: %s", worktreeName)
+	}
+
+	cmd := exec.CommandContext(ctx, "tmux", "list-panes", "-t", windowID, "-F", "#{pane_id}\t#{pane_current_command}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list panes for window %s: %w", windowID, err)
+	}
+
+	var live []string
+	commands := map[string]string{}
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		id, command, _ := strings.Cut(line, "\t")
+		live = append(live, id)
+		commands[id] = strings.TrimSpace(command)
+	}
+
+	var panes []PaneInfo
+	for pane, id := range numberPanes(recordedPaneIDs(worktreeName), live) {
+		if id != "" {
+			panes = append(panes, PaneInfo{Pane: pane, CurrentCommand: commands[id]})
+		}
+	}
+	return panes, nil
+}
+
+// Window identifies the tmux window of a worktree, for composing raw tmux
+// commands. ID is unique across the tmux server and stays the same when the
+// window is renamed or moved, so it is the most reliable -t target.
+type Window struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Index       int    `json:"index"`
+	SessionID   string `json:"session_id"`
+	SessionName string `json:"session_name"`
+	Active      bool   `json:"active"`
+	// Panes are the pane IDs (e.g. "%4") in position order
+	Panes []string `json:"panes"`
+}
+
+// windowFormat is the list-windows format parsed by parseWindow
+const windowFormat = "#{window_id}\t" + windowNameFormat + "\t#{window_index}\t#{session_id}\t#{session_name}\t#{window_active}"
+
+// WindowInfoWithContext returns the tmux window of a worktree, or nil when
+// the worktree has no window open
+func WindowInfoWithContext(ctx context.Context, worktreeName string) (*Window, error) {
+	windowID, _, err := findWindowByWorktree(ctx, worktreeName)
+	if err != nil || windowID == "" {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "tmux", "display-message", "-p", "-t", windowID, windowFormat)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe window %s: %w", windowID, err)
+	}
+	window, err := parseWindow(string(output))
+	if err != nil {
+		return nil, err
+	}
+
+	if window.Panes, err = getPanesForWindow(ctx, windowID); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// parseWindow parses a line of windowFormat
+func parseWindow(output string) (*Window, error) {
+	fields := strings.Split(strings.TrimRight(output, "\n"), "\t")
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("unexpected tmux window description: %q", output)
+	}
+	index, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected tmux window index %q", fields[2])
+	}
+	return &Window{
+		ID:          fields[0],
+		Name:        fields[1],
+		Index:       index,
+		SessionID:   fields[3],
+		SessionName: fields[4],
+		Active:      fields[5] == "1",
+	}, nil
+}
+
+// AddPaneWithContext splits the last pane of a worktree's window, starting the
+// new pane in dir with the "KEY=value" entries of env set, and returns its
+// number
+func AddPaneWithContext(ctx context.Context, worktreeName, dir string, env ...string) (int, error) {
+	windowID, _, err := findWindowByWorktree(ctx, worktreeName)
+	if err != nil {
+		return 0, err
+	}
+	if windowID == "" {
+		return 0, fmt.Errorf("no tmux window found for worktree: %s", worktreeName)
+	}
+	live, err := getPanesForWindow(ctx, windowID)
+	if err != nil {
+		return 0, err
+	}
+
+	args := []string{"split-window", "-d", "-v", "-P", "-F", "#{pane_id}", "-t", live[len(live)-1], "-c", dir}
+	for _, kv := range env {
+		args = append(args, "-e", kv)
+	}
+	//nolint:gosec // G204: tmux commands with validated parameters are safe
+	cmd := exec.CommandContext(ctx, "tmux", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add a pane to window %s: %w", windowID, err)
+	}
+	added := strings.TrimSpace(string(output))
+
+	ids, err := PaneIDsWithContext(ctx, worktreeName)
+	if err != nil {
+		return 0, err
+	}
+	for pane, id := range ids {
+		if id == added {
+			return pane, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to find the pane added to window %s", windowID)
+}
+
+// IsShell reports whether a pane's current command is an interactive shell,
+// meaning the pane is idle and safe to type into
+func IsShell(command string) bool {
+	switch strings.TrimPrefix(filepath.Base(command), "-") {
+	case "sh", "bash", "zsh", "fish", "dash", "ksh", "tcsh", "csh", "nu", "elvish", "xonsh":
+		return true
+	}
+	return false
+}
+
 // runTmuxCmd runs a tmux command with the given arguments
 func runTmuxCmd(args ...string) error {
 	return runTmuxCmdWithContext(context.Background(), args...)
@@ -375,43 +801,31 @@ func sendKeys(pane int, keys string) error {
 // the security trust model). The keys are passed to tmux which executes them
 // in the shell context of the pane.
 func sendKeysWithContext(ctx context.Context, pane int, keys string) error {
-	paneTarget := fmt.Sprintf("%d", pane)
+	return sendKeysToTargetWithContext(ctx, fmt.Sprintf("%d", pane), keys)
+}
+
+// sendKeysToTargetWithContext types keys into the pane identified by target
+// (normally a pane ID like "%7") and presses Enter. The same trust model as
+// sendKeysWithContext applies to keys.
+func sendKeysToTargetWithContext(ctx context.Context, target, keys string) error {
 	//nolint:gosec // G204: tmux commands with validated parameters are safe
-	cmd := exec.CommandContext(ctx, "tmux", "send-keys", "-t", paneTarget, keys, "C-m")
+	cmd := exec.CommandContext(ctx, "tmux", "send-keys", "-t", target, keys, "C-m")
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.Canceled {
 			return fmt.Errorf("operation cancelled")
 		}
-		return fmt.Errorf("failed to send keys to pane %d: %w", pane, err)
+		return fmt.Errorf("failed to send keys to pane %s: %w", target, err)
 	}
 	return nil
 }
 
-// getPaneBaseIndex retrieves the pane-base-index setting from tmux configuration
-func getPaneBaseIndex(ctx context.Context) (int, error) {
-	cmd := exec.CommandContext(ctx, "tmux", "show-options", "-gv", "pane-base-index")
-	output, err := cmd.Output()
-	if err != nil {
-		// If the option is not set, default to 0
-		return 0, nil
-	}
-
-	var baseIndex int
-	_, err = fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &baseIndex)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse pane-base-index: %w", err)
-	}
-
-	return baseIndex, nil
-}
-
 // WindowExistsWithContext checks if a tmux window exists for the given worktree name with context support
 func WindowExistsWithContext(ctx context.Context, worktreeName string) (bool, error) {
-	index, _, err := findWindowByWorktree(ctx, worktreeName)
+	windowID, _, err := findWindowByWorktree(ctx, worktreeName)
 	if err != nil {
 		return false, err
 	}
-	return index != "", nil
+	return windowID != "", nil
 }
 
 // WindowExists checks if a tmux window exists for the given worktree name
@@ -421,18 +835,18 @@ func WindowExists(worktreeName string) (bool, error) {
 
 // SwitchToWindowWithContext switches to the tmux window for the given worktree name with context support
 func SwitchToWindowWithContext(ctx context.Context, worktreeName string) error {
-	index, _, err := findWindowByWorktree(ctx, worktreeName)
+	windowID, _, err := findWindowByWorktree(ctx, worktreeName)
 	if err != nil {
 		return err
 	}
 
-	if index == "" {
+	if windowID == "" {
 		return fmt.Errorf("no tmux window found for worktree: %s", worktreeName)
 	}
 
 	// Switch to the window
 	//nolint:gosec // G204: tmux commands with validated parameters are safe
-	cmd := exec.CommandContext(ctx, "tmux", "select-window", "-t", index)
+	cmd := exec.CommandContext(ctx, "tmux", "select-window", "-t", windowID)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to switch to tmux window: %w", err)
 	}
@@ -2,10 +2,12 @@ package tmux
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/bshakr/koh/internal/config"
+	"github.com/bshakr/koh/internal/testutil"
 )
 
 func TestIsInTmux(t *testing.T) {
@@ -18,9 +20,7 @@ func TestIsInTmux(t *testing.T) {
 }
 
 func TestRunTmuxCmdWithContext(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test a simple tmux command with context
 	ctx := context.Background()
@@ -31,9 +31,7 @@ func TestRunTmuxCmdWithContext(t *testing.T) {
 }
 
 func TestRunTmuxCmdWithContextCancellation(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -51,9 +49,7 @@ func TestRunTmuxCmdWithContextCancellation(t *testing.T) {
 }
 
 func TestRunTmuxCmdWithContextTimeout(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test with a reasonable timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -66,9 +62,7 @@ func TestRunTmuxCmdWithContextTimeout(t *testing.T) {
 }
 
 func TestSendKeysWithContext(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// We can't easily test this without creating actual panes
 	// So we'll just test that the function exists and has the right signature
@@ -81,9 +75,7 @@ func TestSendKeysWithContext(t *testing.T) {
 }
 
 func TestSendKeysWithContextCancellation(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -101,9 +93,7 @@ func TestSendKeysWithContextCancellation(t *testing.T) {
 }
 
 func TestCloseWindow(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// We can't easily test this without creating actual windows
 	// Just verify the function exists
@@ -119,9 +109,7 @@ func TestCloseWindow(t *testing.T) {
 
 // Test that the backwards-compatible functions still work
 func TestBackwardsCompatibility(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test that the non-context versions still work by delegating to context versions
 	err := runTmuxCmd("display-message", "-p", "test")
@@ -137,13 +125,11 @@ func TestBackwardsCompatibility(t *testing.T) {
 
 // TestCreateSessionWithNoPaneCommands tests creating a session with only setup script
 func TestCreateSessionWithNoPaneCommands(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{},
+		PaneCommands: []config.PaneCommand{},
 	}
 
 	ctx := context.Background()
@@ -153,20 +139,18 @@ func TestCreateSessionWithNoPaneCommands(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", "test-worktree-0"); err != nil {
+	if err := CloseWindow(WindowName("test-repo", "test-worktree-0"), "test-worktree-0"); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestCreateSessionWithOnePaneCommand tests creating a session with setup + 1 command
 func TestCreateSessionWithOnePaneCommand(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{"echo 'Command 1'"},
+		PaneCommands: config.PlainPaneCommands([]string{"echo 'Command 1'"}),
 	}
 
 	ctx := context.Background()
@@ -176,20 +160,18 @@ func TestCreateSessionWithOnePaneCommand(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", "test-worktree-1"); err != nil {
+	if err := CloseWindow(WindowName("test-repo", "test-worktree-1"), "test-worktree-1"); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestCreateSessionWithTwoPaneCommands tests creating a session with setup + 2 commands
 func TestCreateSessionWithTwoPaneCommands(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{"echo 'Command 1'", "echo 'Command 2'"},
+		PaneCommands: config.PlainPaneCommands([]string{"echo 'Command 1'", "echo 'Command 2'"}),
 	}
 
 	ctx := context.Background()
@@ -199,20 +181,18 @@ func TestCreateSessionWithTwoPaneCommands(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", "test-worktree-2"); err != nil {
+	if err := CloseWindow(WindowName("test-repo", "test-worktree-2"), "test-worktree-2"); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestCreateSessionWithThreePaneCommands tests creating a session with setup + 3 commands
 func TestCreateSessionWithThreePaneCommands(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{"echo 'Command 1'", "echo 'Command 2'", "echo 'Command 3'"},
+		PaneCommands: config.PlainPaneCommands([]string{"echo 'Command 1'", "echo 'Command 2'", "echo 'Command 3'"}),
 	}
 
 	ctx := context.Background()
@@ -222,26 +202,24 @@ func TestCreateSessionWithThreePaneCommands(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", "test-worktree-3"); err != nil {
+	if err := CloseWindow(WindowName("test-repo", "test-worktree-3"), "test-worktree-3"); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestCreateSessionWithManyPaneCommands tests creating a session with setup + 5 commands
 func TestCreateSessionWithManyPaneCommands(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	cfg := &config.Config{
 		SetupScript: "",
-		PaneCommands: []string{
+		PaneCommands: config.PlainPaneCommands([]string{
 			"echo 'Command 1'",
 			"echo 'Command 2'",
 			"echo 'Command 3'",
 			"echo 'Command 4'",
 			"echo 'Command 5'",
-		},
+		}),
 	}
 
 	ctx := context.Background()
@@ -251,16 +229,14 @@ func TestCreateSessionWithManyPaneCommands(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", "test-worktree-many"); err != nil {
+	if err := CloseWindow(WindowName("test-repo", "test-worktree-many"), "test-worktree-many"); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestWindowExists tests checking if a window exists
 func TestWindowExists(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test with non-existent window
 	exists, err := WindowExists("nonexistent-worktree-12345")
@@ -274,9 +250,7 @@ func TestWindowExists(t *testing.T) {
 
 // TestWindowExistsWithContext tests checking if a window exists with context
 func TestWindowExistsWithContext(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	ctx := context.Background()
 
@@ -302,9 +276,7 @@ func TestWindowExistsWithContext(t *testing.T) {
 
 // TestSwitchToWindow tests switching to a window
 func TestSwitchToWindow(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	// Test with non-existent window should error
 	err := SwitchToWindow("nonexistent-worktree-switch-12345")
@@ -320,9 +292,7 @@ func TestSwitchToWindow(t *testing.T) {
 
 // TestSwitchToWindowWithContext tests switching to a window with context
 func TestSwitchToWindowWithContext(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	ctx := context.Background()
 
@@ -335,9 +305,7 @@ func TestSwitchToWindowWithContext(t *testing.T) {
 
 // TestFindWindowByWorktree tests the helper function
 func TestFindWindowByWorktree(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	ctx := context.Background()
 
@@ -351,16 +319,41 @@ func TestFindWindowByWorktree(t *testing.T) {
 	}
 }
 
+// TestCreateBackgroundSession tests that a background window leaves the current window selected
+func TestCreateBackgroundSession(t *testing.T) {
+	tm := testutil.NewTmux(t)
+
+	worktreeName := "test-background-window"
+	cfg := &config.Config{
+		PaneCommands: config.PlainPaneCommands([]string{"true", "true"}),
+	}
+
+	before := tm.CurrentWindow(t)
+	if err := CreateBackgroundSessionWithContext(context.Background(), "test-repo", worktreeName, "/tmp", cfg); err != nil {
+		t.Fatalf("CreateBackgroundSessionWithContext() failed: %v", err)
+	}
+	defer func() {
+		if err := CloseWindow(WindowName("test-repo", worktreeName), worktreeName); err != nil {
+			t.Logf("Failed to close window: %v", err)
+		}
+	}()
+
+	if after := tm.CurrentWindow(t); after != before {
+		t.Errorf("Expected window %s to stay selected, got %s", before, after)
+	}
+	if exists, _ := WindowExists(worktreeName); !exists {
+		t.Error("Expected the background window to exist")
+	}
+}
+
 // TestWindowExistsAfterCreation tests that WindowExists returns true after creating a window
 func TestWindowExistsAfterCreation(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	worktreeName := "test-exists-window"
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{},
+		PaneCommands: []config.PaneCommand{},
 	}
 
 	// Create the window
@@ -380,7 +373,7 @@ func TestWindowExistsAfterCreation(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", worktreeName); err != nil {
+	if err := CloseWindow(WindowName("test-repo", worktreeName), worktreeName); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 
@@ -396,14 +389,12 @@ func TestWindowExistsAfterCreation(t *testing.T) {
 
 // TestGetPanesForWindow tests getting pane IDs for a window
 func TestGetPanesForWindow(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	worktreeName := "test-get-panes"
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{"echo 'pane 1'", "echo 'pane 2'"},
+		PaneCommands: config.PlainPaneCommands([]string{"echo 'pane 1'", "echo 'pane 2'"}),
 	}
 
 	// Create a window with multiple panes
@@ -439,21 +430,19 @@ func TestGetPanesForWindow(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", worktreeName); err != nil {
+	if err := CloseWindow(WindowName("test-repo", worktreeName), worktreeName); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestSendCtrlCToPane tests sending Ctrl-C to a pane
 func TestSendCtrlCToPane(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	worktreeName := "test-ctrl-c"
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{},
+		PaneCommands: []config.PaneCommand{},
 	}
 
 	// Create a window with one pane
@@ -485,16 +474,14 @@ func TestSendCtrlCToPane(t *testing.T) {
 	}
 
 	// Cleanup
-	if err := CloseWindow("test-repo", worktreeName); err != nil {
+	if err := CloseWindow(WindowName("test-repo", worktreeName), worktreeName); err != nil {
 		t.Logf("Failed to close window: %v", err)
 	}
 }
 
 // TestGetPanesForWindowNonExistent tests error handling for non-existent window
 func TestGetPanesForWindowNonExistent(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	ctx := context.Background()
 	// Use a very high window index that should not exist
@@ -509,14 +496,12 @@ func TestGetPanesForWindowNonExistent(t *testing.T) {
 
 // TestCloseWindowWithCtrlC tests that CloseWindow sends Ctrl-C before killing
 func TestCloseWindowWithCtrlC(t *testing.T) {
-	if !IsInTmux() {
-		t.Skip("Not in a tmux session, skipping test")
-	}
+	testutil.NewTmux(t)
 
 	worktreeName := "test-close-with-ctrl-c"
 	cfg := &config.Config{
 		SetupScript:  "",
-		PaneCommands: []string{"sleep 10", "sleep 20"},
+		PaneCommands: config.PlainPaneCommands([]string{"sleep 10", "sleep 20"}),
 	}
 
 	// Create a window with panes running sleep commands
@@ -536,7 +521,7 @@ func TestCloseWindowWithCtrlC(t *testing.T) {
 	}
 
 	// Close the window (should send Ctrl-C to all panes before killing)
-	err = CloseWindow("test-repo", worktreeName)
+	err = CloseWindow(WindowName("test-repo", worktreeName), worktreeName)
 	if err != nil {
 		t.Errorf("CloseWindow() error: %v", err)
 	}
@@ -564,3 +549,259 @@ func containsAt(s, substr string) bool {
 	}
 	return false
 }
+
+func TestPaneCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want []PaneCommand
+	}{
+		{
+			name: "setup and commands",
+			cfg:  &config.Config{SetupScript: "./bin/setup", PaneCommands: config.PlainPaneCommands([]string{"npm run dev", "vim"})},
+			want: []PaneCommand{{0, "./bin/setup"}, {1, "npm run dev"}, {2, "vim"}},
+		},
+		{
+			name: "commands without setup keep their panes",
+			cfg:  &config.Config{PaneCommands: config.PlainPaneCommands([]string{"npm run dev"})},
+			want: []PaneCommand{{1, "npm run dev"}},
+		},
+		{
+			name: "empty config",
+			cfg:  &config.Config{},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PaneCommands(tt.cfg)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("PaneCommands()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRerunPaneCommandWithoutWindow(t *testing.T) {
+	testutil.NewTmux(t)
+
+	err := RerunPaneCommandWithContext(context.Background(), "nonexistent-worktree-xyz", 0, "true")
+	if err == nil {
+		t.Error("Expected error when the worktree has no window")
+	}
+}
+
+func TestIsShell(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"zsh", true},
+		{"-bash", true},
+		{"/usr/bin/fish", true},
+		{"vim", false},
+		{"node", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsShell(tt.command); got != tt.want {
+			t.Errorf("IsShell(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestParseKohWindows(t *testing.T) {
+	output := "@1\tzsh\t\n@4\tmyapp|feature\tfeature/login\n@7\tmyapp|fix|extra\t\n@9\tother|main\n"
+
+	windows := parseKohWindows(output)
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 koh windows, got %d: %+v", len(windows), windows)
+	}
+	if windows[0].id != "@4" || windows[0].worktree != "feature" || windows[0].name != "myapp|feature" || windows[0].branch != "feature/login" {
+		t.Errorf("Unexpected first window: %+v", windows[0])
+	}
+	if windows[1].id != "@9" || windows[1].worktree != "main" {
+		t.Errorf("Unexpected second window: %+v", windows[1])
+	}
+}
+
+func TestParsePanePIDs(t *testing.T) {
+	output := "@4\t1201\n@4\t1305\n@9\t2002\nbad line\n@9\tnotapid\n"
+
+	pids := parsePanePIDs(output)
+	if len(pids["@4"]) != 2 || pids["@4"][0] != 1201 || pids["@4"][1] != 1305 {
+		t.Errorf("Expected pane PIDs [1201 1305] for @4, got %v", pids["@4"])
+	}
+	if len(pids["@9"]) != 1 || pids["@9"][0] != 2002 {
+		t.Errorf("Expected pane PIDs [2002] for @9, got %v", pids["@9"])
+	}
+}
+
+func TestSequencedPaneCommands(t *testing.T) {
+	cfg := &config.Config{
+		SetupScript:  "./bin/setup",
+		PaneCommands: config.PlainPaneCommands([]string{"npm run dev", "vim"}),
+		WaitForSetup: true,
+	}
+
+	got := sequencedPaneCommands(cfg, "@7")
+	want := []PaneCommand{
+		{Pane: 0, Command: "./bin/setup; tmux wait-for -S koh-@7-1; tmux wait-for -S koh-@7-2"},
+		{Pane: 1, Command: "tmux wait-for koh-@7-1 && npm run dev"},
+		{Pane: 2, Command: "tmux wait-for koh-@7-2 && vim"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d commands, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], got[i])
+		}
+	}
+
+	cfg.WaitForSetup = false
+	if got := sequencedPaneCommands(cfg, "@7"); got[1].Command != "npm run dev" {
+		t.Errorf("Expected pane commands to start right away without wait_for_setup, got %+v", got)
+	}
+
+	noSetup := &config.Config{PaneCommands: config.PlainPaneCommands([]string{"vim"}), WaitForSetup: true}
+	if got := sequencedPaneCommands(noSetup, "@7"); len(got) != 1 || got[0].Command != "vim" {
+		t.Errorf("Expected nothing to wait for without a setup script, got %+v", got)
+	}
+}
+
+func TestPaneCommandLine(t *testing.T) {
+	tests := []struct {
+		name string
+		pane config.PaneCommand
+		want string
+	}{
+		{
+			name: "plain command",
+			pane: config.PaneCommand{Command: "npm run dev"},
+			want: "npm run dev",
+		},
+		{
+			name: "delay",
+			pane: config.PaneCommand{Command: "npm run dev", Delay: "1.5s"},
+			want: "sleep 1.5 && npm run dev",
+		},
+		{
+			name: "retries wait a second between attempts",
+			pane: config.PaneCommand{Command: "npm run dev", Retries: 3},
+			want: `sh -c 'n=0; until npm run dev; do n=$((n+1)); [ "$n" -gt 3 ] && exit 1; echo "koh: retrying ($n/3)"; sleep 1; done'`,
+		},
+		{
+			name: "retries wait the delay",
+			pane: config.PaneCommand{Command: "echo 'up'", Delay: "5s", Retries: 2},
+			want: `sh -c 'sleep 5; n=0; until echo '\''up'\''; do n=$((n+1)); [ "$n" -gt 2 ] && exit 1; echo "koh: retrying ($n/2)"; sleep 5; done'`,
+		},
+		{
+			name: "invalid delay is ignored",
+			pane: config.PaneCommand{Command: "vim", Delay: "soon"},
+			want: "vim",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paneCommandLine(tt.pane); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	window, err := parseWindow("@3\tmyrepo|feature\t2\t$1\twork\t1\n")
+	if err != nil {
+		t.Fatalf("parseWindow() failed: %v", err)
+	}
+	want := Window{ID: "@3", Name: "myrepo|feature", Index: 2, SessionID: "$1", SessionName: "work", Active: true}
+	if window.ID != want.ID || window.Name != want.Name || window.Index != want.Index ||
+		window.SessionID != want.SessionID || window.SessionName != want.SessionName || window.Active != want.Active {
+		t.Errorf("Expected %+v, got %+v", want, *window)
+	}
+
+	for _, output := range []string{"", "@3\tname", "@3\tname\tx\t$1\twork\t0"} {
+		if _, err := parseWindow(output); err == nil {
+			t.Errorf("Expected an error for %q", output)
+		}
+	}
+}
+
+func TestWorktreeWindowIDs(t *testing.T) {
+	testutil.NewTmux(t)
+	ctx := context.Background()
+	for _, repo := range []string{"test-repo", "other-repo"} {
+		if err := CreateBackgroundSessionWithContext(ctx, repo, "feat", "/tmp", &config.Config{}); err != nil {
+			t.Fatalf("CreateBackgroundSessionWithContext() failed: %v", err)
+		}
+	}
+
+	ids, err := WorktreeWindowIDsWithContext(ctx, "test-repo")
+	if err != nil {
+		t.Fatalf("WorktreeWindowIDsWithContext() failed: %v", err)
+	}
+	want, err := findWindowByName(ctx, WindowName("test-repo", "feat"))
+	if err != nil || want == "" {
+		t.Fatalf("Expected the window of test-repo, got %q (%v)", want, err)
+	}
+	if len(ids) != 1 || ids["feat"] != want {
+		t.Errorf("Expected only test-repo's window %s, got %v", want, ids)
+	}
+}
+
+func TestWindowLabel(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{branch: "review", want: "myapp|review"},
+		{branch: "", want: "myapp|review"},
+		{branch: "feature/login", want: "myapp|review (feature/login)"},
+	}
+	for _, tt := range tests {
+		if got := windowLabel("myapp|review", "review", tt.branch); got != tt.want {
+			t.Errorf("windowLabel() with branch %q = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestSetWindowBranchRenamesWindow(t *testing.T) {
+	tm := testutil.NewTmux(t)
+	ctx := context.Background()
+	if err := CreateBackgroundSessionWithContext(ctx, "test-repo", "review", "/tmp", &config.Config{}); err != nil {
+		t.Fatalf("CreateBackgroundSessionWithContext() failed: %v", err)
+	}
+
+	if err := SetWindowBranchWithContext(ctx, "review", "feature/login"); err != nil {
+		t.Fatalf("SetWindowBranchWithContext() failed: %v", err)
+	}
+	if windows := tm.Windows(t); !slices.Contains(windows, "test-repo|review (feature/login)") {
+		t.Errorf("Expected the window to show its branch, got %v", windows)
+	}
+
+	// koh still finds the renamed window by its worktree
+	if exists, err := WindowExistsWithContext(ctx, "review"); err != nil || !exists {
+		t.Errorf("Expected the renamed window to be found, got %v (%v)", exists, err)
+	}
+	branches, err := WindowBranchesWithContext(ctx)
+	if err != nil || branches["review"] != "feature/login" {
+		t.Errorf("Expected the branch to be recorded on the window, got %v (%v)", branches, err)
+	}
+
+	if err := SetWindowBranchWithContext(ctx, "review", "review"); err != nil {
+		t.Fatalf("SetWindowBranchWithContext() failed: %v", err)
+	}
+	if windows := tm.Windows(t); !slices.Contains(windows, "test-repo|review") {
+		t.Errorf("Expected the window name back without a branch, got %v", windows)
+	}
+}
@@ -9,18 +9,67 @@
 // All user-supplied worktree names must pass through ValidateWorktreeName
 // before being used in file operations or shell commands.
 //
-// The validation is designed to be strict and cross-platform compatible,
+// How strict name validation is depends on the active Policy, which users pick
+// in the global config. Every policy rejects path traversal and control
+// characters; the standard policy is designed to be cross-platform compatible,
 // rejecting potentially dangerous input even on systems where it might be safe.
 package validation
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
 )
 
-// ValidateWorktreeName validates that a worktree name is safe to use
+// Policy selects how strictly worktree names are validated
+type Policy string
+
+const (
+	// Strict only allows letters, digits, '.', '_' and '-', starting with a
+	// letter or digit, on top of the standard rules
+	Strict Policy = "strict"
+	// Standard rejects path traversal, control characters and names reserved
+	// on Windows
+	Standard Policy = "standard"
+	// Relaxed only rejects path traversal and control characters, allowing
+	// names such as "CON" or "aux"
+	Relaxed Policy = "relaxed"
+)
+
+// Active is the policy ValidateWorktreeName applies, set from the global
+// config at startup
+var Active = Standard
+
+// ParsePolicy parses a policy name, where "" means Standard
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(strings.ToLower(name)); p {
+	case "":
+		return Standard, nil
+	case Strict, Standard, Relaxed:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown validation policy %q (use strict, standard or relaxed)", name)
+}
+
+// ScratchName is the directory in the worktree directory holding the
+// scratch directories of worktrees, so no worktree can take it
+const ScratchName = "scratch"
+
+// reservedNames are device names reserved on Windows
+var reservedNames = []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "COM4",
+	"COM5", "COM6", "COM7", "COM8", "COM9", "LPT1", "LPT2", "LPT3",
+	"LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
+
+// ValidateWorktreeName validates that a worktree name is safe to use under
+// the active policy
 func ValidateWorktreeName(name string) error {
+	return Active.ValidateWorktreeName(name)
+}
+
+// ValidateWorktreeName validates that a worktree name is safe to use under p
+func (p Policy) ValidateWorktreeName(name string) error {
 	if name == "" {
 		return fmt.Errorf("worktree name cannot be empty")
 	}
@@ -44,8 +93,8 @@ func ValidateWorktreeName(name string) error {
 		return fmt.Errorf("worktree name cannot contain '..'")
 	}
 
-	// Check for special characters that could cause issues
-	if strings.ContainsAny(name, "\x00\n\r\t") {
+	// Check for control characters that could cause issues
+	if strings.IndexFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7f }) >= 0 {
 		return fmt.Errorf("worktree name contains invalid characters")
 	}
 
@@ -55,23 +104,82 @@ func ValidateWorktreeName(name string) error {
 		return fmt.Errorf("worktree name contains invalid path components")
 	}
 
+	if name == ScratchName {
+		return fmt.Errorf("worktree name %q is reserved for scratch directories", ScratchName)
+	}
+
+	if p == Relaxed {
+		return nil
+	}
+
 	// Check for reserved names on Windows (even if we're not on Windows, be safe)
-	reserved := []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "COM4",
-		"COM5", "COM6", "COM7", "COM8", "COM9", "LPT1", "LPT2", "LPT3",
-		"LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
 	upperName := strings.ToUpper(name)
-	for _, r := range reserved {
+	for _, r := range reservedNames {
 		if upperName == r {
 			return fmt.Errorf("worktree name cannot be a reserved system name")
 		}
 	}
 
+	if p == Strict {
+		for i, r := range name {
+			alnum := isAlnum(r)
+			if i == 0 && !alnum {
+				return fmt.Errorf("worktree name must start with a letter or digit")
+			}
+			if !alnum && r != '.' && r != '_' && r != '-' {
+				return fmt.Errorf("worktree name can only contain letters, digits, '.', '_' and '-' (validation policy is strict)")
+			}
+		}
+	}
+
 	return nil
 }
 
+// Normalize turns name into a worktree name the active policy accepts
+func Normalize(name string) string {
+	return Active.Normalize(name)
+}
+
+// Normalize turns name, such as the branch name "feature/login", into a
+// slug p accepts, such as "feature-login": path separators, whitespace and
+// control characters become '-' (under the strict policy, so does anything
+// but letters, digits, '.', '_' and '-'), runs of '-' or '.' are collapsed
+// and leading and trailing ones dropped. What it can't repair, such as a
+// reserved name, is still rejected by ValidateWorktreeName.
+func (p Policy) Normalize(name string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range name {
+		switch {
+		case r == '/' || r == '\\' || r < 0x20 || r == 0x7f || unicode.IsSpace(r):
+			r = '-'
+		case p == Strict && !isAlnum(r) && r != '.' && r != '_' && r != '-':
+			r = '-'
+		}
+		if (r == '-' || r == '.') && r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+
+	slug := strings.Trim(b.String(), "-.")
+	if p == Strict {
+		slug = strings.TrimLeft(slug, "-._")
+	}
+	return slug
+}
+
+// isAlnum reports whether r is an ASCII letter or digit
+func isAlnum(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
 // ValidatePathWithinRepository ensures that targetPath is within repoRoot.
 // This prevents path traversal attacks where a user might try to access
-// files outside the repository boundaries.
+// files outside the repository boundaries. Symlinks are resolved on both
+// sides first, so a link inside the repository can't lead out of it; the
+// part of targetPath that doesn't exist yet is taken as written.
 func ValidatePathWithinRepository(targetPath, repoRoot string) error {
 	cleanTarget, err := filepath.Abs(targetPath)
 	if err != nil {
@@ -83,11 +191,65 @@ func ValidatePathWithinRepository(targetPath, repoRoot string) error {
 		return fmt.Errorf("failed to resolve repository root: %w", err)
 	}
 
-	// Check if target is within root or equal to root
-	if !strings.HasPrefix(cleanTarget, cleanRoot+string(filepath.Separator)) &&
-		cleanTarget != cleanRoot {
+	resolvedTarget, err := resolveSymlinks(cleanTarget)
+	if err != nil {
+		return fmt.Errorf("failed to resolve target path: %w", err)
+	}
+	resolvedRoot, err := resolveSymlinks(cleanRoot)
+	if err != nil {
+		return fmt.Errorf("failed to resolve repository root: %w", err)
+	}
+	if !within(resolvedTarget, resolvedRoot) {
+		if within(cleanTarget, cleanRoot) {
+			return fmt.Errorf("path must be within repository boundaries, but a symlink leads it to %s", resolvedTarget)
+		}
 		return fmt.Errorf("path must be within repository boundaries")
 	}
 
 	return nil
 }
+
+// ValidateRepositoryPath checks a path from the configuration that is
+// relative to the repository root, such as a copy_files pattern, and
+// returns it cleaned, e.g. "./config/" as "config"
+func ValidateRepositoryPath(path string) (string, error) {
+	switch {
+	case strings.TrimSpace(path) == "":
+		return "", fmt.Errorf("path is empty")
+	case filepath.IsAbs(path):
+		return "", fmt.Errorf("path must be relative to the repository root")
+	}
+
+	cleaned := filepath.Clean(path)
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path must be within repository boundaries")
+	}
+	return cleaned, nil
+}
+
+// within reports whether path is root or below it. Both must be absolute
+// and clean.
+func within(path, root string) bool {
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// resolveSymlinks resolves the symlinks in the longest part of an absolute
+// path that exists and appends the rest unchanged
+func resolveSymlinks(path string) (string, error) {
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(append([]string{path}, rest...)...), nil
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
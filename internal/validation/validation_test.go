@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -132,6 +134,12 @@ func TestValidateWorktreeName(t *testing.T) {
 			shouldErr: true,
 			errMsg:    "reserved system name",
 		},
+		{
+			name:      "reserved scratch directory",
+			input:     "scratch",
+			shouldErr: true,
+			errMsg:    "reserved for scratch directories",
+		},
 		{
 			name:      "valid name with numbers at start",
 			input:     "123-feature",
@@ -174,3 +182,157 @@ func TestValidateWorktreeName(t *testing.T) {
 		})
 	}
 }
+
+func TestPolicies(t *testing.T) {
+	tests := []struct {
+		input    string
+		strict   bool
+		standard bool
+		relaxed  bool
+	}{
+		{"feature-123", true, true, true},
+		{"v1.2_Final", true, true, true},
+		{"CON", false, false, true},
+		{"aux", false, false, true},
+		{"scratch", false, false, false},
+		{"feature+login", false, true, true},
+		{"-dash-first", false, true, true},
+		{".hidden", false, true, true},
+		{"../escape", false, false, false},
+		{"a..b", false, false, false},
+		{"bell\x07", false, false, false},
+		{"del\x7f", false, false, false},
+	}
+
+	for _, tt := range tests {
+		for policy, want := range map[Policy]bool{Strict: tt.strict, Standard: tt.standard, Relaxed: tt.relaxed} {
+			err := policy.ValidateWorktreeName(tt.input)
+			if (err == nil) != want {
+				t.Errorf("%s: expected %q valid=%v, got error %v", policy, tt.input, want, err)
+			}
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input    string
+		standard string
+		strict   string
+	}{
+		{"feature/login", "feature-login", "feature-login"},
+		{"bshakr/fix//auth\\flow", "bshakr-fix-auth-flow", "bshakr-fix-auth-flow"},
+		{"fix login bug", "fix-login-bug", "fix-login-bug"},
+		{"../escape", "escape", "escape"},
+		{"release/1..2/", "release-1.2", "release-1.2"},
+		{"feat/ü+x", "feat-ü+x", "feat-x"},
+		{"/_tmp", "_tmp", "tmp"},
+		{"tab\there", "tab-here", "tab-here"},
+	}
+
+	for _, tt := range tests {
+		if got := Standard.Normalize(tt.input); got != tt.standard {
+			t.Errorf("Expected %q under standard, got %q", tt.standard, got)
+		}
+		if got := Strict.Normalize(tt.input); got != tt.strict {
+			t.Errorf("Expected %q under strict, got %q", tt.strict, got)
+		}
+		if err := Strict.ValidateWorktreeName(Strict.Normalize(tt.input)); err != nil {
+			t.Errorf("Expected the strict slug of %q to be valid, got %v", tt.input, err)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Policy
+		wantErr bool
+	}{
+		{"", Standard, false},
+		{"strict", Strict, false},
+		{"Relaxed", Relaxed, false},
+		{"lenient", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePolicy(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("Expected ParsePolicy(%q) = %q (error %v), got %q (%v)", tt.input, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestValidatePathWithinRepository(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	//nolint:gosec // G301: Test directory
+	if err := os.MkdirAll(filepath.Join(root, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "config"), filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		shouldErr bool
+	}{
+		{name: "root", path: root},
+		{name: "existing file", path: filepath.Join(root, "config", "app.yml")},
+		{name: "missing directories", path: filepath.Join(root, "bin", "setup")},
+		{name: "symlink inside", path: filepath.Join(root, "alias", "app.yml")},
+		{name: "parent", path: filepath.Join(root, ".."), shouldErr: true},
+		{name: "sibling with common prefix", path: root + "-other", shouldErr: true},
+		{name: "symlink outside", path: filepath.Join(root, "escape", "secret"), shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePathWithinRepository(tt.path, root)
+			if (err != nil) != tt.shouldErr {
+				t.Errorf("Expected error %v for %s, got %v", tt.shouldErr, tt.path, err)
+			}
+		})
+	}
+
+	// A repository reached through a symlink is still the same repository
+	link := filepath.Join(t.TempDir(), "repo")
+	if err := os.Symlink(root, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidatePathWithinRepository(filepath.Join(root, "config"), link); err != nil {
+		t.Errorf("Expected a path in the repository to be within its symlink, got %v", err)
+	}
+}
+
+func TestValidateRepositoryPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		want      string
+		shouldErr bool
+	}{
+		{path: "./config/", want: "config"},
+		{path: ".env*", want: ".env*"},
+		{path: "a/../b", want: "b"},
+		{path: "", shouldErr: true},
+		{path: "/etc/passwd", shouldErr: true},
+		{path: "../shared", shouldErr: true},
+		{path: "a/../../b", shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ValidateRepositoryPath(tt.path)
+		if (err != nil) != tt.shouldErr {
+			t.Errorf("Expected error %v for %q, got %v", tt.shouldErr, tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
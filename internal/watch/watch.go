@@ -0,0 +1,136 @@
+// Package watch follows the files git changes when worktrees and branches
+// come and go, so koh can refresh its cache as soon as the repository
+// changes instead of checking on every invocation.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long Run waits for the repository to settle before
+// refreshing. Git touches several files for a single command.
+const DefaultDebounce = 200 * time.Millisecond
+
+// Dirs returns the directories to watch for a repository with the given
+// common git directory and koh worktrees directory: the common dir itself
+// for HEAD and packed-refs, the administrative dir of every linked worktree,
+// every directory holding loose branch refs, and the worktrees directory.
+// Directories that don't exist are left out.
+func Dirs(commonDir, kohDir string) []string {
+	var dirs []string
+	add := func(dir string) {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	add(commonDir)
+
+	worktreesDir := filepath.Join(commonDir, "worktrees")
+	add(worktreesDir)
+	if entries, err := os.ReadDir(worktreesDir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				add(filepath.Join(worktreesDir, e.Name()))
+			}
+		}
+	}
+
+	for _, root := range []string{"refs/heads", "refs/remotes"} {
+		_ = filepath.WalkDir(filepath.Join(commonDir, root), func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+	}
+
+	add(kohDir)
+	return dirs
+}
+
+// relevant reports whether a change to path can affect the worktree or
+// branch lists. Lock files and the index change constantly while git works
+// and are ignored.
+func relevant(commonDir, kohDir, path string) bool {
+	if strings.HasSuffix(path, ".lock") {
+		return false
+	}
+
+	if filepath.Dir(path) == kohDir {
+		return true
+	}
+
+	rel, err := filepath.Rel(commonDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	switch parts[0] {
+	case "HEAD", "packed-refs":
+		return len(parts) == 1
+	case "worktrees":
+		// The worktrees dir itself, a worktree's admin dir, or its HEAD
+		return len(parts) <= 2 || (len(parts) == 3 && parts[2] == "HEAD")
+	case "refs":
+		return len(parts) == 1 || parts[1] == "heads" || parts[1] == "remotes"
+	}
+	return false
+}
+
+// Run watches the repository until ctx is done, calling refresh after each
+// burst of relevant changes once nothing has changed for debounce.
+// Directories created while running, such as the admin dir of a new
+// worktree or a new branch namespace, are watched as they appear.
+func Run(ctx context.Context, commonDir, kohDir string, debounce time.Duration, refresh func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	watchDirs := func() {
+		for _, dir := range Dirs(commonDir, kohDir) {
+			// Adding a watched directory again is a no-op
+			_ = watcher.Add(dir)
+		}
+	}
+	watchDirs()
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !relevant(commonDir, kohDir, event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				watchDirs()
+			}
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("file watcher failed: %w", err)
+		case <-timer.C:
+			refresh()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRelevant(t *testing.T) {
+	commonDir := "/repo/.git"
+	kohDir := "/repo/.koh"
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/repo/.git/HEAD", want: true},
+		{path: "/repo/.git/packed-refs", want: true},
+		{path: "/repo/.git/packed-refs.lock", want: false},
+		{path: "/repo/.git/index", want: false},
+		{path: "/repo/.git/worktrees", want: true},
+		{path: "/repo/.git/worktrees/feat-a", want: true},
+		{path: "/repo/.git/worktrees/feat-a/HEAD", want: true},
+		{path: "/repo/.git/worktrees/feat-a/index", want: false},
+		{path: "/repo/.git/refs/heads/main", want: true},
+		{path: "/repo/.git/refs/heads/feat/a", want: true},
+		{path: "/repo/.git/refs/heads/main.lock", want: false},
+		{path: "/repo/.git/refs/remotes/origin/main", want: true},
+		{path: "/repo/.git/refs/tags/v1", want: false},
+		{path: "/repo/.git/logs/HEAD", want: false},
+		{path: "/repo/.koh/feat-a", want: true},
+		{path: "/repo/.koh/feat-a/main.go", want: false},
+		{path: "/repo/main.go", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := relevant(commonDir, kohDir, tt.path); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDirs(t *testing.T) {
+	root := t.TempDir()
+	commonDir := filepath.Join(root, ".git")
+	kohDir := filepath.Join(root, ".koh")
+	for _, dir := range []string{"worktrees/feat-a", "refs/heads/feat", "refs/remotes/origin", "refs/tags", "objects"} {
+		if err := os.MkdirAll(filepath.Join(commonDir, dir), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	want := []string{
+		commonDir,
+		filepath.Join(commonDir, "worktrees"),
+		filepath.Join(commonDir, "worktrees", "feat-a"),
+		filepath.Join(commonDir, "refs", "heads"),
+		filepath.Join(commonDir, "refs", "heads", "feat"),
+		filepath.Join(commonDir, "refs", "remotes"),
+		filepath.Join(commonDir, "refs", "remotes", "origin"),
+	}
+	if got := Dirs(commonDir, kohDir); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if err := os.Mkdir(kohDir, 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", kohDir, err)
+	}
+	if got := Dirs(commonDir, kohDir); !slices.Contains(got, kohDir) {
+		t.Errorf("Expected the worktrees directory once it exists, got %v", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	commonDir := filepath.Join(root, ".git")
+	kohDir := filepath.Join(root, ".koh")
+	if err := os.MkdirAll(filepath.Join(commonDir, "refs", "heads"), 0o755); err != nil {
+		t.Fatalf("Failed to create refs dir: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	refreshes := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, commonDir, kohDir, 10*time.Millisecond, func() { refreshes <- struct{}{} })
+	}()
+
+	write := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte("ref"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	expectRefresh := func(what string) {
+		t.Helper()
+		select {
+		case <-refreshes:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a refresh after %s", what)
+		}
+	}
+
+	// Give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+
+	write(filepath.Join(commonDir, "worktrees", "feat-a", "HEAD"))
+	expectRefresh("adding a worktree")
+
+	// The new admin dir is watched once it appears
+	time.Sleep(50 * time.Millisecond)
+	write(filepath.Join(commonDir, "worktrees", "feat-a", "HEAD"))
+	expectRefresh("changing a worktree's HEAD")
+
+	write(filepath.Join(commonDir, "index"))
+	select {
+	case <-refreshes:
+		t.Error("Expected no refresh when the index changes")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() failed: %v", err)
+	}
+}